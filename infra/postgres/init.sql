@@ -13,11 +13,13 @@ CREATE TABLE IF NOT EXISTS accounts (
     active BOOLEAN DEFAULT true,
     tags TEXT[] DEFAULT '{}',
     notes TEXT,
+    tenant VARCHAR(100) NOT NULL DEFAULT 'default',  -- desk owning the account
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_accounts_platform ON accounts(platform);
+CREATE INDEX idx_accounts_tenant ON accounts(tenant);
 CREATE INDEX idx_accounts_active ON accounts(active);
 CREATE INDEX idx_accounts_tags ON accounts USING GIN(tags);
 
@@ -56,6 +58,8 @@ CREATE TABLE IF NOT EXISTS positions (
     shares DECIMAL(20, 8) DEFAULT 0,
     avg_price DECIMAL(10, 6) DEFAULT 0,
     realized_pnl DECIMAL(20, 8) DEFAULT 0,
+    settlement_price DECIMAL(10, 6),  -- payout per share once the market resolved
+    settled_at TIMESTAMP WITH TIME ZONE,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     UNIQUE(account_id, market_id, outcome_id)
 );
@@ -63,6 +67,59 @@ CREATE TABLE IF NOT EXISTS positions (
 CREATE INDEX idx_positions_account ON positions(account_id);
 CREATE INDEX idx_positions_market ON positions(market_id);
 
+-- ===== Market resolutions (strategy engine) =====
+
+CREATE TABLE IF NOT EXISTS market_resolutions (
+    platform VARCHAR(50) NOT NULL,
+    market_id VARCHAR(255) NOT NULL,
+    outcome VARCHAR(10) NOT NULL,  -- winning side: yes, no
+    resolved_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (platform, market_id)
+);
+
+-- ===== Market mappings (strategy engine) =====
+
+CREATE TABLE IF NOT EXISTS market_mappings (
+    id BIGSERIAL PRIMARY KEY,
+    predict_market_id VARCHAR(255) NOT NULL UNIQUE,
+    polymarket_condition_id VARCHAR(255) NOT NULL UNIQUE,
+    inverted BOOLEAN NOT NULL DEFAULT false,  -- YES on Predict pays like NO on Polymarket
+    note TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===== Trading accounts (strategy engine) =====
+-- The accounts the engine may place orders on, with their limits. Unlike the
+-- accounts table, which keeps the account services' credentials, this is what
+-- commands are checked against.
+
+CREATE TABLE IF NOT EXISTS trading_accounts (
+    platform VARCHAR(50) NOT NULL,
+    account_id VARCHAR(255) NOT NULL,  -- the account service's ID, as commands name it
+    name VARCHAR(255),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    max_order_shares DECIMAL(20, 8),  -- NULL: no limit
+    allowed_markets TEXT[] NOT NULL DEFAULT '{}',  -- empty: any market
+    note TEXT,
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (platform, account_id)
+);
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+    name VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    percent DECIMAL(6, 3) NOT NULL DEFAULT 100,  -- share of markets, 0 to 100
+    environments TEXT[] NOT NULL DEFAULT '{}',  -- empty: every environment
+    description TEXT,
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 -- ===== Strategies =====
 
 CREATE TABLE IF NOT EXISTS strategies (
@@ -71,12 +128,70 @@ CREATE TABLE IF NOT EXISTS strategies (
     type VARCHAR(100) NOT NULL,  -- delta_neutral, arbitrage, market_maker
     config JSONB NOT NULL DEFAULT '{}',
     enabled BOOLEAN DEFAULT false,
+    shadow BOOLEAN DEFAULT false,  -- commands recorded but not executed
+    shadow_reason TEXT,
+    dry_run BOOLEAN NOT NULL DEFAULT false,  -- orders sent unconfirmed while others trade live
+    priority INTEGER NOT NULL DEFAULT 0,  -- higher runs first on the same event (stop-loss before market making)
+    version INTEGER NOT NULL DEFAULT 1,  -- config version currently live
+    tenant VARCHAR(100) NOT NULL DEFAULT 'default',  -- desk owning the strategy
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_strategies_type ON strategies(type);
 CREATE INDEX idx_strategies_enabled ON strategies(enabled);
+CREATE INDEX idx_strategies_tenant ON strategies(tenant);
+
+-- Config versions of a strategy. A candidate runs in shadow mode next to the
+-- live config until it is promoted (copied into strategies.config) or rejected.
+CREATE TABLE IF NOT EXISTS strategy_versions (
+    id BIGSERIAL PRIMARY KEY,
+    strategy_id UUID NOT NULL REFERENCES strategies(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    config JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'candidate',  -- candidate, live, retired, rejected
+    note TEXT,
+    created_by VARCHAR(255),
+    decided_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (strategy_id, version)
+);
+
+CREATE INDEX idx_strategy_versions_candidates ON strategy_versions(strategy_id) WHERE status = 'candidate';
+
+-- Activation periods of a strategy: a run opens when the strategy is enabled
+-- and closes when it is disabled or its config, version or shadow mode
+-- changes, so PnL and journal records can be attributed to the config that was
+-- live at the time. Maintained by the record_strategy_run trigger, which
+-- covers every writer of the strategies table.
+CREATE TABLE IF NOT EXISTS strategy_runs (
+    id BIGSERIAL PRIMARY KEY,
+    strategy_id UUID NOT NULL REFERENCES strategies(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    config JSONB NOT NULL,
+    shadow BOOLEAN NOT NULL DEFAULT false,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    end_reason VARCHAR(50)  -- disabled, config_changed, shadow_changed
+);
+
+CREATE UNIQUE INDEX idx_strategy_runs_open ON strategy_runs(strategy_id) WHERE ended_at IS NULL;
+CREATE INDEX idx_strategy_runs_strategy ON strategy_runs(strategy_id, started_at DESC);
+
+-- ===== Strategy State (strategy engine) =====
+
+-- Durable key-value state of stateful strategies. strategy_id is the engine's
+-- strategy ID (a candidate version's is "<uuid>@v<n>", an A/B test variant's
+-- "<uuid>:<variant>"), so no foreign key.
+CREATE TABLE IF NOT EXISTS strategy_state (
+    strategy_id VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    value JSONB NOT NULL,
+    version BIGINT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (strategy_id, key)
+);
 
 -- ===== Strategy Logs =====
 
@@ -93,6 +208,165 @@ CREATE INDEX idx_strategy_logs_strategy ON strategy_logs(strategy_id);
 CREATE INDEX idx_strategy_logs_level ON strategy_logs(level);
 CREATE INDEX idx_strategy_logs_created ON strategy_logs(created_at DESC);
 
+-- ===== Order Journal (strategy engine) =====
+
+CREATE TABLE IF NOT EXISTS order_journal (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    strategy VARCHAR(255),
+    tenant VARCHAR(100),
+    platform VARCHAR(50) NOT NULL,
+    account_id VARCHAR(255) NOT NULL,
+    market_id VARCHAR(255) NOT NULL,
+    outcome_id VARCHAR(255),  -- outcome token, set for multi-outcome markets
+    side VARCHAR(100) NOT NULL,  -- yes, no, or the outcome name
+    action VARCHAR(10),  -- buy or sell; NULL for orders journaled before it was recorded (buys)
+    price DECIMAL(10, 6) NOT NULL,
+    shares DECIMAL(20, 8) NOT NULL,
+    reference_price DECIMAL(10, 6),  -- market price of the side when sent (book mid or last trade), else the order price
+    order_id VARCHAR(255),
+    status VARCHAR(50) NOT NULL,  -- accepted, rejected, failed, dry_run
+    error_message TEXT,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    hedge_latency_ms INTEGER,  -- accepted hedges: from the hedged fill on the exchange to acceptance
+    filled_shares DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    fill_price DECIMAL(10, 6),
+    filled_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,  -- cancelled by the engine once past
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_journal_platform ON order_journal(platform);
+CREATE INDEX idx_order_journal_order ON order_journal(platform, order_id);
+CREATE INDEX idx_order_journal_created ON order_journal(created_at DESC);
+
+-- ===== Strategy PnL (strategy engine) =====
+
+CREATE TABLE IF NOT EXISTS strategy_pnl (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    strategy VARCHAR(255) NOT NULL,
+    platform VARCHAR(50) NOT NULL,
+    market_id VARCHAR(255),
+    order_id VARCHAR(255),
+    event_id VARCHAR(64),  -- stream entry ID of the booking event; NULL for settlements
+    kind VARCHAR(20) NOT NULL DEFAULT 'realized',  -- realized, fee
+    pnl DECIMAL(20, 8) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_strategy_pnl_strategy ON strategy_pnl(strategy, created_at DESC);
+-- An event books each kind once per strategy, so replays and backfills
+-- cannot count it twice
+CREATE UNIQUE INDEX idx_strategy_pnl_event ON strategy_pnl(event_id, kind, strategy) WHERE event_id IS NOT NULL;
+
+-- ===== Daily reports (strategy engine) =====
+
+-- End-of-day summary per UTC day: orders, volume, fees, PnL and risk events
+-- per strategy
+CREATE TABLE IF NOT EXISTS daily_reports (
+    day DATE PRIMARY KEY,
+    report JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===== Command outbox (strategy engine) =====
+
+CREATE TABLE IF NOT EXISTS command_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    strategy VARCHAR(255) NOT NULL,
+    strategy_id VARCHAR(255),
+    event_id VARCHAR(255),
+    event_type VARCHAR(100),
+    command JSONB NOT NULL,
+    client_order_id VARCHAR(255),  -- idempotency key the command is sent with, outbox-<id>
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, processing, done, failed, unknown (may have executed; reconcile), retried
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_command_outbox_pending ON command_outbox(next_attempt_at) WHERE status = 'pending';
+
+-- place_order commands held until their trigger price is crossed, and the
+-- resting orders of one-cancels-other groups. Rows are deleted once the
+-- trigger fires, the group is resolved or the order is gone.
+CREATE TABLE IF NOT EXISTS conditional_commands (
+    id BIGSERIAL PRIMARY KEY,
+    strategy VARCHAR(255) NOT NULL,
+    strategy_id VARCHAR(255),
+    oco_group VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, resting
+    order_id VARCHAR(255),
+    command JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===== Wire log (strategy engine) =====
+
+-- Sampled requests to the account services and their answers, with
+-- credentials and account IDs redacted, kept for wire_log.retention
+CREATE TABLE IF NOT EXISTS wire_log (
+    id BIGSERIAL PRIMARY KEY,
+    platform VARCHAR(50) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    url TEXT NOT NULL,
+    request_headers JSONB NOT NULL DEFAULT '{}',
+    request_body TEXT,
+    status INTEGER,  -- NULL when no answer came
+    response_headers JSONB,
+    response_body TEXT,
+    error TEXT,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_wire_log_created ON wire_log(created_at);
+
+-- ===== Event archive (strategy engine) =====
+
+-- Copy of the Redis event streams for replay and backtests. stream_ms and
+-- stream_seq are the two halves of the Redis stream entry ID.
+CREATE TABLE IF NOT EXISTS event_archive (
+    stream VARCHAR(100) NOT NULL,
+    stream_ms BIGINT NOT NULL,
+    stream_seq BIGINT NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    platform VARCHAR(50),
+    market_id VARCHAR(255),
+    data JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (stream, stream_ms, stream_seq)
+);
+
+-- Last archived entry and compaction progress per stream
+CREATE TABLE IF NOT EXISTS event_archive_cursors (
+    stream VARCHAR(100) PRIMARY KEY,
+    last_id VARCHAR(50) NOT NULL,
+    compacted_until BIGINT NOT NULL DEFAULT 0,  -- stream_ms
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ===== Strategy decisions (strategy engine) =====
+
+-- Commands each strategy decided on per event, recorded by engines with
+-- record_decisions or compare_mode to compare a candidate build with the
+-- live one (strategy-engine compare)
+CREATE TABLE IF NOT EXISTS strategy_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    instance VARCHAR(100) NOT NULL,  -- instance_name of the engine
+    strategy VARCHAR(255) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100),
+    commands JSONB NOT NULL DEFAULT '[]',
+    error_class VARCHAR(50),  -- why the commands were dropped, if they were
+    error_message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_strategy_decisions_instance ON strategy_decisions(instance, created_at);
+
 -- ===== Users (for web UI auth) =====
 
 CREATE TABLE IF NOT EXISTS users (
@@ -146,3 +420,45 @@ CREATE TRIGGER positions_updated_at
     BEFORE UPDATE ON positions
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at();
+
+CREATE TRIGGER market_mappings_updated_at
+    BEFORE UPDATE ON market_mappings
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at();
+
+-- Closes the open run of a strategy and opens a new one while it is enabled
+CREATE OR REPLACE FUNCTION record_strategy_run()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND NEW.enabled IS NOT DISTINCT FROM OLD.enabled
+        AND NEW.shadow IS NOT DISTINCT FROM OLD.shadow
+        AND NEW.config = OLD.config
+        AND NEW.version = OLD.version THEN
+        RETURN NEW;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        UPDATE strategy_runs
+        SET ended_at = CURRENT_TIMESTAMP,
+            end_reason = CASE
+                WHEN NOT COALESCE(NEW.enabled, false) THEN 'disabled'
+                WHEN NEW.shadow IS DISTINCT FROM OLD.shadow THEN 'shadow_changed'
+                ELSE 'config_changed'
+            END
+        WHERE strategy_id = NEW.id AND ended_at IS NULL;
+    END IF;
+
+    IF COALESCE(NEW.enabled, false) THEN
+        INSERT INTO strategy_runs (strategy_id, version, config, shadow)
+        VALUES (NEW.id, NEW.version, NEW.config, COALESCE(NEW.shadow, false));
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER strategies_record_run
+    AFTER INSERT OR UPDATE OF enabled, shadow, config, version ON strategies
+    FOR EACH ROW
+    EXECUTE FUNCTION record_strategy_run();
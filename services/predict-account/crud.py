@@ -3,6 +3,7 @@
 import uuid
 from typing import Optional
 from sqlalchemy import select
+from sqlalchemy.exc import IntegrityError
 from sqlalchemy.ext.asyncio import AsyncSession
 from eth_account import Account as EthAccount
 
@@ -26,6 +27,7 @@ async def create_account(db: AsyncSession, account_data: AccountCreate) -> Accou
         proxy_url=account_data.proxy_url,
         tags=account_data.tags,
         notes=account_data.notes,
+        tenant=account_data.tenant,
     )
     
     db.add(account)
@@ -147,6 +149,53 @@ async def create_trade(
     return trade
 
 
+async def get_trade_by_client_order_id(db: AsyncSession, client_order_id: str) -> Optional[Trade]:
+    """Get the trade placed with an idempotency key"""
+    result = await db.execute(
+        select(Trade).where(Trade.client_order_id == client_order_id)
+    )
+    return result.scalar_one_or_none()
+
+
+async def reserve_trade(
+    db: AsyncSession,
+    account_id: str,
+    account_name: str,
+    market_id: str,
+    side: str,
+    price: float,
+    shares: float,
+    client_order_id: str,
+) -> tuple[Trade, bool]:
+    """Create the pending trade record of an idempotency key, committed before
+    the order is sent. Returns the record and whether it is new; an existing
+    record belongs to an earlier request with the same key."""
+    existing = await get_trade_by_client_order_id(db, client_order_id)
+    if existing:
+        return existing, False
+
+    trade = Trade(
+        id=str(uuid.uuid4()),
+        account_id=account_id,
+        account_name=account_name,
+        market_id=market_id,
+        outcome_id="",
+        side=side,
+        price=price,
+        shares=shares,
+        client_order_id=client_order_id,
+        status="pending",
+    )
+    db.add(trade)
+    try:
+        await db.commit()
+    except IntegrityError:
+        # A concurrent request reserved the key first
+        await db.rollback()
+        return await get_trade_by_client_order_id(db, client_order_id), False
+    return trade, True
+
+
 async def update_trade_status(
     db: AsyncSession,
     trade_id: str,
@@ -1,7 +1,9 @@
 """Event publisher to Redis Streams"""
 
+import asyncio
 import json
 import logging
+import time
 from datetime import datetime
 from typing import Dict, Any
 import redis.asyncio as redis
@@ -12,10 +14,16 @@ logger = logging.getLogger(__name__)
 class EventPublisher:
     """Publish events to Redis Streams for strategy engine"""
     
-    def __init__(self, redis_host: str = "redis", redis_port: int = 6379):
+    def __init__(self, redis_host: str = "redis", redis_port: int = 6379, producer: str = ""):
         self.redis_host = redis_host
         self.redis_port = redis_port
         self.client = None
+        # With a producer name, entries carry "producer" and a per-stream "seq"
+        # so the strategy engine can detect lost events. The start time makes
+        # a restart a new producer.
+        self.producer = f"{producer}/{int(time.time() * 1000)}" if producer else ""
+        self._seq: Dict[str, int] = {}
+        self._seq_lock = asyncio.Lock()
     
     async def _get_client(self):
         """Get or create Redis client"""
@@ -44,7 +52,17 @@ class EventPublisher:
         }
         
         try:
-            await client.xadd(stream_name, event)
+            if self.producer:
+                # Numbers are taken in the order entries land on the stream;
+                # a failed publish leaves a gap, which is the point
+                async with self._seq_lock:
+                    seq = self._seq.get(stream_name, 0) + 1
+                    self._seq[stream_name] = seq
+                    event["producer"] = self.producer
+                    event["seq"] = str(seq)
+                    await client.xadd(stream_name, event)
+            else:
+                await client.xadd(stream_name, event)
             logger.info(f"Published event: {stream_name} / {event_type}")
         except Exception as e:
             logger.error(f"Failed to publish event: {e}")
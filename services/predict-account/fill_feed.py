@@ -0,0 +1,286 @@
+"""Predict.fun WebSocket fill feed.
+
+Subscribes to the wallet event feed of every active account and publishes
+normalized events to the Redis streams as they happen, instead of waiting for
+someone to poll /orders:
+
+- filled orders (orderTransactionSuccess) -> "fill" on fill_events
+- cancelled / expired orders -> "order_cancelled" on trade_events
+
+Each account gets its own connection. A dropped connection is re-established
+with backoff and a fresh JWT; the account list is re-read periodically, so
+new or deactivated accounts are picked up without a restart. Fills with a
+transaction hash carry a fill_id, which the strategy engine deduplicates on.
+"""
+
+import asyncio
+import json
+import logging
+import random
+from collections import deque
+from datetime import datetime, timezone
+from typing import Any, Optional
+
+import websockets
+from sqlalchemy import select
+
+from crud import get_accounts
+from database import async_session_maker
+from event_publisher import EventPublisher
+from models import Account, Trade
+from predict_client import PredictClient
+
+logger = logging.getLogger(__name__)
+
+WEI = 10**18
+
+# Wallet event types, as sent by Predict
+FILL_EVENTS = {"orderTransactionSuccess"}
+CANCEL_EVENTS = {"orderCancelled", "orderExpired"}
+
+
+def _get(d: dict, *keys: str) -> Any:
+    for k in keys:
+        if isinstance(d, dict) and d.get(k) is not None:
+            return d[k]
+    return None
+
+
+def _amount(value: Any) -> Optional[float]:
+    """Parse a price or quantity; integers of wei size are scaled down."""
+    if value is None:
+        return None
+    try:
+        number = float(value)
+    except (TypeError, ValueError):
+        return None
+    if isinstance(value, str) and "." not in value and number >= 1e9:
+        return number / WEI
+    return number
+
+
+def _timestamp(value: Any) -> Optional[str]:
+    """Normalize an exchange timestamp (epoch seconds or ms, or ISO 8601) to ISO 8601 UTC."""
+    if value is None:
+        return None
+    if isinstance(value, str):
+        try:
+            value = float(value)
+        except ValueError:
+            try:
+                parsed = datetime.fromisoformat(value.replace("Z", "+00:00"))
+            except ValueError:
+                return None
+            if parsed.tzinfo is None:
+                parsed = parsed.replace(tzinfo=timezone.utc)
+            return parsed.astimezone(timezone.utc).isoformat()
+    try:
+        seconds = float(value)
+    except (TypeError, ValueError):
+        return None
+    if seconds > 1e11:
+        seconds /= 1000
+    return datetime.fromtimestamp(seconds, tz=timezone.utc).isoformat()
+
+
+class FillFeed:
+    """Keeps a wallet event subscription open for every active account"""
+
+    def __init__(
+        self,
+        predict_client: PredictClient,
+        event_publisher: EventPublisher,
+        ws_url: str,
+        refresh_interval: float = 300.0,
+        max_backoff: float = 60.0,
+    ):
+        self.predict_client = predict_client
+        self.event_publisher = event_publisher
+        self.ws_url = ws_url
+        self.refresh_interval = refresh_interval
+        self.max_backoff = max_backoff
+
+        self._tasks: dict[str, asyncio.Task] = {}
+        self._connected: set[str] = set()
+        self._seen: deque[str] = deque(maxlen=10000)
+        self._seen_set: set[str] = set()
+        self._supervisor: Optional[asyncio.Task] = None
+
+    def start(self):
+        """Start the feed in the background"""
+        self._supervisor = asyncio.create_task(self._supervise())
+
+    async def stop(self):
+        """Close every subscription"""
+        tasks = list(self._tasks.values())
+        if self._supervisor:
+            tasks.append(self._supervisor)
+        for task in tasks:
+            task.cancel()
+        await asyncio.gather(*tasks, return_exceptions=True)
+        self._tasks.clear()
+
+    def status(self) -> dict:
+        """Accounts subscribed and connected, for /health"""
+        return {
+            "accounts": len(self._tasks),
+            "connected": len(self._connected),
+        }
+
+    async def _supervise(self):
+        """Start and stop account subscriptions as accounts change"""
+        while True:
+            try:
+                async with async_session_maker() as db:
+                    accounts = await get_accounts(db, active_only=True)
+
+                active = {a.id: a for a in accounts}
+                for account_id, task in list(self._tasks.items()):
+                    if account_id not in active or task.done():
+                        task.cancel()
+                        del self._tasks[account_id]
+                for account_id, account in active.items():
+                    if account_id not in self._tasks:
+                        self._tasks[account_id] = asyncio.create_task(self._run_account(account))
+            except asyncio.CancelledError:
+                raise
+            except Exception as e:
+                logger.error(f"Fill feed: failed to refresh accounts: {e}")
+
+            await asyncio.sleep(self.refresh_interval)
+
+    async def _run_account(self, account: Account):
+        """Keep the account's subscription open, reconnecting with backoff"""
+        backoff = 1.0
+        while True:
+            try:
+                await self._subscribe(account)
+                backoff = 1.0
+            except asyncio.CancelledError:
+                raise
+            except Exception as e:
+                logger.warning(f"Fill feed: {account.name} disconnected: {e}")
+            finally:
+                self._connected.discard(account.id)
+
+            await asyncio.sleep(backoff + random.uniform(0, backoff / 2))
+            backoff = min(backoff * 2, self.max_backoff)
+
+    async def _subscribe(self, account: Account):
+        client = PredictClient(api_key=account.api_key) if account.api_key else self.predict_client
+        jwt = await client.authenticate(account.private_key, predict_account=account.address)
+
+        async with websockets.connect(
+            self.ws_url,
+            additional_headers={"x-api-key": client.api_key or ""},
+            ping_interval=20,
+        ) as ws:
+            await ws.send(json.dumps({
+                "method": "subscribe",
+                "requestId": 1,
+                "params": [f"predictWalletEvents/{jwt}"],
+            }))
+            self._connected.add(account.id)
+            logger.info(f"Fill feed: subscribed to wallet events of {account.name}")
+
+            async for raw in ws:
+                try:
+                    message = json.loads(raw)
+                except ValueError:
+                    continue
+
+                if message.get("topic") == "heartbeat":
+                    await ws.send(json.dumps({"method": "heartbeat", "data": message.get("data")}))
+                    continue
+                if message.get("type") == "R" and message.get("success") is False:
+                    raise RuntimeError(f"subscription refused: {message.get('error')}")
+
+                data = message.get("data")
+                if isinstance(data, dict):
+                    await self._handle(account, data)
+
+    async def _handle(self, account: Account, data: dict):
+        event_type = _get(data, "type", "event")
+        if event_type not in FILL_EVENTS and event_type not in CANCEL_EVENTS:
+            return
+
+        order = _get(data, "order") or data
+        order_hash = _get(order, "hash", "orderHash", "order_hash") or _get(data, "orderHash", "hash")
+        if not order_hash:
+            logger.warning(f"Fill feed: {event_type} without order hash for {account.name}")
+            return
+
+        tx_hash = _get(data, "transactionHash", "txHash")
+        event_id = f"{event_type}:{order_hash}:{tx_hash or ''}"
+        if event_id in self._seen_set:
+            return
+        if len(self._seen) == self._seen.maxlen:
+            self._seen_set.discard(self._seen[0])
+        self._seen.append(event_id)
+        self._seen_set.add(event_id)
+
+        # Our own trade log knows market and side of orders placed through us
+        trade = await self._trade(order_hash)
+        market_id = _get(order, "marketId", "market_id") or _get(data, "marketId")
+        side = None
+        outcome_id = None
+        if trade:
+            market_id = trade.market_id
+            side = trade.side
+            outcome_id = trade.outcome_id or None
+        else:
+            outcome = _get(order, "outcome") or _get(data, "outcome")
+            name = _get(outcome, "name", "title") if isinstance(outcome, dict) else outcome
+            if name:
+                side = str(name).lower()
+
+        base = {
+            "account_id": account.id,
+            "account_name": account.name,
+            "tenant": account.tenant,
+            "market_id": str(market_id) if market_id is not None else None,
+            "side": side,
+            "outcome_id": outcome_id,
+            "order_hash": order_hash,
+            "order_id": order_hash,
+            "platform": "predict",
+        }
+
+        if event_type in CANCEL_EVENTS:
+            await self.event_publisher.publish_trade_event("order_cancelled", {
+                **base,
+                "reason": "expired" if event_type == "orderExpired" else "cancelled",
+            })
+            return
+
+        price = _amount(_get(order, "pricePerShare", "price") or _get(data, "price"))
+        shares = _amount(_get(data, "filledAmount", "quantityFilled", "amount") or _get(order, "quantity", "amount"))
+        if price is None and trade:
+            price = trade.price
+        if shares is None and trade:
+            shares = trade.shares
+        if price is None or shares is None or not market_id or not side:
+            logger.warning(f"Fill feed: incomplete fill {order_hash} for {account.name}: {data}")
+            return
+
+        await self.event_publisher.publish_fill_event({
+            **base,
+            # One order can fill in several transactions: without the
+            # transaction there is no telling its fills apart
+            "fill_id": f"{order_hash}:{tx_hash}" if tx_hash else None,
+            "price": price,
+            "shares": shares,
+            "tx_hash": tx_hash,
+            # When the exchange filled the order, for hedge latency
+            "filled_at": _timestamp(_get(data, "timestamp", "createdAt", "executedAt")),
+        })
+        logger.info(f"Fill feed: {account.name} filled {shares} {side} @ {price} on {market_id}")
+
+    async def _trade(self, order_hash: str) -> Optional[Trade]:
+        try:
+            async with async_session_maker() as db:
+                result = await db.execute(select(Trade).where(Trade.order_hash == order_hash).limit(1))
+                return result.scalar_one_or_none()
+        except Exception as e:
+            logger.warning(f"Fill feed: trade lookup for {order_hash} failed: {e}")
+            return None
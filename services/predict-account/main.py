@@ -5,6 +5,7 @@ Manages accounts, executes trades, monitors fills
 
 import os
 import logging
+import httpx
 from contextlib import asynccontextmanager
 from fastapi import FastAPI, HTTPException, Depends
 from fastapi.middleware.cors import CORSMiddleware
@@ -23,6 +24,7 @@ from schemas import (
 )
 from predict_client import PredictClient
 from event_publisher import EventPublisher
+from fill_feed import FillFeed
 from close_all import build_close_all_plan
 
 # Logging
@@ -32,12 +34,13 @@ logger = logging.getLogger(__name__)
 # Global clients
 predict_client: PredictClient = None
 event_publisher: EventPublisher = None
+fill_feed: FillFeed = None
 
 
 @asynccontextmanager
 async def lifespan(app: FastAPI):
     """Startup and shutdown events"""
-    global predict_client, event_publisher
+    global predict_client, event_publisher, fill_feed
     
     # Startup
     logger.info("Starting Predict Account Service...")
@@ -51,14 +54,25 @@ async def lifespan(app: FastAPI):
     event_publisher = EventPublisher(
         redis_host=os.getenv("REDIS_HOST", "redis"),
         redis_port=int(os.getenv("REDIS_PORT", 6379)),
+        producer=os.getenv("EVENT_PRODUCER", ""),
     )
     
+    if os.getenv("PREDICT_FILL_FEED", "true").lower() in ("1", "true", "yes"):
+        fill_feed = FillFeed(
+            predict_client=predict_client,
+            event_publisher=event_publisher,
+            ws_url=os.getenv("PREDICT_WS_URL", "wss://ws.predict.fun/ws"),
+        )
+        fill_feed.start()
+    
     logger.info("Predict Account Service started")
     
     yield
     
     # Shutdown
     logger.info("Shutting down Predict Account Service...")
+    if fill_feed:
+        await fill_feed.stop()
     await event_publisher.close()
 
 
@@ -88,6 +102,8 @@ async def root():
 
 @app.get("/health")
 async def health():
+    if fill_feed:
+        return {"status": "healthy", "fill_feed": fill_feed.status()}
     return {"status": "healthy"}
 
 
@@ -217,7 +233,26 @@ async def execute_trade(
     
     if not account.active:
         raise HTTPException(status_code=400, detail="Account is inactive")
-    
+
+    # Reserve the idempotency key before sending: a repeat of the key gets
+    # the first request's order instead of placing a second one
+    trade_row = None
+    if trade_request.client_order_id and trade_request.confirm:
+        from crud import reserve_trade
+
+        trade_row, new = await reserve_trade(
+            db,
+            account_id=account.id,
+            account_name=account.name,
+            market_id=trade_request.market_id,
+            side=trade_request.side,
+            price=trade_request.price,
+            shares=trade_request.shares,
+            client_order_id=trade_request.client_order_id,
+        )
+        if not new:
+            return duplicate_trade_response(trade_row)
+
     # Execute trade
     try:
         result = await execute_trade(
@@ -229,17 +264,21 @@ async def execute_trade(
         # Persist trade (so UI can display history even for dry-run)
         from crud import create_trade as db_create_trade
 
-        trade_row = await db_create_trade(
-            db,
-            account_id=account.id,
-            account_name=account.name,
-            market_id=trade_request.market_id,
-            outcome_id=result.get("outcome_id") or "",
-            side=trade_request.side,
-            price=trade_request.price,
-            shares=trade_request.shares,
-            order_hash=result.get("order_hash"),
-        )
+        if trade_row is None:
+            trade_row = await db_create_trade(
+                db,
+                account_id=account.id,
+                account_name=account.name,
+                market_id=trade_request.market_id,
+                outcome_id=result.get("outcome_id") or "",
+                side=trade_request.side,
+                price=trade_request.price,
+                shares=trade_request.shares,
+                order_hash=result.get("order_hash"),
+            )
+        else:
+            trade_row.outcome_id = result.get("outcome_id") or ""
+            trade_row.order_hash = result.get("order_hash")
         # Reflect status on the DB row
         if result.get("status") == "dry_run":
             trade_row.status = "dry_run"
@@ -254,6 +293,7 @@ async def execute_trade(
                 {
                     "account_id": account.id,
                     "account_name": account.name,
+                    "tenant": account.tenant,
                     "market_id": trade_request.market_id,
                     "side": trade_request.side,
                     "price": trade_request.price,
@@ -267,6 +307,7 @@ async def execute_trade(
                 {
                     "account_id": account.id,
                     "account_name": account.name,
+                    "tenant": account.tenant,
                     "market_id": trade_request.market_id,
                     "side": trade_request.side,
                     "price": trade_request.price,
@@ -283,19 +324,71 @@ async def execute_trade(
         err_text = str(e) or repr(e)
         logger.error(f"Trade execution failed: {err_text}")
         logger.error(traceback.format_exc())
+
+        # Keep the key reserved: the order may have reached Predict anyway
+        if trade_row is not None and trade_row.status == "pending":
+            trade_row.status = "failed"
+            trade_row.error = err_text
+            await db.commit()
         
         # Publish error event
         await event_publisher.publish_trade_event("trade_error", {
             "account_id": account.id,
             "account_name": account.name,
+            "tenant": account.tenant,
             "market_id": trade_request.market_id,
             "error": err_text,
             "platform": "predict",
         })
         
+        rejection = venue_rejection(e)
+        if rejection is not None:
+            raise HTTPException(status_code=422, detail=rejection)
         raise HTTPException(status_code=500, detail=err_text)
 
 
+def venue_rejection(e: Exception):
+    """The error detail for an order Predict refused with a 4xx, such as for
+    its price: the caller may change the order and send it again. None for
+    other failures, whose outcome is unknown."""
+    if not isinstance(e, httpx.HTTPStatusError) or not 400 <= e.response.status_code < 500:
+        return None
+    return {
+        "code": "venue_rejected",
+        "message": f"Predict rejected the order ({e.response.status_code}): {e.response.text}",
+    }
+
+
+def duplicate_trade_response(trade: Trade) -> dict:
+    """Answer a trade request repeating an idempotency key with the order of
+    the first request. A first request still in flight, or one that failed
+    and may have been placed anyway, is reported as an error for the caller
+    to retry or reconcile."""
+    if trade.status == "pending":
+        raise HTTPException(status_code=503, detail={
+            "code": "order_in_progress",
+            "message": f"An order with client_order_id {trade.client_order_id} is being placed",
+        })
+    if trade.status == "failed":
+        raise HTTPException(status_code=500, detail={
+            "code": "order_outcome_unknown",
+            "message": f"The order with client_order_id {trade.client_order_id} failed: {trade.error}",
+        })
+
+    return {
+        "trade_id": trade.order_hash,
+        "account_id": trade.account_id,
+        "account_name": trade.account_name,
+        "market_id": trade.market_id,
+        "side": trade.side,
+        "price": trade.price,
+        "shares": trade.shares,
+        "order_hash": trade.order_hash,
+        "status": trade.status,
+        "message": f"Duplicate client_order_id {trade.client_order_id}: order already placed",
+    }
+
+
 @app.post("/accounts/{account_id}/close-all")
 async def close_all_positions(
     account_id: str,
@@ -329,6 +422,7 @@ async def close_all_positions(
             {
                 "account_id": account.id,
                 "account_name": account.name,
+                "tenant": account.tenant,
                 "count": len(plan),
                 "slippage_bps": slippage_bps,
             },
@@ -375,6 +469,26 @@ async def get_orders(
         raise HTTPException(status_code=500, detail=str(e))
 
 
+@app.get("/markets/{market_id}")
+async def get_market(market_id: str):
+    """Get a market's details (Predict API): tick size, order size limits, fees, close time"""
+    try:
+        return await predict_client.get_market(market_id)
+    except Exception as e:
+        logger.error(f"Failed to get market: {e}")
+        raise HTTPException(status_code=500, detail=str(e))
+
+
+@app.get("/markets/{market_id}/orderbook")
+async def get_market_orderbook(market_id: str):
+    """Get a market's order book (Predict API): YES bids and asks as [[price, size], ...]"""
+    try:
+        return await predict_client.get_orderbook(market_id)
+    except Exception as e:
+        logger.error(f"Failed to get orderbook: {e}")
+        raise HTTPException(status_code=500, detail=str(e))
+
+
 @app.get("/positions/{account_id}", response_model=list[PositionResponse])
 async def get_positions(
     account_id: str,
@@ -502,6 +616,7 @@ async def close_all_positions(
     await event_publisher.publish_trade_event("close_all_attempt", {
         "account_id": account.id,
         "account_name": account.name,
+        "tenant": account.tenant,
         "positions_count": len(plan),
         "results_count": len(results),
         "errors_count": len(errors),
@@ -519,25 +634,6 @@ async def close_all_positions(
     }
 
 
-@app.websocket("/ws/fills")
-async def websocket_fills(websocket):
-    """WebSocket endpoint for monitoring fills"""
-    await websocket.accept()
-    
-    # TODO: Implement WebSocket fill monitoring
-    # This will listen to Predict.fun WebSocket and forward fill events
-    
-    try:
-        while True:
-            data = await websocket.receive_text()
-            # Handle incoming messages
-            pass
-    except Exception as e:
-        logger.error(f"WebSocket error: {e}")
-    finally:
-        await websocket.close()
-
-
 if __name__ == "__main__":
     import uvicorn
     uvicorn.run(app, host="0.0.0.0", port=8000)
@@ -20,6 +20,7 @@ class Account(Base):
     active = Column(Boolean, default=True)
     tags = Column(ARRAY(String), default=list)  # Tags for grouping
     notes = Column(Text, nullable=True)
+    tenant = Column(String, nullable=False, default="default")  # Desk owning the account
     created_at = Column(DateTime, default=datetime.utcnow)
     updated_at = Column(DateTime, default=datetime.utcnow, onupdate=datetime.utcnow)
 
@@ -37,6 +38,7 @@ class Trade(Base):
     price = Column(Float, nullable=False)
     shares = Column(Float, nullable=False)
     order_hash = Column(String, nullable=True)
+    client_order_id = Column(String, unique=True, nullable=True)  # Caller's idempotency key
     status = Column(String, default="pending")  # pending, filled, cancelled, failed
     error = Column(Text, nullable=True)
     created_at = Column(DateTime, default=datetime.utcnow)
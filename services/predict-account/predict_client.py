@@ -185,12 +185,12 @@ class PredictClient:
         is_neg_risk = market.get("isNegRisk", False)
         is_yield_bearing = market.get("isYieldBearing", False)
 
-        # Find token_id for the outcome
+        # Find token_id for the outcome, by ID or else by name
         token_id = None
-        target_name = "yes" if side.lower() == "yes" else "no"
         for o in market.get("outcomes", []):
+            ids = {str(o.get(k)) for k in ("onChainId", "tokenId", "id") if o.get(k) is not None}
             name = str(o.get("name") or o.get("title") or "").lower()
-            if name == target_name:
+            if (outcome_id and outcome_id in ids) or (not outcome_id and name == side.lower()):
                 token_id = o.get("onChainId") or o.get("tokenId") or o.get("id")
                 break
 
@@ -299,6 +299,9 @@ class PredictClient:
                     response.raise_for_status()
                     return response.json()
             except (httpx.TransportError, httpx.HTTPStatusError) as e:
+                # A refused order would be refused again
+                if isinstance(e, httpx.HTTPStatusError) and e.response.status_code < 500:
+                    raise
                 last_err = e
                 await asyncio.sleep(1.0 * (attempt + 1))
 
@@ -14,6 +14,7 @@ class AccountCreate(BaseModel):
     api_key: Optional[str] = None
     tags: list[str] = []
     notes: Optional[str] = None
+    tenant: str = "default"
 
 
 class AccountUpdate(BaseModel):
@@ -30,6 +31,7 @@ class AccountResponse(BaseModel):
     active: bool
     tags: list[str]
     notes: Optional[str]
+    tenant: str
     created_at: datetime
     updated_at: datetime
     
@@ -42,10 +44,12 @@ class AccountResponse(BaseModel):
 class TradeRequest(BaseModel):
     account_id: str
     market_id: str
-    side: str = Field(..., pattern="^(yes|no)$")
+    side: str = Field(..., min_length=1)  # yes, no, or the outcome name
+    outcome_id: Optional[str] = None  # picks the outcome of multi-outcome markets
     price: float = Field(..., gt=0, le=1)
     shares: float = Field(..., gt=0)
     confirm: bool = False  # Dry-run protection
+    client_order_id: Optional[str] = Field(None, max_length=255)  # Idempotency key: one order per key
 
 
 class TradeResponse(BaseModel):
@@ -58,6 +62,7 @@ class TradeResponse(BaseModel):
     shares: float
     order_hash: Optional[str] = None
     status: str
+    filled_shares: float = 0
     message: str
 
 
@@ -26,17 +26,21 @@ async def execute_trade(
     # Get market to find outcome ID (needed for both dry-run and confirm)
     market = await client.get_market(trade_request.market_id)
 
-    # Find outcome ID based on side
+    # Find outcome ID: the one requested, else the outcome named by side
     outcome_id = None
-    desired_name = "Yes" if trade_request.side.lower() == "yes" else "No"
     for outcome in market.get("outcomes", []):
-        if str(outcome.get("name", "")).lower() == desired_name.lower():
+        ids = {str(outcome.get(k)) for k in ("onChainId", "tokenId", "id") if outcome.get(k) is not None}
+        if trade_request.outcome_id:
+            matched = trade_request.outcome_id in ids
+        else:
+            matched = str(outcome.get("name", "")).lower() == trade_request.side.lower()
+        if matched:
             outcome_id = outcome.get("onChainId") or outcome.get("id")
             break
 
     if not outcome_id:
         raise ValueError(
-            f"Could not find outcome for side '{trade_request.side}' in market {trade_request.market_id}"
+            f"Could not find outcome '{trade_request.outcome_id or trade_request.side}' in market {trade_request.market_id}"
         )
 
     # Dry-run check
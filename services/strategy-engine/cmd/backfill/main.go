@@ -0,0 +1,300 @@
+// Command backfill rebuilds the strategy_pnl ledger for the time before it
+// existed, by replaying archived fills and account events through the PnL
+// calculator the engine uses (internal/pnl), so strategy performance does
+// not start from zero.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/config"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/costs"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/pnl"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const usage = `Usage: backfill [flags]
+
+Reads archived events (event_archive) between --from and --until, computes
+the realized PnL and fill fees each one books, as the engine would have, and
+writes them to strategy_pnl. Fees are modeled with the platforms' costs from
+the config and the market fees announced in archived market_events.
+
+--until defaults to the oldest record already in strategy_pnl, so only the
+time before the ledger existed is filled in. Events book each kind of record
+once per strategy, so running it again, or over a range the engine already
+booked, adds nothing twice; records booked before event IDs were kept are
+the exception, hence the default.
+
+`
+
+const (
+	batchSize    = 1000
+	marketStream = "market_events"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "backfill:", err)
+		os.Exit(1)
+	}
+}
+
+// summary is what the backfill booked for one strategy
+type summary struct {
+	Records  int
+	Inserted int64
+	Realized float64
+	Fees     float64
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	configPath := fs.String("config", os.Getenv("STRATEGY_CONFIG"), "path to YAML config file (env vars override it)")
+	fromFlag := fs.String("from", "", "first event time, RFC3339 (default: the start of the archive)")
+	untilFlag := fs.String("until", "", "end of the range, RFC3339, exclusive (default: the oldest strategy_pnl record)")
+	streamList := fs.String("streams", "fill_events,account_events", "comma-separated archived streams to replay")
+	marketFees := fs.Bool("market-fees", true, "read market fees from archived market_events")
+	dryRun := fs.Bool("dry-run", false, "compute and print the records without writing them")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: secrets.NewRedactingWriter(os.Stderr)}).
+		With().Timestamp().Logger()
+
+	var from, until time.Time
+	var err error
+	if *fromFlag != "" {
+		if from, err = time.Parse(time.RFC3339, *fromFlag); err != nil {
+			return fmt.Errorf("--from: %w", err)
+		}
+	}
+	if *untilFlag != "" {
+		if until, err = time.Parse(time.RFC3339, *untilFlag); err != nil {
+			return fmt.Errorf("--until: %w", err)
+		}
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	store, err := storage.NewPostgres(ctx, storage.Options{
+		URL:      cfg.PostgresURL,
+		MaxConns: 2,
+		Password: cfg.PostgresPasswordFunc(),
+	})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if until.IsZero() {
+		first, ok, err := store.GetFirstPnLTime(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read the PnL ledger: %w", err)
+		}
+		until = time.Now().UTC()
+		if ok {
+			until = first
+		}
+	}
+	if !until.After(from) {
+		return fmt.Errorf("nothing to backfill: --until %s is not after --from %s",
+			until.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+	start := [2]int64{from.UnixMilli(), 0}
+	end := [2]int64{until.UnixMilli() - 1, math.MaxInt64}
+
+	log.Info().
+		Time("from", from).
+		Time("until", until).
+		Bool("dry_run", *dryRun).
+		Msg("Backfilling strategy PnL")
+
+	registry := markets.NewRegistry()
+	if *marketFees {
+		n, err := scanArchive(ctx, store, marketStream, start, end, func(events []types.Event) error {
+			for _, event := range events {
+				registry.Update(event)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", marketStream, err)
+		}
+		log.Info().Int("events", n).Msg("Read market fees from the archive")
+	}
+
+	// Orders are attributed like the engine does, from the event or the
+	// order journal; lookups are cached as fills of an order repeat them
+	attributed := make(map[string]string)
+	lookup := func(ctx context.Context, platform, orderID string) (string, error) {
+		key := platform + "\x00" + orderID
+		if strategy, ok := attributed[key]; ok {
+			return strategy, nil
+		}
+		strategy, err := store.GetOrderStrategy(ctx, platform, orderID)
+		if err != nil {
+			return "", err
+		}
+		attributed[key] = strategy
+		return strategy, nil
+	}
+	calc := pnl.NewCalculator(costs.NewModel(platformCosts(cfg), registry), lookup)
+
+	summaries := make(map[string]*summary)
+	for _, stream := range strings.Split(*streamList, ",") {
+		if stream = strings.TrimSpace(stream); stream == "" {
+			continue
+		}
+		n, err := scanArchive(ctx, store, stream, start, end, func(events []types.Event) error {
+			var records []types.PnLRecord
+			for _, event := range events {
+				booked, err := calc.Records(ctx, event)
+				if err != nil {
+					return fmt.Errorf("event %s: %w", event.ID, err)
+				}
+				records = append(records, booked...)
+			}
+			return book(ctx, store, records, summaries, *dryRun)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", stream, err)
+		}
+		log.Info().Str("stream", stream).Int("events", n).Msg("Replayed archived events")
+	}
+
+	return printSummaries(summaries, *dryRun)
+}
+
+// scanArchive hands the archived events of a stream between start and end to
+// handle, a batch at a time, and returns how many there were
+func scanArchive(ctx context.Context, store *storage.PostgresStorage, stream string, start, end [2]int64, handle func([]types.Event) error) (int, error) {
+	total := 0
+	for {
+		events, err := store.GetArchivedEvents(ctx, stream, start, end, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(events) == 0 {
+			return total, nil
+		}
+		if err := handle(events); err != nil {
+			return total, err
+		}
+		total += len(events)
+		if len(events) < batchSize {
+			return total, nil
+		}
+
+		var ms, seq int64
+		if _, err := fmt.Sscanf(events[len(events)-1].ID, "%d-%d", &ms, &seq); err != nil {
+			return total, fmt.Errorf("archived event %q has no stream ID: %w", events[len(events)-1].ID, err)
+		}
+		start = [2]int64{ms, seq + 1}
+	}
+}
+
+// book writes records to the ledger, unless dryRun, and adds them to the
+// summaries
+func book(ctx context.Context, store *storage.PostgresStorage, records []types.PnLRecord, summaries map[string]*summary, dryRun bool) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	byStrategy := make(map[string][]types.PnLRecord)
+	for _, record := range records {
+		byStrategy[record.Strategy] = append(byStrategy[record.Strategy], record)
+	}
+	for strategy, records := range byStrategy {
+		s, ok := summaries[strategy]
+		if !ok {
+			s = &summary{}
+			summaries[strategy] = s
+		}
+		for _, record := range records {
+			s.Records++
+			if record.Kind == types.PnLFee {
+				s.Fees -= record.PnL
+			} else {
+				s.Realized += record.PnL
+			}
+		}
+		if dryRun {
+			continue
+		}
+		inserted, err := store.BackfillPnL(ctx, records)
+		if err != nil {
+			return err
+		}
+		s.Inserted += inserted
+	}
+	return nil
+}
+
+func printSummaries(summaries map[string]*summary, dryRun bool) error {
+	names := make([]string, 0, len(summaries))
+	for name := range summaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if dryRun {
+		fmt.Fprintln(t, "STRATEGY\tRECORDS\tREALIZED\tFEES\tPNL")
+	} else {
+		fmt.Fprintln(t, "STRATEGY\tRECORDS\tWRITTEN\tREALIZED\tFEES\tPNL")
+	}
+	for _, name := range names {
+		s := summaries[name]
+		if dryRun {
+			fmt.Fprintf(t, "%s\t%d\t%.2f\t%.2f\t%.2f\n", name, s.Records, s.Realized, s.Fees, s.Realized-s.Fees)
+		} else {
+			fmt.Fprintf(t, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\n", name, s.Records, s.Inserted, s.Realized, s.Fees, s.Realized-s.Fees)
+		}
+	}
+	return t.Flush()
+}
+
+// platformCosts builds the cost model of the configured platforms, as the
+// engine does
+func platformCosts(cfg *config.Config) map[string]costs.Platform {
+	models := make(map[string]costs.Platform, len(cfg.Platforms))
+	for name, p := range cfg.Platforms {
+		models[name] = costs.Platform{
+			MakerFee: p.Costs.MakerFee,
+			TakerFee: p.Costs.TakerFee,
+			Slippage: p.Costs.Slippage,
+			Impact:   p.Costs.Impact,
+		}
+	}
+	return models
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Event kinds the generator produces, with the stream each is published to
+var kindStreams = map[string]string{
+	"fill":          "fill_events",
+	"trade":         "trade_events",
+	"market_update": "market_events",
+	"orderbook":     orderbook.Stream,
+}
+
+// flowOptions shapes the generated flow
+type flowOptions struct {
+	Platform   string
+	Markets    int
+	Accounts   []string
+	Mix        map[string]float64 // kind -> weight
+	Skew       float64            // Zipf exponent over markets; <= 1 picks them uniformly
+	Volatility float64            // standard deviation of a price move per market_update
+	Spread     float64            // order book spread around the YES price
+	Shares     float64            // mean order size, exponentially distributed
+}
+
+// market is the simulated state of one market
+type market struct {
+	id    string
+	price float64 // YES price
+}
+
+// flow generates synthetic events. It is not safe for concurrent use.
+type flow struct {
+	opts    flowOptions
+	rng     *rand.Rand
+	zipf    *rand.Zipf
+	markets []*market
+	kinds   []string
+	weights []float64 // cumulative, parallel to kinds
+	seq     int64
+}
+
+func newFlow(opts flowOptions, seed int64) *flow {
+	f := &flow{
+		opts: opts,
+		rng:  rand.New(rand.NewSource(seed)),
+	}
+
+	for i := 0; i < opts.Markets; i++ {
+		f.markets = append(f.markets, &market{
+			id:    fmt.Sprintf("flowgen-m%d", i+1),
+			price: 0.2 + 0.6*f.rng.Float64(),
+		})
+	}
+	if opts.Skew > 1 && opts.Markets > 1 {
+		f.zipf = rand.NewZipf(f.rng, opts.Skew, 1, uint64(opts.Markets-1))
+	}
+
+	for kind := range opts.Mix {
+		f.kinds = append(f.kinds, kind)
+	}
+	sort.Strings(f.kinds)
+	var total float64
+	for _, kind := range f.kinds {
+		total += opts.Mix[kind]
+		f.weights = append(f.weights, total)
+	}
+
+	return f
+}
+
+// next returns the next event and the stream it belongs on
+func (f *flow) next(now time.Time) (string, types.Event) {
+	kind := f.pickKind()
+	m := f.pickMarket()
+	f.seq++
+
+	var eventType string
+	var data map[string]interface{}
+	switch kind {
+	case "market_update":
+		f.move(m)
+		eventType = "market_update"
+		data = map[string]interface{}{
+			"market_id": m.id,
+			"yes_price": round(m.price),
+		}
+	case "orderbook":
+		bid, ask := f.quote(m)
+		eventType = "orderbook_snapshot"
+		data = map[string]interface{}{
+			"market_id": m.id,
+			"bids":      []interface{}{[]interface{}{bid, f.size()}},
+			"asks":      []interface{}{[]interface{}{ask, f.size()}},
+		}
+	case "trade":
+		eventType = "trade_executed"
+		data = f.order(m)
+		data["order_hash"] = fmt.Sprintf("flowgen-order-%d", f.seq)
+	default:
+		eventType = "fill"
+		data = f.order(m)
+		data["fill_id"] = fmt.Sprintf("flowgen-fill-%d", f.seq)
+		data["order_id"] = fmt.Sprintf("flowgen-order-%d", f.seq)
+	}
+	data["synthetic"] = true
+
+	return kindStreams[kind], types.Event{
+		ID:        fmt.Sprintf("flowgen-%d", f.seq),
+		Type:      eventType,
+		Platform:  f.opts.Platform,
+		Timestamp: now.UTC(),
+		Data:      data,
+	}
+}
+
+func (f *flow) pickKind() string {
+	x := f.rng.Float64() * f.weights[len(f.weights)-1]
+	i := sort.SearchFloat64s(f.weights, x)
+	if i == len(f.kinds) {
+		i--
+	}
+	return f.kinds[i]
+}
+
+func (f *flow) pickMarket() *market {
+	if f.zipf != nil {
+		return f.markets[f.zipf.Uint64()]
+	}
+	return f.markets[f.rng.Intn(len(f.markets))]
+}
+
+// move random-walks the YES price, kept inside the tradable range
+func (f *flow) move(m *market) {
+	m.price = clamp(m.price + f.rng.NormFloat64()*f.opts.Volatility)
+}
+
+// quote returns the YES bid and ask around the current price
+func (f *flow) quote(m *market) (bid, ask float64) {
+	half := f.opts.Spread / 2
+	return round(clamp(m.price - half)), round(clamp(m.price + half))
+}
+
+// order returns the fields shared by fills and trades: a random account
+// buying a random outcome at about the price it would cost
+func (f *flow) order(m *market) map[string]interface{} {
+	side := "yes"
+	price := m.price + f.opts.Spread/2
+	if f.rng.Intn(2) == 1 {
+		side = "no"
+		price = 1 - m.price + f.opts.Spread/2
+	}
+
+	return map[string]interface{}{
+		"account_id": f.opts.Accounts[f.rng.Intn(len(f.opts.Accounts))],
+		"market_id":  m.id,
+		"side":       side,
+		"price":      round(clamp(price)),
+		"shares":     f.size(),
+		"platform":   f.opts.Platform,
+	}
+}
+
+// size returns an order or book level size in whole shares, at least one
+func (f *flow) size() float64 {
+	return math.Max(1, math.Round(f.rng.ExpFloat64()*f.opts.Shares))
+}
+
+// interval returns the wait before the next event for a Poisson arrival
+// process at rate events per second
+func (f *flow) interval(rate float64) time.Duration {
+	return time.Duration(f.rng.ExpFloat64() / rate * float64(time.Second))
+}
+
+func clamp(price float64) float64 {
+	return math.Min(0.99, math.Max(0.01, price))
+}
+
+// round keeps prices at the venues' tick of 0.001
+func round(price float64) float64 {
+	return math.Round(price*1000) / 1000
+}
+
+// parseMix parses "fill=1,trade=1,market_update=2,orderbook=2"
+func parseMix(s string) (map[string]float64, error) {
+	mix := make(map[string]float64)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kind, raw, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not kind=weight", part)
+		}
+		if _, known := kindStreams[kind]; !known {
+			return nil, fmt.Errorf("unknown event kind %q", kind)
+		}
+		weight, err := strconv.ParseFloat(raw, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight of %s must be a non-negative number", kind)
+		}
+		if weight > 0 {
+			mix[kind] = weight
+		}
+	}
+	if len(mix) == 0 {
+		return nil, fmt.Errorf("no event kind has a positive weight")
+	}
+	return mix, nil
+}
@@ -0,0 +1,260 @@
+// Command flowgen publishes synthetic fill, trade, market_update and order book
+// events into the Redis streams the engine consumes, for soak-testing the
+// engine and strategies before they trade real money.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/config"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const usage = `Usage: flowgen [flags]
+
+Publishes synthetic events at --rate events per second (Poisson arrivals) until
+--duration or --count is reached or it is interrupted. --mix weighs the event
+kinds: fill, trade, market_update and orderbook. Markets are named
+flowgen-m1..N and are picked with a Zipf distribution (--skew > 1) or
+uniformly; their YES prices random-walk with every market_update. Every event
+carries "synthetic": true.
+
+Strategies whose accounts appear in --accounts react to the fills, so flowgen
+refuses to run unless the config has dry_run enabled or --allow-live is given.
+
+`
+
+const statsInterval = 10 * time.Second
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "flowgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("flowgen", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	configPath := fs.String("config", os.Getenv("STRATEGY_CONFIG"), "path to YAML config file (env vars override it)")
+	rate := fs.Float64("rate", 50, "events per second")
+	duration := fs.Duration("duration", 0, "stop after this long (0: until interrupted)")
+	count := fs.Int64("count", 0, "stop after this many events (0: no limit)")
+	mixSpec := fs.String("mix", "fill=1,trade=1,market_update=3,orderbook=3", "relative weights of the event kinds")
+	platform := fs.String("platform", "predict", "platform of the events")
+	numMarkets := fs.Int("markets", 20, "number of synthetic markets")
+	accountList := fs.String("accounts", "flowgen-1,flowgen-2", "comma-separated account IDs fills and trades belong to")
+	skew := fs.Float64("skew", 1.2, "Zipf exponent of market activity; <= 1 spreads it evenly")
+	volatility := fs.Float64("volatility", 0.01, "standard deviation of a YES price move per market_update")
+	spread := fs.Float64("spread", 0.02, "order book spread")
+	shares := fs.Float64("shares", 25, "mean order size in shares")
+	seed := fs.Int64("seed", 0, "random seed (0: time-based)")
+	stdout := fs.Bool("stdout", false, "print events as JSON lines instead of publishing them")
+	allowLive := fs.Bool("allow-live", false, "publish even though the config does not have dry_run enabled")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	mix, err := parseMix(*mixSpec)
+	if err != nil {
+		return fmt.Errorf("--mix: %w", err)
+	}
+	accounts := splitList(*accountList)
+	switch {
+	case *rate <= 0:
+		return errors.New("--rate must be positive")
+	case *numMarkets < 1:
+		return errors.New("--markets must be at least 1")
+	case len(accounts) == 0:
+		return errors.New("--accounts must name at least one account")
+	case *volatility < 0 || *spread < 0 || *shares <= 0:
+		return errors.New("--volatility and --spread must not be negative, --shares must be positive")
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: secrets.NewRedactingWriter(os.Stderr)}).
+		With().Timestamp().Logger()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if *duration > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeout(ctx, *duration)
+		defer stop()
+	}
+
+	publish := printEvent
+	if !*stdout {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		if !cfg.DryRun && !*allowLive {
+			return errors.New("the config does not have dry_run enabled; pass --allow-live to publish anyway")
+		}
+
+		bus, err := eventbus.NewRedisEventBus(eventBusOptions(cfg))
+		if err != nil {
+			return err
+		}
+		defer bus.Close()
+		publish = bus.Publish
+	}
+
+	f := newFlow(flowOptions{
+		Platform:   *platform,
+		Markets:    *numMarkets,
+		Accounts:   accounts,
+		Mix:        mix,
+		Skew:       *skew,
+		Volatility: *volatility,
+		Spread:     *spread,
+		Shares:     *shares,
+	}, *seed)
+
+	log.Info().
+		Float64("rate", *rate).
+		Int("markets", *numMarkets).
+		Strs("accounts", accounts).
+		Int64("seed", *seed).
+		Msg("Generating synthetic flow")
+
+	s := newStats()
+	next := time.Now()
+	lastReport := next
+	for *count == 0 || s.total() < *count {
+		next = next.Add(f.interval(*rate))
+		if wait := time.Until(next); wait > 0 {
+			select {
+			case <-ctx.Done():
+				s.report("Flow stopped")
+				return nil
+			case <-time.After(wait):
+			}
+		} else if ctx.Err() != nil {
+			break
+		}
+
+		stream, event := f.next(time.Now())
+		if err := publish(ctx, stream, event); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			s.failed++
+			log.Warn().Err(err).Str("stream", stream).Msg("Failed to publish event")
+		} else {
+			s.published[event.Type]++
+		}
+
+		if time.Since(lastReport) >= statsInterval {
+			s.report("Flow progress")
+			lastReport = time.Now()
+		}
+	}
+
+	s.report("Flow stopped")
+	return nil
+}
+
+// printEvent writes an event as one JSON line to stdout
+func printEvent(_ context.Context, stream string, event types.Event) error {
+	return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+		"stream": stream,
+		"event":  event,
+	})
+}
+
+// stats counts what was published
+type stats struct {
+	started   time.Time
+	published map[string]int64 // by event type
+	failed    int64
+}
+
+func newStats() *stats {
+	return &stats{started: time.Now(), published: make(map[string]int64)}
+}
+
+func (s *stats) total() int64 {
+	var n int64
+	for _, published := range s.published {
+		n += published
+	}
+	return n
+}
+
+func (s *stats) report(msg string) {
+	elapsed := time.Since(s.started)
+
+	eventTypes := make([]string, 0, len(s.published))
+	for t := range s.published {
+		eventTypes = append(eventTypes, t)
+	}
+	sort.Strings(eventTypes)
+	counts := zerolog.Dict()
+	for _, t := range eventTypes {
+		counts.Int64(t, s.published[t])
+	}
+
+	log.Info().
+		Dur("elapsed", elapsed.Round(time.Second)).
+		Int64("published", s.total()).
+		Int64("failed", s.failed).
+		Float64("rate", float64(s.total())/elapsed.Seconds()).
+		Dict("types", counts).
+		Msg(msg)
+}
+
+// eventBusOptions builds the Redis options the same way the engine does,
+// stream caps included
+func eventBusOptions(cfg *config.Config) eventbus.Options {
+	trim := make(map[string]eventbus.TrimPolicy, len(cfg.StreamTrim))
+	for stream, t := range cfg.StreamTrim {
+		trim[stream] = eventbus.TrimPolicy{MaxLen: int64(t.MaxLen), MaxAge: t.MaxAge}
+	}
+	return eventbus.Options{
+		Addrs:                 cfg.RedisAddresses(),
+		MasterName:            cfg.RedisMasterName,
+		Cluster:               cfg.RedisCluster,
+		Username:              cfg.RedisUsername,
+		Password:              cfg.RedisPassword,
+		SentinelPassword:      cfg.RedisSentinelPassword,
+		DB:                    cfg.RedisDB,
+		TLS:                   cfg.RedisTLS,
+		TLSServerName:         cfg.RedisTLSServerName,
+		TLSInsecureSkipVerify: cfg.RedisTLSInsecureSkipVerify,
+		MaxLen:                int64(cfg.StreamMaxLen),
+		Trim:                  trim,
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/config"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+const compareUsage = `Usage: strategy-engine compare --candidate NAME [flags]
+
+Compares the decisions a candidate engine running with compare_mode recorded
+with those of the live engine (record_decisions) over the same events, and
+prints the events on which a strategy decided differently. Commands are
+compared on what they would trade; metadata is ignored. Tick events are local
+to each instance and are counted but not compared.
+--since and --until take an RFC3339 time or a duration before now (e.g. 2h).
+
+`
+
+// compareSlack widens the window decisions are loaded from, since an instance
+// may record its decision on an event a while after the event
+const compareSlack = 5 * time.Minute
+
+// Results of comparing the decisions of one strategy on one event
+const (
+	compareMatch         = "match"
+	compareDiffer        = "differ"
+	compareOnlyLive      = "only_live"
+	compareOnlyCandidate = "only_candidate"
+)
+
+// decisionPair is what both instances decided for one strategy on one event
+type decisionPair struct {
+	Strategy  string           `json:"strategy"`
+	EventID   string           `json:"event_id"`
+	EventType string           `json:"event_type"`
+	Result    string           `json:"result"`
+	Live      []types.Decision `json:"live,omitempty"`
+	Candidate []types.Decision `json:"candidate,omitempty"`
+}
+
+// runCompare implements the compare subcommand
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), compareUsage)
+		fs.PrintDefaults()
+	}
+
+	configPath := fs.String("config", os.Getenv("STRATEGY_CONFIG"), "path to YAML config file (env vars override it)")
+	live := fs.String("live", "live", "instance_name of the live engine")
+	candidate := fs.String("candidate", "", "instance_name of the candidate engine")
+	since := fs.String("since", "1h", "first event: RFC3339 time or duration before now")
+	until := fs.String("until", "", "end of the events: RFC3339 time or duration before now (default: now)")
+	strategyName := fs.String("strategy", "", "compare only this strategy")
+	all := fs.Bool("all", false, "also print matching decisions")
+	asJSON := fs.Bool("json", false, "print one JSON object per strategy and event")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *candidate == "" {
+		fs.Usage()
+		return errors.New("--candidate is required")
+	}
+	if *candidate == *live {
+		return errors.New("--candidate and --live must name different instances")
+	}
+
+	now := time.Now()
+	start, err := compareTime(*since, now)
+	if err != nil {
+		return fmt.Errorf("--since: %w", err)
+	}
+	end := now
+	if *until != "" {
+		if end, err = compareTime(*until, now); err != nil {
+			return fmt.Errorf("--until: %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return errors.New("--since must be before --until")
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	store, err := storage.NewPostgres(ctx, storage.Options{
+		URL:      cfg.PostgresURL,
+		MaxConns: 1,
+		Password: cfg.PostgresPasswordFunc(),
+	})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	liveDecisions, err := store.GetDecisions(ctx, *live, start.Add(-compareSlack), end.Add(compareSlack))
+	if err != nil {
+		return fmt.Errorf("failed to load decisions of %s: %w", *live, err)
+	}
+	candidateDecisions, err := store.GetDecisions(ctx, *candidate, start.Add(-compareSlack), end.Add(compareSlack))
+	if err != nil {
+		return fmt.Errorf("failed to load decisions of %s: %w", *candidate, err)
+	}
+	if len(candidateDecisions) == 0 {
+		return fmt.Errorf("no decisions recorded by %s in the window, is it running with compare_mode?", *candidate)
+	}
+
+	pairs, ticks := pairDecisions(liveDecisions, candidateDecisions, *strategyName, start, end)
+
+	counts := make(map[string]int)
+	for _, pair := range pairs {
+		counts[pair.Result]++
+		if !*all && pair.Result == compareMatch {
+			continue
+		}
+		if err := printDecisionPair(os.Stdout, pair, *asJSON); err != nil {
+			return err
+		}
+	}
+
+	if !*asJSON {
+		fmt.Printf("\n%d strategy decisions compared between %s and %s: %d match, %d differ, %d only %s, %d only %s (%d tick decisions not compared)\n",
+			len(pairs), *live, *candidate,
+			counts[compareMatch], counts[compareDiffer],
+			counts[compareOnlyLive], *live, counts[compareOnlyCandidate], *candidate,
+			ticks)
+	}
+	return nil
+}
+
+// compareTime parses an RFC3339 time or a duration before now
+func compareTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither an RFC3339 time nor a duration", value)
+}
+
+// pairDecisions groups the decisions of both instances by strategy and event
+// and compares them. Only events from the streams whose stream ID falls in
+// [start, end) are paired; decisions on other events, such as ticks, are
+// counted in skipped.
+func pairDecisions(live, candidate []types.Decision, strategy string, start, end time.Time) (pairs []decisionPair, skipped int) {
+	type pairKey struct{ strategy, eventID string }
+	byKey := make(map[pairKey]*decisionPair)
+	var keys []pairKey
+
+	add := func(d types.Decision, isLive bool) {
+		if strategy != "" && d.Strategy != strategy {
+			return
+		}
+		ms, _, _, ok := eventbus.ParseStreamID(d.EventID)
+		if !ok {
+			skipped++
+			return
+		}
+		if at := time.UnixMilli(ms); at.Before(start) || !at.Before(end) {
+			return
+		}
+
+		key := pairKey{d.Strategy, d.EventID}
+		pair, ok := byKey[key]
+		if !ok {
+			pair = &decisionPair{Strategy: d.Strategy, EventID: d.EventID, EventType: d.EventType}
+			byKey[key] = pair
+			keys = append(keys, key)
+		}
+		if isLive {
+			pair.Live = append(pair.Live, d)
+		} else {
+			pair.Candidate = append(pair.Candidate, d)
+		}
+	}
+	for _, d := range live {
+		add(d, true)
+	}
+	for _, d := range candidate {
+		add(d, false)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].eventID != keys[j].eventID {
+			return streamIDLess(keys[i].eventID, keys[j].eventID)
+		}
+		return keys[i].strategy < keys[j].strategy
+	})
+
+	pairs = make([]decisionPair, 0, len(keys))
+	for _, key := range keys {
+		pair := byKey[key]
+		switch {
+		case len(pair.Candidate) == 0:
+			pair.Result = compareOnlyLive
+		case len(pair.Live) == 0:
+			pair.Result = compareOnlyCandidate
+		case equalStrings(decisionSummary(pair.Live), decisionSummary(pair.Candidate)):
+			pair.Result = compareMatch
+		default:
+			pair.Result = compareDiffer
+		}
+		pairs = append(pairs, *pair)
+	}
+	return pairs, skipped
+}
+
+// decisionSummary describes each command and error of decisions in a form
+// compared between instances, sorted so the order of commands does not matter
+func decisionSummary(decisions []types.Decision) []string {
+	var lines []string
+	for _, d := range decisions {
+		if d.ErrorClass != "" && len(d.Commands) == 0 {
+			lines = append(lines, "error "+d.ErrorClass)
+		}
+		for _, cmd := range d.Commands {
+			line := commandSummary(cmd)
+			if d.ErrorClass != "" {
+				line = "error " + d.ErrorClass + ": " + line
+			}
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// commandSummary describes what a command would trade
+func commandSummary(cmd types.Command) string {
+	fields := []string{cmd.Type, cmd.Platform + "/" + cmd.AccountID, cmd.MarketID}
+	if cmd.PlacesOrder() {
+		action := cmd.Action
+		if action == "" {
+			action = "buy"
+		}
+		fields = append(fields,
+			action, cmd.Side,
+			fmt.Sprintf("%.4f @ %.4f", cmd.Shares, cmd.Price),
+			cmd.OrderType, cmd.TimeInForce,
+		)
+	}
+	return strings.Join(strings.Fields(strings.Join(fields, " ")), " ")
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func printDecisionPair(w io.Writer, pair decisionPair, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(pair)
+	}
+
+	fmt.Fprintf(w, "%s  %s  %s  %s\n", pair.EventID, pair.EventType, pair.Strategy, pair.Result)
+	for _, side := range []struct {
+		name      string
+		decisions []types.Decision
+	}{{"live", pair.Live}, {"candidate", pair.Candidate}} {
+		summary := decisionSummary(side.decisions)
+		if len(summary) == 0 {
+			fmt.Fprintf(w, "    %-10s -\n", side.name)
+		}
+		for _, line := range summary {
+			fmt.Fprintf(w, "    %-10s %s\n", side.name, line)
+		}
+	}
+	return nil
+}
@@ -2,54 +2,172 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/api"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/archive"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/chaos"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/clob"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/config"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/costs"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/incident"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/logbuf"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/reports"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategies"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/units"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/wirelog"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 func main() {
-	// Setup logger
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "replay:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		if err := runCompare(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "compare:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configPath := flag.String("config", os.Getenv("STRATEGY_CONFIG"), "path to YAML config file (env vars override it)")
+	flag.Parse()
+
+	// Setup logger; recent entries are also kept in memory for incident bundles.
+	// Known credentials are masked before anything reaches stdout or the ring
 	zerolog.TimeFieldFormat = time.RFC3339
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
+	logRing := logbuf.NewRing(5000)
+	logs := setupLogger("console", logRing)
 
 	log.Info().Msg("Starting Strategy Engine...")
 
 	// Load config
-	cfg := config.Load()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load config")
+	}
+	if cfg.LogFormat != "console" {
+		logs = setupLogger(cfg.LogFormat, logRing)
+	}
+	logs.SetLevels(cfg.LogLevel, cfg.StrategyLogLevels)
 
 	// Setup storage
-	store, err := storage.NewPostgres(cfg.PostgresURL)
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	store, err := storage.NewPostgres(connectCtx, storage.Options{
+		URL:              cfg.PostgresURL,
+		MaxConns:         int32(cfg.PostgresMaxConns),
+		MinConns:         int32(cfg.PostgresMinConns),
+		MaxConnLifetime:  cfg.PostgresMaxConnLifetime,
+		StatementTimeout: cfg.PostgresStatementTimeout,
+		Password:         cfg.PostgresPasswordFunc(),
+	})
+	connectCancel()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
 	defer store.Close()
 
 	// Setup event bus
-	bus, err := eventbus.NewRedisEventBus(cfg.RedisHost, cfg.RedisPort)
+	bus, err := eventbus.NewRedisEventBus(eventBusOptions(cfg))
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to Redis")
 	}
 	defer bus.Close()
 
+	// Wire logging sits next to the network, below injected faults
+	platforms := executorPlatforms(cfg)
+	wire := wireLogRecorder(cfg, store)
+	if wire != nil {
+		for name, p := range platforms {
+			p.WrapTransport = wire.Transport(name, p.AuthHeader)
+			platforms[name] = p
+		}
+	}
+
+	// Fault injection, on test deployments only
+	var engineBus eventbus.EventBus = bus
+	if faults := chaosInjector(cfg); faults != nil {
+		engineBus = faults.Bus(bus)
+		for name, p := range platforms {
+			if recorded := p.WrapTransport; recorded != nil {
+				p.WrapTransport = func(next http.RoundTripper) http.RoundTripper {
+					return faults.Transport(recorded(next))
+				}
+			} else {
+				p.WrapTransport = faults.Transport
+			}
+			platforms[name] = p
+		}
+	}
+
 	// Setup executor
-	exec := executor.NewExecutor(
-		cfg.PredictAccountURL,
-		cfg.PolymarketAccountURL,
-		cfg.DryRun,
-	)
+	exec, err := executor.NewExecutor(platforms, cfg.DryRun)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up executor")
+	}
+	exec.SetJournal(store)
 
 	// Create engine
-	eng := engine.NewEngine(store, bus, exec)
+	opts := engine.Options{
+		DedupTTL:            cfg.DedupTTL,
+		RecoverOrders:       cfg.RecoverOrders,
+		ReconcileInterval:   cfg.ReconcileInterval,
+		CancelOrphans:       cfg.ReconcileCancelOrphans,
+		DriftInterval:       cfg.PositionDriftInterval,
+		DriftTolerance:      cfg.PositionDriftTolerance,
+		BookPollInterval:    cfg.OrderBookPollInterval,
+		AutoDisable:         autoDisablePolicy(cfg),
+		Shard:               engine.Shard{Index: cfg.ShardIndex, Count: cfg.ShardCount},
+		Outbox:              cfg.Outbox,
+		MaxHandlerPanics:    cfg.MaxHandlerPanics,
+		RejectBackoffAfter:  cfg.RejectBackoffAfter,
+		RejectBackoff:       cfg.RejectBackoff,
+		RejectBackoffMax:    cfg.RejectBackoffMax,
+		HandlerTimeout:      cfg.HandlerTimeout,
+		Groups:              groupBudgets(cfg),
+		Tenants:             tenants(cfg),
+		OrderTTL:            cfg.OrderTTL,
+		Warmup:              cfg.StrategyWarmup,
+		MaxStreamLag:        cfg.MaxStreamLag,
+		MaxQueueBacklog:     cfg.MaxQueueBacklog,
+		ReplayGaps:          cfg.ReplayEventGaps,
+		StateFlushInterval:  cfg.StateFlushInterval,
+		MaxPriceDeviation:   cfg.MaxPriceDeviation,
+		MaxPriceMove:        cfg.MaxPriceMove,
+		PriceMoveWindow:     cfg.PriceMoveWindow,
+		PriceMoveCooldown:   cfg.PriceMoveCooldown,
+		SelfTradePrevention: cfg.SelfTradePrevention,
+		Costs:               platformCosts(cfg),
+		ConfigKeys:          configKeyring(cfg),
+		Instance:            cfg.InstanceName,
+		RecordDecisions:     cfg.RecordDecisions,
+		Compare:             cfg.CompareMode,
+		Trace:               cfg.Trace,
+		Middlewares:         cfg.CommandMiddlewares,
+
+		RequireRegisteredAccounts: cfg.RequireRegisteredAccounts,
+		Environment:               cfg.Environment,
+	}
+	eng := engine.NewEngine(store, engineBus, exec, opts)
 
 	// Register strategies
 	strategies.RegisterAll(eng)
@@ -64,14 +182,350 @@ func main() {
 		}
 	}()
 
+	// Start execution quality reporting
+	if cfg.ExecutionReportInterval > 0 && !cfg.CompareMode {
+		reporter := reports.NewExecutionQualityReporter(
+			store,
+			bus,
+			cfg.ExecutionReportInterval,
+			cfg.ExecutionReportWindow,
+		)
+		go reporter.Run(ctx)
+	}
+
+	// Start daily reporting
+	if cfg.DailyReport && !cfg.CompareMode {
+		go reports.NewDailyReporter(eng, bus, cfg.DailyReportAt, reportNotifiers(cfg)...).Run(ctx)
+	}
+
+	// Start writing the wire log
+	if wire != nil {
+		go wire.Run(ctx)
+	}
+
+	// Start event archiving
+	if cfg.ArchiveInterval > 0 && !cfg.CompareMode {
+		archiver := archive.NewArchiver(store, bus, archive.Options{
+			Streams:         eng.Streams(),
+			Interval:        cfg.ArchiveInterval,
+			Retention:       cfg.ArchiveRetention,
+			StreamRetention: cfg.ArchiveStreamRetention,
+			CompactAfter:    cfg.ArchiveCompactAfter,
+		})
+		go archiver.Run(ctx)
+	}
+
+	// Start trimming the engine's own streams
+	if cfg.StreamTrimInterval > 0 && !cfg.CompareMode {
+		go eventbus.RunTrimmer(ctx, bus, ownedStreamPolicies(cfg), cfg.StreamTrimInterval)
+	}
+
+	// Start admin API. A candidate in compare mode runs none of the above
+	// and no API, so it cannot change anything next to the live engine.
+	reload := newReloader(*configPath, cfg, eng, exec, logs)
+	var server *api.Server
+	if !cfg.CompareMode {
+		incidents := incident.NewManager(eng, bus, store, logRing, cfg.IncidentDir)
+		server = api.NewServer(cfg.HTTPAddr, eng, incidents)
+		server.SetReloader(reload)
+		server.SetAccess(apiAccess(cfg))
+		server.Start()
+	}
+
 	log.Info().Msg("Strategy Engine started")
 
-	// Wait for interrupt
+	// Reload config on SIGHUP, stop on interrupt
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		log.Info().Msg("SIGHUP received, reloading config")
+		if _, err := reload.Reload(); err != nil {
+			log.Error().Err(err).Msg("Config reload failed, keeping current settings")
+		}
+	}
 
 	log.Info().Msg("Shutting down...")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	if server != nil {
+		server.Shutdown(shutdownCtx)
+	}
+	shutdownCancel()
 	cancel()
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := eng.Flush(flushCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush strategy state")
+	}
+	flushCancel()
 	time.Sleep(2 * time.Second)
 }
+
+// configKeyring builds the keyring of encrypted strategy config values, or nil
+// if no keys are configured
+func configKeyring(cfg *config.Config) *secrets.Keyring {
+	if len(cfg.ConfigKeys) == 0 {
+		return nil
+	}
+	keys := make(map[string]string, len(cfg.ConfigKeys))
+	for id, k := range cfg.ConfigKeys {
+		keys[id] = k.Key
+	}
+	keyring, err := secrets.NewKeyring(keys, cfg.ConfigKeyID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid config_keys")
+	}
+	log.Info().Strs("keys", keyring.KeyIDs()).Str("active", cfg.ConfigKeyID).Msg("Config encryption enabled")
+	return keyring
+}
+
+// eventBusOptions maps the Redis settings onto the event bus
+func eventBusOptions(cfg *config.Config) eventbus.Options {
+	return eventbus.Options{
+		Addrs:                 cfg.RedisAddresses(),
+		MasterName:            cfg.RedisMasterName,
+		Cluster:               cfg.RedisCluster,
+		Username:              cfg.RedisUsername,
+		Password:              cfg.RedisPassword,
+		SentinelPassword:      cfg.RedisSentinelPassword,
+		DB:                    cfg.RedisDB,
+		TLS:                   cfg.RedisTLS,
+		TLSServerName:         cfg.RedisTLSServerName,
+		TLSInsecureSkipVerify: cfg.RedisTLSInsecureSkipVerify,
+		MaxLen:                int64(cfg.StreamMaxLen),
+		Trim:                  streamTrimPolicies(cfg),
+		Producer:              eventProducer(cfg),
+		Consumer:              eventConsumer(cfg),
+		CatchUp:               catchUpPolicy(cfg.CatchUp),
+		CatchUpStreams:        catchUpStreamPolicies(cfg),
+	}
+}
+
+// eventProducer is the name the engine numbers its published entries under,
+// empty unless sequence_events is set
+func eventProducer(cfg *config.Config) string {
+	if !cfg.SequenceEvents {
+		return ""
+	}
+	return "strategy-engine/" + cfg.InstanceName
+}
+
+// eventConsumer is the name the engine saves its stream offsets under, one
+// per instance and shard
+func eventConsumer(cfg *config.Config) string {
+	name := "strategy-engine/" + cfg.InstanceName
+	if cfg.ShardCount > 1 {
+		name += fmt.Sprintf("/shard-%d", cfg.ShardIndex)
+	}
+	return name
+}
+
+func catchUpPolicy(c config.CatchUpConfig) eventbus.CatchUpPolicy {
+	return eventbus.CatchUpPolicy{Mode: c.Mode, MaxAge: c.MaxAge}
+}
+
+// catchUpStreamPolicies maps the per-stream catch-up settings onto the event bus
+func catchUpStreamPolicies(cfg *config.Config) map[string]eventbus.CatchUpPolicy {
+	policies := make(map[string]eventbus.CatchUpPolicy, len(cfg.CatchUpStreams))
+	for stream, c := range cfg.CatchUpStreams {
+		policies[stream] = catchUpPolicy(*c)
+	}
+	return policies
+}
+
+// streamTrimPolicies maps the per-stream trim settings onto the event bus
+func streamTrimPolicies(cfg *config.Config) map[string]eventbus.TrimPolicy {
+	policies := make(map[string]eventbus.TrimPolicy, len(cfg.StreamTrim))
+	for stream, t := range cfg.StreamTrim {
+		policies[stream] = eventbus.TrimPolicy{MaxLen: int64(t.MaxLen), MaxAge: t.MaxAge}
+	}
+	return policies
+}
+
+// ownedStreamPolicies returns the trim policies of the streams only the
+// engine publishes to, which the engine trims in the background
+func ownedStreamPolicies(cfg *config.Config) map[string]eventbus.TrimPolicy {
+	configured := streamTrimPolicies(cfg)
+	owned := []string{engine.CommandResultStream, engine.ErrorStream, reports.ReportStream, engine.TraceStream}
+
+	policies := make(map[string]eventbus.TrimPolicy, len(owned))
+	for _, stream := range owned {
+		policy := configured[stream]
+		if policy.MaxLen == 0 {
+			policy.MaxLen = int64(cfg.StreamMaxLen)
+		}
+		policies[stream] = policy
+	}
+	return policies
+}
+
+// reportNotifiers returns where daily reports are sent
+func reportNotifiers(cfg *config.Config) []reports.Notifier {
+	var notifiers []reports.Notifier
+	if cfg.DailyReportSlackWebhook != "" {
+		notifiers = append(notifiers, reports.NewSlackNotifier(cfg.DailyReportSlackWebhook))
+	}
+	if e := cfg.DailyReportEmail; e != nil {
+		notifiers = append(notifiers, reports.NewEmailNotifier(e.SMTPAddr, e.Username, e.Password, e.From, e.To))
+	}
+	return notifiers
+}
+
+// executorPlatforms converts the configured platforms for the executor
+func executorPlatforms(cfg *config.Config) map[string]executor.Platform {
+	platforms := make(map[string]executor.Platform, len(cfg.Platforms))
+	for name, p := range cfg.Platforms {
+		platforms[name] = executor.Platform{
+			URL:              p.URL,
+			AuthHeader:       p.AuthHeader,
+			AuthToken:        p.AuthToken,
+			HMACKeyID:        p.HMACKeyID,
+			HMACSecret:       p.HMACSecret,
+			TLSCertFile:      p.TLSCertFile,
+			TLSKeyFile:       p.TLSKeyFile,
+			TLSCAFile:        p.TLSCAFile,
+			TLSServerName:    p.TLSServerName,
+			Timeout:          p.Timeout,
+			ConnectTimeout:   p.ConnectTimeout,
+			MaxIdleConns:     p.MaxIdleConns,
+			IdleConnTimeout:  p.IdleConnTimeout,
+			ProxyURL:         p.Proxy,
+			Headers:          p.Headers,
+			RateLimit:        p.RateLimit,
+			RateBurst:        p.RateBurst,
+			TimeInForce:      p.TimeInForce,
+			OrderTypes:       p.OrderTypes,
+			Sells:            p.Sells,
+			Amend:            p.Amend,
+			NegRisk:          p.NegRisk,
+			Cancels:          p.Cancels,
+			CloseAll:         p.CloseAll,
+			BatchSize:        p.BatchSize,
+			IdempotentOrders: p.IdempotentOrders,
+			CLOB:             clobOptions(p.CLOB),
+			Units:            platformUnits(p),
+		}
+	}
+	return platforms
+}
+
+// chaosInjector builds the fault injector of the chaos section, or nil when
+// it is off
+func chaosInjector(cfg *config.Config) *chaos.Injector {
+	c := cfg.Chaos
+	if c == nil || !c.Enabled {
+		return nil
+	}
+	log.Warn().
+		Float64("drop_events", c.DropEvents).
+		Float64("delay_requests", c.DelayRequests).
+		Dur("max_delay", c.MaxDelay).
+		Float64("service_errors", c.ServiceErrors).
+		Bool("errors_after_send", c.ErrorsAfterSend).
+		Bool("non_production", c.NonProduction).
+		Msg("Chaos fault injection enabled, do not run this against real money")
+	return chaos.New(chaos.Config{
+		DropEvents:      c.DropEvents,
+		DelayRequests:   c.DelayRequests,
+		MaxDelay:        c.MaxDelay,
+		ServiceErrors:   c.ServiceErrors,
+		ErrorsAfterSend: c.ErrorsAfterSend,
+	})
+}
+
+// wireLogRecorder builds the recorder of the wire_log section, or nil when it
+// is off. A candidate in compare mode records nothing.
+func wireLogRecorder(cfg *config.Config, store *storage.PostgresStorage) *wirelog.Recorder {
+	w := cfg.WireLog
+	if w == nil || !w.Enabled || cfg.CompareMode {
+		return nil
+	}
+	log.Info().
+		Float64("sample_rate", w.SampleRate).
+		Dur("retention", w.Retention).
+		Msg("Recording account service requests in the wire log")
+	return wirelog.New(store, wirelog.Options{
+		SampleRate:   w.SampleRate,
+		Retention:    w.Retention,
+		MaxBodyBytes: w.MaxBodyBytes,
+	})
+}
+
+// apiAccess builds the admin API tokens
+func apiAccess(cfg *config.Config) api.Access {
+	access := api.Access{AdminToken: cfg.AdminToken, TenantTokens: make(map[string]string)}
+	for name, t := range cfg.Tenants {
+		if t.APIToken != "" {
+			access.TenantTokens[name] = t.APIToken
+		}
+	}
+	return access
+}
+
+// platformCosts converts the configured cost models by platform
+func platformCosts(cfg *config.Config) map[string]costs.Platform {
+	models := make(map[string]costs.Platform, len(cfg.Platforms))
+	for name, p := range cfg.Platforms {
+		models[name] = costs.Platform{
+			MakerFee: p.Costs.MakerFee,
+			TakerFee: p.Costs.TakerFee,
+			Slippage: p.Costs.Slippage,
+			Impact:   p.Costs.Impact,
+		}
+	}
+	return models
+}
+
+// platformUnits converts the share convention of a platform
+func platformUnits(p *config.PlatformConfig) units.Platform {
+	return units.Platform{ContractSize: p.ContractSize, CollateralUSD: p.CollateralUSD}
+}
+
+// sizeUnits builds the size converter of the configured platforms, for code
+// running without an executor
+func sizeUnits(cfg *config.Config) *units.Converter {
+	platforms := make(map[string]units.Platform, len(cfg.Platforms))
+	for name, p := range cfg.Platforms {
+		platforms[name] = platformUnits(p)
+	}
+	return units.NewConverter(platforms)
+}
+
+// clobOptions converts a platform's direct CLOB settings; nil when it has none
+func clobOptions(c *config.CLOBConfig) *clob.Options {
+	if c == nil {
+		return nil
+	}
+	opts := &clob.Options{
+		URL:      c.URL,
+		ChainID:  c.ChainID,
+		Timeout:  c.Timeout,
+		Accounts: make(map[string]clob.Account, len(c.Accounts)),
+	}
+	for id, a := range c.Accounts {
+		opts.Accounts[id] = clob.Account{
+			PrivateKey:    a.PrivateKey,
+			Funder:        a.Funder,
+			SignatureType: a.SignatureType,
+			APIKey:        a.APIKey,
+			APISecret:     a.APISecret,
+			APIPassphrase: a.APIPassphrase,
+		}
+	}
+	return opts
+}
+
+// setupLogger sends log entries through a level filter to stdout, in the
+// given format, and to the ring
+func setupLogger(format string, ring *logbuf.Ring) *logbuf.LevelFilter {
+	var stdout io.Writer = secrets.NewRedactingWriter(os.Stdout)
+	if format != "json" {
+		stdout = zerolog.ConsoleWriter{Out: stdout}
+	}
+
+	logs := logbuf.NewLevelFilter(zerolog.MultiLevelWriter(stdout, secrets.NewRedactingWriter(ring)))
+	log.Logger = log.Output(logs)
+	return logs
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/analytics"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/config"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/logbuf"
+	"github.com/rs/zerolog/log"
+)
+
+// reloader re-reads the config file and environment and applies the
+// reloadable settings to the running engine, without touching stream
+// subscriptions or connections.
+type reloader struct {
+	path     string
+	engine   *engine.Engine
+	executor *executor.Executor
+	logs     *logbuf.LevelFilter
+
+	mu      sync.Mutex
+	current *config.Config
+}
+
+func newReloader(path string, cfg *config.Config, eng *engine.Engine, exec *executor.Executor, logs *logbuf.LevelFilter) *reloader {
+	return &reloader{
+		path:     path,
+		engine:   eng,
+		executor: exec,
+		logs:     logs,
+		current:  cfg,
+	}
+}
+
+// Reload applies the new config. An invalid config is rejected as a whole and
+// the running settings are left untouched.
+func (r *reloader) Reload() (*config.Changes, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Load(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := r.current.Diff(next)
+
+	r.logs.SetLevels(next.LogLevel, next.StrategyLogLevels)
+	r.executor.SetDryRun(next.DryRun)
+	r.engine.SetDedupTTL(next.DedupTTL)
+	r.engine.SetAutoDisablePolicy(autoDisablePolicy(next))
+	r.engine.SetMaxPriceDeviation(next.MaxPriceDeviation)
+	r.engine.SetSelfTradePrevention(next.SelfTradePrevention)
+	r.engine.SetHandlerTimeout(next.HandlerTimeout)
+	r.engine.SetGroups(groupBudgets(next))
+	r.engine.SetOrderTTL(next.OrderTTL)
+	r.engine.SetLagThresholds(next.MaxStreamLag, next.MaxQueueBacklog)
+
+	for _, key := range changes.Applied {
+		log.Info().Str("setting", key).Msg("Config setting reloaded")
+	}
+	if len(changes.RestartRequired) > 0 {
+		log.Warn().Strs("settings", changes.RestartRequired).Msg("Config changes require a restart to take effect")
+	}
+
+	// Keep startup-only settings as they are running, so a later reload
+	// still reports them until the process is restarted
+	r.current = mergeReloadable(r.current, next)
+
+	return &changes, nil
+}
+
+// mergeReloadable returns current with the reloadable settings taken from next
+func mergeReloadable(current, next *config.Config) *config.Config {
+	merged := *current
+	merged.LogLevel = next.LogLevel
+	merged.StrategyLogLevels = next.StrategyLogLevels
+	merged.DryRun = next.DryRun
+	merged.DedupTTL = next.DedupTTL
+	merged.AutoDisable = next.AutoDisable
+	merged.AutoDisableWindow = next.AutoDisableWindow
+	merged.AutoDisableMinPnL = next.AutoDisableMinPnL
+	merged.AutoDisableMinHitRate = next.AutoDisableMinHitRate
+	merged.AutoDisableMinTrades = next.AutoDisableMinTrades
+	merged.MaxPriceDeviation = next.MaxPriceDeviation
+	merged.SelfTradePrevention = next.SelfTradePrevention
+	merged.HandlerTimeout = next.HandlerTimeout
+	merged.StrategyGroups = next.StrategyGroups
+	merged.OrderTTL = next.OrderTTL
+	merged.MaxStreamLag = next.MaxStreamLag
+	merged.MaxQueueBacklog = next.MaxQueueBacklog
+	return &merged
+}
+
+func groupBudgets(cfg *config.Config) map[string]*engine.GroupBudget {
+	budgets := make(map[string]*engine.GroupBudget, len(cfg.StrategyGroups))
+	for name, g := range cfg.StrategyGroups {
+		budgets[name] = &engine.GroupBudget{
+			MaxExposure:  g.MaxExposure,
+			MaxDailyLoss: g.MaxDailyLoss,
+		}
+	}
+	return budgets
+}
+
+func tenants(cfg *config.Config) map[string]*engine.Tenant {
+	tenants := make(map[string]*engine.Tenant, len(cfg.Tenants))
+	for name, t := range cfg.Tenants {
+		tenants[name] = &engine.Tenant{
+			Accounts:     t.Accounts,
+			MaxExposure:  t.MaxExposure,
+			MaxDailyLoss: t.MaxDailyLoss,
+		}
+	}
+	return tenants
+}
+
+func autoDisablePolicy(cfg *config.Config) *analytics.AutoDisablePolicy {
+	if !cfg.AutoDisable {
+		return nil
+	}
+	return &analytics.AutoDisablePolicy{
+		Window:     cfg.AutoDisableWindow,
+		MinPnL:     cfg.AutoDisableMinPnL,
+		MinHitRate: cfg.AutoDisableMinHitRate,
+		MinTrades:  cfg.AutoDisableMinTrades,
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/config"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/costs"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/logbuf"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/state"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategies"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategyctx"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const replayUsage = `Usage: strategy-engine replay (--strategy NAME | --strategy-file FILE) [flags]
+
+Replays events from the Redis streams, or with --source archive from the
+event archive in Postgres, through one strategy handler in isolation and
+prints the commands it would emit. Nothing is executed.
+--from and --to take an RFC3339 time or a stream ID (e.g. 1712345678901-0).
+
+`
+
+// replayStep is one replayed event and what the strategy made of it
+type replayStep struct {
+	Stream   string          `json:"stream"`
+	Event    types.Event     `json:"event"`
+	Commands []types.Command `json:"commands,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// runReplay implements the replay subcommand
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), replayUsage)
+		fs.PrintDefaults()
+	}
+
+	configPath := fs.String("config", os.Getenv("STRATEGY_CONFIG"), "path to YAML config file (env vars override it)")
+	strategyName := fs.String("strategy", "", "name of the strategy to load from Postgres")
+	strategyFile := fs.String("strategy-file", "", "JSON file with the strategy (name, type, config) instead of Postgres")
+	source := fs.String("source", "redis", "where to read events: redis or archive")
+	streamList := fs.String("streams", strings.Join(engine.EventStreams(), ","), "comma-separated streams to replay")
+	from := fs.String("from", "", "first event: RFC3339 time or stream ID (default: 1h ago)")
+	to := fs.String("to", "+", "last event: RFC3339 time or stream ID")
+	limit := fs.Int64("limit", 10000, "maximum events read per stream")
+	all := fs.Bool("all", false, "also print events that produced no commands")
+	asJSON := fs.Bool("json", false, "print one JSON object per event")
+	logLevel := fs.String("log-level", "warn", "log level for handler output (written to stderr)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*strategyName == "") == (*strategyFile == "") {
+		fs.Usage()
+		return errors.New("exactly one of --strategy or --strategy-file is required")
+	}
+	if *source != "redis" && *source != "archive" {
+		return fmt.Errorf("--source must be redis or archive, not %q", *source)
+	}
+
+	logs := logbuf.NewLevelFilter(zerolog.ConsoleWriter{Out: secrets.NewRedactingWriter(os.Stderr)})
+	logs.SetLevels(*logLevel, nil)
+	log.Logger = log.Output(logs)
+
+	start, err := replayBound(*from, time.Now().Add(-time.Hour))
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+	end, err := replayBound(*to, time.Time{})
+	if err != nil {
+		return fmt.Errorf("--to: %w", err)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	strategy, err := loadReplayStrategy(ctx, cfg, *strategyName, *strategyFile)
+	if err != nil {
+		return err
+	}
+	if strategy.Config, err = configKeyring(cfg).DecryptConfig(strategy.Config); err != nil {
+		return fmt.Errorf("failed to decrypt strategy config: %w", err)
+	}
+
+	// Market mappings come from the database like the strategy; a strategy
+	// file replays without them
+	mappings := markets.NewMappings()
+	if *strategyFile == "" {
+		if err := loadReplayMappings(ctx, cfg, mappings); err != nil {
+			log.Warn().Err(err).Msg("Failed to load market mappings, replaying without them")
+		}
+	}
+
+	// Replays keep strategy state in memory, away from the live strategies'
+	registry := markets.NewRegistry()
+	sctx := &strategyctx.Context{
+		Markets:    registry,
+		Mappings:   mappings,
+		OrderBooks: orderbook.NewCache(),
+		Costs:      costs.NewModel(platformCosts(cfg), registry),
+		Units:      sizeUnits(cfg),
+		State:      state.NewStore(state.NewMemory()),
+	}
+	handler, ok := strategies.Handlers(sctx)[strategy.Type]
+	if !ok {
+		return fmt.Errorf("no handler for strategy type %q", strategy.Type)
+	}
+
+	var steps []replayStep
+	if *source == "archive" {
+		steps, err = readArchivedEvents(ctx, cfg, strings.Split(*streamList, ","), start, end, *limit)
+	} else {
+		steps, err = readReplayEvents(ctx, cfg, strings.Split(*streamList, ","), start, end, *limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	var withCommands, commands, failed int
+	for i := range steps {
+		step := &steps[i]
+
+		// Same context handling as the engine: book events only refresh the
+		// cache, market updates reach the registry before the handler runs
+		if sctx.OrderBooks.Update(step.Event) {
+			continue
+		}
+		sctx.Markets.Update(step.Event)
+
+		step.Commands, err = handler(step.Event, *strategy)
+		if err != nil {
+			step.Error = err.Error()
+			failed++
+		}
+		if len(step.Commands) > 0 {
+			withCommands++
+			commands += len(step.Commands)
+		}
+
+		if *all || len(step.Commands) > 0 || step.Error != "" {
+			if err := printReplayStep(os.Stdout, *step, *asJSON); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !*asJSON {
+		fmt.Printf("\n%d events replayed through %s (%s): %d produced %d commands, %d handler errors\n",
+			len(steps), strategy.Name, strategy.Type, withCommands, commands, failed)
+	}
+	return nil
+}
+
+func loadReplayStrategy(ctx context.Context, cfg *config.Config, name, file string) (*types.Strategy, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read strategy file: %w", err)
+		}
+		var strategy types.Strategy
+		if err := json.Unmarshal(data, &strategy); err != nil {
+			return nil, fmt.Errorf("failed to parse strategy file: %w", err)
+		}
+		if strategy.Type == "" {
+			return nil, errors.New("strategy file has no type")
+		}
+		if strategy.Name == "" {
+			strategy.Name = strategy.Type
+		}
+		return &strategy, nil
+	}
+
+	store, err := storage.NewPostgres(ctx, storage.Options{
+		URL:      cfg.PostgresURL,
+		MaxConns: 1,
+		Password: cfg.PostgresPasswordFunc(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	strategy, err := store.GetStrategyByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load strategy: %w", err)
+	}
+	if strategy == nil {
+		return nil, fmt.Errorf("strategy %q not found", name)
+	}
+	return strategy, nil
+}
+
+func loadReplayMappings(ctx context.Context, cfg *config.Config, mappings *markets.Mappings) error {
+	store, err := storage.NewPostgres(ctx, storage.Options{
+		URL:      cfg.PostgresURL,
+		MaxConns: 1,
+		Password: cfg.PostgresPasswordFunc(),
+	})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	stored, err := store.GetMarketMappings(ctx)
+	if err != nil {
+		return err
+	}
+	mappings.Set(stored)
+	return nil
+}
+
+// readReplayEvents reads every stream from Redis
+func readReplayEvents(ctx context.Context, cfg *config.Config, streams []string, start, end string, limit int64) ([]replayStep, error) {
+	bus, err := eventbus.NewRedisEventBus(eventBusOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	defer bus.Close()
+
+	return mergeReplayEvents(streams, limit, func(stream string) ([]types.Event, error) {
+		return bus.RangeIDs(ctx, stream, start, end, limit)
+	})
+}
+
+// readArchivedEvents reads every stream from the event archive
+func readArchivedEvents(ctx context.Context, cfg *config.Config, streams []string, start, end string, limit int64) ([]replayStep, error) {
+	store, err := storage.NewPostgres(ctx, storage.Options{
+		URL:      cfg.PostgresURL,
+		MaxConns: 1,
+		Password: cfg.PostgresPasswordFunc(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	from, to := archiveBound(start, false), archiveBound(end, true)
+	return mergeReplayEvents(streams, limit, func(stream string) ([]types.Event, error) {
+		return store.GetArchivedEvents(ctx, stream, from, to, limit)
+	})
+}
+
+// mergeReplayEvents reads every stream and merges the events in stream ID order
+func mergeReplayEvents(streams []string, limit int64, read func(stream string) ([]types.Event, error)) ([]replayStep, error) {
+	var steps []replayStep
+	for _, stream := range streams {
+		stream = strings.TrimSpace(stream)
+		if stream == "" {
+			continue
+		}
+		events, err := read(stream)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", stream, err)
+		}
+		if int64(len(events)) == limit {
+			log.Warn().Str("stream", stream).Int64("limit", limit).Msg("Stream range truncated, raise --limit or narrow the range")
+		}
+		for _, event := range events {
+			steps = append(steps, replayStep{Stream: stream, Event: event})
+		}
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return streamIDLess(steps[i].Event.ID, steps[j].Event.ID)
+	})
+	return steps, nil
+}
+
+// replayBound turns an RFC3339 time or a stream ID into a stream ID. An empty
+// value means fallback, or the end of the stream when fallback is zero.
+func replayBound(value string, fallback time.Time) (string, error) {
+	if value == "" {
+		if fallback.IsZero() {
+			return "+", nil
+		}
+		return eventbus.StreamID(fallback), nil
+	}
+	if value == "-" || value == "+" {
+		return value, nil
+	}
+	// A bare millisecond ID covers every entry of that millisecond, as start or end
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	}
+	if _, _, _, ok := eventbus.ParseStreamID(value); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("%q is neither an RFC3339 time nor a stream ID", value)
+}
+
+// archiveBound turns a replay bound into an archive position. A bare
+// millisecond ID covers every entry of that millisecond, as start or end.
+func archiveBound(bound string, end bool) [2]int64 {
+	switch bound {
+	case "-":
+		return [2]int64{0, 0}
+	case "+":
+		return [2]int64{math.MaxInt64, math.MaxInt64}
+	}
+	ms, seq, found, _ := eventbus.ParseStreamID(bound)
+	if !found && end {
+		seq = math.MaxInt64
+	}
+	return [2]int64{ms, seq}
+}
+
+func streamIDLess(a, b string) bool {
+	aMs, aSeq, _, _ := eventbus.ParseStreamID(a)
+	bMs, bSeq, _, _ := eventbus.ParseStreamID(b)
+	if aMs != bMs {
+		return aMs < bMs
+	}
+	return aSeq < bSeq
+}
+
+func printReplayStep(w io.Writer, step replayStep, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(step)
+	}
+
+	market, _ := step.Event.Data["market_id"].(string)
+	fmt.Fprintf(w, "%s  %-15s %-16s %-10s market=%s\n",
+		step.Event.ID, step.Stream, step.Event.Type, step.Event.Platform, market)
+
+	if step.Error != "" {
+		fmt.Fprintf(w, "    error: %s\n", step.Error)
+	}
+	for _, cmd := range step.Commands {
+		metadata, _ := json.Marshal(cmd.Metadata)
+		fmt.Fprintf(w, "    -> %s %s/%s market=%s %s %.4g x %.4g %s\n",
+			cmd.Type, cmd.Platform, cmd.AccountID, cmd.MarketID, cmd.Side, cmd.Price, cmd.Shares, metadata)
+	}
+	return nil
+}
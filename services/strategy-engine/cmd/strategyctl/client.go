@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// client calls the admin API
+type client struct {
+	baseURL  string
+	operator string
+	json     bool
+	http     *http.Client
+}
+
+func newClient(baseURL, operator string, asJSON bool, timeout time.Duration) *client {
+	return &client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		operator: operator,
+		json:     asJSON,
+		http:     &http.Client{Timeout: timeout},
+	}
+}
+
+// operatorBody is the body of audited admin actions
+func (c *client) operatorBody(reason string) map[string]string {
+	return map[string]string{"reason": reason, "operator": c.operator}
+}
+
+// get calls path with the non-empty query values and decodes the response into out
+func (c *client) get(path string, query map[string]string, out interface{}) error {
+	values := url.Values{}
+	for k, v := range query {
+		if v != "" {
+			values.Set(k, v)
+		}
+	}
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+func (c *client) post(path string, body, out interface{}) error {
+	return c.send(http.MethodPost, path, body, out)
+}
+
+// send makes an audited request with a JSON body
+func (c *client) send(method, path string, body, out interface{}) error {
+	if c.operator == "" {
+		return fmt.Errorf("no operator name: set --operator or STRATEGYCTL_OPERATOR")
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(method, path, bytes.NewReader(data), out)
+}
+
+// do sends a request and decodes the response into out; with --json the raw
+// response is printed as well
+func (c *client) do(method, path string, body io.Reader, out interface{}) error {
+	data, err := c.fetch(method, path, "application/json", body)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		os.Stdout.Write(data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// fetch sends a request with a body of the given content type and returns the
+// response body
+func (c *client) fetch(method, path, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s (HTTP %d)", apiErr.Error, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+func (c *client) printJSON(v interface{}) {
+	data, _ := json.Marshal(v)
+	fmt.Println(string(data))
+}
@@ -0,0 +1,1271 @@
+// Command strategyctl is the operator CLI for the strategy engine. It talks to
+// the engine's admin API.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/reports"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+const usage = `Usage: strategyctl [global flags] COMMAND [flags]
+
+Commands:
+  strategies                     list strategies
+  export [--out FILE]            write every strategy as YAML (stdout
+                                 without --out)
+  import FILE --reason R [--apply]
+                                 show how a YAML export differs from the
+                                 strategies (applied only with --apply)
+  enable NAME --reason R         enable a strategy
+  disable NAME --reason R        disable a strategy
+  positions (--strategy NAME | --account ID [--platform P])
+                                 show positions from the account services
+  positions resync [--strategy NAME | --account ID [--platform P]] --reason R
+                                 make the venue's positions the engine's
+                                 baseline for drift checks (all accounts
+                                 without --strategy or --account)
+  pnl [--window 24h]             per-strategy activity and realized PnL
+  report [DAY] [--generate]      show the daily report of a UTC day
+                                 (yesterday without DAY), generating it
+                                 again with --generate
+  experiment NAME [--window 24h] compare the variants of a strategy's A/B test
+  explain NAME [--limit 20] [--on | --off]
+                                 show why a strategy in explain mode acted or
+                                 not on its latest events; --on/--off switch
+                                 explain mode until the engine restarts
+  tail [--since 5m]              follow the order journal
+  flatten (--strategy NAME | --account ID [--platform P]) --reason R [--mode offset|venue] [--preview]
+                                 emergency flatten
+  halt --reason R / resume --reason R
+                                 engage or release the kill switch
+  dlq [--limit N]                list outbox commands given up on (failed, unknown outcome)
+  dlq retry [ID...] [--since 1h] --reason R
+                                 send failed commands through the checks again
+  inject TYPE --data JSON [--platform P] [--live] --reason R
+                                 show how strategies react to a crafted event
+                                 (dry run unless --live)
+  encrypt [VALUE]                encrypt a strategy config value (read from
+                                 stdin without VALUE)
+  mappings                       list Predict-Polymarket market mappings
+  mappings add PREDICT_ID POLYMARKET_ID [--inverted] [--note N]
+                                 map a Predict market to a Polymarket condition
+  mappings rm ID --reason R      delete a mapping
+  mappings suggest [--min-score 0.6] [--limit 50]
+                                 propose mappings of markets with similar
+                                 questions
+  accounts                       list registered trading accounts
+  accounts set PLATFORM ID [--name N] [--disabled] [--max-shares S]
+               [--markets M1,M2] [--note N]
+                                 register an account or replace its limits
+  accounts rm PLATFORM ID --reason R
+                                 remove an account from the registry
+  flags [NAME --market M]        list feature flags, or check one for a market
+  flags set NAME [--on] [--percent P] [--env E1,E2] [--description D]
+                                 create a feature flag or replace its settings
+  flags rm NAME --reason R       delete a feature flag, turning it off
+  conditional [--strategy NAME]  list orders held until their trigger price
+                                 and resting one-cancels-other orders
+  conditional rm ID --reason R   drop a held order, or stop watching a group
+                                 order (it stays on the book)
+  wirelog [--platform P] [--since 1h] [--grep S] [--limit 100]
+                                 list recorded account service requests
+                                 (bodies with --json)
+
+Global flags:
+`
+
+// command runs one subcommand with its arguments
+type command func(c *client, args []string) error
+
+func main() {
+	fs := flag.NewFlagSet("strategyctl", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	addr := fs.String("addr", envOr("STRATEGYCTL_ADDR", "http://localhost:8080"), "admin API base URL (env STRATEGYCTL_ADDR)")
+	operator := fs.String("operator", envOr("STRATEGYCTL_OPERATOR", currentUser()), "operator name recorded with actions (env STRATEGYCTL_OPERATOR)")
+	asJSON := fs.Bool("json", false, "print raw JSON responses")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	commands := map[string]command{
+		"strategies":  runStrategies,
+		"export":      runExport,
+		"import":      runImport,
+		"enable":      runSetEnabled(true),
+		"disable":     runSetEnabled(false),
+		"positions":   runPositions,
+		"pnl":         runPnL,
+		"report":      runReport,
+		"experiment":  runExperiment,
+		"explain":     runExplain,
+		"tail":        runTail,
+		"flatten":     runFlatten,
+		"halt":        runKillSwitch("halt"),
+		"resume":      runKillSwitch("resume"),
+		"dlq":         runDLQ,
+		"inject":      runInject,
+		"encrypt":     runEncrypt,
+		"mappings":    runMappings,
+		"accounts":    runAccounts,
+		"flags":       runFlags,
+		"conditional": runConditional,
+		"wirelog":     runWireLog,
+	}
+
+	name := fs.Arg(0)
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "strategyctl: unknown command %q\n\n", name)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	c := newClient(*addr, *operator, *asJSON, *timeout)
+	if err := run(c, fs.Args()[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "strategyctl %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// subcommand parses the flags of a subcommand; flags may follow positional
+// arguments, which are returned
+func subcommand(name string, args []string, define func(fs *flag.FlagSet)) ([]string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	define(fs)
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func requireReason(reason string) error {
+	if reason == "" {
+		return errors.New("--reason is required")
+	}
+	return nil
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func runStrategies(c *client, args []string) error {
+	if _, err := subcommand("strategies", args, func(fs *flag.FlagSet) {}); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Strategies []struct {
+			Name         string    `json:"name"`
+			Type         string    `json:"type"`
+			Enabled      bool      `json:"enabled"`
+			Shadow       bool      `json:"shadow"`
+			ShadowReason string    `json:"shadow_reason"`
+			Version      int       `json:"version"`
+			UpdatedAt    time.Time `json:"updated_at"`
+		} `json:"strategies"`
+	}
+	if err := c.get("/admin/strategies", nil, &resp); err != nil || c.json {
+		return err
+	}
+
+	t := newTable()
+	fmt.Fprintln(t, "NAME\tTYPE\tENABLED\tSHADOW\tVERSION\tUPDATED")
+	for _, s := range resp.Strategies {
+		shadow := "-"
+		if s.Shadow {
+			shadow = "yes"
+			if s.ShadowReason != "" {
+				shadow += " (" + s.ShadowReason + ")"
+			}
+		}
+		fmt.Fprintf(t, "%s\t%s\t%t\t%s\tv%d\t%s\n",
+			s.Name, s.Type, s.Enabled, shadow, s.Version, s.UpdatedAt.Local().Format(time.DateTime))
+	}
+	return t.Flush()
+}
+
+func runExport(c *client, args []string) error {
+	var out string
+	if _, err := subcommand("export", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&out, "out", "", "file to write instead of stdout")
+	}); err != nil {
+		return err
+	}
+
+	data, err := c.fetch(http.MethodGet, "/admin/strategies/export", "", nil)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(out, data, 0o600)
+}
+
+func runImport(c *client, args []string) error {
+	var reason string
+	var apply bool
+	positional, err := subcommand("import", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&reason, "reason", "", "why (required)")
+		fs.BoolVar(&apply, "apply", false, "write the changes; only show them otherwise")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("exactly one file is required")
+	}
+	if err := requireReason(reason); err != nil {
+		return err
+	}
+	if c.operator == "" {
+		return fmt.Errorf("no operator name: set --operator or STRATEGYCTL_OPERATOR")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("operator", c.operator)
+	query.Set("reason", reason)
+	query.Set("dry_run", strconv.FormatBool(!apply))
+
+	resp, err := c.fetch(http.MethodPost, "/admin/strategies/import?"+query.Encode(), "application/yaml", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if c.json {
+		_, err = os.Stdout.Write(resp)
+		return err
+	}
+	var result engine.ImportResult
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	for _, s := range result.Strategies {
+		fmt.Printf("%-9s %s\n", s.Action, s.Strategy)
+		for _, change := range s.Changes {
+			fmt.Println("           ", change)
+		}
+	}
+	for _, name := range result.Untouched {
+		fmt.Printf("%-9s %s (not in the file)\n", "untouched", name)
+	}
+	if result.DryRun {
+		fmt.Println("dry run: nothing written, rerun with --apply")
+	}
+	return nil
+}
+
+func runSetEnabled(enabled bool) command {
+	action := "disable"
+	if enabled {
+		action = "enable"
+	}
+
+	return func(c *client, args []string) error {
+		var reason string
+		names, err := subcommand(action, args, func(fs *flag.FlagSet) {
+			fs.StringVar(&reason, "reason", "", "why (required)")
+		})
+		if err != nil {
+			return err
+		}
+		if len(names) != 1 {
+			return errors.New("exactly one strategy name is required")
+		}
+		if err := requireReason(reason); err != nil {
+			return err
+		}
+
+		var resp map[string]interface{}
+		if err := c.post("/admin/strategies/"+names[0]+"/"+action, c.operatorBody(reason), &resp); err != nil || c.json {
+			return err
+		}
+		fmt.Printf("%s %sd\n", names[0], action)
+		return nil
+	}
+}
+
+func runPositions(c *client, args []string) error {
+	if len(args) > 0 && args[0] == "resync" {
+		return runPositionsResync(c, args[1:])
+	}
+
+	var strategy, platform, account string
+	if _, err := subcommand("positions", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&strategy, "strategy", "", "positions on the accounts of this strategy")
+		fs.StringVar(&account, "account", "", "positions of one account")
+		fs.StringVar(&platform, "platform", "", "platform of --account (default predict)")
+	}); err != nil {
+		return err
+	}
+
+	query := map[string]string{"strategy": strategy, "platform": platform, "account_id": account}
+	var resp struct {
+		Accounts []engine.AccountPositions `json:"accounts"`
+	}
+	if err := c.get("/admin/positions", query, &resp); err != nil || c.json {
+		return err
+	}
+	return printPositions(resp.Accounts)
+}
+
+func runPositionsResync(c *client, args []string) error {
+	req := engine.ResyncRequest{Operator: c.operator}
+	if _, err := subcommand("positions resync", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&req.Strategy, "strategy", "", "resync the accounts of this strategy")
+		fs.StringVar(&req.AccountID, "account", "", "resync one account")
+		fs.StringVar(&req.Platform, "platform", "", "platform of --account (default predict)")
+		fs.StringVar(&req.Reason, "reason", "", "why (required)")
+	}); err != nil {
+		return err
+	}
+	if err := requireReason(req.Reason); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Accounts []engine.AccountPositions `json:"accounts"`
+	}
+	if err := c.post("/admin/positions/resync", req, &resp); err != nil || c.json {
+		return err
+	}
+	return printPositions(resp.Accounts)
+}
+
+func printPositions(accounts []engine.AccountPositions) error {
+	t := newTable()
+	fmt.Fprintln(t, "PLATFORM\tACCOUNT\tMARKET\tSIDE\tSHARES\tAVG PRICE\tCOST")
+	for _, account := range accounts {
+		if account.Error != "" {
+			fmt.Fprintf(t, "%s\t%s\terror: %s\t\t\t\t\n", account.Platform, account.AccountID, account.Error)
+			continue
+		}
+		for _, p := range account.Positions {
+			fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%.4g\t%.4f\t%.2f\n",
+				p.Platform, p.AccountID, p.MarketID, p.Side, p.Shares, p.AvgPrice, p.Shares*p.AvgPrice)
+		}
+	}
+	return t.Flush()
+}
+
+func runPnL(c *client, args []string) error {
+	var window time.Duration
+	if _, err := subcommand("pnl", args, func(fs *flag.FlagSet) {
+		fs.DurationVar(&window, "window", 24*time.Hour, "period covered by order and PnL figures")
+	}); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Window     string                      `json:"window"`
+		Strategies []types.StrategyAttribution `json:"strategies"`
+	}
+	if err := c.get("/admin/attribution", map[string]string{"window": window.String()}, &resp); err != nil || c.json {
+		return err
+	}
+
+	var total float64
+	t := newTable()
+	fmt.Fprintln(t, "STRATEGY\tEVENTS\tCOMMANDS\tERRORS\tORDERS\tFILL RATE\tTRADES\tREALIZED PNL")
+	for _, a := range resp.Strategies {
+		total += a.RealizedPnL
+		fmt.Fprintf(t, "%s\t%d\t%d\t%d\t%d\t%.0f%%\t%d\t%.2f\n",
+			a.Strategy, a.Events, a.Commands, a.HandlerErrors, a.Orders, a.FillRate*100, a.Trades, a.RealizedPnL)
+	}
+	fmt.Fprintf(t, "TOTAL (%s)\t\t\t\t\t\t\t%.2f\n", resp.Window, total)
+	return t.Flush()
+}
+
+func runReport(c *client, args []string) error {
+	var generate bool
+	positional, err := subcommand("report", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&generate, "generate", false, "generate the report again from the journal")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return errors.New("at most one day is accepted")
+	}
+	day := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+	if len(positional) == 1 {
+		day = positional[0]
+	}
+
+	var report types.DailyReport
+	if generate {
+		err = c.post("/admin/reports/daily/"+url.PathEscape(day), nil, &report)
+	} else {
+		err = c.get("/admin/reports/daily/"+url.PathEscape(day), nil, &report)
+	}
+	if err != nil || c.json {
+		return err
+	}
+
+	fmt.Printf("Daily report %s\n\n", report.Day)
+	fmt.Print(reports.Summary(&report))
+	return nil
+}
+
+func runExperiment(c *client, args []string) error {
+	var window time.Duration
+	names, err := subcommand("experiment", args, func(fs *flag.FlagSet) {
+		fs.DurationVar(&window, "window", 24*time.Hour, "period covered by order and PnL figures")
+	})
+	if err != nil {
+		return err
+	}
+	if len(names) != 1 {
+		return errors.New("exactly one strategy name is required")
+	}
+
+	var report engine.ExperimentReport
+	if err := c.get("/admin/strategies/"+names[0]+"/experiment", map[string]string{"window": window.String()}, &report); err != nil || c.json {
+		return err
+	}
+
+	fmt.Printf("%s, split by %s, last %s\n", report.Strategy, report.Split, report.Window)
+	t := newTable()
+	fmt.Fprintln(t, "VARIANT\tWEIGHT\tEVENTS\tCOMMANDS\tORDERS\tFILL RATE\tTRADES\tREALIZED PNL\tPNL/TRADE")
+	for _, v := range report.Variants {
+		fmt.Fprintf(t, "%s\t%.0f%%\t%d\t%d\t%d\t%.0f%%\t%d\t%.2f\t%.4f\n",
+			v.Variant, v.Weight*100, v.Events, v.Commands, v.Orders, v.FillRate*100, v.Trades, v.RealizedPnL, v.PnLPerTrade)
+	}
+	return t.Flush()
+}
+
+func runExplain(c *client, args []string) error {
+	var limit int
+	var on, off bool
+	names, err := subcommand("explain", args, func(fs *flag.FlagSet) {
+		fs.IntVar(&limit, "limit", 20, "number of events")
+		fs.BoolVar(&on, "on", false, "switch explain mode on")
+		fs.BoolVar(&off, "off", false, "switch explain mode off")
+	})
+	if err != nil {
+		return err
+	}
+	if len(names) != 1 {
+		return errors.New("exactly one strategy name is required")
+	}
+	if on && off {
+		return errors.New("--on and --off are exclusive")
+	}
+
+	path := "/admin/strategies/" + names[0] + "/explain"
+	if on || off {
+		var resp map[string]interface{}
+		if err := c.post(path, map[string]bool{"enabled": on}, &resp); err != nil || c.json {
+			return err
+		}
+		fmt.Printf("Explain mode of %s switched %s\n", names[0], map[bool]string{true: "on", false: "off"}[on])
+		return nil
+	}
+
+	var resp struct {
+		Explanations []types.Explanation `json:"explanations"`
+	}
+	if err := c.get(path, map[string]string{"limit": strconv.Itoa(limit)}, &resp); err != nil || c.json {
+		return err
+	}
+	if len(resp.Explanations) == 0 {
+		fmt.Println("No explained events (is explain mode on?)")
+		return nil
+	}
+
+	// Oldest first, like a log
+	for i := len(resp.Explanations) - 1; i >= 0; i-- {
+		x := resp.Explanations[i]
+		outcome := "skipped"
+		if x.Acted {
+			outcome = fmt.Sprintf("acted, %d command(s)", x.Commands)
+		}
+		fmt.Printf("%s  %s %s: %s\n", x.At.Local().Format("15:04:05.000"), x.EventType, x.EventID, outcome)
+		for _, reason := range x.Reasons {
+			fmt.Printf("    - %s\n", reason)
+		}
+	}
+	return nil
+}
+
+func runTail(c *client, args []string) error {
+	var since, interval time.Duration
+	if _, err := subcommand("tail", args, func(fs *flag.FlagSet) {
+		fs.DurationVar(&since, "since", 5*time.Minute, "start this long ago")
+		fs.DurationVar(&interval, "interval", 2*time.Second, "poll interval")
+	}); err != nil {
+		return err
+	}
+
+	// Orders are printed one by one, also with --json
+	fetch := *c
+	fetch.json = false
+
+	cursor := time.Now().Add(-since)
+	seen := make(map[string]bool)
+	for {
+		var resp struct {
+			Orders []types.OrderRecord `json:"orders"`
+		}
+		if err := fetch.get("/admin/orders", map[string]string{"since": cursor.Format(time.RFC3339Nano)}, &resp); err != nil {
+			fmt.Fprintln(os.Stderr, "strategyctl tail:", err)
+		}
+
+		// since is inclusive: orders at the cursor come back on the next poll
+		for _, o := range resp.Orders {
+			key := fmt.Sprintf("%s|%s|%s|%s", o.Platform, o.AccountID, o.MarketID, o.OrderID)
+			if !o.CreatedAt.After(cursor) && seen[key] {
+				continue
+			}
+			if o.CreatedAt.After(cursor) {
+				cursor = o.CreatedAt
+				seen = make(map[string]bool)
+			}
+			seen[key] = true
+			printOrder(c, o)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+func printOrder(c *client, o types.OrderRecord) {
+	if c.json {
+		c.printJSON(o)
+		return
+	}
+
+	line := fmt.Sprintf("%s  %-20s %-9s %s/%s market=%s %s %.4f x %.4g",
+		o.CreatedAt.Local().Format("15:04:05.000"), o.Strategy, o.Status,
+		o.Platform, o.AccountID, o.MarketID, o.Side, o.Price, o.Shares)
+	if o.OrderID != "" {
+		line += " id=" + o.OrderID
+	}
+	if o.Error != "" {
+		line += " error=" + strconv.Quote(o.Error)
+	}
+	fmt.Println(line)
+}
+
+func runFlatten(c *client, args []string) error {
+	req := engine.FlattenRequest{Operator: c.operator}
+	if _, err := subcommand("flatten", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&req.Strategy, "strategy", "", "flatten every account of this strategy")
+		fs.StringVar(&req.AccountID, "account", "", "flatten one account")
+		fs.StringVar(&req.Platform, "platform", "", "platform of --account (default predict)")
+		fs.StringVar(&req.Mode, "mode", engine.FlattenModeOffset, "offset (buy the opposite outcome) or venue (account service sells; --account only)")
+		fs.Float64Var(&req.Slippage, "slippage", 0, "added to the crossing price (default 0.02)")
+		fs.BoolVar(&req.Preview, "preview", false, "only show the commands")
+		fs.StringVar(&req.Reason, "reason", "", "why (required)")
+	}); err != nil {
+		return err
+	}
+	if err := requireReason(req.Reason); err != nil {
+		return err
+	}
+
+	var result engine.FlattenResult
+	if err := c.post("/admin/flatten", req, &result); err != nil || c.json {
+		return err
+	}
+
+	verb := "sent"
+	if result.Preview {
+		verb = "planned"
+	}
+	fmt.Printf("%d accounts, %d commands %s\n", result.Accounts, len(result.Commands), verb)
+	for _, cmd := range result.Commands {
+		fmt.Printf("  %s %s/%s market=%s %s %.4f x %.4g\n",
+			cmd.Type, cmd.Platform, cmd.AccountID, cmd.MarketID, cmd.Side, cmd.Price, cmd.Shares)
+	}
+	for _, s := range result.Skipped {
+		fmt.Println("  skipped:", s)
+	}
+	for _, e := range result.Errors {
+		fmt.Println("  error:", e)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d errors", len(result.Errors))
+	}
+	return nil
+}
+
+func runKillSwitch(action string) command {
+	return func(c *client, args []string) error {
+		var reason string
+		if _, err := subcommand(action, args, func(fs *flag.FlagSet) {
+			fs.StringVar(&reason, "reason", "", "why (required)")
+		}); err != nil {
+			return err
+		}
+		if err := requireReason(reason); err != nil {
+			return err
+		}
+
+		var resp struct {
+			Halted bool `json:"halted"`
+		}
+		if err := c.post("/admin/"+action, c.operatorBody(reason), &resp); err != nil || c.json {
+			return err
+		}
+		fmt.Printf("halted: %t\n", resp.Halted)
+		return nil
+	}
+}
+
+func runDLQ(c *client, args []string) error {
+	if len(args) > 0 && args[0] == "retry" {
+		return runDLQRetry(c, args[1:])
+	}
+
+	var limit int
+	if _, err := subcommand("dlq", args, func(fs *flag.FlagSet) {
+		fs.IntVar(&limit, "limit", 100, "maximum commands to list")
+	}); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Commands []types.OutboxEntry `json:"commands"`
+	}
+	if err := c.get("/admin/outbox/failed", map[string]string{"limit": strconv.Itoa(limit)}, &resp); err != nil || c.json {
+		return err
+	}
+
+	t := newTable()
+	fmt.Fprintln(t, "ID\tSTATUS\tSTRATEGY\tEVENT\tCOMMAND\tATTEMPTS\tLAST ERROR")
+	for _, e := range resp.Commands {
+		cmd := fmt.Sprintf("%s %s/%s %s %s", e.Command.Type, e.Command.Platform, e.Command.AccountID, e.Command.MarketID, e.Command.Side)
+		fmt.Fprintf(t, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n", e.ID, e.Status, e.Strategy, e.EventType, strings.TrimSpace(cmd), e.Attempts, e.LastError)
+	}
+	return t.Flush()
+}
+
+func runDLQRetry(c *client, args []string) error {
+	var reason string
+	var since time.Duration
+	positional, err := subcommand("dlq retry", args, func(fs *flag.FlagSet) {
+		fs.DurationVar(&since, "since", 0, "also retry the failed commands created this long ago or later")
+		fs.StringVar(&reason, "reason", "", "why (required)")
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireReason(reason); err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(positional))
+	for _, arg := range positional {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid command ID %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 && since <= 0 {
+		return errors.New("dlq retry needs command IDs or --since")
+	}
+
+	body := map[string]interface{}{"ids": ids, "reason": reason, "operator": c.operator}
+	if since > 0 {
+		body["since"] = time.Now().Add(-since).UTC().Format(time.RFC3339Nano)
+	}
+	var resp struct {
+		Retried int64 `json:"retried"`
+	}
+	if err := c.post("/admin/outbox/retry", body, &resp); err != nil || c.json {
+		return err
+	}
+	fmt.Printf("%d commands sent through the checks again\n", resp.Retried)
+	return nil
+}
+
+func runInject(c *client, args []string) error {
+	req := engine.InjectRequest{Operator: c.operator}
+	var data string
+	var live bool
+	positional, err := subcommand("inject", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&data, "data", "{}", "event data as a JSON object")
+		fs.StringVar(&req.Event.Platform, "platform", "", "event platform")
+		fs.BoolVar(&live, "live", false, "run the full pipeline; orders are real")
+		fs.StringVar(&req.Reason, "reason", "", "why (required)")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("exactly one event type is required")
+	}
+	if err := requireReason(req.Reason); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(data), &req.Event.Data); err != nil {
+		return fmt.Errorf("invalid --data: %w", err)
+	}
+	req.Event.Type = positional[0]
+	req.DryRun = !live
+
+	var result engine.InjectResult
+	if err := c.post("/admin/events/inject", req, &result); err != nil || c.json {
+		return err
+	}
+
+	if !result.DryRun {
+		fmt.Printf("event %s handed to %d strategies\n", result.EventID, len(result.Strategies))
+		for _, s := range result.Strategies {
+			fmt.Println(" ", s.Strategy)
+		}
+		return nil
+	}
+
+	fmt.Printf("event %s (dry run)\n", result.EventID)
+	for _, s := range result.Strategies {
+		switch {
+		case s.Error != "":
+			fmt.Printf("  %s: error: %s\n", s.Strategy, s.Error)
+		case s.Skipped != "":
+			fmt.Printf("  %s: skipped: %s\n", s.Strategy, s.Skipped)
+		default:
+			fmt.Printf("  %s: %d commands\n", s.Strategy, len(s.Commands))
+		}
+		for _, cmd := range s.Commands {
+			fmt.Printf("    %s %s/%s market=%s %s %.4f x %.4g\n",
+				cmd.Type, cmd.Platform, cmd.AccountID, cmd.MarketID, cmd.Side, cmd.Price, cmd.Shares)
+		}
+	}
+	return nil
+}
+
+func runEncrypt(c *client, args []string) error {
+	positional, err := subcommand("encrypt", args, func(fs *flag.FlagSet) {})
+	if err != nil {
+		return err
+	}
+
+	var value string
+	switch len(positional) {
+	case 0:
+		// Reading from stdin keeps the secret out of the shell history
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	case 1:
+		value = positional[0]
+	default:
+		return errors.New("at most one value is allowed")
+	}
+	if value == "" {
+		return errors.New("empty value")
+	}
+
+	var resp struct {
+		Encrypted string `json:"encrypted"`
+	}
+	if err := c.post("/admin/config/encrypt", map[string]string{"value": value}, &resp); err != nil || c.json {
+		return err
+	}
+	fmt.Println(resp.Encrypted)
+	return nil
+}
+
+func runMappings(c *client, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "add":
+			return runMappingsAdd(c, args[1:])
+		case "rm":
+			return runMappingsRemove(c, args[1:])
+		case "suggest":
+			return runMappingsSuggest(c, args[1:])
+		}
+	}
+	if _, err := subcommand("mappings", args, func(fs *flag.FlagSet) {}); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Mappings []types.MarketMapping `json:"mappings"`
+	}
+	if err := c.get("/admin/mappings", nil, &resp); err != nil || c.json {
+		return err
+	}
+
+	t := newTable()
+	fmt.Fprintln(t, "ID\tPREDICT\tPOLYMARKET\tINVERTED\tBY\tNOTE")
+	for _, m := range resp.Mappings {
+		fmt.Fprintf(t, "%d\t%s\t%s\t%t\t%s\t%s\n",
+			m.ID, m.PredictMarketID, m.PolymarketConditionID, m.Inverted, m.CreatedBy, m.Note)
+	}
+	return t.Flush()
+}
+
+func runMappingsAdd(c *client, args []string) error {
+	var inverted bool
+	var note string
+	positional, err := subcommand("mappings add", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&inverted, "inverted", false, "YES on Predict pays like NO on Polymarket")
+		fs.StringVar(&note, "note", "", "note kept with the mapping")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("a Predict market ID and a Polymarket condition ID are required")
+	}
+
+	body := map[string]interface{}{
+		"predict_market_id":       positional[0],
+		"polymarket_condition_id": positional[1],
+		"inverted":                inverted,
+		"note":                    note,
+		"operator":                c.operator,
+	}
+	var mapping types.MarketMapping
+	if err := c.post("/admin/mappings", body, &mapping); err != nil || c.json {
+		return err
+	}
+	fmt.Printf("mapping %d: predict %s -> polymarket %s\n", mapping.ID, mapping.PredictMarketID, mapping.PolymarketConditionID)
+	return nil
+}
+
+func runMappingsRemove(c *client, args []string) error {
+	var reason string
+	positional, err := subcommand("mappings rm", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&reason, "reason", "", "why (required)")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("exactly one mapping ID is required")
+	}
+	if err := requireReason(reason); err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(positional[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid mapping ID %q", positional[0])
+	}
+
+	if err := c.send(http.MethodDelete, "/admin/mappings/"+positional[0], c.operatorBody(reason), nil); err != nil || c.json {
+		return err
+	}
+	fmt.Printf("mapping %d deleted\n", id)
+	return nil
+}
+
+func runMappingsSuggest(c *client, args []string) error {
+	var minScore float64
+	var limit int
+	if _, err := subcommand("mappings suggest", args, func(fs *flag.FlagSet) {
+		fs.Float64Var(&minScore, "min-score", 0.6, "lowest similarity to suggest, 0 to 1")
+		fs.IntVar(&limit, "limit", 50, "maximum suggestions")
+	}); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Suggestions []markets.Suggestion `json:"suggestions"`
+	}
+	query := map[string]string{
+		"min_score": strconv.FormatFloat(minScore, 'f', -1, 64),
+		"limit":     strconv.Itoa(limit),
+	}
+	if err := c.get("/admin/mappings/suggestions", query, &resp); err != nil || c.json {
+		return err
+	}
+
+	t := newTable()
+	fmt.Fprintln(t, "SCORE\tPREDICT\tPOLYMARKET\tPREDICT QUESTION\tPOLYMARKET QUESTION")
+	for _, s := range resp.Suggestions {
+		fmt.Fprintf(t, "%.2f\t%s\t%s\t%s\t%s\n",
+			s.Score, s.PredictMarketID, s.PolymarketConditionID, s.PredictTitle, s.PolymarketTitle)
+	}
+	return t.Flush()
+}
+
+func runAccounts(c *client, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "set":
+			return runAccountsSet(c, args[1:])
+		case "rm":
+			return runAccountsRemove(c, args[1:])
+		}
+	}
+	if _, err := subcommand("accounts", args, func(fs *flag.FlagSet) {}); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Accounts []types.TradingAccount `json:"accounts"`
+	}
+	if err := c.get("/admin/accounts", nil, &resp); err != nil || c.json {
+		return err
+	}
+
+	t := newTable()
+	fmt.Fprintln(t, "PLATFORM\tACCOUNT\tNAME\tENABLED\tMAX SHARES\tMARKETS\tBY\tNOTE")
+	for _, a := range resp.Accounts {
+		maxShares := "-"
+		if a.MaxOrderShares > 0 {
+			maxShares = strconv.FormatFloat(a.MaxOrderShares, 'f', -1, 64)
+		}
+		markets := "any"
+		if len(a.AllowedMarkets) > 0 {
+			markets = strings.Join(a.AllowedMarkets, ",")
+		}
+		fmt.Fprintf(t, "%s\t%s\t%s\t%t\t%s\t%s\t%s\t%s\n",
+			a.Platform, a.AccountID, a.Name, a.Enabled, maxShares, markets, a.UpdatedBy, a.Note)
+	}
+	return t.Flush()
+}
+
+func runAccountsSet(c *client, args []string) error {
+	var name, markets, note string
+	var disabled bool
+	var maxShares float64
+	positional, err := subcommand("accounts set", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&name, "name", "", "display name")
+		fs.BoolVar(&disabled, "disabled", false, "block new orders on the account")
+		fs.Float64Var(&maxShares, "max-shares", 0, "largest order in shares, 0 for no limit")
+		fs.StringVar(&markets, "markets", "", "comma-separated markets the account may trade, empty for any")
+		fs.StringVar(&note, "note", "", "note kept with the account")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("a platform and an account ID are required")
+	}
+
+	var allowed []string
+	for _, market := range strings.Split(markets, ",") {
+		if market = strings.TrimSpace(market); market != "" {
+			allowed = append(allowed, market)
+		}
+	}
+	body := map[string]interface{}{
+		"name":             name,
+		"enabled":          !disabled,
+		"max_order_shares": maxShares,
+		"allowed_markets":  allowed,
+		"note":             note,
+		"operator":         c.operator,
+	}
+	path := "/admin/accounts/" + url.PathEscape(positional[0]) + "/" + url.PathEscape(positional[1])
+	var account types.TradingAccount
+	if err := c.send(http.MethodPut, path, body, &account); err != nil || c.json {
+		return err
+	}
+	fmt.Printf("account %s on %s saved (enabled: %t)\n", account.AccountID, account.Platform, account.Enabled)
+	return nil
+}
+
+func runAccountsRemove(c *client, args []string) error {
+	var reason string
+	positional, err := subcommand("accounts rm", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&reason, "reason", "", "why (required)")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("a platform and an account ID are required")
+	}
+	if err := requireReason(reason); err != nil {
+		return err
+	}
+
+	path := "/admin/accounts/" + url.PathEscape(positional[0]) + "/" + url.PathEscape(positional[1])
+	if err := c.send(http.MethodDelete, path, c.operatorBody(reason), nil); err != nil || c.json {
+		return err
+	}
+	fmt.Printf("account %s on %s removed\n", positional[1], positional[0])
+	return nil
+}
+
+func runFlags(c *client, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "set":
+			return runFlagsSet(c, args[1:])
+		case "rm":
+			return runFlagsRemove(c, args[1:])
+		}
+	}
+	var market string
+	positional, err := subcommand("flags", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&market, "market", "", "market to check the flag for")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return runFlagCheck(c, positional[0], market)
+	}
+
+	var resp struct {
+		Environment string              `json:"environment"`
+		Flags       []types.FeatureFlag `json:"flags"`
+	}
+	if err := c.get("/admin/flags", nil, &resp); err != nil || c.json {
+		return err
+	}
+
+	fmt.Printf("environment: %q\n", resp.Environment)
+	t := newTable()
+	fmt.Fprintln(t, "FLAG\tENABLED\tPERCENT\tENVIRONMENTS\tBY\tUPDATED\tDESCRIPTION")
+	for _, f := range resp.Flags {
+		environments := "all"
+		if len(f.Environments) > 0 {
+			environments = strings.Join(f.Environments, ",")
+		}
+		fmt.Fprintf(t, "%s\t%t\t%s\t%s\t%s\t%s\t%s\n",
+			f.Name, f.Enabled, strconv.FormatFloat(f.Percent, 'f', -1, 64), environments,
+			f.UpdatedBy, f.UpdatedAt.Format(time.RFC3339), f.Description)
+	}
+	return t.Flush()
+}
+
+func runFlagCheck(c *client, name, market string) error {
+	var resp struct {
+		Environment string `json:"environment"`
+		On          bool   `json:"on"`
+	}
+	query := map[string]string{"market": market}
+	if err := c.get("/admin/flags/"+url.PathEscape(name), query, &resp); err != nil || c.json {
+		return err
+	}
+	state := "off"
+	if resp.On {
+		state = "on"
+	}
+	if market == "" {
+		market = "(none)"
+	}
+	fmt.Printf("%s is %s for market %s in environment %q\n", name, state, market, resp.Environment)
+	return nil
+}
+
+func runFlagsSet(c *client, args []string) error {
+	var on bool
+	var percent float64
+	var environments, description string
+	positional, err := subcommand("flags set", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&on, "on", false, "enable the flag; it is saved disabled otherwise")
+		fs.Float64Var(&percent, "percent", 100, "share of markets the flag is on for, 0 to 100")
+		fs.StringVar(&environments, "env", "", "comma-separated environments the flag is on in, empty for all")
+		fs.StringVar(&description, "description", "", "what the flag gates")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("a flag name is required")
+	}
+
+	var envs []string
+	for _, env := range strings.Split(environments, ",") {
+		if env = strings.TrimSpace(env); env != "" {
+			envs = append(envs, env)
+		}
+	}
+	body := map[string]interface{}{
+		"enabled":      on,
+		"percent":      percent,
+		"environments": envs,
+		"description":  description,
+		"operator":     c.operator,
+	}
+	var saved types.FeatureFlag
+	if err := c.send(http.MethodPut, "/admin/flags/"+url.PathEscape(positional[0]), body, &saved); err != nil || c.json {
+		return err
+	}
+	fmt.Printf("flag %s saved (enabled: %t, %s%% of markets)\n", saved.Name, saved.Enabled, strconv.FormatFloat(saved.Percent, 'f', -1, 64))
+	return nil
+}
+
+func runFlagsRemove(c *client, args []string) error {
+	var reason string
+	positional, err := subcommand("flags rm", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&reason, "reason", "", "why (required)")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("a flag name is required")
+	}
+	if err := requireReason(reason); err != nil {
+		return err
+	}
+
+	if err := c.send(http.MethodDelete, "/admin/flags/"+url.PathEscape(positional[0]), c.operatorBody(reason), nil); err != nil || c.json {
+		return err
+	}
+	fmt.Printf("flag %s deleted\n", positional[0])
+	return nil
+}
+
+func runConditional(c *client, args []string) error {
+	if len(args) > 0 && args[0] == "rm" {
+		return runConditionalRemove(c, args[1:])
+	}
+	var strategy string
+	if _, err := subcommand("conditional", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&strategy, "strategy", "", "only this strategy's orders")
+	}); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Conditional []types.ConditionalCommand `json:"conditional"`
+	}
+	var query map[string]string
+	if strategy != "" {
+		query = map[string]string{"strategy": strategy}
+	}
+	if err := c.get("/admin/orders/conditional", query, &resp); err != nil || c.json {
+		return err
+	}
+
+	t := newTable()
+	fmt.Fprintln(t, "ID\tSTRATEGY\tSTATUS\tGROUP\tMARKET\tSIDE\tACTION\tPRICE\tSHARES\tTRIGGER\tORDER")
+	for _, cc := range resp.Conditional {
+		cmd := cc.Command
+		trigger := "-"
+		if price, ok := cmd.Metadata["trigger_price"].(float64); ok && cc.Status == types.ConditionalPending {
+			direction, _ := cmd.Metadata["trigger"].(string)
+			trigger = strings.TrimSpace(fmt.Sprintf("%s %.4f", direction, price))
+		}
+		fmt.Fprintf(t, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%.4f\t%.2f\t%s\t%s\n",
+			cc.ID, cc.Strategy, cc.Status, cc.Group, cmd.MarketID, cmd.Side, cmd.Action,
+			cmd.Price, cmd.Shares, trigger, cc.OrderID)
+	}
+	return t.Flush()
+}
+
+func runConditionalRemove(c *client, args []string) error {
+	var reason string
+	positional, err := subcommand("conditional rm", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&reason, "reason", "", "why (required)")
+	})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("exactly one conditional order ID is required")
+	}
+	if err := requireReason(reason); err != nil {
+		return err
+	}
+	if _, err := strconv.ParseInt(positional[0], 10, 64); err != nil {
+		return fmt.Errorf("invalid conditional order ID %q", positional[0])
+	}
+
+	if err := c.send(http.MethodDelete, "/admin/orders/conditional/"+positional[0], c.operatorBody(reason), nil); err != nil || c.json {
+		return err
+	}
+	fmt.Printf("conditional order %s deleted\n", positional[0])
+	return nil
+}
+
+func runWireLog(c *client, args []string) error {
+	var platform, grep string
+	var since time.Duration
+	var limit int
+	if _, err := subcommand("wirelog", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&platform, "platform", "", "only this platform's requests")
+		fs.DurationVar(&since, "since", time.Hour, "start this long ago")
+		fs.StringVar(&grep, "grep", "", "only requests with this string in the URL or a body")
+		fs.IntVar(&limit, "limit", 100, "maximum requests to list")
+	}); err != nil {
+		return err
+	}
+
+	query := map[string]string{
+		"since": time.Now().Add(-since).Format(time.RFC3339Nano),
+		"limit": strconv.Itoa(limit),
+	}
+	if platform != "" {
+		query["platform"] = platform
+	}
+	if grep != "" {
+		query["q"] = grep
+	}
+	var resp struct {
+		Requests []types.WireLogEntry `json:"requests"`
+	}
+	if err := c.get("/admin/wirelog", query, &resp); err != nil || c.json {
+		return err
+	}
+
+	t := newTable()
+	fmt.Fprintln(t, "ID\tTIME\tPLATFORM\tMETHOD\tURL\tSTATUS\tLATENCY\tERROR")
+	for _, e := range resp.Requests {
+		status := "-"
+		if e.Status != 0 {
+			status = strconv.Itoa(e.Status)
+		}
+		fmt.Fprintf(t, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID, e.CreatedAt.Local().Format(time.RFC3339), e.Platform, e.Method, e.URL, status, e.Latency, e.Error)
+	}
+	return t.Flush()
+}
@@ -0,0 +1,100 @@
+package accountsvc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Account services can require any combination of:
+//
+//   - an API key: AuthToken in AuthHeader (Authorization: Bearer <token> by default)
+//   - HMAC signing: hex HMAC-SHA256 with HMACSecret over
+//     "<unix timestamp>\n<METHOD>\n<path with query>\n<body>", sent in
+//     X-Signature with the timestamp in X-Timestamp and HMACKeyID in X-Key-Id
+//   - mTLS: the client certificate in TLSCertFile/TLSKeyFile, verified against
+//     TLSCAFile when set. The pair is re-read on every handshake, so rotated
+//     certificates are picked up by new connections.
+
+const (
+	signatureHeader = "X-Signature"
+	timestampHeader = "X-Timestamp"
+	keyIDHeader     = "X-Key-Id"
+)
+
+// authenticate adds the API key and HMAC signature headers to req
+func (c *Client) authenticate(req *http.Request, body []byte) {
+	if c.config.AuthToken != "" {
+		header := c.config.AuthHeader
+		if header == "" || strings.EqualFold(header, "Authorization") {
+			req.Header.Set("Authorization", "Bearer "+c.config.AuthToken)
+		} else {
+			req.Header.Set(header, c.config.AuthToken)
+		}
+	}
+
+	if c.config.HMACSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(timestampHeader, timestamp)
+		req.Header.Set(signatureHeader, sign(c.config.HMACSecret, timestamp, req.Method, req.URL.RequestURI(), body))
+		if c.config.HMACKeyID != "" {
+			req.Header.Set(keyIDHeader, c.config.HMACKeyID)
+		}
+	}
+}
+
+func sign(secret, timestamp, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + method + "\n" + path + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// tlsConfig returns the client TLS settings of an account service, or nil
+// when it uses neither a client certificate nor a private CA
+func tlsConfig(p Config) (*tls.Config, error) {
+	if p.TLSCertFile == "" && p.TLSCAFile == "" && p.TLSServerName == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: p.TLSServerName,
+	}
+
+	if p.TLSCAFile != "" {
+		pem, err := os.ReadFile(p.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", p.TLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if p.TLSCertFile != "" {
+		// Fail fast on a bad pair; later handshakes reload it from disk
+		if _, err := tls.LoadX509KeyPair(p.TLSCertFile, p.TLSKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(p.TLSCertFile, p.TLSKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,345 @@
+// Package accountsvc is the client of the account services (predict-account,
+// polymarket-account, ...). Every venue's account service speaks the same
+// HTTP API, so a Client is configured per platform with the service's URL and
+// credentials. Order responses are decoded into typed results; positions,
+// open orders and market metadata are venue payloads passed through as-is.
+package accountsvc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Config describes how to reach one account service
+type Config struct {
+	URL string
+
+	// Authentication, see auth.go. AuthToken is sent in AuthHeader (default
+	// Authorization, as "Bearer <token>"; any other header carries it as-is).
+	AuthHeader string
+	AuthToken  string
+	HMACKeyID  string
+	HMACSecret string
+
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSCAFile     string
+	TLSServerName string
+
+	// Timeout bounds each request; zero means 30s
+	Timeout time.Duration
+
+	// ConnectTimeout bounds dialing the service (or the proxy); zero keeps
+	// Go's default of 30s
+	ConnectTimeout time.Duration
+
+	// MaxIdleConns caps the keep-alive connections kept open to the service,
+	// and IdleConnTimeout closes them after being unused that long. Zero
+	// keeps Go's defaults (2 and 90s); a negative MaxIdleConns disables
+	// keep-alive.
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+
+	// ProxyURL sends requests through an HTTP(S) or SOCKS5 proxy instead of
+	// the one from the environment
+	ProxyURL string
+
+	// Headers are added to every request; authentication headers win
+	Headers map[string]string
+
+	// RateLimit caps requests per second to the service, with bursts of up to
+	// RateBurst requests. Zero means unlimited.
+	RateLimit float64
+	RateBurst int
+
+	// WrapTransport, if set, wraps the HTTP transport, e.g. to inject faults
+	// (see the chaos package)
+	WrapTransport func(http.RoundTripper) http.RoundTripper
+}
+
+// Client sends requests to one account service
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	limiter    *rateLimiter
+}
+
+// NewClient builds a client for the account service described by config
+func NewClient(config Config) (*Client, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	config.URL = strings.TrimRight(config.URL, "/")
+
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if config.WrapTransport != nil {
+		roundTripper = config.WrapTransport(transport)
+	}
+
+	client := &Client{
+		config:     config,
+		httpClient: &http.Client{Timeout: timeout, Transport: roundTripper},
+	}
+	if config.RateLimit > 0 {
+		client.limiter = newRateLimiter(config.RateLimit, config.RateBurst)
+	}
+	return client, nil
+}
+
+// newTransport builds the connection settings of an account service on top
+// of Go's default transport
+func newTransport(config Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := tlsConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if config.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: config.ConnectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+	switch {
+	case config.MaxIdleConns < 0:
+		transport.DisableKeepAlives = true
+	case config.MaxIdleConns > 0:
+		transport.MaxIdleConns = config.MaxIdleConns
+		transport.MaxIdleConnsPerHost = config.MaxIdleConns
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+
+	if config.ProxyURL != "" {
+		proxy, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return transport, nil
+}
+
+// APIError is returned when an account service answers with a non-200 status.
+// Code and Message are read from the error body: either top-level "code" (or
+// "error_code") and "error"/"message" fields, or FastAPI's "detail", which is
+// a string or an object with "code" and "message".
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Body       map[string]interface{}
+}
+
+func (e *APIError) Error() string {
+	var detail string
+	switch {
+	case e.Code != "" && e.Message != "":
+		detail = e.Code + ": " + e.Message
+	case e.Message != "":
+		detail = e.Message
+	case e.Code != "":
+		detail = e.Code
+	default:
+		detail = fmt.Sprint(e.Body)
+	}
+	return fmt.Sprintf("account service request failed (status %d): %s", e.StatusCode, detail)
+}
+
+func newAPIError(statusCode int, body map[string]interface{}) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: body}
+	apiErr.Code, apiErr.Message = errorDetail(body)
+	return apiErr
+}
+
+// errorDetail extracts the error code and message of an error body
+func errorDetail(body map[string]interface{}) (code, message string) {
+	code = stringField(body, "code", "error_code")
+	message = stringField(body, "error", "message")
+
+	switch detail := body["detail"].(type) {
+	case string:
+		if message == "" {
+			message = detail
+		}
+	case map[string]interface{}:
+		if code == "" {
+			code = stringField(detail, "code", "error_code")
+		}
+		if message == "" {
+			message = stringField(detail, "message", "error", "msg")
+		}
+	}
+	return code, message
+}
+
+func stringField(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, _ := m[key].(string); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// post sends payload as JSON to path and decodes the response into v, which
+// may be nil to ignore it
+func (c *Client) post(ctx context.Context, path string, payload, v interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+	}
+	return c.do(ctx, http.MethodPost, path, body, v)
+}
+
+// get fetches path and decodes the response into v
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	return c.do(ctx, http.MethodGet, path, nil, v)
+}
+
+// ErrNotSent wraps the failures of requests that provably never reached the
+// service: the rate limiter gave up, the request could not be built, or the
+// connection could not be made. Anything else may have been processed.
+var ErrNotSent = errors.New("request not sent")
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, v interface{}) error {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotSent, err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if notSent(err) {
+			return fmt.Errorf("%w: failed to send request: %w", ErrNotSent, err)
+		}
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errBody map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		return newAPIError(resp.StatusCode, errBody)
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// notSent reports whether a transport error happened before the request was
+// written: resolving the host or dialing it failed
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// newRequest builds an authenticated request to path on the account service,
+// waiting for the rate limiter first
+func (c *Client) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.URL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range c.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.authenticate(req, body)
+
+	return req, nil
+}
+
+// rateLimiter is a token bucket refilled at rate tokens per second
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token is available or ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
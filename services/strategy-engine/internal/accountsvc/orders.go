@@ -0,0 +1,195 @@
+package accountsvc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Order statuses reported by account services. Venues may report others
+// (e.g. the CLOB's "live", "matched", "delayed").
+const (
+	StatusSubmitted = "submitted"
+	StatusDryRun    = "dry_run"
+)
+
+// OrderRequest is the body of POST /trade and of each /trade/batch item.
+// Confirm false asks the service for a dry run.
+type OrderRequest struct {
+	AccountID   string  `json:"account_id"`
+	MarketID    string  `json:"market_id"`
+	OutcomeID   string  `json:"outcome_id,omitempty"` // outcome token, for markets with more than yes/no
+	Side        string  `json:"side"`
+	Action      string  `json:"action,omitempty"` // sell; buy is the default
+	Price       float64 `json:"price"`
+	Shares      float64 `json:"shares"`
+	Confirm     bool    `json:"confirm"`
+	OrderType   string  `json:"order_type,omitempty"`
+	TimeInForce string  `json:"time_in_force,omitempty"`
+	ExpireAt    string  `json:"expire_at,omitempty"` // RFC3339, with GTD
+
+	// ClientOrderID is the order's idempotency key: a service deduplicating
+	// orders answers a request repeating a key with the first one's result
+	ClientOrderID string `json:"client_order_id,omitempty"`
+}
+
+// OrderResult is an account service's answer to an accepted order
+type OrderResult struct {
+	TradeID      string  `json:"trade_id,omitempty"`
+	OrderID      string  `json:"order_id,omitempty"`
+	OrderHash    string  `json:"order_hash,omitempty"` // Predict's order ID
+	Status       string  `json:"status"`
+	FilledShares float64 `json:"filled_shares,omitempty"`
+	Message      string  `json:"message,omitempty"`
+}
+
+// ID returns the venue order ID, or "" when the service returned none
+func (r *OrderResult) ID() string {
+	if r == nil {
+		return ""
+	}
+	if r.OrderHash != "" {
+		return r.OrderHash
+	}
+	return r.OrderID
+}
+
+// DryRun reports whether the order was only simulated
+func (r *OrderResult) DryRun() bool {
+	return r != nil && r.Status == StatusDryRun
+}
+
+// Filled reports whether the venue filled all shares on placement, so
+// nothing is left resting
+func (r *OrderResult) Filled(shares float64) bool {
+	return r != nil && shares > 0 && r.FilledShares >= shares
+}
+
+// BatchItem is the result of one order of a /trade/batch request. Items that
+// failed carry Error, and usually StatusCode and ErrorCode.
+type BatchItem struct {
+	OrderResult
+	Error      string `json:"error,omitempty"`
+	ErrorCode  string `json:"error_code,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+// Err returns the item's failure as an *APIError, or nil if it was accepted.
+// Failed items without a status code count as venue rejections (400).
+func (i BatchItem) Err() error {
+	if i.Error == "" && i.ErrorCode == "" && i.StatusCode < 300 {
+		return nil
+	}
+	status := i.StatusCode
+	if status < 300 {
+		status = 400
+	}
+	return &APIError{
+		StatusCode: status,
+		Code:       i.ErrorCode,
+		Message:    i.Error,
+		Body: map[string]interface{}{
+			"error":       i.Error,
+			"error_code":  i.ErrorCode,
+			"status_code": i.StatusCode,
+		},
+	}
+}
+
+// PlaceOrder places one order
+func (c *Client) PlaceOrder(ctx context.Context, order OrderRequest) (*OrderResult, error) {
+	var result OrderResult
+	if err := c.post(ctx, "/trade", order, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PlaceOrders places several orders in one request and returns one item per
+// order, in the same order. An *APIError with status 404 or 405 means the
+// service does not support batches and nothing was placed.
+func (c *Client) PlaceOrders(ctx context.Context, orders []OrderRequest) ([]BatchItem, error) {
+	var response struct {
+		Results []BatchItem `json:"results"`
+	}
+	if err := c.post(ctx, "/trade/batch", map[string]interface{}{"orders": orders}, &response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// AmendRequest is the body of POST /trade/amend: the order to replace and
+// the terms of its replacement
+type AmendRequest struct {
+	OrderRequest
+	OrderID string `json:"order_id"`
+}
+
+// AmendOrder replaces a resting order in one request. An *APIError with
+// status 404 or 405 means the service does not support amending and nothing
+// was changed.
+func (c *Client) AmendOrder(ctx context.Context, req AmendRequest) (*OrderResult, error) {
+	var result OrderResult
+	if err := c.post(ctx, "/trade/amend", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CancelOrder cancels one order by its venue order ID
+func (c *Client) CancelOrder(ctx context.Context, accountID, orderID string, confirm bool) error {
+	return c.post(ctx, "/cancel", map[string]interface{}{
+		"account_id": accountID,
+		"order_id":   orderID,
+		"confirm":    confirm,
+	}, nil)
+}
+
+// CloseAll asks the service to close every position on the account
+func (c *Client) CloseAll(ctx context.Context, accountID string, confirm bool) error {
+	path := fmt.Sprintf("/accounts/%s/close-all?confirm=%t", url.PathEscape(accountID), confirm)
+	return c.post(ctx, path, nil, nil)
+}
+
+// ConvertRequest converts NO shares across the markets of a negative-risk
+// group into collateral plus YES shares on the remaining markets
+type ConvertRequest struct {
+	AccountID       string   `json:"account_id"`
+	NegRiskMarketID string   `json:"neg_risk_market_id"`
+	MarketIDs       []string `json:"market_ids"`
+	Shares          float64  `json:"shares"`
+	Confirm         bool     `json:"confirm"`
+}
+
+// ConvertPositions runs a neg-risk conversion
+func (c *Client) ConvertPositions(ctx context.Context, req ConvertRequest) error {
+	return c.post(ctx, "/neg-risk/convert", req, nil)
+}
+
+// OpenOrders returns the venue's order list of an account, up to 200 orders
+func (c *Client) OpenOrders(ctx context.Context, accountID string) ([]map[string]interface{}, error) {
+	var orders []map[string]interface{}
+	err := c.get(ctx, fmt.Sprintf("/orders/%s?limit=200", url.PathEscape(accountID)), &orders)
+	return orders, err
+}
+
+// Positions returns the venue's positions of an account
+func (c *Client) Positions(ctx context.Context, accountID string) ([]map[string]interface{}, error) {
+	var positions []map[string]interface{}
+	err := c.get(ctx, "/positions/"+url.PathEscape(accountID), &positions)
+	return positions, err
+}
+
+// Market returns the venue's metadata of one market
+func (c *Client) Market(ctx context.Context, marketID string) (map[string]interface{}, error) {
+	var market map[string]interface{}
+	err := c.get(ctx, "/markets/"+url.PathEscape(marketID), &market)
+	return market, err
+}
+
+// OrderBook returns the venue's order book of one market
+func (c *Client) OrderBook(ctx context.Context, marketID string) (map[string]interface{}, error) {
+	var book map[string]interface{}
+	err := c.get(ctx, "/markets/"+url.PathEscape(marketID)+"/orderbook", &book)
+	return book, err
+}
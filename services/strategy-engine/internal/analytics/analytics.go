@@ -0,0 +1,50 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Analytics computes strategy performance from the realized PnL ledger
+type Analytics struct {
+	storage *storage.PostgresStorage
+}
+
+func New(storage *storage.PostgresStorage) *Analytics {
+	return &Analytics{storage: storage}
+}
+
+// Performance returns a strategy's rolling performance over the trailing window
+func (a *Analytics) Performance(ctx context.Context, strategy string, window time.Duration) (types.StrategyPerformance, error) {
+	return a.storage.GetStrategyPerformance(ctx, strategy, time.Now().UTC().Add(-window))
+}
+
+// AutoDisablePolicy moves strategies to shadow mode when their rolling
+// performance falls below thresholds. Restoring live trading is left to an
+// operator; the policy never re-enables a strategy on its own.
+type AutoDisablePolicy struct {
+	Window     time.Duration
+	MinPnL     float64
+	MinHitRate float64
+	MinTrades  int // below this sample size the strategy is not judged
+}
+
+// Evaluate returns the reason a strategy breaches the policy, or "" if it does not
+func (p AutoDisablePolicy) Evaluate(perf types.StrategyPerformance) string {
+	if perf.Trades < p.MinTrades || perf.Trades == 0 {
+		return ""
+	}
+
+	if perf.PnL < p.MinPnL {
+		return fmt.Sprintf("rolling %s PnL %.2f below %.2f", p.Window, perf.PnL, p.MinPnL)
+	}
+	if perf.HitRate < p.MinHitRate {
+		return fmt.Sprintf("rolling %s hit rate %.2f below %.2f", p.Window, perf.HitRate, p.MinHitRate)
+	}
+
+	return ""
+}
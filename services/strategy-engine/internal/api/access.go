@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Access holds the bearer tokens of the admin API. The admin token reaches
+// every tenant; a tenant token only that tenant's strategies, orders,
+// attribution and trading accounts, and none of the engine-wide operations. With no token set the
+// API is open, as before tenants existed.
+type Access struct {
+	AdminToken   string
+	TenantTokens map[string]string // tenant -> token
+}
+
+func (a Access) enabled() bool {
+	return a.AdminToken != "" || len(a.TenantTokens) > 0
+}
+
+type tenantKey struct{}
+
+// SetAccess enables bearer token authentication of the admin API
+func (s *Server) SetAccess(access Access) {
+	s.access = access
+}
+
+// authenticate resolves the caller's bearer token to a tenant ("" for
+// operators of every tenant). /health and /readyz stay open.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.access.enabled() || r.URL.Path == "/health" || r.URL.Path == "/readyz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, errors.New("bearer token required"))
+			return
+		}
+
+		if s.access.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.access.AdminToken)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for tenant, tenantToken := range s.access.TenantTokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(tenantToken)) == 1 {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant)))
+				return
+			}
+		}
+
+		log.Warn().Str("path", r.URL.Path).Str("remote", r.RemoteAddr).Msg("Admin API: unknown token")
+		writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+	})
+}
+
+// callerTenant returns the tenant the caller is scoped to, or "" for operators
+// of every tenant
+func callerTenant(r *http.Request) string {
+	tenant, _ := r.Context().Value(tenantKey{}).(string)
+	return tenant
+}
+
+// operatorOnly refuses engine-wide operations to tenant-scoped callers
+func operatorOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tenant := callerTenant(r); tenant != "" {
+			writeError(w, http.StatusForbidden, fmt.Errorf("tenant %s may not use %s", tenant, r.URL.Path))
+			return
+		}
+		h(w, r)
+	}
+}
+
+// strategyScoped lets tenant-scoped callers reach only their own strategies;
+// others' look as if they did not exist
+func (s *Server) strategyScoped(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.ownsStrategy(w, r, r.PathValue("name")) {
+			return
+		}
+		h(w, r)
+	}
+}
+
+// ownsStrategy reports whether the caller may act on the named strategy,
+// writing the error response if not
+func (s *Server) ownsStrategy(w http.ResponseWriter, r *http.Request, name string) bool {
+	tenant := callerTenant(r)
+	if tenant == "" {
+		return true
+	}
+
+	owner, err := s.engine.StrategyTenant(r.Context(), name)
+	if err == nil && owner != tenant {
+		err = fmt.Errorf("%w: %s", engine.ErrStrategyNotFound, name)
+	}
+	if err != nil {
+		writeVersionError(w, err)
+		return false
+	}
+	return true
+}
+
+// ownStrategyOnly lets tenant-scoped callers use an account-level action
+// (positions, flatten) only through one of their own strategies
+func (s *Server) ownStrategyOnly(w http.ResponseWriter, r *http.Request, name string) bool {
+	if callerTenant(r) == "" {
+		return true
+	}
+	if name == "" {
+		writeError(w, http.StatusForbidden, errors.New("tenant callers must name one of their strategies"))
+		return false
+	}
+	return s.ownsStrategy(w, r, name)
+}
+
+// visibleTo reports whether records of a tenant are visible to the caller
+func visibleTo(r *http.Request, tenant string) bool {
+	caller := callerTenant(r)
+	if caller == "" {
+		return true
+	}
+	if tenant == "" {
+		tenant = engine.DefaultTenant
+	}
+	return tenant == caller
+}
+
+// visibleStrategies filters strategies to the caller's tenant
+func visibleStrategies(r *http.Request, strategies []types.Strategy) []types.Strategy {
+	visible := strategies[:0]
+	for _, strategy := range strategies {
+		if visibleTo(r, strategy.Tenant) {
+			visible = append(visible, strategy)
+		}
+	}
+	return visible
+}
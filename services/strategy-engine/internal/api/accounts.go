@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// accountRequest is the body of PUT /admin/accounts/{platform}/{account_id}.
+// Enabled defaults to true.
+type accountRequest struct {
+	Name           string   `json:"name"`
+	Enabled        *bool    `json:"enabled"`
+	MaxOrderShares float64  `json:"max_order_shares"`
+	AllowedMarkets []string `json:"allowed_markets"`
+	Note           string   `json:"note"`
+	Operator       string   `json:"operator"`
+}
+
+func (s *Server) handleListAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts := s.engine.TradingAccounts()
+	visible := accounts[:0]
+	for _, account := range accounts {
+		if visibleTo(r, s.engine.AccountTenant(account.AccountID)) {
+			visible = append(visible, account)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"accounts": visible})
+}
+
+func (s *Server) handlePutAccount(w http.ResponseWriter, r *http.Request) {
+	var req accountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Operator == "" {
+		writeError(w, http.StatusBadRequest, errors.New("operator is required"))
+		return
+	}
+
+	account := types.TradingAccount{
+		Platform:       r.PathValue("platform"),
+		AccountID:      r.PathValue("account_id"),
+		Name:           req.Name,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+		MaxOrderShares: req.MaxOrderShares,
+		AllowedMarkets: req.AllowedMarkets,
+		Note:           req.Note,
+		UpdatedBy:      req.Operator,
+	}
+
+	log.Info().
+		Str("operator", req.Operator).
+		Str("platform", account.Platform).
+		Str("account_id", account.AccountID).
+		Msg("Admin: trading account update requested")
+
+	stored, err := s.engine.PutTradingAccount(r.Context(), account)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (s *Server) handleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	platform, accountID := r.PathValue("platform"), r.PathValue("account_id")
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Str("platform", platform).
+		Str("account_id", accountID).
+		Msg("Admin: trading account deletion requested")
+
+	if err := s.engine.DeleteTradingAccount(r.Context(), platform, accountID); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": accountID, "platform": platform})
+}
+
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, engine.ErrInvalidAccount):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, storage.ErrAccountNotFound):
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"gopkg.in/yaml.v3"
+)
+
+// maxImportSize bounds the YAML body of POST /admin/strategies/import
+const maxImportSize = 10 << 20
+
+// handleExportStrategies writes every strategy as a YAML bundle
+func (s *Server) handleExportStrategies(w http.ResponseWriter, r *http.Request) {
+	bundle, err := s.engine.ExportStrategies(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(bundle); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// handleImportStrategies applies a YAML bundle. The body is the bundle, so
+// operator, reason and dry_run are query parameters.
+func (s *Server) handleImportStrategies(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := engine.ImportRequest{Operator: query.Get("operator"), Reason: query.Get("reason")}
+	if req.Reason == "" || req.Operator == "" {
+		writeError(w, http.StatusBadRequest, errors.New("reason and operator are required"))
+		return
+	}
+	if raw := query.Get("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("dry_run must be true or false"))
+			return
+		}
+		req.DryRun = dryRun
+	}
+
+	decoder := yaml.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportSize))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&req.Bundle); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid YAML body: "+err.Error()))
+		return
+	}
+
+	result, err := s.engine.ImportStrategies(r.Context(), req)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
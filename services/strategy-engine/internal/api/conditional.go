@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// handleConditionalOrders lists the orders held until their trigger fires
+// and the resting orders of one-cancels-other groups, with ?strategy=
+func (s *Server) handleConditionalOrders(w http.ResponseWriter, r *http.Request) {
+	strategy := r.URL.Query().Get("strategy")
+
+	commands := []types.ConditionalCommand{}
+	for _, c := range s.engine.ConditionalCommands() {
+		tenant, _ := c.Command.Metadata["tenant"].(string)
+		if (strategy == "" || c.Strategy == strategy) && visibleTo(r, tenant) {
+			commands = append(commands, c)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"conditional": commands})
+}
+
+// handleDeleteConditionalOrder drops a held order, or stops watching a group
+// order without cancelling it
+func (s *Server) handleDeleteConditionalOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Int64("id", id).
+		Msg("Admin: conditional order deletion requested")
+
+	if err := s.engine.DeleteConditionalCommand(r.Context(), id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, engine.ErrConditionalNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": id})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultExplainLimit is used when GET /admin/strategies/{name}/explain has
+// no ?limit=
+const defaultExplainLimit = 50
+
+func (s *Server) handleExplanations(w http.ResponseWriter, r *http.Request) {
+	limit := defaultExplainLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	name := r.PathValue("name")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"strategy":     name,
+		"explanations": s.engine.Explanations(name, limit),
+	})
+}
+
+// handleSetExplain switches explain mode of a strategy until the engine
+// restarts; config "explain" keeps it on across restarts
+func (s *Server) handleSetExplain(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+
+	name := r.PathValue("name")
+	log.Info().Str("strategy", name).Bool("enabled", req.Enabled).Msg("Admin: explain mode changed")
+	s.engine.SetExplain(name, req.Enabled)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"strategy": name, "explain": req.Enabled})
+}
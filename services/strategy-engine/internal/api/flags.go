@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// flagRequest is the body of PUT /admin/flags/{name}. Percent defaults to
+// 100, every market.
+type flagRequest struct {
+	Enabled      bool     `json:"enabled"`
+	Percent      *float64 `json:"percent"`
+	Environments []string `json:"environments"`
+	Description  string   `json:"description"`
+	Operator     string   `json:"operator"`
+}
+
+func (s *Server) handleListFlags(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"environment": s.engine.Flags().Environment(),
+		"flags":       s.engine.FeatureFlags(),
+	})
+}
+
+// handleCheckFlag answers whether a flag is on for the market in the query,
+// as the engine would decide
+func (s *Server) handleCheckFlag(w http.ResponseWriter, r *http.Request) {
+	name, market := r.PathValue("name"), r.URL.Query().Get("market")
+	flag, ok := s.engine.Flags().Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, storage.ErrFeatureFlagNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"flag":        flag,
+		"market":      market,
+		"environment": s.engine.Flags().Environment(),
+		"on":          s.engine.FeatureEnabled(name, market),
+	})
+}
+
+func (s *Server) handlePutFlag(w http.ResponseWriter, r *http.Request) {
+	var req flagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Operator == "" {
+		writeError(w, http.StatusBadRequest, errors.New("operator is required"))
+		return
+	}
+
+	flag := types.FeatureFlag{
+		Name:         r.PathValue("name"),
+		Enabled:      req.Enabled,
+		Percent:      100,
+		Environments: req.Environments,
+		Description:  req.Description,
+		UpdatedBy:    req.Operator,
+	}
+	if req.Percent != nil {
+		flag.Percent = *req.Percent
+	}
+
+	log.Info().
+		Str("operator", req.Operator).
+		Str("flag", flag.Name).
+		Bool("enabled", flag.Enabled).
+		Float64("percent", flag.Percent).
+		Msg("Admin: feature flag update requested")
+
+	stored, err := s.engine.PutFeatureFlag(r.Context(), flag)
+	if err != nil {
+		writeFlagError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stored)
+}
+
+func (s *Server) handleDeleteFlag(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Str("flag", name).
+		Msg("Admin: feature flag deletion requested")
+
+	if err := s.engine.DeleteFeatureFlag(r.Context(), name); err != nil {
+		writeFlagError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": name})
+}
+
+func writeFlagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, engine.ErrInvalidFlag):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, storage.ErrFeatureFlagNotFound):
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
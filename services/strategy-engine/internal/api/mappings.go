@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Defaults of GET /admin/mappings/suggestions
+const (
+	defaultSuggestionScore = 0.6
+	defaultSuggestionLimit = 50
+)
+
+// mappingRequest is the body of POST /admin/mappings and
+// PUT /admin/mappings/{id}
+type mappingRequest struct {
+	PredictMarketID       string `json:"predict_market_id"`
+	PolymarketConditionID string `json:"polymarket_condition_id"`
+	Inverted              bool   `json:"inverted"`
+	Note                  string `json:"note"`
+	Operator              string `json:"operator"`
+}
+
+func decodeMappingRequest(r *http.Request) (types.MarketMapping, error) {
+	var req mappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return types.MarketMapping{}, errors.New("invalid JSON body")
+	}
+	if req.Operator == "" {
+		return types.MarketMapping{}, errors.New("operator is required")
+	}
+	return types.MarketMapping{
+		PredictMarketID:       req.PredictMarketID,
+		PolymarketConditionID: req.PolymarketConditionID,
+		Inverted:              req.Inverted,
+		Note:                  req.Note,
+		CreatedBy:             req.Operator,
+	}, nil
+}
+
+func (s *Server) handleListMappings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"mappings": s.engine.MarketMappings()})
+}
+
+func (s *Server) handleCreateMapping(w http.ResponseWriter, r *http.Request) {
+	mapping, err := decodeMappingRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := s.engine.CreateMarketMapping(r.Context(), mapping)
+	if err != nil {
+		writeMappingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) handleUpdateMapping(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+	mapping, err := decodeMappingRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	mapping.ID = id
+
+	log.Info().Str("operator", mapping.CreatedBy).Int64("id", id).Msg("Admin: market mapping update requested")
+
+	updated, err := s.engine.UpdateMarketMapping(r.Context(), mapping)
+	if err != nil {
+		writeMappingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *Server) handleDeleteMapping(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("id must be an integer"))
+		return
+	}
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Int64("id", id).
+		Msg("Admin: market mapping deletion requested")
+
+	if err := s.engine.DeleteMarketMapping(r.Context(), id); err != nil {
+		writeMappingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": id})
+}
+
+// handleSuggestMappings proposes pairs of unmapped markets with similar
+// questions, with ?min_score= (0 to 1) and ?limit=
+func (s *Server) handleSuggestMappings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	minScore := defaultSuggestionScore
+	if raw := query.Get("min_score"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			writeError(w, http.StatusBadRequest, errors.New("min_score must be between 0 and 1"))
+			return
+		}
+		minScore = parsed
+	}
+
+	limit := defaultSuggestionLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"suggestions": s.engine.SuggestMarketMappings(minScore, limit),
+	})
+}
+
+func writeMappingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, engine.ErrInvalidMapping):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, storage.ErrMappingNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, storage.ErrMappingConflict):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultOrdersWindow is used when GET /admin/orders has no ?since=
+	defaultOrdersWindow = 5 * time.Minute
+
+	defaultFailedCommandsLimit = 100
+)
+
+// strategySummary is one entry of GET /admin/strategies
+type strategySummary struct {
+	Name         string    `json:"name"`
+	Tenant       string    `json:"tenant"`
+	Type         string    `json:"type"`
+	Enabled      bool      `json:"enabled"`
+	Shadow       bool      `json:"shadow"`
+	ShadowReason string    `json:"shadow_reason,omitempty"`
+	DryRun       bool      `json:"dry_run"`
+	Priority     int       `json:"priority"`
+	Version      int       `json:"version"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// encryptRequest is the body of POST /admin/config/encrypt
+type encryptRequest struct {
+	Value string `json:"value"`
+}
+
+// retryCommandsRequest is the body of POST /admin/outbox/retry
+type retryCommandsRequest struct {
+	IDs      []int64   `json:"ids"`
+	Since    time.Time `json:"since"`
+	Reason   string    `json:"reason"`
+	Operator string    `json:"operator"`
+}
+
+func (s *Server) handleListStrategies(w http.ResponseWriter, r *http.Request) {
+	strategies, err := s.engine.Strategies(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	strategies = visibleStrategies(r, strategies)
+
+	summaries := make([]strategySummary, len(strategies))
+	for i, strategy := range strategies {
+		summaries[i] = strategySummary{
+			Name:         strategy.Name,
+			Tenant:       strategy.Tenant,
+			Type:         strategy.Type,
+			Enabled:      strategy.Active,
+			Shadow:       strategy.Shadow,
+			ShadowReason: strategy.ShadowReason,
+			DryRun:       strategy.DryRun,
+			Priority:     strategy.Priority,
+			Version:      strategy.Version,
+			UpdatedAt:    strategy.UpdatedAt,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"strategies": summaries})
+}
+
+func (s *Server) handleEnableStrategy(w http.ResponseWriter, r *http.Request) {
+	s.setStrategyEnabled(w, r, true)
+}
+
+func (s *Server) handleDisableStrategy(w http.ResponseWriter, r *http.Request) {
+	s.setStrategyEnabled(w, r, false)
+}
+
+func (s *Server) setStrategyEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := r.PathValue("name")
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Str("strategy", name).
+		Bool("enabled", enabled).
+		Msg("Admin: strategy state change requested")
+
+	if err := s.engine.SetStrategyEnabled(r.Context(), name, enabled, req.Operator, req.Reason); err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"strategy": name,
+		"enabled":  enabled,
+	})
+}
+
+func (s *Server) handleEnablePair(w http.ResponseWriter, r *http.Request) {
+	s.setPairEnabled(w, r, true)
+}
+
+func (s *Server) handleDisablePair(w http.ResponseWriter, r *http.Request) {
+	s.setPairEnabled(w, r, false)
+}
+
+func (s *Server) setPairEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name, primary := r.PathValue("name"), r.PathValue("primary")
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Str("strategy", name).
+		Str("primary", primary).
+		Bool("enabled", enabled).
+		Msg("Admin: strategy pair change requested")
+
+	if err := s.engine.SetPairEnabled(r.Context(), name, primary, enabled, req.Operator, req.Reason); err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"strategy": name,
+		"primary":  primary,
+		"enabled":  enabled,
+	})
+}
+
+func (s *Server) handlePositions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if !s.ownStrategyOnly(w, r, query.Get("strategy")) {
+		return
+	}
+	positions, err := s.engine.Positions(r.Context(), query.Get("strategy"), query.Get("platform"), query.Get("account_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"accounts": positions})
+}
+
+func (s *Server) handleResyncPositions(w http.ResponseWriter, r *http.Request) {
+	var req engine.ResyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Reason == "" || req.Operator == "" {
+		writeError(w, http.StatusBadRequest, errors.New("reason and operator are required"))
+		return
+	}
+
+	positions, err := s.engine.ResyncPositions(r.Context(), req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"accounts": positions})
+}
+
+func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().Add(-defaultOrdersWindow)
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("since must be an RFC3339 time"))
+			return
+		}
+		since = parsed
+	}
+
+	records, err := s.engine.Journal(r.Context(), since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	visible := records[:0]
+	for _, record := range records {
+		if visibleTo(r, record.Tenant) {
+			visible = append(visible, record)
+		}
+	}
+	records = visible
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"orders": records})
+}
+
+func (s *Server) handleFailedCommands(w http.ResponseWriter, r *http.Request) {
+	limit := defaultFailedCommandsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := s.engine.FailedCommands(r.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"commands": entries})
+}
+
+func (s *Server) handleLag(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.engine.Lag(r.Context()))
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.engine.Stats(r.Context()))
+}
+
+// handleMetrics serves the engine's and strategies' metrics to Prometheus
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.engine.WriteMetrics(w); err != nil {
+		log.Error().Err(err).Msg("Failed to write metrics")
+	}
+}
+
+func (s *Server) handleReconciliation(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.engine.Reconciliation(r.Context()))
+}
+
+func (s *Server) handleEncryptConfigValue(w http.ResponseWriter, r *http.Request) {
+	var req encryptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Value == "" {
+		writeError(w, http.StatusBadRequest, errors.New("value is required"))
+		return
+	}
+
+	encrypted, err := s.engine.EncryptConfigValue(req.Value)
+	switch {
+	case errors.Is(err, secrets.ErrNoKeyring):
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"encrypted": encrypted})
+}
+
+func (s *Server) handleRetryCommands(w http.ResponseWriter, r *http.Request) {
+	var req retryCommandsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Reason == "" || req.Operator == "" {
+		writeError(w, http.StatusBadRequest, errors.New("reason and operator are required"))
+		return
+	}
+
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Int("ids", len(req.IDs)).
+		Time("since", req.Since).
+		Msg("Admin: failed commands retry requested")
+
+	n, err := s.engine.RetryFailedCommands(r.Context(), req.IDs, req.Since)
+	switch {
+	case errors.Is(err, engine.ErrRetryUnscoped):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, engine.ErrHalted):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"retried": n})
+}
+
+func (s *Server) handleInjectEvent(w http.ResponseWriter, r *http.Request) {
+	var req engine.InjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Reason == "" || req.Operator == "" {
+		writeError(w, http.StatusBadRequest, errors.New("reason and operator are required"))
+		return
+	}
+
+	result, err := s.engine.InjectEvent(r.Context(), req)
+	switch {
+	case errors.Is(err, engine.ErrNotStarted):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
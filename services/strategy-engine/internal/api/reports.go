@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultDailyReportsLimit is how many daily reports are listed by default
+const defaultDailyReportsLimit = 30
+
+// handleListDailyReports lists the latest daily reports, newest first
+func (s *Server) handleListDailyReports(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDailyReportsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	reports, err := s.engine.DailyReports(r.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reports": reports})
+}
+
+// handleGetDailyReport returns the stored report of one day
+func (s *Server) handleGetDailyReport(w http.ResponseWriter, r *http.Request) {
+	day, ok := reportDay(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := s.engine.DailyReport(r.Context(), day.Format(time.DateOnly))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if report == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no daily report for %s", day.Format(time.DateOnly)))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleGenerateDailyReport (re)generates and stores the report of one day,
+// without sending it
+func (s *Server) handleGenerateDailyReport(w http.ResponseWriter, r *http.Request) {
+	day, ok := reportDay(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := s.engine.GenerateDailyReport(r.Context(), day)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// reportDay parses the {day} path value, a UTC date not in the future
+func reportDay(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	day, err := time.Parse(time.DateOnly, r.PathValue("day"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("day must be a date such as 2024-01-31"))
+		return time.Time{}, false
+	}
+	if day.After(time.Now().UTC()) {
+		writeError(w, http.StatusBadRequest, errors.New("day is in the future"))
+		return time.Time{}, false
+	}
+	return day, true
+}
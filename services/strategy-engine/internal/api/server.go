@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/config"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/incident"
+	"github.com/rs/zerolog/log"
+)
+
+// Reloader re-applies the runtime-reloadable config settings
+type Reloader interface {
+	Reload() (*config.Changes, error)
+}
+
+// Server is the engine's HTTP admin API
+type Server struct {
+	engine     *engine.Engine
+	incidents  *incident.Manager
+	reloader   Reloader
+	access     Access
+	mux        *http.ServeMux
+	httpServer *http.Server
+}
+
+func NewServer(addr string, engine *engine.Engine, incidents *incident.Manager) *Server {
+	s := &Server{
+		engine:    engine,
+		incidents: incidents,
+		mux:       http.NewServeMux(),
+	}
+
+	s.routes()
+
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           s.authenticate(s.mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /readyz", s.handleReady)
+	s.mux.HandleFunc("GET /stats", operatorOnly(s.handleStats))
+	s.mux.HandleFunc("GET /metrics", operatorOnly(s.handleMetrics))
+	s.mux.HandleFunc("POST /admin/halt", operatorOnly(s.handleHalt))
+	s.mux.HandleFunc("POST /admin/resume", operatorOnly(s.handleResume))
+	s.mux.HandleFunc("POST /admin/incident", operatorOnly(s.handleIncident))
+	s.mux.HandleFunc("POST /admin/reload", operatorOnly(s.handleReload))
+	s.mux.HandleFunc("POST /admin/config/encrypt", operatorOnly(s.handleEncryptConfigValue))
+	s.mux.HandleFunc("GET /admin/attribution", s.handleAttribution)
+	s.mux.HandleFunc("GET /admin/reports/daily", operatorOnly(s.handleListDailyReports))
+	s.mux.HandleFunc("GET /admin/reports/daily/{day}", operatorOnly(s.handleGetDailyReport))
+	s.mux.HandleFunc("POST /admin/reports/daily/{day}", operatorOnly(s.handleGenerateDailyReport))
+	s.mux.HandleFunc("POST /admin/flatten", s.handleFlatten)
+	s.mux.HandleFunc("GET /admin/strategies", s.handleListStrategies)
+	s.mux.HandleFunc("GET /admin/strategies/export", operatorOnly(s.handleExportStrategies))
+	s.mux.HandleFunc("POST /admin/strategies/import", operatorOnly(s.handleImportStrategies))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/enable", s.strategyScoped(s.handleEnableStrategy))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/disable", s.strategyScoped(s.handleDisableStrategy))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/pairs/{primary}/enable", s.strategyScoped(s.handleEnablePair))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/pairs/{primary}/disable", s.strategyScoped(s.handleDisablePair))
+	s.mux.HandleFunc("GET /admin/mappings", operatorOnly(s.handleListMappings))
+	s.mux.HandleFunc("GET /admin/mappings/suggestions", operatorOnly(s.handleSuggestMappings))
+	s.mux.HandleFunc("POST /admin/mappings", operatorOnly(s.handleCreateMapping))
+	s.mux.HandleFunc("PUT /admin/mappings/{id}", operatorOnly(s.handleUpdateMapping))
+	s.mux.HandleFunc("DELETE /admin/mappings/{id}", operatorOnly(s.handleDeleteMapping))
+	s.mux.HandleFunc("GET /admin/accounts", s.handleListAccounts)
+	s.mux.HandleFunc("PUT /admin/accounts/{platform}/{account_id}", operatorOnly(s.handlePutAccount))
+	s.mux.HandleFunc("DELETE /admin/accounts/{platform}/{account_id}", operatorOnly(s.handleDeleteAccount))
+	s.mux.HandleFunc("GET /admin/flags", s.handleListFlags)
+	s.mux.HandleFunc("GET /admin/flags/{name}", s.handleCheckFlag)
+	s.mux.HandleFunc("PUT /admin/flags/{name}", operatorOnly(s.handlePutFlag))
+	s.mux.HandleFunc("DELETE /admin/flags/{name}", operatorOnly(s.handleDeleteFlag))
+	s.mux.HandleFunc("GET /admin/positions", s.handlePositions)
+	s.mux.HandleFunc("POST /admin/positions/resync", operatorOnly(s.handleResyncPositions))
+	s.mux.HandleFunc("GET /admin/orders", s.handleOrders)
+	s.mux.HandleFunc("GET /admin/orders/reconciliation", operatorOnly(s.handleReconciliation))
+	s.mux.HandleFunc("GET /admin/orders/conditional", s.handleConditionalOrders)
+	s.mux.HandleFunc("DELETE /admin/orders/conditional/{id}", operatorOnly(s.handleDeleteConditionalOrder))
+	s.mux.HandleFunc("GET /admin/outbox/failed", operatorOnly(s.handleFailedCommands))
+	s.mux.HandleFunc("POST /admin/outbox/retry", operatorOnly(s.handleRetryCommands))
+	s.mux.HandleFunc("GET /admin/lag", operatorOnly(s.handleLag))
+	s.mux.HandleFunc("GET /admin/wirelog", operatorOnly(s.handleWireLog))
+	s.mux.HandleFunc("POST /admin/events/inject", operatorOnly(s.handleInjectEvent))
+	s.mux.HandleFunc("GET /admin/strategies/{name}/versions", s.strategyScoped(s.handleListVersions))
+	s.mux.HandleFunc("GET /admin/strategies/{name}/runs", s.strategyScoped(s.handleListRuns))
+	s.mux.HandleFunc("GET /admin/strategies/{name}/experiment", s.strategyScoped(s.handleExperiment))
+	s.mux.HandleFunc("GET /admin/strategies/{name}/explain", s.strategyScoped(s.handleExplanations))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/explain", s.strategyScoped(s.handleSetExplain))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/preview", s.strategyScoped(s.handlePreviewStrategy))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/versions", s.strategyScoped(s.handleProposeVersion))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/versions/{version}/promote", s.strategyScoped(s.handlePromoteVersion))
+	s.mux.HandleFunc("POST /admin/strategies/{name}/versions/{version}/reject", s.strategyScoped(s.handleRejectVersion))
+}
+
+// SetReloader enables POST /admin/reload
+func (s *Server) SetReloader(reloader Reloader) {
+	s.reloader = reloader
+}
+
+// Start serves the API in the background
+func (s *Server) Start() {
+	go func() {
+		log.Info().Str("addr", s.httpServer.Addr).Msg("Admin API listening")
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Admin API failed")
+		}
+	}()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	halted, reason := s.engine.Halted()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":      "ok",
+		"halted":      halted,
+		"halt_reason": reason,
+	})
+}
+
+// handleReady answers 503 while the engine cannot consume events, e.g. while
+// it is reconnecting to Redis
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	bus := s.engine.EventBusHealth()
+	status := http.StatusOK
+	if !bus.Connected {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"ready":     bus.Connected,
+		"event_bus": bus,
+	})
+}
+
+// operatorRequest is the body of audited admin actions
+type operatorRequest struct {
+	Reason   string `json:"reason"`
+	Operator string `json:"operator"`
+}
+
+func decodeOperatorRequest(r *http.Request) (operatorRequest, error) {
+	var req operatorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, errors.New("invalid JSON body")
+	}
+	if req.Reason == "" || req.Operator == "" {
+		return req, errors.New("reason and operator are required")
+	}
+	return req, nil
+}
+
+func (s *Server) handleHalt(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Warn().Str("operator", req.Operator).Str("reason", req.Reason).Msg("Admin: halt requested")
+	s.engine.Halt(req.Reason)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"halted": true})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Warn().Str("operator", req.Operator).Str("reason", req.Reason).Msg("Admin: resume requested")
+	s.engine.Resume()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"halted": false})
+}
+
+func (s *Server) handleIncident(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	summary, err := s.incidents.Trigger(r.Context(), req.Reason, req.Operator)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.reloader == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("config reload is not enabled"))
+		return
+	}
+
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Info().Str("operator", req.Operator).Str("reason", req.Reason).Msg("Admin: config reload requested")
+
+	changes, err := s.reloader.Reload()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, changes)
+}
+
+func (s *Server) handleFlatten(w http.ResponseWriter, r *http.Request) {
+	var req engine.FlattenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Reason == "" || req.Operator == "" {
+		writeError(w, http.StatusBadRequest, errors.New("reason and operator are required"))
+		return
+	}
+
+	if !s.ownStrategyOnly(w, r, req.Strategy) {
+		return
+	}
+
+	result, err := s.engine.Flatten(r.Context(), req)
+	if errors.Is(err, engine.ErrFlattenUnpriced) {
+		// Without a book from the account service nothing could be closed
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// defaultAttributionWindow is used when ?window= is not given
+const defaultAttributionWindow = 24 * time.Hour
+
+func (s *Server) handleAttribution(w http.ResponseWriter, r *http.Request) {
+	window := defaultAttributionWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("window must be a positive duration such as 24h"))
+			return
+		}
+		window = parsed
+	}
+
+	attribution, err := s.engine.Attribution(r.Context(), window)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	visible := attribution[:0]
+	for _, a := range attribution {
+		if visibleTo(r, a.Tenant) {
+			visible = append(visible, a)
+		}
+	}
+	attribution = visible
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"window":     window.String(),
+		"strategies": attribution,
+	})
+}
+
+func (s *Server) handleExperiment(w http.ResponseWriter, r *http.Request) {
+	window := defaultAttributionWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("window must be a positive duration such as 24h"))
+			return
+		}
+		window = parsed
+	}
+
+	report, err := s.engine.Experiment(r.Context(), r.PathValue("name"), window)
+	switch {
+	case errors.Is(err, engine.ErrNoExperiment):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// proposeVersionRequest is the body of POST /admin/strategies/{name}/versions
+type proposeVersionRequest struct {
+	Config   map[string]interface{} `json:"config"`
+	Note     string                 `json:"note"`
+	Operator string                 `json:"operator"`
+}
+
+func (s *Server) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	strategy, versions, err := s.engine.StrategyVersions(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"strategy":     strategy.Name,
+		"live_version": strategy.Version,
+		"versions":     versions,
+	})
+}
+
+// defaultRunsLimit is used when GET /admin/strategies/{name}/runs has no ?limit=
+const defaultRunsLimit = 50
+
+// handleListRuns lists the activation periods of a strategy, or with ?at= the
+// one that was live at that time
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	query := r.URL.Query()
+
+	if raw := query.Get("at"); raw != "" {
+		at, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("at must be an RFC3339 time"))
+			return
+		}
+		run, err := s.engine.StrategyRunAt(r.Context(), name, at)
+		if err != nil {
+			writeVersionError(w, err)
+			return
+		}
+		if run == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("strategy %s was not enabled at %s", name, raw))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"strategy": name, "run": run})
+		return
+	}
+
+	limit := defaultRunsLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	strategy, runs, err := s.engine.StrategyRuns(r.Context(), name, limit)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"strategy": strategy.Name,
+		"runs":     runs,
+	})
+}
+
+func (s *Server) handleProposeVersion(w http.ResponseWriter, r *http.Request) {
+	var req proposeVersionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Config == nil || req.Operator == "" {
+		writeError(w, http.StatusBadRequest, errors.New("config and operator are required"))
+		return
+	}
+
+	name := r.PathValue("name")
+	log.Info().Str("operator", req.Operator).Str("strategy", name).Msg("Admin: strategy version proposed")
+
+	version, err := s.engine.ProposeVersion(r.Context(), name, req.Config, req.Operator, req.Note)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"version":     version,
+		"shadow_name": engine.CandidateName(name, version.Version),
+	})
+}
+
+// handlePreviewStrategy returns the commands a strategy would emit for an
+// event, without executing them
+func (s *Server) handlePreviewStrategy(w http.ResponseWriter, r *http.Request) {
+	var req engine.PreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+	if req.Event.Type == "" {
+		writeError(w, http.StatusBadRequest, errors.New("event type is required"))
+		return
+	}
+
+	preview, err := s.engine.PreviewStrategy(r.Context(), r.PathValue("name"), req)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
+
+func (s *Server) handlePromoteVersion(w http.ResponseWriter, r *http.Request) {
+	s.decideVersion(w, r, "promoted", s.engine.PromoteVersion)
+}
+
+func (s *Server) handleRejectVersion(w http.ResponseWriter, r *http.Request) {
+	s.decideVersion(w, r, "rejected", s.engine.RejectVersion)
+}
+
+func (s *Server) decideVersion(
+	w http.ResponseWriter,
+	r *http.Request,
+	status string,
+	decide func(ctx context.Context, name string, version int, operator, reason string) error,
+) {
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version < 1 {
+		writeError(w, http.StatusBadRequest, errors.New("version must be a positive integer"))
+		return
+	}
+
+	req, err := decodeOperatorRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := decide(r.Context(), name, version, req.Operator, req.Reason); err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"strategy": name,
+		"version":  version,
+		"status":   status,
+	})
+}
+
+func writeVersionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, engine.ErrStrategyNotFound), errors.Is(err, storage.ErrPairNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, engine.ErrInvalidVersion):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, storage.ErrVersionNotCandidate):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
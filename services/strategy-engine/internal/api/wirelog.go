@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+const (
+	// defaultWireLogWindow is used when GET /admin/wirelog has no ?since=
+	defaultWireLogWindow = time.Hour
+
+	defaultWireLogLimit = 100
+)
+
+// handleWireLog lists recorded account service requests, with ?platform=,
+// ?since=, ?until=, ?q= (a string in the URL or a body) and ?limit=
+func (s *Server) handleWireLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	until := time.Now()
+	if raw := query.Get("until"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("until must be an RFC3339 time"))
+			return
+		}
+		until = parsed
+	}
+	since := until.Add(-defaultWireLogWindow)
+	if raw := query.Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("since must be an RFC3339 time"))
+			return
+		}
+		since = parsed
+	}
+	limit := defaultWireLogLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := s.engine.WireLog(r.Context(), query.Get("platform"), since, until, query.Get("q"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if entries == nil {
+		entries = []types.WireLogEntry{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"requests": entries})
+}
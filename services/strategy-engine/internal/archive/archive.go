@@ -0,0 +1,178 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// The archiver copies the Redis event streams into the event_archive table,
+// which outlives the trimmed streams and backs `replay --source archive`.
+//
+// Every Interval it reads each stream from its cursor (the last archived entry
+// ID) and stores the new entries; on the first run it starts from whatever the
+// stream still holds. Once an hour it applies the retention and compacts the
+// streams in CompactStreams: order book entries older than CompactAfter are
+// thinned out to the last one per market and CompactBucket.
+//
+// Several engine instances may archive the same streams; duplicate entries
+// are ignored.
+
+const (
+	batchSize     = 1000
+	pruneInterval = time.Hour
+
+	// CompactBucket is the resolution compacted streams are kept at
+	CompactBucket = time.Minute
+)
+
+// CompactStreams are compacted after CompactAfter. Order book snapshots make
+// up most of the volume and only their latest state matters for a backtest.
+var CompactStreams = []string{orderbook.Stream}
+
+// Options configures an Archiver
+type Options struct {
+	Streams  []string
+	Interval time.Duration
+
+	// Retention is how long archived events are kept; StreamRetention
+	// overrides it per stream. Zero keeps events forever.
+	Retention       time.Duration
+	StreamRetention map[string]time.Duration
+
+	// CompactAfter is the age at which CompactStreams are compacted; zero
+	// disables compaction
+	CompactAfter time.Duration
+}
+
+// Archiver copies event streams to Postgres
+type Archiver struct {
+	storage  *storage.PostgresStorage
+	eventBus eventbus.EventBus
+	opts     Options
+}
+
+func NewArchiver(storage *storage.PostgresStorage, eventBus eventbus.EventBus, opts Options) *Archiver {
+	return &Archiver{
+		storage:  storage,
+		eventBus: eventBus,
+		opts:     opts,
+	}
+}
+
+// Run archives every interval and prunes every hour until ctx is cancelled
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		for _, stream := range a.opts.Streams {
+			archived, err := a.Archive(ctx, stream)
+			if err != nil {
+				log.Error().Err(err).Str("stream", stream).Msg("Failed to archive stream")
+				continue
+			}
+			if archived > 0 {
+				log.Debug().Str("stream", stream).Int("events", archived).Msg("Archived events")
+			}
+		}
+
+		if time.Since(lastPrune) >= pruneInterval {
+			a.Prune(ctx)
+			lastPrune = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Archive stores the entries added to a stream since its cursor and returns
+// how many there were
+func (a *Archiver) Archive(ctx context.Context, stream string) (int, error) {
+	cursor, err := a.storage.GetArchiveCursor(ctx, stream)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive cursor: %w", err)
+	}
+
+	start := cursor.LastID
+	if start == "" {
+		start = "-"
+	}
+
+	var archived int
+	for {
+		events, err := a.eventBus.RangeIDs(ctx, stream, start, "+", batchSize)
+		if err != nil {
+			return archived, err
+		}
+		read := len(events)
+
+		// The range includes the cursor entry itself
+		if len(events) > 0 && events[0].ID == cursor.LastID {
+			events = events[1:]
+		}
+		if len(events) == 0 {
+			return archived, nil
+		}
+
+		if err := a.storage.ArchiveEvents(ctx, stream, events); err != nil {
+			return archived, fmt.Errorf("failed to store events: %w", err)
+		}
+		archived += len(events)
+
+		if read < batchSize {
+			return archived, nil
+		}
+		cursor.LastID = events[len(events)-1].ID
+		start = cursor.LastID
+	}
+}
+
+// Prune deletes archived events past their retention and compacts old order
+// book entries
+func (a *Archiver) Prune(ctx context.Context) {
+	now := time.Now()
+
+	for _, stream := range a.opts.Streams {
+		retention, ok := a.opts.StreamRetention[stream]
+		if !ok {
+			retention = a.opts.Retention
+		}
+		if retention <= 0 {
+			continue
+		}
+
+		deleted, err := a.storage.DeleteArchivedEvents(ctx, stream, now.Add(-retention))
+		if err != nil {
+			log.Error().Err(err).Str("stream", stream).Msg("Failed to apply archive retention")
+			continue
+		}
+		if deleted > 0 {
+			log.Info().Str("stream", stream).Int64("deleted", deleted).Msg("Deleted archived events past retention")
+		}
+	}
+
+	if a.opts.CompactAfter <= 0 {
+		return
+	}
+	for _, stream := range CompactStreams {
+		compacted, err := a.storage.CompactArchivedEvents(ctx, stream, now.Add(-a.opts.CompactAfter), CompactBucket)
+		if err != nil {
+			log.Error().Err(err).Str("stream", stream).Msg("Failed to compact archived events")
+			continue
+		}
+		if compacted > 0 {
+			log.Info().Str("stream", stream).Int64("deleted", compacted).Msg("Compacted archived events")
+		}
+	}
+}
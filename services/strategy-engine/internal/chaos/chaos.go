@@ -0,0 +1,172 @@
+// Package chaos injects faults into the engine's event bus and account service
+// calls, to check on a test deployment that retries, rejection handling, the
+// outbox and idempotency hold up when things fail. It is switched on by the
+// chaos section of the config and must never run against real money.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Config sets how often each fault is injected. Rates are probabilities
+// between 0 and 1; zero leaves that fault off.
+type Config struct {
+	// DropEvents is the chance a consumed event is acknowledged without
+	// reaching the engine, as if it had been lost
+	DropEvents float64
+
+	// DelayRequests is the chance an account service request is held for a
+	// random time of up to MaxDelay before it is sent
+	DelayRequests float64
+	MaxDelay      time.Duration
+
+	// ServiceErrors is the chance an account service request is answered
+	// with a 503. With ErrorsAfterSend the request still reaches the service
+	// and only its answer is replaced, as when the service fails after
+	// acting on it; otherwise it is not sent.
+	ServiceErrors   float64
+	ErrorsAfterSend bool
+}
+
+// Stats counts the faults injected so far
+type Stats struct {
+	DroppedEvents   int64 `json:"dropped_events"`
+	DelayedRequests int64 `json:"delayed_requests"`
+	ServiceErrors   int64 `json:"service_errors"`
+}
+
+// Injector injects the faults of one Config
+type Injector struct {
+	config Config
+
+	droppedEvents   atomic.Int64
+	delayedRequests atomic.Int64
+	serviceErrors   atomic.Int64
+}
+
+// New returns an injector for config
+func New(config Config) *Injector {
+	return &Injector{config: config}
+}
+
+// Stats returns the faults injected so far
+func (i *Injector) Stats() Stats {
+	return Stats{
+		DroppedEvents:   i.droppedEvents.Load(),
+		DelayedRequests: i.delayedRequests.Load(),
+		ServiceErrors:   i.serviceErrors.Load(),
+	}
+}
+
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+// Bus wraps an event bus so that subscribers lose events at the configured
+// rate. Publishing and reading ranges are left alone.
+func (i *Injector) Bus(bus eventbus.EventBus) eventbus.EventBus {
+	if i.config.DropEvents <= 0 {
+		return bus
+	}
+	return &faultyBus{EventBus: bus, injector: i}
+}
+
+type faultyBus struct {
+	eventbus.EventBus
+	injector *Injector
+}
+
+func (b *faultyBus) Subscribe(ctx context.Context, streams []string, handler func(types.Event) error) error {
+	return b.EventBus.Subscribe(ctx, streams, func(event types.Event) error {
+		if hit(b.injector.config.DropEvents) {
+			b.injector.droppedEvents.Add(1)
+			log.Warn().
+				Str("event_id", event.ID).
+				Str("type", event.Type).
+				Msg("Chaos: event dropped")
+			return nil
+		}
+		return handler(event)
+	})
+}
+
+// Transport wraps the HTTP transport of an account service client so that
+// requests are delayed or fail at the configured rates
+func (i *Injector) Transport(next http.RoundTripper) http.RoundTripper {
+	if i.config.DelayRequests <= 0 && i.config.ServiceErrors <= 0 {
+		return next
+	}
+	return &faultyTransport{next: next, injector: i}
+}
+
+type faultyTransport struct {
+	next     http.RoundTripper
+	injector *Injector
+}
+
+func (t *faultyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	config := t.injector.config
+
+	if hit(config.DelayRequests) && config.MaxDelay > 0 {
+		delay := time.Duration(rand.Int63n(int64(config.MaxDelay)) + 1)
+		t.injector.delayedRequests.Add(1)
+		log.Warn().
+			Str("path", req.URL.Path).
+			Dur("delay", delay).
+			Msg("Chaos: account service request delayed")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if !hit(config.ServiceErrors) {
+		return t.next.RoundTrip(req)
+	}
+
+	t.injector.serviceErrors.Add(1)
+	if config.ErrorsAfterSend {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	log.Warn().
+		Str("path", req.URL.Path).
+		Bool("sent", config.ErrorsAfterSend).
+		Msg("Chaos: account service request failed with 503")
+	return serviceUnavailable(req), nil
+}
+
+// serviceUnavailable is the injected answer, in the account services' error
+// format
+func serviceUnavailable(req *http.Request) *http.Response {
+	body := []byte(`{"code":"chaos_injected","error":"service unavailable (injected fault)"}`)
+	return &http.Response{
+		Status:        "503 Service Unavailable",
+		StatusCode:    http.StatusServiceUnavailable,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
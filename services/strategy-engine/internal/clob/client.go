@@ -0,0 +1,363 @@
+// Package clob places and cancels orders directly on the Polymarket CLOB,
+// without going through the polymarket-account service. Orders are signed
+// with the account's key; requests carry the account's API credentials (L2
+// authentication). Creating API credentials is left to the account setup.
+package clob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultURL     = "https://clob.polymarket.com"
+	DefaultChainID = 137 // Polygon
+
+	defaultTimeout = 10 * time.Second
+	marketCacheTTL = time.Hour
+
+	// expirationThreshold is the margin the CLOB requires on top of the time
+	// a GTD order should live
+	expirationThreshold = time.Minute
+)
+
+// Signature types: who holds the funds the signer trades with
+const (
+	SignatureEOA        = 0 // the signer's own address
+	SignaturePolyProxy  = 1 // a Polymarket proxy wallet (Funder)
+	SignatureGnosisSafe = 2 // a Gnosis Safe (Funder)
+)
+
+// Account holds the signing key and API credentials of one trading account
+type Account struct {
+	PrivateKey    string // hex
+	Funder        string // address holding the funds, when it is not the signer
+	SignatureType int
+
+	APIKey        string
+	APISecret     string // base64
+	APIPassphrase string
+}
+
+// Options configures a Client. Accounts are keyed by the account IDs commands
+// carry.
+type Options struct {
+	URL      string
+	ChainID  int64
+	Timeout  time.Duration
+	Accounts map[string]Account
+}
+
+// APIError is returned when the CLOB refuses a request
+type APIError struct {
+	StatusCode int
+	Body       map[string]interface{}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("clob request failed (status %d): %v", e.StatusCode, e.Body)
+}
+
+// Order is a buy of one outcome of a market
+type Order struct {
+	AccountID string
+	MarketID  string // condition ID
+	Side      string // yes, no
+	Price     float64
+	Shares    float64
+
+	// TimeInForce is GTC, GTD, FOK or IOC (sent as FAK); ExpiresAt is
+	// required for GTD
+	TimeInForce string
+	ExpiresAt   time.Time
+
+	// TokenID overrides the outcome token looked up from the market
+	TokenID string
+}
+
+// OrderResult is the CLOB's answer to an accepted order
+type OrderResult struct {
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"` // live, matched, delayed
+}
+
+type account struct {
+	key           *PrivateKey
+	funder        string
+	signatureType int
+	apiKey        string
+	apiSecret     []byte
+	apiPassphrase string
+}
+
+// market is what orders need from GET /markets/{condition_id}
+type market struct {
+	tokens     map[string]string // yes/no -> token ID
+	tickSize   float64
+	feeRateBps int
+	negRisk    bool
+	fetchedAt  time.Time
+}
+
+// Client talks to the CLOB on behalf of the configured accounts
+type Client struct {
+	url        string
+	chainID    int64
+	httpClient *http.Client
+	accounts   map[string]*account
+
+	mu      sync.Mutex
+	markets map[string]market
+}
+
+func NewClient(opts Options) (*Client, error) {
+	c := &Client{
+		url:        strings.TrimRight(opts.URL, "/"),
+		chainID:    opts.ChainID,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+		accounts:   make(map[string]*account, len(opts.Accounts)),
+		markets:    make(map[string]market),
+	}
+	if c.url == "" {
+		c.url = DefaultURL
+	}
+	if c.chainID == 0 {
+		c.chainID = DefaultChainID
+	}
+	if c.httpClient.Timeout <= 0 {
+		c.httpClient.Timeout = defaultTimeout
+	}
+
+	for id, a := range opts.Accounts {
+		key, err := ParsePrivateKey(a.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %w", id, err)
+		}
+		secret, err := base64.URLEncoding.DecodeString(a.APISecret)
+		if err != nil {
+			return nil, fmt.Errorf("account %s: api secret is not base64: %w", id, err)
+		}
+		if a.Funder != "" {
+			if _, err := parseAddress(a.Funder); err != nil {
+				return nil, fmt.Errorf("account %s: funder: %w", id, err)
+			}
+		}
+		c.accounts[id] = &account{
+			key:           key,
+			funder:        a.Funder,
+			signatureType: a.SignatureType,
+			apiKey:        a.APIKey,
+			apiSecret:     secret,
+			apiPassphrase: a.APIPassphrase,
+		}
+	}
+	return c, nil
+}
+
+// HasAccount reports whether orders of the account go through this client
+func (c *Client) HasAccount(accountID string) bool {
+	_, ok := c.accounts[accountID]
+	return ok
+}
+
+// PlaceOrder signs and posts a buy order
+func (c *Client) PlaceOrder(ctx context.Context, o Order) (OrderResult, error) {
+	acct, ok := c.accounts[o.AccountID]
+	if !ok {
+		return OrderResult{}, fmt.Errorf("no CLOB credentials for account %s", o.AccountID)
+	}
+
+	m, err := c.market(ctx, acct, o.MarketID)
+	if err != nil {
+		return OrderResult{}, err
+	}
+	tokenID := o.TokenID
+	if tokenID == "" {
+		tokenID = m.tokens[strings.ToLower(o.Side)]
+	}
+	if tokenID == "" {
+		return OrderResult{}, fmt.Errorf("market %s has no %q outcome token", o.MarketID, o.Side)
+	}
+
+	orderType := strings.ToUpper(o.TimeInForce)
+	var expiration int64
+	switch orderType {
+	case "", "GTC":
+		orderType = "GTC"
+	case "IOC":
+		orderType = "FAK"
+	case "FOK":
+	case "GTD":
+		if o.ExpiresAt.IsZero() {
+			return OrderResult{}, errors.New("GTD order without expiry")
+		}
+		expiration = o.ExpiresAt.Add(expirationThreshold).Unix()
+	default:
+		return OrderResult{}, fmt.Errorf("unsupported time in force %q", o.TimeInForce)
+	}
+
+	order, err := buyOrder{
+		tokenID:    tokenID,
+		price:      o.Price,
+		shares:     o.Shares,
+		tickSize:   m.tickSize,
+		feeRateBps: m.feeRateBps,
+		expiration: expiration,
+		negRisk:    m.negRisk,
+	}.sign(acct, c.chainID)
+	if err != nil {
+		return OrderResult{}, err
+	}
+
+	var resp struct {
+		Success  bool   `json:"success"`
+		ErrorMsg string `json:"errorMsg"`
+		OrderID  string `json:"orderID"`
+		Status   string `json:"status"`
+	}
+	body := map[string]interface{}{
+		"order":     order,
+		"owner":     acct.apiKey,
+		"orderType": orderType,
+	}
+	if err := c.do(ctx, acct, http.MethodPost, "/order", body, &resp); err != nil {
+		return OrderResult{}, err
+	}
+	if !resp.Success || resp.OrderID == "" {
+		return OrderResult{}, &APIError{
+			StatusCode: http.StatusBadRequest,
+			Body:       map[string]interface{}{"error": resp.ErrorMsg, "status": resp.Status},
+		}
+	}
+
+	return OrderResult{OrderID: resp.OrderID, Status: resp.Status}, nil
+}
+
+// CancelOrder cancels one order of the account
+func (c *Client) CancelOrder(ctx context.Context, accountID, orderID string) error {
+	acct, ok := c.accounts[accountID]
+	if !ok {
+		return fmt.Errorf("no CLOB credentials for account %s", accountID)
+	}
+
+	var resp struct {
+		Canceled    []string               `json:"canceled"`
+		NotCanceled map[string]interface{} `json:"not_canceled"`
+	}
+	if err := c.do(ctx, acct, http.MethodDelete, "/order", map[string]string{"orderID": orderID}, &resp); err != nil {
+		return err
+	}
+	if reason, failed := resp.NotCanceled[orderID]; failed {
+		return &APIError{
+			StatusCode: http.StatusBadRequest,
+			Body:       map[string]interface{}{"error": reason, "order_id": orderID},
+		}
+	}
+	return nil
+}
+
+// market returns the outcome tokens and trading parameters of a market,
+// cached for marketCacheTTL
+func (c *Client) market(ctx context.Context, acct *account, conditionID string) (market, error) {
+	c.mu.Lock()
+	m, ok := c.markets[conditionID]
+	c.mu.Unlock()
+	if ok && time.Since(m.fetchedAt) < marketCacheTTL {
+		return m, nil
+	}
+
+	var raw struct {
+		Tokens []struct {
+			TokenID string `json:"token_id"`
+			Outcome string `json:"outcome"`
+		} `json:"tokens"`
+		MinimumTickSize float64 `json:"minimum_tick_size"`
+		TakerBaseFee    int     `json:"taker_base_fee"`
+		NegRisk         bool    `json:"neg_risk"`
+	}
+	if err := c.do(ctx, acct, http.MethodGet, "/markets/"+url.PathEscape(conditionID), nil, &raw); err != nil {
+		return market{}, fmt.Errorf("failed to fetch market %s: %w", conditionID, err)
+	}
+
+	m = market{
+		tokens:     make(map[string]string, len(raw.Tokens)),
+		tickSize:   raw.MinimumTickSize,
+		feeRateBps: raw.TakerBaseFee,
+		negRisk:    raw.NegRisk,
+		fetchedAt:  time.Now(),
+	}
+	for _, token := range raw.Tokens {
+		m.tokens[strings.ToLower(token.Outcome)] = token.TokenID
+	}
+
+	c.mu.Lock()
+	c.markets[conditionID] = m
+	c.mu.Unlock()
+	return m, nil
+}
+
+// do sends an authenticated JSON request and decodes the JSON answer into v
+func (c *Client) do(ctx context.Context, acct *account, method, path string, payload, v interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authenticate(req, acct, body)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return &APIError{StatusCode: resp.StatusCode, Body: errResp}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// authenticate adds the L2 headers: an HMAC-SHA256 with the API secret over
+// timestamp, method, path and body
+func (c *Client) authenticate(req *http.Request, acct *account, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	mac := hmac.New(sha256.New, acct.apiSecret)
+	mac.Write([]byte(timestamp + req.Method + req.URL.Path))
+	mac.Write(body)
+
+	req.Header.Set("POLY_ADDRESS", acct.key.Address())
+	req.Header.Set("POLY_SIGNATURE", base64.URLEncoding.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("POLY_TIMESTAMP", timestamp)
+	req.Header.Set("POLY_API_KEY", acct.apiKey)
+	req.Header.Set("POLY_PASSPHRASE", acct.apiPassphrase)
+}
@@ -0,0 +1,187 @@
+package clob
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Orders are EIP-712 signed structs of the CTF Exchange contract, which
+// settles matched orders on chain. A buy of shares at price offers
+// makerAmount = price * shares of collateral (USDC) for takerAmount = shares
+// outcome tokens, both in units of 1e-6.
+
+const (
+	// Exchange contracts on Polygon; markets of negative-risk groups settle
+	// on their own exchange
+	exchangeAddress        = "0x4bFb41d5B3570DeFd03C39a9A4D8dE6Bd8B8982E"
+	negRiskExchangeAddress = "0xC5d563A36AE78145C45a50134d48A1215220f80a"
+
+	zeroAddress   = "0x0000000000000000000000000000000000000000"
+	tokenDecimals = 6
+
+	sideBuy = 0
+)
+
+var (
+	domainTypeHash = keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	orderTypeHash  = keccak256([]byte("Order(uint256 salt,address maker,address signer,address taker,uint256 tokenId," +
+		"uint256 makerAmount,uint256 takerAmount,uint256 expiration,uint256 nonce,uint256 feeRateBps,uint8 side,uint8 signatureType)"))
+	domainNameHash    = keccak256([]byte("Polymarket CTF Exchange"))
+	domainVersionHash = keccak256([]byte("1"))
+)
+
+// signedOrder is the order as POST /order takes it
+type signedOrder struct {
+	Salt          int64  `json:"salt"`
+	Maker         string `json:"maker"`
+	Signer        string `json:"signer"`
+	Taker         string `json:"taker"`
+	TokenID       string `json:"tokenId"`
+	MakerAmount   string `json:"makerAmount"`
+	TakerAmount   string `json:"takerAmount"`
+	Expiration    string `json:"expiration"`
+	Nonce         string `json:"nonce"`
+	FeeRateBps    string `json:"feeRateBps"`
+	Side          string `json:"side"`
+	SignatureType int    `json:"signatureType"`
+	Signature     string `json:"signature"`
+}
+
+// buyOrder describes a buy before it is signed
+type buyOrder struct {
+	tokenID    string
+	price      float64
+	shares     float64
+	tickSize   float64
+	feeRateBps int
+	expiration int64 // unix seconds, 0 for none
+	negRisk    bool
+}
+
+// amounts returns maker and taker amounts in token units. Shares are rounded
+// down to 0.01 and the collateral to two decimals more than the tick size has.
+func (o buyOrder) amounts() (maker, taker *big.Int) {
+	priceDecimals := 2
+	if o.tickSize > 0 {
+		priceDecimals = int(math.Round(-math.Log10(o.tickSize)))
+	}
+
+	shares := math.Floor(o.shares*100+1e-9) / 100
+	scale := math.Pow10(priceDecimals + 2)
+	collateral := math.Round(shares*o.price*scale) / scale
+
+	return toTokenUnits(collateral), toTokenUnits(shares)
+}
+
+func toTokenUnits(v float64) *big.Int {
+	return big.NewInt(int64(math.Round(v * math.Pow10(tokenDecimals))))
+}
+
+// sign builds and signs the order for an account
+func (o buyOrder) sign(account *account, chainID int64) (signedOrder, error) {
+	tokenID, ok := new(big.Int).SetString(o.tokenID, 10)
+	if !ok {
+		return signedOrder{}, fmt.Errorf("invalid token ID %q", o.tokenID)
+	}
+	maker, taker := o.amounts()
+	if maker.Sign() <= 0 || taker.Sign() <= 0 {
+		return signedOrder{}, fmt.Errorf("order of %.4f shares at %.4f rounds to nothing", o.shares, o.price)
+	}
+
+	salt, err := randomSalt()
+	if err != nil {
+		return signedOrder{}, err
+	}
+
+	makerAddress := account.key.Address()
+	if account.funder != "" {
+		makerAddress = account.funder
+	}
+
+	order := signedOrder{
+		Salt:          salt,
+		Maker:         makerAddress,
+		Signer:        account.key.Address(),
+		Taker:         zeroAddress,
+		TokenID:       tokenID.String(),
+		MakerAmount:   maker.String(),
+		TakerAmount:   taker.String(),
+		Expiration:    strconv.FormatInt(o.expiration, 10),
+		Nonce:         "0",
+		FeeRateBps:    strconv.Itoa(o.feeRateBps),
+		Side:          "BUY",
+		SignatureType: account.signatureType,
+	}
+
+	exchange := exchangeAddress
+	if o.negRisk {
+		exchange = negRiskExchangeAddress
+	}
+	digest, err := orderDigest(order, chainID, exchange)
+	if err != nil {
+		return signedOrder{}, err
+	}
+
+	sig, err := account.key.Sign(digest)
+	if err != nil {
+		return signedOrder{}, err
+	}
+	order.Signature = "0x" + hex.EncodeToString(sig)
+	return order, nil
+}
+
+// orderDigest is the EIP-712 hash of an order for the given exchange
+func orderDigest(o signedOrder, chainID int64, exchange string) ([32]byte, error) {
+	var digest [32]byte
+
+	exchangeAddr, err := parseAddress(exchange)
+	if err != nil {
+		return digest, err
+	}
+	domain := keccak256(
+		domainTypeHash,
+		domainNameHash,
+		domainVersionHash,
+		pad32(big.NewInt(chainID)),
+		leftPad(exchangeAddr),
+	)
+
+	words := [][]byte{orderTypeHash, pad32(big.NewInt(o.Salt))}
+	for _, addr := range []string{o.Maker, o.Signer, o.Taker} {
+		raw, err := parseAddress(addr)
+		if err != nil {
+			return digest, err
+		}
+		words = append(words, leftPad(raw))
+	}
+	for _, number := range []string{o.TokenID, o.MakerAmount, o.TakerAmount, o.Expiration, o.Nonce, o.FeeRateBps} {
+		n, ok := new(big.Int).SetString(number, 10)
+		if !ok || n.Sign() < 0 {
+			return digest, fmt.Errorf("invalid order field %q", number)
+		}
+		words = append(words, pad32(n))
+	}
+	words = append(words, pad32(big.NewInt(sideBuy)), pad32(big.NewInt(int64(o.SignatureType))))
+
+	copy(digest[:], keccak256([]byte{0x19, 0x01}, domain, keccak256(words...)))
+	return digest, nil
+}
+
+func leftPad(b []byte) []byte {
+	out := make([]byte, 32)
+	copy(out[32-len(b):], b)
+	return out
+}
+
+// randomSalt returns a salt that survives JSON number parsing
+func randomSalt() (int64, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1<<53))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return n.Int64(), nil
+}
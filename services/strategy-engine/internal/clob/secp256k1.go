@@ -0,0 +1,95 @@
+package clob
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+// Orders are signed with decred's secp256k1: deterministic nonces (RFC 6979)
+// and low-s signatures, with the recovery byte Ethereum expects.
+
+// PrivateKey is a secp256k1 signing key
+type PrivateKey struct {
+	key     *secp256k1.PrivateKey
+	address string
+}
+
+// ParsePrivateKey reads a hex private key, with or without 0x prefix
+func ParsePrivateKey(s string) (*PrivateKey, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("private key must be 32 bytes of hex")
+	}
+
+	var scalar secp256k1.ModNScalar
+	if overflow := scalar.SetByteSlice(raw); overflow || scalar.IsZero() {
+		return nil, errors.New("private key out of range")
+	}
+	key := secp256k1.NewPrivateKey(&scalar)
+
+	// The uncompressed public key without its 0x04 prefix
+	pub := key.PubKey().SerializeUncompressed()[1:]
+	hash := keccak256(pub)
+	return &PrivateKey{key: key, address: checksumAddress(hash[12:])}, nil
+}
+
+// Address returns the Ethereum address of the key, EIP-55 checksummed
+func (k *PrivateKey) Address() string {
+	return k.address
+}
+
+// Sign signs a 32-byte digest and returns r || s || v with v = 27 or 28
+func (k *PrivateKey) Sign(digest [32]byte) ([]byte, error) {
+	// v || r || s, with v = 27 + recovery for an uncompressed key
+	compact := ecdsa.SignCompact(k.key, digest[:], false)
+	if len(compact) != 65 {
+		return nil, errors.New("unexpected signature length")
+	}
+
+	sig := make([]byte, 0, 65)
+	sig = append(sig, compact[1:]...)
+	return append(sig, compact[0]), nil
+}
+
+func keccak256(parts ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+func pad32(n *big.Int) []byte {
+	out := make([]byte, 32)
+	return n.FillBytes(out)
+}
+
+// checksumAddress formats a 20-byte address as EIP-55 hex
+func checksumAddress(addr []byte) string {
+	lower := hex.EncodeToString(addr)
+	hash := hex.EncodeToString(keccak256([]byte(lower)))
+
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+// parseAddress reads a 0x-prefixed 20-byte hex address
+func parseAddress(s string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(raw) != 20 {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	return raw, nil
+}
@@ -0,0 +1,145 @@
+package clob
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// The key, address and personal_sign signature of the web3.js documentation
+const (
+	testKey     = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	testAddress = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+)
+
+func TestParsePrivateKeyAddress(t *testing.T) {
+	for _, raw := range []string{testKey, strings.TrimPrefix(testKey, "0x"), " " + testKey + "\n"} {
+		key, err := ParsePrivateKey(raw)
+		if err != nil {
+			t.Fatalf("ParsePrivateKey(%q): %v", raw, err)
+		}
+		if got := key.Address(); got != testAddress {
+			t.Errorf("ParsePrivateKey(%q).Address() = %s, want %s", raw, got, testAddress)
+		}
+	}
+}
+
+func TestParsePrivateKeyInvalid(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"0x1234",
+		"not hex",
+		"0x0000000000000000000000000000000000000000000000000000000000000000",
+		// The curve order n is out of range
+		"0xfffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+	} {
+		if _, err := ParsePrivateKey(raw); err == nil {
+			t.Errorf("ParsePrivateKey(%q) succeeded, want an error", raw)
+		}
+	}
+}
+
+func TestSignKnownAnswer(t *testing.T) {
+	key, err := ParsePrivateKey(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// personal_sign of "Some data"
+	message := "Some data"
+	var digest [32]byte
+	copy(digest[:], keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message))))
+	if got, want := hex.EncodeToString(digest[:]), "1da44b586eb0729ff70a73c326926f6ed5a25f5b056e7f47fbc6e58d86871655"; got != want {
+		t.Fatalf("digest = %s, want %s", got, want)
+	}
+
+	sig, err := key.Sign(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "b91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd" +
+		"6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a029" +
+		"1c"
+	if got := hex.EncodeToString(sig); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+}
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(keccak256([]byte(tt.input))); got != tt.want {
+			t.Errorf("keccak256(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestChecksumAddress(t *testing.T) {
+	// Examples of EIP-55
+	for _, want := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		raw, err := parseAddress(strings.ToLower(want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checksumAddress(raw); got != want {
+			t.Errorf("checksumAddress = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestSignedOrderRecoversSigner(t *testing.T) {
+	key, err := ParsePrivateKey(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acct := &account{key: key}
+
+	for _, negRisk := range []bool{false, true} {
+		order := buyOrder{tokenID: "1234567890", price: 0.42, shares: 10, tickSize: 0.01, negRisk: negRisk}
+		signed, err := order.sign(acct, DefaultChainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signed.MakerAmount != "4200000" || signed.TakerAmount != "10000000" {
+			t.Errorf("amounts = %s/%s, want 4200000/10000000", signed.MakerAmount, signed.TakerAmount)
+		}
+
+		exchange := exchangeAddress
+		if negRisk {
+			exchange = negRiskExchangeAddress
+		}
+		digest, err := orderDigest(signed, DefaultChainID, exchange)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sig, err := hex.DecodeString(strings.TrimPrefix(signed.Signature, "0x"))
+		if err != nil || len(sig) != 65 {
+			t.Fatalf("signature %q is not 65 bytes of hex", signed.Signature)
+		}
+		// RecoverCompact takes v || r || s
+		compact := append([]byte{sig[64]}, sig[:64]...)
+		pub, _, err := ecdsa.RecoverCompact(compact, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := keccak256(pub.SerializeUncompressed()[1:])
+		if got := checksumAddress(hash[12:]); got != testAddress {
+			t.Errorf("neg_risk=%t: signature recovers %s, want %s", negRisk, got, testAddress)
+		}
+	}
+}
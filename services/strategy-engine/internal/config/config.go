@@ -1,63 +1,1060 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+	"gopkg.in/yaml.v3"
 )
 
+// Config is loaded in three layers: built-in defaults, then an optional YAML
+// file, then environment variables, which always win. Keys in the file match
+// the yaml tags below; env var names are listed in applyEnv.
+//
+// Credentials may be given as secret references (file:/path or
+// vault:path#key, see package secrets) or through *_FILE env vars; they are
+// resolved after the env layer.
 type Config struct {
-	PostgresURL          string
-	RedisHost            string
-	RedisPort            int
-	PredictAccountURL    string
-	PolymarketAccountURL string
-	LogLevel             string
-	DryRun               bool
+	// PostgresURL takes precedence; otherwise the URL is built from the parts
+	PostgresURL      string `yaml:"postgres_url"`
+	PostgresHost     string `yaml:"postgres_host"`
+	PostgresPort     int    `yaml:"postgres_port"`
+	PostgresDB       string `yaml:"postgres_db"`
+	PostgresUser     string `yaml:"postgres_user"`
+	PostgresPassword string `yaml:"postgres_password"`
+
+	// Connection pool; StatementTimeout is enforced server-side per statement
+	PostgresMaxConns         int           `yaml:"postgres_max_conns"`
+	PostgresMinConns         int           `yaml:"postgres_min_conns"`
+	PostgresMaxConnLifetime  time.Duration `yaml:"postgres_max_conn_lifetime"`
+	PostgresStatementTimeout time.Duration `yaml:"postgres_statement_timeout"`
+
+	// Redis: RedisHost/RedisPort for a single server, or RedisAddrs with
+	// RedisMasterName (Sentinel) or RedisCluster for managed deployments
+	RedisHost                  string   `yaml:"redis_host"`
+	RedisPort                  int      `yaml:"redis_port"`
+	RedisAddrs                 []string `yaml:"redis_addrs"`
+	RedisMasterName            string   `yaml:"redis_master_name"`
+	RedisCluster               bool     `yaml:"redis_cluster"`
+	RedisUsername              string   `yaml:"redis_username"`
+	RedisPassword              string   `yaml:"redis_password"`
+	RedisSentinelPassword      string   `yaml:"redis_sentinel_password"`
+	RedisDB                    int      `yaml:"redis_db"`
+	RedisTLS                   bool     `yaml:"redis_tls"`
+	RedisTLSServerName         string   `yaml:"redis_tls_server_name"`
+	RedisTLSInsecureSkipVerify bool     `yaml:"redis_tls_insecure_skip_verify"`
+
+	// Platforms maps a platform name to its account service. The predict and
+	// polymarket entries default to the URL and bearer token settings below.
+	Platforms              map[string]*PlatformConfig `yaml:"platforms"`
+	PredictAccountURL      string                     `yaml:"predict_account_url"`
+	PredictAccountToken    string                     `yaml:"predict_account_token"`
+	PolymarketAccountURL   string                     `yaml:"polymarket_account_url"`
+	PolymarketAccountToken string                     `yaml:"polymarket_account_token"`
+
+	LogLevel string `yaml:"log_level"`
+	DryRun   bool   `yaml:"dry_run"`
+
+	// LogFormat is console (human-readable) or json (one object per line)
+	LogFormat string `yaml:"log_format"`
+
+	// StrategyLogLevels overrides log_level for entries of single strategies,
+	// by strategy name
+	StrategyLogLevels map[string]string `yaml:"strategy_log_levels"`
+
+	// DedupTTL is how long a fill/order key is remembered to suppress
+	// duplicate deliveries. Zero disables deduplication.
+	DedupTTL time.Duration `yaml:"dedup_ttl"`
+
+	// ExecutionReportInterval controls how often per-venue execution quality
+	// is computed over the trailing ExecutionReportWindow. Zero disables it.
+	ExecutionReportInterval time.Duration `yaml:"exec_report_interval"`
+	ExecutionReportWindow   time.Duration `yaml:"exec_report_window"`
+
+	// DailyReport stores an end-of-day summary of every UTC day in
+	// daily_reports, DailyReportAt past midnight UTC. The summary is also
+	// posted to the Slack webhook and mailed through DailyReportEmail when
+	// they are set; the webhook accepts a secret reference.
+	DailyReport             bool          `yaml:"daily_report"`
+	DailyReportAt           time.Duration `yaml:"daily_report_at"`
+	DailyReportSlackWebhook string        `yaml:"daily_report_slack_webhook"`
+	DailyReportEmail        *EmailConfig  `yaml:"daily_report_email"`
+
+	// ArchiveInterval is how often the event streams are copied to the
+	// event_archive table; zero disables archiving. Archived events are kept
+	// for ArchiveRetention (zero: forever), overridable per stream, and order
+	// book events are compacted to one per market and minute after
+	// ArchiveCompactAfter (zero: never).
+	ArchiveInterval        time.Duration            `yaml:"archive_interval"`
+	ArchiveRetention       time.Duration            `yaml:"archive_retention"`
+	ArchiveStreamRetention map[string]time.Duration `yaml:"archive_stream_retention"`
+	ArchiveCompactAfter    time.Duration            `yaml:"archive_compact_after"`
+
+	// ConfigKeys are the master keys of encrypted strategy config values, by
+	// key ID: base64-encoded 32-byte keys, usually secret references. Values
+	// are encrypted with ConfigKeyID; the others are kept for decrypting
+	// values encrypted before a rotation.
+	ConfigKeys  map[string]*ConfigKeyConfig `yaml:"config_keys"`
+	ConfigKeyID string                      `yaml:"config_key_id"`
+
+	// StreamMaxLen caps the streams the engine publishes to at about this many
+	// entries (XADD MAXLEN ~); zero leaves them unbounded. StreamTrim
+	// overrides it by stream and may bound the age of entries as well. The
+	// streams the engine owns are trimmed every StreamTrimInterval too; zero
+	// disables that.
+	StreamMaxLen       int                          `yaml:"stream_max_len"`
+	StreamTrim         map[string]*StreamTrimConfig `yaml:"stream_trim"`
+	StreamTrimInterval time.Duration                `yaml:"stream_trim_interval"`
+
+	// SequenceEvents numbers the entries the engine publishes per stream, as
+	// producer instance_name, so consumers can detect lost entries. Gaps in
+	// the numbering of consumed streams always raise an alert; ReplayEventGaps
+	// also handles the lost events the event archive still holds.
+	SequenceEvents  bool `yaml:"sequence_events"`
+	ReplayEventGaps bool `yaml:"replay_event_gaps"`
+
+	// CatchUp is what the engine does with the entries added to the streams
+	// it consumes while it was down: skip starts at the newest entries,
+	// dry_run replays them with every order sent as a dry run, and replay
+	// replays them, executing the orders decided on entries younger than
+	// max_age only. CatchUpStreams overrides it by stream. Stream offsets are
+	// saved in Redis under instance_name (and the shard index) either way.
+	CatchUp        CatchUpConfig             `yaml:"catch_up"`
+	CatchUpStreams map[string]*CatchUpConfig `yaml:"catch_up_streams"`
+
+	// RecoverOrders adopts open orders found on managed accounts at startup
+	RecoverOrders bool `yaml:"recover_orders"`
+
+	// ReconcileInterval is how often the open orders of managed accounts are
+	// compared with what the engine tracks, flagging orphans and ghosts; zero
+	// disables it. ReconcileCancelOrphans cancels the orphans.
+	ReconcileInterval      time.Duration `yaml:"reconcile_interval"`
+	ReconcileCancelOrphans bool          `yaml:"reconcile_cancel_orphans"`
+
+	// PositionDriftInterval is how often the engine's positions are checked
+	// against the account services; zero disables it. Positions differing by
+	// more than PositionDriftTolerance shares raise position_drift events.
+	PositionDriftInterval  time.Duration `yaml:"position_drift_interval"`
+	PositionDriftTolerance float64       `yaml:"position_drift_tolerance"`
+
+	// OrderBookPollInterval is how often the books of the markets the engine
+	// trades are fetched from the account services, for as long as nothing
+	// publishes orderbook_events; zero disables it
+	OrderBookPollInterval time.Duration `yaml:"orderbook_poll_interval"`
+
+	// AutoDisable moves strategies to shadow mode when their rolling
+	// AutoDisableWindow performance falls below the PnL or hit-rate thresholds.
+	AutoDisable           bool          `yaml:"auto_disable"`
+	AutoDisableWindow     time.Duration `yaml:"auto_disable_window"`
+	AutoDisableMinPnL     float64       `yaml:"auto_disable_min_pnl"`
+	AutoDisableMinHitRate float64       `yaml:"auto_disable_min_hit_rate"`
+	AutoDisableMinTrades  int           `yaml:"auto_disable_min_trades"`
+
+	// MaxHandlerPanics disables a strategy after this many consecutive panics
+	MaxHandlerPanics int `yaml:"max_handler_panics"`
+
+	// RejectBackoffAfter suspends a strategy after this many consecutive
+	// rejected orders, for RejectBackoff at first and twice as long on each
+	// further suspension, up to RejectBackoffMax. Zero disables it.
+	RejectBackoffAfter int           `yaml:"reject_backoff_after"`
+	RejectBackoff      time.Duration `yaml:"reject_backoff"`
+	RejectBackoffMax   time.Duration `yaml:"reject_backoff_max"`
+
+	// HandlerTimeout is how long a strategy handler may run per event before
+	// the event is given up on. Zero disables the timeout.
+	HandlerTimeout time.Duration `yaml:"handler_timeout"`
+
+	// OrderTTL cancels orders that are still open this long after they were
+	// placed, unless the command sets its own expiry. Zero disables it.
+	OrderTTL time.Duration `yaml:"order_ttl"`
+
+	// StrategyWarmup is how long a strategy only observes after it is
+	// activated: its handler builds state, but its orders are dropped.
+	// Strategies override it with config "warmup" in seconds.
+	StrategyWarmup time.Duration `yaml:"strategy_warmup"`
+
+	// MaxStreamLag alerts when the engine is this far behind an event stream
+	// and MaxQueueBacklog when a strategy has this many events queued. Zero
+	// disables the respective alert.
+	MaxStreamLag    time.Duration `yaml:"max_stream_lag"`
+	MaxQueueBacklog int           `yaml:"max_queue_backlog"`
+
+	// StateFlushInterval keeps strategy state in memory and writes it to
+	// Postgres this often. Zero writes it through on every change.
+	StateFlushInterval time.Duration `yaml:"state_flush_interval"`
+
+	// MaxPriceDeviation blocks orders priced further than this, in price
+	// units, from a fresh market price. Zero disables the check.
+	MaxPriceDeviation float64 `yaml:"max_price_deviation"`
+
+	// MaxPriceMove blocks new opening orders in a market whose YES price
+	// moved more than this (0.1 = 10 cents) within PriceMoveWindow, for
+	// PriceMoveCooldown. Zero disables the guard.
+	MaxPriceMove      float64       `yaml:"max_price_move"`
+	PriceMoveWindow   time.Duration `yaml:"price_move_window"`
+	PriceMoveCooldown time.Duration `yaml:"price_move_cooldown"`
+
+	// SelfTradePrevention handles orders that would cross our own resting
+	// orders on the same market: off, skip, reprice or cancel_replace
+	SelfTradePrevention string `yaml:"self_trade_prevention"`
+
+	// StrategyGroups are shared risk budgets, by group name. A strategy joins
+	// a group with "group" in its config.
+	StrategyGroups map[string]*GroupConfig `yaml:"strategy_groups"`
+
+	// Outbox persists commands to command_outbox before they are executed
+	Outbox bool `yaml:"outbox"`
+
+	// HTTPAddr is where the admin API listens
+	HTTPAddr string `yaml:"http_addr"`
+
+	// AdminToken is the bearer token of operators with access to every
+	// tenant. It is required unless HTTPAddr is a loopback address, where
+	// without it and tenant tokens the admin API is open.
+	AdminToken string `yaml:"admin_token"`
+
+	// Tenants are the desks sharing the engine, by tenant name. Strategies
+	// belong to the tenant in strategies.tenant.
+	Tenants map[string]*TenantConfig `yaml:"tenants"`
+
+	// IncidentDir is where incident bundles are written
+	IncidentDir string `yaml:"incident_dir"`
+
+	// Chaos injects faults into the engine's event consumption and account
+	// service calls, for test deployments
+	Chaos *ChaosConfig `yaml:"chaos"`
+
+	// WireLog records a sample of the requests sent to the account services
+	// and their answers, redacted, to settle disputes about what was sent
+	WireLog *WireLogConfig `yaml:"wire_log"`
+
+	// ShardIndex/ShardCount split markets between engine instances; each
+	// instance handles only events whose market hashes into its shard.
+	ShardIndex int `yaml:"shard_index"`
+	ShardCount int `yaml:"shard_count"`
+
+	// InstanceName names this engine in recorded strategy decisions.
+	// RecordDecisions records them; CompareMode runs the engine as a
+	// candidate that only records its decisions, to be compared with the
+	// live engine's by `strategy-engine compare`.
+	InstanceName    string `yaml:"instance_name"`
+	RecordDecisions bool   `yaml:"record_decisions"`
+	CompareMode     bool   `yaml:"compare_mode"`
+
+	// Trace republishes every consumed event with the strategies' decisions
+	// on it to the engine_trace stream
+	Trace bool `yaml:"trace"`
+
+	// CommandMiddlewares are the custom command middlewares to run, in
+	// order; unset runs every registered one
+	CommandMiddlewares []string `yaml:"command_middlewares"`
+
+	// RequireRegisteredAccounts drops orders on accounts missing from the
+	// trading_accounts registry; registered accounts are always checked
+	RequireRegisteredAccounts bool `yaml:"require_registered_accounts"`
+
+	// Environment names the deployment (staging, production, ...); feature
+	// flags limited to some environments are off in the others
+	Environment string `yaml:"environment"`
+
+	// secretRefs keeps the original reference of every resolved secret, by
+	// yaml key, so rotated values can be re-read
+	secretRefs map[string]string
+	resolver   *secrets.Resolver
+}
+
+// PlatformConfig describes one platform's account service
+type PlatformConfig struct {
+	URL string `yaml:"url"`
+
+	// AuthToken is sent as "Bearer <token>" unless AuthHeader names another
+	// header. HMACSecret signs every request. Both accept secret references.
+	AuthHeader string `yaml:"auth_header"`
+	AuthToken  string `yaml:"auth_token"`
+	HMACKeyID  string `yaml:"hmac_key_id"`
+	HMACSecret string `yaml:"hmac_secret"`
+
+	// mTLS client certificate and the CA that signed the service's certificate
+	TLSCertFile   string `yaml:"tls_cert_file"`
+	TLSKeyFile    string `yaml:"tls_key_file"`
+	TLSCAFile     string `yaml:"tls_ca_file"`
+	TLSServerName string `yaml:"tls_server_name"`
+
+	// Timeout bounds each request and ConnectTimeout dialing the service
+	Timeout        time.Duration `yaml:"timeout"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+
+	// MaxIdleConns caps the keep-alive connections to the service, closed
+	// after IdleConnTimeout unused; -1 disables keep-alive
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout"`
+
+	// Proxy is an http(s):// or socks5:// proxy for the service, instead of
+	// HTTP_PROXY/HTTPS_PROXY. It accepts a secret reference.
+	Proxy string `yaml:"proxy"`
+
+	// Headers are sent with every request to the service
+	Headers map[string]string `yaml:"headers"`
+
+	// RateLimit is in requests per second; zero means unlimited
+	RateLimit float64 `yaml:"rate_limit"`
+	RateBurst int     `yaml:"rate_burst"`
+
+	// TimeInForce lists the values the venue enforces natively; others are emulated
+	TimeInForce []string `yaml:"time_in_force"`
+
+	// OrderTypes lists the order types besides limit the venue takes natively
+	// (market, post_only); market orders are emulated otherwise
+	OrderTypes []string `yaml:"order_types"`
+
+	// Sells means the account service takes sell orders ("action": "sell");
+	// sells are emulated by buying the opposite outcome otherwise
+	Sells bool `yaml:"sells"`
+
+	// Amend means the account service replaces orders atomically via
+	// POST /trade/amend; amend_order cancels and places otherwise
+	Amend bool `yaml:"amend"`
+
+	// NegRisk enables convert_positions for negative-risk market groups; the
+	// account service must implement POST /neg-risk/convert
+	NegRisk bool `yaml:"neg_risk"`
+
+	// Cancels means the account service cancels orders via POST /cancel.
+	// Without it, emulated time in force (IOC, FOK, GTD), order expiry,
+	// one-cancels-other groups and cancel commands are refused, except for
+	// accounts on the CLOB.
+	Cancels bool `yaml:"cancels"`
+
+	// CloseAll means the account service closes every position of an account
+	// via POST /accounts/{id}/close-all; without it flatten_account and venue
+	// flattens are refused
+	CloseAll bool `yaml:"close_all"`
+
+	// BatchSize sends consecutive orders in /trade/batch requests of up to
+	// this many; zero disables batching
+	BatchSize int `yaml:"batch_size"`
+
+	// IdempotentOrders means the account service deduplicates orders on their
+	// client_order_id, so the outbox may send again an order whose outcome is
+	// unknown; without it such orders are left for reconciliation
+	IdempotentOrders bool `yaml:"idempotent_orders"`
+
+	// CLOB places and cancels the orders of its accounts directly on the
+	// Polymarket CLOB instead of through the account service
+	CLOB *CLOBConfig `yaml:"clob"`
+
+	// Costs is the fee and slippage model of the platform
+	Costs CostsConfig `yaml:"costs"`
+
+	// ContractSize is how many collateral units one share pays out when it
+	// wins, and CollateralUSD the USD value of one collateral unit; both
+	// default to 1. Orders sized in contracts or USD are converted with them.
+	ContractSize  float64 `yaml:"contract_size"`
+	CollateralUSD float64 `yaml:"collateral_usd"`
+}
+
+// CostsConfig models the trading costs of a platform. Fees are fractions of
+// notional and apply where the market does not report its own; slippage is
+// the expected price move per share of an order crossing the book, plus
+// impact for every share it takes.
+type CostsConfig struct {
+	MakerFee float64 `yaml:"maker_fee"`
+	TakerFee float64 `yaml:"taker_fee"`
+	Slippage float64 `yaml:"slippage"`
+	Impact   float64 `yaml:"impact"`
+}
+
+// CLOBConfig is the direct Polymarket CLOB backend of a platform
+type CLOBConfig struct {
+	URL     string        `yaml:"url"`      // default https://clob.polymarket.com
+	ChainID int64         `yaml:"chain_id"` // default 137 (Polygon)
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Accounts by the account IDs commands carry; other accounts keep using
+	// the account service
+	Accounts map[string]*CLOBAccountConfig `yaml:"accounts"`
+}
+
+// CLOBAccountConfig holds the signing key and API credentials of one account.
+// PrivateKey, APIKey, APISecret and APIPassphrase accept secret references.
+type CLOBAccountConfig struct {
+	PrivateKey string `yaml:"private_key"`
+
+	// Funder is the proxy wallet or Safe holding the funds, with
+	// SignatureType 1 (proxy) or 2 (Safe); 0 trades from the key's address
+	Funder        string `yaml:"funder"`
+	SignatureType int    `yaml:"signature_type"`
+
+	APIKey        string `yaml:"api_key"`
+	APISecret     string `yaml:"api_secret"`
+	APIPassphrase string `yaml:"api_passphrase"`
 }
 
-func Load() *Config {
+// GroupConfig is the risk budget shared by a strategy group. Zero disables a limit.
+type GroupConfig struct {
+	// MaxExposure caps fill cost on unresolved markets plus open order notional
+	MaxExposure float64 `yaml:"max_exposure"`
+
+	// MaxDailyLoss disables the whole group once its realized loss since
+	// midnight UTC reaches this amount
+	MaxDailyLoss float64 `yaml:"max_daily_loss"`
+}
+
+// EmailConfig is an SMTP server and the recipients of a mail. Password
+// accepts a secret reference.
+type EmailConfig struct {
+	SMTPAddr string   `yaml:"smtp_addr"` // host:port
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// ConfigKeyConfig is one master key of encrypted strategy config values
+type ConfigKeyConfig struct {
+	Key string `yaml:"key"`
+}
+
+// StreamTrimConfig bounds one stream. A zero MaxLen falls back to
+// stream_max_len; a zero MaxAge keeps entries of any age.
+type StreamTrimConfig struct {
+	MaxLen int           `yaml:"max_len"`
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// CatchUpConfig is the catch-up policy of a stream. MaxAge is required with
+// mode replay; entries older than it are replayed as a dry run.
+type CatchUpConfig struct {
+	Mode   string        `yaml:"mode"`
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// TenantConfig is one desk: the accounts it owns, its admin API token and its
+// risk budget over all its strategies. Zero disables a limit.
+type TenantConfig struct {
+	// APIToken scopes admin API callers to the tenant's strategies
+	APIToken string `yaml:"api_token"`
+
+	// Accounts are the account IDs (or names) of the tenant; events on them
+	// only reach its strategies, and other tenants cannot trade them
+	Accounts []string `yaml:"accounts"`
+
+	MaxExposure  float64 `yaml:"max_exposure"`
+	MaxDailyLoss float64 `yaml:"max_daily_loss"`
+}
+
+// ChaosConfig sets the faults injected on a test deployment (see the chaos
+// package). Rates are probabilities between 0 and 1.
+type ChaosConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// NonProduction confirms the deployment trades no real money; without it
+	// chaos is only allowed in dry-run mode
+	NonProduction bool `yaml:"non_production"`
+
+	DropEvents      float64       `yaml:"drop_events"`
+	DelayRequests   float64       `yaml:"delay_requests"`
+	MaxDelay        time.Duration `yaml:"max_delay"`
+	ServiceErrors   float64       `yaml:"service_errors"`
+	ErrorsAfterSend bool          `yaml:"errors_after_send"`
+}
+
+// WireLogConfig sets which account service requests are recorded and for
+// how long (see the wirelog package)
+type WireLogConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// SampleRate is the share of requests recorded, from 0 to 1; failed
+	// requests and 4xx/5xx answers are always recorded
+	SampleRate float64 `yaml:"sample_rate"`
+
+	// Retention is how long entries are kept; zero means 30 days
+	Retention time.Duration `yaml:"retention"`
+
+	// MaxBodyBytes truncates the request and response bodies stored; zero
+	// means 64 KiB
+	MaxBodyBytes int `yaml:"max_body_bytes"`
+}
+
+func defaults() *Config {
 	return &Config{
-		PostgresURL: getEnv("POSTGRES_URL", buildPostgresURL()),
-		RedisHost:   getEnv("REDIS_HOST", "redis"),
-		RedisPort:   getEnvInt("REDIS_PORT", 6379),
-		PredictAccountURL: getEnv("PREDICT_ACCOUNT_URL", "http://predict-account:8000"),
-		PolymarketAccountURL: getEnv("POLYMARKET_ACCOUNT_URL", "http://polymarket-account:8000"),
-		LogLevel:    getEnv("STRATEGY_LOG_LEVEL", "info"),
-		DryRun:      getEnvBool("STRATEGY_DRY_RUN", false),
+		PostgresHost:             "postgres",
+		PostgresPort:             5432,
+		PostgresDB:               "trading_system",
+		PostgresUser:             "trading",
+		PostgresMaxConns:         10,
+		PostgresMinConns:         2,
+		PostgresMaxConnLifetime:  time.Hour,
+		PostgresStatementTimeout: 30 * time.Second,
+		RedisHost:                "redis",
+		RedisPort:                6379,
+		PredictAccountURL:        "http://predict-account:8000",
+		PolymarketAccountURL:     "http://polymarket-account:8000",
+		LogLevel:                 "info",
+		LogFormat:                "console",
+		DedupTTL:                 10 * time.Minute,
+		ExecutionReportInterval:  time.Hour,
+		ExecutionReportWindow:    24 * time.Hour,
+		DailyReportAt:            5 * time.Minute,
+		ArchiveRetention:         90 * 24 * time.Hour,
+		ArchiveCompactAfter:      24 * time.Hour,
+		ConfigKeyID:              "default",
+		StreamMaxLen:             1000000,
+		StreamTrimInterval:       5 * time.Minute,
+		CatchUp:                  CatchUpConfig{Mode: "skip"},
+		RecoverOrders:            true,
+		PositionDriftTolerance:   1,
+		OrderBookPollInterval:    10 * time.Second,
+		AutoDisableWindow:        7 * 24 * time.Hour,
+		AutoDisableMinHitRate:    0.4,
+		AutoDisableMinTrades:     20,
+		HTTPAddr:                 ":8080",
+		IncidentDir:              "/var/lib/strategy-engine/incidents",
+		ShardCount:               1,
+		MaxPriceDeviation:        0.2,
+		PriceMoveWindow:          time.Minute,
+		PriceMoveCooldown:        5 * time.Minute,
+		SelfTradePrevention:      "skip",
+		MaxHandlerPanics:         3,
+		RejectBackoffAfter:       10,
+		RejectBackoff:            time.Minute,
+		RejectBackoffMax:         time.Hour,
+		HandlerTimeout:           10 * time.Second,
+		MaxStreamLag:             time.Minute,
+		MaxQueueBacklog:          500,
+		InstanceName:             "live",
 	}
 }
 
-func buildPostgresURL() string {
-	host := getEnv("POSTGRES_HOST", "postgres")
-	db := getEnv("POSTGRES_DB", "trading_system")
-	user := getEnv("POSTGRES_USER", "trading")
-	pass := getEnv("POSTGRES_PASSWORD", "changeme123")
-	
-	return fmt.Sprintf("postgres://%s:%s@%s:5432/%s?sslmode=disable", user, pass, host, db)
+// Load builds the config from defaults, the YAML file at path (if non-empty)
+// and environment overrides, and validates the result.
+func Load(path string) (*Config, error) {
+	cfg := defaults()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		// Unknown keys are errors, so a misspelt setting does not silently
+		// keep its default
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	cfg.addLegacyPlatforms()
+
+	if err := cfg.resolveSecrets(secrets.NewResolverFromEnv()); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+
+	if cfg.PostgresURL == "" && cfg.PostgresPassword != "" {
+		cfg.PostgresURL = cfg.buildPostgresURL()
+		// The built URL embeds the password, so it rotates along with it
+		if ref := cfg.secretRefs["postgres_password"]; ref != "" {
+			cfg.secretRefs["postgres_url"] = ref
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	env := &envOverrides{}
+
+	env.string("POSTGRES_URL", &c.PostgresURL)
+	env.file("POSTGRES_URL_FILE", &c.PostgresURL)
+	env.string("POSTGRES_HOST", &c.PostgresHost)
+	env.int("POSTGRES_PORT", &c.PostgresPort)
+	env.string("POSTGRES_DB", &c.PostgresDB)
+	env.string("POSTGRES_USER", &c.PostgresUser)
+	env.string("POSTGRES_PASSWORD", &c.PostgresPassword)
+	env.file("POSTGRES_PASSWORD_FILE", &c.PostgresPassword)
+	env.int("POSTGRES_MAX_CONNS", &c.PostgresMaxConns)
+	env.int("POSTGRES_MIN_CONNS", &c.PostgresMinConns)
+	env.duration("POSTGRES_MAX_CONN_LIFETIME", &c.PostgresMaxConnLifetime)
+	env.duration("POSTGRES_STATEMENT_TIMEOUT", &c.PostgresStatementTimeout)
+	env.string("REDIS_HOST", &c.RedisHost)
+	env.int("REDIS_PORT", &c.RedisPort)
+	env.strings("REDIS_ADDRS", &c.RedisAddrs)
+	env.string("REDIS_MASTER_NAME", &c.RedisMasterName)
+	env.bool("REDIS_CLUSTER", &c.RedisCluster)
+	env.string("REDIS_USERNAME", &c.RedisUsername)
+	env.file("REDIS_USERNAME_FILE", &c.RedisUsername)
+	env.string("REDIS_PASSWORD", &c.RedisPassword)
+	env.file("REDIS_PASSWORD_FILE", &c.RedisPassword)
+	env.string("REDIS_SENTINEL_PASSWORD", &c.RedisSentinelPassword)
+	env.file("REDIS_SENTINEL_PASSWORD_FILE", &c.RedisSentinelPassword)
+	env.int("REDIS_DB", &c.RedisDB)
+	env.bool("REDIS_TLS", &c.RedisTLS)
+	env.string("REDIS_TLS_SERVER_NAME", &c.RedisTLSServerName)
+	env.bool("REDIS_TLS_INSECURE_SKIP_VERIFY", &c.RedisTLSInsecureSkipVerify)
+	env.string("PREDICT_ACCOUNT_URL", &c.PredictAccountURL)
+	env.string("PREDICT_ACCOUNT_TOKEN", &c.PredictAccountToken)
+	env.file("PREDICT_ACCOUNT_TOKEN_FILE", &c.PredictAccountToken)
+	env.string("POLYMARKET_ACCOUNT_URL", &c.PolymarketAccountURL)
+	env.string("POLYMARKET_ACCOUNT_TOKEN", &c.PolymarketAccountToken)
+	env.file("POLYMARKET_ACCOUNT_TOKEN_FILE", &c.PolymarketAccountToken)
+	env.string("STRATEGY_LOG_LEVEL", &c.LogLevel)
+	env.string("STRATEGY_LOG_FORMAT", &c.LogFormat)
+	env.bool("STRATEGY_DRY_RUN", &c.DryRun)
+	env.duration("STRATEGY_DEDUP_TTL", &c.DedupTTL)
+	env.duration("STRATEGY_EXEC_REPORT_INTERVAL", &c.ExecutionReportInterval)
+	env.duration("STRATEGY_EXEC_REPORT_WINDOW", &c.ExecutionReportWindow)
+	env.bool("STRATEGY_DAILY_REPORT", &c.DailyReport)
+	env.duration("STRATEGY_DAILY_REPORT_AT", &c.DailyReportAt)
+	env.string("STRATEGY_DAILY_REPORT_SLACK_WEBHOOK", &c.DailyReportSlackWebhook)
+	env.file("STRATEGY_DAILY_REPORT_SLACK_WEBHOOK_FILE", &c.DailyReportSlackWebhook)
+	env.duration("STRATEGY_ARCHIVE_INTERVAL", &c.ArchiveInterval)
+	env.duration("STRATEGY_ARCHIVE_RETENTION", &c.ArchiveRetention)
+	env.duration("STRATEGY_ARCHIVE_COMPACT_AFTER", &c.ArchiveCompactAfter)
+	env.string("STRATEGY_CONFIG_KEY_ID", &c.ConfigKeyID)
+	var configKey string
+	env.string("STRATEGY_CONFIG_KEY", &configKey)
+	env.file("STRATEGY_CONFIG_KEY_FILE", &configKey)
+	if configKey != "" {
+		if c.ConfigKeys == nil {
+			c.ConfigKeys = make(map[string]*ConfigKeyConfig)
+		}
+		c.ConfigKeys[c.ConfigKeyID] = &ConfigKeyConfig{Key: configKey}
+	}
+	env.int("STRATEGY_STREAM_MAX_LEN", &c.StreamMaxLen)
+	env.bool("STRATEGY_SEQUENCE_EVENTS", &c.SequenceEvents)
+	env.bool("STRATEGY_REPLAY_EVENT_GAPS", &c.ReplayEventGaps)
+	env.string("STRATEGY_CATCH_UP", &c.CatchUp.Mode)
+	env.duration("STRATEGY_CATCH_UP_MAX_AGE", &c.CatchUp.MaxAge)
+	env.duration("STRATEGY_STREAM_TRIM_INTERVAL", &c.StreamTrimInterval)
+	env.bool("STRATEGY_RECOVER_ORDERS", &c.RecoverOrders)
+	env.duration("STRATEGY_RECONCILE_INTERVAL", &c.ReconcileInterval)
+	env.bool("STRATEGY_RECONCILE_CANCEL_ORPHANS", &c.ReconcileCancelOrphans)
+	env.duration("STRATEGY_POSITION_DRIFT_INTERVAL", &c.PositionDriftInterval)
+	env.float("STRATEGY_POSITION_DRIFT_TOLERANCE", &c.PositionDriftTolerance)
+	env.duration("STRATEGY_ORDERBOOK_POLL_INTERVAL", &c.OrderBookPollInterval)
+	env.bool("STRATEGY_AUTO_DISABLE", &c.AutoDisable)
+	env.duration("STRATEGY_AUTO_DISABLE_WINDOW", &c.AutoDisableWindow)
+	env.float("STRATEGY_AUTO_DISABLE_MIN_PNL", &c.AutoDisableMinPnL)
+	env.float("STRATEGY_AUTO_DISABLE_MIN_HIT_RATE", &c.AutoDisableMinHitRate)
+	env.int("STRATEGY_AUTO_DISABLE_MIN_TRADES", &c.AutoDisableMinTrades)
+	env.int("STRATEGY_MAX_HANDLER_PANICS", &c.MaxHandlerPanics)
+	env.int("STRATEGY_REJECT_BACKOFF_AFTER", &c.RejectBackoffAfter)
+	env.duration("STRATEGY_REJECT_BACKOFF", &c.RejectBackoff)
+	env.duration("STRATEGY_REJECT_BACKOFF_MAX", &c.RejectBackoffMax)
+	env.duration("STRATEGY_HANDLER_TIMEOUT", &c.HandlerTimeout)
+	env.duration("STRATEGY_ORDER_TTL", &c.OrderTTL)
+	env.duration("STRATEGY_WARMUP", &c.StrategyWarmup)
+	env.duration("STRATEGY_MAX_STREAM_LAG", &c.MaxStreamLag)
+	env.int("STRATEGY_MAX_QUEUE_BACKLOG", &c.MaxQueueBacklog)
+	env.duration("STRATEGY_STATE_FLUSH_INTERVAL", &c.StateFlushInterval)
+	env.float("STRATEGY_MAX_PRICE_DEVIATION", &c.MaxPriceDeviation)
+	env.float("STRATEGY_MAX_PRICE_MOVE", &c.MaxPriceMove)
+	env.duration("STRATEGY_PRICE_MOVE_WINDOW", &c.PriceMoveWindow)
+	env.duration("STRATEGY_PRICE_MOVE_COOLDOWN", &c.PriceMoveCooldown)
+	env.string("STRATEGY_SELF_TRADE_PREVENTION", &c.SelfTradePrevention)
+	env.bool("STRATEGY_OUTBOX", &c.Outbox)
+	env.string("STRATEGY_HTTP_ADDR", &c.HTTPAddr)
+	env.string("STRATEGY_ADMIN_TOKEN", &c.AdminToken)
+	env.file("STRATEGY_ADMIN_TOKEN_FILE", &c.AdminToken)
+	env.string("STRATEGY_INCIDENT_DIR", &c.IncidentDir)
+	env.int("STRATEGY_SHARD_INDEX", &c.ShardIndex)
+	env.int("STRATEGY_SHARD_COUNT", &c.ShardCount)
+	env.string("STRATEGY_INSTANCE_NAME", &c.InstanceName)
+	env.bool("STRATEGY_RECORD_DECISIONS", &c.RecordDecisions)
+	env.bool("STRATEGY_COMPARE_MODE", &c.CompareMode)
+	env.bool("STRATEGY_TRACE", &c.Trace)
+	env.strings("STRATEGY_COMMAND_MIDDLEWARES", &c.CommandMiddlewares)
+	env.bool("STRATEGY_REQUIRE_REGISTERED_ACCOUNTS", &c.RequireRegisteredAccounts)
+	env.string("STRATEGY_ENVIRONMENT", &c.Environment)
+
+	return errors.Join(env.errs...)
+}
+
+// addLegacyPlatforms fills in predict and polymarket from their URL settings
+// unless the platforms map already defines them
+func (c *Config) addLegacyPlatforms() {
+	if c.Platforms == nil {
+		c.Platforms = make(map[string]*PlatformConfig)
+	}
+	if c.Platforms["predict"] == nil {
+		c.Platforms["predict"] = &PlatformConfig{
+			URL:              c.PredictAccountURL,
+			AuthToken:        c.PredictAccountToken,
+			IdempotentOrders: true,
+		}
+	}
+	if c.Platforms["polymarket"] == nil {
+		c.Platforms["polymarket"] = &PlatformConfig{
+			URL:       c.PolymarketAccountURL,
+			AuthToken: c.PolymarketAccountToken,
+		}
+	}
 }
 
-func getEnv(key, fallback string) string {
+// RedisAddresses returns the configured Redis nodes, falling back to
+// RedisHost:RedisPort
+func (c *Config) RedisAddresses() []string {
+	if len(c.RedisAddrs) > 0 {
+		return c.RedisAddrs
+	}
+	return []string{fmt.Sprintf("%s:%d", c.RedisHost, c.RedisPort)}
+}
+
+func (c *Config) buildPostgresURL() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.PostgresUser, c.PostgresPassword),
+		Host:     fmt.Sprintf("%s:%d", c.PostgresHost, c.PostgresPort),
+		Path:     "/" + c.PostgresDB,
+		RawQuery: "sslmode=disable",
+	}
+	return u.String()
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.PostgresURL != "", "postgres: set postgres_url or postgres_password (POSTGRES_URL / POSTGRES_PASSWORD)")
+	check(c.PostgresMaxConns >= 1, "postgres_max_conns must be at least 1")
+	check(c.PostgresMinConns >= 0 && c.PostgresMinConns <= c.PostgresMaxConns, "postgres_min_conns must be within [0, postgres_max_conns]")
+	check(c.PostgresMaxConnLifetime >= 0, "postgres_max_conn_lifetime must not be negative")
+	check(c.PostgresStatementTimeout >= 0, "postgres_statement_timeout must not be negative")
+	if len(c.RedisAddrs) == 0 {
+		check(c.RedisHost != "", "redis_host is required")
+		check(c.RedisPort > 0 && c.RedisPort < 65536, "redis_port %d out of range", c.RedisPort)
+	}
+	check(c.RedisMasterName == "" || !c.RedisCluster, "redis_master_name and redis_cluster are mutually exclusive")
+	check((c.RedisMasterName == "" && !c.RedisCluster) || len(c.RedisAddrs) > 0, "redis_addrs is required for Sentinel or Cluster")
+	check(!c.RedisCluster || c.RedisDB == 0, "redis_db is not supported with redis_cluster")
+	for name, p := range c.Platforms {
+		if p == nil {
+			errs = append(errs, fmt.Errorf("platforms.%s is empty", name))
+			continue
+		}
+		check(isHTTPURL(p.URL), "platforms.%s.url %q is not an http(s) URL", name, p.URL)
+		check((p.TLSCertFile == "") == (p.TLSKeyFile == ""), "platforms.%s: tls_cert_file and tls_key_file must be set together", name)
+		check(p.TLSCertFile == "" || strings.HasPrefix(p.URL, "https://"), "platforms.%s: mTLS requires an https url", name)
+		check(p.Timeout >= 0, "platforms.%s.timeout must not be negative", name)
+		check(p.ConnectTimeout >= 0, "platforms.%s.connect_timeout must not be negative", name)
+		check(p.MaxIdleConns >= -1, "platforms.%s.max_idle_conns must be -1 or more", name)
+		check(p.IdleConnTimeout >= 0, "platforms.%s.idle_conn_timeout must not be negative", name)
+		check(p.Proxy == "" || isProxyURL(p.Proxy), "platforms.%s.proxy is not an http(s) or socks5 URL", name)
+		check(p.RateLimit >= 0, "platforms.%s.rate_limit must not be negative", name)
+		check(p.RateBurst >= 0, "platforms.%s.rate_burst must not be negative", name)
+		check(p.BatchSize >= 0, "platforms.%s.batch_size must not be negative", name)
+		check(p.Costs.MakerFee >= 0 && p.Costs.MakerFee < 1, "platforms.%s.costs.maker_fee must be in [0, 1)", name)
+		check(p.Costs.TakerFee >= 0 && p.Costs.TakerFee < 1, "platforms.%s.costs.taker_fee must be in [0, 1)", name)
+		check(p.Costs.Slippage >= 0 && p.Costs.Impact >= 0, "platforms.%s.costs: slippage and impact must not be negative", name)
+		check(p.ContractSize >= 0 && p.CollateralUSD >= 0, "platforms.%s: contract_size and collateral_usd must not be negative", name)
+		for _, t := range p.OrderTypes {
+			switch strings.ToLower(t) {
+			case "limit", "market", "post_only":
+			default:
+				errs = append(errs, fmt.Errorf("platforms.%s.order_types: unknown value %q", name, t))
+			}
+		}
+		for _, tif := range p.TimeInForce {
+			switch strings.ToUpper(tif) {
+			case "GTC", "IOC", "FOK", "GTD":
+			default:
+				errs = append(errs, fmt.Errorf("platforms.%s.time_in_force: unknown value %q", name, tif))
+			}
+		}
+		if p.CLOB != nil {
+			check(p.CLOB.URL == "" || isHTTPURL(p.CLOB.URL), "platforms.%s.clob.url %q is not an http(s) URL", name, p.CLOB.URL)
+			check(p.CLOB.ChainID >= 0, "platforms.%s.clob.chain_id must not be negative", name)
+			check(p.CLOB.Timeout >= 0, "platforms.%s.clob.timeout must not be negative", name)
+			for id, a := range p.CLOB.Accounts {
+				if a == nil {
+					errs = append(errs, fmt.Errorf("platforms.%s.clob.accounts.%s is empty", name, id))
+					continue
+				}
+				check(a.PrivateKey != "", "platforms.%s.clob.accounts.%s.private_key is required", name, id)
+				check(a.APIKey != "" && a.APISecret != "" && a.APIPassphrase != "",
+					"platforms.%s.clob.accounts.%s: api_key, api_secret and api_passphrase are required", name, id)
+				check(a.SignatureType >= 0 && a.SignatureType <= 2,
+					"platforms.%s.clob.accounts.%s.signature_type must be 0, 1 or 2", name, id)
+				check(a.SignatureType == 0 || a.Funder != "",
+					"platforms.%s.clob.accounts.%s: signature_type %d requires funder", name, id, a.SignatureType)
+			}
+		}
+	}
+
+	validLevel := func(level string) bool {
+		switch level {
+		case "trace", "debug", "info", "warn", "error", "fatal", "panic", "disabled":
+			return true
+		}
+		return false
+	}
+	check(validLevel(c.LogLevel), "log_level %q is not a valid level", c.LogLevel)
+	for name, level := range c.StrategyLogLevels {
+		check(validLevel(level), "strategy_log_levels.%s: %q is not a valid level", name, level)
+	}
+	check(c.LogFormat == "console" || c.LogFormat == "json", "log_format %q must be console or json", c.LogFormat)
+
+	check(c.DedupTTL >= 0, "dedup_ttl must not be negative")
+	check(c.ExecutionReportInterval >= 0, "exec_report_interval must not be negative")
+	check(c.ExecutionReportWindow > 0, "exec_report_window must be positive")
+	check(c.DailyReportAt >= 0 && c.DailyReportAt < 24*time.Hour, "daily_report_at must be within [0, 24h)")
+	check(c.DailyReportSlackWebhook == "" || isHTTPURL(c.DailyReportSlackWebhook), "daily_report_slack_webhook is not an http(s) URL")
+	if e := c.DailyReportEmail; e != nil {
+		check(e.SMTPAddr != "" && strings.Contains(e.SMTPAddr, ":"), "daily_report_email.smtp_addr must be host:port")
+		check(e.From != "", "daily_report_email.from is required")
+		check(len(e.To) > 0, "daily_report_email.to is required")
+	}
+	if ch := c.Chaos; ch != nil {
+		rate := func(r float64) bool { return r >= 0 && r <= 1 }
+		check(rate(ch.DropEvents), "chaos.drop_events must be within [0, 1]")
+		check(rate(ch.DelayRequests), "chaos.delay_requests must be within [0, 1]")
+		check(rate(ch.ServiceErrors), "chaos.service_errors must be within [0, 1]")
+		check(ch.MaxDelay >= 0, "chaos.max_delay must not be negative")
+		check(ch.DelayRequests == 0 || ch.MaxDelay > 0, "chaos.max_delay is required with chaos.delay_requests")
+		check(!ch.Enabled || c.DryRun || ch.NonProduction, "chaos needs dry_run or chaos.non_production")
+	}
+	if w := c.WireLog; w != nil {
+		check(w.SampleRate >= 0 && w.SampleRate <= 1, "wire_log.sample_rate must be within [0, 1]")
+		check(w.Retention >= 0, "wire_log.retention must not be negative")
+		check(w.MaxBodyBytes >= 0, "wire_log.max_body_bytes must not be negative")
+	}
+	check(c.ArchiveInterval >= 0, "archive_interval must not be negative")
+	check(c.ArchiveRetention >= 0, "archive_retention must not be negative")
+	for stream, retention := range c.ArchiveStreamRetention {
+		check(retention >= 0, "archive_stream_retention.%s must not be negative", stream)
+	}
+	check(c.ArchiveCompactAfter >= 0, "archive_compact_after must not be negative")
+	for id, k := range c.ConfigKeys {
+		check(k != nil && k.Key != "", "config_keys.%s.key is required", id)
+		check(!strings.Contains(id, ":"), "config_keys.%s: key IDs must not contain ':'", id)
+	}
+	if len(c.ConfigKeys) > 0 {
+		check(c.ConfigKeys[c.ConfigKeyID] != nil, "config_key_id %q is not in config_keys", c.ConfigKeyID)
+	}
+	check(c.StreamMaxLen >= 0, "stream_max_len must not be negative")
+	for stream, t := range c.StreamTrim {
+		if t == nil {
+			errs = append(errs, fmt.Errorf("stream_trim.%s is empty", stream))
+			continue
+		}
+		check(t.MaxLen >= 0, "stream_trim.%s.max_len must not be negative", stream)
+		check(t.MaxAge >= 0, "stream_trim.%s.max_age must not be negative", stream)
+		// Entries must outlive the archiver's cursor or they are never archived
+		check(t.MaxAge == 0 || c.ArchiveInterval == 0 || t.MaxAge > 2*c.ArchiveInterval,
+			"stream_trim.%s.max_age must be more than twice archive_interval", stream)
+	}
+	check(c.StreamTrimInterval >= 0, "stream_trim_interval must not be negative")
+	catchUps := map[string]*CatchUpConfig{"catch_up": &c.CatchUp}
+	for stream, policy := range c.CatchUpStreams {
+		if policy == nil {
+			errs = append(errs, fmt.Errorf("catch_up_streams.%s is empty", stream))
+			continue
+		}
+		catchUps["catch_up_streams."+stream] = policy
+	}
+	for name, policy := range catchUps {
+		check(policy.Mode == "skip" || policy.Mode == "dry_run" || policy.Mode == "replay",
+			"%s.mode must be skip, dry_run or replay", name)
+		check(policy.MaxAge >= 0, "%s.max_age must not be negative", name)
+		check(policy.Mode != "replay" || policy.MaxAge > 0, "%s.max_age is required with mode replay", name)
+	}
+	check(c.OrderBookPollInterval >= 0, "orderbook_poll_interval must not be negative")
+	check(c.AutoDisableWindow > 0, "auto_disable_window must be positive")
+	check(c.AutoDisableMinHitRate >= 0 && c.AutoDisableMinHitRate <= 1, "auto_disable_min_hit_rate must be within [0, 1]")
+	check(c.AutoDisableMinTrades >= 0, "auto_disable_min_trades must not be negative")
+	check(c.MaxHandlerPanics >= 1, "max_handler_panics must be at least 1")
+	check(c.RejectBackoffAfter >= 0, "reject_backoff_after must not be negative")
+	if c.RejectBackoffAfter > 0 {
+		check(c.RejectBackoff > 0, "reject_backoff must be positive")
+		check(c.RejectBackoffMax >= c.RejectBackoff, "reject_backoff_max must be at least reject_backoff")
+	}
+	check(c.HandlerTimeout >= 0, "handler_timeout must not be negative")
+	for name, g := range c.StrategyGroups {
+		if g == nil {
+			errs = append(errs, fmt.Errorf("strategy_groups.%s is empty", name))
+			continue
+		}
+		check(g.MaxExposure >= 0, "strategy_groups.%s.max_exposure must not be negative", name)
+		check(g.MaxDailyLoss >= 0, "strategy_groups.%s.max_daily_loss must not be negative", name)
+	}
+	check(c.OrderTTL >= 0, "order_ttl must not be negative")
+	check(c.StrategyWarmup >= 0, "strategy_warmup must not be negative")
+	check(c.ReconcileInterval >= 0, "reconcile_interval must not be negative")
+	check(c.PositionDriftInterval >= 0, "position_drift_interval must not be negative")
+	check(c.PositionDriftTolerance >= 0, "position_drift_tolerance must not be negative")
+	check(c.MaxStreamLag >= 0, "max_stream_lag must not be negative")
+	check(c.MaxQueueBacklog >= 0, "max_queue_backlog must not be negative")
+	check(c.StateFlushInterval >= 0, "state_flush_interval must not be negative")
+	check(c.MaxPriceDeviation >= 0, "max_price_deviation must not be negative")
+	check(c.MaxPriceMove >= 0, "max_price_move must not be negative")
+	check(c.MaxPriceMove == 0 || c.PriceMoveWindow > 0, "price_move_window must be positive")
+	check(c.PriceMoveCooldown >= 0, "price_move_cooldown must not be negative")
+	switch c.SelfTradePrevention {
+	case "off", "skip", "reprice", "cancel_replace":
+	default:
+		errs = append(errs, fmt.Errorf("self_trade_prevention %q must be off, skip, reprice or cancel_replace", c.SelfTradePrevention))
+	}
+	check(c.HTTPAddr != "", "http_addr is required")
+	for name := range c.StrategyGroups {
+		check(!strings.HasPrefix(name, "tenant:"), "strategy_groups.%s: the tenant: prefix is reserved for tenants", name)
+	}
+	owners := make(map[string]string)
+	tokens := make(map[string]string)
+	for name, t := range c.Tenants {
+		if t == nil {
+			errs = append(errs, fmt.Errorf("tenants.%s is empty", name))
+			continue
+		}
+		check(t.MaxExposure >= 0, "tenants.%s.max_exposure must not be negative", name)
+		check(t.MaxDailyLoss >= 0, "tenants.%s.max_daily_loss must not be negative", name)
+		for _, account := range t.Accounts {
+			if owner, ok := owners[account]; ok && owner != name {
+				errs = append(errs, fmt.Errorf("tenants.%s: account %s already belongs to tenant %s", name, account, owner))
+			}
+			owners[account] = name
+		}
+		if t.APIToken != "" {
+			check(t.APIToken != c.AdminToken, "tenants.%s.api_token must differ from admin_token", name)
+			if other, ok := tokens[t.APIToken]; ok {
+				errs = append(errs, fmt.Errorf("tenants.%s.api_token is also the token of tenant %s", name, other))
+			}
+			tokens[t.APIToken] = name
+		}
+	}
+	check(len(tokens) == 0 || c.AdminToken != "", "admin_token is required when tenants have api tokens")
+	check(c.CompareMode || c.AdminToken != "" || loopbackAddr(c.HTTPAddr),
+		"admin_token is required when http_addr %s is not a loopback address", c.HTTPAddr)
+	check(c.IncidentDir != "", "incident_dir is required")
+	check(c.ShardCount >= 1, "shard_count must be at least 1")
+	check(c.ShardIndex >= 0 && c.ShardIndex < c.ShardCount, "shard_index %d out of range for shard_count %d", c.ShardIndex, c.ShardCount)
+	check(c.InstanceName != "", "instance_name is required")
+	check(!c.CompareMode || c.InstanceName != "live", "compare_mode needs an instance_name other than live")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isProxyURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "socks5") && u.Host != ""
+}
+
+// envOverrides applies set environment variables over the current values and
+// collects values that fail to parse, instead of silently ignoring them.
+type envOverrides struct {
+	errs []error
+}
+
+func (o *envOverrides) string(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+// file points dst at a mounted secret file; it is read when secrets are resolved
+func (o *envOverrides) file(key string, dst *string) {
+	if path := os.Getenv(key); path != "" {
+		*dst = "file:" + path
+	}
+}
+
+// strings parses a comma-separated list
+func (o *envOverrides) strings(key string, dst *[]string) {
+	if value := os.Getenv(key); value != "" {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*dst = list
+	}
+}
+
+func (o *envOverrides) int(key string, dst *int) {
+	if value := os.Getenv(key); value != "" {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: %w", key, err))
+			return
+		}
+		*dst = i
+	}
+}
+
+func (o *envOverrides) float(key string, dst *float64) {
 	if value := os.Getenv(key); value != "" {
-		return value
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: %w", key, err))
+			return
+		}
+		*dst = f
 	}
-	return fallback
 }
 
-func getEnvInt(key string, fallback int) int {
+func (o *envOverrides) bool(key string, dst *bool) {
 	if value := os.Getenv(key); value != "" {
-		if i, err := strconv.Atoi(value); err == nil {
-			return i
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: %w", key, err))
+			return
 		}
+		*dst = b
 	}
-	return fallback
 }
 
-func getEnvBool(key string, fallback bool) bool {
+func (o *envOverrides) duration(key string, dst *time.Duration) {
 	if value := os.Getenv(key); value != "" {
-		if b, err := strconv.ParseBool(value); err == nil {
-			return b
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: %w", key, err))
+			return
 		}
+		*dst = d
+	}
+}
+
+// loopbackAddr reports whether a listen address only accepts connections from
+// the same host
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
 	}
-	return fallback
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// reloadable lists the settings (by yaml key) that can change while the engine
+// runs. Everything else is wired into connections, listeners or goroutines at
+// startup and needs a restart.
+var reloadable = map[string]bool{
+	"log_level":                 true,
+	"strategy_log_levels":       true,
+	"dry_run":                   true,
+	"dedup_ttl":                 true,
+	"auto_disable":              true,
+	"auto_disable_window":       true,
+	"auto_disable_min_pnl":      true,
+	"auto_disable_min_hit_rate": true,
+	"auto_disable_min_trades":   true,
+	"max_price_deviation":       true,
+	"self_trade_prevention":     true,
+	"handler_timeout":           true,
+	"strategy_groups":           true,
+	"order_ttl":                 true,
+	"max_stream_lag":            true,
+	"max_queue_backlog":         true,
+}
+
+// Changes lists the settings that differ between two configs, by yaml key
+type Changes struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// Diff compares c with next. Only key names are reported, never values, so the
+// result is safe to log even when credentials changed.
+func (c *Config) Diff(next *Config) Changes {
+	changes := Changes{Applied: []string{}, RestartRequired: []string{}}
+
+	current := reflect.ValueOf(c).Elem()
+	updated := reflect.ValueOf(next).Elem()
+	for i := 0; i < current.NumField(); i++ {
+		field := current.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if reflect.DeepEqual(current.Field(i).Interface(), updated.Field(i).Interface()) {
+			continue
+		}
+
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		// A rotated secret behind an unchanged reference is not a config change
+		if ref := c.secretRefs[key]; ref != "" && ref == next.secretRefs[key] {
+			continue
+		}
+
+		if reloadable[key] {
+			changes.Applied = append(changes.Applied, key)
+		} else {
+			changes.RestartRequired = append(changes.RestartRequired, key)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+)
+
+// secretFields lists the settings (by yaml key) that may hold a secret
+// reference (file:... or vault:path#key) instead of a literal value
+func (c *Config) secretFields() map[string]*string {
+	fields := map[string]*string{
+		"postgres_url":               &c.PostgresURL,
+		"postgres_password":          &c.PostgresPassword,
+		"redis_username":             &c.RedisUsername,
+		"redis_password":             &c.RedisPassword,
+		"redis_sentinel_password":    &c.RedisSentinelPassword,
+		"predict_account_url":        &c.PredictAccountURL,
+		"predict_account_token":      &c.PredictAccountToken,
+		"polymarket_account_url":     &c.PolymarketAccountURL,
+		"polymarket_account_token":   &c.PolymarketAccountToken,
+		"admin_token":                &c.AdminToken,
+		"daily_report_slack_webhook": &c.DailyReportSlackWebhook,
+	}
+	if c.DailyReportEmail != nil {
+		fields["daily_report_email.password"] = &c.DailyReportEmail.Password
+	}
+	for name, t := range c.Tenants {
+		if t != nil {
+			fields["tenants."+name+".api_token"] = &t.APIToken
+		}
+	}
+	for id, k := range c.ConfigKeys {
+		if k != nil {
+			fields["config_keys."+id+".key"] = &k.Key
+		}
+	}
+	for name, p := range c.Platforms {
+		if p != nil {
+			fields["platforms."+name+".url"] = &p.URL
+			fields["platforms."+name+".auth_token"] = &p.AuthToken
+			fields["platforms."+name+".hmac_secret"] = &p.HMACSecret
+			fields["platforms."+name+".proxy"] = &p.Proxy
+			if p.CLOB != nil {
+				for id, a := range p.CLOB.Accounts {
+					if a == nil {
+						continue
+					}
+					prefix := "platforms." + name + ".clob.accounts." + id
+					fields[prefix+".private_key"] = &a.PrivateKey
+					fields[prefix+".api_key"] = &a.APIKey
+					fields[prefix+".api_secret"] = &a.APISecret
+					fields[prefix+".api_passphrase"] = &a.APIPassphrase
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// resolveSecrets replaces secret references with their values, remembering the
+// references so credentials can be re-read later, and registers every
+// credential for log redaction.
+func (c *Config) resolveSecrets(resolver *secrets.Resolver) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c.resolver = resolver
+	c.secretRefs = make(map[string]string)
+
+	for key, field := range c.secretFields() {
+		if !secrets.IsRef(*field) {
+			continue
+		}
+		value, err := resolver.Resolve(ctx, *field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		c.secretRefs[key] = *field
+		*field = value
+	}
+
+	secrets.Register(c.PostgresPassword, c.RedisPassword, c.RedisSentinelPassword, c.AdminToken, c.DailyReportSlackWebhook)
+	if c.DailyReportEmail != nil {
+		secrets.Register(c.DailyReportEmail.Password)
+	}
+	for _, t := range c.Tenants {
+		if t != nil {
+			secrets.Register(t.APIToken)
+		}
+	}
+	for _, k := range c.ConfigKeys {
+		if k != nil {
+			secrets.Register(k.Key)
+		}
+	}
+	urls := []string{c.PostgresURL}
+	for _, p := range c.Platforms {
+		if p != nil {
+			secrets.Register(p.AuthToken, p.HMACSecret)
+			urls = append(urls, p.URL, p.Proxy)
+			if p.CLOB != nil {
+				for _, a := range p.CLOB.Accounts {
+					if a != nil {
+						secrets.Register(a.PrivateKey, a.APIKey, a.APISecret, a.APIPassphrase)
+					}
+				}
+			}
+		}
+	}
+	for _, raw := range urls {
+		if u, err := url.Parse(raw); err == nil && u.User != nil {
+			password, _ := u.User.Password()
+			secrets.Register(password)
+		}
+	}
+
+	return nil
+}
+
+// PostgresPasswordFunc re-resolves postgres_password on every call, so new pool
+// connections pick up a rotated secret file or Vault value. It returns nil when
+// the password is a literal.
+func (c *Config) PostgresPasswordFunc() func(ctx context.Context) (string, error) {
+	ref := c.secretRefs["postgres_password"]
+	if ref == "" {
+		return nil
+	}
+	resolver := c.resolver
+	return func(ctx context.Context) (string, error) {
+		return resolver.Resolve(ctx, ref)
+	}
+}
@@ -0,0 +1,91 @@
+// Package costs models the trading fees and expected slippage of an order, so
+// strategies can price against what a fill will actually cost.
+package costs
+
+import (
+	"math"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+)
+
+// Platform is the cost model of one platform
+type Platform struct {
+	// MakerFee and TakerFee are fractions of notional, used for markets whose
+	// own fees are unknown
+	MakerFee float64
+	TakerFee float64
+
+	// Slippage is the expected price move per share of an order that crosses
+	// the book, plus Impact for every share it takes
+	Slippage float64
+	Impact   float64
+}
+
+// Estimate is the modeled cost of buying shares at a limit price
+type Estimate struct {
+	Price          float64 `json:"price"`
+	Shares         float64 `json:"shares"`
+	Fee            float64 `json:"fee"`      // total, in USD
+	Slippage       float64 `json:"slippage"` // total, in USD
+	Cost           float64 `json:"cost"`     // Fee + Slippage
+	EffectivePrice float64 `json:"effective_price"`
+}
+
+// Model estimates order costs per platform. Market fees from the registry take
+// precedence over the platform's. A nil Model has no costs. It is safe for
+// concurrent use once built.
+type Model struct {
+	platforms map[string]Platform
+	markets   *markets.Registry
+}
+
+// NewModel returns a model over the given platforms. registry may be nil.
+func NewModel(platforms map[string]Platform, registry *markets.Registry) *Model {
+	return &Model{platforms: platforms, markets: registry}
+}
+
+// Fee returns the fee rate of a market, taker or maker
+func (m *Model) Fee(platform, marketID string, taker bool) float64 {
+	if m == nil {
+		return 0
+	}
+	if m.markets != nil {
+		if info, ok := m.markets.Info(marketID); ok {
+			if taker && info.TakerFee > 0 {
+				return info.TakerFee
+			}
+			if !taker && info.MakerFee > 0 {
+				return info.MakerFee
+			}
+		}
+	}
+	p := m.platforms[platform]
+	if taker {
+		return p.TakerFee
+	}
+	return p.MakerFee
+}
+
+// Estimate models the cost of buying shares at price. Resting (maker) orders
+// are not expected to slip.
+func (m *Model) Estimate(platform, marketID string, price, shares float64, taker bool) Estimate {
+	est := Estimate{Price: price, Shares: shares, EffectivePrice: price}
+	if m == nil || shares <= 0 {
+		return est
+	}
+
+	est.Fee = price * shares * m.Fee(platform, marketID, taker)
+	if taker {
+		p := m.platforms[platform]
+		slip := math.Min(p.Slippage+p.Impact*shares, math.Max(1-price, 0))
+		est.Slippage = slip * shares
+	}
+	est.Cost = est.Fee + est.Slippage
+	est.EffectivePrice = price + est.Cost/shares
+	return est
+}
+
+// EffectivePrice returns the all-in price per share of crossing the book at price
+func (m *Model) EffectivePrice(platform, marketID string, price, shares float64) float64 {
+	return m.Estimate(platform, marketID, price, shares, true).EffectivePrice
+}
@@ -0,0 +1,320 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// A strategy runs an A/B test when its config has an "ab_test" section:
+//
+//	"ab_test": {
+//	  "split": "market",
+//	  "variants": {"control": {}, "wide": {"spread": 0.04}},
+//	  "weights": {"control": 0.5, "wide": 0.5}
+//	}
+//
+// Each variant is loaded as its own strategy named "<name>:<variant>", with
+// the variant's keys laid over the base config. Variants have their own
+// state, counters and journal entries, and their commands carry "variant" and
+// "experiment" metadata. The split decides which variant gets an event:
+// "market" hashes the event's market, so a market stays with one variant,
+// and "random" draws every event. Weights default to equal shares. Events
+// without a market reach every variant, and fills and results of a variant's
+// own orders go back to that variant. Engine.Experiment compares the
+// variants over a window.
+
+// abTestKey is the config key of an A/B test
+const abTestKey = "ab_test"
+
+// Split modes of an A/B test
+const (
+	SplitMarket = "market"
+	SplitRandom = "random"
+)
+
+// ErrNoExperiment is returned for experiment reports of strategies without
+// an A/B test
+var ErrNoExperiment = errors.New("strategy has no A/B test")
+
+// ABTest is the parsed ab_test section of a strategy config
+type ABTest struct {
+	Strategy string // name of the strategy under test
+	Split    string
+	Variants []ABVariant // sorted by name
+}
+
+// ABVariant is one arm of an A/B test
+type ABVariant struct {
+	Name      string
+	Weight    float64 // share of the events, the weights summing to 1
+	Overrides map[string]interface{}
+}
+
+// VariantName is the name a variant of a strategy runs under
+func VariantName(name, variant string) string {
+	return name + ":" + variant
+}
+
+// ParseABTest reads the ab_test section of a strategy config; it returns nil
+// if there is none
+func ParseABTest(name string, config map[string]interface{}) (*ABTest, error) {
+	raw, ok := config[abTestKey]
+	if !ok {
+		return nil, nil
+	}
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("ab_test must be an object")
+	}
+
+	test := &ABTest{Strategy: name, Split: SplitMarket}
+	if split, ok := section["split"].(string); ok {
+		test.Split = split
+	}
+	if test.Split != SplitMarket && test.Split != SplitRandom {
+		return nil, fmt.Errorf("ab_test split %q must be market or random", test.Split)
+	}
+
+	variants, ok := section["variants"].(map[string]interface{})
+	if !ok || len(variants) < 2 {
+		return nil, errors.New("ab_test needs at least two variants")
+	}
+	weights, _ := section["weights"].(map[string]interface{})
+
+	var total float64
+	for variant, raw := range variants {
+		if variant == "" || strings.ContainsAny(variant, ":/") {
+			return nil, fmt.Errorf("ab_test variant name %q is invalid", variant)
+		}
+		overrides, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ab_test variant %s must be an object", variant)
+		}
+		if _, nested := overrides[abTestKey]; nested {
+			return nil, fmt.Errorf("ab_test variant %s cannot have an ab_test", variant)
+		}
+
+		weight := 1.0
+		if weights != nil {
+			w, ok := weights[variant].(float64)
+			if !ok || w <= 0 {
+				return nil, fmt.Errorf("ab_test weight of variant %s must be a positive number", variant)
+			}
+			weight = w
+		}
+		total += weight
+		test.Variants = append(test.Variants, ABVariant{Name: variant, Weight: weight, Overrides: overrides})
+	}
+	for name := range weights {
+		if _, ok := variants[name]; !ok {
+			return nil, fmt.Errorf("ab_test weight for unknown variant %s", name)
+		}
+	}
+
+	sort.Slice(test.Variants, func(i, j int) bool { return test.Variants[i].Name < test.Variants[j].Name })
+	for i := range test.Variants {
+		test.Variants[i].Weight /= total
+	}
+	return test, nil
+}
+
+// expandVariants returns a strategy as the strategies of its variants, or as
+// is if it has no A/B test
+func expandVariants(strategy types.Strategy) ([]types.Strategy, *ABTest, error) {
+	test, err := ParseABTest(strategy.Name, strategy.Config)
+	if err != nil || test == nil {
+		return []types.Strategy{strategy}, nil, err
+	}
+
+	variants := make([]types.Strategy, 0, len(test.Variants))
+	for _, v := range test.Variants {
+		config := make(map[string]interface{}, len(strategy.Config)+len(v.Overrides))
+		for key, value := range strategy.Config {
+			if key != abTestKey {
+				config[key] = value
+			}
+		}
+		for key, value := range v.Overrides {
+			config[key] = value
+		}
+
+		variant := strategy
+		variant.ID = strategy.ID + ":" + v.Name
+		variant.Name = VariantName(strategy.Name, v.Name)
+		variant.Config = config
+		variant.Variant = v.Name
+		variant.VariantOf = strategy.ID
+		variants = append(variants, variant)
+	}
+	return variants, test, nil
+}
+
+// liveStrategyID returns the ID a strategy is stored under: a variant's is
+// the one of the strategy under test
+func liveStrategyID(strategy types.Strategy) string {
+	if strategy.VariantOf != "" {
+		return strategy.VariantOf
+	}
+	return strategy.ID
+}
+
+// assign picks the variant that gets an event, or "" if every variant should
+func (t *ABTest) assign(event types.Event) string {
+	marketID, _ := event.Data["market_id"].(string)
+	if marketID == "" {
+		return ""
+	}
+
+	var u float64
+	switch t.Split {
+	case SplitRandom:
+		u = rand.Float64()
+	default:
+		h := fnv.New32a()
+		h.Write([]byte(t.Strategy + ":" + marketID))
+		u = float64(h.Sum32()) / (1 << 32)
+	}
+
+	for _, v := range t.Variants {
+		if u < v.Weight {
+			return v.Name
+		}
+		u -= v.Weight
+	}
+	return t.Variants[len(t.Variants)-1].Name
+}
+
+// splitVariants drops the variants of A/B tests that should not get an
+// event. A variant's own orders and results only reach that variant.
+func (e *Engine) splitVariants(event types.Event, receivers []types.Strategy) []types.Strategy {
+	e.mu.RLock()
+	experiments := e.experiments
+	e.mu.RUnlock()
+	if len(experiments) == 0 {
+		return receivers
+	}
+
+	owner := e.eventOwner(event)
+	chosen := make(map[string]string) // by live strategy ID
+	kept := receivers[:0]
+	for _, strategy := range receivers {
+		test := experiments[strategy.VariantOf]
+		if strategy.VariantOf == "" || test == nil {
+			kept = append(kept, strategy)
+			continue
+		}
+
+		if strings.HasPrefix(owner, test.Strategy+":") {
+			if owner == strategy.Name {
+				kept = append(kept, strategy)
+			}
+			continue
+		}
+
+		variant, ok := chosen[strategy.VariantOf]
+		if !ok {
+			variant = test.assign(event)
+			chosen[strategy.VariantOf] = variant
+		}
+		if variant == "" || variant == strategy.Variant {
+			kept = append(kept, strategy)
+		}
+	}
+	return kept
+}
+
+// eventOwner returns the strategy that placed the order an event is about,
+// from the event itself or the tracked orders
+func (e *Engine) eventOwner(event types.Event) string {
+	if strategy, _ := event.Data["strategy"].(string); strategy != "" {
+		return strategy
+	}
+	if orderID, _ := event.Data["order_id"].(string); orderID != "" {
+		if order, ok := e.orders.Get(event.Platform, orderID); ok {
+			return order.Strategy
+		}
+	}
+	return ""
+}
+
+// tagVariant marks the commands of a variant with its name and experiment
+func tagVariant(strategy types.Strategy, commands []types.Command) {
+	if strategy.Variant == "" {
+		return
+	}
+	experiment := strings.TrimSuffix(strategy.Name, ":"+strategy.Variant)
+	for i := range commands {
+		if commands[i].Metadata == nil {
+			commands[i].Metadata = make(map[string]interface{})
+		}
+		commands[i].Metadata["variant"] = strategy.Variant
+		commands[i].Metadata["experiment"] = experiment
+		if _, ok := commands[i].Metadata["strategy"]; !ok {
+			commands[i].Metadata["strategy"] = strategy.Name
+		}
+	}
+}
+
+// ExperimentReport compares the variants of an A/B test over a window
+type ExperimentReport struct {
+	Strategy string          `json:"strategy"`
+	Split    string          `json:"split"`
+	Window   string          `json:"window"`
+	Variants []VariantReport `json:"variants"`
+}
+
+// VariantReport is the activity, fills and realized PnL of one variant
+type VariantReport struct {
+	Variant string  `json:"variant"`
+	Weight  float64 `json:"weight"`
+	types.StrategyAttribution
+	PnLPerTrade float64 `json:"pnl_per_trade"`
+}
+
+// Experiment reports on the A/B test of the named strategy
+func (e *Engine) Experiment(ctx context.Context, name string, window time.Duration) (ExperimentReport, error) {
+	var test *ABTest
+	e.mu.RLock()
+	for _, t := range e.experiments {
+		if t.Strategy == name {
+			test = t
+			break
+		}
+	}
+	e.mu.RUnlock()
+	if test == nil {
+		return ExperimentReport{}, fmt.Errorf("%w: %s", ErrNoExperiment, name)
+	}
+
+	attribution, err := e.Attribution(ctx, window)
+	if err != nil {
+		return ExperimentReport{}, err
+	}
+	byName := make(map[string]types.StrategyAttribution, len(attribution))
+	for _, a := range attribution {
+		byName[a.Strategy] = a
+	}
+
+	report := ExperimentReport{Strategy: name, Split: test.Split, Window: window.String()}
+	for _, v := range test.Variants {
+		variantName := VariantName(name, v.Name)
+		a, ok := byName[variantName]
+		if !ok {
+			a = types.StrategyAttribution{Strategy: variantName}
+		}
+		entry := VariantReport{Variant: v.Name, Weight: v.Weight, StrategyAttribution: a}
+		if a.Trades > 0 {
+			entry.PnLPerTrade = a.RealizedPnL / float64(a.Trades)
+		}
+		report.Variants = append(report.Variants, entry)
+	}
+	return report, nil
+}
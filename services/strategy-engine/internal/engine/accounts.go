@@ -0,0 +1,46 @@
+package engine
+
+import "github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+
+type managedAccount struct {
+	Platform  string
+	AccountID string
+}
+
+// managedAccounts returns the accounts a strategy trades on: its ActiveAccounts
+// plus both sides of every configured pair, on the strategy's target platform.
+func managedAccounts(strategy types.Strategy) []managedAccount {
+	platform, _ := strategy.Config["target_platform"].(string)
+	if platform == "" {
+		platform = "predict"
+	}
+
+	seen := make(map[string]bool)
+	var accounts []managedAccount
+
+	add := func(accountID string) {
+		if accountID == "" || seen[accountID] {
+			return
+		}
+		seen[accountID] = true
+		accounts = append(accounts, managedAccount{Platform: platform, AccountID: accountID})
+	}
+
+	for _, accountID := range strategy.ActiveAccounts {
+		add(accountID)
+	}
+
+	pairs, _ := strategy.Config["pairs"].([]interface{})
+	for _, pairRaw := range pairs {
+		pair, ok := pairRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		primaryID, _ := pair["primary"].(string)
+		hedgeID, _ := pair["hedge"].(string)
+		add(primaryID)
+		add(hedgeID)
+	}
+
+	return accounts
+}
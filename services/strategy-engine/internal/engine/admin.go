@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Strategies returns every strategy in the database, enabled or not
+func (e *Engine) Strategies(ctx context.Context) ([]types.Strategy, error) {
+	return e.storage.GetStrategies(ctx)
+}
+
+// SetStrategyEnabled turns the named strategy on or off and reloads the
+// strategies, so the change takes effect immediately
+func (e *Engine) SetStrategyEnabled(ctx context.Context, name string, enabled bool, operator, reason string) error {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if enabled {
+		if err := e.storage.EnableStrategy(ctx, strategy.ID); err != nil {
+			return err
+		}
+		log.Warn().
+			Str("strategy", strategy.Name).
+			Str("operator", operator).
+			Str("reason", reason).
+			Msg("Strategy enabled")
+	} else {
+		e.disableStrategy(ctx, *strategy, fmt.Sprintf("disabled by %s: %s", operator, reason))
+	}
+
+	return e.ReloadStrategies(ctx)
+}
+
+// SetPairEnabled switches one pair of a strategy's "pairs" config on or off,
+// by its primary account, leaving the strategy and its other pairs running.
+// The change is written to the live config.
+func (e *Engine) SetPairEnabled(ctx context.Context, name, primary string, enabled bool, operator, reason string) error {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if err := e.storage.SetStrategyPairEnabled(ctx, strategy.ID, primary, enabled); err != nil {
+		return err
+	}
+
+	log.Warn().
+		Str("strategy", strategy.Name).
+		Str("primary", primary).
+		Bool("enabled", enabled).
+		Str("operator", operator).
+		Str("reason", reason).
+		Msg("Strategy pair switched")
+
+	return e.ReloadStrategies(ctx)
+}
+
+// AccountPositions is what an account service reports for one account
+type AccountPositions struct {
+	Platform  string           `json:"platform"`
+	AccountID string           `json:"account_id"`
+	Positions []types.Position `json:"positions"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// Positions fetches the positions on the accounts of a strategy, or on a
+// single platform/account, from the account services
+func (e *Engine) Positions(ctx context.Context, strategy, platform, accountID string) ([]AccountPositions, error) {
+	accounts, err := e.flattenAccounts(FlattenRequest{Strategy: strategy, Platform: platform, AccountID: accountID})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]AccountPositions, 0, len(accounts))
+	for _, account := range accounts {
+		entry := AccountPositions{Platform: account.Platform, AccountID: account.AccountID, Positions: []types.Position{}}
+		positions, err := e.executor.FetchPositions(ctx, account.Platform, account.AccountID)
+		if err != nil {
+			entry.Error = err.Error()
+		} else if positions != nil {
+			entry.Positions = positions
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// Journal returns the journaled orders since the given time, oldest first
+func (e *Engine) Journal(ctx context.Context, since time.Time) ([]types.OrderRecord, error) {
+	return e.storage.GetJournalSince(ctx, since)
+}
+
+// WireLog returns the recorded account service requests between since and
+// until, newest first, optionally of one platform and containing a string
+func (e *Engine) WireLog(ctx context.Context, platform string, since, until time.Time, contains string, limit int) ([]types.WireLogEntry, error) {
+	return e.storage.GetWireLog(ctx, platform, since, until, contains, limit)
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// strategyCounters counts handler activity per strategy name since start
+type strategyCounters struct {
+	mu     sync.Mutex
+	counts map[string]*types.StrategyAttribution
+}
+
+func newStrategyCounters() *strategyCounters {
+	return &strategyCounters{counts: make(map[string]*types.StrategyAttribution)}
+}
+
+// record counts one handler invocation and the commands it returned
+func (c *strategyCounters) record(strategy string, commands int, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts, ok := c.counts[strategy]
+	if !ok {
+		counts = &types.StrategyAttribution{Strategy: strategy}
+		c.counts[strategy] = counts
+	}
+
+	counts.Events++
+	if failed {
+		counts.HandlerErrors++
+		return
+	}
+	if commands > 0 {
+		counts.EventsMatched++
+		counts.Commands += int64(commands)
+	}
+}
+
+// recordTimeout counts a handler invocation that timed out
+func (c *strategyCounters) recordTimeout(strategy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts, ok := c.counts[strategy]
+	if !ok {
+		counts = &types.StrategyAttribution{Strategy: strategy}
+		c.counts[strategy] = counts
+	}
+
+	counts.Events++
+	counts.HandlerErrors++
+	counts.Timeouts++
+}
+
+func (c *strategyCounters) snapshot() map[string]types.StrategyAttribution {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := make(map[string]types.StrategyAttribution, len(c.counts))
+	for name, counts := range c.counts {
+		snapshot[name] = *counts
+	}
+	return snapshot
+}
+
+// Attribution combines the in-memory event and command counters with the
+// journaled orders, fills and realized PnL of each strategy over the window.
+// Strategies are included if they appear in either source.
+func (e *Engine) Attribution(ctx context.Context, window time.Duration) ([]types.StrategyAttribution, error) {
+	stored, err := e.storage.GetStrategyAttribution(ctx, time.Now().UTC().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+
+	counters := e.counters.snapshot()
+
+	result := make([]types.StrategyAttribution, 0, len(stored)+len(counters))
+	for _, a := range stored {
+		if c, ok := counters[a.Strategy]; ok {
+			a.Events = c.Events
+			a.EventsMatched = c.EventsMatched
+			a.Commands = c.Commands
+			a.HandlerErrors = c.HandlerErrors
+			a.Timeouts = c.Timeouts
+			delete(counters, a.Strategy)
+		}
+		result = append(result, a)
+	}
+	if len(counters) > 0 {
+		e.mu.RLock()
+		for _, strategy := range e.strategies {
+			if c, ok := counters[strategy.Name]; ok {
+				c.Tenant = strategyTenant(strategy)
+				counters[strategy.Name] = c
+			}
+		}
+		e.mu.RUnlock()
+	}
+	for _, c := range counters {
+		result = append(result, c)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Strategy < result[j].Strategy })
+	return result, nil
+}
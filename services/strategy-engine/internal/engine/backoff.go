@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Rejection backoff suspends a strategy whose orders keep being rejected,
+// usually because of a bad config or a delisted market, instead of letting it
+// send the same failing orders on every event. After
+// Options.RejectBackoffAfter consecutive rejections the strategy's events are
+// skipped for Options.RejectBackoff, doubling on each further suspension up
+// to Options.RejectBackoffMax. An accepted order resets the count and the
+// escalation. Orders that failed for other reasons (the account service was
+// down) don't count.
+//
+// Each suspension is published as a strategy_suspended event on ErrorStream
+// and raises an alert. Suspensions live in memory and end on restart.
+
+// EventStrategySuspended is the type of the events published when a strategy
+// is suspended
+const EventStrategySuspended = "strategy_suspended"
+
+type rejectionBackoff struct {
+	mu         sync.Mutex
+	strategies map[string]*strategyBackoff // by strategy name
+}
+
+type strategyBackoff struct {
+	rejections int       // consecutive rejected orders
+	level      int       // suspensions since the last accepted order
+	until      time.Time // end of the current suspension
+}
+
+func newRejectionBackoff() *rejectionBackoff {
+	return &rejectionBackoff{strategies: make(map[string]*strategyBackoff)}
+}
+
+// recordOrderOutcome counts a rejected order against its strategy, or resets
+// the strategy's backoff on an accepted one
+func (e *Engine) recordOrderOutcome(ctx context.Context, record types.OrderRecord) {
+	if e.opts.RejectBackoffAfter <= 0 || record.Strategy == "" {
+		return
+	}
+
+	b := e.backoff
+	b.mu.Lock()
+	state, ok := b.strategies[record.Strategy]
+	switch record.Status {
+	case "accepted":
+		// Orders accepted during a suspension were sent before it
+		if ok && !time.Now().Before(state.until) {
+			delete(b.strategies, record.Strategy)
+		}
+		b.mu.Unlock()
+		return
+	case "rejected":
+	default:
+		b.mu.Unlock()
+		return
+	}
+
+	if !ok {
+		state = &strategyBackoff{}
+		b.strategies[record.Strategy] = state
+	}
+	now := time.Now().UTC()
+	// Rejections of orders sent before the suspension don't extend it
+	if now.Before(state.until) {
+		b.mu.Unlock()
+		return
+	}
+	state.rejections++
+	if state.rejections < e.opts.RejectBackoffAfter {
+		b.mu.Unlock()
+		return
+	}
+
+	duration := e.opts.RejectBackoff << state.level
+	if duration > e.opts.RejectBackoffMax || duration <= 0 {
+		duration = e.opts.RejectBackoffMax
+	}
+	state.level++
+	state.rejections = 0
+	state.until = now.Add(duration)
+	suspension := *state
+	b.mu.Unlock()
+
+	e.publishSuspension(ctx, record, suspension, duration)
+}
+
+// suspendedUntil returns the end of a strategy's suspension, if it is
+// suspended at now
+func (e *Engine) suspendedUntil(name string, now time.Time) (time.Time, bool) {
+	e.backoff.mu.Lock()
+	defer e.backoff.mu.Unlock()
+
+	state, ok := e.backoff.strategies[name]
+	if !ok || !now.Before(state.until) {
+		return time.Time{}, false
+	}
+	return state.until, true
+}
+
+// suspensions returns the end of every current suspension, by strategy name
+func (e *Engine) suspensions(now time.Time) map[string]time.Time {
+	e.backoff.mu.Lock()
+	defer e.backoff.mu.Unlock()
+
+	result := make(map[string]time.Time)
+	for name, state := range e.backoff.strategies {
+		if now.Before(state.until) {
+			result[name] = state.until
+		}
+	}
+	return result
+}
+
+func (e *Engine) publishSuspension(ctx context.Context, record types.OrderRecord, state strategyBackoff, duration time.Duration) {
+	log.Warn().
+		Str("strategy", record.Strategy).
+		Int("suspension", state.level).
+		Dur("duration", duration).
+		Time("until", state.until).
+		Str("last_error", record.Error).
+		Msg("Strategy suspended after repeated order rejections")
+
+	data := map[string]interface{}{
+		"strategy":   record.Strategy,
+		"tenant":     record.Tenant,
+		"rejections": e.opts.RejectBackoffAfter,
+		"suspension": state.level,
+		"duration":   duration.String(),
+		"until":      state.until,
+		"last_error": record.Error,
+	}
+
+	now := time.Now().UTC()
+	event := types.Event{
+		ID:        fmt.Sprintf("%s:%s:%d", EventStrategySuspended, record.Strategy, now.UnixNano()),
+		Type:      EventStrategySuspended,
+		Platform:  record.Platform,
+		Timestamp: now,
+		Data:      data,
+	}
+	if err := e.eventBus.Publish(ctx, ErrorStream, event); err != nil {
+		log.Error().Err(err).Str("strategy", record.Strategy).Msg("Failed to publish strategy suspended event")
+	}
+
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		"Strategy suspended",
+		fmt.Sprintf("Strategy %s had %d orders rejected in a row and is suspended for %s; last error: %s",
+			record.Strategy, e.opts.RejectBackoffAfter, duration, record.Error),
+		data,
+	); err != nil {
+		log.Error().Err(err).Str("strategy", record.Strategy).Msg("Failed to create strategy suspended alert")
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// No account service publishes orderbook_events yet, so with
+// Options.BookPollInterval set the engine fetches the books of the markets it
+// trades itself (GET /markets/{id}/orderbook) and feeds them to the order book
+// cache. A market is polled while the engine has open orders on it or has
+// seen an event for it within bookWatchWindow. Books published on the stream
+// are still read as they arrive.
+
+const (
+	bookWatchWindow  = 10 * time.Minute
+	bookFetchTimeout = 5 * time.Second
+)
+
+// watchedBooks are the markets whose books are polled, with when each was
+// last seen in an event
+type watchedBooks struct {
+	mu      sync.Mutex
+	markets map[bookKey]time.Time
+}
+
+type bookKey struct {
+	platform string
+	marketID string
+}
+
+func newWatchedBooks() *watchedBooks {
+	return &watchedBooks{markets: make(map[bookKey]time.Time)}
+}
+
+// see records an event's market as traded
+func (w *watchedBooks) see(event types.Event) {
+	marketID, _ := event.Data["market_id"].(string)
+	if marketID == "" || event.Platform == "" {
+		return
+	}
+
+	w.mu.Lock()
+	w.markets[bookKey{event.Platform, marketID}] = time.Now()
+	w.mu.Unlock()
+}
+
+// recent returns the markets seen since cutoff and forgets the others
+func (w *watchedBooks) recent(cutoff time.Time) []bookKey {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	keys := make([]bookKey, 0, len(w.markets))
+	for key, seen := range w.markets {
+		if seen.Before(cutoff) {
+			delete(w.markets, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// runBookPoller refreshes the books of the watched markets every interval
+func (e *Engine) runBookPoller(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.pollBooks(ctx)
+		}
+	}
+}
+
+func (e *Engine) pollBooks(ctx context.Context) {
+	markets := make(map[bookKey]bool)
+	for _, key := range e.watched.recent(time.Now().Add(-bookWatchWindow)) {
+		markets[key] = true
+	}
+	for _, order := range e.orders.Open() {
+		markets[bookKey{order.Platform, order.MarketID}] = true
+	}
+
+	configured := make(map[string]bool)
+	for _, platform := range e.executor.Platforms() {
+		configured[platform] = true
+	}
+
+	var polled, failed int
+	for key := range markets {
+		if !configured[key.platform] || !e.opts.Shard.OwnsMarket(key.marketID) {
+			continue
+		}
+		polled++
+
+		fetchCtx, cancel := context.WithTimeout(ctx, bookFetchTimeout)
+		event, err := e.executor.FetchOrderBook(fetchCtx, key.platform, key.marketID)
+		cancel()
+		if err != nil {
+			failed++
+			log.Debug().
+				Err(err).
+				Str("platform", key.platform).
+				Str("market", key.marketID).
+				Msg("Failed to poll order book")
+			continue
+		}
+		e.books.Update(event)
+	}
+
+	if failed > 0 {
+		log.Warn().Int("markets", failed).Int("polled", polled).Msg("Some order books could not be polled")
+	}
+}
@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Strategy definitions can be exported as a YAML bundle and imported back, so
+// they can live in Git and be promoted between environments. Strategies are
+// matched by name. An import creates the missing ones and updates the rest;
+// strategies the bundle does not mention are left alone. Config values are
+// exported as stored, so encrypted values stay encrypted and can only be
+// imported where the same config keys are available. A changed config becomes
+// a new live version, with the previous one kept as retired.
+
+// StrategyBundle is the exported form of a set of strategies
+type StrategyBundle struct {
+	Strategies []StrategySpec `yaml:"strategies" json:"strategies"`
+}
+
+// StrategySpec is the portable definition of a strategy, without the IDs,
+// versions and timestamps of the database it came from
+type StrategySpec struct {
+	Name     string                 `yaml:"name" json:"name"`
+	Type     string                 `yaml:"type" json:"type"`
+	Enabled  bool                   `yaml:"enabled" json:"enabled"`
+	Tenant   string                 `yaml:"tenant,omitempty" json:"tenant,omitempty"`
+	Priority int                    `yaml:"priority,omitempty" json:"priority,omitempty"`
+	DryRun   bool                   `yaml:"dry_run,omitempty" json:"dry_run,omitempty"`
+	Config   map[string]interface{} `yaml:"config" json:"config"`
+}
+
+// ImportRequest is a bundle to import
+type ImportRequest struct {
+	Bundle   StrategyBundle
+	DryRun   bool
+	Operator string
+	Reason   string
+}
+
+// ImportResult lists what an import changed, or would change on a dry run
+type ImportResult struct {
+	DryRun     bool             `json:"dry_run"`
+	Strategies []StrategyImport `json:"strategies"`
+
+	// Untouched are the strategies in the database the bundle does not mention
+	Untouched []string `json:"untouched,omitempty"`
+}
+
+// StrategyImport is the change to one strategy: "create", "update" or
+// "unchanged", with the differing fields of an update
+type StrategyImport struct {
+	Strategy string   `json:"strategy"`
+	Action   string   `json:"action"`
+	Changes  []string `json:"changes,omitempty"`
+}
+
+// ExportStrategies returns every strategy in the database, by name. Of
+// strategies sharing a name, the most recently updated one is exported.
+func (e *Engine) ExportStrategies(ctx context.Context) (*StrategyBundle, error) {
+	strategies, err := e.storage.GetStrategies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := latestByName(strategies)
+	bundle := &StrategyBundle{Strategies: make([]StrategySpec, 0, len(latest))}
+	for _, strategy := range latest {
+		bundle.Strategies = append(bundle.Strategies, StrategySpec{
+			Name:     strategy.Name,
+			Type:     strategy.Type,
+			Enabled:  strategy.Active,
+			Tenant:   strategy.Tenant,
+			Priority: strategy.Priority,
+			DryRun:   strategy.DryRun,
+			Config:   strategy.Config,
+		})
+	}
+	return bundle, nil
+}
+
+// ImportStrategies compares a bundle with the strategies in the database and,
+// unless it is a dry run, applies the differences in one transaction
+func (e *Engine) ImportStrategies(ctx context.Context, req ImportRequest) (*ImportResult, error) {
+	existing, err := e.storage.GetStrategies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]types.Strategy)
+	for _, strategy := range latestByName(existing) {
+		current[strategy.Name] = strategy
+	}
+
+	result := &ImportResult{DryRun: req.DryRun, Strategies: []StrategyImport{}}
+	var writes []storage.StrategyWrite
+	seen := make(map[string]bool, len(req.Bundle.Strategies))
+
+	for i, spec := range req.Bundle.Strategies {
+		if spec.Name == "" || spec.Type == "" {
+			return nil, fmt.Errorf("%w: strategy %d: name and type are required", ErrInvalidVersion, i+1)
+		}
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("%w: strategy %s appears twice", ErrInvalidVersion, spec.Name)
+		}
+		seen[spec.Name] = true
+
+		if spec.Tenant == "" {
+			spec.Tenant = DefaultTenant
+		}
+		// Numbers from YAML are ints; configs read back from the database are
+		// JSON, so compare them in the same form
+		config, err := normalizeConfig(spec.Config)
+		if err != nil {
+			return nil, fmt.Errorf("%w: strategy %s: %v", ErrInvalidVersion, spec.Name, err)
+		}
+		if err := e.validateConfig(spec.Type, config); err != nil {
+			return nil, fmt.Errorf("strategy %s: %w", spec.Name, err)
+		}
+
+		write := storage.StrategyWrite{
+			Name:     spec.Name,
+			Type:     spec.Type,
+			Enabled:  spec.Enabled,
+			DryRun:   spec.DryRun,
+			Priority: spec.Priority,
+			Tenant:   spec.Tenant,
+			Config:   config,
+		}
+
+		strategy, exists := current[spec.Name]
+		if !exists {
+			result.Strategies = append(result.Strategies, StrategyImport{Strategy: spec.Name, Action: "create"})
+			writes = append(writes, write)
+			continue
+		}
+		if strategy.Type != spec.Type {
+			return nil, fmt.Errorf("%w: strategy %s is of type %s, not %s", ErrInvalidVersion, spec.Name, strategy.Type, spec.Type)
+		}
+
+		var changes []string
+		changes = appendChange(changes, "enabled", strategy.Active, spec.Enabled)
+		changes = appendChange(changes, "dry_run", strategy.DryRun, spec.DryRun)
+		changes = appendChange(changes, "priority", strategy.Priority, spec.Priority)
+		changes = appendChange(changes, "tenant", strategy.Tenant, spec.Tenant)
+		configChanges := diffConfig("config", strategy.Config, config)
+		changes = append(changes, configChanges...)
+
+		if len(changes) == 0 {
+			result.Strategies = append(result.Strategies, StrategyImport{Strategy: spec.Name, Action: "unchanged"})
+			continue
+		}
+		result.Strategies = append(result.Strategies, StrategyImport{Strategy: spec.Name, Action: "update", Changes: changes})
+		write.ID = strategy.ID
+		write.ConfigChanged = len(configChanges) > 0
+		writes = append(writes, write)
+	}
+
+	for name := range current {
+		if !seen[name] {
+			result.Untouched = append(result.Untouched, name)
+		}
+	}
+	sort.Strings(result.Untouched)
+
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Int("strategies", len(req.Bundle.Strategies)).
+		Int("changed", len(writes)).
+		Bool("dry_run", req.DryRun).
+		Msg("Importing strategies")
+
+	if req.DryRun || len(writes) == 0 {
+		return result, nil
+	}
+
+	if err := e.storage.ImportStrategies(ctx, writes, req.Operator, "import: "+req.Reason); err != nil {
+		return nil, err
+	}
+	if err := e.ReloadStrategies(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to reload strategies after import")
+	}
+
+	names := make([]string, 0, len(writes))
+	for _, w := range writes {
+		names = append(names, w.Name)
+	}
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		"Strategies imported",
+		fmt.Sprintf("%d strategies imported by %s: %s", len(writes), req.Operator, req.Reason),
+		map[string]interface{}{
+			"strategies": names,
+			"operator":   req.Operator,
+		},
+	); err != nil {
+		log.Error().Err(err).Msg("Failed to create strategy import alert")
+	}
+	return result, nil
+}
+
+// latestByName keeps the most recently updated of strategies sharing a name,
+// as GetStrategyByName does, and returns them by name
+func latestByName(strategies []types.Strategy) []types.Strategy {
+	latest := make(map[string]types.Strategy, len(strategies))
+	for _, strategy := range strategies {
+		if prev, ok := latest[strategy.Name]; !ok || strategy.UpdatedAt.After(prev.UpdatedAt) {
+			latest[strategy.Name] = strategy
+		}
+	}
+
+	result := make([]types.Strategy, 0, len(latest))
+	for _, strategy := range latest {
+		result = append(result, strategy)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// normalizeConfig converts a config to the form it takes after a round trip
+// through JSONB
+func normalizeConfig(config map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	if normalized == nil {
+		normalized = map[string]interface{}{}
+	}
+	return normalized, nil
+}
+
+func appendChange(changes []string, field string, from, to interface{}) []string {
+	if from == to {
+		return changes
+	}
+	return append(changes, fmt.Sprintf("%s: %v -> %v", field, from, to))
+}
+
+// diffConfig describes the differences between two configs by key path,
+// descending into nested objects
+func diffConfig(path string, from, to map[string]interface{}) []string {
+	keys := make(map[string]bool, len(from)+len(to))
+	for k := range from {
+		keys[k] = true
+	}
+	for k := range to {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, k := range sorted {
+		key := path + "." + k
+		old, hadOld := from[k]
+		value, hasNew := to[k]
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("%s: added %s", key, configValue(value)))
+		case !hasNew:
+			changes = append(changes, fmt.Sprintf("%s: removed", key))
+		case reflect.DeepEqual(old, value):
+		default:
+			oldMap, oldIsMap := old.(map[string]interface{})
+			newMap, newIsMap := value.(map[string]interface{})
+			if oldIsMap && newIsMap {
+				changes = append(changes, diffConfig(key, oldMap, newMap)...)
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, configValue(old), configValue(value)))
+		}
+	}
+	return changes
+}
+
+// configValue renders a config value as compact JSON
+func configValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Compare mode runs a candidate build of the engine next to the live one for
+// validating refactors. The candidate reads the same streams (XREAD without
+// consumer groups, so both see every event) and runs the same strategies, but
+// only records what each strategy decided in the strategy_decisions table:
+// nothing is executed, published or written anywhere else, and strategy state
+// is read from production and kept in memory (state.Overlay). The live engine
+// records its own decisions with RecordDecisions, and `strategy-engine
+// compare` diffs the two instances.
+//
+// The candidate places no orders, so it tracks no open orders of its own:
+// decisions depending on them (self-trade prevention, order reaping) may
+// differ from the live engine's for that reason alone.
+
+const (
+	// decisionBuffer is how many decisions wait for the writer before new
+	// ones are dropped
+	decisionBuffer = 10000
+
+	// decisionBatch and decisionFlushInterval bound how long decisions wait
+	// before they are written
+	decisionBatch         = 500
+	decisionFlushInterval = time.Second
+)
+
+// recordingDecisions reports whether decisions are recorded
+func (e *Engine) recordingDecisions() bool {
+	return e.opts.RecordDecisions || e.opts.Compare
+}
+
+// recordDecision queues what a strategy made of an event for the decision
+// writer, adds it to the event's trace and explains it. class is empty for
+// commands about to be executed.
+func (e *Engine) recordDecision(strategy types.Strategy, event types.Event, commands []types.Command, class string, err error) {
+	e.explainDecision(strategy, event, commands, class, err)
+	if !e.recordingDecisions() && e.traces == nil {
+		return
+	}
+
+	decision := types.Decision{
+		Instance:   e.opts.Instance,
+		Strategy:   strategy.Name,
+		EventID:    event.ID,
+		EventType:  event.Type,
+		ErrorClass: class,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err != nil {
+		decision.Error = err.Error()
+	}
+	// The executor may still change the commands; keep them as decided
+	if data, err := json.Marshal(commands); err == nil {
+		json.Unmarshal(data, &decision.Commands)
+	}
+
+	if e.traces != nil {
+		e.traces.add(decision)
+	}
+	if !e.recordingDecisions() {
+		return
+	}
+
+	select {
+	case e.decisions <- decision:
+	default:
+		log.Warn().
+			Str("strategy", strategy.Name).
+			Str("event_id", event.ID).
+			Msg("Decision buffer full, decision dropped")
+	}
+}
+
+// runDecisionWriter writes queued decisions in batches until ctx is done,
+// then writes what is left
+func (e *Engine) runDecisionWriter(ctx context.Context) {
+	ticker := time.NewTicker(decisionFlushInterval)
+	defer ticker.Stop()
+
+	var batch []types.Decision
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.storage.RecordDecisions(ctx, batch); err != nil {
+			log.Error().Err(err).Int("decisions", len(batch)).Msg("Failed to record strategy decisions")
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+		drain:
+			for {
+				select {
+				case decision := <-e.decisions:
+					batch = append(batch, decision)
+				default:
+					break drain
+				}
+			}
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			flush(flushCtx)
+			cancel()
+			return
+		case decision := <-e.decisions:
+			batch = append(batch, decision)
+			if len(batch) >= decisionBatch {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// observeEvent applies an event to the in-memory views the strategies and
+// checks read, without the journal, PnL and settlement writes of handleEvent.
+// It stands in for them in compare mode.
+func (e *Engine) observeEvent(event types.Event) {
+	switch event.Type {
+	case "fill":
+		if orderID := eventOrderID(event); orderID != "" {
+			shares, _ := event.Data["shares"].(float64)
+			e.orders.ApplyFill(event.Platform, orderID, shares)
+		}
+		e.books.RecordTrade(event)
+		e.applyFillPosition(event)
+	case "cancel", "order_cancelled":
+		e.removeOrder(event)
+	case "market_resolved":
+		if marketID, outcome, ok := resolvedOutcome(event); ok {
+			e.markets.SetResolved(marketID, outcome)
+		}
+	}
+
+	// Loss throttles only see losses of events naming their strategy, as the
+	// journal is not consulted
+	if pnl, ok := event.Data["realized_pnl"].(float64); ok {
+		if strategy, _ := event.Data["strategy"].(string); strategy != "" {
+			e.throttle.recordLoss(strategy, pnl, time.Now())
+		}
+	}
+}
@@ -0,0 +1,538 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Conditional commands: a place_order with metadata "trigger_price" is not
+// sent when the strategy returns it. The engine holds it and watches the
+// market's market_update events; once the price of the command's side
+// (yes_price, or 1 - yes_price for no) crosses the trigger, the order goes
+// through the pipeline as if the strategy had just returned it, on the
+// strategy's worker. The real order is a plain limit or market order, so
+// stop-losses work on platforms without native stops. Metadata "trigger"
+// says which way: "below" fires at or under the trigger price, "above" at or
+// over it; it defaults to "below" for sells and closes (a stop-loss) and
+// "above" for buys. A trigger already crossed when the command arrives fires
+// at once. Fired orders carry "triggered_at" and "trigger_fired_price".
+//
+// Commands sharing metadata "oco_group" are one-cancels-other: once an order
+// of the group fills, or a trigger of the group fires, the group's other
+// orders are cancelled and its other held commands dropped. Groups belong to
+// one strategy, and are refused on platforms that cannot cancel orders.
+//
+// Held commands and the resting orders of groups are kept in the
+// conditional_commands table and reloaded on start, so stop-losses survive
+// restarts. Held commands past their Deadline or ExpiresAt, on resolved
+// markets or of strategies no longer active are dropped. Nothing fires while
+// the kill switch is engaged; a trigger crossed meanwhile fires on the first
+// update after it is released. Compare mode keeps them in memory only.
+
+// ErrConditionalNotFound is returned for an unknown conditional command
+var ErrConditionalNotFound = errors.New("conditional command not found")
+
+// Trigger directions
+const (
+	triggerAbove = "above"
+	triggerBelow = "below"
+)
+
+type conditionalBook struct {
+	mu       sync.Mutex
+	commands map[int64]types.ConditionalCommand
+	localID  int64 // last ID given to a command that is not stored
+}
+
+func newConditionalBook() *conditionalBook {
+	return &conditionalBook{commands: make(map[int64]types.ConditionalCommand)}
+}
+
+func (b *conditionalBook) add(c types.ConditionalCommand) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.commands[c.ID] = c
+}
+
+// nextLocalID returns an ID for a command kept in memory only; they are
+// negative so they never clash with stored ones
+func (b *conditionalBook) nextLocalID() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.localID--
+	return b.localID
+}
+
+// take removes the commands match selects and returns them by ID
+func (b *conditionalBook) take(match func(types.ConditionalCommand) bool) []types.ConditionalCommand {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var taken []types.ConditionalCommand
+	for id, c := range b.commands {
+		if match(c) {
+			taken = append(taken, c)
+			delete(b.commands, id)
+		}
+	}
+	sort.Slice(taken, func(i, j int) bool { return taken[i].ID < taken[j].ID })
+	return taken
+}
+
+// list returns every command by ID
+func (b *conditionalBook) list() []types.ConditionalCommand {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	commands := make([]types.ConditionalCommand, 0, len(b.commands))
+	for _, c := range b.commands {
+		commands = append(commands, c)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].ID < commands[j].ID })
+	return commands
+}
+
+// loadConditionalCommands restores the held commands and group orders a
+// previous run stored
+func (e *Engine) loadConditionalCommands(ctx context.Context) error {
+	stored, err := e.storage.GetConditionalCommands(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range stored {
+		e.conditional.add(c)
+	}
+	if len(stored) > 0 {
+		log.Info().Int("count", len(stored)).Msg("Restored conditional commands")
+	}
+	return nil
+}
+
+// ConditionalCommands returns the held commands and the resting orders of
+// one-cancels-other groups
+func (e *Engine) ConditionalCommands() []types.ConditionalCommand {
+	return e.conditional.list()
+}
+
+// DeleteConditionalCommand drops a held command, or forgets a group order
+// without cancelling it
+func (e *Engine) DeleteConditionalCommand(ctx context.Context, id int64) error {
+	taken := e.conditional.take(func(c types.ConditionalCommand) bool { return c.ID == id })
+	if len(taken) == 0 {
+		return ErrConditionalNotFound
+	}
+	e.forgetConditional(ctx, taken)
+	return nil
+}
+
+// holdConditionalCommands takes commands with a trigger out of the batch
+// until it fires, and tags the orders of one-cancels-other groups with the
+// strategy the group belongs to
+func (e *Engine) holdConditionalCommands(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	// Orders decided on an event replayed as a dry run go on as dry runs;
+	// held, they would fire for real later
+	if eventbus.CatchUpMode(event) == eventbus.CatchUpDryRun {
+		return commands
+	}
+
+	kept := commands[:0]
+	for _, cmd := range commands {
+		if _, ok := cmd.Metadata["strategy"]; !ok && ocoGroup(cmd) != "" {
+			cmd.Metadata = withMetadata(cmd.Metadata, "strategy", strategy.Name)
+		}
+
+		trigger, direction, ok, err := commandTrigger(cmd)
+		if err == nil && ocoGroup(cmd) != "" && !e.executor.CanCancel(cmd) {
+			err = fmt.Errorf("one-cancels-other groups cancel orders, which %s cannot do", cmd.Platform)
+		}
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Str("market", cmd.MarketID).
+				Msg("Conditional command dropped")
+			e.publishStrategyError(ctx, strategy, event, ErrorClassTrigger, err, &cmd)
+			continue
+		}
+		if !ok {
+			kept = append(kept, cmd)
+			continue
+		}
+
+		if yesPrice, known := e.markets.Price(cmd.MarketID); known {
+			if price := sidePrice(cmd.Side, yesPrice); triggered(direction, trigger, price) {
+				e.resolveGroup(ctx, strategy.Name, ocoGroup(cmd), "", "trigger fired")
+				kept = append(kept, markTriggered(cmd, price))
+				continue
+			}
+		}
+
+		held := e.keepConditional(ctx, types.ConditionalCommand{
+			Strategy:   strategy.Name,
+			StrategyID: strategy.ID,
+			Group:      ocoGroup(cmd),
+			Status:     types.ConditionalPending,
+			Command:    cmd,
+		})
+		log.Info().
+			Int64("id", held.ID).
+			Str("strategy", strategy.Name).
+			Str("market", cmd.MarketID).
+			Str("side", cmd.Side).
+			Str("trigger", direction).
+			Float64("trigger_price", trigger).
+			Msg("Holding order until its trigger fires")
+		e.explainf(strategy, event, "%s %s order on %s held until the price is %s %.4f",
+			cmd.Side, cmd.Action, cmd.MarketID, direction, trigger)
+	}
+	return kept
+}
+
+// fireTriggers sends the held orders whose trigger a market_update crossed,
+// each on its strategy's worker, and drops the expired ones
+func (e *Engine) fireTriggers(ctx context.Context, event types.Event) {
+	// Prices replayed as a dry run after downtime are stale, triggers wait
+	// for current ones
+	if event.Type != "market_update" || eventbus.CatchUpMode(event) == eventbus.CatchUpDryRun {
+		return
+	}
+	marketID, _ := event.Data["market_id"].(string)
+	yesPrice, ok := event.Data["yes_price"].(float64)
+	if marketID == "" || !ok {
+		return
+	}
+
+	now := time.Now()
+	taken := e.conditional.take(func(c types.ConditionalCommand) bool {
+		cmd := c.Command
+		if c.Status != types.ConditionalPending || cmd.MarketID != marketID || cmd.Platform != event.Platform {
+			return false
+		}
+		if conditionalExpired(cmd, now) {
+			return true
+		}
+		trigger, direction, _, _ := commandTrigger(cmd)
+		return triggered(direction, trigger, sidePrice(cmd.Side, yesPrice))
+	})
+	if len(taken) == 0 {
+		return
+	}
+	e.forgetConditional(ctx, taken)
+
+	for _, c := range taken {
+		if conditionalExpired(c.Command, now) {
+			log.Info().
+				Int64("id", c.ID).
+				Str("strategy", c.Strategy).
+				Str("market", marketID).
+				Msg("Held order expired before its trigger fired")
+			continue
+		}
+
+		strategy, ok := e.activeStrategy(c.StrategyID)
+		if !ok {
+			log.Warn().
+				Int64("id", c.ID).
+				Str("strategy", c.Strategy).
+				Str("market", marketID).
+				Msg("Trigger fired for a strategy no longer active, order dropped")
+			continue
+		}
+
+		price := sidePrice(c.Command.Side, yesPrice)
+		e.resolveGroup(ctx, c.Strategy, c.Group, "", "trigger fired")
+		log.Info().
+			Int64("id", c.ID).
+			Str("strategy", c.Strategy).
+			Str("market", marketID).
+			Str("side", c.Command.Side).
+			Float64("price", price).
+			Msg("Trigger fired, sending order")
+		e.enqueue(ctx, strategyJob{strategy: strategy, event: event, commands: []types.Command{markTriggered(c.Command, price)}})
+	}
+}
+
+// expireConditionalCommands drops held orders past their deadline or expiry,
+// for markets that stopped updating
+func (e *Engine) expireConditionalCommands(ctx context.Context) {
+	now := time.Now()
+	expired := e.conditional.take(func(c types.ConditionalCommand) bool {
+		return c.Status == types.ConditionalPending && conditionalExpired(c.Command, now)
+	})
+	if len(expired) == 0 {
+		return
+	}
+	e.forgetConditional(ctx, expired)
+	log.Info().Int("count", len(expired)).Msg("Held orders expired before their trigger fired")
+}
+
+// dropMarketConditionals drops the held orders and group orders of a
+// resolved market
+func (e *Engine) dropMarketConditionals(ctx context.Context, marketID string) {
+	dropped := e.conditional.take(func(c types.ConditionalCommand) bool {
+		return c.Command.MarketID == marketID
+	})
+	if len(dropped) == 0 {
+		return
+	}
+	e.forgetConditional(ctx, dropped)
+	log.Info().
+		Str("market", marketID).
+		Int("count", len(dropped)).
+		Msg("Market resolved, conditional commands dropped")
+}
+
+// trackGroupOrder records a placed order of a one-cancels-other group, or
+// resolves the group if the order filled on placement
+func (e *Engine) trackGroupOrder(ctx context.Context, result executor.CommandResult) {
+	group := ocoGroup(result.Command)
+	record := result.Record
+	if group == "" || e.opts.Compare || record.Status != "accepted" || record.OrderID == "" {
+		return
+	}
+
+	if result.FilledShares > 0 {
+		e.resolveGroup(ctx, record.Strategy, group, record.OrderID, "order filled")
+		return
+	}
+	e.keepConditional(ctx, types.ConditionalCommand{
+		Strategy: record.Strategy,
+		Group:    group,
+		Status:   types.ConditionalResting,
+		OrderID:  record.OrderID,
+		Command:  result.Command,
+	})
+}
+
+// resolveFilledGroup resolves the group of an order a fill event is about
+func (e *Engine) resolveFilledGroup(ctx context.Context, event types.Event) {
+	orderID := eventOrderID(event)
+	if orderID == "" {
+		return
+	}
+
+	var owner *types.ConditionalCommand
+	e.conditional.mu.Lock()
+	for _, c := range e.conditional.commands {
+		if c.Status == types.ConditionalResting && c.OrderID == orderID && c.Command.Platform == event.Platform {
+			owner = &c
+			break
+		}
+	}
+	e.conditional.mu.Unlock()
+
+	if owner != nil {
+		e.resolveGroup(ctx, owner.Strategy, owner.Group, orderID, "order filled")
+	}
+}
+
+// forgetGroupOrder stops watching a group order that is gone
+func (e *Engine) forgetGroupOrder(ctx context.Context, platform, orderID string) {
+	gone := e.conditional.take(func(c types.ConditionalCommand) bool {
+		return c.Status == types.ConditionalResting && c.OrderID == orderID && c.Command.Platform == platform
+	})
+	e.forgetConditional(ctx, gone)
+}
+
+// resolveGroup ends a one-cancels-other group: its held orders are dropped
+// and its resting orders cancelled, except winner, the order that filled
+func (e *Engine) resolveGroup(ctx context.Context, strategy, group, winner, reason string) {
+	if group == "" {
+		return
+	}
+	members := e.conditional.take(func(c types.ConditionalCommand) bool {
+		return c.Strategy == strategy && c.Group == group
+	})
+	if len(members) == 0 {
+		return
+	}
+	e.forgetConditional(ctx, members)
+
+	var dropped int
+	var cancels []types.Command
+	for _, c := range members {
+		switch {
+		case c.Status == types.ConditionalPending:
+			dropped++
+		case c.OrderID != winner:
+			cancels = append(cancels, types.Command{
+				Type:      "cancel_order",
+				Platform:  c.Command.Platform,
+				AccountID: c.Command.AccountID,
+				MarketID:  c.Command.MarketID,
+				Metadata: map[string]interface{}{
+					"order_id": c.OrderID,
+					"strategy": c.Strategy,
+					"reason":   "one_cancels_other",
+				},
+			})
+		}
+	}
+
+	log.Info().
+		Str("strategy", strategy).
+		Str("group", group).
+		Str("reason", reason).
+		Int("dropped", dropped).
+		Int("cancelled", len(cancels)).
+		Msg("One-cancels-other group resolved")
+
+	if len(cancels) == 0 {
+		return
+	}
+	// Cancels go out in the background, so fills do not wait on the venue
+	go func() {
+		if err := e.executor.ExecuteCommands(ctx, cancels); err != nil {
+			log.Error().
+				Err(err).
+				Str("strategy", strategy).
+				Str("group", group).
+				Msg("Failed to cancel the other orders of a one-cancels-other group")
+		}
+	}()
+}
+
+// keepConditional stores a conditional command and adds it to the book. One
+// that cannot be stored is kept in memory only, so a stop-loss still fires
+// unless the engine restarts.
+func (e *Engine) keepConditional(ctx context.Context, c types.ConditionalCommand) types.ConditionalCommand {
+	if !e.opts.Compare {
+		stored, err := e.storage.AddConditionalCommand(ctx, c)
+		if err == nil {
+			e.conditional.add(stored)
+			return stored
+		}
+		log.Error().
+			Err(err).
+			Str("strategy", c.Strategy).
+			Str("market", c.Command.MarketID).
+			Msg("Failed to store conditional command, keeping it in memory only")
+	}
+
+	c.ID = e.conditional.nextLocalID()
+	c.CreatedAt = time.Now().UTC()
+	e.conditional.add(c)
+	return c
+}
+
+// forgetConditional deletes commands taken from the book from storage
+func (e *Engine) forgetConditional(ctx context.Context, commands []types.ConditionalCommand) {
+	var ids []int64
+	for _, c := range commands {
+		if c.ID > 0 {
+			ids = append(ids, c.ID)
+		}
+	}
+	if len(ids) == 0 || e.opts.Compare {
+		return
+	}
+	if err := e.storage.DeleteConditionalCommands(ctx, ids); err != nil {
+		log.Error().
+			Err(err).
+			Ints64("ids", ids).
+			Msg("Failed to delete conditional commands, they will be restored on restart")
+	}
+}
+
+// activeStrategy returns the active strategy with the given ID
+func (e *Engine) activeStrategy(id string) (types.Strategy, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, strategy := range e.strategies {
+		if strategy.ID == id && strategy.Active {
+			return strategy, true
+		}
+	}
+	return types.Strategy{}, false
+}
+
+// commandTrigger returns the trigger of a command that has not fired yet; ok
+// is false for commands without one
+func commandTrigger(cmd types.Command) (price float64, direction string, ok bool, err error) {
+	raw, exists := cmd.Metadata["trigger_price"]
+	if !exists {
+		return 0, "", false, nil
+	}
+	if _, fired := cmd.Metadata["triggered_at"]; fired {
+		return 0, "", false, nil
+	}
+
+	if cmd.Type != "place_order" {
+		return 0, "", false, fmt.Errorf("trigger_price is only supported on place_order, not %s", cmd.Type)
+	}
+	price, isNumber := raw.(float64)
+	if !isNumber || price <= 0 || price >= 1 {
+		return 0, "", false, fmt.Errorf("trigger_price must be a price between 0 and 1, got %v", raw)
+	}
+	if (cmd.Side != "yes" && cmd.Side != "no") || cmd.OutcomeID != "" {
+		return 0, "", false, fmt.Errorf("triggers follow the yes price and need side yes or no, got %q", cmd.Side)
+	}
+
+	direction, _ = cmd.Metadata["trigger"].(string)
+	switch direction {
+	case "":
+		direction = triggerAbove
+		if action := strings.ToLower(cmd.Action); action == "sell" || action == "close" {
+			direction = triggerBelow
+		}
+	case triggerAbove, triggerBelow:
+	default:
+		return 0, "", false, fmt.Errorf("trigger must be %q or %q, got %q", triggerAbove, triggerBelow, direction)
+	}
+	return price, direction, true, nil
+}
+
+// triggered reports whether price crossed a trigger
+func triggered(direction string, trigger, price float64) bool {
+	if direction == triggerBelow {
+		return price <= trigger
+	}
+	return price >= trigger
+}
+
+// sidePrice is the price of a side given the market's yes price
+func sidePrice(side string, yesPrice float64) float64 {
+	if side == "no" {
+		return 1 - yesPrice
+	}
+	return yesPrice
+}
+
+// conditionalExpired reports whether a held order can no longer be sent
+func conditionalExpired(cmd types.Command, now time.Time) bool {
+	return (cmd.Deadline != nil && !now.Before(*cmd.Deadline)) ||
+		(cmd.ExpiresAt != nil && !now.Before(*cmd.ExpiresAt))
+}
+
+// markTriggered returns the command marked as fired, so the pipeline sends it
+func markTriggered(cmd types.Command, price float64) types.Command {
+	cmd.Metadata = withMetadata(cmd.Metadata, "triggered_at", time.Now().UTC().Format(time.RFC3339Nano))
+	cmd.Metadata["trigger_fired_price"] = price
+	return cmd
+}
+
+func ocoGroup(cmd types.Command) string {
+	group, _ := cmd.Metadata["oco_group"].(string)
+	return group
+}
+
+// withMetadata returns a copy of metadata with key set
+func withMetadata(metadata map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
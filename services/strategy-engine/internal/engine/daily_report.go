@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// maxReportErrorEvents bounds how many strategy_error events of a day are
+// read back for its report
+const maxReportErrorEvents = 100000
+
+// GenerateDailyReport summarizes the UTC day containing day from the order
+// journal, the PnL ledger, the alerts and the strategy_error events the error
+// stream still holds, and stores the report, replacing an earlier one
+func (e *Engine) GenerateDailyReport(ctx context.Context, day time.Time) (*types.DailyReport, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	activity, err := e.storage.GetDailyActivity(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate activity: %w", err)
+	}
+	alerts, err := e.storage.CountAlerts(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count alerts: %w", err)
+	}
+
+	report := &types.DailyReport{
+		Day:         from.Format(time.DateOnly),
+		RiskEvents:  make(map[string]int),
+		Alerts:      alerts,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	// Dropped commands are only published, so count them from the stream
+	events, err := e.eventBus.RangeIDs(ctx, ErrorStream,
+		strconv.FormatInt(from.UnixMilli(), 10), strconv.FormatInt(to.UnixMilli()-1, 10), maxReportErrorEvents)
+	if err != nil {
+		log.Warn().Err(err).Str("day", report.Day).Msg("Failed to read error events for daily report")
+	}
+	riskEvents := make(map[string]map[string]int) // strategy -> error class -> count
+	tenants := make(map[string]string)
+	for _, event := range events {
+		if event.Type != "strategy_error" {
+			continue
+		}
+		name, _ := event.Data["strategy"].(string)
+		class, _ := event.Data["error_class"].(string)
+		if name == "" || class == "" {
+			continue
+		}
+		report.RiskEvents[class]++
+		if riskEvents[name] == nil {
+			riskEvents[name] = make(map[string]int)
+		}
+		riskEvents[name][class]++
+		tenants[name], _ = event.Data["tenant"].(string)
+	}
+
+	for i := range activity {
+		activity[i].RiskEvents = riskEvents[activity[i].Strategy]
+		delete(riskEvents, activity[i].Strategy)
+	}
+	// Strategies that only had commands dropped
+	for name, counts := range riskEvents {
+		activity = append(activity, types.StrategyDay{Strategy: name, Tenant: tenants[name], RiskEvents: counts})
+	}
+
+	sort.Slice(activity, func(i, j int) bool { return activity[i].Strategy < activity[j].Strategy })
+	for _, strategy := range activity {
+		report.Orders += strategy.Orders
+		report.Accepted += strategy.Accepted
+		report.Rejected += strategy.Rejected
+		report.Failed += strategy.Failed
+		report.Filled += strategy.Filled
+		report.Volume += strategy.Volume
+		report.Fees += strategy.Fees
+		report.RealizedPnL += strategy.RealizedPnL
+	}
+	report.Strategies = activity
+	if report.Strategies == nil {
+		report.Strategies = []types.StrategyDay{}
+	}
+
+	if err := e.storage.SaveDailyReport(ctx, *report); err != nil {
+		return nil, fmt.Errorf("failed to store daily report: %w", err)
+	}
+	return report, nil
+}
+
+// DailyReport returns the stored report of a day (YYYY-MM-DD), or nil
+func (e *Engine) DailyReport(ctx context.Context, day string) (*types.DailyReport, error) {
+	return e.storage.GetDailyReport(ctx, day)
+}
+
+// DailyReports returns the latest stored reports, newest first
+func (e *Engine) DailyReports(ctx context.Context, limit int) ([]types.DailyReport, error) {
+	return e.storage.GetDailyReports(ctx, limit)
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Deduplicator suppresses events that were already processed within a TTL window.
+// Upstream services may publish the same fill twice under different stream IDs,
+// so events are keyed on (platform, fill_id) rather than the stream ID.
+type Deduplicator struct {
+	ttl       time.Duration
+	seen      map[string]time.Time
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+func NewDeduplicator(ttl time.Duration) *Deduplicator {
+	return &Deduplicator{
+		ttl:       ttl,
+		seen:      make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// dedupOrderFields identify the order of a fill without a fill_id
+var dedupOrderFields = []string{"order_id", "order_hash"}
+
+// dedupKey returns the identity of an event: its fill_id, else its order with
+// the fill's tx_hash, since one order can fill in several parts. A fill with
+// neither is keyed on its stream ID, which catches redeliveries only. Events
+// without a fill or order ID get "".
+func dedupKey(event types.Event) string {
+	prefix := event.Platform + ":" + event.Type + ":"
+	if fillID, _ := event.Data["fill_id"].(string); fillID != "" {
+		return prefix + "fill_id:" + fillID
+	}
+
+	for _, field := range dedupOrderFields {
+		orderID, _ := event.Data[field].(string)
+		if orderID == "" {
+			continue
+		}
+		if txHash, _ := event.Data["tx_hash"].(string); txHash != "" {
+			return prefix + field + ":" + orderID + ":" + txHash
+		}
+		if event.ID != "" {
+			return prefix + "id:" + event.ID
+		}
+		return ""
+	}
+	return ""
+}
+
+// IsDuplicate reports whether the event was already seen within the TTL window,
+// and remembers it otherwise. Events without a fill/order ID are never duplicates.
+func (d *Deduplicator) IsDuplicate(event types.Event) bool {
+	key := dedupKey(event)
+	if key == "" {
+		return false
+	}
+
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ttl <= 0 {
+		return false
+	}
+
+	if now.Sub(d.lastSweep) > d.ttl {
+		d.sweep(now)
+	}
+
+	if seenAt, ok := d.seen[key]; ok && now.Sub(seenAt) < d.ttl {
+		return true
+	}
+
+	d.seen[key] = now
+	return false
+}
+
+// SetTTL changes the deduplication window; zero disables deduplication.
+// Keys already seen are kept and judged against the new window.
+func (d *Deduplicator) SetTTL(ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ttl = ttl
+	if ttl <= 0 {
+		d.seen = make(map[string]time.Time)
+	}
+}
+
+// sweep drops expired keys. Caller must hold d.mu.
+func (d *Deduplicator) sweep(now time.Time) {
+	for key, seenAt := range d.seen {
+		if now.Sub(seenAt) >= d.ttl {
+			delete(d.seen, key)
+		}
+	}
+	d.lastSweep = now
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+func fillEvent(id string, data map[string]interface{}) types.Event {
+	return types.Event{ID: id, Type: "order_filled", Platform: "predict", Data: data}
+}
+
+func TestDeduplicator(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []types.Event
+		want   []bool
+	}{
+		{
+			name: "fill republished under another stream ID",
+			events: []types.Event{
+				fillEvent("1-0", map[string]interface{}{"fill_id": "0xabc:0x1", "order_hash": "0xabc"}),
+				fillEvent("2-0", map[string]interface{}{"fill_id": "0xabc:0x1", "order_hash": "0xabc"}),
+			},
+			want: []bool{false, true},
+		},
+		{
+			name: "partial fills of one order",
+			events: []types.Event{
+				fillEvent("1-0", map[string]interface{}{"fill_id": "0xabc:0x1", "order_hash": "0xabc", "shares": 5.0}),
+				fillEvent("2-0", map[string]interface{}{"fill_id": "0xabc:0x2", "order_hash": "0xabc", "shares": 5.0}),
+			},
+			want: []bool{false, false},
+		},
+		{
+			name: "partial fills without a fill_id, by transaction",
+			events: []types.Event{
+				fillEvent("1-0", map[string]interface{}{"order_id": "o1", "tx_hash": "0x1"}),
+				fillEvent("2-0", map[string]interface{}{"order_id": "o1", "tx_hash": "0x2"}),
+				fillEvent("3-0", map[string]interface{}{"order_id": "o1", "tx_hash": "0x1"}),
+			},
+			want: []bool{false, false, true},
+		},
+		{
+			name: "partial fills with only the order ID",
+			events: []types.Event{
+				fillEvent("1-0", map[string]interface{}{"order_id": "o1"}),
+				fillEvent("2-0", map[string]interface{}{"order_id": "o1"}),
+				fillEvent("2-0", map[string]interface{}{"order_id": "o1"}),
+			},
+			want: []bool{false, false, true},
+		},
+		{
+			name: "events without an ID",
+			events: []types.Event{
+				{ID: "1-0", Type: "market_update", Data: map[string]interface{}{"market_id": "m1"}},
+				{ID: "1-0", Type: "market_update", Data: map[string]interface{}{"market_id": "m1"}},
+			},
+			want: []bool{false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDeduplicator(time.Minute)
+			for i, event := range tt.events {
+				if got := d.IsDuplicate(event); got != tt.want[i] {
+					t.Errorf("event %d: duplicate %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		d := NewDeduplicator(0)
+		event := fillEvent("1-0", map[string]interface{}{"fill_id": "f1"})
+		if d.IsDuplicate(event) || d.IsDuplicate(event) {
+			t.Error("duplicate with deduplication off")
+		}
+	})
+}
@@ -0,0 +1,323 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Position reconciliation checks the engine's position tracker against the
+// positions the account services report, every Options.DriftInterval. The
+// first check of an account takes its positions as the baseline, and fills
+// on it move the tracked positions from then on.
+//
+// A position whose venue and tracked shares differ by more than
+// Options.DriftTolerance on two checks in a row has drifted, usually because
+// a fill event was lost or the account traded outside the engine; checking
+// twice keeps fills in flight from being reported. Each drift is published as
+// a position_drift event on ErrorStream once, and again if it changes. Markets
+// that resolved are skipped, as venues keep listing their positions until
+// they are redeemed. ResyncPositions makes the venue's positions the new
+// baseline.
+
+// EventPositionDrift is the type of the events published for drifted positions
+const EventPositionDrift = "position_drift"
+
+// PositionDrift is a position on which the venue and the engine disagree
+type PositionDrift struct {
+	Platform      string  `json:"platform"`
+	AccountID     string  `json:"account_id"`
+	MarketID      string  `json:"market_id"`
+	Side          string  `json:"side"`
+	VenueShares   float64 `json:"venue_shares"`
+	TrackedShares float64 `json:"tracked_shares"`
+	Drift         float64 `json:"drift"` // venue minus tracked
+}
+
+func (d PositionDrift) key() string {
+	return d.Platform + ":" + d.AccountID + ":" + d.MarketID + ":" + d.Side
+}
+
+type driftState struct {
+	mu       sync.Mutex
+	seen     map[string]float64 // drift on the last check, by position
+	reported map[string]float64 // drift last published, by position
+}
+
+func newDriftState() *driftState {
+	return &driftState{
+		seen:     make(map[string]float64),
+		reported: make(map[string]float64),
+	}
+}
+
+// ResyncRequest selects the accounts whose tracked positions are replaced by
+// the venue's: those of a strategy, one account, or every managed account
+// when both are empty
+type ResyncRequest struct {
+	Strategy  string `json:"strategy,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	AccountID string `json:"account_id,omitempty"`
+	Operator  string `json:"operator"`
+	Reason    string `json:"reason"`
+}
+
+// runPositionReconciler checks positions for drift every interval until ctx
+// is cancelled
+func (e *Engine) runPositionReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.checkPositions(ctx)
+		}
+	}
+}
+
+// applyFillPosition moves the tracked position of the account a fill is on
+func (e *Engine) applyFillPosition(event types.Event) {
+	accountID, _ := event.Data["account_id"].(string)
+	marketID, _ := event.Data["market_id"].(string)
+	side, _ := event.Data["side"].(string)
+	price, _ := event.Data["price"].(float64)
+	shares, _ := event.Data["shares"].(float64)
+	if accountID == "" || marketID == "" || shares <= 0 {
+		return
+	}
+	if action, _ := event.Data["action"].(string); action == "sell" {
+		shares = -shares
+	}
+	e.positions.ApplyFill(event.Platform, accountID, marketID, side, price, shares)
+}
+
+// checkPositions compares the tracked positions of every managed account with
+// the venue's, taking a baseline for accounts checked for the first time
+func (e *Engine) checkPositions(ctx context.Context) {
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	seen := make(map[managedAccount]bool)
+	var drifts []PositionDrift
+	checked := make(map[string]bool)
+
+	for _, strategy := range strategies {
+		for _, account := range managedAccounts(strategy) {
+			if seen[account] {
+				continue
+			}
+			seen[account] = true
+
+			venue, err := e.executor.FetchPositions(ctx, account.Platform, account.AccountID)
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("platform", account.Platform).
+					Str("account", account.AccountID).
+					Msg("Failed to fetch positions for reconciliation")
+				continue
+			}
+
+			tracked, ok := e.positions.Account(account.Platform, account.AccountID)
+			if !ok {
+				e.positions.Replace(account.Platform, account.AccountID, venue)
+				log.Info().
+					Str("platform", account.Platform).
+					Str("account", account.AccountID).
+					Int("positions", len(venue)).
+					Msg("Position baseline taken")
+				continue
+			}
+
+			checked[account.Platform+":"+account.AccountID] = true
+			drifts = append(drifts, e.positionDrifts(account, venue, tracked)...)
+		}
+	}
+
+	e.reportDrifts(ctx, drifts, checked)
+}
+
+// positionDrifts returns the positions of an account whose venue and tracked
+// shares differ by more than the tolerance
+func (e *Engine) positionDrifts(account managedAccount, venue, tracked []types.Position) []PositionDrift {
+	byKey := make(map[string]*PositionDrift)
+	entry := func(position types.Position) *PositionDrift {
+		d := PositionDrift{Platform: account.Platform, AccountID: account.AccountID, MarketID: position.MarketID, Side: position.Side}
+		if existing, ok := byKey[d.key()]; ok {
+			return existing
+		}
+		byKey[d.key()] = &d
+		return &d
+	}
+	for _, position := range venue {
+		entry(position).VenueShares += position.Shares
+	}
+	for _, position := range tracked {
+		entry(position).TrackedShares += position.Shares
+	}
+
+	var drifts []PositionDrift
+	for _, d := range byKey {
+		if _, resolved := e.markets.Resolved(d.MarketID); resolved || !e.opts.Shard.OwnsMarket(d.MarketID) {
+			continue
+		}
+		d.Drift = d.VenueShares - d.TrackedShares
+		if math.Abs(d.Drift) > e.opts.DriftTolerance {
+			drifts = append(drifts, *d)
+		}
+	}
+	return drifts
+}
+
+// reportDrifts publishes the drifts seen on this check and the last, unless
+// already published with the same drift, and forgets the positions of the
+// checked accounts that no longer drift
+func (e *Engine) reportDrifts(ctx context.Context, drifts []PositionDrift, checked map[string]bool) {
+	var publish []PositionDrift
+	current := make(map[string]bool, len(drifts))
+
+	e.drift.mu.Lock()
+	for _, d := range drifts {
+		key := d.key()
+		current[key] = true
+		previous, seenBefore := e.drift.seen[key]
+		e.drift.seen[key] = d.Drift
+		if !seenBefore || math.Abs(previous-d.Drift) > e.opts.DriftTolerance {
+			continue
+		}
+		if reported, ok := e.drift.reported[key]; ok && math.Abs(reported-d.Drift) <= e.opts.DriftTolerance {
+			continue
+		}
+		e.drift.reported[key] = d.Drift
+		publish = append(publish, d)
+	}
+	for key := range e.drift.seen {
+		if !current[key] && checked[accountOf(key)] {
+			delete(e.drift.seen, key)
+			delete(e.drift.reported, key)
+		}
+	}
+	e.drift.mu.Unlock()
+
+	for _, d := range publish {
+		e.publishDrift(ctx, d)
+	}
+}
+
+// accountOf returns the platform:account part of a position key
+func accountOf(key string) string {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) < 3 {
+		return key
+	}
+	return parts[0] + ":" + parts[1]
+}
+
+func (e *Engine) publishDrift(ctx context.Context, d PositionDrift) {
+	log.Warn().
+		Str("platform", d.Platform).
+		Str("account", d.AccountID).
+		Str("market", d.MarketID).
+		Str("side", d.Side).
+		Float64("venue_shares", d.VenueShares).
+		Float64("tracked_shares", d.TrackedShares).
+		Float64("drift", d.Drift).
+		Msg("Position drift")
+
+	now := time.Now().UTC()
+	event := types.Event{
+		ID:        fmt.Sprintf("%s:%s:%d", EventPositionDrift, d.key(), now.UnixNano()),
+		Type:      EventPositionDrift,
+		Platform:  d.Platform,
+		Timestamp: now,
+		Data: map[string]interface{}{
+			"account_id":     d.AccountID,
+			"tenant":         e.accountTenant(d.AccountID),
+			"market_id":      d.MarketID,
+			"side":           d.Side,
+			"venue_shares":   d.VenueShares,
+			"tracked_shares": d.TrackedShares,
+			"drift":          d.Drift,
+		},
+	}
+	if err := e.eventBus.Publish(ctx, ErrorStream, event); err != nil {
+		log.Error().Err(err).Str("account", d.AccountID).Msg("Failed to publish position drift event")
+	}
+}
+
+// ResyncPositions replaces the tracked positions of the selected accounts by
+// the venue's and forgets their drifts
+func (e *Engine) ResyncPositions(ctx context.Context, req ResyncRequest) ([]AccountPositions, error) {
+	var accounts []managedAccount
+	if req.Strategy == "" && req.AccountID == "" {
+		e.mu.RLock()
+		strategies := e.strategies
+		e.mu.RUnlock()
+
+		seen := make(map[managedAccount]bool)
+		for _, strategy := range strategies {
+			for _, account := range managedAccounts(strategy) {
+				if !seen[account] {
+					seen[account] = true
+					accounts = append(accounts, account)
+				}
+			}
+		}
+	} else {
+		selected, err := e.flattenAccounts(FlattenRequest{Strategy: req.Strategy, Platform: req.Platform, AccountID: req.AccountID})
+		if err != nil {
+			return nil, err
+		}
+		accounts = selected
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("no accounts to resync")
+	}
+
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Str("strategy", req.Strategy).
+		Str("account", req.AccountID).
+		Int("accounts", len(accounts)).
+		Msg("Resyncing positions")
+
+	result := make([]AccountPositions, 0, len(accounts))
+	for _, account := range accounts {
+		entry := AccountPositions{Platform: account.Platform, AccountID: account.AccountID, Positions: []types.Position{}}
+		positions, err := e.executor.FetchPositions(ctx, account.Platform, account.AccountID)
+		if err != nil {
+			entry.Error = err.Error()
+			result = append(result, entry)
+			continue
+		}
+
+		e.positions.Replace(account.Platform, account.AccountID, positions)
+		if positions != nil {
+			entry.Positions = positions
+		}
+		result = append(result, entry)
+
+		prefix := account.Platform + ":" + account.AccountID
+		e.drift.mu.Lock()
+		for key := range e.drift.seen {
+			if accountOf(key) == prefix {
+				delete(e.drift.seen, key)
+				delete(e.drift.reported, key)
+			}
+		}
+		e.drift.mu.Unlock()
+	}
+	return result, nil
+}
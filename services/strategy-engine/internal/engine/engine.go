@@ -2,124 +2,793 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/analytics"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/costs"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/flags"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/metrics"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orders"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/pnl"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/positions"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/state"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategyctx"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
 	"github.com/rs/zerolog/log"
 )
 
+// Options holds optional engine behaviour. The zero value disables all of it.
+type Options struct {
+	// DedupTTL enables event deduplication on (platform, fill_id) within the
+	// given window (see dedupKey).
+	DedupTTL time.Duration
+
+	// RecoverOrders adopts open orders found on managed accounts at startup
+	RecoverOrders bool
+
+	// ReconcileInterval is how often open orders are reconciled with the
+	// account services; zero disables it. CancelOrphans cancels the open
+	// orders reconciliation finds that the engine did not place.
+	ReconcileInterval time.Duration
+	CancelOrphans     bool
+
+	// DriftInterval is how often tracked positions are checked against the
+	// account services; zero disables it. Positions differing by more than
+	// DriftTolerance shares are reported as drifted.
+	DriftInterval  time.Duration
+	DriftTolerance float64
+
+	// BookPollInterval is how often the books of traded markets are fetched
+	// from the account services (see books.go); zero disables it
+	BookPollInterval time.Duration
+
+	// AutoDisable moves under-performing strategies to shadow mode
+	AutoDisable *analytics.AutoDisablePolicy
+
+	// Shard restricts this instance to the events of its share of markets
+	Shard Shard
+
+	// Outbox persists commands before execution and delivers them with retries
+	Outbox bool
+
+	// MaxHandlerPanics disables a strategy after this many consecutive
+	// handler panics. Zero uses defaultMaxHandlerPanics.
+	MaxHandlerPanics int
+
+	// RejectBackoffAfter suspends a strategy after this many consecutive
+	// rejected orders, for RejectBackoff doubling on each further suspension
+	// up to RejectBackoffMax. Zero disables it.
+	RejectBackoffAfter int
+	RejectBackoff      time.Duration
+	RejectBackoffMax   time.Duration
+
+	// HandlerTimeout bounds how long the worker waits for a strategy handler.
+	// Zero disables the timeout.
+	HandlerTimeout time.Duration
+
+	// MaxPriceDeviation blocks orders priced further than this from a fresh
+	// market price, in price units (see price_check.go). Zero disables the check.
+	MaxPriceDeviation float64
+
+	// MaxPriceMove blocks opening orders in a market for PriceMoveCooldown
+	// once its price moved more than this within PriceMoveWindow (see
+	// volatility.go). Zero disables the guard.
+	MaxPriceMove      float64
+	PriceMoveWindow   time.Duration
+	PriceMoveCooldown time.Duration
+
+	// SelfTradePrevention is the default handling of orders that would cross
+	// our own resting orders: off, skip, reprice or cancel_replace
+	SelfTradePrevention string
+
+	// Groups are the shared risk budgets of strategy groups, by group name
+	Groups map[string]*GroupBudget
+
+	// Tenants are the desks sharing the engine, by tenant name
+	Tenants map[string]*Tenant
+
+	// OrderTTL is how long orders without an explicit expiry may rest before
+	// they are cancelled. Zero lets them rest until cancelled.
+	OrderTTL time.Duration
+
+	// Warmup is how long a strategy only observes after it is activated; the
+	// orders it places meanwhile are dropped (see warmup.go)
+	Warmup time.Duration
+
+	// StateFlushInterval keeps strategy state in memory, written to storage
+	// this often (see state.Cached). Zero writes it through.
+	StateFlushInterval time.Duration
+
+	// MaxStreamLag and MaxQueueBacklog raise an alert when the engine falls
+	// this far behind a stream or a strategy queue holds this many events.
+	// Zero disables the respective alert.
+	MaxStreamLag    time.Duration
+	MaxQueueBacklog int
+
+	// ReplayGaps handles the events of a sequence gap on a stream from the
+	// event archive when it still holds them (see gaps.go)
+	ReplayGaps bool
+
+	// Costs are the fee and slippage models of the platforms, by platform.
+	// Fill fees are booked as strategy PnL.
+	Costs map[string]costs.Platform
+
+	// ConfigKeys decrypt the encrypted values of strategy configs when the
+	// strategies are loaded. Without it, strategies with encrypted values
+	// are not loaded.
+	ConfigKeys *secrets.Keyring
+
+	// Instance names this engine in recorded decisions. RecordDecisions
+	// records what every strategy decided on every event; Compare runs the
+	// engine as a candidate that records its decisions and does nothing else
+	// (see compare.go).
+	Instance        string
+	RecordDecisions bool
+	Compare         bool
+
+	// Trace publishes every consumed event with what the strategies decided
+	// on it to TraceStream (see trace.go). Ignored in compare mode.
+	Trace bool
+
+	// Middlewares are the custom command middlewares to run, in order (see
+	// pipeline.go). Nil runs every registered one in registration order.
+	Middlewares []string
+
+	// RequireRegisteredAccounts drops orders on accounts missing from the
+	// trading account registry (see trading_accounts.go)
+	RequireRegisteredAccounts bool
+
+	// Environment names the deployment (e.g. staging, production) for
+	// feature flags limited to some environments (see flags.go)
+	Environment string
+}
+
 type Engine struct {
 	storage   *storage.PostgresStorage
-	eventBus  *eventbus.RedisEventBus
+	eventBus  eventbus.EventBus
 	executor  *executor.Executor
 	handlers  map[string]types.StrategyHandler
+	dedup     *Deduplicator
+	markets   *markets.Registry
+	mappings  *markets.Mappings
+	accounts  *accountRegistry
+	flags     *flags.Set
+	books     *orderbook.Cache
+	watched   *watchedBooks
+	unchecked *uncheckedPrices
+	costs     *costs.Model
+	pnl       *pnl.Calculator
+	state     *state.Store
+	orders    *orders.Tracker
+	analytics *analytics.Analytics
+	counters  *strategyCounters
+	opts      Options
 	mu        sync.RWMutex
+
+	strategies []types.Strategy
+	activated  map[string]time.Time // by strategy ID, when each active strategy was loaded
+	schedules  map[string]*Schedule // by strategy ID, only for scheduled strategies
+	throttles  map[string]*Throttle // by strategy ID, only for throttled strategies
+	throttle   *throttleState
+	abandoned  *abandonedCalls
+	groupRisk  *groupRisk
+	lag        *lagMonitor
+	streams    []string
+	outboxWake chan struct{}
+
+	accountTenants map[string]string // account ID -> tenant
+
+	tickHandlers  map[string]types.StrategyHandler // by strategy type
+	tickIntervals map[string]time.Duration         // by strategy ID, only for ticking strategies
+
+	rejectionHandlers map[string]types.StrategyHandler // by strategy type
+
+	middlewares []namedMiddleware // custom, in registration order
+	pipeline    CommandHandler    // rebuilt when a middleware is registered
+
+	workersMu sync.Mutex
+	workers   map[string]*strategyWorker // by strategy ID
+
+	halted     bool
+	haltReason string
+	haltedAt   time.Time
+
+	runCtx context.Context // set by Start, for work that outlives a request
+
+	stateCache *state.Cached // write-behind strategy state, nil when written through
+
+	reconcile reconcileState // latest open order reconciliation
+
+	positions *positions.Tracker // positions of managed accounts, from fills
+	drift     *driftState
+
+	events      *eventCounters // for Stats
+	backoff     *rejectionBackoff
+	volatility  *volatilityGuard
+	experiments map[string]*ABTest  // by strategy ID, only for strategies under test
+	decisions   chan types.Decision // for the decision writer, nil unless recording
+	traces      *traces             // events being traced, nil unless tracing
+
+	explanations *explanations    // of strategies in explain mode
+	conditional  *conditionalBook // held orders and one-cancels-other groups
+	metrics      *metrics.Registry
 }
 
 func NewEngine(
 	storage *storage.PostgresStorage,
-	eventBus *eventbus.RedisEventBus,
+	eventBus eventbus.EventBus,
 	executor *executor.Executor,
+	opts Options,
 ) *Engine {
-	return &Engine{
-		storage:  storage,
-		eventBus: eventBus,
-		executor: executor,
-		handlers: make(map[string]types.StrategyHandler),
+	e := &Engine{
+		storage:    storage,
+		eventBus:   eventBus,
+		executor:   executor,
+		handlers:   make(map[string]types.StrategyHandler),
+		schedules:  make(map[string]*Schedule),
+		markets:    markets.NewRegistry(),
+		mappings:   markets.NewMappings(),
+		accounts:   newAccountRegistry(),
+		flags:      flags.NewSet(opts.Environment),
+		books:      orderbook.NewCache(),
+		watched:    newWatchedBooks(),
+		unchecked:  newUncheckedPrices(),
+		orders:     orders.NewTracker(),
+		analytics:  analytics.New(storage),
+		counters:   newStrategyCounters(),
+		throttle:   newThrottleState(),
+		abandoned:  newAbandonedCalls(),
+		groupRisk:  newGroupRisk(),
+		lag:        newLagMonitor(),
+		opts:       opts,
+		outboxWake: make(chan struct{}, 1),
+		workers:    make(map[string]*strategyWorker),
+		streams:    EventStreams(),
+
+		accountTenants: buildAccountTenants(opts.Tenants),
+		tickHandlers:   make(map[string]types.StrategyHandler),
+
+		rejectionHandlers: make(map[string]types.StrategyHandler),
+	}
+	e.positions = positions.NewTracker()
+	e.metrics = metrics.NewRegistry()
+	e.drift = newDriftState()
+	e.events = newEventCounters()
+	e.backoff = newRejectionBackoff()
+	e.volatility = newVolatilityGuard()
+	e.explanations = newExplanations()
+	e.conditional = newConditionalBook()
+	e.costs = costs.NewModel(opts.Costs, e.markets)
+	e.pnl = pnl.NewCalculator(e.costs, storage.GetOrderStrategy)
+	e.state = state.NewStore(storage)
+	switch {
+	case opts.Compare:
+		e.state = state.NewStore(state.NewOverlay(storage))
+	case opts.StateFlushInterval > 0:
+		e.stateCache = state.NewCached(storage, storage, opts.StateFlushInterval)
+		e.state = state.NewStore(e.stateCache)
+	}
+	if e.recordingDecisions() {
+		e.decisions = make(chan types.Decision, decisionBuffer)
 	}
+	if opts.Trace && !opts.Compare {
+		e.traces = newTraces(e)
+	}
+	executor.SetTracker(e.orders)
+	executor.SetMarkets(e.markets)
+	executor.SetOrderBooks(e.books)
+	executor.SetResultPublisher(e)
+
+	// Always present so the TTL can be enabled on reload; a zero TTL is a no-op
+	e.dedup = NewDeduplicator(opts.DedupTTL)
+
+	e.pipeline = e.buildPipeline()
+
+	return e
 }
 
 func (e *Engine) RegisterStrategy(name string, handler types.StrategyHandler) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	
+
 	e.handlers[name] = handler
 	log.Info().Str("strategy", name).Msg("Registered strategy handler")
 }
 
+// Markets returns the market metadata registry maintained from market events
+func (e *Engine) Markets() *markets.Registry {
+	return e.markets
+}
+
+// OrderBooks returns the top-of-book cache maintained from order book events
+func (e *Engine) OrderBooks() *orderbook.Cache {
+	return e.books
+}
+
+// Context returns the market data view handed to strategy handlers
+func (e *Engine) Context() *strategyctx.Context {
+	return &strategyctx.Context{
+		Markets:    e.markets,
+		Mappings:   e.mappings,
+		OrderBooks: e.books,
+		Costs:      e.costs,
+		Units:      e.executor.Units(),
+		Positions:  e.positions,
+		State:      e.state,
+		Explainer:  e,
+		Metrics:    e.metrics,
+		Flags:      e.flags,
+	}
+}
+
+// Orders returns the open order tracker
+func (e *Engine) Orders() *orders.Tracker {
+	return e.orders
+}
+
+// SetDedupTTL changes the event deduplication window at runtime
+func (e *Engine) SetDedupTTL(ttl time.Duration) {
+	e.dedup.SetTTL(ttl)
+}
+
+// SetAutoDisablePolicy replaces the auto-disable policy at runtime; nil turns
+// auto-disable off. It takes effect from the next performance check.
+func (e *Engine) SetAutoDisablePolicy(policy *analytics.AutoDisablePolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.opts.AutoDisable = policy
+}
+
+// EventStreams lists the streams an engine subscribes to
+func EventStreams() []string {
+	return []string{
+		"fill_events",
+		"trade_events",
+		"account_events",
+		"market_events",
+		CommandResultStream,
+		orderbook.Stream,
+	}
+}
+
+// Streams returns the event streams the engine consumes
+func (e *Engine) Streams() []string {
+	return e.streams
+}
+
 func (e *Engine) Start(ctx context.Context) error {
 	log.Info().Msg("Starting strategy engine...")
 
-	// Load active strategies from database
-	strategies, err := e.storage.GetActiveStrategies()
+	e.mu.Lock()
+	e.runCtx = ctx
+	e.mu.Unlock()
+
+	if err := e.checkMiddlewares(); err != nil {
+		return err
+	}
+	log.Info().Strs("pipeline", e.Pipeline()).Msg("Command pipeline")
+
+	e.eventBus.OnGap(func(gap eventbus.Gap) {
+		e.handleGap(ctx, gap)
+	})
+
+	// Load active strategies and their candidate versions from database
+	strategies, err := e.loadStrategies(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to load strategies: %w", err)
 	}
 
+	e.setStrategies(strategies)
+
 	log.Info().Int("count", len(strategies)).Msg("Loaded active strategies")
 
-	// Subscribe to event streams
-	streams := []string{
-		"fill_events",
-		"trade_events",
-		"account_events",
+	if err := e.loadResolutions(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to load market resolutions")
+	}
+	if err := e.loadMappings(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to load market mappings")
+	}
+	if err := e.loadTradingAccounts(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to load trading accounts")
+	}
+	if err := e.loadFeatureFlags(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to load feature flags")
+	}
+
+	if e.opts.Shard.Enabled() {
+		log.Info().
+			Int("shard", e.opts.Shard.Index).
+			Int("shards", e.opts.Shard.Count).
+			Msg("Sharding enabled, handling only events for this shard's markets")
+	}
+
+	if e.recordingDecisions() {
+		go e.runDecisionWriter(ctx)
+	}
+
+	if e.opts.Compare {
+		log.Info().Str("instance", e.opts.Instance).Msg("Compare mode: recording decisions only, nothing is executed")
+		if e.opts.Shard.Primary() {
+			go e.runTicks(ctx)
+		}
+		go e.runStrategyRefresh(ctx)
+		return e.eventBus.Subscribe(ctx, e.streams, func(event types.Event) error {
+			return e.handleEvent(ctx, event)
+		})
 	}
 
-	return e.eventBus.Subscribe(ctx, streams, func(event types.Event) error {
-		return e.handleEvent(ctx, event, strategies)
+	if err := e.loadConditionalCommands(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to load conditional commands")
+	}
+	if e.opts.RecoverOrders {
+		e.recoverOpenOrders(ctx)
+	}
+
+	if e.stateCache != nil {
+		go e.stateCache.Run(ctx)
+	}
+	go e.runScheduler(ctx, scheduleCheckInterval)
+	if e.opts.Shard.Primary() {
+		go e.runTicks(ctx)
+	}
+	go e.runStrategyRefresh(ctx)
+	go e.runGroupRisk(ctx)
+	go e.runOrderReaper(ctx)
+	if e.opts.ReconcileInterval > 0 {
+		go e.runReconciler(ctx, e.opts.ReconcileInterval)
+	}
+	if e.opts.DriftInterval > 0 {
+		go e.runPositionReconciler(ctx, e.opts.DriftInterval)
+	}
+	go e.runLagMonitor(ctx)
+
+	if e.opts.BookPollInterval > 0 {
+		go e.runBookPoller(ctx, e.opts.BookPollInterval)
+	}
+
+	if e.opts.Shard.Primary() {
+		go e.runAutoDisable(ctx)
+	}
+
+	if e.opts.Outbox {
+		go e.runOutbox(ctx)
+	}
+
+	// Subscribe to event streams
+	return e.eventBus.Subscribe(ctx, e.streams, func(event types.Event) error {
+		return e.handleEvent(ctx, event)
 	})
 }
 
-func (e *Engine) handleEvent(
-	ctx context.Context,
-	event types.Event,
-	strategies []types.Strategy,
-) error {
-	log.Debug().
-		Str("type", event.Type).
-		Str("platform", event.Platform).
-		Msg("Received event")
+// scheduleCheckInterval is how often trading windows are checked for closing
+const scheduleCheckInterval = 30 * time.Second
+
+// setStrategies installs the loaded strategies, decrypting their configs and
+// expanding A/B tests into their variants, and parses their schedules,
+// throttles and tick intervals. A strategy with an invalid one is dropped
+// rather than run unrestricted.
+func (e *Engine) setStrategies(loaded []types.Strategy) {
+	strategies := make([]types.Strategy, 0, len(loaded))
+	schedules := make(map[string]*Schedule)
+	throttles := make(map[string]*Throttle)
+	tickIntervals := make(map[string]time.Duration)
+	experiments := make(map[string]*ABTest)
 
-	// Process event through all active strategies
-	for _, strategy := range strategies {
-		if !strategy.Active {
+	var expanded []types.Strategy
+	for _, strategy := range loaded {
+		config, err := e.opts.ConfigKeys.DecryptConfig(strategy.Config)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Msg("Failed to decrypt config, strategy not loaded")
 			continue
 		}
+		strategy.Config = config
 
-		// Get handler for this strategy
-		e.mu.RLock()
-		handler, exists := e.handlers[strategy.Type]
-		e.mu.RUnlock()
-
-		if !exists {
-			log.Warn().
+		variants, test, err := expandVariants(strategy)
+		if err != nil {
+			log.Error().
+				Err(err).
 				Str("strategy", strategy.Name).
-				Str("type", strategy.Type).
-				Msg("No handler registered for strategy type")
+				Msg("Invalid A/B test, strategy not loaded")
 			continue
 		}
+		if test != nil {
+			experiments[strategy.ID] = test
+		}
+		expanded = append(expanded, variants...)
+	}
 
-		// Execute strategy handler
-		commands, err := handler(event, strategy)
+	for _, strategy := range expanded {
+		schedule, err := ParseSchedule(strategy.Config)
 		if err != nil {
 			log.Error().
 				Err(err).
 				Str("strategy", strategy.Name).
-				Msg("Strategy handler failed")
+				Msg("Invalid schedule, strategy not loaded")
 			continue
 		}
-
-		if len(commands) == 0 {
+		throttle, err := ParseThrottle(strategy.Config)
+		if throttle != nil {
+			throttle.MaxOrdersPerMinute = e.opts.Shard.Share(throttle.MaxOrdersPerMinute)
+		}
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Msg("Invalid throttle, strategy not loaded")
 			continue
 		}
-
-		// Execute commands
-		log.Info().
-			Str("strategy", strategy.Name).
-			Int("commands", len(commands)).
-			Msg("Executing commands from strategy")
-
-		if err := e.executor.ExecuteCommands(ctx, commands); err != nil {
+		tickInterval, err := ParseTickInterval(strategy.Config)
+		if err != nil {
 			log.Error().
 				Err(err).
 				Str("strategy", strategy.Name).
-				Msg("Failed to execute commands")
+				Msg("Invalid tick interval, strategy not loaded")
+			continue
+		}
+		if schedule != nil {
+			schedules[strategy.ID] = schedule
+		}
+		if tickInterval > 0 {
+			tickIntervals[strategy.ID] = tickInterval
+		}
+		if throttle != nil {
+			throttles[strategy.ID] = throttle
 		}
+		strategies = append(strategies, strategy)
+	}
+
+	e.mu.Lock()
+	e.trackActivations(strategies, time.Now())
+	e.strategies = strategies
+	e.schedules = schedules
+	e.throttles = throttles
+	e.tickIntervals = tickIntervals
+	e.experiments = experiments
+	e.mu.Unlock()
+}
+
+func (e *Engine) handleEvent(ctx context.Context, event types.Event) error {
+	log.Debug().
+		Str("type", event.Type).
+		Str("platform", event.Platform).
+		Msg("Received event")
+
+	if !e.opts.Shard.Owns(event) {
+		return nil
 	}
+	e.events.record(event)
+	e.watched.see(event)
+
+	// Book updates only refresh the cache; strategies read it on demand
+	if e.books.Update(event) {
+		return nil
+	}
+
+	if e.dedup.IsDuplicate(event) {
+		e.events.recordDuplicate()
+		e.traceSkipped(ctx, event, "duplicate")
+		log.Info().
+			Str("id", event.ID).
+			Str("type", event.Type).
+			Str("platform", event.Platform).
+			Msg("Skipping duplicate event")
+		return nil
+	}
+
+	if e.opts.Compare {
+		e.observeEvent(event)
+	} else {
+		switch event.Type {
+		case "fill":
+			e.recordFill(ctx, event)
+			e.books.RecordTrade(event)
+			e.applyFillPosition(event)
+			e.resolveFilledGroup(ctx, event)
+		case "cancel", "order_cancelled":
+			e.removeOrder(event)
+			e.forgetGroupOrder(ctx, event.Platform, eventOrderID(event))
+		case "market_resolved":
+			e.handleMarketResolved(ctx, event)
+		}
+		e.recordPnL(ctx, event)
+	}
+
+	if halted, _ := e.Halted(); halted {
+		log.Debug().Str("id", event.ID).Msg("Kill switch engaged, not running strategies")
+		e.traceSkipped(ctx, event, "halted")
+		return nil
+	}
+	e.markets.Update(event)
+	e.observePrice(event)
+	e.fireTriggers(ctx, event)
+
+	// Hand the event to each active strategy's worker, within its tenant
+	receivers := e.receivers(event)
+	var finished func()
+	if e.traces != nil {
+		finished = e.traces.start(ctx, event, receivers)
+	}
+	e.dispatchByPriority(ctx, receivers, event, finished)
+	e.dispatchRejection(ctx, event, receivers)
 
 	return nil
 }
+
+// runStrategy runs one strategy's handler for an event and executes, queues
+// or records the resulting commands. It runs on the strategy's worker.
+func (e *Engine) runStrategy(ctx context.Context, strategy types.Strategy, event types.Event) {
+	e.mu.RLock()
+	schedule := e.schedules[strategy.ID]
+	handler, exists := e.handlers[strategy.Type]
+	switch event.Type {
+	case EventTick:
+		handler, exists = e.tickHandlers[strategy.Type]
+	case EventOrderRejected:
+		handler, exists = e.rejectionHandlers[strategy.Type]
+	}
+	e.mu.RUnlock()
+
+	defer e.beginExplanation(strategy, event)()
+
+	// The window gates trading now, whenever the event happened: a backlog
+	// read after a restart must not trade outside it
+	if schedule != nil && !schedule.IsOpen(time.Now()) {
+		log.Debug().
+			Str("strategy", strategy.Name).
+			Msg("Outside trading window, skipping")
+		e.explainf(strategy, event, "outside trading window")
+		return
+	}
+
+	if until, suspended := e.suspendedUntil(strategy.Name, time.Now()); suspended {
+		log.Debug().
+			Str("strategy", strategy.Name).
+			Time("until", until).
+			Msg("Suspended after order rejections, skipping")
+		e.explainf(strategy, event, "suspended after order rejections until %s", until.Format(time.RFC3339))
+		return
+	}
+
+	if !exists {
+		log.Warn().
+			Str("strategy", strategy.Name).
+			Str("type", strategy.Type).
+			Str("event", event.Type).
+			Msg("No handler registered for strategy type")
+		e.explainf(strategy, event, "no %s handler for strategy type %s", event.Type, strategy.Type)
+		return
+	}
+
+	// Execute strategy handler
+	commands, err := e.callHandler(ctx, handler, strategy, event)
+	if errors.Is(err, ErrHandlerTimeout) {
+		e.counters.recordTimeout(strategy.Name)
+		log.Error().
+			Err(err).
+			Str("strategy", strategy.Name).
+			Str("event_id", event.ID).
+			Msg("Strategy handler timed out")
+		e.publishStrategyError(ctx, strategy, event, ErrorClassTimeout, err, nil)
+		return
+	}
+	e.counters.record(strategy.Name, len(commands), err != nil)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("strategy", strategy.Name).
+			Msg("Strategy handler failed")
+		e.publishStrategyError(ctx, strategy, event, ErrorClassHandler, err, nil)
+		return
+	}
+
+	if len(commands) == 0 {
+		return
+	}
+
+	e.runPipeline(ctx, strategy, event, commands)
+}
+
+// recordFill attributes a fill to the journaled order it belongs to, if any
+func (e *Engine) recordFill(ctx context.Context, event types.Event) {
+	orderID := eventOrderID(event)
+	if orderID == "" {
+		return
+	}
+
+	price, _ := event.Data["price"].(float64)
+	shares, _ := event.Data["shares"].(float64)
+
+	if err := e.storage.RecordFill(ctx, event.Platform, orderID, price, shares, event.Timestamp); err != nil {
+		log.Error().Err(err).Str("order_id", orderID).Msg("Failed to record fill in journal")
+	}
+
+	e.recordGroupFill(event.Platform, orderID, price, shares)
+	e.orders.ApplyFill(event.Platform, orderID, shares)
+}
+
+// removeOrder stops tracking an order the venue reports as cancelled
+func (e *Engine) removeOrder(event types.Event) {
+	if orderID := eventOrderID(event); orderID != "" {
+		e.orders.Remove(event.Platform, orderID)
+	}
+}
+
+// eventOrderID returns the venue order ID an event refers to
+func eventOrderID(event types.Event) string {
+	return pnl.OrderID(event)
+}
+
+// markDryRun flags every command of a dry-run strategy, or decided on an
+// event replayed as a dry run after downtime, including cancels added along
+// the way, so the executor sends them unconfirmed
+func markDryRun(strategy types.Strategy, event types.Event, commands []types.Command) {
+	if !strategy.DryRun && eventbus.CatchUpMode(event) != eventbus.CatchUpDryRun {
+		return
+	}
+	for i := range commands {
+		metadata := make(map[string]interface{}, len(commands[i].Metadata)+1)
+		for k, v := range commands[i].Metadata {
+			metadata[k] = v
+		}
+		metadata["dry_run"] = true
+		commands[i].Metadata = metadata
+	}
+}
+
+// applyExecutionDefaults copies the strategy's "execution" config into
+// place_order commands: "order_type" and "time_in_force" unless the command
+// sets them, and the algo and its parameters into the metadata of commands
+// that don't set their own algo.
+func applyExecutionDefaults(strategy types.Strategy, commands []types.Command) {
+	execution, ok := strategy.Config["execution"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	orderType, _ := execution["order_type"].(string)
+	tif, _ := execution["time_in_force"].(string)
+
+	for i := range commands {
+		cmd := &commands[i]
+		if !cmd.PlacesOrder() {
+			continue
+		}
+		if cmd.OrderType == "" {
+			cmd.OrderType = orderType
+		}
+		if cmd.TimeInForce == "" && cmd.Metadata["time_in_force"] == nil {
+			cmd.TimeInForce = tif
+		}
+		if cmd.Metadata == nil {
+			cmd.Metadata = make(map[string]interface{})
+		}
+		if _, set := cmd.Metadata["algo"]; set {
+			continue
+		}
+		for k, v := range execution {
+			if _, set := cmd.Metadata[k]; !set {
+				cmd.Metadata[k] = v
+			}
+		}
+	}
+}
+
+// Flush writes the state kept in memory to storage; call it on shutdown
+func (e *Engine) Flush(ctx context.Context) error {
+	if e.stateCache == nil {
+		return nil
+	}
+	return e.stateCache.Flush(ctx)
+}
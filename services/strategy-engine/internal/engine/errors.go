@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrorStream receives strategy_error events for other services and alerting
+const ErrorStream = "error_events"
+
+// Error classes carried by strategy_error events
+const (
+	ErrorClassHandler    = "handler_error"
+	ErrorClassPanic      = "handler_panic"
+	ErrorClassRejected   = "order_rejected"
+	ErrorClassTimeout    = "timeout"
+	ErrorClassExecution  = "execution_failed"
+	ErrorClassPriceCheck = "price_check"
+	ErrorClassThrottle   = "throttled"
+	ErrorClassSelfTrade  = "self_trade"
+	ErrorClassResolved   = "market_resolved"
+	ErrorClassRiskBudget = "risk_budget"
+	ErrorClassTenant     = "tenant_isolation"
+	ErrorClassSize       = "invalid_size"
+	ErrorClassVolatility = "volatility"
+	ErrorClassExpired    = "deadline_exceeded"
+	ErrorClassMiddleware = "middleware"
+	ErrorClassFanOut     = "invalid_fan_out"
+	ErrorClassAccount    = "account_check"
+	ErrorClassTrigger    = "invalid_trigger"
+	ErrorClassUnknown    = "unknown_outcome" // outbox command that may or may not have executed
+)
+
+// classifyExecutionError maps an executor failure to an error class
+func classifyExecutionError(err error) string {
+	var rejected *executor.OrderRejectedError
+	switch {
+	case errors.As(err, &rejected) && rejected.StatusCode < 500:
+		return ErrorClassRejected
+	case errors.Is(err, executor.ErrInvalidOrder):
+		return ErrorClassRejected
+	case errors.Is(err, executor.ErrDeadlineExceeded):
+		return ErrorClassExpired
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	default:
+		return ErrorClassExecution
+	}
+}
+
+// publishStrategyError records a decision dropped by an error and publishes a
+// structured strategy_error event. cmd is nil when the failure happened in the
+// handler, before any command existed.
+func (e *Engine) publishStrategyError(
+	ctx context.Context,
+	strategy types.Strategy,
+	event types.Event,
+	class string,
+	err error,
+	cmd *types.Command,
+) {
+	var dropped []types.Command
+	if cmd != nil {
+		dropped = []types.Command{*cmd}
+	}
+	e.recordDecision(strategy, event, dropped, class, err)
+	if e.opts.Compare {
+		return
+	}
+	e.publishError(ctx, strategy, event, class, err, cmd)
+}
+
+// publishError publishes a strategy_error event without recording a decision,
+// for failures after the commands were decided
+func (e *Engine) publishError(
+	ctx context.Context,
+	strategy types.Strategy,
+	event types.Event,
+	class string,
+	err error,
+	cmd *types.Command,
+) {
+	platform := event.Platform
+	data := map[string]interface{}{
+		"strategy":    strategy.Name,
+		"strategy_id": strategy.ID,
+		"tenant":      strategyTenant(strategy),
+		"event_id":    event.ID,
+		"event_type":  event.Type,
+		"error_class": class,
+		"error":       err.Error(),
+	}
+	if cmd != nil {
+		data["command"] = cmd
+		platform = cmd.Platform
+	}
+
+	now := time.Now().UTC()
+	errorEvent := types.Event{
+		ID:        fmt.Sprintf("strategy_error:%s:%d", strategy.ID, now.UnixNano()),
+		Type:      "strategy_error",
+		Platform:  platform,
+		Timestamp: now,
+		Data:      data,
+	}
+
+	if err := e.eventBus.Publish(ctx, ErrorStream, errorEvent); err != nil {
+		log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to publish strategy error event")
+	}
+}
+
+// publishExecutionErrors publishes one strategy_error per failed command
+func (e *Engine) publishExecutionErrors(ctx context.Context, strategy types.Strategy, event types.Event, err error) {
+	for _, cmdErr := range executor.CommandErrors(err) {
+		cmd := cmdErr.Command
+		e.publishError(ctx, strategy, event, classifyExecutionError(cmdErr.Err), cmdErr.Err, &cmd)
+	}
+}
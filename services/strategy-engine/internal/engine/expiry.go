@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Orders can expire: a place_order with ExpiresAt set is cancelled by the
+// order reaper once that time has passed, so orders nobody looks after any
+// more (e.g. a hedge whose strategy was disabled) do not rest for days.
+// Commands without ExpiresAt get one from the order TTL: Options.OrderTTL,
+// overridable per strategy with config "order_ttl" in seconds. Zero means
+// orders never expire. Orders on platforms that cannot cancel get no TTL.
+//
+// The expiry is journaled with the order and restored by open order recovery,
+// so it survives restarts. Orphan orders are left alone.
+
+const orderReapInterval = 30 * time.Second
+
+func (e *Engine) orderTTL(strategy types.Strategy) time.Duration {
+	if seconds, ok := strategy.Config["order_ttl"].(float64); ok && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.opts.OrderTTL
+}
+
+// applyOrderTTL sets the expiry of place_order commands that have none, where
+// the order can be cancelled
+func (e *Engine) applyOrderTTL(strategy types.Strategy, commands []types.Command) {
+	ttl := e.orderTTL(strategy)
+	if ttl <= 0 {
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(ttl)
+	for i := range commands {
+		if commands[i].PlacesOrder() && commands[i].ExpiresAt == nil && e.executor.CanCancel(commands[i]) {
+			commands[i].ExpiresAt = &expiresAt
+		}
+	}
+}
+
+// runOrderReaper cancels expired orders until ctx is cancelled
+func (e *Engine) runOrderReaper(ctx context.Context) {
+	ticker := time.NewTicker(orderReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.reapExpiredOrders(ctx)
+			e.expireConditionalCommands(ctx)
+		}
+	}
+}
+
+// reapExpiredOrders cancels every tracked order past its expiry. Orders that
+// fail to cancel stay tracked and are tried again on the next run.
+func (e *Engine) reapExpiredOrders(ctx context.Context) {
+	now := time.Now()
+	for _, order := range e.orders.Open() {
+		if order.Orphan || !order.Expired(now) {
+			continue
+		}
+
+		cancel := types.Command{
+			Type:      "cancel_order",
+			Platform:  order.Platform,
+			AccountID: order.AccountID,
+			MarketID:  order.MarketID,
+			Metadata: map[string]interface{}{
+				"order_id": order.OrderID,
+				"strategy": order.Strategy,
+				"reason":   "expired",
+			},
+		}
+		if !e.executor.CanCancel(cancel) {
+			continue
+		}
+		if err := e.executor.ExecuteCommands(ctx, []types.Command{cancel}); err != nil {
+			log.Warn().
+				Err(err).
+				Str("order_id", order.OrderID).
+				Str("strategy", order.Strategy).
+				Msg("Failed to cancel expired order")
+			continue
+		}
+		e.forgetGroupOrder(ctx, order.Platform, order.OrderID)
+
+		log.Info().
+			Str("platform", order.Platform).
+			Str("order_id", order.OrderID).
+			Str("strategy", order.Strategy).
+			Time("expires_at", *order.ExpiresAt).
+			Float64("remaining", order.Remaining()).
+			Msg("Expired order cancelled")
+	}
+}
+
+// SetOrderTTL changes the default order TTL at runtime; zero disables it
+func (e *Engine) SetOrderTTL(ttl time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.opts.OrderTTL = ttl
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Explain mode: a strategy with config "explain" set, or switched on with
+// SetExplain, keeps for each of its last explainEvents events why it did or
+// did not act on it. Its handler adds reasons with strategyctx.Strategy.Explain
+// (missing pair, below minimum size, ...); the engine adds its own (outside
+// the trading window, suspended, dropped by a check, executed). Explanations
+// live in memory only.
+
+// explainEvents is how many explained events are kept per strategy
+const explainEvents = 200
+
+// explanations are the explained events of the strategies in explain mode
+type explanations struct {
+	mu      sync.Mutex
+	enabled map[string]bool                // by strategy name, set at runtime
+	pending map[string]*types.Explanation  // by strategy name and event ID, while being handled
+	recent  map[string][]types.Explanation // by strategy name, oldest first
+}
+
+func newExplanations() *explanations {
+	return &explanations{
+		enabled: make(map[string]bool),
+		pending: make(map[string]*types.Explanation),
+		recent:  make(map[string][]types.Explanation),
+	}
+}
+
+func explanationKey(strategy types.Strategy, event types.Event) string {
+	return strategy.Name + "\x00" + event.ID
+}
+
+// Explaining reports whether a strategy runs in explain mode; it implements
+// strategyctx.Explainer. A runtime switch overrides config "explain".
+func (e *Engine) Explaining(strategy types.Strategy) bool {
+	e.explanations.mu.Lock()
+	enabled, set := e.explanations.enabled[strategy.Name]
+	e.explanations.mu.Unlock()
+	if set {
+		return enabled
+	}
+	explain, _ := strategy.Config["explain"].(bool)
+	return explain
+}
+
+// Explain adds a reason to the explanation of an event being handled; it
+// implements strategyctx.Explainer. Reasons for events that are no longer
+// being handled, e.g. from a handler that timed out, are dropped.
+func (e *Engine) Explain(strategy types.Strategy, event types.Event, reason string) {
+	e.explanations.mu.Lock()
+	defer e.explanations.mu.Unlock()
+
+	if explanation, ok := e.explanations.pending[explanationKey(strategy, event)]; ok {
+		explanation.Reasons = append(explanation.Reasons, reason)
+	}
+}
+
+// explainf adds a reason from the engine
+func (e *Engine) explainf(strategy types.Strategy, event types.Event, format string, args ...interface{}) {
+	e.Explain(strategy, event, fmt.Sprintf(format, args...))
+}
+
+// beginExplanation starts explaining what a strategy makes of an event and
+// returns the function that files the explanation, or a no-op outside
+// explain mode
+func (e *Engine) beginExplanation(strategy types.Strategy, event types.Event) func() {
+	if !e.Explaining(strategy) {
+		return func() {}
+	}
+
+	key := explanationKey(strategy, event)
+	e.explanations.mu.Lock()
+	e.explanations.pending[key] = &types.Explanation{
+		Strategy:  strategy.Name,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Platform:  event.Platform,
+		Reasons:   []string{},
+		At:        time.Now().UTC(),
+	}
+	e.explanations.mu.Unlock()
+
+	return func() {
+		e.explanations.mu.Lock()
+		defer e.explanations.mu.Unlock()
+
+		explanation := e.explanations.pending[key]
+		delete(e.explanations.pending, key)
+		if !explanation.Acted && len(explanation.Reasons) == 0 {
+			explanation.Reasons = append(explanation.Reasons, "handler returned no commands")
+		}
+
+		recent := append(e.explanations.recent[strategy.Name], *explanation)
+		if len(recent) > explainEvents {
+			recent = recent[len(recent)-explainEvents:]
+		}
+		e.explanations.recent[strategy.Name] = recent
+	}
+}
+
+// explainDecision adds what the engine decided on the commands of an event
+// being explained
+func (e *Engine) explainDecision(strategy types.Strategy, event types.Event, commands []types.Command, class string, err error) {
+	e.explanations.mu.Lock()
+	defer e.explanations.mu.Unlock()
+
+	explanation, ok := e.explanations.pending[explanationKey(strategy, event)]
+	if !ok {
+		return
+	}
+	if class == "" {
+		explanation.Acted = true
+		explanation.Commands = len(commands)
+		explanation.Reasons = append(explanation.Reasons, fmt.Sprintf("%d command(s) sent to execution", len(commands)))
+		return
+	}
+	reason := class
+	if err != nil {
+		reason += ": " + err.Error()
+	}
+	if len(commands) > 0 {
+		reason = fmt.Sprintf("%s (%d command(s) dropped)", reason, len(commands))
+	}
+	explanation.Reasons = append(explanation.Reasons, reason)
+}
+
+// SetExplain switches explain mode of a strategy on or off until the engine
+// restarts, overriding its config
+func (e *Engine) SetExplain(name string, enabled bool) {
+	e.explanations.mu.Lock()
+	defer e.explanations.mu.Unlock()
+
+	e.explanations.enabled[name] = enabled
+}
+
+// Explanations returns the latest explained events of a strategy, newest
+// first. limit <= 0 returns all that are kept.
+func (e *Engine) Explanations(name string, limit int) []types.Explanation {
+	e.explanations.mu.Lock()
+	defer e.explanations.mu.Unlock()
+
+	recent := e.explanations.recent[name]
+	if limit <= 0 || limit > len(recent) {
+		limit = len(recent)
+	}
+	result := make([]types.Explanation, 0, limit)
+	for i := len(recent) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, recent[i])
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Fan-out: a command listing Accounts stands for the same command on each of
+// them, e.g. mirroring a trade across several accounts. The first stage of
+// the pipeline expands it into one command per account, so every check sees
+// per-account commands. Shares (or Size) are split by Weights, or evenly
+// without them; cancels are copied as they are. Each command carries the
+// group in its metadata:
+//
+//	"fan_out_group"    shared by the commands of one fan-out
+//	"fan_out_index"    position of the account in Accounts
+//	"fan_out_accounts" number of accounts
+//	"fan_out_weight"   share of the size the account got
+
+// expandFanOuts replaces fan-out commands by their per-account commands and
+// drops the ones that cannot be expanded
+func (e *Engine) expandFanOuts(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	var expanded []types.Command
+	for i, cmd := range commands {
+		if len(cmd.Accounts) == 0 {
+			expanded = append(expanded, cmd)
+			continue
+		}
+
+		group := fmt.Sprintf("%s:%s:%d", strategy.Name, event.ID, i)
+		fanned, err := fanOut(cmd, group)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Str("market", cmd.MarketID).
+				Msg("Fan-out command dropped")
+			e.publishStrategyError(ctx, strategy, event, ErrorClassFanOut, err, &cmd)
+			continue
+		}
+		expanded = append(expanded, fanned...)
+	}
+	return expanded
+}
+
+// fanOut expands one fan-out command
+func fanOut(cmd types.Command, group string) ([]types.Command, error) {
+	if cmd.Type == "amend_order" {
+		return nil, errors.New("amend_order replaces one account's order and cannot fan out")
+	}
+	weights := cmd.Weights
+	if len(weights) == 0 {
+		weights = make([]float64, len(cmd.Accounts))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	if len(weights) != len(cmd.Accounts) {
+		return nil, fmt.Errorf("%d weights for %d accounts", len(weights), len(cmd.Accounts))
+	}
+
+	var total float64
+	seen := make(map[string]bool, len(cmd.Accounts))
+	for i, account := range cmd.Accounts {
+		if account == "" {
+			return nil, errors.New("empty account in accounts")
+		}
+		if seen[account] {
+			return nil, fmt.Errorf("account %s listed twice", account)
+		}
+		seen[account] = true
+		if weights[i] < 0 || math.IsNaN(weights[i]) || math.IsInf(weights[i], 0) {
+			return nil, fmt.Errorf("invalid weight %v for account %s", weights[i], account)
+		}
+		total += weights[i]
+	}
+	if total <= 0 {
+		return nil, errors.New("weights add up to zero")
+	}
+
+	commands := make([]types.Command, 0, len(cmd.Accounts))
+	for i, account := range cmd.Accounts {
+		share := weights[i] / total
+		if share == 0 {
+			continue
+		}
+
+		metadata := make(map[string]interface{}, len(cmd.Metadata)+4)
+		for k, v := range cmd.Metadata {
+			metadata[k] = v
+		}
+		metadata["fan_out_group"] = group
+		metadata["fan_out_index"] = i
+		metadata["fan_out_accounts"] = len(cmd.Accounts)
+		metadata["fan_out_weight"] = share
+
+		c := cmd
+		c.AccountID = account
+		c.Accounts = nil
+		c.Weights = nil
+		c.Metadata = metadata
+		if cmd.PlacesOrder() {
+			c.Shares = cmd.Shares * share
+			c.Size = cmd.Size * share
+		}
+		commands = append(commands, c)
+	}
+	return commands, nil
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/flags"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Feature flags gate risky subsystems, so a new risk check or pricing model
+// can be switched on in staging first, then for a share of live markets,
+// without a deploy. They are kept in the feature_flags table, edited through
+// the admin API and, like market mappings, picked up from other instances on
+// the next strategy refresh. A flag is on when it is enabled, lists the
+// engine's environment (Options.Environment) or no environment, and the
+// market falls in its percentage.
+//
+// Strategies check flags with strategyctx.Strategy.Feature. Custom
+// middlewares are gated by the flag named MiddlewareFlagPrefix + their name,
+// when one exists: the middleware then only runs on batches with a command
+// on a market the flag is on for; other batches skip it.
+
+// MiddlewareFlagPrefix starts the name of the flag gating a custom middleware
+const MiddlewareFlagPrefix = "middleware."
+
+// ErrInvalidFlag is returned for feature flags with a bad name or percentage
+var ErrInvalidFlag = errors.New("feature flag needs a name of letters, digits, '.', '_' or '-' and a percentage from 0 to 100")
+
+var flagName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
+// loadFeatureFlags replaces the flags with the stored ones
+func (e *Engine) loadFeatureFlags(ctx context.Context) error {
+	stored, err := e.storage.GetFeatureFlags(ctx)
+	if err != nil {
+		return err
+	}
+	e.flags.Set(stored)
+	return nil
+}
+
+// Flags returns the feature flags, as seen from the engine's environment
+func (e *Engine) Flags() *flags.Set {
+	return e.flags
+}
+
+// FeatureFlags returns every feature flag
+func (e *Engine) FeatureFlags() []types.FeatureFlag {
+	return e.flags.All()
+}
+
+// FeatureEnabled reports whether a flag is on for a market in the engine's
+// environment; "" asks about no market in particular
+func (e *Engine) FeatureEnabled(name, marketID string) bool {
+	return e.flags.Enabled(name, marketID)
+}
+
+// PutFeatureFlag creates a feature flag or replaces its settings
+func (e *Engine) PutFeatureFlag(ctx context.Context, flag types.FeatureFlag) (types.FeatureFlag, error) {
+	if err := validateFeatureFlag(&flag); err != nil {
+		return flag, err
+	}
+	stored, err := e.storage.PutFeatureFlag(ctx, flag)
+	if err != nil {
+		return stored, err
+	}
+	e.flags.Put(stored)
+
+	log.Info().
+		Str("flag", stored.Name).
+		Bool("enabled", stored.Enabled).
+		Float64("percent", stored.Percent).
+		Strs("environments", stored.Environments).
+		Str("operator", stored.UpdatedBy).
+		Msg("Feature flag saved")
+	return stored, nil
+}
+
+// DeleteFeatureFlag removes a feature flag, which turns it off
+func (e *Engine) DeleteFeatureFlag(ctx context.Context, name string) error {
+	if err := e.storage.DeleteFeatureFlag(ctx, name); err != nil {
+		return err
+	}
+	e.flags.Remove(name)
+
+	log.Info().Str("flag", name).Msg("Feature flag deleted")
+	return nil
+}
+
+func validateFeatureFlag(flag *types.FeatureFlag) error {
+	flag.Name = strings.TrimSpace(flag.Name)
+	if !flagName.MatchString(flag.Name) {
+		return ErrInvalidFlag
+	}
+	if flag.Percent < 0 || flag.Percent > 100 || math.IsNaN(flag.Percent) {
+		return ErrInvalidFlag
+	}
+
+	var environments []string
+	for _, env := range flag.Environments {
+		if env = strings.TrimSpace(env); env != "" {
+			environments = append(environments, env)
+		}
+	}
+	flag.Environments = environments
+	return nil
+}
+
+// flagged gates a custom middleware on its feature flag, if one exists
+func (e *Engine) flagged(m namedMiddleware) namedMiddleware {
+	name := MiddlewareFlagPrefix + m.name
+	return namedMiddleware{m.name, func(next CommandHandler) CommandHandler {
+		gated := m.middleware(next)
+		return func(ctx context.Context, b *CommandBatch) error {
+			if flag, ok := e.flags.Get(name); ok && !e.flagOnForBatch(flag, b) {
+				return next(ctx, b)
+			}
+			return gated(ctx, b)
+		}
+	}}
+}
+
+// flagOnForBatch reports whether a flag is on for any market the batch's
+// commands trade
+func (e *Engine) flagOnForBatch(flag types.FeatureFlag, b *CommandBatch) bool {
+	for _, cmd := range b.Commands {
+		if flags.On(flag, e.flags.Environment(), cmd.MarketID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,440 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Emergency flatten closes every position on the accounts of a strategy, or on
+// a single account. Account services only buy outcomes, so by default a
+// position is closed by buying the same number of shares of the opposite
+// outcome, priced to cross the live book. The book comes from the cache, or
+// from the account service when the cache has none; if a position still cannot
+// be priced, nothing is sent and the flatten fails. Mode "venue" instead asks
+// the account service to sell everything (flatten_account), which only
+// platforms with close_all support, and only for whole accounts.
+//
+// A strategy flatten closes the positions the journal attributes to the
+// strategy, capped at what the venue reports, and cancels only the
+// strategy's open orders; other strategies sharing the accounts are left
+// alone. YES and NO shares of one market offset each other, so only the
+// excess is closed.
+//
+// Flatten commands go straight to the executor: they bypass the outbox, the
+// price guard and the kill switch. Open orders are cancelled first so resting
+// orders cannot add exposure while positions are closed.
+
+const (
+	FlattenModeOffset = "offset"
+	FlattenModeVenue  = "venue"
+
+	// defaultFlattenSlippage is added to the crossing price so the order fills
+	defaultFlattenSlippage = 0.02
+
+	// flattenBookMaxAge is how old a book may be to price a closing order
+	flattenBookMaxAge = 30 * time.Second
+
+	// flattenBookTimeout bounds fetching a book from the account service
+	flattenBookTimeout = 5 * time.Second
+)
+
+// ErrFlattenUnpriced is returned when a flatten found positions it cannot
+// price; nothing was sent
+var ErrFlattenUnpriced = errors.New("positions cannot be priced")
+
+// FlattenRequest selects what to flatten: all accounts of Strategy, or one
+// Platform/AccountID. Preview builds the plan without sending anything.
+type FlattenRequest struct {
+	Strategy  string  `json:"strategy"`
+	Platform  string  `json:"platform"`
+	AccountID string  `json:"account_id"`
+	Mode      string  `json:"mode"`
+	Slippage  float64 `json:"slippage"`
+	Preview   bool    `json:"preview"`
+	Reason    string  `json:"reason"`
+	Operator  string  `json:"operator"`
+
+	// ownMarkets limits closing to the markets of this instance's shard
+	ownMarkets bool
+}
+
+// FlattenResult lists the commands generated and what could not be closed
+type FlattenResult struct {
+	Accounts int             `json:"accounts"`
+	Commands []types.Command `json:"commands"`
+	Skipped  []string        `json:"skipped,omitempty"`
+	Errors   []string        `json:"errors,omitempty"`
+	Preview  bool            `json:"preview"`
+}
+
+// Flatten runs an emergency flatten. Per-account failures are collected in the
+// result. An error is returned for an invalid request, and wrapping
+// ErrFlattenUnpriced when positions could not be priced.
+func (e *Engine) Flatten(ctx context.Context, req FlattenRequest) (*FlattenResult, error) {
+	if req.Mode == "" {
+		req.Mode = FlattenModeOffset
+	}
+	if req.Mode != FlattenModeOffset && req.Mode != FlattenModeVenue {
+		return nil, fmt.Errorf("unknown flatten mode %q", req.Mode)
+	}
+	if req.Mode == FlattenModeVenue && req.Strategy != "" {
+		return nil, errors.New("venue mode closes whole accounts; flatten a strategy in offset mode")
+	}
+	if req.Slippage <= 0 {
+		req.Slippage = defaultFlattenSlippage
+	}
+
+	accounts, err := e.flattenAccounts(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Mode == FlattenModeVenue {
+		for _, account := range accounts {
+			if !e.executor.CanCloseAll(account.Platform) {
+				return nil, fmt.Errorf("venue mode is not supported on %s", account.Platform)
+			}
+		}
+	}
+
+	// Once started, a flatten must not be abandoned because the caller went away
+	ctx = context.WithoutCancel(ctx)
+
+	var held map[string]float64
+	if req.Strategy != "" {
+		attributed, err := e.storage.StrategyPositions(ctx, req.Strategy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the strategy's positions: %w", err)
+		}
+		held = netPositions(attributed)
+	}
+
+	result := &FlattenResult{Accounts: len(accounts), Commands: []types.Command{}, Preview: req.Preview}
+	label := req.Strategy
+	if label == "" {
+		label = "emergency_flatten"
+	}
+	metadata := func() map[string]interface{} {
+		return map[string]interface{}{
+			"strategy":         label,
+			"skip_price_check": true,
+			"flatten_reason":   req.Reason,
+		}
+	}
+
+	log.Warn().
+		Str("strategy", req.Strategy).
+		Str("account", req.AccountID).
+		Str("mode", req.Mode).
+		Bool("preview", req.Preview).
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Msg("Emergency flatten requested")
+
+	var cancels []types.Command
+	var unpriced []string
+	for _, account := range accounts {
+		base := types.Command{Platform: account.Platform, AccountID: account.AccountID, Metadata: metadata()}
+
+		if e.executor.CanCancel(base) {
+			cancels = append(cancels, e.flattenCancels(req, base)...)
+		} else {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s/%s: open orders: %s cannot cancel orders",
+				account.Platform, account.AccountID, account.Platform))
+		}
+
+		if req.Mode == FlattenModeVenue {
+			flatten := base
+			flatten.Type = "flatten_account"
+			result.Commands = append(result.Commands, flatten)
+			continue
+		}
+
+		positions, err := e.executor.FetchPositions(ctx, account.Platform, account.AccountID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", account.Platform, account.AccountID, err))
+			continue
+		}
+
+		for _, position := range flattenPositions(positions, held) {
+			if req.ownMarkets && !e.opts.Shard.OwnsMarket(position.MarketID) {
+				continue
+			}
+			name := fmt.Sprintf("%s/%s %s %s", position.Platform, position.AccountID, position.MarketID, position.Side)
+			if position.Shares <= 0 {
+				result.Skipped = append(result.Skipped, name+": not held by "+req.Strategy)
+				continue
+			}
+
+			cmd, err := e.closingOrder(ctx, position, req.Slippage)
+			if err != nil {
+				unpriced = append(unpriced, name)
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			cmd.Metadata = metadata()
+			cmd.Metadata["closing_side"] = position.Side
+			result.Commands = append(result.Commands, cmd)
+		}
+	}
+
+	if req.Preview {
+		return result, nil
+	}
+
+	if len(unpriced) > 0 {
+		err := fmt.Errorf("%w, nothing was sent: %s", ErrFlattenUnpriced, strings.Join(unpriced, ", "))
+		e.postFlattenFailure(ctx, req, err)
+		return result, err
+	}
+
+	for _, cancel := range cancels {
+		if err := e.executor.ExecuteCommands(ctx, []types.Command{cancel}); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: cancel orders: %v", cancel.Platform, cancel.AccountID, err))
+		}
+	}
+
+	if err := e.executor.ExecuteCommands(ctx, result.Commands); err != nil {
+		for _, cmdErr := range executor.CommandErrors(err) {
+			result.Errors = append(result.Errors, cmdErr.Error())
+		}
+	}
+
+	e.postFlattenAlert(ctx, req, result)
+
+	return result, nil
+}
+
+// flattenCancels returns the cancels that clear an account before it is
+// flattened: every open order, or only the strategy's tracked orders, in this
+// instance's markets with ownMarkets
+func (e *Engine) flattenCancels(req FlattenRequest, base types.Command) []types.Command {
+	if req.Strategy == "" {
+		cancel := base
+		cancel.Type = "cancel_all_orders"
+		return []types.Command{cancel}
+	}
+
+	var cancels []types.Command
+	for _, order := range e.orders.ByAccount(base.Platform, base.AccountID) {
+		if order.Strategy != req.Strategy {
+			continue
+		}
+		if req.ownMarkets && !e.opts.Shard.OwnsMarket(order.MarketID) {
+			continue
+		}
+		cancel := base
+		cancel.Type = "cancel_order"
+		cancel.MarketID = order.MarketID
+		cancel.Metadata = make(map[string]interface{}, len(base.Metadata)+1)
+		for k, v := range base.Metadata {
+			cancel.Metadata[k] = v
+		}
+		cancel.Metadata["order_id"] = order.OrderID
+		cancels = append(cancels, cancel)
+	}
+	return cancels
+}
+
+// netPositions offsets the YES and NO shares of each market, since a pair
+// pays out 1 whatever the outcome. It returns the net YES shares (negative
+// for NO) by platform, account and market.
+func netPositions(positions []types.Position) map[string]float64 {
+	net := make(map[string]float64)
+	for _, p := range positions {
+		key := p.Platform + ":" + p.AccountID + ":" + p.MarketID
+		switch p.Side {
+		case "yes":
+			net[key] += p.Shares
+		case "no":
+			net[key] -= p.Shares
+		}
+	}
+	return net
+}
+
+// flattenPositions returns the positions to close: the venue's positions net
+// of offsetting YES and NO shares and, when held is set, capped at what the
+// strategy holds. Positions the strategy does not hold come back with zero
+// shares.
+func flattenPositions(venue []types.Position, held map[string]float64) []types.Position {
+	var positions []types.Position
+	seen := make(map[string]bool)
+	net := netPositions(venue)
+	for _, p := range venue {
+		key := p.Platform + ":" + p.AccountID + ":" + p.MarketID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		shares := net[key]
+		if shares == 0 {
+			continue
+		}
+		if held != nil {
+			switch strategy := held[key]; {
+			case strategy*shares <= 0:
+				shares = 0
+			case math.Abs(strategy) < math.Abs(shares):
+				shares = strategy
+			}
+		}
+
+		position := p
+		position.Side = "yes"
+		if shares < 0 {
+			position.Side = "no"
+		}
+		position.Shares = math.Abs(shares)
+		positions = append(positions, position)
+	}
+	return positions
+}
+
+func (e *Engine) flattenAccounts(req FlattenRequest) ([]managedAccount, error) {
+	if req.Strategy == "" {
+		if req.AccountID == "" {
+			return nil, errors.New("strategy or account_id is required")
+		}
+		platform := req.Platform
+		if platform == "" {
+			platform = "predict"
+		}
+		return []managedAccount{{Platform: platform, AccountID: req.AccountID}}, nil
+	}
+
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	for _, strategy := range strategies {
+		if strategy.Name == req.Strategy {
+			// Never touch another tenant's accounts through a misconfigured pair
+			tenant := strategyTenant(strategy)
+			var accounts []managedAccount
+			for _, account := range managedAccounts(strategy) {
+				if owner := e.accountTenant(account.AccountID); owner == "" || owner == tenant {
+					accounts = append(accounts, account)
+				}
+			}
+			return accounts, nil
+		}
+	}
+	return nil, fmt.Errorf("strategy %q is not loaded", req.Strategy)
+}
+
+// closingOrder buys the opposite outcome of a position at the price that
+// crosses the book, or the last market price when no book is available
+func (e *Engine) closingOrder(ctx context.Context, position types.Position, slippage float64) (types.Command, error) {
+	side := "no"
+	if position.Side == "no" {
+		side = "yes"
+	}
+
+	var price float64
+	if quote, ok := e.flattenQuote(ctx, position.Platform, position.MarketID); ok {
+		if ask, _, ok := quote.AskFor(side); ok {
+			price = ask
+		}
+	}
+	if price == 0 {
+		yesPrice, ok := e.markets.Price(position.MarketID)
+		if !ok {
+			return types.Command{}, errors.New("no book or market price to close at")
+		}
+		price = yesPrice
+		if side == "no" {
+			price = 1 - yesPrice
+		}
+	}
+
+	price += slippage
+	if price > 0.99 {
+		price = 0.99
+	}
+
+	return types.Command{
+		Type:      "place_order",
+		Platform:  position.Platform,
+		AccountID: position.AccountID,
+		MarketID:  position.MarketID,
+		Side:      side,
+		Price:     price,
+		Shares:    position.Shares,
+	}, nil
+}
+
+// flattenQuote returns a fresh top of book for a market, asking the account
+// service when the cache has none
+func (e *Engine) flattenQuote(ctx context.Context, platform, marketID string) (orderbook.Quote, bool) {
+	if quote, ok := e.books.Top(platform, marketID, flattenBookMaxAge); ok {
+		return quote, true
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, flattenBookTimeout)
+	defer cancel()
+
+	event, err := e.executor.FetchOrderBook(fetchCtx, platform, marketID)
+	if err != nil {
+		log.Warn().Err(err).Str("platform", platform).Str("market", marketID).Msg("No order book to price a closing order")
+		return orderbook.Quote{}, false
+	}
+	e.books.Update(event)
+	return e.books.Top(platform, marketID, flattenBookMaxAge)
+}
+
+// postFlattenFailure raises an alert for a flatten that sent nothing
+func (e *Engine) postFlattenFailure(ctx context.Context, req FlattenRequest, cause error) {
+	target := req.Strategy
+	if target == "" {
+		target = req.AccountID
+	}
+
+	message := fmt.Sprintf("%s could not flatten %s (%s): %v. Reason: %s", req.Operator, target, req.Mode, cause, req.Reason)
+	log.Error().Err(cause).Str("target", target).Msg("Emergency flatten failed")
+
+	if err := e.storage.CreateAlert(ctx, "strategy", "Emergency flatten failed", message, map[string]interface{}{
+		"strategy": req.Strategy,
+		"account":  req.AccountID,
+		"mode":     req.Mode,
+		"operator": req.Operator,
+		"error":    cause.Error(),
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to create flatten alert")
+	}
+}
+
+func (e *Engine) postFlattenAlert(ctx context.Context, req FlattenRequest, result *FlattenResult) {
+	target := req.Strategy
+	if target == "" {
+		target = req.AccountID
+	}
+
+	message := fmt.Sprintf(
+		"%s flattened %s (%s): %d closing commands on %d accounts, %d skipped, %d errors. Reason: %s",
+		req.Operator, target, req.Mode, len(result.Commands), result.Accounts,
+		len(result.Skipped), len(result.Errors), req.Reason,
+	)
+
+	if err := e.storage.CreateAlert(ctx, "strategy", "Emergency flatten executed", message, map[string]interface{}{
+		"strategy": req.Strategy,
+		"account":  req.AccountID,
+		"mode":     req.Mode,
+		"operator": req.Operator,
+		"commands": len(result.Commands),
+		"skipped":  result.Skipped,
+		"errors":   result.Errors,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to create flatten alert")
+	}
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// The event bus reports entries of numbered producers that never reached the
+// engine (see eventbus/sequence.go). Every gap raises an event_loss alert, at
+// most every lagAlertCooldown per stream, and the lost counts show up in the
+// lag report. With Options.ReplayGaps the engine also looks for the lost
+// entries in the event archive and handles the ones it finds before the entry
+// that revealed the gap; entries that were never published, or trimmed before
+// the archiver copied them, stay lost.
+
+// maxGapReplay caps the archived events replayed for one gap
+const maxGapReplay = 1000
+
+// handleGap alerts on a sequence gap and replays what the archive holds of it
+func (e *Engine) handleGap(ctx context.Context, gap eventbus.Gap) {
+	e.alertGap(ctx, gap)
+
+	if !e.opts.ReplayGaps || gap.AfterID == "" {
+		return
+	}
+
+	replayed, err := e.replayGap(ctx, gap)
+	if err != nil {
+		log.Error().Err(err).Str("stream", gap.Stream).Msg("Failed to replay lost events from the archive")
+		return
+	}
+	log.Info().
+		Str("stream", gap.Stream).
+		Str("producer", gap.Producer).
+		Int64("missing", gap.Missing()).
+		Int("replayed", replayed).
+		Msg("Replayed lost events from the archive")
+}
+
+// replayGap handles the archived entries between the last delivered entry and
+// the one that revealed the gap
+func (e *Engine) replayGap(ctx context.Context, gap eventbus.Gap) (int, error) {
+	afterMs, afterSeq, _, ok := eventbus.ParseStreamID(gap.AfterID)
+	if !ok {
+		return 0, fmt.Errorf("invalid stream ID %q", gap.AfterID)
+	}
+	beforeMs, beforeSeq, _, ok := eventbus.ParseStreamID(gap.BeforeID)
+	if !ok {
+		return 0, fmt.Errorf("invalid stream ID %q", gap.BeforeID)
+	}
+
+	events, err := e.storage.GetArchivedEvents(ctx, gap.Stream,
+		[2]int64{afterMs, afterSeq}, [2]int64{beforeMs, beforeSeq}, maxGapReplay+2)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, event := range events {
+		// The bounds were delivered already
+		if event.ID == gap.AfterID || event.ID == gap.BeforeID {
+			continue
+		}
+		if replayed == maxGapReplay {
+			log.Warn().Str("stream", gap.Stream).Int("limit", maxGapReplay).Msg("Lost events beyond the replay limit skipped")
+			break
+		}
+		if err := e.handleEvent(ctx, event); err != nil {
+			log.Error().Err(err).Str("event_id", event.ID).Msg("Failed to handle replayed event")
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+func (e *Engine) alertGap(ctx context.Context, gap eventbus.Gap) {
+	if !e.lag.shouldAlert("gap:"+gap.Stream, gap.DetectedAt) {
+		return
+	}
+
+	data := map[string]interface{}{
+		"stream":    gap.Stream,
+		"producer":  gap.Producer,
+		"from_seq":  gap.FromSeq,
+		"to_seq":    gap.ToSeq,
+		"missing":   gap.Missing(),
+		"after_id":  gap.AfterID,
+		"before_id": gap.BeforeID,
+	}
+	message := fmt.Sprintf("%d events of %s on stream %s never reached the engine (sequence %d-%d)",
+		gap.Missing(), gap.Producer, gap.Stream, gap.FromSeq, gap.ToSeq)
+
+	lossEvent := types.Event{
+		ID:        fmt.Sprintf("event_loss:%s:%d", gap.Stream, gap.DetectedAt.UnixNano()),
+		Type:      "event_loss",
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+	if err := e.eventBus.Publish(ctx, ErrorStream, lossEvent); err != nil {
+		log.Error().Err(err).Str("stream", gap.Stream).Msg("Failed to publish event loss event")
+	}
+
+	if err := e.storage.CreateAlert(ctx, "strategy", fmt.Sprintf("Events lost on %s", gap.Stream), message, data); err != nil {
+		log.Error().Err(err).Str("stream", gap.Stream).Msg("Failed to create event loss alert")
+	}
+}
@@ -0,0 +1,347 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Strategy groups share a risk budget. A strategy joins a group with config
+// "group": "<name>"; the budgets are Options.Groups, by group name. Every
+// strategy is also a member of its tenant's group (see tenants.go).
+//
+//   - MaxExposure caps the capital the live members have at risk: the cost of
+//     their fills on unresolved markets plus the notional of their open
+//     orders. An order that would take the group over it is blocked.
+//   - MaxDailyLoss disables every member of the group once their combined
+//     realized PnL since midnight UTC falls to minus this amount. They stay
+//     disabled until an operator enables them again.
+//
+// Fill costs come from the order journal, refreshed every groupRiskInterval
+// and bumped by every fill in between. Shadow strategies are not counted.
+// With sharding an instance sees only its own open orders, so the exposure
+// limit is approximate: each instance's open orders may use an equal share of
+// what the fills leave of it, the way order throttles are split (Shard.Share).
+// Together the instances stay within the limit, though one busy shard may be
+// blocked while the others leave their share unused.
+
+const groupRiskInterval = time.Minute
+
+// GroupBudget is the shared risk budget of a strategy group. Zero limits are off.
+type GroupBudget struct {
+	MaxExposure  float64
+	MaxDailyLoss float64
+}
+
+type groupRisk struct {
+	mu     sync.Mutex
+	filled map[string]float64 // group -> cost of fills on unresolved markets
+}
+
+func newGroupRisk() *groupRisk {
+	return &groupRisk{filled: make(map[string]float64)}
+}
+
+func (g *groupRisk) addFill(group string, cost float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.filled[group] += cost
+}
+
+func (g *groupRisk) setFilled(group string, cost float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.filled[group] = cost
+}
+
+func (g *groupRisk) filledCost(group string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.filled[group]
+}
+
+// strategyGroup returns the group a strategy belongs to, or ""
+func strategyGroup(strategy types.Strategy) string {
+	group, _ := strategy.Config["group"].(string)
+	return group
+}
+
+// strategyGroups returns the groups of a strategy that have a budget: its
+// configured group and its tenant's
+func (e *Engine) strategyGroups(strategy types.Strategy) []string {
+	var groups []string
+	if group := strategyGroup(strategy); group != "" && e.groupBudget(group) != nil {
+		groups = append(groups, group)
+	}
+	if group := TenantGroup(strategyTenant(strategy)); e.groupBudget(group) != nil {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// groupBudget returns the budget of a group, or nil
+func (e *Engine) groupBudget(group string) *GroupBudget {
+	if tenant, ok := strings.CutPrefix(group, TenantGroup("")); ok {
+		return e.tenantBudget(tenant)
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.opts.Groups[group]
+}
+
+// inGroup reports whether a strategy is a member of a group
+func inGroup(strategy types.Strategy, group string) bool {
+	return group == strategyGroup(strategy) || group == TenantGroup(strategyTenant(strategy))
+}
+
+// groupMembers returns the loaded live strategies of a group, candidates excluded
+func (e *Engine) groupMembers(group string) []types.Strategy {
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	var members []types.Strategy
+	for _, strategy := range strategies {
+		if strategy.CandidateOf == "" && inGroup(strategy, group) {
+			members = append(members, strategy)
+		}
+	}
+	return members
+}
+
+func memberNames(members []types.Strategy) []string {
+	names := make([]string, len(members))
+	for i, member := range members {
+		names[i] = member.Name
+	}
+	return names
+}
+
+// groupOpenNotional returns the notional of the group's open orders on this
+// instance
+func (e *Engine) groupOpenNotional(group string) float64 {
+	members := make(map[string]bool)
+	for _, member := range e.groupMembers(group) {
+		members[member.Name] = true
+	}
+	var open float64
+	for _, order := range e.orders.Open() {
+		if members[order.Strategy] {
+			open += order.Remaining() * order.Price
+		}
+	}
+	return open
+}
+
+// groupExposureLimit returns the group's exposure limit as this instance
+// applies it: the fill cost plus its share of the rest of the limit
+func (e *Engine) groupExposureLimit(limit, filled float64) float64 {
+	return filled + e.opts.Shard.ShareOf(limit-filled)
+}
+
+// applyGroupBudget blocks place_order commands that would take one of the
+// strategy's groups over its exposure limit, publishing a strategy_error for each
+func (e *Engine) applyGroupBudget(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	if strategy.Shadow {
+		return commands
+	}
+
+	limits := make(map[string]float64)
+	exposures := make(map[string]float64)
+	var groups []string
+	for _, group := range e.strategyGroups(strategy) {
+		if budget := e.groupBudget(group); budget != nil && budget.MaxExposure > 0 {
+			groups = append(groups, group)
+			filled := e.groupRisk.filledCost(group)
+			limits[group] = e.groupExposureLimit(budget.MaxExposure, filled)
+			exposures[group] = filled + e.groupOpenNotional(group)
+		}
+	}
+	if len(groups) == 0 {
+		return commands
+	}
+
+	allowed := commands[:0]
+	for _, cmd := range commands {
+		if cmd.PlacesOrder() {
+			notional := cmd.Price * cmd.Shares
+			var err error
+			for _, group := range groups {
+				if exposures[group]+notional > limits[group] {
+					err = fmt.Errorf("group %s exposure %.2f + %.2f would exceed its limit of %.2f",
+						group, exposures[group], notional, limits[group])
+					break
+				}
+			}
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("strategy", strategy.Name).
+					Str("market", cmd.MarketID).
+					Msg("Order blocked by group risk budget")
+				e.publishStrategyError(ctx, strategy, event, ErrorClassRiskBudget, err, &cmd)
+				continue
+			}
+			for _, group := range groups {
+				exposures[group] += notional
+			}
+		}
+		allowed = append(allowed, cmd)
+	}
+	return allowed
+}
+
+// recordGroupFill adds a fill of one of our orders to its groups' exposure
+func (e *Engine) recordGroupFill(platform, orderID string, price, shares float64) {
+	order, ok := e.orders.Get(platform, orderID)
+	if !ok || order.Strategy == "" {
+		return
+	}
+
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	for _, strategy := range strategies {
+		if strategy.Name == order.Strategy {
+			for _, group := range e.strategyGroups(strategy) {
+				e.groupRisk.addFill(group, price*shares)
+			}
+			return
+		}
+	}
+}
+
+// runGroupRisk periodically refreshes group exposure and checks daily losses
+func (e *Engine) runGroupRisk(ctx context.Context) {
+	ticker := time.NewTicker(groupRiskInterval)
+	defer ticker.Stop()
+
+	for {
+		e.refreshGroups(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) refreshGroups(ctx context.Context) {
+	e.mu.RLock()
+	groups := make([]string, 0, len(e.opts.Groups)+len(e.opts.Tenants))
+	for group := range e.opts.Groups {
+		groups = append(groups, group)
+	}
+	for name, tenant := range e.opts.Tenants {
+		if tenant != nil && (tenant.MaxExposure > 0 || tenant.MaxDailyLoss > 0) {
+			groups = append(groups, TenantGroup(name))
+		}
+	}
+	e.mu.RUnlock()
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		members := e.groupMembers(group)
+		if len(members) == 0 {
+			continue
+		}
+
+		cost, err := e.storage.GetOpenFillCost(ctx, memberNames(members))
+		if err != nil {
+			log.Error().Err(err).Str("group", group).Msg("Failed to refresh group exposure")
+		} else {
+			e.groupRisk.setFilled(group, cost)
+		}
+
+		e.checkGroupLoss(ctx, group)
+	}
+}
+
+// checkGroupLoss disables the whole group once its daily loss limit is reached
+func (e *Engine) checkGroupLoss(ctx context.Context, group string) {
+	budget := e.groupBudget(group)
+	if budget == nil || budget.MaxDailyLoss <= 0 {
+		return
+	}
+
+	var members []types.Strategy
+	for _, member := range e.groupMembers(group) {
+		if member.Active {
+			members = append(members, member)
+		}
+	}
+	if len(members) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	pnl, err := e.storage.GetPnLSince(ctx, memberNames(members), midnight)
+	if err != nil {
+		log.Error().Err(err).Str("group", group).Msg("Failed to compute group daily PnL")
+		return
+	}
+	if pnl > -budget.MaxDailyLoss {
+		return
+	}
+
+	reason := fmt.Sprintf("group %s lost %.2f today (limit %.2f)", group, -pnl, budget.MaxDailyLoss)
+	log.Error().
+		Str("group", group).
+		Float64("pnl", pnl).
+		Int("strategies", len(members)).
+		Msg("Group daily loss limit reached, disabling the group")
+
+	for _, member := range members {
+		e.disableStrategy(ctx, member, reason)
+	}
+
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		fmt.Sprintf("Strategy group %s disabled", group),
+		reason+". All strategies of the group were disabled.",
+		map[string]interface{}{
+			"group":          group,
+			"pnl":            pnl,
+			"max_daily_loss": budget.MaxDailyLoss,
+			"strategies":     memberNames(members),
+		},
+	); err != nil {
+		log.Error().Err(err).Str("group", group).Msg("Failed to create group disabled alert")
+	}
+}
+
+// checkStrategyGroupLoss runs the daily loss check for the group of the named
+// strategy, after PnL was recorded for it
+func (e *Engine) checkStrategyGroupLoss(ctx context.Context, name string) {
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	for _, strategy := range strategies {
+		if strategy.Name == name {
+			for _, group := range e.strategyGroups(strategy) {
+				e.checkGroupLoss(ctx, group)
+			}
+			return
+		}
+	}
+}
+
+// SetGroups replaces the group budgets at runtime
+func (e *Engine) SetGroups(groups map[string]*GroupBudget) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.opts.Groups = groups
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Injected events let operators check how strategies react to a crafted event,
+// such as a hypothetical fill, without it appearing on any stream. A dry run
+// only calls the handlers of the strategies that would receive the event and
+// reports their commands; trading windows are checked, but throttles, budgets,
+// price checks and self-trade prevention are not, and handlers that keep state
+// update it as usual. A live injection goes through the full pipeline, as if
+// the event had arrived on a stream, and its orders are real.
+
+// ErrNotStarted is returned when an event is injected before Start
+var ErrNotStarted = errors.New("engine not started")
+
+// InjectRequest is an event to inject
+type InjectRequest struct {
+	Event    types.Event `json:"event"`
+	DryRun   bool        `json:"dry_run"`
+	Operator string      `json:"operator"`
+	Reason   string      `json:"reason"`
+}
+
+// InjectResult reports what the strategies did with an injected event
+type InjectResult struct {
+	EventID    string             `json:"event_id"`
+	DryRun     bool               `json:"dry_run"`
+	Strategies []InjectedStrategy `json:"strategies"`
+}
+
+// InjectedStrategy is the reaction of one strategy. Live injections only list
+// the strategies the event was handed to; their commands go through the
+// pipeline in the background.
+type InjectedStrategy struct {
+	Strategy string          `json:"strategy"`
+	Commands []types.Command `json:"commands,omitempty"`
+	Skipped  string          `json:"skipped,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// InjectEvent runs a crafted event through the engine. The event gets an ID
+// and a timestamp if it has none, and "injected": true in its data.
+func (e *Engine) InjectEvent(ctx context.Context, req InjectRequest) (*InjectResult, error) {
+	if req.Event.Type == "" {
+		return nil, fmt.Errorf("event type is required")
+	}
+
+	e.mu.RLock()
+	runCtx := e.runCtx
+	e.mu.RUnlock()
+	if runCtx == nil {
+		return nil, ErrNotStarted
+	}
+
+	event := req.Event
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	if event.ID == "" {
+		event.ID = fmt.Sprintf("injected-%d", event.Timestamp.UnixNano())
+	}
+	data := make(map[string]interface{}, len(event.Data)+1)
+	for k, v := range event.Data {
+		data[k] = v
+	}
+	data["injected"] = true
+	event.Data = data
+
+	log.Warn().
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Str("event_id", event.ID).
+		Str("type", event.Type).
+		Bool("dry_run", req.DryRun).
+		Msg("Injecting event")
+
+	result := &InjectResult{EventID: event.ID, DryRun: req.DryRun, Strategies: []InjectedStrategy{}}
+	receivers := e.receivers(event)
+
+	if !req.DryRun {
+		// Workers outlive the request, so the event runs on the engine's context
+		if err := e.handleEvent(runCtx, event); err != nil {
+			return nil, err
+		}
+		if halted, _ := e.Halted(); !halted {
+			for _, strategy := range receivers {
+				result.Strategies = append(result.Strategies, InjectedStrategy{Strategy: strategy.Name})
+			}
+		}
+		return result, nil
+	}
+
+	for _, strategy := range receivers {
+		result.Strategies = append(result.Strategies, e.previewStrategy(ctx, strategy, event))
+	}
+	return result, nil
+}
+
+// receivers returns the strategies handleEvent hands an event to, by
+// descending priority and then by name
+func (e *Engine) receivers(event types.Event) []types.Strategy {
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	tenant := e.eventTenant(event)
+	var receivers []types.Strategy
+	for _, strategy := range strategies {
+		if !strategy.Active || (tenant != "" && strategyTenant(strategy) != tenant) {
+			continue
+		}
+		receivers = append(receivers, strategy)
+	}
+	receivers = e.splitVariants(event, receivers)
+	sort.SliceStable(receivers, func(i, j int) bool {
+		if receivers[i].Priority != receivers[j].Priority {
+			return receivers[i].Priority > receivers[j].Priority
+		}
+		return receivers[i].Name < receivers[j].Name
+	})
+	return receivers
+}
+
+// previewStrategy calls a strategy's handler for an event and returns its
+// commands without executing them
+func (e *Engine) previewStrategy(ctx context.Context, strategy types.Strategy, event types.Event) InjectedStrategy {
+	preview := InjectedStrategy{Strategy: strategy.Name}
+
+	e.mu.RLock()
+	schedule := e.schedules[strategy.ID]
+	handler, exists := e.handlers[strategy.Type]
+	switch event.Type {
+	case EventTick:
+		handler, exists = e.tickHandlers[strategy.Type]
+	case EventOrderRejected:
+		handler, exists = e.rejectionHandlers[strategy.Type]
+	}
+	e.mu.RUnlock()
+
+	switch {
+	case schedule != nil && !schedule.IsOpen(event.Timestamp):
+		preview.Skipped = "outside trading window"
+		return preview
+	case !exists:
+		preview.Skipped = "no handler for strategy type " + strategy.Type
+		return preview
+	}
+
+	commands, err := e.callHandler(ctx, handler, strategy, event)
+	if err != nil {
+		preview.Error = err.Error()
+		return preview
+	}
+	applyExecutionDefaults(strategy, commands)
+	e.applyOrderTTL(strategy, commands)
+	markDryRun(strategy, event, commands)
+	preview.Commands = commands
+	return preview
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orders"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrHalted is returned for operator actions refused under the kill switch
+var ErrHalted = errors.New("trading is halted")
+
+// Halt engages the kill switch: events are still consumed and bookkeeping
+// (fills, open orders, PnL) continues, but no strategy runs, no trading
+// window closes and no order is placed until Resume is called. Running
+// execution algos are stopped; their resting child orders stay open. Cancels
+// the engine sends for its own orders (expiry, one-cancels-other, resolved
+// markets) and operator flattens still go out.
+func (e *Engine) Halt(reason string) {
+	e.mu.Lock()
+	e.halted = true
+	e.haltReason = reason
+	e.haltedAt = time.Now().UTC()
+	e.mu.Unlock()
+
+	stopped := e.executor.CancelAlgos()
+	log.Warn().Str("reason", reason).Int("algos_stopped", stopped).Msg("Kill switch engaged, trading halted")
+}
+
+// Resume releases the kill switch
+func (e *Engine) Resume() {
+	e.mu.Lock()
+	e.halted = false
+	e.haltReason = ""
+	e.haltedAt = time.Time{}
+	e.mu.Unlock()
+
+	log.Warn().Msg("Kill switch released, trading resumed")
+}
+
+// Halted reports whether the kill switch is engaged and why
+func (e *Engine) Halted() (bool, string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.halted, e.haltReason
+}
+
+// StateSnapshot is a point-in-time view of the engine's in-memory state
+type StateSnapshot struct {
+	TakenAt    time.Time        `json:"taken_at"`
+	Halted     bool             `json:"halted"`
+	HaltReason string           `json:"halt_reason,omitempty"`
+	HaltedAt   *time.Time       `json:"halted_at,omitempty"`
+	Strategies []types.Strategy `json:"strategies"`
+	Handlers   []string         `json:"handlers"`
+	OpenOrders []orders.Order   `json:"open_orders"`
+	Streams    []string         `json:"streams"`
+}
+
+// Snapshot captures the engine's current state
+func (e *Engine) Snapshot() StateSnapshot {
+	e.mu.RLock()
+	snapshot := StateSnapshot{
+		TakenAt:    time.Now().UTC(),
+		Halted:     e.halted,
+		HaltReason: e.haltReason,
+		Strategies: e.strategies,
+		Streams:    e.streams,
+	}
+	if e.halted {
+		haltedAt := e.haltedAt
+		snapshot.HaltedAt = &haltedAt
+	}
+	for name := range e.handlers {
+		snapshot.Handlers = append(snapshot.Handlers, name)
+	}
+	e.mu.RUnlock()
+
+	snapshot.OpenOrders = e.orders.Open()
+	return snapshot
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// The lag monitor measures how far the engine is behind: per event stream,
+// the gap between the newest entry and the last one delivered to the engine,
+// and per strategy, the events waiting in its worker queue. Every
+// lagCheckInterval it takes a LagReport, served by GET /admin/lag, and raises
+// an alert (an engine_lag event on ErrorStream plus a stored alert) when a
+// stream lags more than Options.MaxStreamLag or a queue holds more than
+// Options.MaxQueueBacklog events. Alerts for the same stream or strategy are
+// repeated at most every lagAlertCooldown.
+
+const (
+	lagCheckInterval = 15 * time.Second
+	lagAlertCooldown = 10 * time.Minute
+)
+
+// LagReport is a snapshot of the engine's stream lag and queue backlog
+type LagReport struct {
+	Streams   []eventbus.StreamLag `json:"streams"`
+	Queues    map[string]int       `json:"queues"` // strategy name -> queued events
+	CheckedAt time.Time            `json:"checked_at"`
+}
+
+type lagMonitor struct {
+	mu      sync.Mutex
+	report  LagReport
+	alerted map[string]time.Time // alert key -> last alert
+}
+
+func newLagMonitor() *lagMonitor {
+	return &lagMonitor{alerted: make(map[string]time.Time)}
+}
+
+// shouldAlert reports whether an alert for key is due and records it
+func (m *lagMonitor) shouldAlert(key string, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if last, ok := m.alerted[key]; ok && now.Sub(last) < lagAlertCooldown {
+		return false
+	}
+	m.alerted[key] = now
+	return true
+}
+
+// runLagMonitor checks lag and backlog until ctx is cancelled
+func (e *Engine) runLagMonitor(ctx context.Context) {
+	ticker := time.NewTicker(lagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.checkLag(ctx)
+		}
+	}
+}
+
+func (e *Engine) checkLag(ctx context.Context) {
+	report := e.measureLag(ctx)
+
+	e.lag.mu.Lock()
+	e.lag.report = report
+	e.lag.mu.Unlock()
+
+	e.mu.RLock()
+	maxLag := e.opts.MaxStreamLag
+	maxBacklog := e.opts.MaxQueueBacklog
+	e.mu.RUnlock()
+
+	if maxLag > 0 {
+		for _, stream := range report.Streams {
+			if stream.LagSeconds <= maxLag.Seconds() {
+				continue
+			}
+			e.alertLag(ctx, "stream:"+stream.Stream,
+				fmt.Sprintf("Event stream %s lagging", stream.Stream),
+				fmt.Sprintf("The engine is %.0fs (%d events) behind stream %s (limit %s)",
+					stream.LagSeconds, stream.Behind, stream.Stream, maxLag),
+				map[string]interface{}{
+					"stream":       stream.Stream,
+					"lag_seconds":  stream.LagSeconds,
+					"behind":       stream.Behind,
+					"last_id":      stream.LastID,
+					"delivered_id": stream.DeliveredID,
+					"max_lag":      maxLag.Seconds(),
+				})
+		}
+	}
+
+	if maxBacklog > 0 {
+		for strategy, queued := range report.Queues {
+			if queued <= maxBacklog {
+				continue
+			}
+			e.alertLag(ctx, "queue:"+strategy,
+				fmt.Sprintf("Strategy %s backlogged", strategy),
+				fmt.Sprintf("Strategy %s has %d events queued (limit %d)", strategy, queued, maxBacklog),
+				map[string]interface{}{
+					"strategy":    strategy,
+					"queued":      queued,
+					"max_backlog": maxBacklog,
+				})
+		}
+	}
+}
+
+// measureLag reads the lag of every consumed stream and the length of every
+// strategy queue
+func (e *Engine) measureLag(ctx context.Context) LagReport {
+	report := LagReport{
+		Queues:    make(map[string]int),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	for _, stream := range e.streams {
+		lag, err := e.eventBus.Lag(ctx, stream)
+		if err != nil {
+			log.Warn().Err(err).Str("stream", stream).Msg("Failed to measure stream lag")
+			continue
+		}
+		report.Streams = append(report.Streams, lag)
+	}
+	sort.Slice(report.Streams, func(i, j int) bool {
+		return report.Streams[i].Stream < report.Streams[j].Stream
+	})
+
+	e.workersMu.Lock()
+	for _, w := range e.workers {
+		report.Queues[w.strategy] = len(w.queue)
+	}
+	e.workersMu.Unlock()
+
+	return report
+}
+
+func (e *Engine) alertLag(ctx context.Context, key, title, message string, data map[string]interface{}) {
+	now := time.Now().UTC()
+	if !e.lag.shouldAlert(key, now) {
+		return
+	}
+
+	log.Warn().Str("key", key).Msg(message)
+
+	lagEvent := types.Event{
+		ID:        fmt.Sprintf("engine_lag:%s:%d", key, now.UnixNano()),
+		Type:      "engine_lag",
+		Timestamp: now,
+		Data:      data,
+	}
+	if err := e.eventBus.Publish(ctx, ErrorStream, lagEvent); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to publish engine lag event")
+	}
+
+	if err := e.storage.CreateAlert(ctx, "strategy", title, message, data); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to create engine lag alert")
+	}
+}
+
+// Lag returns the latest lag report, measuring it if none was taken yet
+func (e *Engine) Lag(ctx context.Context) LagReport {
+	e.lag.mu.Lock()
+	report := e.lag.report
+	e.lag.mu.Unlock()
+
+	if report.CheckedAt.IsZero() {
+		report = e.measureLag(ctx)
+	}
+	return report
+}
+
+// SetLagThresholds changes the lag alert thresholds at runtime; zero disables
+// the respective alert
+func (e *Engine) SetLagThresholds(maxStreamLag time.Duration, maxQueueBacklog int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.opts.MaxStreamLag = maxStreamLag
+	e.opts.MaxQueueBacklog = maxQueueBacklog
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Market mappings pair each Predict market with the Polymarket condition that
+// asks the same question, for strategies that trade or hedge across the two.
+// They are kept in the market_mappings table, edited through the admin API
+// and handed to strategies as strategyctx.Context.Mappings. Instances pick up
+// changes made through another instance on the next strategy refresh.
+
+// ErrInvalidMapping is returned for mappings missing a market
+var ErrInvalidMapping = errors.New("mapping needs a Predict market and a Polymarket condition")
+
+// loadMappings replaces the mappings with the stored ones
+func (e *Engine) loadMappings(ctx context.Context) error {
+	mappings, err := e.storage.GetMarketMappings(ctx)
+	if err != nil {
+		return err
+	}
+	e.mappings.Set(mappings)
+	return nil
+}
+
+// MarketMappings returns every market mapping
+func (e *Engine) MarketMappings() []types.MarketMapping {
+	return e.mappings.All()
+}
+
+// CreateMarketMapping stores a new mapping and makes it visible to strategies
+func (e *Engine) CreateMarketMapping(ctx context.Context, mapping types.MarketMapping) (types.MarketMapping, error) {
+	if err := validateMapping(&mapping); err != nil {
+		return mapping, err
+	}
+	created, err := e.storage.CreateMarketMapping(ctx, mapping)
+	if err != nil {
+		return created, err
+	}
+	e.mappings.Put(created)
+
+	log.Info().
+		Int64("id", created.ID).
+		Str("predict_market", created.PredictMarketID).
+		Str("polymarket_condition", created.PolymarketConditionID).
+		Bool("inverted", created.Inverted).
+		Str("operator", created.CreatedBy).
+		Msg("Market mapping created")
+	return created, nil
+}
+
+// UpdateMarketMapping changes the markets, direction or note of a mapping
+func (e *Engine) UpdateMarketMapping(ctx context.Context, mapping types.MarketMapping) (types.MarketMapping, error) {
+	if err := validateMapping(&mapping); err != nil {
+		return mapping, err
+	}
+	updated, err := e.storage.UpdateMarketMapping(ctx, mapping)
+	if err != nil {
+		return updated, err
+	}
+	e.mappings.Put(updated)
+
+	log.Info().
+		Int64("id", updated.ID).
+		Str("predict_market", updated.PredictMarketID).
+		Str("polymarket_condition", updated.PolymarketConditionID).
+		Bool("inverted", updated.Inverted).
+		Msg("Market mapping updated")
+	return updated, nil
+}
+
+// DeleteMarketMapping removes a mapping
+func (e *Engine) DeleteMarketMapping(ctx context.Context, id int64) error {
+	if err := e.storage.DeleteMarketMapping(ctx, id); err != nil {
+		return err
+	}
+	e.mappings.Remove(id)
+
+	log.Info().Int64("id", id).Msg("Market mapping deleted")
+	return nil
+}
+
+func validateMapping(mapping *types.MarketMapping) error {
+	mapping.PredictMarketID = strings.TrimSpace(mapping.PredictMarketID)
+	mapping.PolymarketConditionID = strings.TrimSpace(mapping.PolymarketConditionID)
+	if mapping.PredictMarketID == "" || mapping.PolymarketConditionID == "" {
+		return ErrInvalidMapping
+	}
+	return nil
+}
+
+// SuggestMarketMappings proposes pairs among the unmapped, unresolved markets
+// whose questions were seen in market_update events (see markets.Suggest)
+func (e *Engine) SuggestMarketMappings(minScore float64, limit int) []markets.Suggestion {
+	unmapped := func(platform string, mapped func(string) (types.MarketMapping, bool)) []markets.Title {
+		var titles []markets.Title
+		for _, t := range e.markets.Titles(platform) {
+			if _, ok := mapped(t.MarketID); ok {
+				continue
+			}
+			if _, resolved := e.markets.Resolved(t.MarketID); resolved {
+				continue
+			}
+			titles = append(titles, t)
+		}
+		return titles
+	}
+
+	return markets.Suggest(
+		unmapped("predict", e.mappings.Polymarket),
+		unmapped("polymarket", e.mappings.Predict),
+		minScore,
+		limit,
+	)
+}
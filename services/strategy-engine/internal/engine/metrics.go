@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/metrics"
+)
+
+// WriteMetrics writes the engine's counters and the strategies' custom
+// metrics in the Prometheus text format. The engine's own metrics are named
+// strategy_engine_*, custom ones strategy_custom_* (see strategyctx.Strategy.Count).
+func (e *Engine) WriteMetrics(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+
+	e.events.mu.Lock()
+	events := make([]metrics.Sample, 0, len(e.events.events))
+	for eventType, counts := range e.events.events {
+		events = append(events, metrics.Sample{Labels: []string{"type", eventType}, Value: float64(counts.Count)})
+	}
+	duplicates := float64(e.events.duplicates)
+	e.events.mu.Unlock()
+
+	var handled, commands, handlerErrors, timeouts []metrics.Sample
+	for name, counts := range e.counters.snapshot() {
+		labels := []string{"strategy", name}
+		handled = append(handled, metrics.Sample{Labels: labels, Value: float64(counts.Events)})
+		commands = append(commands, metrics.Sample{Labels: labels, Value: float64(counts.Commands)})
+		handlerErrors = append(handlerErrors, metrics.Sample{Labels: labels, Value: float64(counts.HandlerErrors)})
+		timeouts = append(timeouts, metrics.Sample{Labels: labels, Value: float64(counts.Timeouts)})
+	}
+
+	var orders []metrics.Sample
+	for status, n := range e.executor.OrderCounts() {
+		orders = append(orders, metrics.Sample{Labels: []string{"status", status}, Value: float64(n)})
+	}
+
+	var unchecked []metrics.Sample
+	for name, n := range e.unchecked.snapshot() {
+		unchecked = append(unchecked, metrics.Sample{Labels: []string{"strategy", name}, Value: float64(n)})
+	}
+
+	halted, _ := e.Halted()
+	connected := e.EventBusHealth().Connected
+
+	families := []struct {
+		name, kind string
+		samples    []metrics.Sample
+	}{
+		{"strategy_engine_events_total", metrics.Counter, events},
+		{"strategy_engine_duplicate_events_total", metrics.Counter, []metrics.Sample{{Value: duplicates}}},
+		{"strategy_engine_handled_events_total", metrics.Counter, handled},
+		{"strategy_engine_commands_total", metrics.Counter, commands},
+		{"strategy_engine_handler_errors_total", metrics.Counter, handlerErrors},
+		{"strategy_engine_handler_timeouts_total", metrics.Counter, timeouts},
+		{"strategy_engine_orders_total", metrics.Counter, orders},
+		{"strategy_engine_price_unchecked_orders_total", metrics.Counter, unchecked},
+		{"strategy_engine_halted", metrics.Gauge, []metrics.Sample{{Value: boolValue(halted)}}},
+		{"strategy_engine_event_bus_connected", metrics.Gauge, []metrics.Sample{{Value: boolValue(connected)}}},
+	}
+	for _, f := range families {
+		if err := metrics.WriteFamily(buf, f.name, f.kind, f.samples); err != nil {
+			return err
+		}
+	}
+
+	if err := e.metrics.WritePrometheus(buf); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
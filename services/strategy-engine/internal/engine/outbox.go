@@ -0,0 +1,312 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// With the outbox enabled, handler commands are persisted to command_outbox
+// before anything is sent, and a dispatcher delivers them with retries. A
+// crash between handler and HTTP call therefore no longer loses commands.
+//
+// Each order carries the client order ID "outbox-<entry id>", stored with the
+// entry and the same on every attempt; a command retried from the dead letter
+// queue is a new entry with a new key. A failure is only retried when sending
+// again cannot place a second order: the request provably never reached the
+// account service, the command only cancels, or the platform deduplicates
+// orders on their client order ID (idempotent_orders). Otherwise the entry is
+// marked unknown and an alert raised, to be reconciled by hand; the same goes
+// for commands whose claim went stale because the engine crashed while sending
+// them.
+
+const (
+	outboxPollInterval = 500 * time.Millisecond
+	outboxBatchSize    = 50
+	outboxMaxAttempts  = 5
+	outboxStaleAfter   = 5 * time.Minute
+)
+
+// enqueueCommands persists commands for the dispatcher. If the outbox cannot
+// be written the commands are executed directly rather than dropped.
+func (e *Engine) enqueueCommands(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) {
+	if err := e.storage.EnqueueCommands(ctx, strategy, event, commands); err != nil {
+		log.Error().
+			Err(err).
+			Str("strategy", strategy.Name).
+			Msg("Failed to write commands to outbox, executing directly")
+
+		if err := e.executor.ExecuteCommands(ctx, commands); err != nil {
+			e.publishExecutionErrors(ctx, strategy, event, err)
+		}
+		return
+	}
+
+	// Wake the dispatcher instead of waiting for the next poll
+	select {
+	case e.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// runOutbox delivers pending outbox commands until ctx is cancelled
+func (e *Engine) runOutbox(ctx context.Context) {
+	e.recoverStaleOutbox(ctx)
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.outboxWake:
+		}
+
+		// Pending commands wait while the kill switch is engaged
+		if halted, _ := e.Halted(); halted {
+			continue
+		}
+
+		e.dispatchOutbox(ctx)
+	}
+}
+
+// recoverStaleOutbox handles the commands a previous run claimed and never
+// finished: those safe to send again are requeued, the others marked unknown
+func (e *Engine) recoverStaleOutbox(ctx context.Context) {
+	entries, err := e.storage.ClaimStaleOutbox(ctx, outboxStaleAfter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to claim stale outbox commands")
+		return
+	}
+
+	var requeued int
+	for _, entry := range entries {
+		entry = withOwnClientOrderID(entry)
+		if !e.resendable(entry.Command) {
+			e.markOutcomeUnknown(ctx, entry, errors.New("claim went stale: the engine stopped while sending the command"))
+			continue
+		}
+		if err := e.storage.RetryOutbox(ctx, entry.ID, "claim went stale", time.Now().UTC()); err != nil {
+			log.Error().Err(err).Int64("outbox_id", entry.ID).Msg("Failed to requeue stale outbox command")
+			continue
+		}
+		requeued++
+	}
+	if requeued > 0 {
+		log.Warn().Int("commands", requeued).Msg("Requeued outbox commands left in flight by a previous run")
+	}
+}
+
+// withOwnClientOrderID gives the entry's command the entry's idempotency key,
+// replacing any key it carried over from the command it was retried from
+func withOwnClientOrderID(entry types.OutboxEntry) types.OutboxEntry {
+	entry.Command.ClientOrderID = entry.ClientOrderID
+	return entry
+}
+
+// resendable reports whether an outbox command that may have been executed
+// can be sent again without doubling it
+func (e *Engine) resendable(cmd types.Command) bool {
+	switch cmd.Type {
+	case "cancel_order", "cancel_all_orders":
+		return true
+	case "place_order", "amend_order":
+		return e.executor.IdempotentOrders(cmd)
+	default:
+		return false
+	}
+}
+
+func (e *Engine) dispatchOutbox(ctx context.Context) {
+	entries, err := e.storage.ClaimOutbox(ctx, outboxBatchSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to claim outbox commands")
+		return
+	}
+
+	for _, entry := range entries {
+		e.deliver(ctx, entry)
+	}
+}
+
+// deliver executes one outbox command. Rejections and expired commands are
+// final, and failures that may have executed the command are marked unknown
+// unless it is resendable; other failures are retried with exponential
+// backoff up to outboxMaxAttempts.
+func (e *Engine) deliver(ctx context.Context, entry types.OutboxEntry) {
+	entry = withOwnClientOrderID(entry)
+
+	err := e.executor.ExecuteCommands(ctx, []types.Command{entry.Command})
+	if err == nil {
+		if err := e.storage.CompleteOutbox(ctx, entry.ID); err != nil {
+			log.Error().Err(err).Int64("outbox_id", entry.ID).Msg("Failed to mark outbox command done")
+		}
+		return
+	}
+
+	cause := err
+	if cmdErrs := executor.CommandErrors(err); len(cmdErrs) > 0 {
+		cause = cmdErrs[0].Err
+	}
+	class := classifyExecutionError(cause)
+
+	unknown := errors.Is(cause, executor.ErrOutcomeUnknown)
+	if unknown && !e.resendable(entry.Command) {
+		e.markOutcomeUnknown(ctx, entry, cause)
+		return
+	}
+
+	if class != ErrorClassRejected && class != ErrorClassExpired && entry.Attempts < outboxMaxAttempts {
+		backoff := time.Second << (entry.Attempts - 1)
+		log.Warn().
+			Err(cause).
+			Int64("outbox_id", entry.ID).
+			Int("attempt", entry.Attempts).
+			Dur("retry_in", backoff).
+			Msg("Outbox command failed, will retry")
+
+		if err := e.storage.RetryOutbox(ctx, entry.ID, cause.Error(), time.Now().UTC().Add(backoff)); err != nil {
+			log.Error().Err(err).Int64("outbox_id", entry.ID).Msg("Failed to reschedule outbox command")
+		}
+		return
+	}
+
+	// Out of attempts with the last one possibly executed
+	if unknown {
+		e.markOutcomeUnknown(ctx, entry, cause)
+		return
+	}
+
+	log.Error().
+		Err(cause).
+		Int64("outbox_id", entry.ID).
+		Str("strategy", entry.Strategy).
+		Int("attempts", entry.Attempts).
+		Msg("Outbox command failed permanently")
+
+	if err := e.storage.FailOutbox(ctx, entry.ID, cause.Error()); err != nil {
+		log.Error().Err(err).Int64("outbox_id", entry.ID).Msg("Failed to mark outbox command failed")
+	}
+
+	strategy := types.Strategy{ID: entry.StrategyID, Name: entry.Strategy}
+	event := types.Event{ID: entry.EventID, Type: entry.EventType, Platform: entry.Command.Platform}
+	e.publishError(ctx, strategy, event, class, cause, &entry.Command)
+}
+
+// markOutcomeUnknown gives up on a command that may or may not have been
+// executed, and raises an alert to reconcile it with the venue
+func (e *Engine) markOutcomeUnknown(ctx context.Context, entry types.OutboxEntry, cause error) {
+	cmd := entry.Command
+	log.Error().
+		Err(cause).
+		Int64("outbox_id", entry.ID).
+		Str("strategy", entry.Strategy).
+		Str("type", cmd.Type).
+		Str("platform", cmd.Platform).
+		Str("account", cmd.AccountID).
+		Msg("Outbox command outcome unknown, needs reconciliation")
+
+	if err := e.storage.MarkOutboxUnknown(ctx, entry.ID, cause.Error()); err != nil {
+		log.Error().Err(err).Int64("outbox_id", entry.ID).Msg("Failed to mark outbox command unknown")
+	}
+
+	data := map[string]interface{}{
+		"outbox_id":       entry.ID,
+		"strategy":        entry.Strategy,
+		"type":            cmd.Type,
+		"platform":        cmd.Platform,
+		"account_id":      cmd.AccountID,
+		"market_id":       cmd.MarketID,
+		"side":            cmd.Side,
+		"price":           cmd.Price,
+		"shares":          cmd.Shares,
+		"client_order_id": cmd.ClientOrderID,
+		"error":           cause.Error(),
+	}
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		"Command outcome unknown",
+		fmt.Sprintf("Outbox command %d (%s on %s/%s, strategy %s) may or may not have been executed and was not sent again; "+
+			"check the account's orders and positions, then retry it with dlq retry %d or leave it; last error: %s",
+			entry.ID, cmd.Type, cmd.Platform, cmd.AccountID, entry.Strategy, entry.ID, cause),
+		data,
+	); err != nil {
+		log.Error().Err(err).Int64("outbox_id", entry.ID).Msg("Failed to create command outcome unknown alert")
+	}
+
+	strategy := types.Strategy{ID: entry.StrategyID, Name: entry.Strategy}
+	event := types.Event{ID: entry.EventID, Type: entry.EventType, Platform: cmd.Platform}
+	e.publishError(ctx, strategy, event, ErrorClassUnknown, cause, &cmd)
+}
+
+// FailedCommands returns up to limit outbox commands that were given up on,
+// failed or of unknown outcome, newest first
+func (e *Engine) FailedCommands(ctx context.Context, limit int) ([]types.OutboxEntry, error) {
+	return e.storage.GetFailedOutbox(ctx, limit)
+}
+
+// ErrRetryUnscoped is returned when a retry names neither command IDs nor a
+// start time
+var ErrRetryUnscoped = errors.New("choose the commands to retry by ID or with since")
+
+// RetryFailedCommands sends given-up outbox commands through the pipeline
+// again, chosen by ID or, for failed ones, by creation time, so they pass the
+// current checks and limits and are queued as new outbox commands. Commands of
+// strategies no longer enabled are skipped. It reports how many were retried.
+func (e *Engine) RetryFailedCommands(ctx context.Context, ids []int64, since time.Time) (int64, error) {
+	if len(ids) == 0 && since.IsZero() {
+		return 0, ErrRetryUnscoped
+	}
+	if halted, reason := e.Halted(); halted {
+		return 0, fmt.Errorf("%w: %s", ErrHalted, reason)
+	}
+
+	entries, err := e.storage.GetRetryableOutbox(ctx, ids, since)
+	if err != nil {
+		return 0, err
+	}
+
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+	byName := make(map[string]types.Strategy, len(strategies))
+	for _, strategy := range strategies {
+		if strategy.CandidateOf == "" {
+			byName[strategy.Name] = strategy
+		}
+	}
+
+	var retried int64
+	for _, entry := range entries {
+		strategy, ok := byName[entry.Strategy]
+		if !ok {
+			log.Warn().
+				Int64("outbox_id", entry.ID).
+				Str("strategy", entry.Strategy).
+				Msg("Failed command not retried, its strategy is not enabled")
+			continue
+		}
+
+		marked, err := e.storage.MarkOutboxRetried(ctx, entry.ID)
+		if err != nil {
+			return retried, err
+		}
+		if !marked {
+			continue
+		}
+
+		event := types.Event{ID: entry.EventID, Type: entry.EventType, Platform: entry.Command.Platform, Timestamp: time.Now().UTC()}
+		e.runPipeline(ctx, strategy, event, []types.Command{entry.Command})
+		retried++
+	}
+	return retried, nil
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+func TestOutboxClientOrderID(t *testing.T) {
+	exec, err := executor.NewExecutor(map[string]executor.Platform{
+		"predict":    {URL: "http://predict-account", IdempotentOrders: true},
+		"polymarket": {URL: "http://polymarket-account"},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{executor: exec}
+
+	tests := []struct {
+		name       string
+		entry      types.OutboxEntry
+		want       string
+		resendable bool
+	}{
+		{
+			name:       "key stored with the entry",
+			entry:      types.OutboxEntry{ID: 7, ClientOrderID: "outbox-7", Command: types.Command{Type: "place_order", Platform: "predict"}},
+			want:       "outbox-7",
+			resendable: true,
+		},
+		{
+			name:       "key inherited from a retried command",
+			entry:      types.OutboxEntry{ID: 9, ClientOrderID: "outbox-9", Command: types.Command{Type: "place_order", Platform: "predict", ClientOrderID: "outbox-7"}},
+			want:       "outbox-9",
+			resendable: true,
+		},
+		{
+			name:  "platform without idempotent orders",
+			entry: types.OutboxEntry{ID: 4, ClientOrderID: "outbox-4", Command: types.Command{Type: "place_order", Platform: "polymarket"}},
+			want:  "outbox-4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := withOwnClientOrderID(tt.entry)
+			if entry.Command.ClientOrderID != tt.want {
+				t.Errorf("client order ID %q, want %q", entry.Command.ClientOrderID, tt.want)
+			}
+			if got := e.resendable(entry.Command); got != tt.resendable {
+				t.Errorf("resendable %v, want %v", got, tt.resendable)
+			}
+		})
+	}
+}
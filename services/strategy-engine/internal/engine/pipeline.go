@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// The commands of a strategy go from its handler to the executor through a
+// chain of middlewares:
+//
+//	fan_out → warmup → tenant → conditional → defaults → sizes → accounts →
+//	resolution → volatility → price_check → self_trade → throttle →
+//	group_budget → custom middlewares → audit → execute
+//
+// The built-in stages drop or adjust commands and end the chain once none are
+// left. conditional holds orders with a trigger price until it fires, then
+// sends them through the stages after it (see conditional.go). Custom middlewares are registered with RegisterMiddleware and run after
+// the engine's own checks, in registration order or the order of
+// Options.Middlewares, each skipped where its feature flag is off (see
+// flags.go). audit marks dry-run commands and records the decision;
+// execute runs the commands, or records or queues them in shadow, compare and
+// outbox mode.
+
+// CommandBatch is what one strategy decided on one event, on its way through
+// the pipeline. Middlewares may change, drop or add commands.
+type CommandBatch struct {
+	Strategy types.Strategy
+	Event    types.Event
+	Commands []types.Command
+}
+
+// CommandHandler passes a batch on to the rest of the pipeline
+type CommandHandler func(ctx context.Context, batch *CommandBatch) error
+
+// Middleware wraps the rest of the pipeline. A check before execution changes
+// batch.Commands and calls next, or returns an error to drop the batch; a hook
+// after execution calls next first and sees the execution error.
+type Middleware func(next CommandHandler) CommandHandler
+
+type namedMiddleware struct {
+	name       string
+	middleware Middleware
+}
+
+// executionError is a failure of the executor, already logged and published
+// as strategy_error events when it leaves the pipeline
+type executionError struct {
+	err error
+}
+
+func (e *executionError) Error() string { return e.err.Error() }
+func (e *executionError) Unwrap() error { return e.err }
+
+// RegisterMiddleware adds a custom middleware to the command pipeline, or
+// replaces the one registered under the same name
+func (e *Engine) RegisterMiddleware(name string, middleware Middleware) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	registered := false
+	for i, m := range e.middlewares {
+		if m.name == name {
+			e.middlewares[i].middleware = middleware
+			registered = true
+		}
+	}
+	if !registered {
+		e.middlewares = append(e.middlewares, namedMiddleware{name: name, middleware: middleware})
+	}
+	e.pipeline = e.buildPipeline()
+
+	log.Info().Str("middleware", name).Msg("Registered command middleware")
+}
+
+// Pipeline returns the names of the pipeline's middlewares, in order
+func (e *Engine) Pipeline() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var names []string
+	for _, m := range e.pipelineStages() {
+		names = append(names, m.name)
+	}
+	return append(names, "execute")
+}
+
+// checkMiddlewares reports configured middlewares that were never registered
+func (e *Engine) checkMiddlewares() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, name := range e.opts.Middlewares {
+		if _, ok := e.customMiddleware(name); !ok {
+			return fmt.Errorf("command middleware %q is not registered", name)
+		}
+	}
+	return nil
+}
+
+func (e *Engine) customMiddleware(name string) (namedMiddleware, bool) {
+	for _, m := range e.middlewares {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return namedMiddleware{}, false
+}
+
+// pipelineStages lists the middlewares in the order a batch goes through
+// them. Callers hold e.mu.
+func (e *Engine) pipelineStages() []namedMiddleware {
+	stages := []namedMiddleware{
+		{"fan_out", e.stage(e.expandFanOuts)},
+		{"warmup", e.stage(func(_ context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+			return e.dropWarmupOrders(strategy, event, commands)
+		})},
+		{"tenant", e.stage(e.applyTenant)},
+		{"conditional", e.stage(e.holdConditionalCommands)},
+		{"defaults", func(next CommandHandler) CommandHandler {
+			return func(ctx context.Context, b *CommandBatch) error {
+				tagVariant(b.Strategy, b.Commands)
+				applyExecutionDefaults(b.Strategy, b.Commands)
+				e.applyOrderTTL(b.Strategy, b.Commands)
+				return next(ctx, b)
+			}
+		}},
+		{"sizes", e.stage(e.convertSizes)},
+		{"accounts", e.stage(e.checkAccounts)},
+		{"resolution", e.stage(e.dropResolvedMarkets)},
+		{"volatility", e.stage(e.dropVolatileMarkets)},
+		{"price_check", e.stage(e.checkPrices)},
+		{"self_trade", e.stage(e.preventSelfTrades)},
+		{"throttle", e.stage(e.applyThrottle)},
+		{"group_budget", e.stage(e.applyGroupBudget)},
+	}
+
+	if e.opts.Middlewares != nil {
+		for _, name := range e.opts.Middlewares {
+			if m, ok := e.customMiddleware(name); ok {
+				stages = append(stages, e.flagged(m))
+			}
+		}
+	} else {
+		for _, m := range e.middlewares {
+			stages = append(stages, e.flagged(m))
+		}
+	}
+
+	return append(stages, namedMiddleware{"audit", func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, b *CommandBatch) error {
+			if len(b.Commands) == 0 {
+				return nil
+			}
+			markDryRun(b.Strategy, b.Event, b.Commands)
+			e.recordDecision(b.Strategy, b.Event, b.Commands, "", nil)
+			return next(ctx, b)
+		}
+	}})
+}
+
+// buildPipeline chains the middlewares around execute. Callers hold e.mu.
+func (e *Engine) buildPipeline() CommandHandler {
+	stages := e.pipelineStages()
+	handler := e.execute
+	for i := len(stages) - 1; i >= 0; i-- {
+		handler = stages[i].middleware(handler)
+	}
+	return handler
+}
+
+// stage adapts a built-in check that drops or adjusts commands, ending the
+// chain once none are left
+func (e *Engine) stage(check func(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command) Middleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, b *CommandBatch) error {
+			b.Commands = check(ctx, b.Strategy, b.Event, b.Commands)
+			if len(b.Commands) == 0 {
+				return nil
+			}
+			return next(ctx, b)
+		}
+	}
+}
+
+// runPipeline takes the commands of a strategy through the pipeline. Failures
+// of custom middlewares are published as strategy_error events.
+func (e *Engine) runPipeline(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) {
+	e.mu.RLock()
+	pipeline := e.pipeline
+	e.mu.RUnlock()
+
+	err := pipeline(ctx, &CommandBatch{Strategy: strategy, Event: event, Commands: commands})
+	var execution *executionError
+	if errors.As(err, &execution) {
+		e.explainf(strategy, event, "execution failed: %v", execution.err)
+		return
+	}
+	if err == nil {
+		return
+	}
+
+	log.Warn().
+		Err(err).
+		Str("strategy", strategy.Name).
+		Str("event_id", event.ID).
+		Msg("Commands dropped by middleware")
+	e.publishStrategyError(ctx, strategy, event, ErrorClassMiddleware, err, nil)
+}
+
+// execute is the end of the pipeline
+func (e *Engine) execute(ctx context.Context, b *CommandBatch) error {
+	strategy, commands := b.Strategy, b.Commands
+	if e.opts.Compare {
+		return nil
+	}
+
+	if strategy.Shadow {
+		e.recordShadowCommands(ctx, strategy, commands)
+		return nil
+	}
+
+	if e.opts.Outbox {
+		log.Info().
+			Str("strategy", strategy.Name).
+			Int("commands", len(commands)).
+			Msg("Queueing commands from strategy")
+		e.enqueueCommands(ctx, strategy, b.Event, commands)
+		return nil
+	}
+
+	// Execute commands
+	log.Info().
+		Str("strategy", strategy.Name).
+		Int("commands", len(commands)).
+		Msg("Executing commands from strategy")
+
+	if err := e.executor.ExecuteCommands(ctx, commands); err != nil {
+		log.Error().
+			Err(err).
+			Str("strategy", strategy.Name).
+			Msg("Failed to execute commands")
+		e.publishExecutionErrors(ctx, strategy, b.Event, err)
+		return &executionError{err: err}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// recordPnL books the realized PnL and fill fees of an event against the
+// strategy that placed the order (see pnl.Calculator). Realized losses feed
+// the loss cooldown and the group loss budgets.
+func (e *Engine) recordPnL(ctx context.Context, event types.Event) {
+	records, err := e.pnl.Records(ctx, event)
+	if err != nil {
+		log.Error().Err(err).Str("event_id", event.ID).Msg("Failed to look up order strategy")
+		return
+	}
+
+	for _, record := range records {
+		realized := record.Kind == types.PnLRealized
+		if realized {
+			e.throttle.recordLoss(record.Strategy, record.PnL, time.Now())
+		}
+		if err := e.storage.RecordPnL(ctx, record); err != nil {
+			log.Error().Err(err).Str("strategy", record.Strategy).Str("kind", record.Kind).Msg("Failed to record PnL")
+			continue
+		}
+		if realized && record.PnL < 0 {
+			e.checkStrategyGroupLoss(ctx, record.Strategy)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// A preview runs one strategy's real handler on a crafted event and returns
+// its commands without executing them, optionally with config keys replaced,
+// so a config can be tuned before it is proposed as a version. The strategy
+// does not need to be enabled. As with dry-run injections, only trading
+// windows are checked, and handlers that keep state update it as usual.
+
+// PreviewRequest is an event to preview a strategy on. Config keys replace
+// the strategy's own.
+type PreviewRequest struct {
+	Event  types.Event            `json:"event"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// PreviewStrategy runs the named strategy's handler on an event. The event
+// gets an ID and a timestamp if it has none, and "preview": true in its data.
+func (e *Engine) PreviewStrategy(ctx context.Context, name string, req PreviewRequest) (*InjectedStrategy, error) {
+	if req.Event.Type == "" {
+		return nil, fmt.Errorf("event type is required")
+	}
+
+	strategy, err := e.previewedStrategy(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Config) > 0 {
+		config := make(map[string]interface{}, len(strategy.Config)+len(req.Config))
+		for k, v := range strategy.Config {
+			config[k] = v
+		}
+		for k, v := range req.Config {
+			config[k] = v
+		}
+		strategy.Config = config
+	}
+
+	event := req.Event
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	if event.ID == "" {
+		event.ID = fmt.Sprintf("preview-%d", event.Timestamp.UnixNano())
+	}
+	data := make(map[string]interface{}, len(event.Data)+1)
+	for k, v := range event.Data {
+		data[k] = v
+	}
+	data["preview"] = true
+	event.Data = data
+
+	log.Info().
+		Str("strategy", strategy.Name).
+		Str("event_id", event.ID).
+		Str("type", event.Type).
+		Int("config_overrides", len(req.Config)).
+		Msg("Previewing strategy")
+
+	preview := e.previewStrategy(ctx, strategy, event)
+	return &preview, nil
+}
+
+// previewedStrategy returns the loaded strategy with the given name, or the
+// stored one with its config decrypted if it is not running
+func (e *Engine) previewedStrategy(ctx context.Context, name string) (types.Strategy, error) {
+	e.mu.RLock()
+	for _, strategy := range e.strategies {
+		if strategy.Name == name {
+			e.mu.RUnlock()
+			return strategy, nil
+		}
+	}
+	e.mu.RUnlock()
+
+	stored, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return types.Strategy{}, err
+	}
+	strategy := *stored
+	if strategy.Config, err = e.opts.ConfigKeys.DecryptConfig(strategy.Config); err != nil {
+		return types.Strategy{}, fmt.Errorf("failed to decrypt config of %s: %w", name, err)
+	}
+	return strategy, nil
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Price sanity guard: a place_order priced further than the allowed distance
+// from its market's reference price, in either direction, is blocked. It
+// protects against fat-fingered configs and bad fill data, not slippage.
+//
+// The reference is the mid of the cached book, or the last trade when a side
+// of the book is empty, either no older than priceReferenceMaxAge. The limit
+// is Options.MaxPriceDeviation in price units (0.2 allows 0.30 to 0.70 around
+// a 0.50 mid), overridable per strategy with config "max_price_deviation". A
+// single command can bypass the check with metadata "skip_price_check": true.
+// Orders without a fresh reference pass unchecked and are counted by strategy
+// (strategy_engine_price_unchecked_orders_total).
+
+const priceReferenceMaxAge = time.Minute
+
+// uncheckedPrices counts the orders let through without a reference price
+type uncheckedPrices struct {
+	mu     sync.Mutex
+	counts map[string]int64 // by strategy name
+}
+
+func newUncheckedPrices() *uncheckedPrices {
+	return &uncheckedPrices{counts: make(map[string]int64)}
+}
+
+func (u *uncheckedPrices) add(strategy string) {
+	u.mu.Lock()
+	u.counts[strategy]++
+	u.mu.Unlock()
+}
+
+func (u *uncheckedPrices) snapshot() map[string]int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	counts := make(map[string]int64, len(u.counts))
+	for strategy, n := range u.counts {
+		counts[strategy] = n
+	}
+	return counts
+}
+
+// checkPrices splits commands into those that pass the guard and those that do
+// not, publishing a strategy_error for every blocked command.
+func (e *Engine) checkPrices(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	maxDeviation := e.maxPriceDeviation()
+	if v, ok := strategy.Config["max_price_deviation"].(float64); ok {
+		maxDeviation = v
+	}
+	if maxDeviation <= 0 {
+		return commands
+	}
+
+	allowed := commands[:0]
+	for _, cmd := range commands {
+		checked, err := e.checkPrice(cmd, maxDeviation)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Str("market", cmd.MarketID).
+				Float64("price", cmd.Price).
+				Msg("Order blocked by price sanity check")
+			e.publishStrategyError(ctx, strategy, event, ErrorClassPriceCheck, err, &cmd)
+			continue
+		}
+		if !checked {
+			e.unchecked.add(strategy.Name)
+			log.Debug().
+				Str("strategy", strategy.Name).
+				Str("platform", cmd.Platform).
+				Str("market", cmd.MarketID).
+				Msg("No fresh reference price, order not price checked")
+		}
+		allowed = append(allowed, cmd)
+	}
+	return allowed
+}
+
+// checkPrice reports whether a command was checked, and why it is blocked
+func (e *Engine) checkPrice(cmd types.Command, maxDeviation float64) (bool, error) {
+	if !cmd.PlacesOrder() {
+		return true, nil
+	}
+	if skip, _ := cmd.Metadata["skip_price_check"].(bool); skip {
+		return true, nil
+	}
+
+	reference, ok := e.referencePrice(cmd.Platform, cmd.MarketID, cmd.Side)
+	if !ok {
+		return false, nil
+	}
+
+	if deviation := math.Abs(cmd.Price - reference); deviation > maxDeviation {
+		return true, fmt.Errorf("price %.4f is %.4f away from market price %.4f (limit %.4f)",
+			cmd.Price, deviation, reference, maxDeviation)
+	}
+	return true, nil
+}
+
+// referencePrice returns a fresh price of one side of a market: the book mid,
+// or else the last trade
+func (e *Engine) referencePrice(platform, marketID, side string) (float64, bool) {
+	if quote, ok := e.books.Top(platform, marketID, priceReferenceMaxAge); ok {
+		if mid, ok := quote.Mid(); ok {
+			if side == "no" {
+				return 1 - mid, true
+			}
+			return mid, true
+		}
+	}
+	if trade, ok := e.books.LastTrade(platform, marketID, priceReferenceMaxAge); ok {
+		return trade.PriceFor(side), true
+	}
+	return 0, false
+}
+
+func (e *Engine) maxPriceDeviation() float64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.opts.MaxPriceDeviation
+}
+
+// SetMaxPriceDeviation changes the price sanity limit at runtime; zero disables it
+func (e *Engine) SetMaxPriceDeviation(maxDeviation float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.opts.MaxPriceDeviation = maxDeviation
+}
@@ -0,0 +1,276 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orders"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Reconciliation compares the open orders of every managed account, as the
+// account services report them, with the order tracker and the order journal,
+// every Options.ReconcileInterval:
+//
+//   - orphans are open on the venue but missing from the journal, so not
+//     placed by the engine; they are adopted as orphans, like at startup
+//     recovery, and cancelled if Options.CancelOrphans is set
+//   - ghosts are tracked as open but no longer on the venue, typically
+//     because a cancel or fill event was lost; they are dropped from the
+//     tracker so risk limits stop counting them
+//   - journaled orders open on the venue but not tracked are adopted
+//
+// Orders placed less than reconcileGrace ago are not called ghosts, since the
+// venue may not list them yet, and accounts listing as many orders as the
+// account services return at most are not checked for ghosts. Newly found
+// orphans and ghosts raise an alert. With sharding, only orders on this
+// instance's markets are reconciled.
+
+// reconcileGrace is how old a tracked order must be before it can be a ghost
+const reconcileGrace = time.Minute
+
+// maxListedOrders is how many orders an account service lists per account
+const maxListedOrders = 200
+
+// ReconcileReport is the outcome of one reconciliation
+type ReconcileReport struct {
+	Accounts  int            `json:"accounts"`
+	Failed    int            `json:"failed"` // accounts whose orders could not be fetched
+	Orphans   []orders.Order `json:"orphans"`
+	Ghosts    []orders.Order `json:"ghosts"`
+	Adopted   int            `json:"adopted"`
+	Cancelled int            `json:"cancelled"`
+	CheckedAt time.Time      `json:"checked_at"`
+}
+
+type reconcileState struct {
+	mu     sync.Mutex
+	report ReconcileReport
+}
+
+// runReconciler reconciles open orders every interval until ctx is cancelled
+func (e *Engine) runReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.reconcileOrders(ctx)
+		}
+	}
+}
+
+// reconcileOrders runs one reconciliation and keeps its report
+func (e *Engine) reconcileOrders(ctx context.Context) ReconcileReport {
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	report := ReconcileReport{Orphans: []orders.Order{}, Ghosts: []orders.Order{}}
+	var newOrphans, ghosts []orders.Order
+
+	seen := make(map[managedAccount]bool)
+	for _, strategy := range strategies {
+		for _, account := range managedAccounts(strategy) {
+			if seen[account] {
+				continue
+			}
+			seen[account] = true
+			report.Accounts++
+
+			orphans, fresh, accountGhosts, adopted, err := e.reconcileAccount(ctx, account)
+			if err != nil {
+				report.Failed++
+				log.Warn().
+					Err(err).
+					Str("platform", account.Platform).
+					Str("account", account.AccountID).
+					Msg("Failed to reconcile open orders")
+				continue
+			}
+			report.Orphans = append(report.Orphans, orphans...)
+			report.Ghosts = append(report.Ghosts, accountGhosts...)
+			report.Adopted += adopted
+			newOrphans = append(newOrphans, fresh...)
+			ghosts = append(ghosts, accountGhosts...)
+		}
+	}
+
+	if e.opts.CancelOrphans {
+		for _, order := range report.Orphans {
+			if e.cancelOrphan(ctx, order) {
+				report.Cancelled++
+			}
+		}
+	}
+
+	report.CheckedAt = time.Now().UTC()
+	e.reconcile.mu.Lock()
+	e.reconcile.report = report
+	e.reconcile.mu.Unlock()
+
+	log.Info().
+		Int("accounts", report.Accounts).
+		Int("failed", report.Failed).
+		Int("orphans", len(report.Orphans)).
+		Int("ghosts", len(report.Ghosts)).
+		Int("adopted", report.Adopted).
+		Int("cancelled", report.Cancelled).
+		Msg("Open order reconciliation complete")
+
+	e.alertReconciliation(ctx, newOrphans, ghosts)
+	return report
+}
+
+// reconcileAccount reconciles the open orders of one account. It returns all
+// its orphans, those found for the first time, its ghosts and how many
+// orders it adopted.
+func (e *Engine) reconcileAccount(ctx context.Context, account managedAccount) (orphans, fresh, ghosts []orders.Order, adopted int, err error) {
+	open, err := e.executor.FetchOpenOrders(ctx, account.Platform, account.AccountID)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+	listed := len(open)
+
+	owned := open[:0]
+	for _, order := range open {
+		if e.opts.Shard.OwnsMarket(order.MarketID) {
+			owned = append(owned, order)
+		}
+	}
+	open = owned
+
+	onVenue := make(map[string]bool, len(open))
+	orderIDs := make([]string, len(open))
+	for i, order := range open {
+		onVenue[order.OrderID] = true
+		orderIDs[i] = order.OrderID
+	}
+
+	known := map[string]bool{}
+	if len(orderIDs) > 0 {
+		if known, err = e.storage.FilterJournaledOrders(ctx, account.Platform, orderIDs); err != nil {
+			return nil, nil, nil, 0, fmt.Errorf("failed to match open orders against journal: %w", err)
+		}
+	}
+
+	for _, order := range open {
+		tracked, isTracked := e.orders.Get(order.Platform, order.OrderID)
+		if !known[order.OrderID] {
+			order.Orphan = true
+			if isTracked {
+				order.CreatedAt = tracked.CreatedAt
+			} else {
+				e.orders.Add(order)
+				fresh = append(fresh, order)
+				log.Warn().
+					Str("platform", order.Platform).
+					Str("account", order.AccountID).
+					Str("order_id", order.OrderID).
+					Str("market", order.MarketID).
+					Msg("Found untracked open order")
+			}
+			orphans = append(orphans, order)
+			continue
+		}
+		if !isTracked {
+			e.orders.Add(order)
+			adopted++
+		}
+	}
+
+	// An account listing the maximum may have open orders beyond the list
+	if listed >= maxListedOrders {
+		return orphans, fresh, nil, adopted, nil
+	}
+
+	cutoff := time.Now().Add(-reconcileGrace)
+	for _, order := range e.orders.ByAccount(account.Platform, account.AccountID) {
+		if onVenue[order.OrderID] || order.CreatedAt.After(cutoff) || !e.opts.Shard.OwnsMarket(order.MarketID) {
+			continue
+		}
+		e.orders.Remove(order.Platform, order.OrderID)
+		ghosts = append(ghosts, order)
+		log.Warn().
+			Str("platform", order.Platform).
+			Str("account", order.AccountID).
+			Str("order_id", order.OrderID).
+			Str("strategy", order.Strategy).
+			Msg("Tracked order no longer open on the venue, dropped")
+	}
+	return orphans, fresh, ghosts, adopted, nil
+}
+
+// cancelOrphan cancels an orphan order; it reports whether the cancel went through
+func (e *Engine) cancelOrphan(ctx context.Context, order orders.Order) bool {
+	cancel := types.Command{
+		Type:      "cancel_order",
+		Platform:  order.Platform,
+		AccountID: order.AccountID,
+		MarketID:  order.MarketID,
+		Metadata: map[string]interface{}{
+			"order_id": order.OrderID,
+			"reason":   "orphan",
+		},
+	}
+	if err := e.executor.ExecuteCommands(ctx, []types.Command{cancel}); err != nil {
+		log.Warn().
+			Err(err).
+			Str("platform", order.Platform).
+			Str("order_id", order.OrderID).
+			Msg("Failed to cancel orphan order")
+		return false
+	}
+
+	log.Info().
+		Str("platform", order.Platform).
+		Str("account", order.AccountID).
+		Str("order_id", order.OrderID).
+		Msg("Orphan order cancelled")
+	return true
+}
+
+// alertReconciliation raises an alert for orphans and ghosts found this run
+func (e *Engine) alertReconciliation(ctx context.Context, orphans, ghosts []orders.Order) {
+	if len(orphans) == 0 && len(ghosts) == 0 {
+		return
+	}
+
+	orphanIDs := make([]string, len(orphans))
+	for i, order := range orphans {
+		orphanIDs[i] = order.OrderID
+	}
+	ghostIDs := make([]string, len(ghosts))
+	for i, order := range ghosts {
+		ghostIDs[i] = order.OrderID
+	}
+
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		"Open orders out of sync",
+		fmt.Sprintf("%d orders open on the venues were not placed by the strategy engine, %d tracked orders were no longer open", len(orphans), len(ghosts)),
+		map[string]interface{}{"orphan_order_ids": orphanIDs, "ghost_order_ids": ghostIDs},
+	); err != nil {
+		log.Error().Err(err).Msg("Failed to create reconciliation alert")
+	}
+}
+
+// Reconciliation returns the latest reconciliation report, reconciling now if
+// none was made yet
+func (e *Engine) Reconciliation(ctx context.Context) ReconcileReport {
+	e.reconcile.mu.Lock()
+	report := e.reconcile.report
+	e.reconcile.mu.Unlock()
+
+	if report.CheckedAt.IsZero() {
+		report = e.reconcileOrders(ctx)
+	}
+	return report
+}
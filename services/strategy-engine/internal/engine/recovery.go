@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// recoverOpenOrders queries every managed account for open orders and adopts
+// them into the order tracker, so orders placed manually or before a crash are
+// visible to risk limits and bulk cancels. Orders missing from the journal are
+// flagged as orphans and reported through an alert. With sharding, only orders
+// on this instance's markets are adopted.
+func (e *Engine) recoverOpenOrders(ctx context.Context) {
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	seen := make(map[managedAccount]bool)
+	adopted, orphaned := 0, 0
+	var orphanIDs []string
+
+	for _, strategy := range strategies {
+		for _, account := range managedAccounts(strategy) {
+			if seen[account] {
+				continue
+			}
+			seen[account] = true
+
+			open, err := e.executor.FetchOpenOrders(ctx, account.Platform, account.AccountID)
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("platform", account.Platform).
+					Str("account", account.AccountID).
+					Msg("Failed to fetch open orders for recovery")
+				continue
+			}
+
+			owned := open[:0]
+			for _, order := range open {
+				if e.opts.Shard.OwnsMarket(order.MarketID) {
+					owned = append(owned, order)
+				}
+			}
+			open = owned
+			if len(open) == 0 {
+				continue
+			}
+
+			orderIDs := make([]string, len(open))
+			for i, order := range open {
+				orderIDs[i] = order.OrderID
+			}
+
+			known, err := e.storage.FilterJournaledOrders(ctx, account.Platform, orderIDs)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to match open orders against journal")
+				continue
+			}
+
+			expiries, err := e.storage.GetOrderExpiries(ctx, account.Platform, orderIDs)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to look up order expiries")
+			}
+
+			for _, order := range open {
+				order.Orphan = !known[order.OrderID]
+				if expiresAt, ok := expiries[order.OrderID]; ok {
+					order.ExpiresAt = &expiresAt
+				}
+				if order.Orphan {
+					orphaned++
+					orphanIDs = append(orphanIDs, order.OrderID)
+					log.Warn().
+						Str("platform", order.Platform).
+						Str("account", order.AccountID).
+						Str("order_id", order.OrderID).
+						Str("market", order.MarketID).
+						Msg("Adopted untracked open order")
+				}
+				e.orders.Add(order)
+				adopted++
+			}
+		}
+	}
+
+	log.Info().
+		Int("adopted", adopted).
+		Int("orphans", orphaned).
+		Msg("Open order recovery complete")
+
+	if orphaned == 0 {
+		return
+	}
+
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		"Untracked open orders found",
+		fmt.Sprintf("%d open orders on managed accounts were not placed by the strategy engine", orphaned),
+		map[string]interface{}{"order_ids": orphanIDs},
+	); err != nil {
+		log.Error().Err(err).Msg("Failed to create orphan orders alert")
+	}
+}
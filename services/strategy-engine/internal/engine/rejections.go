@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Rejection feedback lets a strategy fix an order the venue refused for its
+// price instead of losing it. When a command result reports a rejection with
+// a reason repricing may fix (executor.RejectPriceOutOfRange or
+// executor.RejectCrossedMarket), the strategy that sent the order gets an
+// EventOrderRejected event on its OnRejected handler, registered with
+// RegisterRejectionHandler for the strategy type. Its data holds:
+//
+//   - "command": the rejected order, as a types.Command
+//   - "reason": the rejection reason
+//   - "error", "error_code": what the account service answered
+//   - "strategy", "tenant", "account_id": as on the command result
+//
+// The commands the handler returns go through the usual pipeline. Handlers
+// must bound their retries, for instance with a counter in the command's
+// metadata, since each retry can be rejected in turn. Strategy types without
+// an OnRejected handler only see the command_result event, as before.
+
+// EventOrderRejected is the type of the events sent to OnRejected handlers
+const EventOrderRejected = "order_rejected"
+
+// RegisterRejectionHandler registers the OnRejected handler of a strategy type
+func (e *Engine) RegisterRejectionHandler(name string, handler types.StrategyHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rejectionHandlers[name] = handler
+	log.Info().Str("strategy", name).Msg("Registered strategy rejection handler")
+}
+
+// dispatchRejection hands a repriceable rejection reported by a command
+// result to the OnRejected handler of the strategy that sent the order
+func (e *Engine) dispatchRejection(ctx context.Context, result types.Event, receivers []types.Strategy) {
+	reason, _ := result.Data["reject_reason"].(string)
+	name, _ := result.Data["strategy"].(string)
+	if result.Type != EventCommandResult || reason == "" || name == "" {
+		return
+	}
+
+	for _, strategy := range receivers {
+		if strategy.Name != name {
+			continue
+		}
+
+		e.mu.RLock()
+		_, exists := e.rejectionHandlers[strategy.Type]
+		e.mu.RUnlock()
+		if !exists {
+			return
+		}
+
+		event, err := rejectionEvent(result, reason)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("strategy", name).
+				Str("event_id", result.ID).
+				Msg("Failed to read rejected command")
+			return
+		}
+		e.dispatch(ctx, strategy, event)
+		return
+	}
+}
+
+// rejectionEvent builds the EventOrderRejected event for a command result
+func rejectionEvent(result types.Event, reason string) (types.Event, error) {
+	// The command went through the stream as JSON
+	raw, err := json.Marshal(result.Data["command"])
+	if err != nil {
+		return types.Event{}, err
+	}
+	var cmd types.Command
+	if err := json.Unmarshal(raw, &cmd); err != nil {
+		return types.Event{}, err
+	}
+
+	data := map[string]interface{}{
+		"command":    cmd,
+		"reason":     reason,
+		"strategy":   result.Data["strategy"],
+		"tenant":     result.Data["tenant"],
+		"account_id": result.Data["account_id"],
+	}
+	for _, key := range []string{"error", "error_code"} {
+		if value, ok := result.Data[key]; ok {
+			data[key] = value
+		}
+	}
+
+	return types.Event{
+		ID:        EventOrderRejected + ":" + result.ID,
+		Type:      EventOrderRejected,
+		Platform:  result.Platform,
+		Timestamp: result.Timestamp,
+		Data:      data,
+	}, nil
+}
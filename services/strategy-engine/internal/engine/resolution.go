@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Market resolution: a market_resolved event on market_events carries
+// "market_id" and the winning side in "outcome" ("yes" or "no"). The engine
+//
+//   - records the resolution, so strategies can no longer place orders on the
+//     market, also after a restart,
+//   - cancels our open orders on the market,
+//   - settles the open positions at 1 for the winning side and 0 otherwise,
+//     and attributes the settlement PnL to strategies in proportion to their
+//     journaled fills on each position.
+//
+// Strategies still receive the event, e.g. to drop state for the market.
+
+// resolutionHistory is how far back resolutions are loaded at startup
+const resolutionHistory = 90 * 24 * time.Hour
+
+// loadResolutions marks the recently resolved markets in the registry
+func (e *Engine) loadResolutions(ctx context.Context) error {
+	resolutions, err := e.storage.GetResolutions(ctx, time.Now().Add(-resolutionHistory))
+	if err != nil {
+		return err
+	}
+	for _, r := range resolutions {
+		e.markets.SetResolved(r.MarketID, r.Outcome)
+	}
+	return nil
+}
+
+// resolvedOutcome returns the market and the winning side, "yes" or "no", of
+// a market_resolved event
+func resolvedOutcome(event types.Event) (marketID, outcome string, ok bool) {
+	marketID, _ = event.Data["market_id"].(string)
+	outcome, _ = event.Data["outcome"].(string)
+	if outcome == "" {
+		outcome, _ = event.Data["winning_outcome"].(string)
+	}
+	outcome = strings.ToLower(outcome)
+	return marketID, outcome, marketID != "" && (outcome == "yes" || outcome == "no")
+}
+
+// handleMarketResolved cleans up after a market_resolved event. Every step is
+// idempotent, so redelivered events are harmless.
+func (e *Engine) handleMarketResolved(ctx context.Context, event types.Event) {
+	marketID, outcome, ok := resolvedOutcome(event)
+	if !ok {
+		log.Warn().
+			Str("id", event.ID).
+			Str("market", marketID).
+			Str("outcome", outcome).
+			Msg("Ignoring market_resolved event without market_id and yes/no outcome")
+		return
+	}
+
+	e.markets.SetResolved(marketID, outcome)
+	e.dropMarketConditionals(ctx, marketID)
+
+	resolvedAt := event.Timestamp
+	if resolvedAt.IsZero() {
+		resolvedAt = time.Now().UTC()
+	}
+	if err := e.storage.RecordResolution(ctx, types.MarketResolution{
+		Platform:   event.Platform,
+		MarketID:   marketID,
+		Outcome:    outcome,
+		ResolvedAt: resolvedAt,
+	}); err != nil {
+		log.Error().Err(err).Str("market", marketID).Msg("Failed to record market resolution")
+	}
+
+	cancelled := e.cancelResolvedOrders(ctx, event.Platform, marketID)
+
+	settlements, err := e.storage.SettlePositions(ctx, event.Platform, marketID, outcome, resolvedAt)
+	if err != nil {
+		log.Error().Err(err).Str("market", marketID).Msg("Failed to settle positions")
+	}
+
+	var total float64
+	for _, settlement := range settlements {
+		total += settlement.PnL
+		e.attributeSettlement(ctx, settlement, resolvedAt)
+	}
+
+	log.Info().
+		Str("platform", event.Platform).
+		Str("market", marketID).
+		Str("outcome", outcome).
+		Int("cancelled", cancelled).
+		Int("settled", len(settlements)).
+		Float64("pnl", total).
+		Msg("Market resolved")
+
+	if cancelled == 0 && len(settlements) == 0 {
+		return
+	}
+
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		fmt.Sprintf("Market %s resolved %s", marketID, strings.ToUpper(outcome)),
+		fmt.Sprintf("%d open orders cancelled, %d positions settled for a PnL of %.2f", cancelled, len(settlements), total),
+		map[string]interface{}{
+			"platform":    event.Platform,
+			"market_id":   marketID,
+			"outcome":     outcome,
+			"cancelled":   cancelled,
+			"settlements": settlements,
+		},
+	); err != nil {
+		log.Error().Err(err).Msg("Failed to create market resolution alert")
+	}
+}
+
+// cancelResolvedOrders cancels our open orders on a resolved market and stops
+// tracking them. Venues usually cancel them on their own, so failures are
+// only logged; the orders cannot fill any more either way.
+func (e *Engine) cancelResolvedOrders(ctx context.Context, platform, marketID string) int {
+	open := e.orders.ByMarket(platform, marketID)
+	if len(open) == 0 {
+		return 0
+	}
+
+	commands := make([]types.Command, len(open))
+	for i, order := range open {
+		commands[i] = types.Command{
+			Type:      "cancel_order",
+			Platform:  order.Platform,
+			AccountID: order.AccountID,
+			MarketID:  order.MarketID,
+			Metadata: map[string]interface{}{
+				"order_id": order.OrderID,
+				"strategy": order.Strategy,
+				"reason":   "market_resolved",
+			},
+		}
+	}
+
+	if err := e.executor.ExecuteCommands(ctx, commands); err != nil {
+		log.Warn().Err(err).Str("market", marketID).Msg("Some orders on the resolved market could not be cancelled")
+	}
+	for _, order := range open {
+		e.orders.Remove(order.Platform, order.OrderID)
+	}
+	return len(open)
+}
+
+// attributeSettlement splits the PnL of a settled position between the
+// strategies whose fills built it
+func (e *Engine) attributeSettlement(ctx context.Context, settlement types.Settlement, settledAt time.Time) {
+	shares, err := e.storage.GetFilledSharesByStrategy(ctx, settlement.Platform, settlement.AccountID, settlement.MarketID, settlement.Side)
+	if err != nil {
+		log.Error().Err(err).Str("market", settlement.MarketID).Msg("Failed to look up strategy fills for settlement")
+		return
+	}
+
+	var filled float64
+	for _, s := range shares {
+		filled += s
+	}
+	if filled <= 0 {
+		log.Debug().
+			Str("account", settlement.AccountID).
+			Str("market", settlement.MarketID).
+			Msg("Settled position has no strategy fills, PnL not attributed")
+		return
+	}
+
+	for strategy, s := range shares {
+		pnl := settlement.PnL * s / filled
+		e.throttle.recordLoss(strategy, pnl, time.Now())
+		if err := e.storage.RecordPnL(ctx, types.PnLRecord{
+			Strategy:  strategy,
+			Platform:  settlement.Platform,
+			MarketID:  settlement.MarketID,
+			PnL:       pnl,
+			CreatedAt: settledAt,
+		}); err != nil {
+			log.Error().Err(err).Str("strategy", strategy).Msg("Failed to record settlement PnL")
+			continue
+		}
+		if pnl < 0 {
+			e.checkStrategyGroupLoss(ctx, strategy)
+		}
+	}
+}
+
+// dropResolvedMarkets blocks place_order commands on resolved markets,
+// publishing a strategy_error for each
+func (e *Engine) dropResolvedMarkets(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	allowed := commands[:0]
+	for _, cmd := range commands {
+		if cmd.PlacesOrder() {
+			if outcome, resolved := e.markets.Resolved(cmd.MarketID); resolved {
+				err := fmt.Errorf("market %s already resolved %s", cmd.MarketID, outcome)
+				log.Warn().
+					Err(err).
+					Str("strategy", strategy.Name).
+					Msg("Command blocked for resolved market")
+				e.publishStrategyError(ctx, strategy, event, ErrorClassResolved, err, &cmd)
+				continue
+			}
+		}
+		allowed = append(allowed, cmd)
+	}
+	return allowed
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// CommandResultStream receives a command_result event for every order the
+// executor placed, or failed to place. The engine consumes it as well, so
+// strategies can react to the outcome of their own or other strategies'
+// orders; like other account events, a result only reaches the strategies of
+// the tenant it belongs to.
+const CommandResultStream = "command_events"
+
+// EventCommandResult is the type of the events on CommandResultStream
+const EventCommandResult = "command_result"
+
+// PublishCommandResult publishes the outcome of an order; it implements
+// executor.ResultPublisher
+func (e *Engine) PublishCommandResult(ctx context.Context, result executor.CommandResult) {
+	record := result.Record
+	e.recordOrderOutcome(ctx, record)
+	e.trackGroupOrder(ctx, result)
+
+	data := map[string]interface{}{
+		"strategy":      record.Strategy,
+		"tenant":        record.Tenant,
+		"account_id":    record.AccountID,
+		"market_id":     record.MarketID,
+		"side":          record.Side,
+		"price":         record.Price,
+		"shares":        record.Shares,
+		"status":        record.Status,
+		"order_id":      record.OrderID,
+		"filled_shares": result.FilledShares,
+		"latency_ms":    record.Latency.Milliseconds(),
+		"command":       result.Command,
+	}
+	if record.HedgeLatency > 0 {
+		data["hedge_latency_ms"] = record.HedgeLatency.Milliseconds()
+	}
+	if record.Error != "" {
+		data["error"] = record.Error
+	}
+	if result.ErrorCode != "" {
+		data["error_code"] = result.ErrorCode
+	}
+	if result.RejectReason != "" {
+		data["reject_reason"] = result.RejectReason
+	}
+
+	event := types.Event{
+		ID:        fmt.Sprintf("command_result:%s:%s:%d", record.Platform, record.AccountID, record.CreatedAt.UnixNano()),
+		Type:      EventCommandResult,
+		Platform:  record.Platform,
+		Timestamp: record.CreatedAt,
+		Data:      data,
+	}
+
+	if err := e.eventBus.Publish(ctx, CommandResultStream, event); err != nil {
+		log.Error().
+			Err(err).
+			Str("strategy", record.Strategy).
+			Str("status", record.Status).
+			Msg("Failed to publish command result event")
+	}
+}
@@ -0,0 +1,273 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Schedule restricts a strategy to trading windows in a given timezone.
+//
+// Configured under the "schedule" key of the strategy config:
+//
+//	"schedule": {
+//	  "timezone": "America/New_York",
+//	  "windows": [{"days": ["mon", "tue", "wed", "thu", "fri"], "start": "09:30", "end": "16:00"}],
+//	  "on_close": "cancel"  // "", "cancel" or "flatten"
+//	}
+//
+// A window whose end is before its start wraps past midnight. Omitting days
+// means every day.
+// When a window closes, "cancel" cancels the strategy's open orders and
+// "flatten" also closes the positions attributed to it.
+type Schedule struct {
+	Location *time.Location
+	Windows  []ScheduleWindow
+	OnClose  string
+}
+
+type ScheduleWindow struct {
+	Days  map[time.Weekday]bool
+	Start time.Duration // offset from midnight
+	End   time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseSchedule reads the "schedule" key of a strategy config.
+// It returns nil if the strategy has no schedule.
+func ParseSchedule(config map[string]interface{}) (*Schedule, error) {
+	raw, ok := config["schedule"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	schedule := &Schedule{Location: time.UTC}
+
+	if tz, _ := raw["timezone"].(string); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+		}
+		schedule.Location = loc
+	}
+
+	schedule.OnClose, _ = raw["on_close"].(string)
+	switch schedule.OnClose {
+	case "", "cancel", "flatten":
+	default:
+		return nil, fmt.Errorf("invalid on_close %q", schedule.OnClose)
+	}
+
+	windows, _ := raw["windows"].([]interface{})
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("schedule has no windows")
+	}
+
+	for _, windowRaw := range windows {
+		w, ok := windowRaw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule window")
+		}
+
+		var window ScheduleWindow
+		var err error
+
+		start, _ := w["start"].(string)
+		if window.Start, err = parseClock(start); err != nil {
+			return nil, err
+		}
+		end, _ := w["end"].(string)
+		if window.End, err = parseClock(end); err != nil {
+			return nil, err
+		}
+
+		if days, ok := w["days"].([]interface{}); ok && len(days) > 0 {
+			window.Days = make(map[time.Weekday]bool)
+			for _, dayRaw := range days {
+				day, _ := dayRaw.(string)
+				weekday, ok := weekdays[strings.ToLower(day)]
+				if !ok {
+					return nil, fmt.Errorf("invalid schedule day %q", day)
+				}
+				window.Days[weekday] = true
+			}
+		}
+
+		schedule.Windows = append(schedule.Windows, window)
+	}
+
+	return schedule, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule time %q (want HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsOpen reports whether t falls inside any of the schedule's windows
+func (s *Schedule) IsOpen(t time.Time) bool {
+	local := t.In(s.Location)
+	// Wall-clock time of day; elapsed time since midnight is an hour off on
+	// days the clocks change
+	offset := time.Duration(local.Hour())*time.Hour +
+		time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second +
+		time.Duration(local.Nanosecond())
+	yesterday := local.AddDate(0, 0, -1).Weekday()
+
+	for _, w := range s.Windows {
+		if w.Start <= w.End {
+			if w.includes(local.Weekday()) && offset >= w.Start && offset < w.End {
+				return true
+			}
+			continue
+		}
+
+		// Overnight window: the evening part belongs to today's day,
+		// the early-morning part to the day the window started.
+		if w.includes(local.Weekday()) && offset >= w.Start {
+			return true
+		}
+		if w.includes(yesterday) && offset < w.End {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w ScheduleWindow) includes(day time.Weekday) bool {
+	return w.Days == nil || w.Days[day]
+}
+
+// runScheduler watches scheduled strategies and, when a trading window
+// closes, emits the strategy's on_close cancel/flatten commands. Nothing is
+// sent while the kill switch is engaged.
+func (e *Engine) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	wasOpen := make(map[string]bool)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.mu.RLock()
+			strategies := e.strategies
+			schedules := e.schedules
+			e.mu.RUnlock()
+
+			for _, strategy := range strategies {
+				// Candidates share the live strategy's accounts; only the
+				// live version acts on them
+				schedule := schedules[strategy.ID]
+				if schedule == nil || strategy.CandidateOf != "" {
+					continue
+				}
+
+				open := schedule.IsOpen(now)
+				prev, known := wasOpen[strategy.ID]
+				wasOpen[strategy.ID] = open
+
+				if !known || !prev || open {
+					continue
+				}
+				if halted, _ := e.Halted(); halted {
+					log.Warn().
+						Str("strategy", strategy.Name).
+						Str("on_close", schedule.OnClose).
+						Msg("Strategy trading window closed while halted, on_close skipped")
+					continue
+				}
+
+				log.Info().
+					Str("strategy", strategy.Name).
+					Str("on_close", schedule.OnClose).
+					Msg("Strategy trading window closed")
+
+				e.closeWindow(ctx, strategy, schedule.OnClose)
+			}
+		}
+	}
+}
+
+// closeWindow runs a strategy's on_close action. Accounts may be shared, so
+// only the strategy's own orders are cancelled and, for "flatten", only the
+// positions attributed to it are closed (see Flatten).
+func (e *Engine) closeWindow(ctx context.Context, strategy types.Strategy, onClose string) {
+	switch onClose {
+	case "cancel":
+		// Every shard runs the scheduler and cancels in its own markets
+		if err := e.executor.ExecuteCommands(ctx, e.windowCloseCancels(strategy)); err != nil {
+			log.Error().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Msg("Failed to cancel orders on window close")
+		}
+
+	case "flatten":
+		// Every shard runs the scheduler and closes its own markets
+		result, err := e.Flatten(ctx, FlattenRequest{
+			Strategy:   strategy.Name,
+			Reason:     "schedule_close",
+			Operator:   "scheduler",
+			ownMarkets: true,
+		})
+		if err != nil {
+			log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to flatten on window close")
+			return
+		}
+		if len(result.Errors) > 0 {
+			log.Error().
+				Strs("errors", result.Errors).
+				Str("strategy", strategy.Name).
+				Msg("Window close flatten had errors")
+		}
+	}
+}
+
+// windowCloseCancels returns the cancels of a strategy's orders in this
+// instance's markets, on the accounts whose platform can cancel orders
+func (e *Engine) windowCloseCancels(strategy types.Strategy) []types.Command {
+	req := FlattenRequest{Strategy: strategy.Name, ownMarkets: true}
+
+	var cancels []types.Command
+	for _, account := range managedAccounts(strategy) {
+		base := types.Command{
+			Platform:  account.Platform,
+			AccountID: account.AccountID,
+			Metadata: map[string]interface{}{
+				"strategy": strategy.Name,
+				"reason":   "schedule_close",
+			},
+		}
+		if !e.executor.CanCancel(base) {
+			log.Warn().
+				Str("strategy", strategy.Name).
+				Str("platform", account.Platform).
+				Str("account", account.AccountID).
+				Msg("Orders not cancelled on window close, the platform cannot cancel orders")
+			continue
+		}
+		cancels = append(cancels, e.flattenCancels(req, base)...)
+	}
+	return cancels
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func mustSchedule(t *testing.T, schedule map[string]interface{}) *Schedule {
+	t.Helper()
+
+	s, err := ParseSchedule(map[string]interface{}{"schedule": schedule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func window(start, end string, days ...interface{}) map[string]interface{} {
+	w := map[string]interface{}{"start": start, "end": end}
+	if len(days) > 0 {
+		w["days"] = days
+	}
+	return w
+}
+
+func TestScheduleIsOpen(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, newYork)
+	}
+
+	weekdays := mustSchedule(t, map[string]interface{}{
+		"timezone": "America/New_York",
+		"windows":  []interface{}{window("09:30", "16:00", "mon", "tue", "wed", "thu", "fri")},
+	})
+	everyDay := mustSchedule(t, map[string]interface{}{
+		"timezone": "America/New_York",
+		"windows":  []interface{}{window("09:30", "16:00")},
+	})
+	overnight := mustSchedule(t, map[string]interface{}{
+		"timezone": "America/New_York",
+		"windows":  []interface{}{window("22:00", "02:00", "fri")},
+	})
+
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		at       time.Time
+		want     bool
+	}{
+		{"before the window", weekdays, at(2026, 3, 10, 9, 29), false},
+		{"at the start", weekdays, at(2026, 3, 10, 9, 30), true},
+		{"inside", weekdays, at(2026, 3, 10, 12, 0), true},
+		{"end is exclusive", weekdays, at(2026, 3, 10, 16, 0), false},
+		{"weekend", weekdays, at(2026, 3, 14, 12, 0), false},
+
+		// Clocks go forward at 02:00 on 2026-03-08 and back on 2026-11-01:
+		// the window follows the wall clock, not the time since midnight
+		{"spring forward, inside", everyDay, at(2026, 3, 8, 9, 45), true},
+		{"spring forward, before", everyDay, at(2026, 3, 8, 9, 15), false},
+		{"spring forward, after", everyDay, at(2026, 3, 8, 16, 15), false},
+		{"fall back, inside", everyDay, at(2026, 11, 1, 9, 45), true},
+		{"fall back, before", everyDay, at(2026, 11, 1, 9, 15), false},
+		{"fall back, after", everyDay, at(2026, 11, 1, 16, 15), false},
+
+		{"overnight, evening of the day", overnight, at(2026, 3, 13, 23, 0), true},
+		{"overnight, morning after", overnight, at(2026, 3, 14, 1, 0), true},
+		{"overnight, after the end", overnight, at(2026, 3, 14, 2, 0), false},
+		{"overnight, evening of another day", overnight, at(2026, 3, 14, 23, 0), false},
+		{"overnight, morning of the day", overnight, at(2026, 3, 13, 1, 0), false},
+
+		{"other timezone", weekdays, time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.IsOpen(tt.at); got != tt.want {
+				t.Errorf("IsOpen(%s) = %t, want %t", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	if s, err := ParseSchedule(map[string]interface{}{}); s != nil || err != nil {
+		t.Errorf("no schedule: got %v, %v", s, err)
+	}
+
+	s := mustSchedule(t, map[string]interface{}{
+		"windows":  []interface{}{window("09:30", "16:00", "Mon")},
+		"on_close": "flatten",
+	})
+	if s.Location != time.UTC || s.OnClose != "flatten" {
+		t.Errorf("schedule = %+v, want UTC and flatten", s)
+	}
+	if w := s.Windows[0]; w.Start != 9*time.Hour+30*time.Minute || w.End != 16*time.Hour || !w.Days[time.Monday] || len(w.Days) != 1 {
+		t.Errorf("window = %+v", w)
+	}
+
+	invalid := []map[string]interface{}{
+		{"windows": []interface{}{}},
+		{"timezone": "Mars/Olympus", "windows": []interface{}{window("09:30", "16:00")}},
+		{"on_close": "sell", "windows": []interface{}{window("09:30", "16:00")}},
+		{"windows": []interface{}{window("9.30", "16:00")}},
+		{"windows": []interface{}{window("09:30", "16:00", "someday")}},
+		{"windows": []interface{}{"09:30-16:00"}},
+	}
+	for _, schedule := range invalid {
+		if _, err := ParseSchedule(map[string]interface{}{"schedule": schedule}); err == nil {
+			t.Errorf("ParseSchedule(%v) succeeded, want an error", schedule)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orders"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Self-trade prevention: every order is a buy of one outcome, so a new order
+// on one side at price q would match our own resting order on the other side
+// at price p when p + q >= 1. That happens when two strategies work the same
+// market, or when a hedge meets the order it is hedging. Each place_order is
+// checked against the tracked open orders of all our accounts on the same
+// platform and market, and handled per mode (a sell of one outcome is checked
+// as the buy of the other outcome at the complement price):
+//
+//	off             no check
+//	skip            drop the new order (default)
+//	reprice         lower the new order to one tick below crossing
+//	cancel_replace  cancel the crossing resting orders first, then place it
+//
+// The mode is Options.SelfTradePrevention, overridable per strategy with
+// config "self_trade_prevention".
+const (
+	SelfTradeOff           = "off"
+	SelfTradeSkip          = "skip"
+	SelfTradeReprice       = "reprice"
+	SelfTradeCancelReplace = "cancel_replace"
+)
+
+// selfTradeTick is how far below the crossing price a repriced order rests
+const selfTradeTick = 0.01
+
+// ValidSelfTradeMode reports whether mode is a known self-trade prevention mode
+func ValidSelfTradeMode(mode string) bool {
+	switch mode {
+	case SelfTradeOff, SelfTradeSkip, SelfTradeReprice, SelfTradeCancelReplace:
+		return true
+	}
+	return false
+}
+
+// preventSelfTrades applies the self-trade policy to the place_order commands,
+// publishing a strategy_error for every order it drops
+func (e *Engine) preventSelfTrades(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	mode := e.selfTradeMode()
+	if v, ok := strategy.Config["self_trade_prevention"].(string); ok && ValidSelfTradeMode(v) {
+		mode = v
+	}
+	if mode == "" || mode == SelfTradeOff {
+		return commands
+	}
+
+	result := make([]types.Command, 0, len(commands))
+	for _, cmd := range commands {
+		if !cmd.PlacesOrder() {
+			result = append(result, cmd)
+			continue
+		}
+
+		crossing := e.crossingOrders(cmd)
+		if len(crossing) == 0 {
+			result = append(result, cmd)
+			continue
+		}
+
+		switch mode {
+		case SelfTradeReprice:
+			price := 1 - highestPrice(crossing) - selfTradeTick
+			price = math.Round(price*100) / 100
+			if price <= 0 {
+				e.blockSelfTrade(ctx, strategy, event, cmd, crossing, "no price below the resting order is left")
+				continue
+			}
+			log.Info().
+				Str("strategy", strategy.Name).
+				Str("market", cmd.MarketID).
+				Float64("price", cmd.Price).
+				Float64("repriced", price).
+				Msg("Order repriced to avoid a self-trade")
+			if isSell(cmd) {
+				price = math.Round((1-price)*100) / 100
+			}
+			cmd.Price = price
+			result = append(result, cmd)
+
+		case SelfTradeCancelReplace:
+			cancels := make([]types.Command, 0, len(crossing))
+			for _, order := range crossing {
+				cancels = append(cancels, types.Command{
+					Type:      "cancel_order",
+					Platform:  order.Platform,
+					AccountID: order.AccountID,
+					MarketID:  order.MarketID,
+					Metadata: map[string]interface{}{
+						"order_id": order.OrderID,
+						"strategy": strategy.Name,
+						"reason":   "self_trade_prevention",
+					},
+				})
+			}
+			if !e.canCancelAll(cancels) {
+				e.blockSelfTrade(ctx, strategy, event, cmd, crossing, "the resting orders cannot be cancelled")
+				continue
+			}
+			result = append(result, cancels...)
+			log.Info().
+				Str("strategy", strategy.Name).
+				Str("market", cmd.MarketID).
+				Int("cancelled", len(crossing)).
+				Msg("Cancelling resting orders to avoid a self-trade")
+			result = append(result, cmd)
+
+		default:
+			e.blockSelfTrade(ctx, strategy, event, cmd, crossing, "order skipped")
+		}
+	}
+	return result
+}
+
+// canCancelAll reports whether every cancel can be sent on its platform
+func (e *Engine) canCancelAll(cancels []types.Command) bool {
+	for _, cancel := range cancels {
+		if !e.executor.CanCancel(cancel) {
+			return false
+		}
+	}
+	return true
+}
+
+// crossingOrders returns our open orders that cmd would trade against
+func (e *Engine) crossingOrders(cmd types.Command) []orders.Order {
+	side, price := cmd.Side, cmd.Price
+	if isSell(cmd) {
+		side, price = "yes", 1-price
+		if cmd.Side == "yes" {
+			side = "no"
+		}
+	}
+
+	var crossing []orders.Order
+	for _, order := range e.orders.ByMarket(cmd.Platform, cmd.MarketID) {
+		if order.Side == side || order.Remaining() <= 0 {
+			continue
+		}
+		if order.Price+price >= 1 {
+			crossing = append(crossing, order)
+		}
+	}
+	return crossing
+}
+
+func (e *Engine) blockSelfTrade(ctx context.Context, strategy types.Strategy, event types.Event, cmd types.Command, crossing []orders.Order, action string) {
+	err := fmt.Errorf("%s at %.4f would cross our resting %s order %s at %.4f: %s",
+		cmd.Side, cmd.Price, crossing[0].Side, crossing[0].OrderID, crossing[0].Price, action)
+	log.Warn().
+		Err(err).
+		Str("strategy", strategy.Name).
+		Str("market", cmd.MarketID).
+		Msg("Order blocked by self-trade prevention")
+	e.publishStrategyError(ctx, strategy, event, ErrorClassSelfTrade, err, &cmd)
+}
+
+// isSell reports whether a place_order sells rather than buys
+func isSell(cmd types.Command) bool {
+	switch strings.ToLower(cmd.Action) {
+	case executor.ActionSell, executor.ActionClose:
+		return true
+	}
+	return false
+}
+
+func highestPrice(resting []orders.Order) float64 {
+	var highest float64
+	for _, order := range resting {
+		highest = math.Max(highest, order.Price)
+	}
+	return highest
+}
+
+func (e *Engine) selfTradeMode() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.opts.SelfTradePrevention
+}
+
+// SetSelfTradePrevention changes the default self-trade prevention mode at runtime
+func (e *Engine) SetSelfTradePrevention(mode string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.opts.SelfTradePrevention = mode
+}
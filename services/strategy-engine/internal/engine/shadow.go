@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// recordShadowCommands journals commands from a shadow-mode strategy instead
+// of executing them, so their hypothetical behaviour can be reviewed.
+func (e *Engine) recordShadowCommands(ctx context.Context, strategy types.Strategy, commands []types.Command) {
+	for _, cmd := range commands {
+		if !cmd.PlacesOrder() {
+			continue
+		}
+
+		// Like sent orders, referenced to the market price of the moment
+		reference := cmd.Price
+		if price, ok := e.referencePrice(cmd.Platform, cmd.MarketID, cmd.Side); ok {
+			reference = price
+		}
+
+		if err := e.storage.RecordOrder(ctx, types.OrderRecord{
+			Strategy:       strategy.Name,
+			Tenant:         strategyTenant(strategy),
+			Platform:       cmd.Platform,
+			AccountID:      cmd.AccountID,
+			MarketID:       cmd.MarketID,
+			OutcomeID:      cmd.OutcomeID,
+			Side:           cmd.Side,
+			Action:         cmd.Action,
+			Price:          cmd.Price,
+			Shares:         cmd.Shares,
+			ReferencePrice: reference,
+			Status:         "shadow",
+			CreatedAt:      time.Now().UTC(),
+		}); err != nil {
+			log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to record shadow command")
+		}
+	}
+
+	log.Info().
+		Str("strategy", strategy.Name).
+		Int("commands", len(commands)).
+		Msg("Shadow mode: recorded commands without executing")
+}
+
+// updateStrategy applies fn to the loaded strategy with the given ID, or to
+// its variants if it runs an A/B test. The slice is copied so in-flight readers keep a consistent snapshot.
+func (e *Engine) updateStrategy(id string, fn func(*types.Strategy)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	strategies := make([]types.Strategy, len(e.strategies))
+	copy(strategies, e.strategies)
+	for i := range strategies {
+		if strategies[i].ID == id || strategies[i].VariantOf == id {
+			fn(&strategies[i])
+		}
+	}
+	e.strategies = strategies
+}
+
+// autoDisableInterval is how often strategy performance is checked against the policy
+const autoDisableInterval = time.Hour
+
+// runAutoDisable periodically moves live strategies whose rolling performance
+// breaches the auto-disable policy into shadow mode and raises an alert.
+func (e *Engine) runAutoDisable(ctx context.Context) {
+	ticker := time.NewTicker(autoDisableInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.checkPerformance(ctx)
+		}
+	}
+}
+
+func (e *Engine) checkPerformance(ctx context.Context) {
+	e.mu.RLock()
+	policy := e.opts.AutoDisable
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	if policy == nil {
+		return
+	}
+
+	for _, strategy := range strategies {
+		if strategy.Shadow {
+			continue
+		}
+
+		perf, err := e.analytics.Performance(ctx, strategy.Name, policy.Window)
+		if err != nil {
+			log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to compute strategy performance")
+			continue
+		}
+
+		reason := policy.Evaluate(perf)
+		if reason == "" {
+			continue
+		}
+
+		if err := e.storage.SetStrategyShadow(ctx, liveStrategyID(strategy), true, reason); err != nil {
+			log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to move strategy to shadow mode")
+			continue
+		}
+
+		e.updateStrategy(liveStrategyID(strategy), func(s *types.Strategy) {
+			s.Shadow = true
+			s.ShadowReason = reason
+		})
+
+		log.Warn().
+			Str("strategy", strategy.Name).
+			Str("reason", reason).
+			Msg("Strategy moved to shadow mode by auto-disable policy")
+
+		if err := e.storage.CreateAlert(
+			ctx,
+			"strategy",
+			fmt.Sprintf("Strategy %s moved to shadow mode", strategy.Name),
+			reason+". Review and clear the shadow flag to restore live trading.",
+			map[string]interface{}{
+				"strategy_id": strategy.ID,
+				"trades":      perf.Trades,
+				"pnl":         perf.PnL,
+				"hit_rate":    perf.HitRate,
+			},
+		); err != nil {
+			log.Error().Err(err).Msg("Failed to create auto-disable alert")
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"hash/fnv"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Shard assigns this instance a slice of the market space so several engines
+// can consume the same streams without executing the same event twice. Every
+// instance reads every stream; an event is handled only by the instance whose
+// Index equals hash(market_id) mod Count. The zero value disables sharding.
+//
+// Events without a market_id (account events, for example) and engine-wide
+// housekeeping such as auto-disable belong to shard 0.
+//
+// A hedge placed on another market is tracked by the instance that placed it,
+// while its fills are routed to the instance owning the hedge market, so
+// strategies whose hedges cross markets should be pinned to one shard
+// (Count = 1) until open orders are shared between instances.
+//
+// Each instance only knows the open orders it placed, so actions on a
+// strategy's accounts are split by market rather than run once: on a window
+// close every instance cancels the orders it tracks and closes the positions
+// on its own markets. Limits are kept engine-wide: fill costs and daily losses
+// come from the shared journal, a strategy's max_orders_per_minute is divided
+// between the instances (Share), and each instance keeps its open orders
+// within its share of a group's remaining exposure. A throttle's
+// loss_cooldown only follows the losses an instance sees.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// Enabled reports whether events are split between instances
+func (s Shard) Enabled() bool {
+	return s.Count > 1
+}
+
+// Primary reports whether this instance runs engine-wide housekeeping
+func (s Shard) Primary() bool {
+	return !s.Enabled() || s.Index == 0
+}
+
+// Owns reports whether the event belongs to this instance
+func (s Shard) Owns(event types.Event) bool {
+	if !s.Enabled() {
+		return true
+	}
+
+	marketID, _ := event.Data["market_id"].(string)
+	return s.OwnsMarket(marketID)
+}
+
+// OwnsMarket reports whether the market belongs to this instance; the empty
+// market ID belongs to shard 0
+func (s Shard) OwnsMarket(marketID string) bool {
+	if !s.Enabled() {
+		return true
+	}
+	if marketID == "" {
+		return s.Index == 0
+	}
+	return ShardFor(marketID, s.Count) == s.Index
+}
+
+// Share returns this instance's part of an engine-wide count limit: the limit
+// divided by Count, rounded down but at least 1
+func (s Shard) Share(limit int) int {
+	if !s.Enabled() || limit <= 0 {
+		return limit
+	}
+	return max(1, limit/s.Count)
+}
+
+// ShareOf returns this instance's part of an engine-wide amount limit: the
+// amount divided by Count
+func (s Shard) ShareOf(amount float64) float64 {
+	if !s.Enabled() || amount <= 0 {
+		return amount
+	}
+	return amount / float64(s.Count)
+}
+
+// ShardFor returns the shard a market belongs to out of count shards
+func ShardFor(marketID string, count int) int {
+	h := fnv.New32a()
+	h.Write([]byte(marketID))
+	return int(h.Sum32() % uint32(count))
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orders"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// marketsOfShards returns a market ID of each of count shards
+func marketsOfShards(count int) []string {
+	markets := make([]string, count)
+	found := 0
+	for i := 0; found < count; i++ {
+		market := fmt.Sprintf("m%d", i)
+		if shard := ShardFor(market, count); markets[shard] == "" {
+			markets[shard] = market
+			found++
+		}
+	}
+	return markets
+}
+
+func TestShardOwnsMarket(t *testing.T) {
+	markets := marketsOfShards(3)
+	for index := 0; index < 3; index++ {
+		shard := Shard{Index: index, Count: 3}
+		for owner, market := range markets {
+			if got := shard.OwnsMarket(market); got != (owner == index) {
+				t.Errorf("shard %d owns %s: %v", index, market, got)
+			}
+		}
+		if got := shard.OwnsMarket(""); got != (index == 0) {
+			t.Errorf("shard %d owns the empty market: %v", index, got)
+		}
+	}
+
+	if !(Shard{}).OwnsMarket(markets[1]) {
+		t.Error("an unsharded instance owns every market")
+	}
+}
+
+func TestShardShare(t *testing.T) {
+	shard := Shard{Index: 1, Count: 4}
+	if got := shard.Share(10); got != 2 {
+		t.Errorf("Share(10) = %d, want 2", got)
+	}
+	if got := shard.Share(2); got != 1 {
+		t.Errorf("Share(2) = %d, want 1", got)
+	}
+	if got := shard.ShareOf(100); got != 25 {
+		t.Errorf("ShareOf(100) = %v, want 25", got)
+	}
+	if got := (Shard{}).ShareOf(100); got != 100 {
+		t.Errorf("unsharded ShareOf(100) = %v, want 100", got)
+	}
+}
+
+func TestGroupExposureLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		shard  Shard
+		filled float64
+		want   float64
+	}{
+		{"unsharded", Shard{}, 400, 1000},
+		{"share of what the fills leave", Shard{Index: 1, Count: 4}, 400, 550},
+		{"fills over the limit", Shard{Index: 1, Count: 4}, 1200, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{opts: Options{Shard: tt.shard}}
+			if got := e.groupExposureLimit(1000, tt.filled); got != tt.want {
+				t.Errorf("limit %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowCloseCancels(t *testing.T) {
+	markets := marketsOfShards(2)
+	exec, err := executor.NewExecutor(map[string]executor.Platform{
+		"predict":    {URL: "http://predict-account", Cancels: true},
+		"polymarket": {URL: "http://polymarket-account"},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := orders.NewTracker()
+	for i, order := range []orders.Order{
+		{Platform: "predict", AccountID: "a", MarketID: markets[0], Strategy: "s"},
+		{Platform: "predict", AccountID: "a", MarketID: markets[1], Strategy: "s"},
+		{Platform: "predict", AccountID: "a", MarketID: markets[0], Strategy: "other"},
+		{Platform: "polymarket", AccountID: "a", MarketID: markets[0], Strategy: "s"},
+	} {
+		order.OrderID = fmt.Sprintf("o%d", i)
+		order.Price = 0.5
+		order.Shares = 10
+		tracker.Add(order)
+	}
+
+	tests := []struct {
+		name     string
+		platform string
+		shard    Shard
+		want     []string
+	}{
+		{"unsharded", "predict", Shard{}, []string{"o0", "o1"}},
+		{"shard 0", "predict", Shard{Index: 0, Count: 2}, []string{"o0"}},
+		{"shard 1", "predict", Shard{Index: 1, Count: 2}, []string{"o1"}},
+		{"platform cannot cancel", "polymarket", Shard{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{executor: exec, orders: tracker, opts: Options{Shard: tt.shard}}
+			strategy := types.Strategy{
+				Name:           "s",
+				ActiveAccounts: []string{"a"},
+				Config:         map[string]interface{}{"target_platform": tt.platform},
+			}
+
+			var got []string
+			for _, cancel := range e.windowCloseCancels(strategy) {
+				if cancel.Type != "cancel_order" {
+					t.Errorf("cancel of type %s", cancel.Type)
+				}
+				orderID, _ := cancel.Metadata["order_id"].(string)
+				got = append(got, orderID)
+			}
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("cancelled %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
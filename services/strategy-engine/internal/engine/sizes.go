@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// convertSizes turns place_orders sized in contracts or USD into shares of
+// their platform before any limit sees them; orders that cannot be converted
+// are dropped
+func (e *Engine) convertSizes(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	converted := commands[:0]
+	for _, cmd := range commands {
+		result, err := e.executor.ConvertSize(cmd)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Str("market", cmd.MarketID).
+				Msg("Command dropped for invalid size")
+			e.publishStrategyError(ctx, strategy, event, ErrorClassSize, err, &cmd)
+			continue
+		}
+		converted = append(converted, result)
+	}
+	return converted
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Stats is a point-in-time view of what the engine has done since it started,
+// served by GET /stats. Counters live in memory and restart at zero.
+type Stats struct {
+	TakenAt       time.Time                            `json:"taken_at"`
+	StartedAt     time.Time                            `json:"started_at"`
+	UptimeSeconds float64                              `json:"uptime_seconds"`
+	Events        map[string]EventStats                `json:"events"`     // by event type
+	Duplicates    int64                                `json:"duplicates"` // events skipped by deduplication
+	Strategies    map[string]types.StrategyAttribution `json:"strategies"` // handler counters by strategy name
+	Streams       []StreamStats                        `json:"streams"`
+	Orders        map[string]int64                     `json:"orders"` // executor outcomes by journal status
+	Halted        bool                                 `json:"halted"`
+	HaltReason    string                               `json:"halt_reason,omitempty"`
+	HaltedAt      *time.Time                           `json:"halted_at,omitempty"`
+	Paused        []string                             `json:"paused"`     // active strategies outside their trading window
+	Suspended     map[string]time.Time                 `json:"suspended"`  // end of the rejection backoff, by strategy name
+	WarmingUp     map[string]time.Time                 `json:"warming_up"` // end of the warm-up, by strategy name
+	Pipeline      []string                             `json:"pipeline"`   // command middlewares, in order
+
+	// HedgeLatency is the time from a fill to its hedge being accepted, by
+	// strategy name
+	HedgeLatency map[string]executor.LatencyHistogram `json:"hedge_latency"`
+
+	// EventBus is the state of the connection to the event bus
+	EventBus eventbus.Health `json:"event_bus"`
+}
+
+// EventStats counts the events of one type the engine received
+type EventStats struct {
+	Count  int64     `json:"count"`
+	LastAt time.Time `json:"last_at"` // timestamp of the latest one
+}
+
+// StreamStats is the position of the engine on one event stream. LastEventAt
+// is when the last entry delivered to the engine was published, read from
+// its stream ID; nil if none was delivered since the engine subscribed.
+type StreamStats struct {
+	Stream      string     `json:"stream"`
+	LastEventAt *time.Time `json:"last_event_at"`
+	LagSeconds  float64    `json:"lag_seconds"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type eventCounters struct {
+	mu         sync.Mutex
+	startedAt  time.Time
+	events     map[string]*EventStats
+	duplicates int64
+}
+
+func newEventCounters() *eventCounters {
+	return &eventCounters{startedAt: time.Now().UTC(), events: make(map[string]*EventStats)}
+}
+
+// record counts an event received from a stream
+func (c *eventCounters) record(event types.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, ok := c.events[event.Type]
+	if !ok {
+		stats = &EventStats{}
+		c.events[event.Type] = stats
+	}
+	stats.Count++
+	if event.Timestamp.After(stats.LastAt) {
+		stats.LastAt = event.Timestamp
+	}
+}
+
+func (c *eventCounters) recordDuplicate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.duplicates++
+}
+
+// Stats takes a statistics snapshot. Stream positions are read from the event
+// bus on every call.
+func (e *Engine) Stats(ctx context.Context) Stats {
+	now := time.Now().UTC()
+
+	e.events.mu.Lock()
+	stats := Stats{
+		TakenAt:       now,
+		StartedAt:     e.events.startedAt,
+		UptimeSeconds: now.Sub(e.events.startedAt).Seconds(),
+		Events:        make(map[string]EventStats, len(e.events.events)),
+		Duplicates:    e.events.duplicates,
+	}
+	for eventType, counts := range e.events.events {
+		stats.Events[eventType] = *counts
+	}
+	e.events.mu.Unlock()
+
+	stats.Strategies = e.counters.snapshot()
+	stats.Orders = e.executor.OrderCounts()
+	stats.HedgeLatency = e.executor.HedgeLatency()
+	stats.Pipeline = e.Pipeline()
+	stats.EventBus = e.EventBusHealth()
+
+	e.mu.RLock()
+	stats.Halted, stats.HaltReason = e.halted, e.haltReason
+	if e.halted {
+		haltedAt := e.haltedAt
+		stats.HaltedAt = &haltedAt
+	}
+	stats.Paused = []string{}
+	for _, strategy := range e.strategies {
+		if schedule := e.schedules[strategy.ID]; strategy.Active && schedule != nil && !schedule.IsOpen(now) {
+			stats.Paused = append(stats.Paused, strategy.Name)
+		}
+	}
+	e.mu.RUnlock()
+	sort.Strings(stats.Paused)
+	stats.Suspended = e.suspensions(now)
+	stats.WarmingUp = e.warmingUp(now)
+
+	for _, stream := range e.streams {
+		entry := StreamStats{Stream: stream}
+		lag, err := e.eventBus.Lag(ctx, stream)
+		if err != nil {
+			entry.Error = err.Error()
+			stats.Streams = append(stats.Streams, entry)
+			continue
+		}
+		entry.LagSeconds = lag.LagSeconds
+		if ms, _, found, ok := eventbus.ParseStreamID(lag.DeliveredID); ok && found {
+			at := time.UnixMilli(ms).UTC()
+			entry.LastEventAt = &at
+		}
+		stats.Streams = append(stats.Streams, entry)
+	}
+	sort.Slice(stats.Streams, func(i, j int) bool {
+		return stats.Streams[i].Stream < stats.Streams[j].Stream
+	})
+
+	return stats
+}
+
+// EventBusHealth returns the state of the engine's connection to the event
+// bus; events are not consumed while it is down
+func (e *Engine) EventBusHealth() eventbus.Health {
+	return e.eventBus.Health()
+}
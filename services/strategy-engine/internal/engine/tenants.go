@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Tenants are the desks sharing one engine. Every strategy belongs to one
+// (strategies.tenant, DefaultTenant if unset), and Options.Tenants lists the
+// accounts each desk owns:
+//
+//   - An event naming an account (or carrying "tenant") only reaches the
+//     strategies of the tenant owning it; market data reaches everyone.
+//   - A strategy may not place orders on another tenant's accounts.
+//   - A tenant's MaxExposure and MaxDailyLoss are enforced like a strategy
+//     group budget over all its strategies, under the group TenantGroup(name).
+//   - Commands, journaled orders and strategy errors carry the tenant.
+
+// DefaultTenant owns strategies and accounts not assigned to a tenant
+const DefaultTenant = "default"
+
+// Tenant is one desk's accounts and risk budget. Zero limits are off.
+type Tenant struct {
+	Accounts     []string
+	MaxExposure  float64
+	MaxDailyLoss float64
+}
+
+// TenantGroup names the implicit strategy group holding a tenant's strategies
+func TenantGroup(tenant string) string {
+	return "tenant:" + tenant
+}
+
+// strategyTenant returns the tenant a strategy belongs to
+func strategyTenant(strategy types.Strategy) string {
+	if strategy.Tenant == "" {
+		return DefaultTenant
+	}
+	return strategy.Tenant
+}
+
+// buildAccountTenants indexes the owning tenant by account ID
+func buildAccountTenants(tenants map[string]*Tenant) map[string]string {
+	owners := make(map[string]string)
+	for name, tenant := range tenants {
+		if tenant == nil {
+			continue
+		}
+		for _, accountID := range tenant.Accounts {
+			owners[accountID] = name
+		}
+	}
+	return owners
+}
+
+// AccountTenant returns the tenant owning an account, or "" if none is
+// configured
+func (e *Engine) AccountTenant(accountID string) string {
+	return e.accountTenant(accountID)
+}
+
+// accountTenant returns the tenant owning an account, or "" if none is configured
+func (e *Engine) accountTenant(accountID string) string {
+	if accountID == "" {
+		return ""
+	}
+	return e.accountTenants[accountID]
+}
+
+// eventTenant returns the tenant an event belongs to, or "" for events every
+// tenant may see
+func (e *Engine) eventTenant(event types.Event) string {
+	if tenant, _ := event.Data["tenant"].(string); tenant != "" {
+		return tenant
+	}
+	accountID, _ := event.Data["account_id"].(string)
+	if tenant := e.accountTenant(accountID); tenant != "" {
+		return tenant
+	}
+	accountName, _ := event.Data["account_name"].(string)
+	return e.accountTenant(accountName)
+}
+
+// tenantBudget returns the risk budget of a tenant, or nil
+func (e *Engine) tenantBudget(tenant string) *GroupBudget {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	t := e.opts.Tenants[tenant]
+	if t == nil || (t.MaxExposure <= 0 && t.MaxDailyLoss <= 0) {
+		return nil
+	}
+	return &GroupBudget{MaxExposure: t.MaxExposure, MaxDailyLoss: t.MaxDailyLoss}
+}
+
+// applyTenant tags commands with the strategy's tenant and blocks those that
+// would act on another tenant's accounts, publishing a strategy_error for each
+func (e *Engine) applyTenant(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	tenant := strategyTenant(strategy)
+
+	allowed := commands[:0]
+	for _, cmd := range commands {
+		if owner := e.accountTenant(cmd.AccountID); owner != "" && owner != tenant {
+			err := fmt.Errorf("account %s belongs to tenant %s, not %s", cmd.AccountID, owner, tenant)
+			log.Warn().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Str("tenant", tenant).
+				Msg("Command blocked by tenant isolation")
+			e.publishStrategyError(ctx, strategy, event, ErrorClassTenant, err, &cmd)
+			continue
+		}
+
+		metadata := make(map[string]interface{}, len(cmd.Metadata)+1)
+		for k, v := range cmd.Metadata {
+			metadata[k] = v
+		}
+		metadata["tenant"] = tenant
+		cmd.Metadata = metadata
+		allowed = append(allowed, cmd)
+	}
+	return allowed
+}
+
+// StrategyTenant returns the tenant of the named strategy
+func (e *Engine) StrategyTenant(ctx context.Context, name string) (string, error) {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return strategyTenant(*strategy), nil
+}
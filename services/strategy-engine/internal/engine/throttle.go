@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Throttle limits how fast a strategy can place orders, so a feedback loop
+// (our hedge fill triggering another hedge) cannot run away.
+//
+// Configured under the "throttle" key of the strategy config:
+//
+//	"throttle": {
+//	  "max_orders_per_minute": 30,  // rolling 60s window
+//	  "market_interval": 10,        // seconds between orders on the same market
+//	  "loss_cooldown": 300,         // seconds without new orders after a loss
+//	  "loss_threshold": 25          // only losses of at least this size (default: any)
+//	}
+//
+// Only place_order commands are throttled; cancels and flattens always pass.
+// A loss event is realized PnL below zero attributed to the strategy.
+type Throttle struct {
+	MaxOrdersPerMinute int
+	MarketInterval     time.Duration
+	LossCooldown       time.Duration
+	LossThreshold      float64
+}
+
+// ParseThrottle reads the "throttle" key of a strategy config.
+// It returns nil if the strategy has no throttle.
+func ParseThrottle(config map[string]interface{}) (*Throttle, error) {
+	raw, ok := config["throttle"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var t Throttle
+	number := func(key string) (float64, error) {
+		v, set := raw[key]
+		if !set {
+			return 0, nil
+		}
+		f, ok := v.(float64)
+		if !ok || f < 0 {
+			return 0, fmt.Errorf("throttle %s must be a non-negative number", key)
+		}
+		return f, nil
+	}
+
+	perMinute, err := number("max_orders_per_minute")
+	if err != nil {
+		return nil, err
+	}
+	interval, err := number("market_interval")
+	if err != nil {
+		return nil, err
+	}
+	cooldown, err := number("loss_cooldown")
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := number("loss_threshold")
+	if err != nil {
+		return nil, err
+	}
+
+	t.MaxOrdersPerMinute = int(perMinute)
+	t.MarketInterval = time.Duration(interval * float64(time.Second))
+	t.LossCooldown = time.Duration(cooldown * float64(time.Second))
+	t.LossThreshold = threshold
+	return &t, nil
+}
+
+// throttleState remembers recent orders and losses per strategy name
+type throttleState struct {
+	mu         sync.Mutex
+	strategies map[string]*strategyThrottle
+}
+
+type strategyThrottle struct {
+	orders      []time.Time          // place_order times within the last minute
+	markets     map[string]time.Time // last order per market
+	lastLoss    time.Time
+	lastLossPnL float64
+}
+
+func newThrottleState() *throttleState {
+	return &throttleState{strategies: make(map[string]*strategyThrottle)}
+}
+
+func (s *throttleState) get(strategy string) *strategyThrottle {
+	st, ok := s.strategies[strategy]
+	if !ok {
+		st = &strategyThrottle{markets: make(map[string]time.Time)}
+		s.strategies[strategy] = st
+	}
+	return st
+}
+
+// recordLoss notes a realized loss for the cooldown
+func (s *throttleState) recordLoss(strategy string, pnl float64, at time.Time) {
+	if pnl >= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.get(strategy)
+	st.lastLoss = at
+	st.lastLossPnL = pnl
+}
+
+// allow checks one place_order against the throttle and, if allowed, counts it
+func (s *throttleState) allow(strategy string, t *Throttle, cmd types.Command, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.get(strategy)
+
+	if t.LossCooldown > 0 && !st.lastLoss.IsZero() && -st.lastLossPnL >= t.LossThreshold {
+		if until := st.lastLoss.Add(t.LossCooldown); now.Before(until) {
+			return fmt.Errorf("in cooldown until %s after a loss of %.2f", until.Format(time.RFC3339), st.lastLossPnL)
+		}
+	}
+
+	if t.MarketInterval > 0 {
+		// Orders from the same handler call share now and are not held apart
+		if last, ok := st.markets[cmd.MarketID]; ok && last.Before(now) && now.Sub(last) < t.MarketInterval {
+			return fmt.Errorf("last order on market %s was %s ago (minimum %s)",
+				cmd.MarketID, now.Sub(last).Round(time.Millisecond), t.MarketInterval)
+		}
+	}
+
+	recent := st.orders[:0]
+	for _, at := range st.orders {
+		if now.Sub(at) < time.Minute {
+			recent = append(recent, at)
+		}
+	}
+	st.orders = recent
+
+	if t.MaxOrdersPerMinute > 0 && len(st.orders) >= t.MaxOrdersPerMinute {
+		return fmt.Errorf("%d orders in the last minute (limit %d)", len(st.orders), t.MaxOrdersPerMinute)
+	}
+
+	st.orders = append(st.orders, now)
+	st.markets[cmd.MarketID] = now
+	return nil
+}
+
+// applyThrottle drops place_order commands that exceed the strategy's
+// throttle, publishing a strategy_error for each
+func (e *Engine) applyThrottle(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	e.mu.RLock()
+	throttle := e.throttles[strategy.ID]
+	e.mu.RUnlock()
+
+	if throttle == nil {
+		return commands
+	}
+
+	now := time.Now()
+	allowed := commands[:0]
+	for _, cmd := range commands {
+		if cmd.PlacesOrder() {
+			if err := e.throttle.allow(strategy.Name, throttle, cmd, now); err != nil {
+				log.Warn().
+					Err(err).
+					Str("strategy", strategy.Name).
+					Str("market", cmd.MarketID).
+					Msg("Order blocked by strategy throttle")
+				e.publishStrategyError(ctx, strategy, event, ErrorClassThrottle, err, &cmd)
+				continue
+			}
+		}
+		allowed = append(allowed, cmd)
+	}
+	return allowed
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Ticks drive strategies on a timer rather than on events, for work such as
+// periodic rebalancing or cancelling stale quotes.
+//
+// Configured with the "tick_interval" key of the strategy config, in seconds:
+//
+//	"tick_interval": 300
+//
+// Every interval the strategy's worker runs its OnTick handler (registered
+// with RegisterTickHandler for the strategy type) with a synthetic EventTick
+// event. Ticks go through the same pipeline as events: trading windows,
+// throttles, budgets, the kill switch and the outbox all apply. Only the
+// primary shard ticks, so sharded engines do not act twice.
+
+// EventTick is the type of the synthetic events sent to OnTick handlers
+const EventTick = "tick"
+
+// tickCheckInterval is the resolution of tick intervals
+const tickCheckInterval = time.Second
+
+// ParseTickInterval reads the "tick_interval" key of a strategy config.
+// It returns zero if the strategy does not tick.
+func ParseTickInterval(config map[string]interface{}) (time.Duration, error) {
+	raw, set := config["tick_interval"]
+	if !set {
+		return 0, nil
+	}
+	seconds, ok := raw.(float64)
+	if !ok || seconds < 0 {
+		return 0, fmt.Errorf("tick_interval must be a non-negative number of seconds")
+	}
+
+	interval := time.Duration(seconds * float64(time.Second))
+	if interval != 0 && interval < tickCheckInterval {
+		return 0, fmt.Errorf("tick_interval must be at least %s", tickCheckInterval)
+	}
+	return interval, nil
+}
+
+// RegisterTickHandler registers the OnTick handler of a strategy type
+func (e *Engine) RegisterTickHandler(name string, handler types.StrategyHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.tickHandlers[name] = handler
+	log.Info().Str("strategy", name).Msg("Registered strategy tick handler")
+}
+
+// runTicks dispatches tick events to strategies whose tick interval has elapsed
+func (e *Engine) runTicks(ctx context.Context) {
+	ticker := time.NewTicker(tickCheckInterval)
+	defer ticker.Stop()
+
+	next := make(map[string]time.Time) // by strategy ID
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if halted, _ := e.Halted(); halted {
+				continue
+			}
+
+			e.mu.RLock()
+			strategies := e.strategies
+			intervals := e.tickIntervals
+			e.mu.RUnlock()
+
+			due := make(map[string]time.Time, len(intervals))
+			for _, strategy := range strategies {
+				interval := intervals[strategy.ID]
+				if interval <= 0 || !strategy.Active {
+					continue
+				}
+
+				// The first tick comes one interval after the strategy is loaded
+				at, ok := next[strategy.ID]
+				if !ok {
+					due[strategy.ID] = now.Add(interval)
+					continue
+				}
+				if now.Before(at) {
+					due[strategy.ID] = at
+					continue
+				}
+
+				due[strategy.ID] = now.Add(interval)
+				e.dispatch(ctx, strategy, tickEvent(strategy, interval, now))
+			}
+			// Strategies no longer loaded or ticking are forgotten
+			next = due
+		}
+	}
+}
+
+func tickEvent(strategy types.Strategy, interval time.Duration, now time.Time) types.Event {
+	return types.Event{
+		ID:        fmt.Sprintf("tick-%s-%d", strategy.ID, now.UnixNano()),
+		Type:      EventTick,
+		Timestamp: now.UTC(),
+		Data: map[string]interface{}{
+			"strategy": strategy.Name,
+			"interval": interval.Seconds(),
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Handlers take no context, so a handler blocked on e.g. an HTTP call cannot
+// be interrupted. Instead each call runs on its own goroutine and the worker
+// stops waiting after the handler timeout: the event counts as a timeout,
+// whatever the call returns later is discarded, and the worker moves on to the
+// next event. At most maxAbandonedCalls timed-out calls per strategy may still
+// be running; further events are skipped until one returns, so a handler that
+// hangs for good cannot pile up goroutines.
+//
+// The timeout is Options.HandlerTimeout, overridable per strategy with config
+// "handler_timeout" in seconds. Zero calls handlers directly.
+
+const maxAbandonedCalls = 4
+
+// ErrHandlerTimeout is returned when a handler does not return in time
+var ErrHandlerTimeout = errors.New("handler timed out")
+
+// handlerPanic carries a panic from the handler goroutine to the worker, with
+// the stack of the goroutine where it happened
+type handlerPanic struct {
+	value interface{}
+	stack string
+}
+
+type handlerResult struct {
+	commands []types.Command
+	err      error
+	panic    *handlerPanic
+}
+
+// abandonedCalls counts timed-out handler calls still running, per strategy ID
+type abandonedCalls struct {
+	mu      sync.Mutex
+	running map[string]int
+}
+
+func newAbandonedCalls() *abandonedCalls {
+	return &abandonedCalls{running: make(map[string]int)}
+}
+
+func (a *abandonedCalls) count(strategyID string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.running[strategyID]
+}
+
+func (a *abandonedCalls) add(strategyID string, delta int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.running[strategyID] += delta
+	if a.running[strategyID] <= 0 {
+		delete(a.running, strategyID)
+	}
+}
+
+// callHandler runs the handler with the strategy's timeout. A panic in the
+// handler is re-raised on the calling goroutine as a *handlerPanic.
+func (e *Engine) callHandler(ctx context.Context, handler types.StrategyHandler, strategy types.Strategy, event types.Event) ([]types.Command, error) {
+	timeout := e.handlerTimeout(strategy)
+	if timeout <= 0 {
+		return handler(event, strategy)
+	}
+
+	if n := e.abandoned.count(strategy.ID); n >= maxAbandonedCalls {
+		return nil, fmt.Errorf("%w: %d earlier calls are still running", ErrHandlerTimeout, n)
+	}
+
+	done := make(chan handlerResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- handlerResult{panic: &handlerPanic{value: r, stack: string(debug.Stack())}}
+			}
+		}()
+		commands, err := handler(event, strategy)
+		done <- handlerResult{commands: commands, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		if result.panic != nil {
+			panic(result.panic)
+		}
+		return result.commands, result.err
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	e.abandoned.add(strategy.ID, 1)
+	go func() {
+		result := <-done
+		e.abandoned.add(strategy.ID, -1)
+
+		entry := log.Warn().Str("strategy", strategy.Name).Str("event_id", event.ID)
+		if result.panic != nil {
+			entry = entry.Interface("panic", result.panic.value)
+		}
+		entry.Err(result.err).
+			Int("discarded_commands", len(result.commands)).
+			Msg("Timed-out strategy handler returned, result discarded")
+	}()
+
+	return nil, fmt.Errorf("%w after %s", ErrHandlerTimeout, timeout)
+}
+
+func (e *Engine) handlerTimeout(strategy types.Strategy) time.Duration {
+	if seconds, ok := strategy.Config["handler_timeout"].(float64); ok && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.opts.HandlerTimeout
+}
+
+// SetHandlerTimeout changes the default handler timeout at runtime; zero disables it
+func (e *Engine) SetHandlerTimeout(timeout time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.opts.HandlerTimeout = timeout
+}
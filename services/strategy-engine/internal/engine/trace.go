@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// TraceStream receives an engine_trace event for every consumed event when
+// Options.Trace is set, so analytics jobs can reconstruct offline what the
+// engine did with it. Its data is
+//
+//	"event":       the consumed event (id, type, platform, timestamp, data)
+//	"strategies":  names of the strategies it was handed to
+//	"decisions":   what each of them decided (see types.Decision), once all are done
+//	"commands":    the number of commands they produced
+//	"skipped":     why no strategy saw it: "duplicate" or "halted"
+//	"duration_ms": from dispatch until the last strategy was done
+//
+// Order book updates only refresh the book cache and are not traced; they
+// are in their own stream. Ticks and rejection follow-ups are local to the
+// engine and not traced either.
+const TraceStream = "engine_trace"
+
+// eventTrace collects the decisions on one event until every strategy it was
+// handed to is done with it
+type eventTrace struct {
+	ctx        context.Context
+	event      types.Event
+	strategies []string
+	started    time.Time
+
+	mu        sync.Mutex
+	decisions []types.Decision
+	pending   int
+}
+
+// traces are the events being traced, by event ID
+type traces struct {
+	mu     sync.Mutex
+	byID   map[string]*eventTrace
+	engine *Engine
+}
+
+func newTraces(e *Engine) *traces {
+	return &traces{byID: make(map[string]*eventTrace), engine: e}
+}
+
+// start traces an event handed to receivers and returns the callback each of
+// their jobs calls when done. Without receivers the trace is published at once.
+func (t *traces) start(ctx context.Context, event types.Event, receivers []types.Strategy) func() {
+	trace := &eventTrace{ctx: ctx, event: event, started: time.Now(), pending: len(receivers)}
+	for _, strategy := range receivers {
+		trace.strategies = append(trace.strategies, strategy.Name)
+	}
+	if len(receivers) == 0 {
+		t.engine.publishTrace(trace, "")
+		return nil
+	}
+
+	t.mu.Lock()
+	t.byID[event.ID] = trace
+	t.mu.Unlock()
+
+	return func() {
+		trace.mu.Lock()
+		trace.pending--
+		finished := trace.pending == 0
+		trace.mu.Unlock()
+		if !finished {
+			return
+		}
+
+		t.mu.Lock()
+		delete(t.byID, event.ID)
+		t.mu.Unlock()
+		t.engine.publishTrace(trace, "")
+	}
+}
+
+// add records a decision on a traced event
+func (t *traces) add(decision types.Decision) {
+	t.mu.Lock()
+	trace, ok := t.byID[decision.EventID]
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	trace.mu.Lock()
+	trace.decisions = append(trace.decisions, decision)
+	trace.mu.Unlock()
+}
+
+// traceSkipped publishes the trace of an event no strategy saw
+func (e *Engine) traceSkipped(ctx context.Context, event types.Event, reason string) {
+	if e.traces == nil {
+		return
+	}
+	e.publishTrace(&eventTrace{ctx: ctx, event: event, started: time.Now()}, reason)
+}
+
+// publishTrace publishes the engine_trace event of a finished trace
+func (e *Engine) publishTrace(trace *eventTrace, skipped string) {
+	trace.mu.Lock()
+	decisions := trace.decisions
+	trace.mu.Unlock()
+
+	commands := 0
+	for _, decision := range decisions {
+		if decision.ErrorClass == "" {
+			commands += len(decision.Commands)
+		}
+	}
+
+	strategies := trace.strategies
+	if strategies == nil {
+		strategies = []string{}
+	}
+	data := map[string]interface{}{
+		"event":       trace.event,
+		"strategies":  strategies,
+		"decisions":   decisions,
+		"commands":    commands,
+		"duration_ms": time.Since(trace.started).Milliseconds(),
+	}
+	if skipped != "" {
+		data["skipped"] = skipped
+	}
+
+	now := time.Now().UTC()
+	err := e.eventBus.Publish(trace.ctx, TraceStream, types.Event{
+		ID:        trace.event.ID,
+		Type:      "engine_trace",
+		Platform:  trace.event.Platform,
+		Timestamp: now,
+		Data:      data,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("event_id", trace.event.ID).Msg("Failed to publish engine trace")
+	}
+}
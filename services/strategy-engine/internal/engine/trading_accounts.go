@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// The trading account registry lists the accounts the engine may place orders
+// on, with their limits, so a typo or a stale account in a strategy's config
+// does not reach an account service. It is kept in the trading_accounts
+// table, edited through the admin API and, like market mappings, picked up
+// from other instances on the next strategy refresh. The "accounts" stage of
+// the pipeline drops place_orders
+//
+//   - on disabled accounts,
+//   - above the account's MaxOrderShares,
+//   - on markets outside its AllowedMarkets,
+//   - on accounts missing from the registry, with
+//     Options.RequireRegisteredAccounts.
+//
+// Cancels always pass, so orders on a disabled account can still be pulled.
+
+// ErrInvalidAccount is returned for trading accounts missing their platform
+// or ID, or with a negative size limit
+var ErrInvalidAccount = errors.New("trading account needs a platform, an account ID and a max order size of zero or more")
+
+// accountRegistry is the in-memory copy of the trading_accounts table
+type accountRegistry struct {
+	mu       sync.RWMutex
+	accounts map[string]types.TradingAccount // by platform and account ID
+}
+
+func newAccountRegistry() *accountRegistry {
+	return &accountRegistry{accounts: make(map[string]types.TradingAccount)}
+}
+
+func accountKey(platform, accountID string) string {
+	return platform + "\x00" + accountID
+}
+
+func (r *accountRegistry) set(accounts []types.TradingAccount) {
+	indexed := make(map[string]types.TradingAccount, len(accounts))
+	for _, a := range accounts {
+		indexed[accountKey(a.Platform, a.AccountID)] = a
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.accounts = indexed
+}
+
+func (r *accountRegistry) put(a types.TradingAccount) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.accounts[accountKey(a.Platform, a.AccountID)] = a
+}
+
+func (r *accountRegistry) remove(platform, accountID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.accounts, accountKey(platform, accountID))
+}
+
+func (r *accountRegistry) get(platform, accountID string) (types.TradingAccount, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.accounts[accountKey(platform, accountID)]
+	return a, ok
+}
+
+func (r *accountRegistry) all() []types.TradingAccount {
+	r.mu.RLock()
+	accounts := make([]types.TradingAccount, 0, len(r.accounts))
+	for _, a := range r.accounts {
+		accounts = append(accounts, a)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(accounts, func(i, j int) bool {
+		if accounts[i].Platform != accounts[j].Platform {
+			return accounts[i].Platform < accounts[j].Platform
+		}
+		return accounts[i].AccountID < accounts[j].AccountID
+	})
+	return accounts
+}
+
+// loadTradingAccounts replaces the registry with the stored accounts
+func (e *Engine) loadTradingAccounts(ctx context.Context) error {
+	accounts, err := e.storage.GetTradingAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	e.accounts.set(accounts)
+	return nil
+}
+
+// TradingAccounts returns every registered trading account
+func (e *Engine) TradingAccounts() []types.TradingAccount {
+	return e.accounts.all()
+}
+
+// PutTradingAccount registers a trading account or replaces its settings
+func (e *Engine) PutTradingAccount(ctx context.Context, account types.TradingAccount) (types.TradingAccount, error) {
+	if err := validateTradingAccount(&account); err != nil {
+		return account, err
+	}
+	stored, err := e.storage.PutTradingAccount(ctx, account)
+	if err != nil {
+		return stored, err
+	}
+	e.accounts.put(stored)
+
+	log.Info().
+		Str("platform", stored.Platform).
+		Str("account_id", stored.AccountID).
+		Bool("enabled", stored.Enabled).
+		Float64("max_order_shares", stored.MaxOrderShares).
+		Strs("allowed_markets", stored.AllowedMarkets).
+		Str("operator", stored.UpdatedBy).
+		Msg("Trading account saved")
+	return stored, nil
+}
+
+// DeleteTradingAccount removes a trading account from the registry
+func (e *Engine) DeleteTradingAccount(ctx context.Context, platform, accountID string) error {
+	if err := e.storage.DeleteTradingAccount(ctx, platform, accountID); err != nil {
+		return err
+	}
+	e.accounts.remove(platform, accountID)
+
+	log.Info().Str("platform", platform).Str("account_id", accountID).Msg("Trading account deleted")
+	return nil
+}
+
+func validateTradingAccount(account *types.TradingAccount) error {
+	account.Platform = strings.TrimSpace(account.Platform)
+	account.AccountID = strings.TrimSpace(account.AccountID)
+	if account.Platform == "" || account.AccountID == "" {
+		return ErrInvalidAccount
+	}
+	if account.MaxOrderShares < 0 || math.IsNaN(account.MaxOrderShares) || math.IsInf(account.MaxOrderShares, 0) {
+		return ErrInvalidAccount
+	}
+
+	var markets []string
+	for _, market := range account.AllowedMarkets {
+		if market = strings.TrimSpace(market); market != "" {
+			markets = append(markets, market)
+		}
+	}
+	account.AllowedMarkets = markets
+	return nil
+}
+
+// checkAccounts drops the place_orders the registry does not allow,
+// publishing a strategy_error for each
+func (e *Engine) checkAccounts(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	allowed := commands[:0]
+	for _, cmd := range commands {
+		if !cmd.PlacesOrder() {
+			allowed = append(allowed, cmd)
+			continue
+		}
+		if err := e.checkAccount(cmd); err != nil {
+			log.Warn().
+				Err(err).
+				Str("strategy", strategy.Name).
+				Str("market", cmd.MarketID).
+				Msg("Command dropped by account check")
+			e.publishStrategyError(ctx, strategy, event, ErrorClassAccount, err, &cmd)
+			continue
+		}
+		allowed = append(allowed, cmd)
+	}
+	return allowed
+}
+
+// checkAccount checks one place_order against its account's registry entry
+func (e *Engine) checkAccount(cmd types.Command) error {
+	account, ok := e.accounts.get(cmd.Platform, cmd.AccountID)
+	if !ok {
+		if e.opts.RequireRegisteredAccounts {
+			return fmt.Errorf("account %s is not registered on %s", cmd.AccountID, cmd.Platform)
+		}
+		return nil
+	}
+	if !account.Enabled {
+		return fmt.Errorf("account %s on %s is disabled", cmd.AccountID, cmd.Platform)
+	}
+	if account.MaxOrderShares > 0 && cmd.Shares > account.MaxOrderShares {
+		return fmt.Errorf("%.2f shares exceed the %.2f limit of account %s", cmd.Shares, account.MaxOrderShares, cmd.AccountID)
+	}
+	if len(account.AllowedMarkets) > 0 {
+		for _, market := range account.AllowedMarkets {
+			if market == cmd.MarketID {
+				return nil
+			}
+		}
+		return fmt.Errorf("market %s is not allowed on account %s", cmd.MarketID, cmd.AccountID)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// A strategy can have candidate config versions that run in shadow mode next
+// to the live config. Each candidate is loaded as its own strategy named
+// "<name>@v<version>" with shadow set: its commands are journaled with status
+// "shadow" and its counters appear under that name in attribution, but nothing
+// is executed. Promoting copies the candidate config into the live strategy;
+// rejecting drops it. Strategies are re-read periodically, so every instance
+// picks up versions proposed or promoted through another one.
+
+// strategyRefreshInterval is how often strategies and candidates are re-read
+const strategyRefreshInterval = time.Minute
+
+var (
+	// ErrStrategyNotFound is returned for version operations on an unknown strategy
+	ErrStrategyNotFound = errors.New("strategy not found")
+
+	// ErrInvalidVersion is returned when a proposed config cannot run
+	ErrInvalidVersion = errors.New("invalid strategy version")
+)
+
+// CandidateName is the name a candidate version runs under
+func CandidateName(name string, version int) string {
+	return fmt.Sprintf("%s@v%d", name, version)
+}
+
+// loadStrategies reads the enabled strategies plus a shadow strategy for each
+// of their pending candidate versions
+func (e *Engine) loadStrategies(ctx context.Context) ([]types.Strategy, error) {
+	strategies, err := e.storage.GetActiveStrategies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := e.storage.GetCandidateVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load candidate versions: %w", err)
+	}
+
+	live := make(map[string]types.Strategy, len(strategies))
+	for _, strategy := range strategies {
+		live[strategy.ID] = strategy
+	}
+
+	for _, version := range candidates {
+		strategy, ok := live[version.StrategyID]
+		if !ok {
+			continue
+		}
+		strategies = append(strategies, candidateStrategy(strategy, version))
+	}
+
+	return strategies, nil
+}
+
+func candidateStrategy(live types.Strategy, version types.StrategyVersion) types.Strategy {
+	candidate := live
+	candidate.ID = fmt.Sprintf("%s@v%d", live.ID, version.Version)
+	candidate.Name = CandidateName(live.Name, version.Version)
+	candidate.Config = version.Config
+	candidate.Version = version.Version
+	candidate.Shadow = true
+	candidate.ShadowReason = fmt.Sprintf("candidate version %d of %s", version.Version, live.Name)
+	candidate.CandidateOf = live.ID
+	return candidate
+}
+
+// ReloadStrategies re-reads strategies and candidate versions from the database
+func (e *Engine) ReloadStrategies(ctx context.Context) error {
+	strategies, err := e.loadStrategies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load strategies: %w", err)
+	}
+	e.setStrategies(strategies)
+	return nil
+}
+
+func (e *Engine) runStrategyRefresh(ctx context.Context) {
+	ticker := time.NewTicker(strategyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.ReloadStrategies(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to refresh strategies")
+			}
+			// Mappings may have been changed through another instance
+			if err := e.loadMappings(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to refresh market mappings")
+			}
+			if err := e.loadTradingAccounts(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to refresh trading accounts")
+			}
+			if err := e.loadFeatureFlags(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to refresh feature flags")
+			}
+		}
+	}
+}
+
+// liveStrategy looks up a strategy by name in the database
+func (e *Engine) liveStrategy(ctx context.Context, name string) (*types.Strategy, error) {
+	strategy, err := e.storage.GetStrategyByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if strategy == nil {
+		return nil, fmt.Errorf("%w: %s", ErrStrategyNotFound, name)
+	}
+	return strategy, nil
+}
+
+// StrategyVersions returns a strategy and its stored versions, newest first
+func (e *Engine) StrategyVersions(ctx context.Context, name string) (*types.Strategy, []types.StrategyVersion, error) {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	versions, err := e.storage.GetStrategyVersions(ctx, strategy.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return strategy, versions, nil
+}
+
+// ProposeVersion stores config as a new candidate version of the named
+// strategy and starts running it in shadow mode
+func (e *Engine) ProposeVersion(ctx context.Context, name string, config map[string]interface{}, operator, note string) (types.StrategyVersion, error) {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return types.StrategyVersion{}, err
+	}
+
+	if err := e.validateConfig(strategy.Type, config); err != nil {
+		return types.StrategyVersion{}, err
+	}
+
+	version, err := e.storage.CreateStrategyVersion(ctx, strategy.ID, config, operator, note)
+	if err != nil {
+		return version, err
+	}
+
+	log.Info().
+		Str("strategy", strategy.Name).
+		Int("version", version.Version).
+		Str("operator", operator).
+		Msg("Candidate strategy version running in shadow mode")
+
+	if err := e.ReloadStrategies(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to reload strategies after proposing a version")
+	}
+
+	e.postVersionAlert(ctx, strategy, version.Version, "proposed", operator, note)
+	return version, nil
+}
+
+// validateConfig checks that a config of the given strategy type can run
+func (e *Engine) validateConfig(strategyType string, config map[string]interface{}) error {
+	e.mu.RLock()
+	_, handled := e.handlers[strategyType]
+	e.mu.RUnlock()
+	if !handled {
+		return fmt.Errorf("%w: no handler registered for strategy type %s", ErrInvalidVersion, strategyType)
+	}
+	if _, err := ParseSchedule(config); err != nil {
+		return fmt.Errorf("%w: invalid schedule: %v", ErrInvalidVersion, err)
+	}
+	if _, err := ParseThrottle(config); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidVersion, err)
+	}
+	if execution, ok := config["execution"].(map[string]interface{}); ok {
+		if t, set := execution["order_type"]; set {
+			if s, _ := t.(string); !executor.ValidOrderType(s) {
+				return fmt.Errorf("%w: unknown execution.order_type %v", ErrInvalidVersion, t)
+			}
+		}
+	}
+	if mode, set := config["self_trade_prevention"]; set {
+		if s, _ := mode.(string); !ValidSelfTradeMode(s) {
+			return fmt.Errorf("%w: unknown self_trade_prevention %v", ErrInvalidVersion, mode)
+		}
+	}
+	if warmup, set := config["warmup"]; set {
+		if seconds, ok := warmup.(float64); !ok || seconds < 0 {
+			return fmt.Errorf("%w: warmup must be a non-negative number of seconds", ErrInvalidVersion)
+		}
+	}
+	return nil
+}
+
+// PromoteVersion makes a candidate version the live config of the named strategy
+func (e *Engine) PromoteVersion(ctx context.Context, name string, version int, operator, reason string) error {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if err := e.storage.PromoteStrategyVersion(ctx, strategy.ID, version, operator); err != nil {
+		return err
+	}
+
+	log.Warn().
+		Str("strategy", strategy.Name).
+		Int("from_version", strategy.Version).
+		Int("to_version", version).
+		Str("operator", operator).
+		Str("reason", reason).
+		Msg("Strategy version promoted to live")
+
+	if err := e.ReloadStrategies(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to reload strategies after promotion")
+	}
+
+	e.postVersionAlert(ctx, strategy, version, "promoted", operator, reason)
+	return nil
+}
+
+// RejectVersion stops a candidate version and marks it rejected
+func (e *Engine) RejectVersion(ctx context.Context, name string, version int, operator, reason string) error {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if err := e.storage.RejectStrategyVersion(ctx, strategy.ID, version, operator); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("strategy", strategy.Name).
+		Int("version", version).
+		Str("operator", operator).
+		Msg("Candidate strategy version rejected")
+
+	if err := e.ReloadStrategies(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to reload strategies after rejection")
+	}
+
+	e.postVersionAlert(ctx, strategy, version, "rejected", operator, reason)
+	return nil
+}
+
+func (e *Engine) postVersionAlert(ctx context.Context, strategy *types.Strategy, version int, action, operator, reason string) {
+	message := fmt.Sprintf("Version %d of %s %s by %s", version, strategy.Name, action, operator)
+	if reason != "" {
+		message += ": " + reason
+	}
+
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		fmt.Sprintf("Strategy version %s", action),
+		message,
+		map[string]interface{}{
+			"strategy":     strategy.Name,
+			"strategy_id":  strategy.ID,
+			"version":      version,
+			"live_version": strategy.Version,
+			"operator":     operator,
+		},
+	); err != nil {
+		log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to create strategy version alert")
+	}
+}
+
+// StrategyRuns returns a strategy and its latest activation periods, newest
+// first
+func (e *Engine) StrategyRuns(ctx context.Context, name string, limit int) (*types.Strategy, []types.StrategyRun, error) {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runs, err := e.storage.GetStrategyRuns(ctx, strategy.ID, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return strategy, runs, nil
+}
+
+// StrategyRunAt returns the run of the named strategy that was live at the
+// given time, or nil if it was not enabled then
+func (e *Engine) StrategyRunAt(ctx context.Context, name string, at time.Time) (*types.StrategyRun, error) {
+	strategy, err := e.liveStrategy(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return e.storage.GetStrategyRunAt(ctx, strategy.ID, at)
+}
+
+// EncryptConfigValue encrypts a strategy config value with the active config
+// key; it returns secrets.ErrNoKeyring when no keys are configured
+func (e *Engine) EncryptConfigValue(value string) (string, error) {
+	return e.opts.ConfigKeys.Encrypt(value)
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Volatility guard: when a market's YES price moves by more than
+// Options.MaxPriceMove within Options.PriceMoveWindow, as seen in
+// market_update events, new opening orders in that market are blocked for
+// Options.PriceMoveCooldown, so strategies stand down while news moves the
+// market. The cooldown restarts while the market keeps moving. Cancels and
+// orders that sell or close a position still go through, and a single command
+// can bypass the guard with metadata "skip_volatility_guard": true.
+
+// priceSample is a market price seen at a point in time
+type priceSample struct {
+	at    time.Time
+	price float64
+}
+
+// volatilityGuard tracks recent prices and blocked markets
+type volatilityGuard struct {
+	mu      sync.Mutex
+	samples map[string][]priceSample // by market ID, oldest first
+	blocked map[string]time.Time     // market ID -> blocked until
+}
+
+func newVolatilityGuard() *volatilityGuard {
+	return &volatilityGuard{
+		samples: make(map[string][]priceSample),
+		blocked: make(map[string]time.Time),
+	}
+}
+
+// observe records a price and blocks the market if it moved more than maxMove
+// within window. It returns the move and whether it blocked the market.
+func (g *volatilityGuard) observe(marketID string, price float64, now time.Time, maxMove float64, window, cooldown time.Duration) (float64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	samples := g.samples[marketID]
+	cutoff := now.Add(-window)
+	kept := samples[:0]
+	for _, s := range samples {
+		if s.at.After(cutoff) {
+			kept = append(kept, s)
+		}
+	}
+	kept = append(kept, priceSample{at: now, price: price})
+	g.samples[marketID] = kept
+
+	low, high := price, price
+	for _, s := range kept {
+		low, high = min(low, s.price), max(high, s.price)
+	}
+	move := high - low
+	if move <= maxMove {
+		return move, false
+	}
+
+	g.blocked[marketID] = now.Add(cooldown)
+	return move, true
+}
+
+// blockedUntil returns when a blocked market opens again
+func (g *volatilityGuard) blockedUntil(marketID string, now time.Time) (time.Time, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	until, ok := g.blocked[marketID]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !now.Before(until) {
+		delete(g.blocked, marketID)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// observePrice feeds the guard from a market_update event
+func (e *Engine) observePrice(event types.Event) {
+	if event.Type != "market_update" || e.opts.MaxPriceMove <= 0 {
+		return
+	}
+	marketID, _ := event.Data["market_id"].(string)
+	price, ok := event.Data["yes_price"].(float64)
+	if marketID == "" || !ok {
+		return
+	}
+
+	_, wasBlocked := e.volatility.blockedUntil(marketID, time.Now())
+	move, blocked := e.volatility.observe(marketID, price, time.Now(), e.opts.MaxPriceMove, e.opts.PriceMoveWindow, e.opts.PriceMoveCooldown)
+	if blocked && !wasBlocked {
+		log.Warn().
+			Str("platform", event.Platform).
+			Str("market", marketID).
+			Float64("price", price).
+			Float64("move", move).
+			Dur("window", e.opts.PriceMoveWindow).
+			Msg("Market price moving too fast, blocking opening orders")
+	}
+}
+
+// dropVolatileMarkets blocks opening place_order commands on markets the
+// volatility guard has blocked, publishing a strategy_error for each
+func (e *Engine) dropVolatileMarkets(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	if e.opts.MaxPriceMove <= 0 {
+		return commands
+	}
+
+	now := time.Now()
+	allowed := commands[:0]
+	for _, cmd := range commands {
+		if opensPosition(cmd) {
+			if skip, _ := cmd.Metadata["skip_volatility_guard"].(bool); !skip {
+				if until, blocked := e.volatility.blockedUntil(cmd.MarketID, now); blocked {
+					err := fmt.Errorf("market %s is moving too fast, opening orders blocked until %s",
+						cmd.MarketID, until.UTC().Format(time.RFC3339))
+					log.Warn().
+						Err(err).
+						Str("strategy", strategy.Name).
+						Msg("Command blocked by volatility guard")
+					e.publishStrategyError(ctx, strategy, event, ErrorClassVolatility, err, &cmd)
+					continue
+				}
+			}
+		}
+		allowed = append(allowed, cmd)
+	}
+	return allowed
+}
+
+// opensPosition reports whether a command places an order that opens or adds
+// to a position, rather than selling or closing one
+func opensPosition(cmd types.Command) bool {
+	if !cmd.PlacesOrder() {
+		return false
+	}
+	switch cmd.Action {
+	case "sell", "close":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Strategies can warm up before they trade: for a while after a strategy is
+// activated in this engine (loaded at startup, enabled, or started as a
+// candidate version or A/B test variant) its handler sees every event and
+// builds its state as usual, but the orders it places are dropped, so a
+// momentum or market making strategy does not trade on an empty state right
+// after a restart. Cancels and other commands go through, so it can still
+// clean up orders left from before. The period is Options.Warmup,
+// overridable per strategy with config "warmup" in seconds.
+
+// ErrorClassWarmup marks the decisions of orders dropped during warm-up. They
+// are recorded, not published as strategy errors.
+const ErrorClassWarmup = "warming_up"
+
+// warmup returns the warm-up period of a strategy
+func (e *Engine) warmup(strategy types.Strategy) time.Duration {
+	if seconds, ok := strategy.Config["warmup"].(float64); ok && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return e.opts.Warmup
+}
+
+// warmingUntil returns the end of a strategy's warm-up and whether it is
+// still warming up at now
+func (e *Engine) warmingUntil(strategy types.Strategy, now time.Time) (time.Time, bool) {
+	e.mu.RLock()
+	activated, ok := e.activated[strategy.ID]
+	e.mu.RUnlock()
+	if !ok {
+		return time.Time{}, false
+	}
+
+	until := activated.Add(e.warmup(strategy))
+	return until, now.Before(until)
+}
+
+// activatedAt returns when a strategy was last activated, or the zero time if
+// it is not active
+func (e *Engine) activatedAt(id string) time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.activated[id]
+}
+
+// trackActivations records when each loaded strategy became active, keeping
+// the time of strategies that were loaded before. The caller holds e.mu.
+func (e *Engine) trackActivations(strategies []types.Strategy, now time.Time) {
+	activated := make(map[string]time.Time, len(strategies))
+	for _, strategy := range strategies {
+		if !strategy.Active {
+			continue
+		}
+		if at, ok := e.activated[strategy.ID]; ok {
+			activated[strategy.ID] = at
+		} else {
+			activated[strategy.ID] = now
+		}
+	}
+	e.activated = activated
+}
+
+// dropWarmupOrders drops the place_order commands of a strategy that is
+// still warming up
+func (e *Engine) dropWarmupOrders(strategy types.Strategy, event types.Event, commands []types.Command) []types.Command {
+	until, warming := e.warmingUntil(strategy, time.Now())
+	if !warming {
+		return commands
+	}
+
+	allowed := commands[:0]
+	var dropped []types.Command
+	for _, cmd := range commands {
+		if cmd.PlacesOrder() {
+			dropped = append(dropped, cmd)
+			continue
+		}
+		allowed = append(allowed, cmd)
+	}
+	if len(dropped) > 0 {
+		log.Debug().
+			Str("strategy", strategy.Name).
+			Int("orders", len(dropped)).
+			Time("until", until).
+			Msg("Warming up, orders dropped")
+		e.recordDecision(strategy, event, dropped, ErrorClassWarmup,
+			fmt.Errorf("warming up until %s", until.Format(time.RFC3339)))
+	}
+	return allowed
+}
+
+// warmingUp returns the end of every current warm-up, by strategy name
+func (e *Engine) warmingUp(now time.Time) map[string]time.Time {
+	e.mu.RLock()
+	strategies := e.strategies
+	e.mu.RUnlock()
+
+	result := make(map[string]time.Time)
+	for _, strategy := range strategies {
+		if until, warming := e.warmingUntil(strategy, now); warming {
+			result[strategy.Name] = until
+		}
+	}
+	return result
+}
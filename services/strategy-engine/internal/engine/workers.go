@@ -0,0 +1,275 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Each strategy processes events on its own goroutine, fed by a bounded queue,
+// so a slow or misbehaving strategy does not hold up the others. Events reach
+// a strategy in stream order. A panic in a strategy is recovered and counted;
+// after MaxHandlerPanics consecutive panics the strategy is disabled. Its
+// worker drops events until an operator enables the strategy again, then
+// starts over with a clean count.
+//
+// Strategies with a higher Priority handle an event before those with a lower
+// one, so risk-reducing strategies (stop-loss) act before opportunistic ones
+// (grid, market making) react to the same fill. Strategies of equal priority
+// handle it in parallel. A strategy waiting for higher priorities to finish
+// an event holds back its later events too, keeping them in stream order.
+
+const (
+	strategyQueueSize       = 1000
+	defaultMaxHandlerPanics = 3
+)
+
+type strategyJob struct {
+	strategy types.Strategy
+	event    types.Event
+	after    <-chan struct{} // closed once higher priorities are done with the event; nil to run at once
+	commands []types.Command // fired conditional orders, run through the pipeline instead of the handler
+	done     func()          // called once the job is processed or dropped; may be nil
+}
+
+type strategyWorker struct {
+	strategy string // name, for the lag report
+	queue    chan strategyJob
+
+	// Only touched by the worker goroutine
+	panics   int
+	disabled bool
+
+	// disabledActivation is when the strategy had been activated when the
+	// worker disabled it; a later activation means it was enabled again
+	disabledActivation time.Time
+}
+
+// dispatch queues the event for the strategy's worker, starting it on first
+// use. A full queue blocks the caller rather than dropping the event.
+func (e *Engine) dispatch(ctx context.Context, strategy types.Strategy, event types.Event) {
+	e.enqueue(ctx, strategyJob{strategy: strategy, event: event})
+}
+
+// dispatchByPriority hands an event to strategies sorted by descending
+// priority, each priority starting once the higher ones are done with it.
+// finished, if not nil, is called as each strategy is done with the event.
+func (e *Engine) dispatchByPriority(ctx context.Context, strategies []types.Strategy, event types.Event, finished func()) {
+	var after <-chan struct{}
+	for start := 0; start < len(strategies); {
+		end := start
+		for end < len(strategies) && strategies[end].Priority == strategies[start].Priority {
+			end++
+		}
+
+		// The lowest priority has nobody waiting for it
+		done := finished
+		var wg sync.WaitGroup
+		if end < len(strategies) {
+			wg.Add(end - start)
+			done = func() {
+				wg.Done()
+				if finished != nil {
+					finished()
+				}
+			}
+		}
+		for _, strategy := range strategies[start:end] {
+			e.enqueue(ctx, strategyJob{strategy: strategy, event: event, after: after, done: done})
+		}
+		if end < len(strategies) {
+			levelDone := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(levelDone)
+			}()
+			after = levelDone
+		}
+		start = end
+	}
+}
+
+// enqueue queues a job for its strategy's worker
+func (e *Engine) enqueue(ctx context.Context, job strategyJob) {
+	w := e.worker(ctx, job.strategy)
+	strategy := job.strategy
+
+	select {
+	case w.queue <- job:
+		return
+	default:
+	}
+
+	log.Warn().
+		Str("strategy", strategy.Name).
+		Int("queue", strategyQueueSize).
+		Msg("Strategy queue full, waiting")
+
+	select {
+	case w.queue <- job:
+	case <-ctx.Done():
+		if job.done != nil {
+			job.done()
+		}
+	}
+}
+
+func (e *Engine) worker(ctx context.Context, strategy types.Strategy) *strategyWorker {
+	e.workersMu.Lock()
+	defer e.workersMu.Unlock()
+
+	w, ok := e.workers[strategy.ID]
+	if !ok {
+		w = &strategyWorker{
+			strategy: strategy.Name,
+			queue:    make(chan strategyJob, strategyQueueSize),
+		}
+		e.workers[strategy.ID] = w
+		go e.runWorker(ctx, w)
+	}
+	return w
+}
+
+func (e *Engine) runWorker(ctx context.Context, w *strategyWorker) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-w.queue:
+			if job.after != nil {
+				select {
+				case <-job.after:
+				case <-ctx.Done():
+					return
+				}
+			}
+			e.process(ctx, w, job)
+			if job.done != nil {
+				job.done()
+			}
+		}
+	}
+}
+
+func (e *Engine) process(ctx context.Context, w *strategyWorker, job strategyJob) {
+	if w.disabled {
+		if !e.activatedAt(job.strategy.ID).After(w.disabledActivation) {
+			return
+		}
+		w.disabled = false
+		w.panics = 0
+		log.Info().Str("strategy", job.strategy.Name).Msg("Strategy enabled again, processing its events")
+	}
+
+	// Events queued before the kill switch engaged are dropped too
+	if halted, _ := e.Halted(); halted {
+		return
+	}
+
+	recovered, stack := e.runRecovered(ctx, job)
+	if recovered == nil {
+		w.panics = 0
+		return
+	}
+
+	w.panics++
+	e.counters.record(job.strategy.Name, 0, true)
+
+	err := fmt.Errorf("handler panic: %v", recovered)
+	log.Error().
+		Str("strategy", job.strategy.Name).
+		Str("event_id", job.event.ID).
+		Int("consecutive", w.panics).
+		Str("stack", stack).
+		Msg("Strategy panicked")
+	e.publishStrategyError(ctx, job.strategy, job.event, ErrorClassPanic, err, nil)
+
+	if w.panics >= e.maxHandlerPanics() {
+		w.disabled = true
+		w.disabledActivation = e.activatedAt(job.strategy.ID)
+		e.disableStrategy(ctx, job.strategy, fmt.Sprintf("%d consecutive panics, last: %v", w.panics, recovered))
+	}
+}
+
+// runRecovered runs the strategy and returns the recovered panic value and
+// stack, or nil if it completed normally
+func (e *Engine) runRecovered(ctx context.Context, job strategyJob) (recovered interface{}, stack string) {
+	defer func() {
+		if r := recover(); r != nil {
+			// Panics from a handler goroutine carry their own stack
+			if p, ok := r.(*handlerPanic); ok {
+				recovered, stack = p.value, p.stack
+				return
+			}
+			recovered = r
+			stack = string(debug.Stack())
+		}
+	}()
+
+	if job.commands != nil {
+		e.runPipeline(ctx, job.strategy, job.event, job.commands)
+		return nil, ""
+	}
+	e.runStrategy(ctx, job.strategy, job.event)
+	return nil, ""
+}
+
+func (e *Engine) maxHandlerPanics() int {
+	if e.opts.MaxHandlerPanics > 0 {
+		return e.opts.MaxHandlerPanics
+	}
+	return defaultMaxHandlerPanics
+}
+
+// disableStrategy turns a strategy off in the database and in memory and
+// raises an alert. It needs an operator to re-enable it.
+func (e *Engine) disableStrategy(ctx context.Context, strategy types.Strategy, reason string) {
+	// A candidate in compare mode leaves the database to the live engine
+	if e.opts.Compare {
+		e.updateStrategy(liveStrategyID(strategy), func(s *types.Strategy) {
+			s.Active = false
+		})
+		log.Error().
+			Str("strategy", strategy.Name).
+			Str("reason", reason).
+			Msg("Strategy disabled in compare mode until the next refresh")
+		return
+	}
+
+	// A misbehaving candidate is rejected; the live version keeps running
+	if strategy.CandidateOf != "" {
+		if err := e.storage.RejectStrategyVersion(ctx, strategy.CandidateOf, strategy.Version, "engine"); err != nil {
+			log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to reject candidate version")
+		}
+	} else if err := e.storage.DisableStrategy(ctx, liveStrategyID(strategy)); err != nil {
+		log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to disable strategy in database")
+	}
+
+	e.updateStrategy(liveStrategyID(strategy), func(s *types.Strategy) {
+		s.Active = false
+	})
+
+	log.Error().
+		Str("strategy", strategy.Name).
+		Str("reason", reason).
+		Msg("Strategy disabled")
+
+	if err := e.storage.CreateAlert(
+		ctx,
+		"strategy",
+		"Strategy disabled",
+		fmt.Sprintf("Strategy %s was disabled: %s", strategy.Name, reason),
+		map[string]interface{}{
+			"strategy":    strategy.Name,
+			"strategy_id": strategy.ID,
+			"reason":      reason,
+		},
+	); err != nil {
+		log.Error().Err(err).Str("strategy", strategy.Name).Msg("Failed to create strategy disabled alert")
+	}
+}
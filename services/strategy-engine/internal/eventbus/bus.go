@@ -0,0 +1,49 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// EventBus carries events between the services. RedisEventBus is the
+// production implementation, on Redis streams; InMemory keeps the streams in
+// the process, for tests and embedding.
+type EventBus interface {
+	// Publish appends an event to a stream
+	Publish(ctx context.Context, stream string, event types.Event) error
+
+	// Subscribe hands every event published to the streams after the call,
+	// one at a time, to handler until ctx is done. Handler errors are logged
+	// and do not stop the subscription. A RedisEventBus with a consumer name
+	// may start earlier, to catch up on entries missed while down.
+	Subscribe(ctx context.Context, streams []string, handler func(types.Event) error) error
+
+	// Range returns up to limit events published to a stream since the given time
+	Range(ctx context.Context, stream string, since time.Time, limit int64) ([]types.Event, error)
+
+	// RangeIDs returns up to limit events with stream IDs between start and
+	// end, inclusive. "-" and "+" stand for the first and last entry.
+	RangeIDs(ctx context.Context, stream, start, end string, limit int64) ([]types.Event, error)
+
+	// Lag returns the gap between the subscriber and a stream
+	Lag(ctx context.Context, stream string) (StreamLag, error)
+
+	// Trim removes the entries of a stream beyond a policy's bounds
+	Trim(ctx context.Context, stream string, policy TrimPolicy) (int64, error)
+
+	// OnGap installs the function lost entries of subscribed streams are
+	// reported to, see sequence.go
+	OnGap(handler func(Gap))
+
+	// Health returns the state of the subscriber's connection
+	Health() Health
+
+	Close() error
+}
+
+var (
+	_ EventBus = (*RedisEventBus)(nil)
+	_ EventBus = (*InMemory)(nil)
+)
@@ -0,0 +1,215 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// A subscriber named by Options.Consumer saves the ID of the last entry it
+// delivered from each stream to Redis, every second and when the
+// subscription ends. When it subscribes again after downtime, the stream's
+// CatchUpPolicy decides what happens to the entries added in between:
+//
+//   - CatchUpSkip starts at the newest entry, as without a consumer name; the
+//     entries are never delivered
+//   - CatchUpDryRun delivers them with "catch_up": "dry_run" in their data, so
+//     fills and prices are taken into account but the orders placed on them
+//     are sent as dry runs
+//   - CatchUpReplay delivers them with "catch_up": "replay", to be handled as
+//     usual, except those older than MaxAge, which are delivered as in
+//     CatchUpDryRun
+//
+// A stream without a saved offset starts at the newest entry. Entries
+// delivered less than a second before a crash may be delivered again.
+
+// Catch-up modes
+const (
+	CatchUpSkip   = "skip"
+	CatchUpDryRun = "dry_run"
+	CatchUpReplay = "replay"
+)
+
+// CatchUpField is the event data field marking the entries delivered while
+// catching up, set to the mode they were delivered under
+const CatchUpField = "catch_up"
+
+const (
+	offsetSaveInterval = time.Second
+	offsetSaveTimeout  = 5 * time.Second
+)
+
+// CatchUpPolicy is how the entries added to a stream while its subscriber
+// was down are delivered. The zero value skips them.
+type CatchUpPolicy struct {
+	Mode   string
+	MaxAge time.Duration
+}
+
+// CatchUpMode reports the mode an event was delivered under while catching
+// up, or "" if it was not
+func CatchUpMode(event types.Event) string {
+	mode, _ := event.Data[CatchUpField].(string)
+	return mode
+}
+
+// catchUp is a stream being caught up: the entries up to until, its newest
+// entry when the subscription started, are marked
+type catchUp struct {
+	stream    string
+	until     string
+	policy    CatchUpPolicy
+	delivered int
+	dryRun    int
+}
+
+// mark flags an entry delivered while catching up with its mode
+func (c *catchUp) mark(event types.Event, id string) types.Event {
+	mode := c.policy.Mode
+	if mode == CatchUpReplay && c.policy.MaxAge > 0 {
+		if ms, _, _, ok := ParseStreamID(id); ok && time.Since(time.UnixMilli(ms)) > c.policy.MaxAge {
+			mode = CatchUpDryRun
+		}
+	}
+	c.delivered++
+	if mode == CatchUpDryRun {
+		c.dryRun++
+	}
+
+	data := make(map[string]interface{}, len(event.Data)+1)
+	for k, v := range event.Data {
+		data[k] = v
+	}
+	data[CatchUpField] = mode
+	event.Data = data
+	return event
+}
+
+func (c *catchUp) done() {
+	log.Info().
+		Str("stream", c.stream).
+		Int("delivered", c.delivered).
+		Int("dry_run", c.dryRun).
+		Msg("Caught up on stream")
+}
+
+func (b *RedisEventBus) catchUpPolicy(stream string) CatchUpPolicy {
+	if policy, ok := b.catchUpStreams[stream]; ok {
+		return policy
+	}
+	return b.catchUp
+}
+
+func offsetsKey(consumer string) string {
+	return "stream-offsets:" + consumer
+}
+
+// catchUpStarts moves the start IDs of the streams with a saved offset and a
+// policy that delivers missed entries back to the offset, and returns those
+// streams' catch-ups. The others keep ids, their newest entries, which
+// become their saved offsets.
+func (b *RedisEventBus) catchUpStarts(ctx context.Context, streams, ids []string) (map[string]*catchUp, error) {
+	if b.consumer == "" {
+		return nil, nil
+	}
+	saved, err := b.client.HGetAll(ctx, offsetsKey(b.consumer)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	catchUps := make(map[string]*catchUp)
+	for i, stream := range streams {
+		offset, ok := saved[stream]
+		if !ok || !streamIDBefore(offset, ids[i]) {
+			b.saveOffset(stream, ids[i])
+			continue
+		}
+
+		policy := b.catchUpPolicy(stream)
+		if policy.Mode != CatchUpDryRun && policy.Mode != CatchUpReplay {
+			log.Warn().
+				Str("stream", stream).
+				Str("from", offset).
+				Str("until", ids[i]).
+				Msg("Skipping entries added to stream while down")
+			b.saveOffset(stream, ids[i])
+			continue
+		}
+
+		log.Warn().
+			Str("stream", stream).
+			Str("mode", policy.Mode).
+			Dur("max_age", policy.MaxAge).
+			Str("from", offset).
+			Str("until", ids[i]).
+			Msg("Catching up on stream")
+		catchUps[stream] = &catchUp{stream: stream, until: ids[i], policy: policy}
+		ids[i] = offset
+	}
+	return catchUps, nil
+}
+
+// saveOffset queues the offset of a stream for the next save
+func (b *RedisEventBus) saveOffset(stream, id string) {
+	if b.consumer == "" {
+		return
+	}
+	b.offsetsMu.Lock()
+	defer b.offsetsMu.Unlock()
+
+	if b.offsets == nil {
+		b.offsets = make(map[string]string)
+	}
+	b.offsets[stream] = id
+}
+
+// runOffsetSaver saves the queued offsets every second, and once more when
+// ctx is done
+func (b *RedisEventBus) runOffsetSaver(ctx context.Context) {
+	ticker := time.NewTicker(offsetSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), offsetSaveTimeout)
+			b.saveOffsets(saveCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			b.saveOffsets(ctx)
+		}
+	}
+}
+
+func (b *RedisEventBus) saveOffsets(ctx context.Context) {
+	b.offsetsMu.Lock()
+	offsets := b.offsets
+	b.offsets = nil
+	b.offsetsMu.Unlock()
+	if len(offsets) == 0 {
+		return
+	}
+
+	values := make(map[string]interface{}, len(offsets))
+	for stream, id := range offsets {
+		values[stream] = id
+	}
+	if err := b.client.HSet(ctx, offsetsKey(b.consumer), values).Err(); err != nil {
+		log.Warn().Err(err).Msg("Failed to save stream offsets")
+
+		// Keep them for the next save unless newer ones were queued
+		b.offsetsMu.Lock()
+		if b.offsets == nil {
+			b.offsets = make(map[string]string)
+		}
+		for stream, id := range offsets {
+			if _, queued := b.offsets[stream]; !queued {
+				b.offsets[stream] = id
+			}
+		}
+		b.offsetsMu.Unlock()
+	}
+}
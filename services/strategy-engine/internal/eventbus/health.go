@@ -0,0 +1,202 @@
+package eventbus
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+)
+
+// Backoff between attempts to reach Redis again after the subscriber lost it
+const (
+	minReconnectBackoff = 500 * time.Millisecond
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// Health is the state of the bus's connection, as its subscriber sees it
+type Health struct {
+	Connected  bool      `json:"connected"`
+	Since      time.Time `json:"since"` // when the connection was last lost or restored
+	LastError  string    `json:"last_error,omitempty"`
+	Reconnects int64     `json:"reconnects"` // since the bus was created
+}
+
+// connectionHealth tracks the connection of a bus's readers, which each
+// report losing and regaining it
+type connectionHealth struct {
+	mu     sync.Mutex
+	health Health
+}
+
+func newConnectionHealth() *connectionHealth {
+	return &connectionHealth{health: Health{Connected: true, Since: time.Now().UTC()}}
+}
+
+func (c *connectionHealth) get() Health {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.health
+}
+
+// lost records a failed read and reports whether the connection was up
+func (c *connectionHealth) lost(err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.health.LastError = err.Error()
+	if !c.health.Connected {
+		return false
+	}
+	c.health.Connected = false
+	c.health.Since = time.Now().UTC()
+	return true
+}
+
+// restored records a working connection and reports whether it was down
+func (c *connectionHealth) restored() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.health.Connected {
+		return false
+	}
+	c.health.Connected = true
+	c.health.Since = time.Now().UTC()
+	c.health.Reconnects++
+	return true
+}
+
+// Health returns the state of the connection to Redis
+func (b *RedisEventBus) Health() Health {
+	return b.health.get()
+}
+
+// reconnect waits until Redis answers again, backing off exponentially, so
+// the reader can resubscribe from offsets, the IDs of the last entries it
+// delivered (or started after). Nothing published while the connection was
+// down is skipped. It returns early once ctx is done.
+func (b *RedisEventBus) reconnect(ctx context.Context, streams, offsets []string, cause error) error {
+	if b.health.lost(cause) {
+		log.Error().Err(cause).Strs("streams", streams).Msg("Lost connection to Redis, reconnecting")
+	}
+
+	backoff := minReconnectBackoff
+	for {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		err := b.checkOffsets(ctx, streams, offsets)
+		if err == nil {
+			if b.health.restored() {
+				log.Info().Strs("streams", streams).Msg("Reconnected to Redis, resubscribing")
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		b.health.lost(err)
+
+		backoff *= 2
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+		log.Warn().Err(err).Dur("retry_in", backoff).Msg("Redis still unreachable")
+	}
+}
+
+// checkOffsets reads the newest entry of every stream, failing while Redis
+// is unreachable, and warns about streams that lost entries already delivered
+func (b *RedisEventBus) checkOffsets(ctx context.Context, streams, offsets []string) error {
+	if err := b.client.Ping(ctx).Err(); err != nil {
+		return err
+	}
+
+	for i, stream := range streams {
+		last, err := b.lastID(ctx, stream)
+		if err != nil {
+			return err
+		}
+		// A replica promoted by a failover may not have had the entries
+		// delivered from the old master. New entries still get higher IDs.
+		if streamIDBefore(last, offsets[i]) {
+			log.Warn().
+				Str("stream", stream).
+				Str("offset", offsets[i]).
+				Str("last_id", last).
+				Msg("Stream is behind the subscriber after reconnecting, delivered entries were lost in the failover")
+		}
+	}
+	return nil
+}
+
+// startIDs returns, for each stream, the ID of its newest entry, so the
+// subscription starts with the entries published after the call. Unlike "$",
+// the IDs stay fixed until the first entry arrives, so nothing published
+// between two reads is skipped.
+func (b *RedisEventBus) startIDs(ctx context.Context, streams []string) ([]string, error) {
+	ids := make([]string, len(streams))
+	for i, stream := range streams {
+		last, err := b.lastID(ctx, stream)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = last
+	}
+	return ids, nil
+}
+
+// subscriptionStarts returns the IDs a subscription to the streams starts
+// after: their newest entries, or the saved offsets of the streams being
+// caught up, which are returned by stream
+func (b *RedisEventBus) subscriptionStarts(ctx context.Context, streams []string) ([]string, map[string]*catchUp, error) {
+	ids, err := b.startIDs(ctx, streams)
+	if err != nil {
+		return nil, nil, err
+	}
+	catchUps, err := b.catchUpStarts(ctx, streams, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ids, catchUps, nil
+}
+
+// lastID returns the ID of the newest entry ever added to a stream, or "0-0"
+// if the stream does not exist
+func (b *RedisEventBus) lastID(ctx context.Context, stream string) (string, error) {
+	info, err := b.client.XInfoStream(ctx, stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return "0-0", nil
+		}
+		return "", err
+	}
+	return info.LastGeneratedID, nil
+}
+
+// isConnectionError reports whether a failed read means Redis cannot be
+// reached or is not serving yet, as opposed to a bad command on a working
+// connection
+func isConnectionError(err error) bool {
+	if err == nil || err == redis.Nil {
+		return false
+	}
+	redisErr, ok := err.(redis.Error)
+	if !ok {
+		return true
+	}
+	for _, prefix := range []string{"LOADING", "READONLY", "MASTERDOWN", "CLUSTERDOWN", "TRYAGAIN"} {
+		if strings.HasPrefix(redisErr.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
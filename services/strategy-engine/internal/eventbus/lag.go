@@ -0,0 +1,91 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxBehindCount caps how many undelivered entries StreamLag counts, so a
+// large backlog does not turn the check itself into a big read
+const maxBehindCount = 10000
+
+// StreamLag compares the newest entry of a stream with the last entry
+// delivered to this bus's subscriber
+type StreamLag struct {
+	Stream      string  `json:"stream"`
+	Length      int64   `json:"length"`
+	LastID      string  `json:"last_id"`
+	DeliveredID string  `json:"delivered_id"`
+	LagSeconds  float64 `json:"lag_seconds"` // age gap between the newest and the last delivered entry
+	Behind      int64   `json:"behind"`      // entries not delivered yet, up to maxBehindCount
+	Lost        int64   `json:"lost"`        // entries skipped by producer sequence numbers since the subscription started
+}
+
+// Lag returns the gap between the subscriber and a stream. Entries published
+// before the subscription started do not count.
+func (b *RedisEventBus) Lag(ctx context.Context, stream string) (StreamLag, error) {
+	info, err := b.client.XInfoStream(ctx, stream).Result()
+	if err != nil {
+		return StreamLag{}, fmt.Errorf("failed to read stream info: %w", err)
+	}
+
+	lag := StreamLag{
+		Stream:      stream,
+		Length:      info.Length,
+		LastID:      info.LastGeneratedID,
+		DeliveredID: b.deliveredID(stream),
+		Lost:        b.sequences.lostCount(stream),
+	}
+	if lag.DeliveredID == "" || !streamIDBefore(lag.DeliveredID, lag.LastID) {
+		return lag, nil
+	}
+
+	lastMs, _, _, _ := ParseStreamID(lag.LastID)
+	deliveredMs, _, _, _ := ParseStreamID(lag.DeliveredID)
+	lag.LagSeconds = float64(lastMs-deliveredMs) / 1000
+
+	pending, err := b.client.XRangeN(ctx, stream, lag.DeliveredID, "+", maxBehindCount+1).Result()
+	if err != nil {
+		return lag, fmt.Errorf("failed to count undelivered entries: %w", err)
+	}
+	lag.Behind = int64(len(pending))
+	if len(pending) > 0 && pending[0].ID == lag.DeliveredID {
+		lag.Behind--
+	}
+	if lag.Behind > maxBehindCount {
+		lag.Behind = maxBehindCount
+	}
+	return lag, nil
+}
+
+// markDelivered records the last entry handed to the subscriber of a stream
+func (b *RedisEventBus) markDelivered(stream, id string) {
+	b.deliveredMu.Lock()
+	defer b.deliveredMu.Unlock()
+
+	if b.delivered == nil {
+		b.delivered = make(map[string]string)
+	}
+	b.delivered[stream] = id
+}
+
+func (b *RedisEventBus) deliveredID(stream string) string {
+	b.deliveredMu.Lock()
+	defer b.deliveredMu.Unlock()
+	return b.delivered[stream]
+}
+
+func streamIDBefore(a, b string) bool {
+	aMs, aSeq, _, _ := ParseStreamID(a)
+	bMs, bSeq, _, _ := ParseStreamID(b)
+	if aMs != bMs {
+		return aMs < bMs
+	}
+	return aSeq < bSeq
+}
+
+// subscriptionStart is the ID delivery is measured from before the first entry arrives
+func subscriptionStart() string {
+	return StreamID(time.Now())
+}
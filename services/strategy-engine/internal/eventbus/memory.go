@@ -0,0 +1,250 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// InMemory is an EventBus that keeps its streams in the process. It behaves
+// like the Redis bus: entries get Redis-style IDs, which become the event
+// IDs; event data goes through JSON and timestamps keep second precision;
+// subscribers only see entries published after they subscribed. Unlike Redis,
+// a subscriber gets the entries of several streams in publish order. Streams
+// are only trimmed by Trim.
+type InMemory struct {
+	mu        sync.Mutex
+	streams   map[string][]redis.XMessage
+	lastMs    int64
+	lastSeq   int64
+	published chan struct{}     // closed and replaced on every publish
+	delivered map[string]string // stream -> last entry ID handed to a subscriber
+}
+
+// NewInMemory returns an empty in-memory bus
+func NewInMemory() *InMemory {
+	return &InMemory{
+		streams:   make(map[string][]redis.XMessage),
+		published: make(chan struct{}),
+		delivered: make(map[string]string),
+	}
+}
+
+func (b *InMemory) Publish(ctx context.Context, stream string, event types.Event) error {
+	values, err := encodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.streams[stream] = append(b.streams[stream], redis.XMessage{ID: b.nextID(), Values: values})
+	close(b.published)
+	b.published = make(chan struct{})
+	return nil
+}
+
+// nextID returns a new entry ID, increasing across all streams
+func (b *InMemory) nextID() string {
+	ms := time.Now().UnixMilli()
+	if ms <= b.lastMs {
+		b.lastSeq++
+	} else {
+		b.lastMs, b.lastSeq = ms, 0
+	}
+	return fmt.Sprintf("%d-%d", b.lastMs, b.lastSeq)
+}
+
+func (b *InMemory) Subscribe(ctx context.Context, streams []string, handler func(types.Event) error) error {
+	log.Info().Strs("streams", streams).Msg("Subscribing to streams")
+
+	// Start after the current last entry of every stream, like "$" in XREAD.
+	// Positions are entry IDs, not indexes, since Trim drops the head.
+	last := make(map[string]string, len(streams))
+	b.mu.Lock()
+	for _, stream := range streams {
+		last[stream] = "0-0"
+		if messages := b.streams[stream]; len(messages) > 0 {
+			last[stream] = messages[len(messages)-1].ID
+		}
+		b.delivered[stream] = subscriptionStart()
+	}
+	b.mu.Unlock()
+
+	for {
+		b.mu.Lock()
+		published := b.published
+		var pending []redis.XMessage
+		var from []string
+		for _, stream := range streams {
+			messages := b.streams[stream]
+			start := sort.Search(len(messages), func(i int) bool {
+				return streamIDBefore(last[stream], messages[i].ID)
+			})
+			for _, message := range messages[start:] {
+				pending = append(pending, message)
+				from = append(from, stream)
+			}
+			if len(messages) > start {
+				last[stream] = messages[len(messages)-1].ID
+			}
+		}
+		b.mu.Unlock()
+
+		// Hand entries over in publish order, across streams
+		order := make([]int, len(pending))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return streamIDBefore(pending[order[i]].ID, pending[order[j]].ID)
+		})
+
+		for _, i := range order {
+			message := pending[i]
+			event, err := parseEvent(message)
+			if err != nil {
+				log.Error().Err(err).Str("stream", from[i]).Msg("Failed to parse event")
+				continue
+			}
+			if err := handler(event); err != nil {
+				log.Error().Err(err).Str("event_type", event.Type).Msg("Failed to handle event")
+			}
+
+			b.mu.Lock()
+			b.delivered[from[i]] = message.ID
+			b.mu.Unlock()
+		}
+		if len(pending) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-published:
+		}
+	}
+}
+
+// Range returns up to limit events published to a stream since the given time
+func (b *InMemory) Range(ctx context.Context, stream string, since time.Time, limit int64) ([]types.Event, error) {
+	return b.RangeIDs(ctx, stream, StreamID(since), "+", limit)
+}
+
+// RangeIDs returns up to limit events with stream IDs between start and end,
+// inclusive. "-" and "+" stand for the first and last entry.
+func (b *InMemory) RangeIDs(ctx context.Context, stream, start, end string, limit int64) ([]types.Event, error) {
+	startMs, startSeq, err := rangeBound(start, 0)
+	if err != nil {
+		return nil, err
+	}
+	endMs, endSeq, err := rangeBound(end, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	messages := b.streams[stream]
+	b.mu.Unlock()
+
+	var events []types.Event
+	for _, message := range messages {
+		if limit > 0 && int64(len(events)) >= limit {
+			break
+		}
+		ms, seq, _, _ := ParseStreamID(message.ID)
+		if ms < startMs || (ms == startMs && seq < startSeq) {
+			continue
+		}
+		if ms > endMs || (ms == endMs && seq > endSeq) {
+			break
+		}
+		event, err := parseEvent(message)
+		if err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// rangeBound parses a range bound. A bare millisecond ID takes sequence
+// defaultSeq: the first entry of that millisecond as a start, the last as an
+// end.
+func rangeBound(id string, defaultSeq int64) (ms, seq int64, err error) {
+	switch id {
+	case "-":
+		return 0, 0, nil
+	case "+":
+		return math.MaxInt64, math.MaxInt64, nil
+	}
+	ms, seq, found, ok := ParseStreamID(id)
+	if !ok {
+		return 0, 0, fmt.Errorf("failed to read stream range: invalid stream ID %q", id)
+	}
+	if !found {
+		seq = defaultSeq
+	}
+	return ms, seq, nil
+}
+
+// Lag returns the gap between the subscriber and a stream
+func (b *InMemory) Lag(ctx context.Context, stream string) (StreamLag, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	messages, ok := b.streams[stream]
+	if !ok {
+		return StreamLag{}, fmt.Errorf("failed to read stream info: no such stream %s", stream)
+	}
+	if len(messages) == 0 {
+		// Trimmed empty
+		return StreamLag{Stream: stream, DeliveredID: b.delivered[stream]}, nil
+	}
+
+	lag := StreamLag{
+		Stream:      stream,
+		Length:      int64(len(messages)),
+		LastID:      messages[len(messages)-1].ID,
+		DeliveredID: b.delivered[stream],
+	}
+	if lag.DeliveredID == "" || !streamIDBefore(lag.DeliveredID, lag.LastID) {
+		return lag, nil
+	}
+
+	lastMs, _, _, _ := ParseStreamID(lag.LastID)
+	deliveredMs, _, _, _ := ParseStreamID(lag.DeliveredID)
+	lag.LagSeconds = float64(lastMs-deliveredMs) / 1000
+
+	for _, message := range messages {
+		if streamIDBefore(lag.DeliveredID, message.ID) {
+			lag.Behind++
+		}
+	}
+	if lag.Behind > maxBehindCount {
+		lag.Behind = maxBehindCount
+	}
+	return lag, nil
+}
+
+// OnGap does nothing: the in-memory bus does not number its entries, and
+// subscribers read every entry that was not trimmed
+func (b *InMemory) OnGap(handler func(Gap)) {}
+
+// Health always reports a connected bus
+func (b *InMemory) Health() Health {
+	return Health{Connected: true}
+}
+
+func (b *InMemory) Close() error {
+	return nil
+}
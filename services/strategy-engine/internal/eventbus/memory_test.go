@@ -0,0 +1,186 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// subscribe starts a subscriber on the streams and returns the events it
+// receives. It returns once the subscription has started.
+func subscribe(t *testing.T, b *InMemory, streams ...string) <-chan types.Event {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	received := make(chan types.Event, 100)
+	go b.Subscribe(ctx, streams, func(event types.Event) error {
+		received <- event
+		return nil
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		b.mu.Lock()
+		started := b.delivered[streams[0]] != ""
+		b.mu.Unlock()
+		if started {
+			return received
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscription did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func publish(t *testing.T, b *InMemory, stream string, n int, from int) {
+	t.Helper()
+
+	for i := from; i < from+n; i++ {
+		event := types.Event{Type: "fill", Platform: "predict", Data: map[string]interface{}{"n": float64(i)}}
+		if err := b.Publish(context.Background(), stream, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// receive waits for n events and returns their "n" fields
+func receive(t *testing.T, events <-chan types.Event, n int) []float64 {
+	t.Helper()
+
+	var got []float64
+	for len(got) < n {
+		select {
+		case event := <-events:
+			v, _ := event.Data["n"].(float64)
+			got = append(got, v)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d events, want %d: %v", len(got), n, got)
+		}
+	}
+	return got
+}
+
+func expectNone(t *testing.T, events <-chan types.Event) {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %+v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestInMemoryDeliversOnlyNewEntries(t *testing.T) {
+	b := NewInMemory()
+	publish(t, b, "fill_events", 3, 0)
+
+	events := subscribe(t, b, "fill_events")
+	expectNone(t, events)
+
+	publish(t, b, "fill_events", 2, 3)
+	if got := receive(t, events, 2); fmt.Sprint(got) != "[3 4]" {
+		t.Errorf("received %v, want [3 4]", got)
+	}
+}
+
+func TestInMemoryTrimDoesNotShiftSubscribers(t *testing.T) {
+	b := NewInMemory()
+	events := subscribe(t, b, "fill_events")
+
+	publish(t, b, "fill_events", 10, 0)
+	if got := receive(t, events, 10); fmt.Sprint(got) != "[0 1 2 3 4 5 6 7 8 9]" {
+		t.Fatalf("received %v", got)
+	}
+
+	removed, err := b.Trim(context.Background(), "fill_events", TrimPolicy{MaxLen: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 8 {
+		t.Errorf("Trim removed %d entries, want 8", removed)
+	}
+
+	publish(t, b, "fill_events", 1, 10)
+	if got := receive(t, events, 1); got[0] != 10 {
+		t.Errorf("received %v after trimming, want [10]", got)
+	}
+	expectNone(t, events)
+}
+
+func TestInMemoryTrimToEmpty(t *testing.T) {
+	b := NewInMemory()
+	events := subscribe(t, b, "fill_events")
+
+	publish(t, b, "fill_events", 3, 0)
+	receive(t, events, 3)
+
+	// Every entry older than a millisecond goes
+	time.Sleep(5 * time.Millisecond)
+	if _, err := b.Trim(context.Background(), "fill_events", TrimPolicy{MaxAge: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	lag, err := b.Lag(context.Background(), "fill_events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lag.Length != 0 || lag.Behind != 0 {
+		t.Errorf("lag of an empty stream = %+v", lag)
+	}
+
+	publish(t, b, "fill_events", 1, 3)
+	if got := receive(t, events, 1); got[0] != 3 {
+		t.Errorf("received %v after trimming to empty, want [3]", got)
+	}
+}
+
+func TestInMemoryOrdersAcrossStreams(t *testing.T) {
+	b := NewInMemory()
+	events := subscribe(t, b, "fill_events", "market_events")
+
+	for i := 0; i < 6; i++ {
+		stream := "fill_events"
+		if i%2 == 1 {
+			stream = "market_events"
+		}
+		publish(t, b, stream, 1, i)
+	}
+	if got := receive(t, events, 6); fmt.Sprint(got) != "[0 1 2 3 4 5]" {
+		t.Errorf("received %v, want publish order", got)
+	}
+}
+
+func TestInMemoryRangeIDs(t *testing.T) {
+	b := NewInMemory()
+	publish(t, b, "fill_events", 5, 0)
+
+	all, err := b.RangeIDs(context.Background(), "fill_events", "-", "+", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("RangeIDs returned %d events, want 5", len(all))
+	}
+
+	some, err := b.RangeIDs(context.Background(), "fill_events", all[1].ID, all[3].ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(some) != 3 || some[0].ID != all[1].ID || some[2].ID != all[3].ID {
+		t.Errorf("RangeIDs(%s, %s) = %v", all[1].ID, all[3].ID, some)
+	}
+
+	limited, err := b.RangeIDs(context.Background(), "fill_events", "-", "+", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 2 {
+		t.Errorf("RangeIDs with limit 2 returned %d events", len(limited))
+	}
+}
@@ -2,8 +2,12 @@ package eventbus
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -12,32 +16,209 @@ import (
 )
 
 type RedisEventBus struct {
-	client *redis.Client
+	client  redis.UniversalClient
+	cluster bool
+	maxLen  int64
+	trim    map[string]TrimPolicy
+
+	deliveredMu sync.Mutex
+	delivered   map[string]string // stream -> last entry ID handed to the subscriber
+
+	health *connectionHealth
+
+	sequencer *sequencer // nil unless Options.Producer is set
+	sequences *sequenceTracker
+
+	consumer       string
+	catchUp        CatchUpPolicy
+	catchUpStreams map[string]CatchUpPolicy
+	offsetsMu      sync.Mutex
+	offsets        map[string]string // stream -> offset to save, since the last save
+}
+
+// Options selects how to reach Redis. With MasterName set the bus goes through
+// Sentinel (Addrs are the sentinels); with Cluster set Addrs are cluster seed
+// nodes; otherwise Addrs[0] is a single server.
+type Options struct {
+	Addrs            []string
+	MasterName       string
+	Cluster          bool
+	Username         string
+	Password         string
+	SentinelPassword string
+	DB               int
+
+	TLS                   bool
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+
+	// MaxLen caps the streams Publish appends to at about this many entries;
+	// Trim overrides it by stream name. Zero leaves streams unbounded.
+	MaxLen int64
+	Trim   map[string]TrimPolicy
+
+	// Producer numbers the entries Publish adds to each stream, under this
+	// name, so subscribers can detect lost entries (see sequence.go). Empty
+	// publishes unnumbered entries.
+	Producer string
+
+	// Consumer saves the subscriber's offsets under this name, so CatchUp,
+	// overridden by stream in CatchUpStreams, decides how the entries added
+	// while it was down are delivered (see catchup.go). Empty starts every
+	// subscription at the newest entries.
+	Consumer       string
+	CatchUp        CatchUpPolicy
+	CatchUpStreams map[string]CatchUpPolicy
+}
+
+func (o Options) mode() string {
+	switch {
+	case o.MasterName != "":
+		return "sentinel"
+	case o.Cluster:
+		return "cluster"
+	default:
+		return "single"
+	}
 }
 
-func NewRedisEventBus(host string, port int) (*RedisEventBus, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", host, port),
-		ReadTimeout:  10 * time.Second, // must be > Block in XREAD
-		WriteTimeout: 10 * time.Second,
-	})
+func NewRedisEventBus(opts Options) (*RedisEventBus, error) {
+	if len(opts.Addrs) == 0 {
+		return nil, fmt.Errorf("no Redis address configured")
+	}
+
+	var tlsConfig *tls.Config
+	if opts.TLS {
+		tlsConfig = &tls.Config{
+			ServerName:         opts.TLSServerName,
+			InsecureSkipVerify: opts.TLSInsecureSkipVerify,
+			MinVersion:         tls.VersionTLS12,
+		}
+	}
+
+	const (
+		readTimeout  = 10 * time.Second // must be > Block in XREAD
+		writeTimeout = 10 * time.Second
+	)
+
+	var client redis.UniversalClient
+	switch opts.mode() {
+	case "sentinel":
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       opts.MasterName,
+			SentinelAddrs:    opts.Addrs,
+			SentinelPassword: opts.SentinelPassword,
+			Username:         opts.Username,
+			Password:         opts.Password,
+			DB:               opts.DB,
+			TLSConfig:        tlsConfig,
+			ReadTimeout:      readTimeout,
+			WriteTimeout:     writeTimeout,
+		})
+	case "cluster":
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        opts.Addrs,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			TLSConfig:    tlsConfig,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+		})
+	default:
+		client = redis.NewClient(&redis.Options{
+			Addr:         opts.Addrs[0],
+			Username:     opts.Username,
+			Password:     opts.Password,
+			DB:           opts.DB,
+			TLSConfig:    tlsConfig,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+		})
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	log.Info().Str("addr", fmt.Sprintf("%s:%d", host, port)).Msg("Connected to Redis")
+	log.Info().
+		Strs("addrs", opts.Addrs).
+		Str("mode", opts.mode()).
+		Bool("tls", opts.TLS).
+		Msg("Connected to Redis")
+
+	bus := &RedisEventBus{
+		client:    client,
+		cluster:   opts.Cluster && opts.MasterName == "",
+		maxLen:    opts.MaxLen,
+		trim:      opts.Trim,
+		sequencer: newSequencer(opts.Producer),
+		health:    newConnectionHealth(),
+
+		consumer:       opts.Consumer,
+		catchUp:        opts.CatchUp,
+		catchUpStreams: opts.CatchUpStreams,
+	}
+	bus.sequences = newSequenceTracker(bus.deliveredID)
+	return bus, nil
+}
 
-	return &RedisEventBus{client: client}, nil
+// OnGap installs the function sequence gaps on subscribed streams are
+// reported to. It runs on the subscriber's goroutine, before the entry that
+// revealed the gap is handed to the handler.
+func (b *RedisEventBus) OnGap(handler func(Gap)) {
+	b.sequences.setHandler(handler)
 }
 
 func (b *RedisEventBus) Subscribe(ctx context.Context, streams []string, handler func(types.Event) error) error {
 	log.Info().Strs("streams", streams).Msg("Subscribing to streams")
 
+	if b.consumer != "" {
+		saved := make(chan struct{})
+		go func() {
+			b.runOffsetSaver(ctx)
+			close(saved)
+		}()
+		defer func() { <-saved }()
+	}
+
+	if b.cluster && len(streams) > 1 {
+		return b.subscribeEach(ctx, streams, handler)
+	}
+	return b.read(ctx, streams, handler)
+}
+
+// subscribeEach reads every stream on its own connection. A multi-key XREAD
+// fails with CROSSSLOT on a cluster unless all streams share a hash slot.
+// Events are still handed to the handler one at a time.
+func (b *RedisEventBus) subscribeEach(ctx context.Context, streams []string, handler func(types.Event) error) error {
+	var mu sync.Mutex
+	serialized := func(event types.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return handler(event)
+	}
+
+	errs := make(chan error, len(streams))
+	for _, stream := range streams {
+		go func(stream string) {
+			errs <- b.read(ctx, []string{stream}, serialized)
+		}(stream)
+	}
+
+	// Readers only return once ctx is done
+	err := <-errs
+	for i := 1; i < len(streams); i++ {
+		<-errs
+	}
+	return err
+}
+
+func (b *RedisEventBus) read(ctx context.Context, streams []string, handler func(types.Event) error) error {
 	// Create stream args for XREAD
 	args := &redis.XReadArgs{
 		Streams: append(streams, make([]string, len(streams))...),
@@ -45,9 +226,27 @@ func (b *RedisEventBus) Subscribe(ctx context.Context, streams []string, handler
 		Count:   10,
 	}
 
-	// Initialize last IDs to "$" (only new messages from now on)
-	for i := range streams {
-		args.Streams[len(streams)+i] = "$"
+	// Start after the current last entry of every stream, or the saved
+	// offset of the streams being caught up
+	for _, stream := range streams {
+		b.markDelivered(stream, subscriptionStart())
+	}
+	ids, catchUps, err := b.subscriptionStarts(ctx, streams)
+	for err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		b.health.lost(err)
+		log.Warn().Err(err).Msg("Failed to read stream positions, retrying...")
+		time.Sleep(time.Second)
+		ids, catchUps, err = b.subscriptionStarts(ctx, streams)
+	}
+	b.health.restored()
+	copy(args.Streams[len(streams):], ids)
+	for i, stream := range streams {
+		if catchUps[stream] != nil {
+			b.markDelivered(stream, ids[i])
+		}
 	}
 
 	for {
@@ -65,6 +264,13 @@ func (b *RedisEventBus) Subscribe(ctx context.Context, streams []string, handler
 				if ctx.Err() != nil {
 					return ctx.Err()
 				}
+				if isConnectionError(err) {
+					// Resubscribe from the last delivered entries
+					if err := b.reconnect(ctx, streams, args.Streams[len(streams):], err); err != nil {
+						return err
+					}
+					continue
+				}
 				log.Warn().Err(err).Msg("Failed to read from stream, retrying...")
 				time.Sleep(time.Second)
 				continue
@@ -73,12 +279,27 @@ func (b *RedisEventBus) Subscribe(ctx context.Context, streams []string, handler
 			// Process messages
 			for _, stream := range result {
 				for _, message := range stream.Messages {
-					event, err := b.parseEvent(message)
+					event, err := parseEvent(message)
 					if err != nil {
 						log.Error().Err(err).Str("stream", stream.Stream).Msg("Failed to parse event")
 						continue
 					}
 
+					b.sequences.observe(stream.Stream, message)
+
+					if c := catchUps[stream.Stream]; c != nil {
+						if streamIDBefore(c.until, message.ID) {
+							c.done()
+							delete(catchUps, stream.Stream)
+						} else {
+							event = c.mark(event, message.ID)
+							if message.ID == c.until {
+								c.done()
+								delete(catchUps, stream.Stream)
+							}
+						}
+					}
+
 					// Handle event
 					if err := handler(event); err != nil {
 						log.Error().Err(err).Str("event_type", event.Type).Msg("Failed to handle event")
@@ -90,6 +311,8 @@ func (b *RedisEventBus) Subscribe(ctx context.Context, streams []string, handler
 							args.Streams[len(streams)+i] = message.ID
 						}
 					}
+					b.markDelivered(stream.Stream, message.ID)
+					b.saveOffset(stream.Stream, message.ID)
 				}
 			}
 		}
@@ -97,22 +320,22 @@ func (b *RedisEventBus) Subscribe(ctx context.Context, streams []string, handler
 }
 
 func (b *RedisEventBus) Publish(ctx context.Context, stream string, event types.Event) error {
-	data, err := json.Marshal(event.Data)
+	values, err := encodeEvent(event)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event data: %w", err)
+		return err
 	}
 
-	values := map[string]interface{}{
-		"id":        event.ID,
-		"type":      event.Type,
-		"platform":  event.Platform,
-		"timestamp": event.Timestamp.Format(time.RFC3339),
-		"data":      string(data),
+	if b.sequencer != nil {
+		b.sequencer.mu.Lock()
+		defer b.sequencer.mu.Unlock()
+		b.sequencer.stamp(stream, values)
 	}
 
 	if err := b.client.XAdd(ctx, &redis.XAddArgs{
 		Stream: stream,
 		Values: values,
+		MaxLen: b.streamMaxLen(stream),
+		Approx: true,
 	}).Err(); err != nil {
 		return fmt.Errorf("failed to publish event: %w", err)
 	}
@@ -125,7 +348,79 @@ func (b *RedisEventBus) Publish(ctx context.Context, stream string, event types.
 	return nil
 }
 
-func (b *RedisEventBus) parseEvent(msg redis.XMessage) (types.Event, error) {
+// streamMaxLen returns the MAXLEN Publish applies to a stream
+func (b *RedisEventBus) streamMaxLen(stream string) int64 {
+	if policy, ok := b.trim[stream]; ok && policy.MaxLen > 0 {
+		return policy.MaxLen
+	}
+	return b.maxLen
+}
+
+// Range returns up to limit events published to a stream since the given time
+func (b *RedisEventBus) Range(ctx context.Context, stream string, since time.Time, limit int64) ([]types.Event, error) {
+	return b.RangeIDs(ctx, stream, StreamID(since), "+", limit)
+}
+
+// StreamID returns the first stream entry ID at or after t
+func StreamID(t time.Time) string {
+	return fmt.Sprintf("%d-0", t.UnixMilli())
+}
+
+// ParseStreamID splits a stream entry ID into its millisecond and sequence
+// parts. A bare millisecond ID has sequence 0 and found false.
+func ParseStreamID(id string) (ms, seq int64, found, ok bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	ms, err := strconv.ParseInt(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false, false
+	}
+	if !found {
+		return ms, 0, false, true
+	}
+	seq, err = strconv.ParseInt(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false, false
+	}
+	return ms, seq, true, true
+}
+
+// RangeIDs returns up to limit events with stream IDs between start and end,
+// inclusive. "-" and "+" stand for the first and last entry.
+func (b *RedisEventBus) RangeIDs(ctx context.Context, stream, start, end string, limit int64) ([]types.Event, error) {
+	messages, err := b.client.XRangeN(ctx, stream, start, end, limit).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream range: %w", err)
+	}
+
+	events := make([]types.Event, 0, len(messages))
+	for _, message := range messages {
+		event, err := parseEvent(message)
+		if err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// encodeEvent returns the stream entry fields of an event
+func encodeEvent(event types.Event) (map[string]interface{}, error) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	return map[string]interface{}{
+		"id":        event.ID,
+		"type":      event.Type,
+		"platform":  event.Platform,
+		"timestamp": event.Timestamp.Format(time.RFC3339),
+		"data":      string(data),
+	}, nil
+}
+
+func parseEvent(msg redis.XMessage) (types.Event, error) {
 	// Be tolerant to missing fields. Our publishers may not set "id".
 	event := types.Event{
 		ID:        msg.ID,
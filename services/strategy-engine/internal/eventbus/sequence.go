@@ -0,0 +1,154 @@
+package eventbus
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+)
+
+// Producers can number the entries they publish to each stream: a "producer"
+// field names the publishing process (its name plus its start time, so a
+// restart begins a new sequence) and "seq" counts its entries on that stream
+// from 1. The subscriber tracks the last sequence number per producer and
+// stream and reports a Gap when one is skipped, which happens when Redis
+// trimmed entries before they were read or a publish failed after the number
+// was taken. Entries without the fields, such as those of older producers, are
+// not checked.
+
+const (
+	producerField = "producer"
+	seqField      = "seq"
+)
+
+// Gap is a run of sequence numbers of one producer that never reached the
+// subscriber. AfterID is the last entry delivered from the stream before the
+// gap was noticed and BeforeID the entry that revealed it; the lost entries,
+// if Redis still had them when they were published, lay between the two.
+type Gap struct {
+	Stream     string    `json:"stream"`
+	Producer   string    `json:"producer"`
+	FromSeq    int64     `json:"from_seq"`
+	ToSeq      int64     `json:"to_seq"`
+	AfterID    string    `json:"after_id"`
+	BeforeID   string    `json:"before_id"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// Missing is the number of entries lost in the gap
+func (g Gap) Missing() int64 {
+	return g.ToSeq - g.FromSeq + 1
+}
+
+// sequencer numbers the entries a producer publishes
+type sequencer struct {
+	mu       sync.Mutex
+	producer string
+	next     map[string]int64 // stream -> last sequence number taken
+}
+
+func newSequencer(name string) *sequencer {
+	if name == "" {
+		return nil
+	}
+	return &sequencer{
+		producer: fmt.Sprintf("%s/%d", name, time.Now().UnixMilli()),
+		next:     make(map[string]int64),
+	}
+}
+
+// stamp adds the producer and the next sequence number of a stream to the
+// fields of an entry. The caller holds s.mu until the entry is added, so
+// entries land on the stream in sequence order.
+func (s *sequencer) stamp(stream string, values map[string]interface{}) {
+	s.next[stream]++
+	values[producerField] = s.producer
+	values[seqField] = strconv.FormatInt(s.next[stream], 10)
+}
+
+// sequenceTracker follows the sequence numbers a subscriber receives
+type sequenceTracker struct {
+	mu        sync.Mutex
+	last      map[string]int64 // stream + "\x00" + producer -> last sequence number
+	lost      map[string]int64 // stream -> entries lost since the subscription started
+	onGap     func(Gap)
+	delivered func(stream string) string
+}
+
+func newSequenceTracker(delivered func(stream string) string) *sequenceTracker {
+	return &sequenceTracker{
+		last:      make(map[string]int64),
+		lost:      make(map[string]int64),
+		delivered: delivered,
+	}
+}
+
+// setHandler installs the function gaps are reported to
+func (t *sequenceTracker) setHandler(handler func(Gap)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onGap = handler
+}
+
+// observe checks the sequence number of an entry about to be delivered and
+// reports a gap before it to the handler. It must run before the entry is
+// marked delivered.
+func (t *sequenceTracker) observe(stream string, message redis.XMessage) {
+	producer, _ := message.Values[producerField].(string)
+	raw, _ := message.Values[seqField].(string)
+	if producer == "" || raw == "" {
+		return
+	}
+	seq, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return
+	}
+
+	key := stream + "\x00" + producer
+	t.mu.Lock()
+	last, seen := t.last[key]
+	if seq > last {
+		t.last[key] = seq
+	}
+	// The first entry of a producer starts its sequence wherever it is;
+	// lower numbers are entries read again
+	if !seen || seq <= last+1 {
+		t.mu.Unlock()
+		return
+	}
+	gap := Gap{
+		Stream:     stream,
+		Producer:   producer,
+		FromSeq:    last + 1,
+		ToSeq:      seq - 1,
+		AfterID:    t.delivered(stream),
+		BeforeID:   message.ID,
+		DetectedAt: time.Now().UTC(),
+	}
+	t.lost[stream] += gap.Missing()
+	handler := t.onGap
+	t.mu.Unlock()
+
+	log.Warn().
+		Str("stream", stream).
+		Str("producer", producer).
+		Int64("from_seq", gap.FromSeq).
+		Int64("to_seq", gap.ToSeq).
+		Str("after_id", gap.AfterID).
+		Str("before_id", gap.BeforeID).
+		Msg("Events lost: sequence gap on stream")
+
+	if handler != nil {
+		handler(gap)
+	}
+}
+
+// lostCount returns how many entries of a stream were lost so far
+func (t *sequenceTracker) lostCount(stream string) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lost[stream]
+}
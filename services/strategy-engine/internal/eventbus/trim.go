@@ -0,0 +1,98 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+)
+
+// TrimPolicy bounds a stream. MaxLen caps it at about that many entries and
+// MaxAge drops entries older than that; zero leaves the respective bound off.
+// Trimming is approximate on Redis: it removes whole macro nodes only, so a
+// stream may keep somewhat more than the bounds allow.
+type TrimPolicy struct {
+	MaxLen int64
+	MaxAge time.Duration
+}
+
+func (p TrimPolicy) empty() bool {
+	return p.MaxLen <= 0 && p.MaxAge <= 0
+}
+
+// Trim applies a policy to a stream and returns how many entries it removed
+func (b *RedisEventBus) Trim(ctx context.Context, stream string, policy TrimPolicy) (int64, error) {
+	var removed int64
+	if policy.MaxLen > 0 {
+		n, err := b.client.XTrimMaxLenApprox(ctx, stream, policy.MaxLen, 0).Result()
+		if err != nil {
+			return removed, fmt.Errorf("failed to trim stream %s: %w", stream, err)
+		}
+		removed += n
+	}
+	if policy.MaxAge > 0 {
+		n, err := b.client.XTrimMinIDApprox(ctx, stream, StreamID(time.Now().Add(-policy.MaxAge)), 0).Result()
+		if err != nil {
+			return removed, fmt.Errorf("failed to trim stream %s: %w", stream, err)
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// Trim applies a policy to a stream exactly and returns how many entries it
+// removed
+func (b *InMemory) Trim(ctx context.Context, stream string, policy TrimPolicy) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	messages := b.streams[stream]
+	keep := len(messages)
+	if policy.MaxLen > 0 && int64(keep) > policy.MaxLen {
+		keep = int(policy.MaxLen)
+	}
+	if policy.MaxAge > 0 {
+		minID := StreamID(time.Now().Add(-policy.MaxAge))
+		for keep > 0 && streamIDBefore(messages[len(messages)-keep].ID, minID) {
+			keep--
+		}
+	}
+
+	removed := len(messages) - keep
+	if removed > 0 {
+		b.streams[stream] = append([]redis.XMessage(nil), messages[removed:]...)
+	}
+	return int64(removed), nil
+}
+
+// RunTrimmer applies the policies to their streams every interval until ctx
+// is cancelled. It is meant for the streams the caller owns; bounding them on
+// Publish alone leaves them at their size when publishing stops.
+func RunTrimmer(ctx context.Context, bus EventBus, policies map[string]TrimPolicy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for stream, policy := range policies {
+			if policy.empty() {
+				continue
+			}
+			removed, err := bus.Trim(ctx, stream, policy)
+			if err != nil {
+				log.Error().Err(err).Str("stream", stream).Msg("Failed to trim stream")
+				continue
+			}
+			if removed > 0 {
+				log.Debug().Str("stream", stream).Int64("removed", removed).Msg("Trimmed stream")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
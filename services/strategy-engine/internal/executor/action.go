@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Order actions are requested with Command.Action:
+//
+//	"buy" (default), "open":  buys Shares of Side at Price or better
+//	"sell", "close":          sells Shares of Side held by the account at
+//	                          Price or better
+//
+// Platforms whose account service sells natively (Platform.Sells) receive
+// "action": "sell" in the order payload. Elsewhere, and for accounts traded
+// directly on the CLOB, a sell is emulated by buying the opposite outcome at
+// the complement price: holding both outcomes of a market is flat, since one
+// of them pays out 1.
+
+const (
+	ActionBuy   = "buy"
+	ActionSell  = "sell"
+	ActionOpen  = "open"
+	ActionClose = "close"
+)
+
+// ValidAction reports whether a is a known action; empty means buy
+func ValidAction(a string) bool {
+	switch normalizeAction(a) {
+	case ActionBuy, ActionSell:
+		return true
+	}
+	return false
+}
+
+// SetNativeSells declares that a platform's account service sells natively
+func (e *Executor) SetNativeSells(platform string) {
+	if e.nativeSells == nil {
+		e.nativeSells = make(map[string]bool)
+	}
+	e.nativeSells[platform] = true
+}
+
+// normalizeAction maps an action to buy or sell
+func normalizeAction(a string) string {
+	switch a = strings.ToLower(a); a {
+	case "", ActionOpen:
+		return ActionBuy
+	case ActionClose:
+		return ActionSell
+	}
+	return a
+}
+
+// applyAction validates the action of a place_order and rewrites sells the
+// platform cannot take into buys of the opposite outcome
+func (e *Executor) applyAction(cmd types.Command) (types.Command, error) {
+	a := normalizeAction(cmd.Action)
+	if !ValidAction(a) {
+		return cmd, fmt.Errorf("%w: unknown action %q", ErrInvalidOrder, cmd.Action)
+	}
+	cmd.Action = a
+	if a == ActionBuy {
+		return cmd, nil
+	}
+
+	if _, viaCLOB := e.clobClient(cmd); e.nativeSells[cmd.Platform] && !viaCLOB {
+		return cmd, nil
+	}
+
+	// The opposite of one outcome of a multi-outcome market is every other
+	// outcome, which a single buy cannot express
+	if cmd.OutcomeID != "" && cmd.Side != "yes" && cmd.Side != "no" {
+		return cmd, fmt.Errorf("%w: %s cannot sell outcome %s of a multi-outcome market", ErrInvalidOrder, cmd.Platform, cmd.OutcomeID)
+	}
+
+	side := "yes"
+	if cmd.Side == "yes" {
+		side = "no"
+	}
+	metadata := make(map[string]interface{}, len(cmd.Metadata)+2)
+	for k, v := range cmd.Metadata {
+		metadata[k] = v
+	}
+	metadata["emulated_action"] = ActionSell
+	metadata["sell_side"] = cmd.Side
+	if cmd.OutcomeID != "" {
+		metadata["sell_outcome"] = cmd.OutcomeID
+	}
+
+	// The outcome token of the other side is looked up from the side
+	cmd.Action = ActionBuy
+	cmd.Side = side
+	cmd.OutcomeID = ""
+	if cmd.Price > 0 {
+		cmd.Price = 1 - cmd.Price
+	}
+	cmd.Metadata = metadata
+	return cmd, nil
+}
@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+func newTestExecutor(t *testing.T) *Executor {
+	t.Helper()
+
+	e, err := NewExecutor(map[string]Platform{
+		"predict":    {URL: "http://127.0.0.1:1", Sells: true},
+		"polymarket": {URL: "http://127.0.0.1:1"},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestApplyAction(t *testing.T) {
+	e := newTestExecutor(t)
+
+	tests := []struct {
+		name       string
+		cmd        types.Command
+		wantAction string
+		wantSide   string
+		wantPrice  float64
+		emulated   bool
+	}{
+		{"buy by default", types.Command{Platform: "polymarket", Side: "yes", Price: 0.4}, ActionBuy, "yes", 0.4, false},
+		{"open is a buy", types.Command{Platform: "polymarket", Action: "open", Side: "no", Price: 0.4}, ActionBuy, "no", 0.4, false},
+		{"native sell", types.Command{Platform: "predict", Action: "sell", Side: "yes", Price: 0.6}, ActionSell, "yes", 0.6, false},
+		{"close is a sell", types.Command{Platform: "predict", Action: "CLOSE", Side: "no", Price: 0.3}, ActionSell, "no", 0.3, false},
+		{"emulated sell of yes", types.Command{Platform: "polymarket", Action: "sell", Side: "yes", Price: 0.6}, ActionBuy, "no", 0.4, true},
+		{"emulated sell of no", types.Command{Platform: "polymarket", Action: "close", Side: "no", Price: 0.25}, ActionBuy, "yes", 0.75, true},
+		{"emulated market sell", types.Command{Platform: "polymarket", Action: "sell", Side: "yes"}, ActionBuy, "no", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cmd.Type = "place_order"
+			got, err := e.applyAction(tt.cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Action != tt.wantAction || got.Side != tt.wantSide || math.Abs(got.Price-tt.wantPrice) > 1e-9 {
+				t.Errorf("got %s %s at %v, want %s %s at %v", got.Action, got.Side, got.Price, tt.wantAction, tt.wantSide, tt.wantPrice)
+			}
+
+			_, emulated := got.Metadata["emulated_action"]
+			if emulated != tt.emulated {
+				t.Errorf("emulated_action set: %t, want %t", emulated, tt.emulated)
+			}
+			if tt.emulated && got.Metadata["sell_side"] != tt.cmd.Side {
+				t.Errorf("sell_side = %v, want %s", got.Metadata["sell_side"], tt.cmd.Side)
+			}
+		})
+	}
+}
+
+func TestApplyActionKeepsCallerMetadata(t *testing.T) {
+	e := newTestExecutor(t)
+
+	metadata := map[string]interface{}{"strategy": "dn"}
+	got, err := e.applyAction(types.Command{Type: "place_order", Platform: "polymarket", Action: "sell", Side: "yes", Price: 0.6, Metadata: metadata})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata["strategy"] != "dn" {
+		t.Errorf("metadata lost: %v", got.Metadata)
+	}
+	if _, ok := metadata["emulated_action"]; ok {
+		t.Error("the caller's metadata map was modified")
+	}
+}
+
+func TestApplyActionErrors(t *testing.T) {
+	e := newTestExecutor(t)
+
+	for _, cmd := range []types.Command{
+		{Type: "place_order", Platform: "predict", Action: "short", Side: "yes", Price: 0.5},
+		// Selling one outcome of a multi-outcome market cannot be emulated
+		{Type: "place_order", Platform: "polymarket", Action: "sell", OutcomeID: "tok-1", Side: "trump", Price: 0.5},
+	} {
+		if _, err := e.applyAction(cmd); !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("applyAction(%s %s %s): got %v, want ErrInvalidOrder", cmd.Action, cmd.Platform, cmd.Side, err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Execution algos slice a large place_order into child orders so it does not
+// move thin markets. They are selected through command metadata:
+//
+//	"algo": "twap"     "algo_slices": 5, "algo_duration": 60         (seconds)
+//	"algo": "iceberg"  "algo_visible": 10, "algo_timeout": 300       (seconds)
+//
+// TWAP places equal slices spread evenly over the duration. Iceberg shows only
+// the visible size and places the next child once the previous one fills (or
+// immediately in dry-run, where nothing rests on the book).
+// Algos run in the background; ExecuteCommands returns once they are started.
+// CancelAlgos stops every running algo, e.g. when the kill switch engages.
+
+const (
+	defaultTWAPSlices     = 5
+	defaultTWAPDuration   = time.Minute
+	defaultIcebergTimeout = 5 * time.Minute
+	icebergPollInterval   = time.Second
+)
+
+func (e *Executor) startAlgo(ctx context.Context, cmd types.Command, algo string) error {
+	switch algo {
+	case "twap":
+		slices := metadataInt(cmd.Metadata, "algo_slices", defaultTWAPSlices)
+		duration := metadataSeconds(cmd.Metadata, "algo_duration", defaultTWAPDuration)
+		if slices < 1 {
+			return fmt.Errorf("invalid algo_slices: %d", slices)
+		}
+		ctx, done := e.algos.start(ctx)
+		go func() {
+			defer done()
+			e.runTWAP(ctx, cmd, slices, duration)
+		}()
+		return nil
+
+	case "iceberg":
+		visible, _ := cmd.Metadata["algo_visible"].(float64)
+		if visible <= 0 {
+			return fmt.Errorf("iceberg requires a positive algo_visible size")
+		}
+		timeout := metadataSeconds(cmd.Metadata, "algo_timeout", defaultIcebergTimeout)
+		ctx, done := e.algos.start(ctx)
+		go func() {
+			defer done()
+			e.runIceberg(ctx, cmd, visible, timeout)
+		}()
+		return nil
+
+	default:
+		return fmt.Errorf("unknown execution algo: %s", algo)
+	}
+}
+
+// CancelAlgos stops every running execution algo and returns how many were
+// running. Child orders already placed are left open.
+func (e *Executor) CancelAlgos() int {
+	return e.algos.cancelAll()
+}
+
+// algoSet keeps the cancel functions of the running algos
+type algoSet struct {
+	mu      sync.Mutex
+	next    int
+	cancels map[int]context.CancelFunc
+}
+
+// start derives the context of a new algo; done must be called once it ends
+func (s *algoSet) start(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancels == nil {
+		s.cancels = make(map[int]context.CancelFunc)
+	}
+	id := s.next
+	s.next++
+	s.cancels[id] = cancel
+
+	return ctx, func() {
+		s.mu.Lock()
+		delete(s.cancels, id)
+		s.mu.Unlock()
+		cancel()
+	}
+}
+
+func (s *algoSet) cancelAll() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	return len(s.cancels)
+}
+
+func (e *Executor) runTWAP(ctx context.Context, parent types.Command, slices int, duration time.Duration) {
+	sizes := splitShares(parent.Shares, slices)
+	interval := time.Duration(0)
+	if len(sizes) > 1 {
+		interval = duration / time.Duration(len(sizes)-1)
+	}
+
+	log.Info().
+		Str("account", parent.AccountID).
+		Str("market", parent.MarketID).
+		Float64("shares", parent.Shares).
+		Int("slices", len(sizes)).
+		Dur("interval", interval).
+		Msg("Starting TWAP execution")
+
+	for i, size := range sizes {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				log.Warn().Int("placed", i).Int("slices", len(sizes)).Msg("TWAP cancelled")
+				return
+			case <-time.After(interval):
+			}
+		}
+
+		if err := e.placeOrder(ctx, childCommand(parent, "twap", i, size)); err != nil {
+			log.Error().Err(err).Int("slice", i).Msg("TWAP child order failed")
+		}
+	}
+}
+
+func (e *Executor) runIceberg(ctx context.Context, parent types.Command, visible float64, timeout time.Duration) {
+	remaining := parent.Shares
+	deadline := time.Now().Add(timeout)
+
+	log.Info().
+		Str("account", parent.AccountID).
+		Str("market", parent.MarketID).
+		Float64("shares", parent.Shares).
+		Float64("visible", visible).
+		Msg("Starting iceberg execution")
+
+	for i := 0; remaining > 1e-9; i++ {
+		size := math.Min(visible, remaining)
+		child := childCommand(parent, "iceberg", i, size)
+
+		orderID, err := e.placeChild(ctx, child)
+		if err != nil {
+			log.Error().Err(err).Int("slice", i).Msg("Iceberg child order failed, stopping")
+			return
+		}
+		remaining -= size
+
+		if remaining <= 1e-9 || orderID == "" || e.tracker == nil {
+			continue
+		}
+
+		// Wait for the visible child to fill before showing the next one
+		for {
+			if _, open := e.tracker.Get(child.Platform, orderID); !open {
+				break
+			}
+			if time.Now().After(deadline) {
+				log.Warn().
+					Str("order_id", orderID).
+					Float64("unplaced", remaining).
+					Msg("Iceberg timed out waiting for fill")
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(icebergPollInterval):
+			}
+		}
+	}
+}
+
+// placeChild places a child order and returns its venue order ID when the
+// order is live (empty in dry-run or when the venue returns no ID).
+func (e *Executor) placeChild(ctx context.Context, cmd types.Command) (string, error) {
+	cmd, err := e.normalizeOrder(cmd)
+	if err != nil {
+		return "", e.refuseOrder(ctx, cmd, err)
+	}
+
+	start := time.Now()
+	result, err := e.sendOrder(ctx, cmd)
+	e.recordOrder(ctx, cmd, result, err, time.Since(start))
+	if err != nil {
+		return "", err
+	}
+
+	e.trackOrder(cmd, result)
+
+	if result.DryRun() {
+		return "", nil
+	}
+	return result.ID(), nil
+}
+
+func childCommand(parent types.Command, algo string, index int, shares float64) types.Command {
+	metadata := make(map[string]interface{}, len(parent.Metadata)+3)
+	for k, v := range parent.Metadata {
+		metadata[k] = v
+	}
+	delete(metadata, "algo")
+	metadata["algo_parent"] = algo
+	metadata["algo_slice"] = index
+	metadata["algo_parent_shares"] = parent.Shares
+
+	child := parent
+	child.Shares = shares
+	child.Metadata = metadata
+	if parent.ClientOrderID != "" {
+		child.ClientOrderID = fmt.Sprintf("%s-%s-%d", parent.ClientOrderID, algo, index)
+	}
+	return child
+}
+
+// splitShares divides shares into n slices rounded to 0.01, with the
+// remainder on the last slice. Slices that would round to zero are dropped.
+func splitShares(shares float64, n int) []float64 {
+	slice := math.Floor(shares/float64(n)*100) / 100
+	if slice <= 0 {
+		return []float64{shares}
+	}
+
+	sizes := make([]float64, n)
+	for i := range sizes {
+		sizes[i] = slice
+	}
+	sizes[n-1] = math.Round((shares-slice*float64(n-1))*100) / 100
+	return sizes
+}
+
+func metadataInt(metadata map[string]interface{}, key string, fallback int) int {
+	if v, ok := metadata[key].(float64); ok {
+		return int(v)
+	}
+	if v, ok := metadata[key].(int); ok {
+		return v
+	}
+	return fallback
+}
+
+func metadataSeconds(metadata map[string]interface{}, key string, fallback time.Duration) time.Duration {
+	if v, ok := metadata[key].(float64); ok && v > 0 {
+		return time.Duration(v * float64(time.Second))
+	}
+	return fallback
+}
+
+// metadataStrings reads a list of strings from command metadata, which holds
+// []interface{} once a command went through JSON
+func metadataStrings(metadata map[string]interface{}, key string) []string {
+	switch v := metadata[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/accountsvc"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Amending: an amend_order command replaces the resting order in metadata
+// "order_id" with an order on the command's terms; Shares is the new total
+// size. Account services that implement POST /trade/amend (Platform.Amend)
+// take the order to replace and the new terms in one request,
+//
+//	{"order_id": "...", <same payload as /trade>}
+//
+// and replace it atomically, keeping its queue position where the venue
+// allows. Elsewhere, on the CLOB, or once the service answered 404 or 405,
+// the order is cancelled and a new one placed, with safeguards:
+//
+//   - nothing is placed unless the cancel succeeded, so a failed cancel can
+//     never leave two orders resting
+//   - what the old order filled, as the tracker knows it, is taken off the
+//     new one; if nothing is left only the cancel happens
+//   - a replacement that fails after the cancel is an *AmendError with
+//     Cancelled set, so the strategy knows the order is gone
+
+// AmendError is a failed amend_order. Cancelled means the old order was
+// cancelled but its replacement was not placed.
+type AmendError struct {
+	OrderID   string
+	Cancelled bool
+	Err       error
+}
+
+func (e *AmendError) Error() string {
+	if e.Cancelled {
+		return fmt.Sprintf("order %s cancelled but not replaced: %v", e.OrderID, e.Err)
+	}
+	return fmt.Sprintf("order %s not amended: %v", e.OrderID, e.Err)
+}
+
+func (e *AmendError) Unwrap() error { return e.Err }
+
+// amendOrder replaces a resting order, atomically where the platform can
+func (e *Executor) amendOrder(ctx context.Context, cmd types.Command) error {
+	orderID, _ := cmd.Metadata["order_id"].(string)
+	if orderID == "" {
+		return fmt.Errorf("amend_order requires metadata order_id")
+	}
+
+	if client, ok := e.amendClient(cmd); ok {
+		err := e.amendAtomically(ctx, client, cmd, orderID)
+		var rejected *OrderRejectedError
+		if !errors.As(err, &rejected) || (rejected.StatusCode != http.StatusNotFound && rejected.StatusCode != http.StatusMethodNotAllowed) {
+			return err
+		}
+		client.amendUnsupported.Store(true)
+		log.Warn().
+			Str("platform", cmd.Platform).
+			Int("status", rejected.StatusCode).
+			Msg("Account service does not support amending orders, cancelling and placing instead")
+	}
+	return e.cancelReplace(ctx, cmd, orderID)
+}
+
+// amendClient returns the account service that amends the command's order
+// in one request, if any
+func (e *Executor) amendClient(cmd types.Command) (*platformClient, bool) {
+	if _, direct := e.clobClient(cmd); direct {
+		return nil, false
+	}
+	client, ok := e.platforms[cmd.Platform]
+	if !ok || !client.config.Amend || client.amendUnsupported.Load() {
+		return nil, false
+	}
+	return client, true
+}
+
+// amendAtomically sends the amendment to the account service. A 404 or 405
+// answer is returned as is, before anything is recorded.
+func (e *Executor) amendAtomically(ctx context.Context, client *platformClient, cmd types.Command, orderID string) error {
+	cmd, err := e.normalizeOrder(cmd)
+	if err != nil {
+		return e.refuseOrder(ctx, cmd, &AmendError{OrderID: orderID, Err: err})
+	}
+
+	start := time.Now()
+	result, err := client.service.AmendOrder(ctx, accountsvc.AmendRequest{
+		OrderRequest: e.orderRequest(cmd),
+		OrderID:      orderID,
+	})
+	latency := time.Since(start)
+	if err = outcomeError(serviceError(err)); err != nil {
+		var rejected *OrderRejectedError
+		if errors.As(err, &rejected) && (rejected.StatusCode == http.StatusNotFound || rejected.StatusCode == http.StatusMethodNotAllowed) {
+			return err
+		}
+		err = &AmendError{OrderID: orderID, Err: err}
+	}
+
+	if err == nil && e.tracker != nil && !result.DryRun() {
+		e.tracker.Remove(cmd.Platform, orderID)
+	}
+	if err := e.finishOrder(ctx, cmd, result, err, latency); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("platform", cmd.Platform).
+		Str("account", cmd.AccountID).
+		Str("replaced_order_id", orderID).
+		Str("order_id", result.ID()).
+		Msg("Order amended")
+	return nil
+}
+
+// cancelReplace cancels the order and places its replacement
+func (e *Executor) cancelReplace(ctx context.Context, cmd types.Command, orderID string) error {
+	// Shares are needed to take off the fills, and a size that cannot be
+	// converted must fail before anything is cancelled
+	cmd, err := e.ConvertSize(cmd)
+	if err != nil {
+		return e.refuseOrder(ctx, cmd, &AmendError{OrderID: orderID, Err: err})
+	}
+
+	// The cancel drops the order from the tracker, so its fills are read
+	// first. Fills reported while the cancel is in flight are not seen.
+	var filled float64
+	if e.tracker != nil {
+		if order, ok := e.tracker.Get(cmd.Platform, orderID); ok {
+			filled = order.FilledShares
+		}
+	}
+
+	cancel := cmd
+	cancel.Type = "cancel_order"
+	if err := e.cancelOrder(ctx, cancel); err != nil {
+		return &AmendError{OrderID: orderID, Err: err}
+	}
+
+	replacement := cmd
+	replacement.Type = "place_order"
+	replacement.Shares = cmd.Shares - filled
+	metadata := make(map[string]interface{}, len(cmd.Metadata)+1)
+	for k, v := range cmd.Metadata {
+		if k != "order_id" {
+			metadata[k] = v
+		}
+	}
+	metadata["replaces_order_id"] = orderID
+	replacement.Metadata = metadata
+
+	if replacement.Shares <= 0 {
+		log.Info().
+			Str("platform", cmd.Platform).
+			Str("order_id", orderID).
+			Float64("filled", filled).
+			Msg("Amended order already filled its new size, cancelled without replacement")
+		return nil
+	}
+
+	if err := e.placeOrder(ctx, replacement); err != nil {
+		log.Error().
+			Err(err).
+			Str("platform", cmd.Platform).
+			Str("account", cmd.AccountID).
+			Str("order_id", orderID).
+			Msg("Order cancelled for amendment but its replacement failed")
+		return &AmendError{OrderID: orderID, Cancelled: true, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/accountsvc"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Batch execution: account services that implement POST /trade/batch take
+// several orders in one request,
+//
+//	{"orders": [<same payload as /trade>, ...]}
+//
+// and answer with one result per order, in the same order:
+//
+//	{"results": [{"order_id": "..."}, {"error": "...", "error_code": "...", "status_code": 400}, ...]}
+//
+// Enabled per platform with BatchSize. Plain place_order commands (no algo)
+// that follow each other in ExecuteCommands are collected per platform and
+// sent in chunks of up to BatchSize. An item with an error fails only its own
+// command. If the service answers 404 or 405 nothing was placed, so the
+// orders are sent one by one and batching is switched off for the platform.
+
+// batchQueue holds orders waiting to be sent, per platform in first-seen order
+type batchQueue struct {
+	platforms []string
+	orders    map[string][]types.Command
+}
+
+func newBatchQueue() *batchQueue {
+	return &batchQueue{orders: make(map[string][]types.Command)}
+}
+
+func (q *batchQueue) add(cmd types.Command) {
+	if _, ok := q.orders[cmd.Platform]; !ok {
+		q.platforms = append(q.platforms, cmd.Platform)
+	}
+	q.orders[cmd.Platform] = append(q.orders[cmd.Platform], cmd)
+}
+
+// batchable reports whether cmd may be sent as part of a batch
+func (e *Executor) batchable(cmd types.Command) bool {
+	if cmd.Type != "place_order" {
+		return false
+	}
+	if algo, _ := cmd.Metadata["algo"].(string); algo != "" {
+		return false
+	}
+	if _, direct := e.clobClient(cmd); direct {
+		return false
+	}
+	client, ok := e.platforms[cmd.Platform]
+	return ok && client.config.BatchSize > 1 && !client.batchUnsupported.Load()
+}
+
+// flushBatches sends and empties the queue, reporting failed commands to fail
+func (e *Executor) flushBatches(ctx context.Context, q *batchQueue, fail func(types.Command, error)) {
+	for _, platform := range q.platforms {
+		pending := q.orders[platform]
+		size := e.platforms[platform].config.BatchSize
+		for len(pending) > 0 {
+			n := min(size, len(pending))
+			var chunk []types.Command
+			for _, cmd := range pending[:n] {
+				if err := e.checkDeadline(cmd, time.Now()); err != nil {
+					fail(cmd, err)
+					continue
+				}
+				chunk = append(chunk, cmd)
+			}
+			pending = pending[n:]
+			if len(chunk) == 0 {
+				continue
+			}
+
+			batchCtx, cancel := batchContext(ctx, chunk)
+			for i, err := range e.placeBatch(batchCtx, platform, chunk) {
+				if err != nil {
+					fail(chunk[i], err)
+				}
+			}
+			cancel()
+		}
+	}
+	q.platforms = q.platforms[:0]
+	clear(q.orders)
+}
+
+// placeBatch places orders on one platform and returns an error per order
+func (e *Executor) placeBatch(ctx context.Context, platform string, cmds []types.Command) []error {
+	errs := make([]error, len(cmds))
+	if len(cmds) == 1 {
+		errs[0] = e.placeOrder(ctx, cmds[0])
+		return errs
+	}
+
+	// Orders failing normalization are left out of the request
+	var sent []int
+	var items []accountsvc.OrderRequest
+	for i, cmd := range cmds {
+		normalized, err := e.normalizeOrder(cmd)
+		if err != nil {
+			errs[i] = e.refuseOrder(ctx, normalized, err)
+			continue
+		}
+		cmds[i] = normalized
+		sent = append(sent, i)
+		items = append(items, e.orderRequest(normalized))
+	}
+	if len(items) == 0 {
+		return errs
+	}
+
+	start := time.Now()
+	results, err := e.platforms[platform].service.PlaceOrders(ctx, items)
+	latency := time.Since(start)
+	err = outcomeError(serviceError(err))
+
+	var rejected *OrderRejectedError
+	if errors.As(err, &rejected) && (rejected.StatusCode == http.StatusNotFound || rejected.StatusCode == http.StatusMethodNotAllowed) {
+		e.platforms[platform].batchUnsupported.Store(true)
+		log.Warn().
+			Str("platform", platform).
+			Int("status", rejected.StatusCode).
+			Msg("Account service does not support batch orders, sending them one by one")
+		for _, i := range sent {
+			errs[i] = e.placeOrder(ctx, cmds[i])
+		}
+		return errs
+	}
+
+	for n, i := range sent {
+		itemErr := err
+		var result *accountsvc.OrderResult
+		if err == nil {
+			if n < len(results) {
+				result = &results[n].OrderResult
+				itemErr = serviceError(results[n].Err())
+			} else {
+				itemErr = errors.New("order missing from batch response")
+			}
+		}
+		errs[i] = e.finishOrder(ctx, cmds[i], result, itemErr, latency)
+	}
+
+	log.Debug().
+		Str("platform", platform).
+		Int("orders", len(items)).
+		Dur("latency", latency).
+		Msg("Batch of orders sent")
+
+	return errs
+}
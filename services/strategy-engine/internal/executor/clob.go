@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/accountsvc"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/clob"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Platforms with Platform.CLOB set place and cancel the orders of the accounts
+// listed there directly on the Polymarket CLOB, saving the hop through the
+// account service on the hedge path. Everything else (other accounts,
+// positions, flattening, market metadata) still goes to the account service.
+// The CLOB enforces every time in force natively, so none is emulated, and
+// direct orders are never batched. In dry-run mode nothing is sent.
+
+// clobClient returns the CLOB client that handles the command's account, if any
+func (e *Executor) clobClient(cmd types.Command) (*clob.Client, bool) {
+	client, ok := e.platforms[cmd.Platform]
+	if !ok || client.clob == nil || !client.clob.HasAccount(cmd.AccountID) {
+		return nil, false
+	}
+	return client.clob, true
+}
+
+// sendCLOBOrder places an order on the CLOB and returns a result shaped like
+// the account service's
+func (e *Executor) sendCLOBOrder(ctx context.Context, client *clob.Client, cmd types.Command) (*accountsvc.OrderResult, error) {
+	if e.dryRunFor(cmd) {
+		return &accountsvc.OrderResult{Status: accountsvc.StatusDryRun}, nil
+	}
+
+	order := clob.Order{
+		AccountID:   cmd.AccountID,
+		MarketID:    cmd.MarketID,
+		Side:        cmd.Side,
+		Price:       cmd.Price,
+		Shares:      cmd.Shares,
+		TimeInForce: timeInForce(cmd),
+	}
+	order.TokenID = cmd.OutcomeID
+	if order.TokenID == "" {
+		order.TokenID, _ = cmd.Metadata["token_id"].(string)
+	}
+	if order.TimeInForce == "GTD" {
+		if expireAt, ok := cmd.Metadata["expire_at"].(string); ok {
+			t, err := time.Parse(time.RFC3339, expireAt)
+			if err != nil {
+				return nil, err
+			}
+			order.ExpiresAt = t
+		} else if cmd.ExpiresAt != nil {
+			order.ExpiresAt = *cmd.ExpiresAt
+		}
+	}
+
+	result, err := client.PlaceOrder(ctx, order)
+	if err != nil {
+		return nil, outcomeError(clobError(err))
+	}
+	return &accountsvc.OrderResult{OrderID: result.OrderID, Status: result.Status}, nil
+}
+
+// clobError reports CLOB refusals as *OrderRejectedError, like the account
+// service's, so they are journaled and classified the same way
+func clobError(err error) error {
+	var apiErr *clob.APIError
+	if errors.As(err, &apiErr) {
+		return &OrderRejectedError{StatusCode: apiErr.StatusCode, Body: apiErr.Body}
+	}
+	return err
+}
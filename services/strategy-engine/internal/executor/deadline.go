@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Command.Deadline says how long a command stays worth sending. A command
+// whose deadline passed before it was dispatched, e.g. after waiting in the
+// outbox or behind a batch, is skipped with ErrDeadlineExceeded and counted as
+// "expired" in OrderCounts. Otherwise the deadline bounds the request to the
+// account service: for a batch, the earliest deadline of its orders. An algo
+// order started before its deadline runs to completion.
+
+// ErrDeadlineExceeded is returned for commands skipped because their deadline
+// passed before they were sent
+var ErrDeadlineExceeded = errors.New("command deadline passed before dispatch")
+
+// checkDeadline fails commands whose deadline passed by now
+func (e *Executor) checkDeadline(cmd types.Command, now time.Time) error {
+	if cmd.Deadline == nil || now.Before(*cmd.Deadline) {
+		return nil
+	}
+	e.orderCounts.add("expired")
+	return fmt.Errorf("%w: deadline %s, %s ago",
+		ErrDeadlineExceeded, cmd.Deadline.UTC().Format(time.RFC3339Nano), now.Sub(*cmd.Deadline).Round(time.Millisecond))
+}
+
+// commandContext bounds ctx by the command's deadline. Algos outlive the call
+// that starts them and keep ctx.
+func commandContext(ctx context.Context, cmd types.Command) (context.Context, context.CancelFunc) {
+	if cmd.Deadline == nil {
+		return ctx, func() {}
+	}
+	if algo, _ := cmd.Metadata["algo"].(string); algo != "" {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, *cmd.Deadline)
+}
+
+// batchContext bounds ctx by the earliest deadline of a batch
+func batchContext(ctx context.Context, cmds []types.Command) (context.Context, context.CancelFunc) {
+	var earliest *time.Time
+	for _, cmd := range cmds {
+		if cmd.Deadline != nil && (earliest == nil || cmd.Deadline.Before(*earliest)) {
+			earliest = cmd.Deadline
+		}
+	}
+	if earliest == nil {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, *earliest)
+}
@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orders"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// fakeService is an account service accepting every order as resting, or
+// answering /trade with reject when set
+type fakeService struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	placed    int
+	cancelled chan string
+	reject    func(w http.ResponseWriter)
+}
+
+func newFakeService(t *testing.T) *fakeService {
+	t.Helper()
+
+	s := &fakeService{cancelled: make(chan string, 10)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeService) serve(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/trade":
+		if s.reject != nil {
+			s.reject(w)
+			return
+		}
+		s.placed++
+		json.NewEncoder(w).Encode(map[string]interface{}{"order_id": fmt.Sprintf("o%d", s.placed), "status": "submitted"})
+	case "/trade/batch":
+		orders, _ := body["orders"].([]interface{})
+		results := make([]map[string]interface{}, len(orders))
+		for i := range orders {
+			s.placed++
+			results[i] = map[string]interface{}{"order_id": fmt.Sprintf("o%d", s.placed), "status": "submitted"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	case "/cancel":
+		orderID, _ := body["order_id"].(string)
+		s.cancelled <- orderID
+		w.Write([]byte("{}"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestDeadlineDoesNotCancelTimeInForce(t *testing.T) {
+	for _, batchSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("batch size %d", batchSize), func(t *testing.T) {
+			service := newFakeService(t)
+			e, err := NewExecutor(map[string]Platform{
+				"predict": {URL: service.URL, Cancels: true, BatchSize: batchSize},
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.SetTracker(orders.NewTracker())
+
+			deadline := time.Now().Add(time.Minute)
+			cmd := types.Command{
+				Type:        "place_order",
+				Platform:    "predict",
+				AccountID:   "a",
+				MarketID:    "m1",
+				Side:        "yes",
+				Price:       0.4,
+				Shares:      10,
+				TimeInForce: "IOC",
+				Deadline:    &deadline,
+				Metadata:    map[string]interface{}{"tif_window": 0.05},
+			}
+			commands := []types.Command{cmd}
+			if batchSize > 1 {
+				other := cmd
+				other.MarketID = "m2"
+				other.Deadline = nil
+				commands = append(commands, other)
+			}
+			if err := e.ExecuteCommands(context.Background(), commands); err != nil {
+				t.Fatal(err)
+			}
+
+			// The IOC window ends after ExecuteCommands returned and its
+			// deadline context was cancelled
+			for range commands {
+				select {
+				case <-service.cancelled:
+				case <-time.After(2 * time.Second):
+					t.Fatal("IOC order was not cancelled after its window")
+				}
+			}
+		})
+	}
+}
@@ -1,127 +1,594 @@
 package executor
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/accountsvc"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orders"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/units"
 	"github.com/rs/zerolog/log"
 )
 
+// Journal records every order the executor sends, for execution-quality reporting
+type Journal interface {
+	RecordOrder(ctx context.Context, record types.OrderRecord) error
+}
+
+// ResultPublisher is told the outcome of every order the executor sends, or
+// refuses to send because it violates the market's limits
+type ResultPublisher interface {
+	PublishCommandResult(ctx context.Context, result CommandResult)
+}
+
+// CommandResult is the outcome of one place_order command. Record is what the
+// journal stores; FilledShares is what the venue filled on placement,
+// ErrorCode the account service's error code, if any, and RejectReason one of
+// the Reject* reasons when repricing the order may get it accepted.
+type CommandResult struct {
+	Command      types.Command
+	Record       types.OrderRecord
+	FilledShares float64
+	ErrorCode    string
+	RejectReason string
+}
+
+// OrderRejectedError is returned when an account service answers with a non-2xx
+// status. Code is the service's error code, when it sent one.
+type OrderRejectedError struct {
+	StatusCode int
+	Code       string
+	Body       map[string]interface{}
+}
+
+func (e *OrderRejectedError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("order failed (status %d, %s): %v", e.StatusCode, e.Code, e.Body)
+	}
+	return fmt.Sprintf("order failed (status %d): %v", e.StatusCode, e.Body)
+}
+
+// serviceError reports account service refusals as *OrderRejectedError
+func serviceError(err error) error {
+	var apiErr *accountsvc.APIError
+	if errors.As(err, &apiErr) {
+		return &OrderRejectedError{StatusCode: apiErr.StatusCode, Code: apiErr.Code, Body: apiErr.Body}
+	}
+	return err
+}
+
+// ErrOutcomeUnknown marks the failures of requests placing orders that may
+// have reached the venue anyway (timeouts, dropped connections, 5xx answers):
+// the order may or may not have been placed
+var ErrOutcomeUnknown = errors.New("order outcome unknown")
+
+// outcomeError marks a failed order request ErrOutcomeUnknown unless the
+// service refused it or provably never got it
+func outcomeError(err error) error {
+	var rejected *OrderRejectedError
+	if err == nil || errors.Is(err, accountsvc.ErrNotSent) || (errors.As(err, &rejected) && rejected.StatusCode < 500) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrOutcomeUnknown, err)
+}
+
 type Executor struct {
-	predictURL    string
-	polymarketURL string
-	httpClient    *http.Client
-	dryRun        bool
+	platforms   map[string]*platformClient
+	dryRun      atomic.Bool // reloadable at runtime
+	journal     Journal
+	results     ResultPublisher
+	tracker     *orders.Tracker
+	markets     *markets.Registry
+	books       *orderbook.Cache
+	sizes       *units.Converter
+	nativeTIF   map[string]map[string]bool // platform -> supported time-in-force values
+	algos       algoSet
+	infoFetches infoFetches
+
+	nativeOrderTypes map[string]map[string]bool // platform -> supported order types
+	nativeSells      map[string]bool            // platform -> account service sells
+
+	orderCounts  orderCounts
+	hedgeLatency hedgeLatencies
 }
 
-func NewExecutor(predictURL, polymarketURL string, dryRun bool) *Executor {
-	return &Executor{
-		predictURL:    predictURL,
-		polymarketURL: polymarketURL,
-		dryRun:        dryRun,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+// NewExecutor routes commands to the account services of the given platforms,
+// keyed by platform name
+func NewExecutor(platforms map[string]Platform, dryRun bool) (*Executor, error) {
+	e := &Executor{
+		platforms: make(map[string]*platformClient, len(platforms)),
 	}
+	conventions := make(map[string]units.Platform, len(platforms))
+	for name, p := range platforms {
+		conventions[name] = p.Units
+		client, err := newPlatformClient(name, p)
+		if err != nil {
+			return nil, fmt.Errorf("platform %s: %w", name, err)
+		}
+		e.platforms[name] = client
+		if len(p.TimeInForce) > 0 {
+			e.SetNativeTimeInForce(name, p.TimeInForce...)
+		}
+		if len(p.OrderTypes) > 0 {
+			e.SetNativeOrderTypes(name, p.OrderTypes...)
+		}
+		if p.Sells {
+			e.SetNativeSells(name)
+		}
+	}
+	e.sizes = units.NewConverter(conventions)
+	e.dryRun.Store(dryRun)
+	return e, nil
+}
+
+// SetDryRun switches between dry-run and live execution. Orders already in
+// flight (including running algos) pick up the new mode on their next request.
+func (e *Executor) SetDryRun(dryRun bool) {
+	e.dryRun.Store(dryRun)
+}
+
+// DryRun reports whether orders are sent without confirmation
+func (e *Executor) DryRun() bool {
+	return e.dryRun.Load()
+}
+
+// dryRunFor reports whether cmd is sent without confirmation: every command
+// in dry-run mode, and the commands of dry-run strategies (metadata "dry_run")
+// while the engine trades live
+func (e *Executor) dryRunFor(cmd types.Command) bool {
+	if e.dryRun.Load() {
+		return true
+	}
+	dryRun, _ := cmd.Metadata["dry_run"].(bool)
+	return dryRun
+}
+
+// SetJournal enables order journaling
+func (e *Executor) SetJournal(journal Journal) {
+	e.journal = journal
+}
+
+// SetResultPublisher enables publishing the outcome of every order
+func (e *Executor) SetResultPublisher(publisher ResultPublisher) {
+	e.results = publisher
+}
+
+// SetTracker makes accepted orders visible in the open order tracker
+func (e *Executor) SetTracker(tracker *orders.Tracker) {
+	e.tracker = tracker
+}
+
+// CommandError reports the failure of one command of a batch
+type CommandError struct {
+	Command types.Command
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s on %s/%s: %v", e.Command.Type, e.Command.Platform, e.Command.AccountID, e.Err)
 }
 
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// CommandErrors splits an error returned by ExecuteCommands into the
+// individual command failures
+func CommandErrors(err error) []*CommandError {
+	if err == nil {
+		return nil
+	}
+
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+
+	var cmdErrs []*CommandError
+	for _, err := range errs {
+		var cmdErr *CommandError
+		if errors.As(err, &cmdErr) {
+			cmdErrs = append(cmdErrs, cmdErr)
+		}
+	}
+	return cmdErrs
+}
+
+// ExecuteCommands executes every command, continuing past failures.
+// The returned error joins one *CommandError per failed command.
+// Consecutive orders for a platform with batching enabled go out in one
+// request, see batch.go.
 func (e *Executor) ExecuteCommands(ctx context.Context, commands []types.Command) error {
+	var errs []error
+	fail := func(cmd types.Command, err error) {
+		log.Error().
+			Err(err).
+			Str("type", cmd.Type).
+			Str("platform", cmd.Platform).
+			Str("account", cmd.AccountID).
+			Msg("Failed to execute command")
+		// Continue with other commands even if one fails
+		errs = append(errs, &CommandError{Command: cmd, Err: err})
+	}
+
+	batches := newBatchQueue()
 	for _, cmd := range commands {
-		if err := e.executeCommand(ctx, cmd); err != nil {
-			log.Error().
-				Err(err).
-				Str("type", cmd.Type).
-				Str("platform", cmd.Platform).
-				Str("account", cmd.AccountID).
-				Msg("Failed to execute command")
-			// Continue with other commands even if one fails
+		if e.batchable(cmd) {
+			batches.add(cmd)
+			continue
+		}
+		// Keep the command order: queued orders go out before anything else
+		e.flushBatches(ctx, batches, fail)
+		if err := e.checkDeadline(cmd, time.Now()); err != nil {
+			fail(cmd, err)
+			continue
+		}
+		cmdCtx, cancel := commandContext(ctx, cmd)
+		err := e.executeCommand(cmdCtx, cmd)
+		cancel()
+		if err != nil {
+			fail(cmd, err)
 		}
 	}
-	return nil
+	e.flushBatches(ctx, batches, fail)
+
+	return errors.Join(errs...)
 }
 
 func (e *Executor) executeCommand(ctx context.Context, cmd types.Command) error {
 	switch cmd.Type {
 	case "place_order":
+		if algo, _ := cmd.Metadata["algo"].(string); algo != "" {
+			return e.startAlgo(ctx, cmd, algo)
+		}
 		return e.placeOrder(ctx, cmd)
+	case "amend_order":
+		return e.amendOrder(ctx, cmd)
 	case "cancel_order":
 		return e.cancelOrder(ctx, cmd)
+	case "cancel_all_orders":
+		return e.cancelAllOrders(ctx, cmd)
+	case "flatten_account":
+		return e.flattenAccount(ctx, cmd)
+	case "convert_positions":
+		return e.convertPositions(ctx, cmd)
 	default:
 		return fmt.Errorf("unknown command type: %s", cmd.Type)
 	}
 }
 
 func (e *Executor) placeOrder(ctx context.Context, cmd types.Command) error {
-	baseURL := e.predictURL
-	if cmd.Platform == "polymarket" {
-		baseURL = e.polymarketURL
+	cmd, err := e.normalizeOrder(cmd)
+	if err != nil {
+		return e.refuseOrder(ctx, cmd, err)
 	}
 
-	// Build request payload
-	payload := map[string]interface{}{
-		"account_id": cmd.AccountID,
-		"market_id":  cmd.MarketID,
-		"side":       cmd.Side,
-		"price":      cmd.Price,
-		"shares":     cmd.Shares,
-		"confirm":    !e.dryRun,
-	}
+	start := time.Now()
+	result, err := e.sendOrder(ctx, cmd)
+	return e.finishOrder(ctx, cmd, result, err, time.Since(start))
+}
 
-	jsonData, err := json.Marshal(payload)
+// finishOrder journals the outcome of a sent order and, if it was accepted,
+// tracks it and enforces its time in force
+func (e *Executor) finishOrder(ctx context.Context, cmd types.Command, result *accountsvc.OrderResult, err error, latency time.Duration) error {
+	e.recordOrder(ctx, cmd, result, err, latency)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return err
 	}
 
-	// Send request
-	req, err := http.NewRequestWithContext(
-		ctx,
-		"POST",
-		fmt.Sprintf("%s/trade", baseURL),
-		bytes.NewBuffer(jsonData),
-	)
+	e.trackOrder(cmd, result)
+	e.enforceTimeInForce(ctx, cmd, result)
+
+	log.Info().
+		Str("platform", cmd.Platform).
+		Str("account", cmd.AccountID).
+		Str("market", cmd.MarketID).
+		Str("outcome", cmd.OutcomeID).
+		Str("side", cmd.Side).
+		Float64("price", cmd.Price).
+		Float64("shares", cmd.Shares).
+		Str("order_id", result.ID()).
+		Str("status", result.Status).
+		Float64("filled", result.FilledShares).
+		Msg("Order placed successfully")
+
+	return nil
+}
+
+func (e *Executor) sendOrder(ctx context.Context, cmd types.Command) (*accountsvc.OrderResult, error) {
+	if client, ok := e.clobClient(cmd); ok {
+		return e.sendCLOBOrder(ctx, client, cmd)
+	}
+
+	client, err := e.platform(cmd.Platform)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	result, err := client.service.PlaceOrder(ctx, e.orderRequest(cmd))
+	if err != nil {
+		return nil, outcomeError(serviceError(err))
+	}
+	return result, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
+// orderRequest builds the account service request for one order
+func (e *Executor) orderRequest(cmd types.Command) accountsvc.OrderRequest {
+	req := accountsvc.OrderRequest{
+		AccountID: cmd.AccountID,
+		MarketID:  cmd.MarketID,
+		OutcomeID: cmd.OutcomeID,
+		Side:      cmd.Side,
+		Price:     cmd.Price,
+		Shares:    cmd.Shares,
+		Confirm:   !e.dryRunFor(cmd),
 
-	resp, err := e.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		ClientOrderID: cmd.ClientOrderID,
+	}
+
+	if normalizeAction(cmd.Action) == ActionSell {
+		req.Action = ActionSell
+	}
+	if t := orderType(cmd); t != OrderTypeLimit && e.supportsOrderType(cmd.Platform, t) {
+		req.OrderType = t
+	}
+	if tif := timeInForce(cmd); tif != "GTC" && e.supportsNativeTIF(cmd.Platform, tif) {
+		req.TimeInForce = tif
+		if expireAt, ok := cmd.Metadata["expire_at"].(string); ok {
+			req.ExpireAt = expireAt
+		} else if cmd.ExpiresAt != nil {
+			req.ExpireAt = cmd.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return req
+}
+
+// refuseOrder publishes the result of an order that failed normalization and
+// was never sent, and returns err
+func (e *Executor) refuseOrder(ctx context.Context, cmd types.Command, err error) error {
+	e.publishResult(ctx, cmd, orderRecord(cmd, nil, err, 0), nil, err)
+	return err
+}
+
+// recordOrder writes the outcome of an order attempt to the journal and
+// publishes it. The write is not cancelled with ctx: once an order was sent
+// it must be journaled.
+func (e *Executor) recordOrder(ctx context.Context, cmd types.Command, result *accountsvc.OrderResult, err error, latency time.Duration) {
+	record := orderRecord(cmd, result, err, latency)
+
+	if e.journal != nil {
+		if err := e.journal.RecordOrder(context.WithoutCancel(ctx), record); err != nil {
+			log.Error().Err(err).Str("platform", cmd.Platform).Msg("Failed to journal order")
+		}
+	}
+
+	e.publishResult(ctx, cmd, record, result, err)
+}
+
+// orderRecord describes the outcome of an order attempt. 4xx answers and
+// orders violating the market's limits count as rejections; transport errors
+// and 5xx as failures.
+func orderRecord(cmd types.Command, result *accountsvc.OrderResult, err error, latency time.Duration) types.OrderRecord {
+	record := types.OrderRecord{
+		Platform:       cmd.Platform,
+		AccountID:      cmd.AccountID,
+		MarketID:       cmd.MarketID,
+		OutcomeID:      cmd.OutcomeID,
+		Side:           cmd.Side,
+		Action:         cmd.Action,
+		Price:          cmd.Price,
+		Shares:         cmd.Shares,
+		ReferencePrice: cmd.Price,
+		Status:         "accepted",
+		Latency:        latency,
+		ExpiresAt:      cmd.ExpiresAt,
+		CreatedAt:      time.Now().UTC(),
+	}
+	record.Strategy, _ = cmd.Metadata["strategy"].(string)
+	record.Tenant, _ = cmd.Metadata["tenant"].(string)
+	if ref, ok := cmd.Metadata["market_price"].(float64); ok {
+		record.ReferencePrice = ref
+	}
+
+	var rejected *OrderRejectedError
+	switch {
+	case errors.As(err, &rejected) && rejected.StatusCode < 500, errors.Is(err, ErrInvalidOrder):
+		record.Status = "rejected"
+		record.Error = err.Error()
+	case err != nil:
+		record.Status = "failed"
+		record.Error = err.Error()
+	default:
+		if result.DryRun() {
+			record.Status = "dry_run"
+		} else if latency, ok := hedgeLatency(cmd, record.CreatedAt); ok {
+			record.HedgeLatency = latency
+		}
+		record.OrderID = result.ID()
+	}
+	return record
+}
+
+func (e *Executor) publishResult(ctx context.Context, cmd types.Command, record types.OrderRecord, result *accountsvc.OrderResult, err error) {
+	e.orderCounts.add(record.Status)
+	if record.HedgeLatency > 0 {
+		e.hedgeLatency.observe(record.Strategy, record.HedgeLatency)
+	}
+	if e.results == nil {
+		return
+	}
+
+	commandResult := CommandResult{Command: cmd, Record: record}
+	if result != nil {
+		commandResult.FilledShares = result.FilledShares
+	}
+	var rejected *OrderRejectedError
+	if errors.As(err, &rejected) {
+		commandResult.ErrorCode = rejected.Code
+	}
+	commandResult.RejectReason = rejectionReason(err)
+	e.results.PublishCommandResult(context.WithoutCancel(ctx), commandResult)
+}
+
+// trackOrder adds an accepted live order to the open order tracker, unless
+// it filled completely on placement
+func (e *Executor) trackOrder(cmd types.Command, result *accountsvc.OrderResult) {
+	if e.tracker == nil || result.DryRun() || result.Filled(cmd.Shares) {
+		return
+	}
+
+	strategy, _ := cmd.Metadata["strategy"].(string)
+	e.tracker.Add(orders.Order{
+		OrderID:   result.ID(),
+		Platform:  cmd.Platform,
+		AccountID: cmd.AccountID,
+		MarketID:  cmd.MarketID,
+		OutcomeID: cmd.OutcomeID,
+		Side:      cmd.Side,
+		Price:     cmd.Price,
+		Shares:    cmd.Shares,
+		Strategy:  strategy,
+		ExpiresAt: cmd.ExpiresAt,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// ErrCancelUnsupported is returned for cancels on platforms whose account
+// service cannot cancel orders
+var ErrCancelUnsupported = errors.New("order cancels not supported")
+
+// cancelOrder cancels one order; the venue order ID is carried in metadata "order_id"
+func (e *Executor) cancelOrder(ctx context.Context, cmd types.Command) error {
+	orderID, _ := cmd.Metadata["order_id"].(string)
+	if orderID == "" {
+		return fmt.Errorf("cancel_order requires metadata order_id")
+	}
+	if !e.CanCancel(cmd) {
+		return fmt.Errorf("%w on %s", ErrCancelUnsupported, cmd.Platform)
+	}
+
+	if client, ok := e.clobClient(cmd); ok {
+		if !e.dryRunFor(cmd) {
+			if err := client.CancelOrder(ctx, cmd.AccountID, orderID); err != nil {
+				return clobError(err)
+			}
+		}
+	} else {
+		client, err := e.platform(cmd.Platform)
+		if err != nil {
+			return err
+		}
+		if err := client.service.CancelOrder(ctx, cmd.AccountID, orderID, !e.dryRunFor(cmd)); err != nil {
+			return serviceError(err)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		var errResp map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errResp)
-		return fmt.Errorf("order failed (status %d): %v", resp.StatusCode, errResp)
+	if e.tracker != nil && !e.dryRunFor(cmd) {
+		e.tracker.Remove(cmd.Platform, orderID)
 	}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	log.Info().
+		Str("platform", cmd.Platform).
+		Str("account", cmd.AccountID).
+		Str("order_id", orderID).
+		Msg("Order cancelled")
+
+	return nil
+}
+
+// cancelAllOrders cancels every tracked open order on the account
+func (e *Executor) cancelAllOrders(ctx context.Context, cmd types.Command) error {
+	if e.tracker == nil {
+		return fmt.Errorf("cancel_all_orders requires the order tracker")
+	}
+	if !e.CanCancel(cmd) {
+		return fmt.Errorf("%w on %s", ErrCancelUnsupported, cmd.Platform)
+	}
+
+	var failed int
+	for _, order := range e.tracker.ByAccount(cmd.Platform, cmd.AccountID) {
+		cancel := cmd
+		cancel.Type = "cancel_order"
+		cancel.MarketID = order.MarketID
+		cancel.Metadata = make(map[string]interface{}, len(cmd.Metadata)+1)
+		for k, v := range cmd.Metadata {
+			cancel.Metadata[k] = v
+		}
+		cancel.Metadata["order_id"] = order.OrderID
+
+		if err := e.cancelOrder(ctx, cancel); err != nil {
+			failed++
+			log.Error().Err(err).Str("order_id", order.OrderID).Msg("Failed to cancel order")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d orders failed to cancel", failed)
+	}
+	return nil
+}
+
+// flattenAccount asks the account service to close every position on the account
+func (e *Executor) flattenAccount(ctx context.Context, cmd types.Command) error {
+	client, err := e.platform(cmd.Platform)
+	if err != nil {
 		return err
 	}
+	if !client.config.CloseAll {
+		return fmt.Errorf("closing whole accounts is not supported on %s", cmd.Platform)
+	}
+	if err := client.service.CloseAll(ctx, cmd.AccountID, !e.dryRunFor(cmd)); err != nil {
+		return outcomeError(serviceError(err))
+	}
 
 	log.Info().
 		Str("platform", cmd.Platform).
 		Str("account", cmd.AccountID).
-		Str("market", cmd.MarketID).
-		Str("side", cmd.Side).
-		Float64("price", cmd.Price).
-		Float64("shares", cmd.Shares).
-		Interface("result", result).
-		Msg("Order placed successfully")
+		Msg("Account flatten requested")
 
 	return nil
 }
 
-func (e *Executor) cancelOrder(ctx context.Context, cmd types.Command) error {
-	// TODO: Implement cancel order
-	log.Warn().Msg("Cancel order not yet implemented")
+// convertPositions converts NO shares across markets of a negative-risk group
+// (Polymarket) into collateral plus YES shares on the remaining markets.
+// cmd.MarketID carries the neg-risk market ID, metadata "market_ids" the NO legs.
+func (e *Executor) convertPositions(ctx context.Context, cmd types.Command) error {
+	client, err := e.platform(cmd.Platform)
+	if err != nil {
+		return err
+	}
+	if !client.config.NegRisk {
+		return fmt.Errorf("position conversion is not supported on %s", cmd.Platform)
+	}
+
+	req := accountsvc.ConvertRequest{
+		AccountID:       cmd.AccountID,
+		NegRiskMarketID: cmd.MarketID,
+		MarketIDs:       metadataStrings(cmd.Metadata, "market_ids"),
+		Shares:          cmd.Shares,
+		Confirm:         !e.dryRunFor(cmd),
+	}
+	if len(req.MarketIDs) == 0 {
+		return fmt.Errorf("convert_positions requires metadata market_ids")
+	}
+
+	if err := client.service.ConvertPositions(ctx, req); err != nil {
+		return outcomeError(serviceError(err))
+	}
+
+	log.Info().
+		Str("account", cmd.AccountID).
+		Str("neg_risk_market_id", cmd.MarketID).
+		Float64("shares", cmd.Shares).
+		Msg("Neg-risk positions converted")
+
 	return nil
 }
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Hedge latency is the time from the exchange filling the order a hedge
+// answers (metadata "filled_at", set by delta neutral) to the hedge being
+// accepted. It covers the event bus, the strategy, the pipeline and the
+// account service, and repriced retries of a rejected hedge.
+
+// hedgeLatencyBuckets are the upper bounds of the hedge latency histogram
+var hedgeLatencyBuckets = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// LatencyHistogram counts latencies into buckets. Counts are cumulative: a
+// bucket counts every latency up to its bound, and Count all of them.
+type LatencyHistogram struct {
+	Buckets []LatencyBucket `json:"buckets"`
+	Count   int64           `json:"count"`
+	SumMs   float64         `json:"sum_ms"`
+	MaxMs   float64         `json:"max_ms"`
+}
+
+// LatencyBucket is one bucket of a LatencyHistogram
+type LatencyBucket struct {
+	LeMs  float64 `json:"le_ms"`
+	Count int64   `json:"count"`
+}
+
+// hedgeLatencies keeps a hedge latency histogram per strategy
+type hedgeLatencies struct {
+	mu         sync.Mutex
+	strategies map[string]*LatencyHistogram
+}
+
+func (h *hedgeLatencies) observe(strategy string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.strategies == nil {
+		h.strategies = make(map[string]*LatencyHistogram)
+	}
+	hist, ok := h.strategies[strategy]
+	if !ok {
+		hist = &LatencyHistogram{Buckets: make([]LatencyBucket, len(hedgeLatencyBuckets))}
+		for i, bound := range hedgeLatencyBuckets {
+			hist.Buckets[i].LeMs = float64(bound.Milliseconds())
+		}
+		h.strategies[strategy] = hist
+	}
+
+	for i, bound := range hedgeLatencyBuckets {
+		if latency <= bound {
+			hist.Buckets[i].Count++
+		}
+	}
+	ms := float64(latency) / float64(time.Millisecond)
+	hist.Count++
+	hist.SumMs += ms
+	if ms > hist.MaxMs {
+		hist.MaxMs = ms
+	}
+}
+
+// HedgeLatency returns the hedge latency histogram of every strategy that had
+// a hedge accepted since the executor was created, by strategy name
+func (e *Executor) HedgeLatency() map[string]LatencyHistogram {
+	e.hedgeLatency.mu.Lock()
+	defer e.hedgeLatency.mu.Unlock()
+
+	histograms := make(map[string]LatencyHistogram, len(e.hedgeLatency.strategies))
+	for strategy, hist := range e.hedgeLatency.strategies {
+		snapshot := *hist
+		snapshot.Buckets = append([]LatencyBucket(nil), hist.Buckets...)
+		histograms[strategy] = snapshot
+	}
+	return histograms
+}
+
+// hedgeLatency returns how long after the hedged fill an accepted hedge was
+// accepted, or false if the order is not a hedge or the fill time is unknown
+func hedgeLatency(cmd types.Command, acceptedAt time.Time) (time.Duration, bool) {
+	filledAt, _ := cmd.Metadata["filled_at"].(string)
+	if filledAt == "" {
+		return 0, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, filledAt)
+	if err != nil {
+		return 0, false
+	}
+	// A fill time ahead of our clock says nothing about the latency
+	latency := acceptedAt.Sub(t)
+	if latency < 0 {
+		return 0, false
+	}
+	return latency, true
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Orders are normalized against the market metadata before they are sent:
+// the price is rounded down to the tick size, and orders outside the size
+// limits or on a closed market fail here instead of at the venue. Metadata
+// comes from market_update events and, for markets without it, from
+// GET /markets/{id} on the account service, re-read after marketInfoTTL.
+// Lookups run in the background so orders never wait on them: the order that
+// misses the metadata, and those sent until the lookup completes, go out with
+// what is known, stale or nothing. Failed lookups are remembered as well, so an
+// account service without the endpoint costs one request per market and TTL.
+// Orders on markets without metadata are sent unchanged.
+
+const (
+	marketInfoTTL          = time.Hour
+	marketInfoFetchTimeout = 10 * time.Second
+)
+
+// infoFetches tracks the markets whose metadata is being fetched, so each is
+// looked up once at a time
+type infoFetches struct {
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// start reports whether a lookup of the market should start
+func (f *infoFetches) start(platform, marketID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := platform + ":" + marketID
+	if f.inFlight[key] {
+		return false
+	}
+	if f.inFlight == nil {
+		f.inFlight = make(map[string]bool)
+	}
+	f.inFlight[key] = true
+	return true
+}
+
+func (f *infoFetches) done(platform, marketID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.inFlight, platform+":"+marketID)
+}
+
+// ErrInvalidOrder is returned for orders that violate the market's limits
+var ErrInvalidOrder = errors.New("invalid order")
+
+// SetMarkets enables order normalization against the market registry
+func (e *Executor) SetMarkets(registry *markets.Registry) {
+	e.markets = registry
+}
+
+// FetchMarketInfo asks an account service for the metadata of one market
+func (e *Executor) FetchMarketInfo(ctx context.Context, platform, marketID string) (markets.Info, error) {
+	client, err := e.platform(platform)
+	if err != nil {
+		return markets.Info{}, err
+	}
+	raw, err := client.service.Market(ctx, marketID)
+	if err != nil {
+		return markets.Info{}, fmt.Errorf("failed to fetch market: %w", err)
+	}
+	return markets.ParseInfo(platform, marketID, raw), nil
+}
+
+// marketInfo returns the cached metadata of a market, starting a background
+// lookup when it is missing or stale
+func (e *Executor) marketInfo(platform, marketID string) (markets.Info, bool) {
+	if e.markets == nil {
+		return markets.Info{}, false
+	}
+
+	info, cached := e.markets.Info(marketID)
+	if !cached || time.Since(info.UpdatedAt) >= marketInfoTTL {
+		if e.infoFetches.start(platform, marketID) {
+			go e.refreshMarketInfo(platform, marketID)
+		}
+	}
+	return info, info.Known()
+}
+
+// refreshMarketInfo looks up the metadata of a market and caches the result
+func (e *Executor) refreshMarketInfo(platform, marketID string) {
+	defer e.infoFetches.done(platform, marketID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), marketInfoFetchTimeout)
+	defer cancel()
+
+	fetched, err := e.FetchMarketInfo(ctx, platform, marketID)
+	if err != nil {
+		log.Debug().Err(err).Str("platform", platform).Str("market", marketID).Msg("No market metadata from account service")
+		// Keep what was known, and do not ask again before the TTL
+		fetched, _ = e.markets.Info(marketID)
+		fetched.Platform = platform
+		fetched.MarketID = marketID
+		fetched.UpdatedAt = time.Now().UTC()
+	}
+	e.markets.SetInfo(fetched)
+}
+
+// normalizeOrder converts the size to shares, maps the action and order type
+// to what the platform supports, applies the market metadata to a place_order
+// and records the market price it is sent at
+func (e *Executor) normalizeOrder(cmd types.Command) (types.Command, error) {
+	cmd, err := e.ConvertSize(cmd)
+	if err != nil {
+		return cmd, err
+	}
+	cmd, err = e.applyAction(cmd)
+	if err != nil {
+		return cmd, err
+	}
+	cmd, err = e.applyOrderType(cmd)
+	if err != nil {
+		return cmd, err
+	}
+	if err := e.checkCancellable(cmd); err != nil {
+		return cmd, err
+	}
+
+	info, ok := e.marketInfo(cmd.Platform, cmd.MarketID)
+	if !ok {
+		return e.withMarketPrice(cmd), nil
+	}
+
+	if info.Closed(time.Now()) {
+		return cmd, fmt.Errorf("%w: market %s closed at %s", ErrInvalidOrder, cmd.MarketID, info.CloseTime.Format(time.RFC3339))
+	}
+	if info.MinShares > 0 && cmd.Shares < info.MinShares {
+		return cmd, fmt.Errorf("%w: %.4g shares is below the minimum of %.4g", ErrInvalidOrder, cmd.Shares, info.MinShares)
+	}
+	if info.MaxShares > 0 && cmd.Shares > info.MaxShares {
+		return cmd, fmt.Errorf("%w: %.4g shares is above the maximum of %.4g", ErrInvalidOrder, cmd.Shares, info.MaxShares)
+	}
+
+	if price := info.RoundPrice(cmd.Price); price != cmd.Price {
+		log.Debug().
+			Str("market", cmd.MarketID).
+			Float64("price", cmd.Price).
+			Float64("rounded", price).
+			Float64("tick_size", info.TickSize).
+			Msg("Order price rounded to tick size")
+		cmd.Price = price
+	}
+	return e.withMarketPrice(cmd), nil
+}
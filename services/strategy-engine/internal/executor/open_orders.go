@@ -0,0 +1,87 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orders"
+)
+
+// FetchOpenOrders asks an account service for the open orders of one account.
+// Account services proxy the venue's order list as-is, so field names are
+// resolved tolerantly across the Predict and Polymarket conventions.
+func (e *Executor) FetchOpenOrders(ctx context.Context, platform, accountID string) ([]orders.Order, error) {
+	client, err := e.platform(platform)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := client.service.OpenOrders(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+
+	var open []orders.Order
+	for _, item := range raw {
+		if !isOpenStatus(firstString(item, "status", "state")) {
+			continue
+		}
+
+		order := orders.Order{
+			OrderID:   firstString(item, "order_hash", "orderHash", "hash", "order_id", "id"),
+			Platform:  platform,
+			AccountID: accountID,
+			MarketID:  firstString(item, "market_id", "marketId", "market"),
+			Side:      strings.ToLower(firstString(item, "side", "outcome")),
+			Price:     firstFloat(item, "price"),
+			Shares:    firstFloat(item, "shares", "size", "amount", "original_size"),
+			CreatedAt: time.Now().UTC(),
+		}
+		order.FilledShares = firstFloat(item, "filled_shares", "size_matched", "amountFilled")
+
+		if order.OrderID == "" {
+			continue
+		}
+		open = append(open, order)
+	}
+
+	return open, nil
+}
+
+func isOpenStatus(status string) bool {
+	switch strings.ToLower(status) {
+	case "", "open", "live", "pending", "partially_filled":
+		return true
+	}
+	return false
+}
+
+func firstString(item map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := item[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+func firstFloat(item map[string]interface{}, keys ...string) float64 {
+	for _, key := range keys {
+		switch v := item[key].(type) {
+		case float64:
+			return v
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Order types are requested with Command.OrderType:
+//
+//	"limit" (default): rests at Price until it fills or is cancelled
+//	"market":          fills right away at Price or better; Price is the
+//	                   worst acceptable price and is required
+//	"post_only":       rests at Price and is rejected if it would take liquidity
+//
+// Platforms that take a type natively (Platform.OrderTypes) receive it as
+// "order_type" in the order payload. Elsewhere a market order is sent as a
+// limit order at Price with time in force IOC, unless the command asks for
+// FOK. Post-only cannot be emulated without the venue's book, so such orders
+// fail instead of possibly taking liquidity.
+
+const (
+	OrderTypeLimit    = "limit"
+	OrderTypeMarket   = "market"
+	OrderTypePostOnly = "post_only"
+)
+
+// ValidOrderType reports whether t is a known order type; empty means limit
+func ValidOrderType(t string) bool {
+	switch normalizeOrderType(t) {
+	case OrderTypeLimit, OrderTypeMarket, OrderTypePostOnly:
+		return true
+	}
+	return false
+}
+
+// SetNativeOrderTypes declares which order types a platform's account service
+// supports natively
+func (e *Executor) SetNativeOrderTypes(platform string, orderTypes ...string) {
+	if e.nativeOrderTypes == nil {
+		e.nativeOrderTypes = make(map[string]map[string]bool)
+	}
+	if e.nativeOrderTypes[platform] == nil {
+		e.nativeOrderTypes[platform] = make(map[string]bool)
+	}
+	for _, t := range orderTypes {
+		e.nativeOrderTypes[platform][normalizeOrderType(t)] = true
+	}
+}
+
+func normalizeOrderType(t string) string {
+	t = strings.ReplaceAll(strings.ToLower(t), "-", "_")
+	if t == "" {
+		return OrderTypeLimit
+	}
+	return t
+}
+
+func orderType(cmd types.Command) string {
+	return normalizeOrderType(cmd.OrderType)
+}
+
+func (e *Executor) supportsOrderType(platform, t string) bool {
+	return t == OrderTypeLimit || e.nativeOrderTypes[platform][t]
+}
+
+// applyOrderType validates the order type of a place_order and rewrites
+// emulated types into what the platform takes
+func (e *Executor) applyOrderType(cmd types.Command) (types.Command, error) {
+	t := orderType(cmd)
+	if !ValidOrderType(t) {
+		return cmd, fmt.Errorf("%w: unknown order type %q", ErrInvalidOrder, cmd.OrderType)
+	}
+	cmd.OrderType = t
+
+	switch {
+	case t == OrderTypeMarket && cmd.Price <= 0:
+		return cmd, fmt.Errorf("%w: market order needs a price as its worst acceptable price", ErrInvalidOrder)
+	case e.supportsOrderType(cmd.Platform, t):
+		return cmd, nil
+	case t == OrderTypeMarket:
+		if tif := timeInForce(cmd); tif != "FOK" {
+			cmd.TimeInForce = "IOC"
+		}
+		return cmd, nil
+	default:
+		return cmd, fmt.Errorf("%w: platform %s does not support %s orders", ErrInvalidOrder, cmd.Platform, t)
+	}
+}
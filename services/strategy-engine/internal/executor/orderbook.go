@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// FetchOrderBook asks an account service for the order book of one market and
+// returns it as an orderbook_snapshot event, the form the order book cache reads
+func (e *Executor) FetchOrderBook(ctx context.Context, platform, marketID string) (types.Event, error) {
+	client, err := e.platform(platform)
+	if err != nil {
+		return types.Event{}, err
+	}
+	raw, err := client.service.OrderBook(ctx, marketID)
+	if err != nil {
+		return types.Event{}, fmt.Errorf("failed to fetch order book: %w", err)
+	}
+
+	// Predict wraps the book in {"data": ...}
+	if inner, ok := raw["data"].(map[string]interface{}); ok {
+		raw = inner
+	}
+
+	data := make(map[string]interface{}, len(raw)+1)
+	for k, v := range raw {
+		data[k] = v
+	}
+	data["market_id"] = marketID
+
+	return types.Event{
+		Type:      "orderbook_snapshot",
+		Platform:  platform,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}, nil
+}
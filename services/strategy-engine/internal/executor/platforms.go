@@ -0,0 +1,193 @@
+package executor
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/accountsvc"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/clob"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/units"
+)
+
+// Platform describes the account service of one venue. Commands and account
+// lookups are routed by their platform name, so a new venue only needs an
+// account service speaking the same API and an entry in the config.
+type Platform struct {
+	URL string
+
+	// Authentication, see accountsvc/auth.go. AuthToken is sent in AuthHeader
+	// (default Authorization, as "Bearer <token>"; any other header carries it
+	// as-is).
+	AuthHeader string
+	AuthToken  string
+	HMACKeyID  string
+	HMACSecret string
+
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSCAFile     string
+	TLSServerName string
+
+	// Timeout bounds each request; zero means 30s
+	Timeout time.Duration
+
+	// Connection settings of the HTTP client, see accountsvc.Config
+	ConnectTimeout  time.Duration
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+	ProxyURL        string
+	Headers         map[string]string
+
+	// RateLimit caps requests per second to the service, with bursts of up to
+	// RateBurst requests. Zero means unlimited.
+	RateLimit float64
+	RateBurst int
+
+	// TimeInForce lists the time-in-force values enforced natively by the venue
+	TimeInForce []string
+
+	// OrderTypes lists the order types besides limit the venue takes natively
+	OrderTypes []string
+
+	// Sells means the account service takes sell orders; sells are emulated
+	// by buying the opposite outcome otherwise
+	Sells bool
+
+	// Amend means the account service replaces orders atomically via
+	// /trade/amend; amend_order cancels and places otherwise (see amend.go)
+	Amend bool
+
+	// NegRisk enables convert_positions for negative-risk market groups
+	NegRisk bool
+
+	// Cancels means the account service cancels orders via POST /cancel.
+	// Without it cancel commands fail, and orders whose time in force or
+	// expiry the engine would enforce by cancelling are refused (see tif.go).
+	Cancels bool
+
+	// CloseAll means the account service closes every position of an account
+	// via POST /accounts/{id}/close-all; flatten_account fails otherwise
+	CloseAll bool
+
+	// BatchSize sends up to this many consecutive orders in one /trade/batch
+	// request; zero or one disables batching
+	BatchSize int
+
+	// IdempotentOrders means the account service places one order per
+	// client_order_id, answering repeats with the first order's result
+	IdempotentOrders bool
+
+	// CLOB sends the orders of its accounts directly to the Polymarket CLOB,
+	// see clob.go
+	CLOB *clob.Options
+
+	// Units is the share convention of the venue, for orders sized in
+	// contracts or USD (see size.go)
+	Units units.Platform
+
+	// WrapTransport, if set, wraps the account service's HTTP transport
+	WrapTransport func(http.RoundTripper) http.RoundTripper
+}
+
+type platformClient struct {
+	name    string
+	config  Platform
+	service *accountsvc.Client
+	clob    *clob.Client
+
+	// batchUnsupported is set once the service turned out to lack /trade/batch
+	batchUnsupported atomic.Bool
+
+	// amendUnsupported is set once the service turned out to lack /trade/amend
+	amendUnsupported atomic.Bool
+}
+
+func newPlatformClient(name string, p Platform) (*platformClient, error) {
+	service, err := accountsvc.NewClient(accountsvc.Config{
+		URL:             p.URL,
+		AuthHeader:      p.AuthHeader,
+		AuthToken:       p.AuthToken,
+		HMACKeyID:       p.HMACKeyID,
+		HMACSecret:      p.HMACSecret,
+		TLSCertFile:     p.TLSCertFile,
+		TLSKeyFile:      p.TLSKeyFile,
+		TLSCAFile:       p.TLSCAFile,
+		TLSServerName:   p.TLSServerName,
+		Timeout:         p.Timeout,
+		ConnectTimeout:  p.ConnectTimeout,
+		MaxIdleConns:    p.MaxIdleConns,
+		IdleConnTimeout: p.IdleConnTimeout,
+		ProxyURL:        p.ProxyURL,
+		Headers:         p.Headers,
+		RateLimit:       p.RateLimit,
+		RateBurst:       p.RateBurst,
+		WrapTransport:   p.WrapTransport,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := &platformClient{
+		name:    name,
+		config:  p,
+		service: service,
+	}
+	if p.CLOB != nil {
+		if client.clob, err = clob.NewClient(*p.CLOB); err != nil {
+			return nil, fmt.Errorf("clob: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// platform returns the client for a platform name
+func (e *Executor) platform(name string) (*platformClient, error) {
+	client, ok := e.platforms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown platform %q", name)
+	}
+	return client, nil
+}
+
+// CanCancel reports whether the order of cmd can be cancelled: its account
+// service cancels orders, or it goes directly to the CLOB
+func (e *Executor) CanCancel(cmd types.Command) bool {
+	if _, direct := e.clobClient(cmd); direct {
+		return true
+	}
+	client, ok := e.platforms[cmd.Platform]
+	return ok && client.config.Cancels
+}
+
+// CanCloseAll reports whether the account service of platform closes whole
+// accounts (flatten_account)
+func (e *Executor) CanCloseAll(platform string) bool {
+	client, ok := e.platforms[platform]
+	return ok && client.config.CloseAll
+}
+
+// IdempotentOrders reports whether sending cmd's order again cannot place it
+// twice: it carries a ClientOrderID, its account service deduplicates on it,
+// and it does not go directly to the CLOB
+func (e *Executor) IdempotentOrders(cmd types.Command) bool {
+	client, ok := e.platforms[cmd.Platform]
+	if !ok || !client.config.IdempotentOrders || cmd.ClientOrderID == "" {
+		return false
+	}
+	_, direct := e.clobClient(cmd)
+	return !direct
+}
+
+// Platforms returns the configured platform names, sorted
+func (e *Executor) Platforms() []string {
+	names := make([]string, 0, len(e.platforms))
+	for name := range e.platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// FetchPositions asks an account service for the positions of one account.
+// Like open orders, venue payloads are passed through, so fields are resolved
+// tolerantly. Positions without a market, a yes/no side or shares are dropped.
+func (e *Executor) FetchPositions(ctx context.Context, platform, accountID string) ([]types.Position, error) {
+	client, err := e.platform(platform)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := client.service.Positions(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch positions: %w", err)
+	}
+
+	var positions []types.Position
+	for _, item := range raw {
+		position := types.Position{
+			AccountID: accountID,
+			Platform:  platform,
+			MarketID:  firstString(item, "market_id", "marketId", "market"),
+			OutcomeID: firstString(item, "outcome_id", "outcomeId", "token_id", "tokenId", "asset"),
+			Side:      strings.ToLower(firstString(item, "side", "outcome", "outcome_name")),
+			Shares:    firstFloat(item, "shares", "size", "quantity", "amount"),
+			AvgPrice:  firstFloat(item, "avg_price", "avgPrice", "average_price", "price"),
+			UpdatedAt: time.Now().UTC(),
+		}
+
+		if position.MarketID == "" || position.Shares <= 0 {
+			continue
+		}
+		if position.Side != "yes" && position.Side != "no" {
+			continue
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// The journal's reference price is the market price of the order's side when
+// it was sent: the mid of a fresh book, or else the last trade. Execution
+// quality measures the effective spread against it. Orders sent without fresh
+// market data, or on an outcome of a multi-outcome market, are referenced to
+// their own limit price.
+
+// referenceMaxAge is how old market data may be to serve as the reference
+const referenceMaxAge = 10 * time.Second
+
+// SetOrderBooks enables journaling the market price at send time as the
+// reference price of orders
+func (e *Executor) SetOrderBooks(books *orderbook.Cache) {
+	e.books = books
+}
+
+// withMarketPrice records the market price of the order's side in metadata
+// "market_price", if there is one
+func (e *Executor) withMarketPrice(cmd types.Command) types.Command {
+	price, ok := e.marketPrice(cmd.Platform, cmd.MarketID, cmd.Side)
+	if !ok {
+		return cmd
+	}
+
+	metadata := make(map[string]interface{}, len(cmd.Metadata)+1)
+	for k, v := range cmd.Metadata {
+		metadata[k] = v
+	}
+	metadata["market_price"] = price
+	cmd.Metadata = metadata
+	return cmd
+}
+
+func (e *Executor) marketPrice(platform, marketID, side string) (float64, bool) {
+	if e.books == nil || (side != "yes" && side != "no") {
+		return 0, false
+	}
+	if quote, ok := e.books.Top(platform, marketID, referenceMaxAge); ok {
+		if mid, ok := quote.Mid(); ok {
+			if side == "no" {
+				return 1 - mid, true
+			}
+			return mid, true
+		}
+	}
+	if trade, ok := e.books.LastTrade(platform, marketID, referenceMaxAge); ok {
+		return trade.PriceFor(side), true
+	}
+	return 0, false
+}
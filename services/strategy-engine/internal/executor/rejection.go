@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+)
+
+// Rejection reasons a strategy can act on by repricing the order and sending
+// it again, reported in CommandResult.RejectReason
+const (
+	// RejectPriceOutOfRange: the price is outside what the venue accepts for
+	// the market, such as beyond its price band or off its tick grid
+	RejectPriceOutOfRange = "price_out_of_range"
+
+	// RejectCrossedMarket: the order would cross the book where that is not
+	// allowed, or the venue saw the book as crossed
+	RejectCrossedMarket = "crossed_market"
+)
+
+// rejectionPatterns map fragments of the account service's error codes and
+// messages to rejection reasons; codes are matched first. The services do not
+// share a code list, so these follow what the venues are known to send.
+var rejectionPatterns = []struct {
+	fragment string
+	reason   string
+}{
+	{"price_out_of_range", RejectPriceOutOfRange},
+	{"invalid_price", RejectPriceOutOfRange},
+	{"price out of range", RejectPriceOutOfRange},
+	{"invalid price", RejectPriceOutOfRange},
+	{"crossed", RejectCrossedMarket},
+	{"would_cross", RejectCrossedMarket},
+	{"would cross", RejectCrossedMarket},
+	{"crosses the book", RejectCrossedMarket},
+}
+
+// rejectionReason classifies a refusal by the account service. It returns ""
+// for errors that repricing would not fix.
+func rejectionReason(err error) string {
+	var rejected *OrderRejectedError
+	if !errors.As(err, &rejected) || rejected.StatusCode >= 500 {
+		return ""
+	}
+
+	code := strings.ToLower(rejected.Code)
+	message := strings.ToLower(err.Error())
+	for _, text := range []string{code, message} {
+		if text == "" {
+			continue
+		}
+		for _, p := range rejectionPatterns {
+			if strings.Contains(text, p.fragment) {
+				return p.reason
+			}
+		}
+	}
+	return ""
+}
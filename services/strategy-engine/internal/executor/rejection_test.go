@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+type resultRecorder struct {
+	results []CommandResult
+}
+
+func (r *resultRecorder) PublishCommandResult(_ context.Context, result CommandResult) {
+	r.results = append(r.results, result)
+}
+
+func TestRejectionReasonFromPredictAccount(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		detail interface{}
+		want   string
+	}{
+		// How predict-account reports an order Predict refused
+		{"price rejected by the venue", http.StatusUnprocessableEntity,
+			map[string]interface{}{"code": "venue_rejected", "message": `Predict rejected the order (400): {"message":"Invalid price"}`},
+			RejectPriceOutOfRange},
+		{"crossing rejected by the venue", http.StatusUnprocessableEntity,
+			map[string]interface{}{"code": "venue_rejected", "message": `Predict rejected the order (400): order would cross the book`},
+			RejectCrossedMarket},
+		{"other venue rejection", http.StatusUnprocessableEntity,
+			map[string]interface{}{"code": "venue_rejected", "message": `Predict rejected the order (400): insufficient collateral`},
+			""},
+		{"outcome unknown", http.StatusInternalServerError, "Invalid price", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newFakeService(t)
+			service.reject = func(w http.ResponseWriter) {
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(map[string]interface{}{"detail": tt.detail})
+			}
+			e, err := NewExecutor(map[string]Platform{"predict": {URL: service.URL}}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results := &resultRecorder{}
+			e.SetResultPublisher(results)
+
+			e.ExecuteCommands(context.Background(), []types.Command{{
+				Type: "place_order", Platform: "predict", AccountID: "a", MarketID: "m1", Side: "yes", Price: 0.4, Shares: 10,
+			}})
+
+			if len(results.results) != 1 {
+				t.Fatalf("got %d results", len(results.results))
+			}
+			if got := results.results[0].RejectReason; got != tt.want {
+				t.Errorf("reject reason %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
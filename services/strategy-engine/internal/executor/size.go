@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/units"
+)
+
+// Strategies may size a place_order in a common unit instead of the venue's
+// shares: Command.Size in Command.SizeUnit, "contracts" paying out 1 USD or
+// "usd" notional (see the units package). ConvertSize turns it into Shares of
+// the command's platform, using the platform's Units convention, and records
+// the requested size in the "requested_size" and "size_unit" metadata. The
+// engine converts commands as soon as a handler returns them, so limits and
+// budgets see shares; the executor converts whatever reaches it unconverted.
+
+// Units returns the size converter of the configured platforms
+func (e *Executor) Units() *units.Converter {
+	return e.sizes
+}
+
+// ConvertSize sets the Shares of a place_order sized in another unit. Other
+// commands are returned unchanged.
+func (e *Executor) ConvertSize(cmd types.Command) (types.Command, error) {
+	if !cmd.PlacesOrder() || (cmd.Size == 0 && cmd.SizeUnit == "") {
+		return cmd, nil
+	}
+	if cmd.Size <= 0 {
+		return cmd, fmt.Errorf("%w: size_unit %s without a positive size", ErrInvalidOrder, cmd.SizeUnit)
+	}
+
+	shares, err := e.sizes.Shares(cmd.Platform, cmd.SizeUnit, cmd.Size, cmd.Price)
+	if err != nil {
+		return cmd, fmt.Errorf("%w: %w", ErrInvalidOrder, err)
+	}
+
+	metadata := make(map[string]interface{}, len(cmd.Metadata)+2)
+	for k, v := range cmd.Metadata {
+		metadata[k] = v
+	}
+	metadata["requested_size"] = cmd.Size
+	metadata["size_unit"] = cmd.SizeUnit
+	if cmd.SizeUnit == "" {
+		metadata["size_unit"] = units.Shares
+	}
+	cmd.Metadata = metadata
+
+	// Strip float noise such as 33.333333333333336
+	cmd.Shares = math.Round(shares*1e6) / 1e6
+	cmd.Size = 0
+	cmd.SizeUnit = ""
+	return cmd, nil
+}
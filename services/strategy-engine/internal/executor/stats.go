@@ -0,0 +1,33 @@
+package executor
+
+import "sync"
+
+// orderCounts counts order outcomes by journal status
+type orderCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *orderCounts) add(status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[status]++
+}
+
+// OrderCounts returns how many orders ended in each journal status (accepted,
+// rejected, failed, dry_run) since the executor was created, and how many
+// commands were skipped as expired (see deadline.go)
+func (e *Executor) OrderCounts() map[string]int64 {
+	e.orderCounts.mu.Lock()
+	defer e.orderCounts.mu.Unlock()
+
+	counts := make(map[string]int64, len(e.orderCounts.counts))
+	for status, n := range e.orderCounts.counts {
+		counts[status] = n
+	}
+	return counts
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/accountsvc"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Time in force is requested with Command.TimeInForce, or through command
+// metadata:
+//
+//	"time_in_force": "GTC" (default) | "IOC" | "FOK" | "GTD"
+//	"expire_at":     RFC3339 timestamp, required for GTD unless Command.ExpiresAt is set
+//	"tif_window":    seconds to wait for an immediate fill (IOC/FOK), default 2
+//
+// Platforms registered with SetNativeTimeInForce receive the fields in the
+// order payload. For everything else the executor emulates them: the order is
+// placed as a resting order, watched through the order tracker, and cancelled
+// once its time in force is over. Emulated FOK cannot prevent partial fills;
+// it only guarantees nothing is left resting. On platforms that cannot cancel
+// (Platform.Cancels), orders needing emulation are refused, as are orders
+// with ExpiresAt unless the venue enforces it as GTD.
+
+const defaultTIFWindow = 2 * time.Second
+
+// SetNativeTimeInForce declares which time-in-force values a platform's
+// account service supports natively
+func (e *Executor) SetNativeTimeInForce(platform string, tifs ...string) {
+	if e.nativeTIF == nil {
+		e.nativeTIF = make(map[string]map[string]bool)
+	}
+	if e.nativeTIF[platform] == nil {
+		e.nativeTIF[platform] = make(map[string]bool)
+	}
+	for _, tif := range tifs {
+		e.nativeTIF[platform][strings.ToUpper(tif)] = true
+	}
+}
+
+func timeInForce(cmd types.Command) string {
+	tif := cmd.TimeInForce
+	if tif == "" {
+		tif, _ = cmd.Metadata["time_in_force"].(string)
+	}
+	if tif == "" {
+		return "GTC"
+	}
+	return strings.ToUpper(tif)
+}
+
+func (e *Executor) supportsNativeTIF(platform, tif string) bool {
+	return e.nativeTIF[platform][tif]
+}
+
+// checkCancellable refuses an order whose time in force or expiry the engine
+// would have to enforce by cancelling it, on a platform that cannot cancel
+func (e *Executor) checkCancellable(cmd types.Command) error {
+	if e.CanCancel(cmd) {
+		return nil
+	}
+	tif := timeInForce(cmd)
+	native := tif == "GTC" || e.supportsNativeTIF(cmd.Platform, tif)
+	if !native {
+		return fmt.Errorf("%w: time in force %s is emulated by cancelling the order, which %s cannot do",
+			ErrInvalidOrder, tif, cmd.Platform)
+	}
+	if cmd.ExpiresAt != nil && tif != "GTD" {
+		return fmt.Errorf("%w: expires_at is enforced by cancelling the order, which %s cannot do",
+			ErrInvalidOrder, cmd.Platform)
+	}
+	return nil
+}
+
+// enforceTimeInForce starts emulation for an accepted order whose time in force
+// the platform cannot enforce itself
+func (e *Executor) enforceTimeInForce(ctx context.Context, cmd types.Command, result *accountsvc.OrderResult) {
+	tif := timeInForce(cmd)
+	if tif == "GTC" || e.supportsNativeTIF(cmd.Platform, tif) {
+		return
+	}
+	if _, direct := e.clobClient(cmd); direct {
+		return
+	}
+
+	orderID := result.ID()
+	if orderID == "" || result.DryRun() || result.Filled(cmd.Shares) || e.tracker == nil {
+		return
+	}
+
+	var wait time.Duration
+	switch tif {
+	case "IOC", "FOK":
+		wait = metadataSeconds(cmd.Metadata, "tif_window", defaultTIFWindow)
+	case "GTD":
+		expireAt, _ := cmd.Metadata["expire_at"].(string)
+		if expireAt == "" && cmd.ExpiresAt != nil {
+			// The order reaper cancels it, also across restarts
+			return
+		}
+		t, err := time.Parse(time.RFC3339, expireAt)
+		if err != nil {
+			log.Error().Err(err).Str("order_id", orderID).Msg("GTD order has invalid expire_at, leaving it resting")
+			return
+		}
+		wait = time.Until(t)
+	default:
+		log.Warn().Str("time_in_force", tif).Str("order_id", orderID).Msg("Unknown time in force, treating as GTC")
+		return
+	}
+
+	// ctx may be bounded by the command's deadline, which ends with the call;
+	// the order has to be cancelled long after that
+	go e.expireOrder(context.WithoutCancel(ctx), cmd, orderID, tif, wait)
+}
+
+// expireOrder cancels whatever remains of an order after wait
+func (e *Executor) expireOrder(ctx context.Context, cmd types.Command, orderID, tif string, wait time.Duration) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(wait):
+	}
+
+	order, open := e.tracker.Get(cmd.Platform, orderID)
+	if !open {
+		return
+	}
+
+	if tif == "FOK" && order.FilledShares > 0 {
+		log.Warn().
+			Str("order_id", orderID).
+			Float64("filled", order.FilledShares).
+			Float64("shares", order.Shares).
+			Msg("Emulated FOK order was partially filled")
+	}
+
+	strategy, _ := cmd.Metadata["strategy"].(string)
+	cancel := types.Command{
+		Type:      "cancel_order",
+		Platform:  cmd.Platform,
+		AccountID: cmd.AccountID,
+		MarketID:  cmd.MarketID,
+		Metadata: map[string]interface{}{
+			"order_id": orderID,
+			"strategy": strategy,
+			"reason":   "time_in_force_" + strings.ToLower(tif),
+		},
+	}
+
+	if err := e.cancelOrder(ctx, cancel); err != nil {
+		log.Error().
+			Err(err).
+			Str("order_id", orderID).
+			Str("time_in_force", tif).
+			Msg("Failed to cancel order at end of time in force")
+	}
+}
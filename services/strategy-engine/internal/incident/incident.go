@@ -0,0 +1,202 @@
+package incident
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/logbuf"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// lookback is how much history an incident bundle captures
+const lookback = time.Hour
+
+// maxEventsPerStream bounds the events exported from each stream
+const maxEventsPerStream = 10000
+
+// Manager runs incident mode: freeze trading, snapshot state, export recent
+// events, commands and logs to a bundle, and post a summary alert.
+type Manager struct {
+	engine   *engine.Engine
+	eventBus eventbus.EventBus
+	storage  *storage.PostgresStorage
+	logs     *logbuf.Ring
+	dir      string
+}
+
+func NewManager(
+	engine *engine.Engine,
+	eventBus eventbus.EventBus,
+	storage *storage.PostgresStorage,
+	logs *logbuf.Ring,
+	dir string,
+) *Manager {
+	return &Manager{
+		engine:   engine,
+		eventBus: eventBus,
+		storage:  storage,
+		logs:     logs,
+		dir:      dir,
+	}
+}
+
+// Summary describes a completed incident action
+type Summary struct {
+	ID         string         `json:"id"`
+	Reason     string         `json:"reason"`
+	Operator   string         `json:"operator"`
+	StartedAt  time.Time      `json:"started_at"`
+	Bundle     string         `json:"bundle"`
+	Events     map[string]int `json:"events"`
+	Commands   int            `json:"commands"`
+	LogLines   int            `json:"log_lines"`
+	OpenOrders int            `json:"open_orders"`
+	Errors     []string       `json:"errors,omitempty"`
+}
+
+// Trigger freezes trading first, then collects what it can. Collection
+// failures are recorded in the summary rather than aborting: the kill switch
+// must never depend on Redis or Postgres being healthy.
+func (m *Manager) Trigger(ctx context.Context, reason, operator string) (*Summary, error) {
+	now := time.Now().UTC()
+	summary := &Summary{
+		ID:        now.Format("20060102T150405Z"),
+		Reason:    reason,
+		Operator:  operator,
+		StartedAt: now,
+		Events:    make(map[string]int),
+	}
+
+	m.engine.Halt(fmt.Sprintf("incident %s: %s", summary.ID, reason))
+
+	log.Warn().
+		Str("incident", summary.ID).
+		Str("reason", reason).
+		Str("operator", operator).
+		Msg("Incident mode triggered")
+
+	files := make(map[string]interface{})
+
+	snapshot := m.engine.Snapshot()
+	summary.OpenOrders = len(snapshot.OpenOrders)
+	files["snapshot.json"] = snapshot
+
+	since := now.Add(-lookback)
+	for _, stream := range m.engine.Streams() {
+		events, err := m.eventBus.Range(ctx, stream, since, maxEventsPerStream)
+		if err != nil {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("%s: %v", stream, err))
+			continue
+		}
+		summary.Events[stream] = len(events)
+		files["events/"+stream+".json"] = events
+	}
+
+	commands, err := m.storage.GetJournalSince(ctx, since)
+	if err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("commands: %v", err))
+	}
+	summary.Commands = len(commands)
+	files["commands.json"] = commands
+
+	var logs []byte
+	if m.logs != nil {
+		lines := m.logs.Lines()
+		summary.LogLines = len(lines)
+		logs = bytes.Join(lines, nil)
+	}
+
+	bundle, err := m.writeBundle(summary, files, logs)
+	if err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("bundle: %v", err))
+	}
+	summary.Bundle = bundle
+
+	m.postAlert(ctx, summary)
+
+	return summary, nil
+}
+
+// writeBundle writes a .tar.gz with one JSON file per artifact plus the logs
+func (m *Manager) writeBundle(summary *Summary, files map[string]interface{}, logs []byte) (string, error) {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(m.dir, fmt.Sprintf("incident-%s.tar.gz", summary.ID))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: summary.StartedAt,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	files["summary.json"] = summary
+	for name, v := range files {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal %s: %w", name, err)
+		}
+		if err := add(name, data); err != nil {
+			return "", err
+		}
+	}
+	if err := add("logs.jsonl", logs); err != nil {
+		return "", err
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (m *Manager) postAlert(ctx context.Context, summary *Summary) {
+	message := fmt.Sprintf(
+		"Trading frozen by %s: %s. %d open orders, %d commands in the last hour. Bundle: %s",
+		summary.Operator, summary.Reason, summary.OpenOrders, summary.Commands, summary.Bundle,
+	)
+
+	data := map[string]interface{}{
+		"incident":    summary.ID,
+		"operator":    summary.Operator,
+		"bundle":      summary.Bundle,
+		"events":      summary.Events,
+		"commands":    summary.Commands,
+		"open_orders": summary.OpenOrders,
+		"errors":      summary.Errors,
+	}
+
+	if err := m.storage.CreateAlert(ctx, "error", "Incident mode engaged", message, data); err != nil {
+		log.Error().Err(err).Str("incident", summary.ID).Msg("Failed to post incident alert")
+	}
+}
@@ -0,0 +1,85 @@
+package logbuf
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// LevelFilter applies the log level in front of the writers, with overrides
+// per strategy: an entry below the base level still goes through when its
+// "strategy" field names a strategy whose override allows it. The zerolog
+// global level is lowered to the most verbose override so such entries are
+// built at all; only they pay for the field lookup.
+type LevelFilter struct {
+	out        zerolog.LevelWriter
+	base       zerolog.Level
+	strategies map[string]zerolog.Level
+	mu         sync.RWMutex
+}
+
+func NewLevelFilter(out io.Writer) *LevelFilter {
+	lw, ok := out.(zerolog.LevelWriter)
+	if !ok {
+		lw = zerolog.MultiLevelWriter(out)
+	}
+	return &LevelFilter{out: lw, base: zerolog.InfoLevel}
+}
+
+// SetLevels sets the base level and the per-strategy overrides. Callers
+// validate the levels; unparseable ones are ignored.
+func (f *LevelFilter) SetLevels(base string, strategies map[string]string) {
+	baseLevel, err := zerolog.ParseLevel(base)
+	if err != nil {
+		baseLevel = zerolog.InfoLevel
+	}
+
+	lowest := baseLevel
+	overrides := make(map[string]zerolog.Level, len(strategies))
+	for name, level := range strategies {
+		parsed, err := zerolog.ParseLevel(level)
+		if err != nil {
+			continue
+		}
+		overrides[name] = parsed
+		if parsed < lowest {
+			lowest = parsed
+		}
+	}
+
+	f.mu.Lock()
+	f.base = baseLevel
+	f.strategies = overrides
+	f.mu.Unlock()
+
+	zerolog.SetGlobalLevel(lowest)
+}
+
+// Write passes entries without a level through unchanged
+func (f *LevelFilter) Write(p []byte) (int, error) {
+	return f.out.Write(p)
+}
+
+// WriteLevel drops entries below the level that applies to them
+func (f *LevelFilter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	f.mu.RLock()
+	base, overrides := f.base, f.strategies
+	f.mu.RUnlock()
+
+	if level >= base {
+		return f.out.WriteLevel(level, p)
+	}
+	if len(overrides) > 0 {
+		var entry struct {
+			Strategy string `json:"strategy"`
+		}
+		if json.Unmarshal(p, &entry) == nil {
+			if override, ok := overrides[entry.Strategy]; ok && level >= override {
+				return f.out.WriteLevel(level, p)
+			}
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,43 @@
+package logbuf
+
+import "sync"
+
+// Ring is an io.Writer keeping the most recent log lines in memory, so they
+// can be exported without access to the container's log driver.
+type Ring struct {
+	lines [][]byte
+	next  int
+	full  bool
+	mu    sync.Mutex
+}
+
+func NewRing(size int) *Ring {
+	return &Ring{lines: make([][]byte, size)}
+}
+
+// Write stores one log entry. zerolog issues one Write per entry.
+func (r *Ring) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	r.mu.Lock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+
+	return len(p), nil
+}
+
+// Lines returns the buffered entries, oldest first
+func (r *Ring) Lines() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([][]byte(nil), r.lines[:r.next]...)
+	}
+	return append(append([][]byte(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
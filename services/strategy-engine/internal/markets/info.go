@@ -0,0 +1,150 @@
+package markets
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Info is the trading metadata of one market. Zero values mean unknown.
+type Info struct {
+	Platform  string    `json:"platform"`
+	MarketID  string    `json:"market_id"`
+	TickSize  float64   `json:"tick_size,omitempty"`
+	MinShares float64   `json:"min_shares,omitempty"`
+	MaxShares float64   `json:"max_shares,omitempty"`
+	MakerFee  float64   `json:"maker_fee,omitempty"` // fraction of notional
+	TakerFee  float64   `json:"taker_fee,omitempty"`
+	CloseTime time.Time `json:"close_time,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Known reports whether any metadata was found for the market
+func (i Info) Known() bool {
+	return i.TickSize > 0 || i.MinShares > 0 || i.MaxShares > 0 ||
+		i.MakerFee > 0 || i.TakerFee > 0 || !i.CloseTime.IsZero()
+}
+
+// Closed reports whether the market stopped trading at now
+func (i Info) Closed(now time.Time) bool {
+	return !i.CloseTime.IsZero() && !now.Before(i.CloseTime)
+}
+
+// RoundPrice rounds a buy price down to the tick size, so the rounded order
+// never pays more than asked, keeping it within one tick of 0 and 1.
+// Without a known tick size the price is returned unchanged.
+func (i Info) RoundPrice(price float64) float64 {
+	if i.TickSize <= 0 {
+		return price
+	}
+	// The epsilon keeps prices already on the grid (0.29 / 0.01 = 28.999...) in place
+	ticks := math.Floor(price/i.TickSize + 1e-9)
+	rounded := ticks * i.TickSize
+	rounded = math.Max(rounded, i.TickSize)
+	rounded = math.Min(rounded, 1-i.TickSize)
+	// Strip float noise such as 0.30000000000000004
+	return math.Round(rounded*1e6) / 1e6
+}
+
+// ParseInfo reads market metadata from a market_update event or an account
+// service response. Field names follow the Predict and Polymarket conventions;
+// numbers may be strings. Fees given in basis points are converted.
+func ParseInfo(platform, marketID string, data map[string]interface{}) Info {
+	info := Info{
+		Platform:  platform,
+		MarketID:  marketID,
+		TickSize:  number(data, "tick_size", "minimum_tick_size", "tickSize", "decimalPrecisionTick"),
+		MinShares: number(data, "min_order_size", "minimum_order_size", "min_size", "minOrderSize"),
+		MaxShares: number(data, "max_order_size", "max_size", "maxOrderSize"),
+		MakerFee:  number(data, "maker_fee", "makerFee"),
+		TakerFee:  number(data, "taker_fee", "takerFee"),
+		UpdatedAt: time.Now().UTC(),
+	}
+	if bps := number(data, "maker_base_fee", "maker_fee_bps", "feeRateBps"); info.MakerFee == 0 && bps > 0 {
+		info.MakerFee = bps / 10000
+	}
+	if bps := number(data, "taker_base_fee", "taker_fee_bps", "feeRateBps"); info.TakerFee == 0 && bps > 0 {
+		info.TakerFee = bps / 10000
+	}
+
+	for _, key := range []string{"close_time", "end_date", "end_date_iso", "endDate", "closeTime"} {
+		value, _ := data[key].(string)
+		if value == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			info.CloseTime = t.UTC()
+			break
+		}
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			info.CloseTime = t.UTC()
+			break
+		}
+	}
+
+	return info
+}
+
+func number(data map[string]interface{}, keys ...string) float64 {
+	for _, key := range keys {
+		switch v := data[key].(type) {
+		case float64:
+			return v
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		}
+	}
+	return 0
+}
+
+// SetInfo stores the metadata of a market, replacing what was known
+func (r *Registry) SetInfo(info Info) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.info[info.MarketID] = info
+}
+
+// Info returns the metadata of a market. The result may be unknown (see
+// Info.Known) if only a lookup failure was recorded.
+func (r *Registry) Info(marketID string) (Info, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info, ok := r.info[marketID]
+	return info, ok
+}
+
+// updateInfo merges the metadata carried by a market_update event; r.mu is held
+func (r *Registry) updateInfo(platform, marketID string, data map[string]interface{}) {
+	update := ParseInfo(platform, marketID, data)
+	if !update.Known() {
+		return
+	}
+
+	info := r.info[marketID]
+	info.Platform = platform
+	info.MarketID = marketID
+	info.UpdatedAt = update.UpdatedAt
+	if update.TickSize > 0 {
+		info.TickSize = update.TickSize
+	}
+	if update.MinShares > 0 {
+		info.MinShares = update.MinShares
+	}
+	if update.MaxShares > 0 {
+		info.MaxShares = update.MaxShares
+	}
+	if update.MakerFee > 0 {
+		info.MakerFee = update.MakerFee
+	}
+	if update.TakerFee > 0 {
+		info.TakerFee = update.TakerFee
+	}
+	if !update.CloseTime.IsZero() {
+		info.CloseTime = update.CloseTime
+	}
+	r.info[marketID] = info
+}
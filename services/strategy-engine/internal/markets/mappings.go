@@ -0,0 +1,176 @@
+package markets
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Title is the question of a market as announced in market_update events
+type Title struct {
+	Platform string `json:"platform"`
+	MarketID string `json:"market_id"`
+	Text     string `json:"title"`
+}
+
+// updateTitle records the question carried by a market_update event; r.mu is
+// held
+func (r *Registry) updateTitle(platform, marketID string, data map[string]interface{}) {
+	for _, key := range []string{"question", "title", "market_title", "name"} {
+		if text, _ := data[key].(string); text != "" {
+			r.titles[marketID] = Title{Platform: platform, MarketID: marketID, Text: text}
+			return
+		}
+	}
+}
+
+// Titles returns the known questions of a platform's markets, by market ID
+func (r *Registry) Titles(platform string) []Title {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var titles []Title
+	for _, t := range r.titles {
+		if t.Platform == platform {
+			titles = append(titles, t)
+		}
+	}
+	sort.Slice(titles, func(i, j int) bool { return titles[i].MarketID < titles[j].MarketID })
+	return titles
+}
+
+// Counterpart is the market on the other platform that matches a market
+type Counterpart struct {
+	Platform string
+	MarketID string
+	Side     string // the side paying like the given one, "" if none was given
+	Mapping  types.MarketMapping
+}
+
+// Mappings is the set of Predict markets mapped to Polymarket conditions. A
+// nil set maps nothing.
+type Mappings struct {
+	mu           sync.RWMutex
+	byID         map[int64]types.MarketMapping
+	byPredict    map[string]types.MarketMapping
+	byPolymarket map[string]types.MarketMapping
+}
+
+func NewMappings() *Mappings {
+	return &Mappings{
+		byID:         make(map[int64]types.MarketMapping),
+		byPredict:    make(map[string]types.MarketMapping),
+		byPolymarket: make(map[string]types.MarketMapping),
+	}
+}
+
+// Set replaces every mapping
+func (m *Mappings) Set(mappings []types.MarketMapping) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.byID = make(map[int64]types.MarketMapping, len(mappings))
+	for _, mapping := range mappings {
+		m.byID[mapping.ID] = mapping
+	}
+	m.reindex()
+}
+
+// Put adds a mapping or replaces the one with the same ID
+func (m *Mappings) Put(mapping types.MarketMapping) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.byID[mapping.ID] = mapping
+	m.reindex()
+}
+
+// Remove drops the mapping with the given ID
+func (m *Mappings) Remove(id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.byID, id)
+	m.reindex()
+}
+
+// reindex rebuilds the lookups by market; m.mu is held
+func (m *Mappings) reindex() {
+	m.byPredict = make(map[string]types.MarketMapping, len(m.byID))
+	m.byPolymarket = make(map[string]types.MarketMapping, len(m.byID))
+	for _, mapping := range m.byID {
+		m.byPredict[mapping.PredictMarketID] = mapping
+		m.byPolymarket[mapping.PolymarketConditionID] = mapping
+	}
+}
+
+// All returns every mapping, by ID
+func (m *Mappings) All() []types.MarketMapping {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mappings := make([]types.MarketMapping, 0, len(m.byID))
+	for _, mapping := range m.byID {
+		mappings = append(mappings, mapping)
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].ID < mappings[j].ID })
+	return mappings
+}
+
+// Polymarket returns the mapping of a Predict market
+func (m *Mappings) Polymarket(predictMarketID string) (types.MarketMapping, bool) {
+	if m == nil {
+		return types.MarketMapping{}, false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mapping, ok := m.byPredict[predictMarketID]
+	return mapping, ok
+}
+
+// Predict returns the mapping of a Polymarket condition
+func (m *Mappings) Predict(conditionID string) (types.MarketMapping, bool) {
+	if m == nil {
+		return types.MarketMapping{}, false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mapping, ok := m.byPolymarket[conditionID]
+	return mapping, ok
+}
+
+// Counterpart returns the market on the other platform that matches a
+// Predict or Polymarket market. With a side ("yes" or "no") it also returns
+// the side there that pays out the same, which differs for inverted mappings.
+func (m *Mappings) Counterpart(platform, marketID, side string) (Counterpart, bool) {
+	var c Counterpart
+	var ok bool
+	switch platform {
+	case "predict":
+		c.Mapping, ok = m.Polymarket(marketID)
+		c.Platform, c.MarketID = "polymarket", c.Mapping.PolymarketConditionID
+	case "polymarket":
+		c.Mapping, ok = m.Predict(marketID)
+		c.Platform, c.MarketID = "predict", c.Mapping.PredictMarketID
+	}
+	if !ok {
+		return Counterpart{}, false
+	}
+
+	c.Side = side
+	if c.Mapping.Inverted {
+		switch side {
+		case "yes":
+			c.Side = "no"
+		case "no":
+			c.Side = "yes"
+		}
+	}
+	return c, true
+}
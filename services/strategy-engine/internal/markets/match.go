@@ -0,0 +1,174 @@
+package markets
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Suggestion is a likely Predict-Polymarket pair found by comparing market
+// questions. Score runs from 0 to 1.
+type Suggestion struct {
+	PredictMarketID       string  `json:"predict_market_id"`
+	PredictTitle          string  `json:"predict_title"`
+	PolymarketConditionID string  `json:"polymarket_condition_id"`
+	PolymarketTitle       string  `json:"polymarket_title"`
+	Score                 float64 `json:"score"`
+}
+
+// stopWords are left out when looking for candidate pairs
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "will": true, "be": true, "by": true,
+	"in": true, "on": true, "of": true, "to": true, "at": true, "or": true,
+	"and": true, "for": true, "is": true, "before": true, "after": true,
+}
+
+// Suggest pairs Predict and Polymarket markets with similar questions. The
+// score is the Sørensen-Dice coefficient of the questions' letter pairs,
+// halved when the numbers in them (dates, thresholds) conflict, since "BTC
+// above 100k" and "BTC above 120k" are different markets. A question leaving
+// out a number of the other, such as the year, does not conflict. Each market
+// appears in at most one suggestion, best scores first, and only pairs
+// scoring at least minScore are returned.
+func Suggest(predict, polymarket []Title, minScore float64, limit int) []Suggestion {
+	type question struct {
+		title   Title
+		bigrams map[string]int
+		numbers map[string]bool
+	}
+	parse := func(t Title) question {
+		words := tokenize(t.Text)
+		return question{title: t, bigrams: bigrams(strings.Join(words, " ")), numbers: numbers(words)}
+	}
+
+	// Only questions sharing a significant word are compared
+	index := make(map[string][]int)
+	targets := make([]question, len(polymarket))
+	for i, t := range polymarket {
+		targets[i] = parse(t)
+		for _, word := range significant(tokenize(t.Text)) {
+			index[word] = append(index[word], i)
+		}
+	}
+
+	var candidates []Suggestion
+	for _, t := range predict {
+		source := parse(t)
+		seen := make(map[int]bool)
+		for _, word := range significant(tokenize(t.Text)) {
+			for _, i := range index[word] {
+				if seen[i] {
+					continue
+				}
+				seen[i] = true
+
+				target := targets[i]
+				score := dice(source.bigrams, target.bigrams)
+				if !subset(source.numbers, target.numbers) && !subset(target.numbers, source.numbers) {
+					score /= 2
+				}
+				if score < minScore {
+					continue
+				}
+				candidates = append(candidates, Suggestion{
+					PredictMarketID:       t.MarketID,
+					PredictTitle:          t.Text,
+					PolymarketConditionID: target.title.MarketID,
+					PolymarketTitle:       target.title.Text,
+					Score:                 score,
+				})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].PredictMarketID != candidates[j].PredictMarketID {
+			return candidates[i].PredictMarketID < candidates[j].PredictMarketID
+		}
+		return candidates[i].PolymarketConditionID < candidates[j].PolymarketConditionID
+	})
+
+	usedPredict := make(map[string]bool)
+	usedPolymarket := make(map[string]bool)
+	var suggestions []Suggestion
+	for _, c := range candidates {
+		if usedPredict[c.PredictMarketID] || usedPolymarket[c.PolymarketConditionID] {
+			continue
+		}
+		usedPredict[c.PredictMarketID] = true
+		usedPolymarket[c.PolymarketConditionID] = true
+		suggestions = append(suggestions, c)
+		if limit > 0 && len(suggestions) == limit {
+			break
+		}
+	}
+	return suggestions
+}
+
+// tokenize lowercases a question and splits it into words and numbers
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+}
+
+func significant(words []string) []string {
+	var result []string
+	for _, word := range words {
+		word = strings.Trim(word, ".")
+		if len(word) > 1 && !stopWords[word] {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+// numbers returns the words of a question that contain digits
+func numbers(words []string) map[string]bool {
+	result := make(map[string]bool)
+	for _, word := range words {
+		if strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+			result[strings.Trim(word, ".")] = true
+		}
+	}
+	return result
+}
+
+func subset(a, b map[string]bool) bool {
+	for word := range a {
+		if !b[word] {
+			return false
+		}
+	}
+	return true
+}
+
+func bigrams(text string) map[string]int {
+	result := make(map[string]int)
+	runes := []rune(text)
+	for i := 0; i+1 < len(runes); i++ {
+		result[string(runes[i:i+2])]++
+	}
+	return result
+}
+
+// dice is the Sørensen-Dice coefficient of two bigram multisets
+func dice(a, b map[string]int) float64 {
+	var total, shared int
+	for bigram, n := range a {
+		total += n
+		if m := b[bigram]; m > 0 {
+			shared += min(n, m)
+		}
+	}
+	for _, n := range b {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(shared) / float64(total)
+}
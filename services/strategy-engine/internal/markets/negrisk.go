@@ -0,0 +1,228 @@
+package markets
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// NegRiskGroup is a set of mutually exclusive Polymarket markets: exactly one
+// of them resolves YES. A NO share in one market is therefore equivalent to a
+// YES share in every other market of the group, and the NegRiskAdapter can
+// convert between the two.
+//
+// Members are learned from market_update events. Complete is set once the
+// group's full membership is known, from "neg_risk_markets" (every market ID
+// of the group) or "neg_risk_market_count" on an event; until then Markets may
+// be a part of the group.
+type NegRiskGroup struct {
+	ID       string   `json:"id"` // negRiskMarketID on Polymarket
+	Markets  []string `json:"markets"`
+	Complete bool     `json:"complete"`
+}
+
+// Registry tracks market metadata learned from market events
+type Registry struct {
+	groupOf map[string]string              // market ID -> neg-risk group ID
+	groups  map[string]map[string]struct{} // group ID -> market IDs
+	size    map[string]int                 // group ID -> declared number of markets
+	prices  map[string]float64             // market ID -> last YES price
+	info    map[string]Info                // market ID -> trading metadata
+	outcome map[string]string              // market ID -> winning side once resolved
+	titles  map[string]Title               // market ID -> question
+	mu      sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		groupOf: make(map[string]string),
+		groups:  make(map[string]map[string]struct{}),
+		size:    make(map[string]int),
+		prices:  make(map[string]float64),
+		info:    make(map[string]Info),
+		outcome: make(map[string]string),
+		titles:  make(map[string]Title),
+	}
+}
+
+// Update applies a market_update event. Polymarket markets that belong to a
+// negative-risk group carry "neg_risk": true and "neg_risk_market_id", and
+// optionally the group's membership (see NegRiskGroup).
+func (r *Registry) Update(event types.Event) {
+	if event.Type != "market_update" {
+		return
+	}
+
+	marketID, _ := event.Data["market_id"].(string)
+	if marketID == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if price, ok := event.Data["yes_price"].(float64); ok {
+		r.prices[marketID] = price
+	}
+	r.updateInfo(event.Platform, marketID, event.Data)
+	r.updateTitle(event.Platform, marketID, event.Data)
+
+	negRisk, _ := event.Data["neg_risk"].(bool)
+	groupID, _ := event.Data["neg_risk_market_id"].(string)
+	if !negRisk || groupID == "" {
+		return
+	}
+
+	r.addToGroup(groupID, marketID)
+
+	members, _ := event.Data["neg_risk_markets"].([]interface{})
+	for _, raw := range members {
+		if id, _ := raw.(string); id != "" {
+			r.addToGroup(groupID, id)
+		}
+	}
+	if len(members) > 0 {
+		r.size[groupID] = len(members)
+	} else if count, ok := event.Data["neg_risk_market_count"].(float64); ok && count > 0 {
+		r.size[groupID] = int(count)
+	}
+}
+
+// addToGroup records a market as a member of a group. The caller holds r.mu.
+func (r *Registry) addToGroup(groupID, marketID string) {
+	if prev, ok := r.groupOf[marketID]; ok && prev != groupID {
+		delete(r.groups[prev], marketID)
+	}
+	r.groupOf[marketID] = groupID
+	if r.groups[groupID] == nil {
+		r.groups[groupID] = make(map[string]struct{})
+	}
+	r.groups[groupID][marketID] = struct{}{}
+}
+
+// NegRiskGroup returns the group a market belongs to
+func (r *Registry) NegRiskGroup(marketID string) (NegRiskGroup, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groupID, ok := r.groupOf[marketID]
+	if !ok {
+		return NegRiskGroup{}, false
+	}
+
+	group := NegRiskGroup{ID: groupID}
+	for id := range r.groups[groupID] {
+		group.Markets = append(group.Markets, id)
+	}
+	sort.Strings(group.Markets)
+	group.Complete = r.size[groupID] > 0 && len(group.Markets) >= r.size[groupID]
+
+	return group, true
+}
+
+// Price returns the last known YES price of a market
+func (r *Registry) Price(marketID string) (float64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	price, ok := r.prices[marketID]
+	return price, ok
+}
+
+// SetResolved marks a market as resolved with the given winning side
+func (r *Registry) SetResolved(marketID, outcome string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.outcome[marketID] = outcome
+}
+
+// Resolved returns the winning side of a resolved market
+func (r *Registry) Resolved(marketID string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	outcome, ok := r.outcome[marketID]
+	return outcome, ok
+}
+
+// Leg is one order of a multi-leg position
+type Leg struct {
+	MarketID string
+	Side     string
+}
+
+// ComplementLegs returns the YES legs on every other market of the group,
+// which together pay out exactly like NO on marketID. That only holds for a
+// complete group; it returns nil otherwise.
+func (g NegRiskGroup) ComplementLegs(marketID string) []Leg {
+	if !g.Complete {
+		return nil
+	}
+	var legs []Leg
+	for _, id := range g.Markets {
+		if id != marketID {
+			legs = append(legs, Leg{MarketID: id, Side: "yes"})
+		}
+	}
+	return legs
+}
+
+// Conversion describes converting NO shares held in several markets of a group.
+// Converting `shares` NO in each of k markets releases (k-1)*shares collateral
+// and yields `shares` YES in each of the remaining markets of the group.
+type Conversion struct {
+	GroupID    string
+	NoMarkets  []string
+	Shares     float64
+	Collateral float64
+	YesMarkets []string
+}
+
+// Convert computes the outcome of converting NO positions in noMarkets
+func (g NegRiskGroup) Convert(noMarkets []string, shares float64) Conversion {
+	converted := make(map[string]bool, len(noMarkets))
+	for _, id := range noMarkets {
+		converted[id] = true
+	}
+
+	conversion := Conversion{
+		GroupID:   g.ID,
+		NoMarkets: noMarkets,
+		Shares:    shares,
+	}
+	if len(noMarkets) > 1 {
+		conversion.Collateral = float64(len(noMarkets)-1) * shares
+	}
+	for _, id := range g.Markets {
+		if !converted[id] {
+			conversion.YesMarkets = append(conversion.YesMarkets, id)
+		}
+	}
+
+	return conversion
+}
+
+// Command builds the convert_positions command executing this conversion
+func (c Conversion) Command(accountID string, metadata map[string]interface{}) types.Command {
+	marketIDs := make([]interface{}, len(c.NoMarkets))
+	for i, id := range c.NoMarkets {
+		marketIDs[i] = id
+	}
+
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["market_ids"] = marketIDs
+
+	return types.Command{
+		Type:      "convert_positions",
+		Platform:  "polymarket",
+		AccountID: accountID,
+		MarketID:  c.GroupID,
+		Side:      "no",
+		Shares:    c.Shares,
+		Metadata:  metadata,
+	}
+}
@@ -0,0 +1,275 @@
+// Package metrics keeps the custom counters and gauges strategies emit and
+// writes them in the Prometheus text format. Every series carries the
+// strategy that emitted it, so two strategies using the same metric name do
+// not mix.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Prefix starts the name of every custom metric, apart from the engine's own
+const Prefix = "strategy_custom_"
+
+// maxSeriesPerStrategy bounds the series one strategy can create, so a tag
+// carrying e.g. an order ID cannot exhaust memory
+const maxSeriesPerStrategy = 500
+
+// Metric kinds
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+type series struct {
+	labels string // rendered, sorted, with the strategy first
+	value  float64
+}
+
+type family struct {
+	kind   string
+	series map[string]*series // by rendered labels
+}
+
+// Registry holds the custom metrics of all strategies. It is safe for
+// concurrent use; a nil registry drops everything.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family // by exported name
+	counts   map[string]int     // series by strategy
+	refused  map[string]bool    // names already warned about
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+		counts:   make(map[string]int),
+		refused:  make(map[string]bool),
+	}
+}
+
+// Add adds delta to a strategy's counter. Negative deltas are ignored, since
+// counters only go up. Tags are key-value pairs.
+func (r *Registry) Add(strategy, name string, delta float64, tags ...string) {
+	if delta < 0 || math.IsNaN(delta) {
+		return
+	}
+	r.update(strategy, name, Counter, tags, func(s *series) { s.value += delta })
+}
+
+// Set sets a strategy's gauge. Tags are key-value pairs.
+func (r *Registry) Set(strategy, name string, value float64, tags ...string) {
+	r.update(strategy, name, Gauge, tags, func(s *series) { s.value = value })
+}
+
+func (r *Registry) update(strategy, name, kind string, tags []string, apply func(*series)) {
+	if r == nil {
+		return
+	}
+	exported := ExportedName(name, kind)
+	labels := renderLabels(strategy, tags)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[exported]
+	if !ok {
+		f = &family{kind: kind, series: make(map[string]*series)}
+		r.families[exported] = f
+	}
+	if f.kind != kind {
+		r.refuse(exported, fmt.Sprintf("already a %s", f.kind))
+		return
+	}
+
+	s, ok := f.series[labels]
+	if !ok {
+		if r.counts[strategy] >= maxSeriesPerStrategy {
+			r.refuse(strategy, fmt.Sprintf("strategy has %d series", maxSeriesPerStrategy))
+			return
+		}
+		s = &series{labels: labels}
+		f.series[labels] = s
+		r.counts[strategy]++
+	}
+	apply(s)
+}
+
+// refuse warns once about a metric that cannot be recorded. Callers hold r.mu.
+func (r *Registry) refuse(key, reason string) {
+	if r.refused[key] {
+		return
+	}
+	r.refused[key] = true
+	log.Warn().Str("metric", key).Str("reason", reason).Msg("Custom metric dropped")
+}
+
+// Value returns the current value of a strategy's metric, for tests and
+// checks
+func (r *Registry) Value(strategy, name, kind string, tags ...string) (float64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[ExportedName(name, kind)]
+	if !ok || f.kind != kind {
+		return 0, false
+	}
+	s, ok := f.series[renderLabels(strategy, tags)]
+	if !ok {
+		return 0, false
+	}
+	return s.value, true
+}
+
+// WritePrometheus writes every metric in the Prometheus text format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		samples := make([]Sample, 0, len(f.series))
+		for _, s := range f.series {
+			samples = append(samples, Sample{labels: s.labels, Value: s.value})
+		}
+		if err := WriteFamily(w, name, f.kind, samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sample is one series of a metric family
+type Sample struct {
+	Labels []string // key-value pairs
+	Value  float64
+
+	labels string // rendered, overrides Labels
+}
+
+// WriteFamily writes one metric family in the Prometheus text format, its
+// series sorted by labels
+func WriteFamily(w io.Writer, name, kind string, samples []Sample) error {
+	if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", name, kind); err != nil {
+		return err
+	}
+	lines := make([]string, 0, len(samples))
+	for _, sample := range samples {
+		labels := sample.labels
+		if labels == "" {
+			labels = renderPairs(sample.Labels)
+		}
+		value := strconv.FormatFloat(sample.Value, 'g', -1, 64)
+		if labels == "" {
+			lines = append(lines, name+" "+value)
+		} else {
+			lines = append(lines, name+"{"+labels+"} "+value)
+		}
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportedName is the Prometheus name of a custom metric: Prefix, the name
+// with characters Prometheus does not allow replaced by "_", and "_total"
+// for counters
+func ExportedName(name, kind string) string {
+	name = Prefix + SanitizeName(name)
+	if kind == Counter && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return name
+}
+
+// SanitizeName replaces the characters Prometheus does not allow in metric
+// and label names by "_"
+func SanitizeName(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+			b.WriteRune(c)
+		case c >= '0' && c <= '9' && i > 0:
+			b.WriteRune(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// renderLabels renders the strategy label followed by the tag pairs; tags
+// cannot override the strategy
+func renderLabels(strategy string, tags []string) string {
+	var own []string
+	for i := 0; i < len(tags); i += 2 {
+		if SanitizeName(tags[i]) == "strategy" {
+			continue
+		}
+		own = append(own, tags[i])
+		if i+1 < len(tags) {
+			own = append(own, tags[i+1])
+		}
+	}
+	labels := `strategy="` + escapeLabel(strategy) + `"`
+	if rest := renderPairs(own); rest != "" {
+		labels += "," + rest
+	}
+	return labels
+}
+
+// renderPairs renders key-value pairs sorted by key. A trailing key without
+// a value gets an empty one.
+func renderPairs(tags []string) string {
+	pairs := make([][2]string, 0, len(tags)/2)
+	for i := 0; i < len(tags); i += 2 {
+		key := SanitizeName(tags[i])
+		if key == "" {
+			continue
+		}
+		value := ""
+		if i+1 < len(tags) {
+			value = tags[i+1]
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+
+	rendered := make([]string, len(pairs))
+	for i, p := range pairs {
+		rendered[i] = p[0] + `="` + escapeLabel(p[1]) + `"`
+	}
+	return strings.Join(rendered, ",")
+}
+
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
@@ -0,0 +1,263 @@
+package orderbook
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Stream carries order book events published by the account services
+const Stream = "orderbook_events"
+
+// Quote is the top of the YES book of one market. Sizes are in shares; a zero
+// price means that side of the book is empty.
+type Quote struct {
+	Platform  string    `json:"platform"`
+	MarketID  string    `json:"market_id"`
+	BidPrice  float64   `json:"bid_price"`
+	BidSize   float64   `json:"bid_size"`
+	AskPrice  float64   `json:"ask_price"`
+	AskSize   float64   `json:"ask_size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Mid returns the midpoint, or false if either side is empty
+func (q Quote) Mid() (float64, bool) {
+	if q.BidPrice <= 0 || q.AskPrice <= 0 {
+		return 0, false
+	}
+	return (q.BidPrice + q.AskPrice) / 2, true
+}
+
+// Spread returns ask minus bid, or false if either side is empty
+func (q Quote) Spread() (float64, bool) {
+	if q.BidPrice <= 0 || q.AskPrice <= 0 {
+		return 0, false
+	}
+	return q.AskPrice - q.BidPrice, true
+}
+
+// AskFor returns the price to buy the given outcome immediately. Buying NO
+// crosses the YES bid: NO ask = 1 - YES bid.
+func (q Quote) AskFor(side string) (price, size float64, ok bool) {
+	if side == "no" {
+		if q.BidPrice <= 0 {
+			return 0, 0, false
+		}
+		return 1 - q.BidPrice, q.BidSize, true
+	}
+	if q.AskPrice <= 0 {
+		return 0, 0, false
+	}
+	return q.AskPrice, q.AskSize, true
+}
+
+// BidFor returns the price to sell the given outcome immediately
+func (q Quote) BidFor(side string) (price, size float64, ok bool) {
+	if side == "no" {
+		if q.AskPrice <= 0 {
+			return 0, 0, false
+		}
+		return 1 - q.AskPrice, q.AskSize, true
+	}
+	if q.BidPrice <= 0 {
+		return 0, 0, false
+	}
+	return q.BidPrice, q.BidSize, true
+}
+
+// Trade is the last trade of one market, as a YES price
+type Trade struct {
+	Price     float64   `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PriceFor returns the trade price of the given outcome
+func (t Trade) PriceFor(side string) float64 {
+	if side == "no" {
+		return 1 - t.Price
+	}
+	return t.Price
+}
+
+// Cache keeps the latest top of book and last trade per platform and market
+type Cache struct {
+	mu     sync.RWMutex
+	quotes map[string]Quote // platform:market_id
+	trades map[string]Trade // platform:market_id
+}
+
+func NewCache() *Cache {
+	return &Cache{quotes: make(map[string]Quote), trades: make(map[string]Trade)}
+}
+
+// Update applies an orderbook_snapshot / orderbook_update event. Either full
+// levels ("bids"/"asks" as [[price, size], ...] or [{"price", "size"}, ...])
+// or flat best_bid/best_ask/bid_size/ask_size fields are accepted.
+// It reports whether the event carried a book.
+func (c *Cache) Update(event types.Event) bool {
+	if event.Type != "orderbook_snapshot" && event.Type != "orderbook_update" {
+		return false
+	}
+
+	marketID, _ := event.Data["market_id"].(string)
+	if marketID == "" {
+		return false
+	}
+
+	quote := Quote{
+		Platform:  event.Platform,
+		MarketID:  marketID,
+		UpdatedAt: event.Timestamp,
+	}
+
+	if bids, ok := event.Data["bids"].([]interface{}); ok {
+		quote.BidPrice, quote.BidSize = bestLevel(bids, true)
+	} else {
+		quote.BidPrice = number(event.Data["best_bid"])
+		quote.BidSize = number(event.Data["bid_size"])
+	}
+	if asks, ok := event.Data["asks"].([]interface{}); ok {
+		quote.AskPrice, quote.AskSize = bestLevel(asks, false)
+	} else {
+		quote.AskPrice = number(event.Data["best_ask"])
+		quote.AskSize = number(event.Data["ask_size"])
+	}
+
+	// Venues that report it carry the last trade on the book
+	if last := number(event.Data["last_trade_price"]); last > 0 {
+		c.recordTrade(event.Platform, marketID, last, event.Timestamp)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Out-of-order delivery must not overwrite a newer book
+	if prev, ok := c.quotes[key(event.Platform, marketID)]; ok && prev.UpdatedAt.After(quote.UpdatedAt) {
+		return true
+	}
+	c.quotes[key(event.Platform, marketID)] = quote
+	return true
+}
+
+// Top returns the latest quote for a market if it is no older than maxAge.
+// A zero maxAge accepts any age.
+func (c *Cache) Top(platform, marketID string, maxAge time.Duration) (Quote, bool) {
+	c.mu.RLock()
+	quote, ok := c.quotes[key(platform, marketID)]
+	c.mu.RUnlock()
+
+	if !ok {
+		return Quote{}, false
+	}
+	if maxAge > 0 && time.Since(quote.UpdatedAt) > maxAge {
+		return Quote{}, false
+	}
+	return quote, true
+}
+
+// RecordTrade records the price of a fill or trade event as the last trade of
+// its market. It reports whether the event carried a price.
+func (c *Cache) RecordTrade(event types.Event) bool {
+	marketID, _ := event.Data["market_id"].(string)
+	price := number(event.Data["price"])
+	if marketID == "" || price <= 0 || price >= 1 {
+		return false
+	}
+
+	if side, _ := event.Data["side"].(string); side == "no" {
+		price = 1 - price
+	}
+	c.recordTrade(event.Platform, marketID, price, event.Timestamp)
+	return true
+}
+
+func (c *Cache) recordTrade(platform, marketID string, price float64, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if prev, ok := c.trades[key(platform, marketID)]; ok && prev.UpdatedAt.After(at) {
+		return
+	}
+	c.trades[key(platform, marketID)] = Trade{Price: price, UpdatedAt: at}
+}
+
+// LastTrade returns the last trade of a market if it is no older than maxAge.
+// A zero maxAge accepts any age.
+func (c *Cache) LastTrade(platform, marketID string, maxAge time.Duration) (Trade, bool) {
+	c.mu.RLock()
+	trade, ok := c.trades[key(platform, marketID)]
+	c.mu.RUnlock()
+
+	if !ok {
+		return Trade{}, false
+	}
+	if maxAge > 0 && time.Since(trade.UpdatedAt) > maxAge {
+		return Trade{}, false
+	}
+	return trade, true
+}
+
+// Quotes returns every cached quote, sorted by platform and market
+func (c *Cache) Quotes() []Quote {
+	c.mu.RLock()
+	quotes := make([]Quote, 0, len(c.quotes))
+	for _, q := range c.quotes {
+		quotes = append(quotes, q)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(quotes, func(i, j int) bool {
+		if quotes[i].Platform != quotes[j].Platform {
+			return quotes[i].Platform < quotes[j].Platform
+		}
+		return quotes[i].MarketID < quotes[j].MarketID
+	})
+	return quotes
+}
+
+func key(platform, marketID string) string {
+	return platform + ":" + marketID
+}
+
+// bestLevel returns the highest bid or lowest ask of a side. Levels are not
+// assumed to be sorted.
+func bestLevel(levels []interface{}, highest bool) (price, size float64) {
+	for _, raw := range levels {
+		var p, s float64
+		switch level := raw.(type) {
+		case []interface{}:
+			if len(level) < 2 {
+				continue
+			}
+			p, s = number(level[0]), number(level[1])
+		case map[string]interface{}:
+			p, s = number(level["price"]), number(level["size"])
+		default:
+			continue
+		}
+		if p <= 0 || s <= 0 {
+			continue
+		}
+		if price == 0 || (highest && p > price) || (!highest && p < price) {
+			price, size = p, s
+		}
+	}
+	return price, size
+}
+
+// number accepts JSON numbers and numeric strings, which some venues use for prices
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,138 @@
+package orders
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Order is an open order the engine knows about
+type Order struct {
+	OrderID      string     `json:"order_id"`
+	Platform     string     `json:"platform"`
+	AccountID    string     `json:"account_id"`
+	MarketID     string     `json:"market_id"`
+	OutcomeID    string     `json:"outcome_id,omitempty"`
+	Side         string     `json:"side"`
+	Price        float64    `json:"price"`
+	Shares       float64    `json:"shares"`
+	FilledShares float64    `json:"filled_shares"`
+	Strategy     string     `json:"strategy,omitempty"`
+	Orphan       bool       `json:"orphan"` // found on the venue but not placed by the engine
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Remaining returns the unfilled size of the order
+func (o Order) Remaining() float64 {
+	return o.Shares - o.FilledShares
+}
+
+// Expired reports whether the order has an expiry at or before now
+func (o Order) Expired(now time.Time) bool {
+	return o.ExpiresAt != nil && !now.Before(*o.ExpiresAt)
+}
+
+// Tracker keeps the set of open orders across platforms and accounts
+type Tracker struct {
+	orders map[string]*Order // by platform:order_id
+	mu     sync.RWMutex
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		orders: make(map[string]*Order),
+	}
+}
+
+func orderKey(platform, orderID string) string {
+	return platform + ":" + orderID
+}
+
+// Add starts tracking an order, replacing any previous entry with the same ID
+func (t *Tracker) Add(order Order) {
+	if order.OrderID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.orders[orderKey(order.Platform, order.OrderID)] = &order
+}
+
+// ApplyFill records a fill and stops tracking the order once fully filled.
+// It returns false if the order is not tracked.
+func (t *Tracker) ApplyFill(platform, orderID string, shares float64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := orderKey(platform, orderID)
+	order, ok := t.orders[key]
+	if !ok {
+		return false
+	}
+
+	order.FilledShares += shares
+	if order.Remaining() <= 1e-9 {
+		delete(t.orders, key)
+	}
+	return true
+}
+
+// Remove stops tracking an order (cancelled, expired or rejected)
+func (t *Tracker) Remove(platform, orderID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.orders, orderKey(platform, orderID))
+}
+
+// Get returns a tracked order
+func (t *Tracker) Get(platform, orderID string) (Order, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	order, ok := t.orders[orderKey(platform, orderID)]
+	if !ok {
+		return Order{}, false
+	}
+	return *order, true
+}
+
+// Open returns all tracked orders, oldest first
+func (t *Tracker) Open() []Order {
+	return t.filter(func(*Order) bool { return true })
+}
+
+// ByAccount returns the tracked orders of one account, oldest first
+func (t *Tracker) ByAccount(platform, accountID string) []Order {
+	return t.filter(func(o *Order) bool {
+		return o.Platform == platform && o.AccountID == accountID
+	})
+}
+
+// ByMarket returns the tracked orders on one market across all accounts, oldest first
+func (t *Tracker) ByMarket(platform, marketID string) []Order {
+	return t.filter(func(o *Order) bool {
+		return o.Platform == platform && o.MarketID == marketID
+	})
+}
+
+func (t *Tracker) filter(match func(*Order) bool) []Order {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var result []Order
+	for _, order := range t.orders {
+		if match(order) {
+			result = append(result, *order)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result
+}
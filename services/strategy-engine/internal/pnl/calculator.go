@@ -0,0 +1,111 @@
+// Package pnl turns events into records of the strategy_pnl ledger: the
+// realized PnL venues report on fills and other account events, and the
+// trading fee of every fill. The engine books them as events arrive;
+// cmd/backfill replays archived events through the same calculator to
+// rebuild the ledger for the time before it existed.
+package pnl
+
+import (
+	"context"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/costs"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// StrategyLookup returns the strategy that placed an order, or "" if unknown
+type StrategyLookup func(ctx context.Context, platform, orderID string) (string, error)
+
+// Calculator computes the PnL records of events
+type Calculator struct {
+	costs    *costs.Model
+	strategy StrategyLookup
+}
+
+// NewCalculator returns a calculator modeling fees with model (nil for no
+// modeled fees) and attributing orders with lookup when events do not name
+// their strategy
+func NewCalculator(model *costs.Model, lookup StrategyLookup) *Calculator {
+	return &Calculator{costs: model, strategy: lookup}
+}
+
+// Records returns the PnL records an event books:
+//
+//   - the event's "realized_pnl", if it carries one
+//   - the trading fee of a fill of a known order: the "fee" the venue
+//     reported, or the modeled one, as a taker fee unless the fill says it
+//     provided liquidity. Slippage is already part of the fill price and is
+//     not booked again.
+//
+// Events of orders no strategy placed book nothing.
+func (c *Calculator) Records(ctx context.Context, event types.Event) ([]types.PnLRecord, error) {
+	orderID := OrderID(event)
+	_, hasPnL := event.Data["realized_pnl"].(float64)
+	hasFee := event.Type == "fill" && orderID != ""
+	if !hasPnL && !hasFee {
+		return nil, nil
+	}
+
+	strategy, _ := event.Data["strategy"].(string)
+	if strategy == "" && orderID != "" && c.strategy != nil {
+		var err error
+		if strategy, err = c.strategy(ctx, event.Platform, orderID); err != nil {
+			return nil, err
+		}
+	}
+	if strategy == "" {
+		return nil, nil
+	}
+
+	marketID, _ := event.Data["market_id"].(string)
+	record := func(kind string, pnl float64) types.PnLRecord {
+		return types.PnLRecord{
+			Strategy:  strategy,
+			Platform:  event.Platform,
+			MarketID:  marketID,
+			OrderID:   orderID,
+			EventID:   event.ID,
+			Kind:      kind,
+			PnL:       pnl,
+			CreatedAt: event.Timestamp,
+		}
+	}
+
+	var records []types.PnLRecord
+	if hasFee {
+		if fee := c.fillFee(event, marketID); fee > 0 {
+			records = append(records, record(types.PnLFee, -fee))
+		}
+	}
+	if pnl, ok := event.Data["realized_pnl"].(float64); ok {
+		records = append(records, record(types.PnLRealized, pnl))
+	}
+	return records, nil
+}
+
+// fillFee returns the fee of a fill, as reported or modeled
+func (c *Calculator) fillFee(event types.Event, marketID string) float64 {
+	if fee, ok := event.Data["fee"].(float64); ok {
+		return fee
+	}
+	price, _ := event.Data["price"].(float64)
+	shares, _ := event.Data["shares"].(float64)
+	return c.costs.Estimate(event.Platform, marketID, price, shares, !MakerFill(event)).Fee
+}
+
+// OrderID returns the venue order ID an event refers to
+func OrderID(event types.Event) string {
+	if orderID, _ := event.Data["order_id"].(string); orderID != "" {
+		return orderID
+	}
+	orderID, _ := event.Data["order_hash"].(string)
+	return orderID
+}
+
+// MakerFill reports whether a fill event says our order was resting
+func MakerFill(event types.Event) bool {
+	if liquidity, _ := event.Data["liquidity"].(string); liquidity != "" {
+		return liquidity == "maker"
+	}
+	maker, _ := event.Data["maker"].(bool)
+	return maker
+}
@@ -0,0 +1,108 @@
+// Package positions keeps the engine's own view of the positions of managed
+// accounts, built from fills, to be checked against what the venues report.
+package positions
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Tracker holds positions per account. An account is tracked from its first
+// Replace, which sets the baseline from the venue; fills on accounts without
+// a baseline are ignored, as the positions they add to are unknown.
+type Tracker struct {
+	mu        sync.RWMutex
+	positions map[string]*types.Position // by platform:account:market:side
+	synced    map[string]time.Time       // platform:account -> last Replace
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		positions: make(map[string]*types.Position),
+		synced:    make(map[string]time.Time),
+	}
+}
+
+func accountKey(platform, accountID string) string {
+	return platform + ":" + accountID
+}
+
+func positionKey(platform, accountID, marketID, side string) string {
+	return platform + ":" + accountID + ":" + marketID + ":" + side
+}
+
+// Replace sets the positions of an account, dropping the ones it had
+func (t *Tracker) Replace(platform, accountID string, positions []types.Position) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, position := range t.positions {
+		if position.Platform == platform && position.AccountID == accountID {
+			delete(t.positions, key)
+		}
+	}
+	for _, position := range positions {
+		position := position
+		position.Platform, position.AccountID = platform, accountID
+		t.positions[positionKey(platform, accountID, position.MarketID, position.Side)] = &position
+	}
+	t.synced[accountKey(platform, accountID)] = time.Now().UTC()
+}
+
+// ApplyFill adds filled shares to a position; sells pass negative shares. It
+// returns false if the account has no baseline yet.
+func (t *Tracker) ApplyFill(platform, accountID, marketID, side string, price, shares float64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.synced[accountKey(platform, accountID)]; !ok {
+		return false
+	}
+
+	key := positionKey(platform, accountID, marketID, side)
+	position, ok := t.positions[key]
+	if !ok {
+		position = &types.Position{Platform: platform, AccountID: accountID, MarketID: marketID, Side: side}
+		t.positions[key] = position
+	}
+
+	// The average price only moves on buys
+	if shares > 0 && position.Shares+shares > 0 {
+		position.AvgPrice = (position.AvgPrice*position.Shares + price*shares) / (position.Shares + shares)
+	}
+	position.Shares += shares
+	position.UpdatedAt = time.Now().UTC()
+
+	if position.Shares <= 1e-9 {
+		delete(t.positions, key)
+	}
+	return true
+}
+
+// Account returns the positions of an account by market and side, and false
+// if it has no baseline
+func (t *Tracker) Account(platform, accountID string) ([]types.Position, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if _, ok := t.synced[accountKey(platform, accountID)]; !ok {
+		return nil, false
+	}
+
+	result := []types.Position{}
+	for _, position := range t.positions {
+		if position.Platform == platform && position.AccountID == accountID {
+			result = append(result, *position)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].MarketID != result[j].MarketID {
+			return result[i].MarketID < result[j].MarketID
+		}
+		return result[i].Side < result[j].Side
+	})
+	return result, true
+}
@@ -0,0 +1,168 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// DailyReports generates and looks up the stored end-of-day reports; the
+// engine implements it
+type DailyReports interface {
+	GenerateDailyReport(ctx context.Context, day time.Time) (*types.DailyReport, error)
+	DailyReport(ctx context.Context, day string) (*types.DailyReport, error)
+}
+
+// DailyReporter writes the report of each UTC day shortly after it ends,
+// publishes it as a daily_report event and sends its summary to the
+// notifiers. A day missed while the engine was down is caught up on start.
+type DailyReporter struct {
+	reports   DailyReports
+	eventBus  eventbus.EventBus
+	at        time.Duration // past midnight UTC
+	notifiers []Notifier
+}
+
+func NewDailyReporter(reports DailyReports, eventBus eventbus.EventBus, at time.Duration, notifiers ...Notifier) *DailyReporter {
+	return &DailyReporter{
+		reports:   reports,
+		eventBus:  eventBus,
+		at:        at,
+		notifiers: notifiers,
+	}
+}
+
+// Run reports every day at the configured time until ctx is cancelled
+func (r *DailyReporter) Run(ctx context.Context) {
+	now := time.Now().UTC()
+	midnight := now.Truncate(24 * time.Hour)
+	if now.Sub(midnight) >= r.at {
+		yesterday := midnight.AddDate(0, 0, -1)
+		if existing, err := r.reports.DailyReport(ctx, yesterday.Format(time.DateOnly)); err != nil {
+			log.Error().Err(err).Msg("Failed to look up yesterday's daily report")
+		} else if existing == nil {
+			r.report(ctx, yesterday)
+		}
+	}
+
+	for {
+		next := midnight.Add(r.at)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now = time.Now().UTC()
+		midnight = now.Truncate(24 * time.Hour)
+		r.report(ctx, midnight.AddDate(0, 0, -1))
+	}
+}
+
+// report generates, publishes and sends the report of one day
+func (r *DailyReporter) report(ctx context.Context, day time.Time) {
+	report, err := r.reports.GenerateDailyReport(ctx, day)
+	if err != nil {
+		log.Error().Err(err).Str("day", day.Format(time.DateOnly)).Msg("Failed to generate daily report")
+		return
+	}
+
+	log.Info().
+		Str("day", report.Day).
+		Int("orders", report.Orders).
+		Int("filled", report.Filled).
+		Float64("volume", report.Volume).
+		Float64("realized_pnl", report.RealizedPnL).
+		Msg("Daily report")
+
+	event := types.Event{
+		ID:        "daily_report:" + report.Day,
+		Type:      "daily_report",
+		Timestamp: report.GeneratedAt,
+		Data: map[string]interface{}{
+			"day":          report.Day,
+			"orders":       report.Orders,
+			"accepted":     report.Accepted,
+			"rejected":     report.Rejected,
+			"failed":       report.Failed,
+			"filled":       report.Filled,
+			"volume":       report.Volume,
+			"fees":         report.Fees,
+			"realized_pnl": report.RealizedPnL,
+			"risk_events":  report.RiskEvents,
+			"alerts":       report.Alerts,
+			"strategies":   len(report.Strategies),
+		},
+	}
+	if err := r.eventBus.Publish(ctx, ReportStream, event); err != nil {
+		log.Error().Err(err).Msg("Failed to publish daily report")
+	}
+
+	subject := "Trading summary " + report.Day
+	body := Summary(report)
+	for _, n := range r.notifiers {
+		if err := n.Notify(ctx, subject, body); err != nil {
+			log.Error().Err(err).Str("day", report.Day).Msg("Failed to send daily report")
+		}
+	}
+}
+
+// Summary renders a report as plain text: the day's totals, then one line per
+// strategy
+func Summary(report *types.DailyReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Orders %d (accepted %d, rejected %d, failed %d), filled %d\n",
+		report.Orders, report.Accepted, report.Rejected, report.Failed, report.Filled)
+	fmt.Fprintf(&b, "Volume %.2f, fees %.2f, realized PnL %+.2f\n", report.Volume, report.Fees, report.RealizedPnL)
+	if len(report.RiskEvents) > 0 {
+		fmt.Fprintf(&b, "Risk events: %s\n", counts(report.RiskEvents))
+	}
+	if len(report.Alerts) > 0 {
+		fmt.Fprintf(&b, "Alerts: %s\n", counts(report.Alerts))
+	}
+	if len(report.Strategies) == 0 {
+		b.WriteString("No strategy activity\n")
+		return b.String()
+	}
+
+	b.WriteString("\n")
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tORDERS\tREJECTED\tFILLED\tVOLUME\tFEES\tPNL\tRISK EVENTS")
+	for _, s := range report.Strategies {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f\t%.2f\t%+.2f\t%s\n",
+			s.Strategy, s.Orders, s.Rejected, s.Filled, s.Volume, s.Fees, s.RealizedPnL, counts(s.RiskEvents))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// counts renders "a=1, b=2" sorted by key, or "-" when empty
+func counts(m map[string]int) string {
+	if len(m) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, m[k]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,104 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/eventbus"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// ReportStream is where the engine publishes periodic reports
+const ReportStream = "report_events"
+
+// ExecutionQualityReporter periodically compares execution quality across
+// platforms (fill rate, reject rate, latency, effective spread) from the order
+// journal and publishes the result.
+type ExecutionQualityReporter struct {
+	storage  *storage.PostgresStorage
+	eventBus eventbus.EventBus
+	interval time.Duration
+	window   time.Duration
+}
+
+func NewExecutionQualityReporter(
+	storage *storage.PostgresStorage,
+	eventBus eventbus.EventBus,
+	interval time.Duration,
+	window time.Duration,
+) *ExecutionQualityReporter {
+	return &ExecutionQualityReporter{
+		storage:  storage,
+		eventBus: eventBus,
+		interval: interval,
+		window:   window,
+	}
+}
+
+// Run generates a report every interval until ctx is cancelled
+func (r *ExecutionQualityReporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Generate(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to generate execution quality report")
+			}
+		}
+	}
+}
+
+// Generate builds a report over the configured window and publishes one
+// execution_quality event per platform.
+func (r *ExecutionQualityReporter) Generate(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	report, err := r.storage.GetExecutionQuality(ctx, now.Add(-r.window))
+	if err != nil {
+		return fmt.Errorf("failed to query order journal: %w", err)
+	}
+
+	for _, q := range report {
+		log.Info().
+			Str("platform", q.Platform).
+			Int("orders", q.Orders).
+			Float64("fill_rate", q.FillRate).
+			Float64("reject_rate", q.RejectRate).
+			Float64("avg_latency_ms", q.AvgLatencyMs).
+			Float64("p95_latency_ms", q.P95LatencyMs).
+			Float64("effective_spread", q.EffectiveSpread).
+			Msg("Execution quality")
+
+		event := types.Event{
+			ID:        fmt.Sprintf("execution_quality:%s:%d", q.Platform, now.Unix()),
+			Type:      "execution_quality",
+			Platform:  q.Platform,
+			Timestamp: now,
+			Data: map[string]interface{}{
+				"window_seconds":   r.window.Seconds(),
+				"orders":           q.Orders,
+				"accepted":         q.Accepted,
+				"rejected":         q.Rejected,
+				"failed":           q.Failed,
+				"filled":           q.Filled,
+				"fill_rate":        q.FillRate,
+				"reject_rate":      q.RejectRate,
+				"avg_latency_ms":   q.AvgLatencyMs,
+				"p95_latency_ms":   q.P95LatencyMs,
+				"effective_spread": q.EffectiveSpread,
+			},
+		}
+		if err := r.eventBus.Publish(ctx, ReportStream, event); err != nil {
+			log.Error().Err(err).Str("platform", q.Platform).Msg("Failed to publish execution quality report")
+		}
+	}
+
+	return nil
+}
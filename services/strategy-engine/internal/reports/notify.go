@@ -0,0 +1,99 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Notifier delivers a report summary to people
+type Notifier interface {
+	Notify(ctx context.Context, subject, body string) error
+}
+
+// SlackNotifier posts to a Slack incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify posts the subject in bold and the body as a code block, keeping the
+// columns of the summary aligned
+func (n *SlackNotifier) Notify(ctx context.Context, subject, body string) error {
+	payload, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n```\n%s\n```", subject, body),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// EmailNotifier mails through an SMTP server, authenticating with PLAIN
+// when a username is set. The server must offer STARTTLS for that.
+type EmailNotifier struct {
+	addr string
+	from string
+	to   []string
+	auth smtp.Auth
+}
+
+func NewEmailNotifier(addr, username, password, from string, to []string) *EmailNotifier {
+	n := &EmailNotifier{addr: addr, from: from, to: to}
+	if username != "" {
+		host := addr
+		if i := strings.LastIndex(addr, ":"); i >= 0 {
+			host = addr[:i]
+		}
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n
+}
+
+// Notify sends a plain-text mail. net/smtp takes no context, so ctx only
+// stops a send that has not started.
+func (n *EmailNotifier) Notify(ctx context.Context, subject, body string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, n.to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
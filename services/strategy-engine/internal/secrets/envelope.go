@@ -0,0 +1,232 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Strategy configs may hold encrypted values, so API keys and webhook URLs
+// are not stored in the clear in the strategies table. Encryption uses an
+// envelope: each value is sealed with a fresh data key, and the data key is
+// sealed with a master key, both with AES-256-GCM. An encrypted value is a
+// string
+//
+//	enc:v1:<key ID>:<sealed data key>:<sealed value>
+//
+// with both parts base64url-encoded, nonce first. The key ID names the master
+// key, so keys can be rotated by adding a new one and re-encrypting; the old
+// one must stay in the Keyring until no config uses it.
+
+const encPrefix = "enc:v1:"
+
+// masterKeyLength is the size of master and data keys (AES-256)
+const masterKeyLength = 32
+
+// ErrNoKeyring is returned when an encrypted value is found but no master
+// keys are configured
+var ErrNoKeyring = errors.New("no config encryption keys configured")
+
+// IsEncrypted reports whether value is an encrypted config value
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix)
+}
+
+// Keyring holds the master keys of encrypted config values, by key ID. New
+// values are encrypted with the active key.
+type Keyring struct {
+	keys   map[string][]byte
+	active string
+}
+
+// NewKeyring builds a keyring from base64-encoded 32-byte master keys
+func NewKeyring(keys map[string]string, active string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte, len(keys)), active: active}
+	for id, encoded := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: not base64: %w", id, err)
+		}
+		if len(key) != masterKeyLength {
+			return nil, fmt.Errorf("key %s: must be %d bytes, got %d", id, masterKeyLength, len(key))
+		}
+		k.keys[id] = key
+	}
+	if _, ok := k.keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q is not configured", active)
+	}
+	return k, nil
+}
+
+// KeyIDs returns the IDs of the configured master keys
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt seals a value with a new data key under the active master key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil {
+		return "", ErrNoKeyring
+	}
+
+	dataKey := make([]byte, masterKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	sealedKey, err := seal(k.keys[k.active], dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealedValue, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return encPrefix + k.active + ":" +
+		base64.RawURLEncoding.EncodeToString(sealedKey) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealedValue), nil
+}
+
+// Decrypt opens an encrypted value and registers the plaintext for redaction
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("not an encrypted value")
+	}
+	if k == nil {
+		return "", ErrNoKeyring
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, encPrefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed encrypted value")
+	}
+	masterKey, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("unknown key %q", parts[0])
+	}
+	sealedKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+	sealedValue, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	dataKey, err := open(masterKey, sealedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to open data key with key %q: %w", parts[0], err)
+	}
+	plaintext, err := open(dataKey, sealedValue)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	Register(string(plaintext))
+	return string(plaintext), nil
+}
+
+// DecryptConfig returns a copy of a strategy config with every encrypted
+// string, at any depth, replaced by its plaintext. Configs without encrypted
+// values come back unchanged, even without a keyring.
+func (k *Keyring) DecryptConfig(config map[string]interface{}) (map[string]interface{}, error) {
+	decrypted, err := k.decryptValue(config, "")
+	if err != nil {
+		return nil, err
+	}
+	result, _ := decrypted.(map[string]interface{})
+	return result, nil
+}
+
+func (k *Keyring) decryptValue(value interface{}, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !IsEncrypted(v) {
+			return v, nil
+		}
+		plaintext, err := k.Decrypt(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return plaintext, nil
+	case map[string]interface{}:
+		if v == nil {
+			return v, nil
+		}
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			decrypted, err := k.decryptValue(item, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			result[key] = decrypted
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			decrypted, err := k.decryptValue(item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = decrypted
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// seal encrypts with AES-GCM and prepends the nonce
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open reverses seal
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"sync"
+)
+
+// Redacted replaces secret values in log output
+const Redacted = "[REDACTED]"
+
+// minSecretLength keeps short values (e.g. "1") from mangling unrelated output
+const minSecretLength = 4
+
+var registry = struct {
+	sync.RWMutex
+	values [][]byte
+	seen   map[string]bool
+}{seen: make(map[string]bool)}
+
+// Register marks values as secret so redacting writers mask them. URL-escaped
+// forms are registered too, since passwords end up inside connection URLs.
+func Register(values ...string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, value := range values {
+		for _, form := range []string{value, url.QueryEscape(value), url.PathEscape(value)} {
+			if len(form) < minSecretLength || registry.seen[form] {
+				continue
+			}
+			registry.seen[form] = true
+			registry.values = append(registry.values, []byte(form))
+		}
+	}
+}
+
+// Redact returns p with every registered secret replaced
+func Redact(p []byte) []byte {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	for _, secret := range registry.values {
+		if bytes.Contains(p, secret) {
+			p = bytes.ReplaceAll(p, secret, []byte(Redacted))
+		}
+	}
+	return p
+}
+
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter masks registered secrets in everything written to w
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return redactingWriter{w: w}
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := r.w.Write(Redact(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
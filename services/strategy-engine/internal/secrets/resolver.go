@@ -0,0 +1,165 @@
+// Package secrets resolves credentials from mounted files or Vault, encrypts
+// secret values of strategy configs and keeps them out of log output.
+//
+// A config value is a secret reference when it starts with one of:
+//
+//	file:/run/secrets/postgres_password
+//	vault:secret/data/strategy-engine#postgres_password   (KV v2 path#key)
+//
+// Any other value is used literally. Vault is reached through VAULT_ADDR with
+// VAULT_TOKEN (or VAULT_TOKEN_FILE, re-read on every request so an agent can
+// rotate it).
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	filePrefix  = "file:"
+	vaultPrefix = "vault:"
+
+	// cacheTTL bounds how long a resolved Vault secret is reused, so rotated
+	// values are picked up without hammering Vault on every connection
+	cacheTTL = time.Minute
+)
+
+// IsRef reports whether value refers to a secret rather than being one
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, filePrefix) || strings.HasPrefix(value, vaultPrefix)
+}
+
+type cachedSecret struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// Resolver resolves secret references. Every resolved value is registered for
+// redaction.
+type Resolver struct {
+	vaultAddr      string
+	vaultToken     string
+	vaultTokenFile string
+	httpClient     *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedSecret
+}
+
+// NewResolverFromEnv configures Vault access from VAULT_ADDR, VAULT_TOKEN and
+// VAULT_TOKEN_FILE. File references work without any configuration.
+func NewResolverFromEnv() *Resolver {
+	r := &Resolver{
+		vaultAddr:      strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		vaultToken:     os.Getenv("VAULT_TOKEN"),
+		vaultTokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		cache:          make(map[string]cachedSecret),
+	}
+	Register(r.vaultToken)
+	return r
+}
+
+// Resolve returns the secret a reference points to, or value itself if it is
+// not a reference. File references are read on every call.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	var secret string
+	var err error
+
+	switch {
+	case strings.HasPrefix(value, filePrefix):
+		secret, err = readFile(strings.TrimPrefix(value, filePrefix))
+	case strings.HasPrefix(value, vaultPrefix):
+		secret, err = r.vault(ctx, strings.TrimPrefix(value, vaultPrefix))
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	Register(secret)
+	return secret, nil
+}
+
+func readFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (r *Resolver) vault(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("vault reference %q must be path#key", ref)
+	}
+
+	r.mu.Lock()
+	cached, hit := r.cache[ref]
+	r.mu.Unlock()
+	if hit && time.Since(cached.fetchedAt) < cacheTTL {
+		return cached.value, nil
+	}
+
+	if r.vaultAddr == "" {
+		return "", fmt.Errorf("vault reference %q but VAULT_ADDR is not set", ref)
+	}
+
+	token := r.vaultToken
+	if r.vaultTokenFile != "" {
+		t, err := readFile(r.vaultTokenFile)
+		if err != nil {
+			return "", err
+		}
+		token = t
+		Register(token)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", r.vaultAddr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d for %s", resp.StatusCode, path)
+	}
+
+	// KV v2 nests the secret under data.data; KV v1 under data
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+
+	r.mu.Lock()
+	r.cache[ref] = cachedSecret{value: value, fetchedAt: time.Now()}
+	r.mu.Unlock()
+
+	return value, nil
+}
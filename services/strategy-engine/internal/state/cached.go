@@ -0,0 +1,94 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/writebehind"
+)
+
+// Saver persists the whole state of a strategy: keys missing from entries are
+// deleted. It is what Cached flushes to.
+type Saver interface {
+	ReplaceState(ctx context.Context, strategyID string, entries map[string]Entry) error
+}
+
+// Cached is a Backend that keeps the state of every strategy it has seen in
+// memory and writes it behind to the store, so handlers reading and writing
+// state do not wait on the database. See package writebehind for what that
+// guarantees; in particular a strategy's state must be written by one engine
+// only, and versions are checked against memory, not the store.
+type Cached struct {
+	cache *writebehind.Cache[string, map[string]Entry]
+}
+
+// NewCached loads from backend and flushes to saver every interval
+func NewCached(backend Backend, saver Saver, interval time.Duration) *Cached {
+	return &Cached{cache: writebehind.New(writebehind.Options[string, map[string]Entry]{
+		Name: "strategy_state",
+		Load: func(ctx context.Context, strategyID string) (map[string]Entry, bool, error) {
+			entries, err := backend.ListState(ctx, strategyID)
+			return entries, true, err
+		},
+		Save:          saver.ReplaceState,
+		FlushInterval: interval,
+	})}
+}
+
+// Run flushes in the background until ctx is done
+func (c *Cached) Run(ctx context.Context) {
+	c.cache.Run(ctx)
+}
+
+// Flush writes every pending change to the store
+func (c *Cached) Flush(ctx context.Context) error {
+	return c.cache.Flush(ctx)
+}
+
+func (c *Cached) GetState(ctx context.Context, strategyID, key string) (Entry, error) {
+	entries, _, err := c.cache.Get(ctx, strategyID)
+	return entries[key], err
+}
+
+func (c *Cached) ListState(ctx context.Context, strategyID string) (map[string]Entry, error) {
+	entries, _, err := c.cache.Get(ctx, strategyID)
+	return cloneEntries(entries), err
+}
+
+func (c *Cached) PutState(ctx context.Context, strategyID, key string, value json.RawMessage, version int64) (int64, error) {
+	entries, err := c.cache.Update(ctx, strategyID, func(entries map[string]Entry, _ bool) (map[string]Entry, error) {
+		if entries[key].Version != version {
+			return nil, ErrConflict
+		}
+		entries = cloneEntries(entries)
+		entries[key] = Entry{Value: value, Version: version + 1, UpdatedAt: time.Now().UTC()}
+		return entries, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return entries[key].Version, nil
+}
+
+func (c *Cached) DeleteState(ctx context.Context, strategyID, key string, version int64) error {
+	_, err := c.cache.Update(ctx, strategyID, func(entries map[string]Entry, _ bool) (map[string]Entry, error) {
+		if version == 0 || entries[key].Version != version {
+			return nil, ErrConflict
+		}
+		entries = cloneEntries(entries)
+		delete(entries, key)
+		return entries, nil
+	})
+	return err
+}
+
+// cloneEntries copies a strategy's entries; cached maps are never modified
+// in place, as a flush may be reading them
+func cloneEntries(entries map[string]Entry) map[string]Entry {
+	clone := make(map[string]Entry, len(entries)+1)
+	for key, entry := range entries {
+		clone[key] = entry
+	}
+	return clone
+}
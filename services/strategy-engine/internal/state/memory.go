@@ -0,0 +1,65 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process Backend, for replays and tests. Nothing survives
+// the process.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]map[string]Entry // strategy ID -> key -> entry
+}
+
+// NewMemory returns an empty in-memory backend
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]map[string]Entry)}
+}
+
+func (m *Memory) GetState(ctx context.Context, strategyID, key string) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.entries[strategyID][key], nil
+}
+
+func (m *Memory) ListState(ctx context.Context, strategyID string) (map[string]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := make(map[string]Entry, len(m.entries[strategyID]))
+	for key, entry := range m.entries[strategyID] {
+		entries[key] = entry
+	}
+	return entries, nil
+}
+
+func (m *Memory) PutState(ctx context.Context, strategyID, key string, value json.RawMessage, version int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entries[strategyID][key].Version != version {
+		return 0, ErrConflict
+	}
+	if m.entries[strategyID] == nil {
+		m.entries[strategyID] = make(map[string]Entry)
+	}
+
+	entry := Entry{Value: value, Version: version + 1, UpdatedAt: time.Now().UTC()}
+	m.entries[strategyID][key] = entry
+	return entry.Version, nil
+}
+
+func (m *Memory) DeleteState(ctx context.Context, strategyID, key string, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if version == 0 || m.entries[strategyID][key].Version != version {
+		return ErrConflict
+	}
+	delete(m.entries[strategyID], key)
+	return nil
+}
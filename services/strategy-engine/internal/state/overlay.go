@@ -0,0 +1,72 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Overlay is a Backend that reads a strategy's state from a base backend the
+// first time the strategy touches it and keeps every later read and write in
+// memory, so an engine can run against the production state without changing
+// it. Nothing is written to the base.
+type Overlay struct {
+	base   Backend
+	memory *Memory
+
+	mu     sync.Mutex
+	loaded map[string]bool // strategy IDs copied from base
+}
+
+// NewOverlay returns an overlay over base
+func NewOverlay(base Backend) *Overlay {
+	return &Overlay{base: base, memory: NewMemory(), loaded: make(map[string]bool)}
+}
+
+// load copies a strategy's entries from the base on first use
+func (o *Overlay) load(ctx context.Context, strategyID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.loaded[strategyID] {
+		return nil
+	}
+	entries, err := o.base.ListState(ctx, strategyID)
+	if err != nil {
+		return err
+	}
+
+	o.memory.mu.Lock()
+	o.memory.entries[strategyID] = entries
+	o.memory.mu.Unlock()
+	o.loaded[strategyID] = true
+	return nil
+}
+
+func (o *Overlay) GetState(ctx context.Context, strategyID, key string) (Entry, error) {
+	if err := o.load(ctx, strategyID); err != nil {
+		return Entry{}, err
+	}
+	return o.memory.GetState(ctx, strategyID, key)
+}
+
+func (o *Overlay) ListState(ctx context.Context, strategyID string) (map[string]Entry, error) {
+	if err := o.load(ctx, strategyID); err != nil {
+		return nil, err
+	}
+	return o.memory.ListState(ctx, strategyID)
+}
+
+func (o *Overlay) PutState(ctx context.Context, strategyID, key string, value json.RawMessage, version int64) (int64, error) {
+	if err := o.load(ctx, strategyID); err != nil {
+		return 0, err
+	}
+	return o.memory.PutState(ctx, strategyID, key, value, version)
+}
+
+func (o *Overlay) DeleteState(ctx context.Context, strategyID, key string, version int64) error {
+	if err := o.load(ctx, strategyID); err != nil {
+		return err
+	}
+	return o.memory.DeleteState(ctx, strategyID, key, version)
+}
@@ -0,0 +1,168 @@
+// Package state gives strategies a durable key-value store, scoped per
+// strategy, that survives engine restarts. Values are JSON; every key carries
+// a version, and writes name the version they replace, so two writers cannot
+// silently overwrite each other (optimistic concurrency).
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrConflict is returned when a write names a version that is no longer
+// current: the key changed (or was created or deleted) since it was read
+var ErrConflict = errors.New("state version conflict")
+
+// defaultTimeout bounds each store operation made from a handler
+const defaultTimeout = 2 * time.Second
+
+// maxUpdateAttempts bounds the retries of Update on conflicts
+const maxUpdateAttempts = 5
+
+// Entry is a stored value with its version. Version 0 means the key does not
+// exist.
+type Entry struct {
+	Value     json.RawMessage `json:"value"`
+	Version   int64           `json:"version"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Backend persists entries. Put with version 0 creates the key and fails with
+// ErrConflict if it exists; otherwise it replaces the entry only if its
+// version matches. Put and Delete return ErrConflict on a version mismatch.
+type Backend interface {
+	GetState(ctx context.Context, strategyID, key string) (Entry, error)
+	ListState(ctx context.Context, strategyID string) (map[string]Entry, error)
+	PutState(ctx context.Context, strategyID, key string, value json.RawMessage, version int64) (int64, error)
+	DeleteState(ctx context.Context, strategyID, key string, version int64) error
+}
+
+// Store hands out per-strategy views of a backend. A nil Store keeps nothing:
+// reads find no keys and writes fail.
+type Store struct {
+	backend Backend
+	timeout time.Duration
+}
+
+// NewStore returns a store over backend
+func NewStore(backend Backend) *Store {
+	return &Store{backend: backend, timeout: defaultTimeout}
+}
+
+// For returns the state of one strategy, by strategy ID
+func (s *Store) For(strategyID string) *Scope {
+	return &Scope{store: s, strategyID: strategyID}
+}
+
+// Scope is the state of one strategy. Its methods are bounded by the store's
+// timeout, so handlers can call them without a context.
+type Scope struct {
+	store      *Store
+	strategyID string
+}
+
+var errNoStore = errors.New("no state store")
+
+func (s *Scope) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.store.timeout)
+}
+
+// Get decodes the value of key into v and returns its version, or 0 without
+// touching v if the key does not exist
+func (s *Scope) Get(key string, v interface{}) (int64, error) {
+	if s.store == nil {
+		return 0, nil
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+
+	entry, err := s.store.backend.GetState(ctx, s.strategyID, key)
+	if err != nil {
+		return 0, fmt.Errorf("state %s: %w", key, err)
+	}
+	if entry.Version == 0 {
+		return 0, nil
+	}
+	if err := json.Unmarshal(entry.Value, v); err != nil {
+		return 0, fmt.Errorf("state %s: %w", key, err)
+	}
+	return entry.Version, nil
+}
+
+// Keys returns every entry of the strategy, by key
+func (s *Scope) Keys() (map[string]Entry, error) {
+	if s.store == nil {
+		return map[string]Entry{}, nil
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+
+	return s.store.backend.ListState(ctx, s.strategyID)
+}
+
+// Put stores v under key if the key is still at version (0: does not exist
+// yet) and returns the new version. It returns ErrConflict otherwise.
+func (s *Scope) Put(key string, v interface{}, version int64) (int64, error) {
+	if s.store == nil {
+		return 0, errNoStore
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("state %s: %w", key, err)
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+
+	next, err := s.store.backend.PutState(ctx, s.strategyID, key, value, version)
+	if err != nil {
+		return 0, fmt.Errorf("state %s: %w", key, err)
+	}
+	return next, nil
+}
+
+// Delete removes key if it is still at version. It returns ErrConflict
+// otherwise.
+func (s *Scope) Delete(key string, version int64) error {
+	if s.store == nil {
+		return errNoStore
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+
+	if err := s.store.backend.DeleteState(ctx, s.strategyID, key, version); err != nil {
+		return fmt.Errorf("state %s: %w", key, err)
+	}
+	return nil
+}
+
+// Update reads key into v, applies fn and writes v back, retrying from a
+// fresh read when another writer got in between. v must be a pointer; it is
+// reset to the zero value before every read, so fn sees the zero value if the
+// key does not exist.
+func (s *Scope) Update(key string, v interface{}, fn func() error) (int64, error) {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return 0, fmt.Errorf("state %s: Update needs a non-nil pointer", key)
+	}
+
+	for attempt := 1; ; attempt++ {
+		target.Elem().SetZero()
+		version, err := s.Get(key, v)
+		if err != nil {
+			return 0, err
+		}
+		if err := fn(); err != nil {
+			return 0, err
+		}
+
+		next, err := s.Put(key, v, version)
+		if !errors.Is(err, ErrConflict) || attempt == maxUpdateAttempts {
+			return next, err
+		}
+	}
+}
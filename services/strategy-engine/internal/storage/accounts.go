@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// ErrAccountNotFound is returned when no trading account has the given
+// platform and ID
+var ErrAccountNotFound = errors.New("trading account not found")
+
+const tradingAccountColumns = `
+	platform, account_id, COALESCE(name, ''), enabled, COALESCE(max_order_shares, 0),
+	allowed_markets, COALESCE(note, ''), COALESCE(updated_by, ''), created_at, updated_at
+`
+
+// GetTradingAccounts returns every trading account, by platform and ID
+func (s *PostgresStorage) GetTradingAccounts(ctx context.Context) ([]types.TradingAccount, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+tradingAccountColumns+` FROM trading_accounts ORDER BY platform, account_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []types.TradingAccount
+	for rows.Next() {
+		a, err := scanTradingAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// PutTradingAccount creates a trading account or replaces its settings
+func (s *PostgresStorage) PutTradingAccount(ctx context.Context, a types.TradingAccount) (types.TradingAccount, error) {
+	markets := a.AllowedMarkets
+	if markets == nil {
+		markets = []string{}
+	}
+
+	query := `
+		INSERT INTO trading_accounts (platform, account_id, name, enabled, max_order_shares, allowed_markets, note, updated_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, 0), $6, NULLIF($7, ''), NULLIF($8, ''))
+		ON CONFLICT (platform, account_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			enabled = EXCLUDED.enabled,
+			max_order_shares = EXCLUDED.max_order_shares,
+			allowed_markets = EXCLUDED.allowed_markets,
+			note = EXCLUDED.note,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING ` + tradingAccountColumns
+
+	return scanTradingAccount(s.pool.QueryRow(ctx, query,
+		a.Platform, a.AccountID, a.Name, a.Enabled, a.MaxOrderShares, markets, a.Note, a.UpdatedBy))
+}
+
+// DeleteTradingAccount removes a trading account
+func (s *PostgresStorage) DeleteTradingAccount(ctx context.Context, platform, accountID string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM trading_accounts WHERE platform = $1 AND account_id = $2`, platform, accountID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s/%s", ErrAccountNotFound, platform, accountID)
+	}
+	return nil
+}
+
+func scanTradingAccount(row pgx.Row) (types.TradingAccount, error) {
+	var a types.TradingAccount
+	err := row.Scan(
+		&a.Platform,
+		&a.AccountID,
+		&a.Name,
+		&a.Enabled,
+		&a.MaxOrderShares,
+		&a.AllowedMarkets,
+		&a.Note,
+		&a.UpdatedBy,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	return a, err
+}
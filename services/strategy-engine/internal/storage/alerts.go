@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// CreateAlert inserts an alert for the UI and Telegram bot
+func (s *PostgresStorage) CreateAlert(ctx context.Context, alertType, title, message string, data map[string]interface{}) error {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO alerts (type, title, message, data)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err = s.pool.Exec(ctx, query, alertType, title, message, dataJSON)
+	return err
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// ArchiveCursor is how far a stream has been archived and compacted
+type ArchiveCursor struct {
+	LastID         string
+	CompactedUntil int64 // stream ms
+}
+
+// GetArchiveCursor returns the archive progress of a stream; a zero cursor
+// when the stream was never archived
+func (s *PostgresStorage) GetArchiveCursor(ctx context.Context, stream string) (ArchiveCursor, error) {
+	var cursor ArchiveCursor
+	err := s.pool.QueryRow(ctx, `
+		SELECT last_id, compacted_until FROM event_archive_cursors WHERE stream = $1
+	`, stream).Scan(&cursor.LastID, &cursor.CompactedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ArchiveCursor{}, nil
+	}
+	return cursor, err
+}
+
+// ArchiveEvents stores events read from a stream and moves the stream's cursor
+// to the last of them, in one transaction. Event IDs must be stream entry IDs;
+// entries that are already archived are skipped.
+func (s *PostgresStorage) ArchiveEvents(ctx context.Context, stream string, events []types.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO event_archive (stream, stream_ms, stream_seq, event_type, platform, market_id, data, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+		ON CONFLICT (stream, stream_ms, stream_seq) DO NOTHING
+	`
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, event := range events {
+		var ms, seq int64
+		if _, err := fmt.Sscanf(event.ID, "%d-%d", &ms, &seq); err != nil {
+			return fmt.Errorf("event %q has no stream ID: %w", event.ID, err)
+		}
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event data: %w", err)
+		}
+		marketID, _ := event.Data["market_id"].(string)
+
+		if _, err := tx.Exec(ctx, query, stream, ms, seq, event.Type, event.Platform, marketID, data, event.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO event_archive_cursors (stream, last_id, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (stream) DO UPDATE SET last_id = EXCLUDED.last_id, updated_at = NOW()
+	`, stream, events[len(events)-1].ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteArchivedEvents removes the archived entries of a stream published before the given time
+func (s *PostgresStorage) DeleteArchivedEvents(ctx context.Context, stream string, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM event_archive WHERE stream = $1 AND stream_ms < $2
+	`, stream, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CompactArchivedEvents thins out the entries of a stream between the
+// compaction cursor and until: of the events of one type, platform and market
+// within each bucket only the last is kept. The cursor moves to until.
+func (s *PostgresStorage) CompactArchivedEvents(ctx context.Context, stream string, until time.Time, bucket time.Duration) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var from int64
+	err = tx.QueryRow(ctx, `
+		SELECT compacted_until FROM event_archive_cursors WHERE stream = $1 FOR UPDATE
+	`, stream).Scan(&from)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Buckets are aligned to bucket boundaries, so a bucket cut by the
+	// previous run's cursor is never thinned twice from different ends
+	end := until.UnixMilli()
+	size := bucket.Milliseconds()
+	end -= end % size
+	if end <= from {
+		return 0, nil
+	}
+
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM event_archive a
+		USING (
+			SELECT stream_ms, stream_seq,
+				ROW_NUMBER() OVER (
+					PARTITION BY event_type, platform, market_id, stream_ms / $4
+					ORDER BY stream_ms DESC, stream_seq DESC
+				) AS rank
+			FROM event_archive
+			WHERE stream = $1 AND stream_ms >= $2 AND stream_ms < $3
+		) d
+		WHERE a.stream = $1 AND a.stream_ms = d.stream_ms AND a.stream_seq = d.stream_seq AND d.rank > 1
+	`, stream, from, end, size)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE event_archive_cursors SET compacted_until = $2, updated_at = NOW() WHERE stream = $1
+	`, stream, end); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetArchivedEvents returns up to limit archived events of a stream with entry
+// IDs between start and end (ms, seq), inclusive, in stream order
+func (s *PostgresStorage) GetArchivedEvents(ctx context.Context, stream string, start, end [2]int64, limit int64) ([]types.Event, error) {
+	query := `
+		SELECT stream_ms, stream_seq, event_type, COALESCE(platform, ''), data, created_at
+		FROM event_archive
+		WHERE stream = $1 AND (stream_ms, stream_seq) >= ($2, $3) AND (stream_ms, stream_seq) <= ($4, $5)
+		ORDER BY stream_ms, stream_seq
+		LIMIT $6
+	`
+
+	rows, err := s.pool.Query(ctx, query, stream, start[0], start[1], end[0], end[1], limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []types.Event
+	for rows.Next() {
+		var ms, seq int64
+		var data []byte
+		var event types.Event
+		if err := rows.Scan(&ms, &seq, &event.Type, &event.Platform, &data, &event.Timestamp); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &event.Data); err != nil {
+			return nil, fmt.Errorf("failed to parse archived event data: %w", err)
+		}
+		event.ID = fmt.Sprintf("%d-%d", ms, seq)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
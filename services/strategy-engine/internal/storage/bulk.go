@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// StrategyWrite is one strategy created or updated by ImportStrategies
+type StrategyWrite struct {
+	ID       string // empty creates the strategy
+	Name     string
+	Type     string
+	Enabled  bool
+	DryRun   bool
+	Priority int
+	Tenant   string
+	Config   map[string]interface{}
+
+	// ConfigChanged stores Config as a new live version, keeping the config it
+	// replaces as a retired one
+	ConfigChanged bool
+}
+
+// ImportStrategies creates and updates strategies in one transaction. New
+// strategies and new configs are recorded as live versions created by
+// createdBy with the given note.
+func (s *PostgresStorage) ImportStrategies(ctx context.Context, writes []StrategyWrite, createdBy, note string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, w := range writes {
+		config, err := json.Marshal(w.Config)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config of %s: %w", w.Name, err)
+		}
+
+		if w.ID == "" {
+			if _, err := tx.Exec(ctx, `
+				WITH created AS (
+					INSERT INTO strategies (name, type, config, enabled, dry_run, priority, tenant)
+					VALUES ($1, $2, $3, $4, $5, $6, $7)
+					RETURNING id, version, config
+				)
+				INSERT INTO strategy_versions (strategy_id, version, config, status, note, created_by, decided_by, decided_at)
+				SELECT id, version, config, 'live', NULLIF($8, ''), NULLIF($9, ''), NULLIF($9, ''), NOW()
+				FROM created
+			`, w.Name, w.Type, config, w.Enabled, w.DryRun, w.Priority, w.Tenant, note, createdBy); err != nil {
+				return fmt.Errorf("failed to create %s: %w", w.Name, err)
+			}
+			continue
+		}
+
+		if w.ConfigChanged {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO strategy_versions (strategy_id, version, config, status, decided_by, decided_at)
+				SELECT id, version, config, 'retired', NULLIF($2, ''), NOW()
+				FROM strategies WHERE id = $1::uuid
+				ON CONFLICT (strategy_id, version)
+				DO UPDATE SET status = 'retired', decided_by = EXCLUDED.decided_by, decided_at = NOW()
+			`, w.ID, createdBy); err != nil {
+				return fmt.Errorf("failed to retire the config of %s: %w", w.Name, err)
+			}
+
+			if _, err := tx.Exec(ctx, `
+				WITH next AS (
+					SELECT s.id, GREATEST(s.version, COALESCE((SELECT MAX(version) FROM strategy_versions WHERE strategy_id = s.id), 0)) + 1 AS version
+					FROM strategies s
+					WHERE s.id = $1::uuid
+				), stored AS (
+					INSERT INTO strategy_versions (strategy_id, version, config, status, note, created_by, decided_by, decided_at)
+					SELECT id, version, $2, 'live', NULLIF($3, ''), NULLIF($4, ''), NULLIF($4, ''), NOW()
+					FROM next
+					RETURNING version
+				)
+				UPDATE strategies SET config = $2, version = (SELECT version FROM stored)
+				WHERE id = $1::uuid
+			`, w.ID, config, note, createdBy); err != nil {
+				return fmt.Errorf("failed to store the config of %s: %w", w.Name, err)
+			}
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE strategies
+			SET enabled = $2, dry_run = $3, priority = $4, tenant = $5
+			WHERE id = $1::uuid
+				AND (enabled IS DISTINCT FROM $2 OR dry_run <> $3 OR priority <> $4 OR tenant <> $5)
+		`, w.ID, w.Enabled, w.DryRun, w.Priority, w.Tenant); err != nil {
+			return fmt.Errorf("failed to update %s: %w", w.Name, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
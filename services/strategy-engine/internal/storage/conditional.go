@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// AddConditionalCommand stores a held command or a resting group order and
+// returns its ID and creation time
+func (s *PostgresStorage) AddConditionalCommand(ctx context.Context, c types.ConditionalCommand) (types.ConditionalCommand, error) {
+	data, err := json.Marshal(c.Command)
+	if err != nil {
+		return c, fmt.Errorf("failed to marshal command: %w", err)
+	}
+
+	query := `
+		INSERT INTO conditional_commands (strategy, strategy_id, oco_group, status, order_id, command)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, NULLIF($5, ''), $6)
+		RETURNING id, created_at
+	`
+	err = s.pool.QueryRow(ctx, query, c.Strategy, c.StrategyID, c.Group, c.Status, c.OrderID, data).
+		Scan(&c.ID, &c.CreatedAt)
+	return c, err
+}
+
+// GetConditionalCommands returns every held command and resting group
+// order, oldest first
+func (s *PostgresStorage) GetConditionalCommands(ctx context.Context) ([]types.ConditionalCommand, error) {
+	query := `
+		SELECT id, strategy, COALESCE(strategy_id, ''), COALESCE(oco_group, ''), status,
+			COALESCE(order_id, ''), command, created_at
+		FROM conditional_commands
+		ORDER BY id
+	`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commands []types.ConditionalCommand
+	for rows.Next() {
+		var c types.ConditionalCommand
+		var commandJSON []byte
+		if err := rows.Scan(
+			&c.ID,
+			&c.Strategy,
+			&c.StrategyID,
+			&c.Group,
+			&c.Status,
+			&c.OrderID,
+			&commandJSON,
+			&c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(commandJSON, &c.Command); err != nil {
+			return nil, fmt.Errorf("conditional command %d: failed to parse command: %w", c.ID, err)
+		}
+		commands = append(commands, c)
+	}
+	return commands, rows.Err()
+}
+
+// DeleteConditionalCommands removes held commands or group orders by ID
+func (s *PostgresStorage) DeleteConditionalCommands(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.pool.Exec(ctx, `DELETE FROM conditional_commands WHERE id = ANY($1)`, ids)
+	return err
+}
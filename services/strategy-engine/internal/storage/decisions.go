@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// RecordDecisions stores a batch of strategy decisions in one transaction
+func (s *PostgresStorage) RecordDecisions(ctx context.Context, decisions []types.Decision) error {
+	query := `
+		INSERT INTO strategy_decisions (instance, strategy, event_id, event_type, commands, error_class, error_message, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), NULLIF($7, ''), $8)
+	`
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, d := range decisions {
+		commands := d.Commands
+		if commands == nil {
+			commands = []types.Command{}
+		}
+		data, err := json.Marshal(commands)
+		if err != nil {
+			return fmt.Errorf("failed to marshal commands: %w", err)
+		}
+		if _, err := tx.Exec(ctx, query,
+			d.Instance, d.Strategy, d.EventID, d.EventType, data, d.ErrorClass, d.Error, d.CreatedAt,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetDecisions returns the decisions an instance recorded in [since, until),
+// oldest first
+func (s *PostgresStorage) GetDecisions(ctx context.Context, instance string, since, until time.Time) ([]types.Decision, error) {
+	query := `
+		SELECT instance, strategy, event_id, COALESCE(event_type, ''), commands,
+			COALESCE(error_class, ''), COALESCE(error_message, ''), created_at
+		FROM strategy_decisions
+		WHERE instance = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY id
+	`
+
+	rows, err := s.pool.Query(ctx, query, instance, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []types.Decision
+	for rows.Next() {
+		var d types.Decision
+		var commandsJSON []byte
+		if err := rows.Scan(
+			&d.Instance,
+			&d.Strategy,
+			&d.EventID,
+			&d.EventType,
+			&commandsJSON,
+			&d.ErrorClass,
+			&d.Error,
+			&d.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(commandsJSON, &d.Commands); err != nil {
+			return nil, fmt.Errorf("decision of %s on %s: failed to parse commands: %w", d.Strategy, d.EventID, err)
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, rows.Err()
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// ErrFeatureFlagNotFound is returned when no feature flag has the given name
+var ErrFeatureFlagNotFound = errors.New("feature flag not found")
+
+const featureFlagColumns = `
+	name, enabled, percent, environments, COALESCE(description, ''),
+	COALESCE(updated_by, ''), created_at, updated_at
+`
+
+// GetFeatureFlags returns every feature flag, by name
+func (s *PostgresStorage) GetFeatureFlags(ctx context.Context) ([]types.FeatureFlag, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+featureFlagColumns+` FROM feature_flags ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []types.FeatureFlag
+	for rows.Next() {
+		f, err := scanFeatureFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+// PutFeatureFlag creates a feature flag or replaces its settings
+func (s *PostgresStorage) PutFeatureFlag(ctx context.Context, f types.FeatureFlag) (types.FeatureFlag, error) {
+	environments := f.Environments
+	if environments == nil {
+		environments = []string{}
+	}
+
+	query := `
+		INSERT INTO feature_flags (name, enabled, percent, environments, description, updated_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+		ON CONFLICT (name) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			percent = EXCLUDED.percent,
+			environments = EXCLUDED.environments,
+			description = EXCLUDED.description,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING ` + featureFlagColumns
+
+	return scanFeatureFlag(s.pool.QueryRow(ctx, query,
+		f.Name, f.Enabled, f.Percent, environments, f.Description, f.UpdatedBy))
+}
+
+// DeleteFeatureFlag removes a feature flag
+func (s *PostgresStorage) DeleteFeatureFlag(ctx context.Context, name string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM feature_flags WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrFeatureFlagNotFound, name)
+	}
+	return nil
+}
+
+func scanFeatureFlag(row pgx.Row) (types.FeatureFlag, error) {
+	var f types.FeatureFlag
+	err := row.Scan(
+		&f.Name,
+		&f.Enabled,
+		&f.Percent,
+		&f.Environments,
+		&f.Description,
+		&f.UpdatedBy,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+	)
+	return f, err
+}
@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// RecordOrder appends an order to the order journal
+func (s *PostgresStorage) RecordOrder(ctx context.Context, record types.OrderRecord) error {
+	query := `
+		INSERT INTO order_journal (
+			strategy, platform, account_id, market_id, side, price, shares,
+			reference_price, order_id, status, error_message, latency_ms, expires_at, created_at, tenant, action,
+			outcome_id, hedge_latency_ms
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, NULLIF($11, ''), $12, $13, $14, NULLIF($15, ''), NULLIF($16, ''),
+			NULLIF($17, ''), NULLIF($18, 0))
+	`
+
+	_, err := s.pool.Exec(ctx, query,
+		record.Strategy,
+		record.Platform,
+		record.AccountID,
+		record.MarketID,
+		record.Side,
+		record.Price,
+		record.Shares,
+		record.ReferencePrice,
+		record.OrderID,
+		record.Status,
+		record.Error,
+		record.Latency.Milliseconds(),
+		record.ExpiresAt,
+		record.CreatedAt,
+		record.Tenant,
+		record.Action,
+		record.OutcomeID,
+		record.HedgeLatency.Milliseconds(),
+	)
+	return err
+}
+
+// RecordFill applies a fill to the journaled order it belongs to, keeping a
+// share-weighted average fill price. Fills for orders we did not place are ignored.
+func (s *PostgresStorage) RecordFill(ctx context.Context, platform, orderID string, price, shares float64, filledAt time.Time) error {
+	query := `
+		UPDATE order_journal
+		SET fill_price = (COALESCE(fill_price, 0) * filled_shares + $3 * $4) / (filled_shares + $4),
+			filled_shares = filled_shares + $4,
+			filled_at = $5
+		WHERE platform = $1 AND order_id = $2
+	`
+
+	_, err := s.pool.Exec(ctx, query, platform, orderID, price, shares, filledAt)
+	return err
+}
+
+// StrategyPositions returns the shares a strategy holds per account, market
+// and side according to the journal: what its accepted orders filled, with
+// sells subtracted. Sides the strategy no longer holds are left out.
+func (s *PostgresStorage) StrategyPositions(ctx context.Context, strategy string) ([]types.Position, error) {
+	query := `
+		SELECT platform, account_id, market_id, side,
+			SUM(CASE WHEN action = 'sell' THEN -filled_shares ELSE filled_shares END) AS shares
+		FROM order_journal
+		WHERE strategy = $1 AND status = 'accepted' AND filled_shares > 0
+		GROUP BY platform, account_id, market_id, side
+		HAVING SUM(CASE WHEN action = 'sell' THEN -filled_shares ELSE filled_shares END) > 0
+	`
+
+	rows, err := s.pool.Query(ctx, query, strategy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []types.Position
+	for rows.Next() {
+		var p types.Position
+		if err := rows.Scan(&p.Platform, &p.AccountID, &p.MarketID, &p.Side, &p.Shares); err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	return positions, rows.Err()
+}
+
+// FilterJournaledOrders returns which of the given order IDs were placed by the engine
+func (s *PostgresStorage) FilterJournaledOrders(ctx context.Context, platform string, orderIDs []string) (map[string]bool, error) {
+	query := `
+		SELECT DISTINCT order_id
+		FROM order_journal
+		WHERE platform = $1 AND order_id = ANY($2)
+	`
+
+	rows, err := s.pool.Query(ctx, query, platform, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[string]bool)
+	for rows.Next() {
+		var orderID string
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, err
+		}
+		known[orderID] = true
+	}
+
+	return known, rows.Err()
+}
+
+// GetOrderExpiries returns the expiry of those of the given orders that have one
+func (s *PostgresStorage) GetOrderExpiries(ctx context.Context, platform string, orderIDs []string) (map[string]time.Time, error) {
+	query := `
+		SELECT order_id, MIN(expires_at)
+		FROM order_journal
+		WHERE platform = $1 AND order_id = ANY($2) AND expires_at IS NOT NULL
+		GROUP BY order_id
+	`
+
+	rows, err := s.pool.Query(ctx, query, platform, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expiries := make(map[string]time.Time)
+	for rows.Next() {
+		var orderID string
+		var expiresAt time.Time
+		if err := rows.Scan(&orderID, &expiresAt); err != nil {
+			return nil, err
+		}
+		expiries[orderID] = expiresAt
+	}
+
+	return expiries, rows.Err()
+}
+
+// GetExecutionQuality aggregates journaled orders per platform since the given time
+func (s *PostgresStorage) GetExecutionQuality(ctx context.Context, since time.Time) ([]types.VenueQuality, error) {
+	query := `
+		SELECT
+			platform,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'accepted'),
+			COUNT(*) FILTER (WHERE status = 'rejected'),
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			COUNT(*) FILTER (WHERE filled_shares > 0),
+			COALESCE(AVG(latency_ms), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms), 0),
+			COALESCE(AVG(CASE WHEN action = 'sell' THEN reference_price - fill_price ELSE fill_price - reference_price END)
+				FILTER (WHERE filled_shares > 0), 0)
+		FROM order_journal
+		WHERE created_at >= $1 AND status NOT IN ('dry_run', 'shadow')
+		GROUP BY platform
+		ORDER BY platform
+	`
+
+	rows, err := s.pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []types.VenueQuality
+	for rows.Next() {
+		var q types.VenueQuality
+		if err := rows.Scan(
+			&q.Platform,
+			&q.Orders,
+			&q.Accepted,
+			&q.Rejected,
+			&q.Failed,
+			&q.Filled,
+			&q.AvgLatencyMs,
+			&q.P95LatencyMs,
+			&q.EffectiveSpread,
+		); err != nil {
+			return nil, err
+		}
+
+		if q.Accepted > 0 {
+			q.FillRate = float64(q.Filled) / float64(q.Accepted)
+		}
+		if q.Orders > 0 {
+			q.RejectRate = float64(q.Rejected) / float64(q.Orders)
+		}
+
+		report = append(report, q)
+	}
+
+	return report, rows.Err()
+}
+
+// GetJournalSince returns journaled orders created since the given time, oldest first
+func (s *PostgresStorage) GetJournalSince(ctx context.Context, since time.Time) ([]types.OrderRecord, error) {
+	query := `
+		SELECT
+			COALESCE(strategy, ''), COALESCE(tenant, ''), platform, account_id, market_id, side, price, shares,
+			COALESCE(reference_price, 0), COALESCE(order_id, ''), status,
+			COALESCE(error_message, ''), latency_ms, created_at
+		FROM order_journal
+		WHERE created_at >= $1
+		ORDER BY created_at
+	`
+
+	rows, err := s.pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []types.OrderRecord
+	for rows.Next() {
+		var r types.OrderRecord
+		var latencyMs int64
+		if err := rows.Scan(
+			&r.Strategy,
+			&r.Tenant,
+			&r.Platform,
+			&r.AccountID,
+			&r.MarketID,
+			&r.Side,
+			&r.Price,
+			&r.Shares,
+			&r.ReferencePrice,
+			&r.OrderID,
+			&r.Status,
+			&r.Error,
+			&latencyMs,
+			&r.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		r.Latency = time.Duration(latencyMs) * time.Millisecond
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// GetOpenFillCost sums what several strategies paid for fills on markets that
+// have not resolved yet
+func (s *PostgresStorage) GetOpenFillCost(ctx context.Context, strategies []string) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(j.filled_shares * j.fill_price), 0)
+		FROM order_journal j
+		WHERE j.strategy = ANY($1)
+			AND j.filled_shares > 0
+			AND NOT EXISTS (
+				SELECT 1 FROM market_resolutions r
+				WHERE r.platform = j.platform AND r.market_id = j.market_id
+			)
+	`
+
+	var cost float64
+	err := s.pool.QueryRow(ctx, query, strategies).Scan(&cost)
+	return cost, err
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+var (
+	// ErrMappingNotFound is returned when no market mapping has the given ID
+	ErrMappingNotFound = errors.New("market mapping not found")
+
+	// ErrMappingConflict is returned when either market of a mapping is
+	// already mapped
+	ErrMappingConflict = errors.New("market is already mapped")
+)
+
+const mappingColumns = `
+	id, predict_market_id, polymarket_condition_id, inverted,
+	COALESCE(note, ''), COALESCE(created_by, ''), created_at, updated_at
+`
+
+// GetMarketMappings returns every market mapping, oldest first
+func (s *PostgresStorage) GetMarketMappings(ctx context.Context) ([]types.MarketMapping, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+mappingColumns+` FROM market_mappings ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []types.MarketMapping
+	for rows.Next() {
+		m, err := scanMapping(rows)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
+
+// CreateMarketMapping stores a new mapping and returns it with its ID
+func (s *PostgresStorage) CreateMarketMapping(ctx context.Context, m types.MarketMapping) (types.MarketMapping, error) {
+	query := `
+		INSERT INTO market_mappings (predict_market_id, polymarket_condition_id, inverted, note, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+		RETURNING ` + mappingColumns
+
+	created, err := scanMapping(s.pool.QueryRow(ctx, query,
+		m.PredictMarketID, m.PolymarketConditionID, m.Inverted, m.Note, m.CreatedBy))
+	return created, mappingError(err)
+}
+
+// UpdateMarketMapping replaces the markets, direction and note of a mapping
+func (s *PostgresStorage) UpdateMarketMapping(ctx context.Context, m types.MarketMapping) (types.MarketMapping, error) {
+	query := `
+		UPDATE market_mappings
+		SET predict_market_id = $2, polymarket_condition_id = $3, inverted = $4, note = NULLIF($5, '')
+		WHERE id = $1
+		RETURNING ` + mappingColumns
+
+	updated, err := scanMapping(s.pool.QueryRow(ctx, query,
+		m.ID, m.PredictMarketID, m.PolymarketConditionID, m.Inverted, m.Note))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return updated, fmt.Errorf("%w: %d", ErrMappingNotFound, m.ID)
+	}
+	return updated, mappingError(err)
+}
+
+// DeleteMarketMapping removes a mapping
+func (s *PostgresStorage) DeleteMarketMapping(ctx context.Context, id int64) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM market_mappings WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %d", ErrMappingNotFound, id)
+	}
+	return nil
+}
+
+func scanMapping(row pgx.Row) (types.MarketMapping, error) {
+	var m types.MarketMapping
+	err := row.Scan(
+		&m.ID,
+		&m.PredictMarketID,
+		&m.PolymarketConditionID,
+		&m.Inverted,
+		&m.Note,
+		&m.CreatedBy,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+	)
+	return m, err
+}
+
+// mappingError turns unique violations into ErrMappingConflict
+func mappingError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%w (%s)", ErrMappingConflict, pgErr.ConstraintName)
+	}
+	return err
+}
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// EnqueueCommands persists a handler's commands to the outbox in one
+// transaction, so either all of them are delivered or none are. Each row gets
+// its idempotency key, outbox-<id>, with it.
+func (s *PostgresStorage) EnqueueCommands(ctx context.Context, strategy types.Strategy, event types.Event, commands []types.Command) error {
+	query := `
+		INSERT INTO command_outbox (id, strategy, strategy_id, event_id, event_type, command, client_order_id)
+		SELECT id, $1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, 'outbox-' || id
+		FROM (SELECT nextval(pg_get_serial_sequence('command_outbox', 'id')) AS id) AS next
+	`
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, cmd := range commands {
+		data, err := json.Marshal(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to marshal command: %w", err)
+		}
+		if _, err := tx.Exec(ctx, query, strategy.Name, strategy.ID, event.ID, event.Type, data); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ClaimOutbox marks up to limit due commands as processing and returns them,
+// oldest first. Rows locked by another instance are skipped.
+func (s *PostgresStorage) ClaimOutbox(ctx context.Context, limit int) ([]types.OutboxEntry, error) {
+	query := `
+		UPDATE command_outbox
+		SET status = 'processing', attempts = attempts + 1, claimed_at = NOW()
+		WHERE id IN (
+			SELECT id FROM command_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, strategy, COALESCE(strategy_id, ''), COALESCE(event_id, ''),
+			COALESCE(event_type, ''), command, COALESCE(client_order_id, ''), attempts
+	`
+
+	rows, err := s.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []types.OutboxEntry
+	for rows.Next() {
+		var entry types.OutboxEntry
+		var commandJSON []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Strategy,
+			&entry.StrategyID,
+			&entry.EventID,
+			&entry.EventType,
+			&commandJSON,
+			&entry.ClientOrderID,
+			&entry.Attempts,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(commandJSON, &entry.Command); err != nil {
+			return nil, fmt.Errorf("outbox entry %d: failed to parse command: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	// RETURNING does not preserve the subquery order
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	return entries, rows.Err()
+}
+
+// CompleteOutbox marks a command as delivered
+func (s *PostgresStorage) CompleteOutbox(ctx context.Context, id int64) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE command_outbox
+		SET status = 'done', last_error = NULL, completed_at = NOW()
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// RetryOutbox returns a command to the queue for another attempt at nextAttempt
+func (s *PostgresStorage) RetryOutbox(ctx context.Context, id int64, lastError string, nextAttempt time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE command_outbox
+		SET status = 'pending', last_error = $2, next_attempt_at = $3
+		WHERE id = $1
+	`, id, lastError, nextAttempt)
+	return err
+}
+
+// FailOutbox gives up on a command
+func (s *PostgresStorage) FailOutbox(ctx context.Context, id int64, lastError string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE command_outbox
+		SET status = 'failed', last_error = $2, completed_at = NOW()
+		WHERE id = $1
+	`, id, lastError)
+	return err
+}
+
+// MarkOutboxUnknown gives up on a command that may or may not have been
+// executed, leaving it for reconciliation
+func (s *PostgresStorage) MarkOutboxUnknown(ctx context.Context, id int64, lastError string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE command_outbox
+		SET status = 'unknown', last_error = $2, completed_at = NOW()
+		WHERE id = $1
+	`, id, lastError)
+	return err
+}
+
+// ClaimStaleOutbox claims the commands left in processing longer than
+// olderThan by a crashed dispatcher and returns them, oldest first
+func (s *PostgresStorage) ClaimStaleOutbox(ctx context.Context, olderThan time.Duration) ([]types.OutboxEntry, error) {
+	query := `
+		UPDATE command_outbox
+		SET claimed_at = NOW()
+		WHERE status = 'processing' AND claimed_at < $1
+		RETURNING id, strategy, COALESCE(strategy_id, ''), COALESCE(event_id, ''),
+			COALESCE(event_type, ''), command, COALESCE(client_order_id, ''), attempts
+	`
+
+	rows, err := s.pool.Query(ctx, query, time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []types.OutboxEntry
+	for rows.Next() {
+		var entry types.OutboxEntry
+		var commandJSON []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Strategy,
+			&entry.StrategyID,
+			&entry.EventID,
+			&entry.EventType,
+			&commandJSON,
+			&entry.ClientOrderID,
+			&entry.Attempts,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(commandJSON, &entry.Command); err != nil {
+			return nil, fmt.Errorf("outbox entry %d: failed to parse command: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	return entries, rows.Err()
+}
+
+// GetFailedOutbox returns up to limit commands that were given up on, failed
+// or of unknown outcome, newest first
+func (s *PostgresStorage) GetFailedOutbox(ctx context.Context, limit int) ([]types.OutboxEntry, error) {
+	query := `
+		SELECT id, strategy, COALESCE(strategy_id, ''), COALESCE(event_id, ''),
+			COALESCE(event_type, ''), command, COALESCE(client_order_id, ''), status, attempts,
+			COALESCE(last_error, '')
+		FROM command_outbox
+		WHERE status IN ('failed', 'unknown')
+		ORDER BY id DESC
+		LIMIT $1
+	`
+
+	rows, err := s.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []types.OutboxEntry
+	for rows.Next() {
+		var entry types.OutboxEntry
+		var commandJSON []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Strategy,
+			&entry.StrategyID,
+			&entry.EventID,
+			&entry.EventType,
+			&commandJSON,
+			&entry.ClientOrderID,
+			&entry.Status,
+			&entry.Attempts,
+			&entry.LastError,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(commandJSON, &entry.Command); err != nil {
+			return nil, fmt.Errorf("outbox entry %d: failed to parse command: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetRetryableOutbox returns the given-up commands chosen for a retry, oldest
+// first: failed or unknown ones by ID, and failed ones created at or after
+// since when it is set. Commands of unknown outcome are only retried by ID,
+// once reconciled.
+func (s *PostgresStorage) GetRetryableOutbox(ctx context.Context, ids []int64, since time.Time) ([]types.OutboxEntry, error) {
+	if ids == nil {
+		ids = []int64{}
+	}
+	var from *time.Time
+	if !since.IsZero() {
+		from = &since
+	}
+
+	query := `
+		SELECT id, strategy, COALESCE(strategy_id, ''), COALESCE(event_id, ''),
+			COALESCE(event_type, ''), command, COALESCE(client_order_id, ''), status, attempts,
+			COALESCE(last_error, '')
+		FROM command_outbox
+		WHERE (status IN ('failed', 'unknown') AND id = ANY($1))
+			OR (status = 'failed' AND $2::timestamptz IS NOT NULL AND created_at >= $2)
+		ORDER BY id
+	`
+
+	rows, err := s.pool.Query(ctx, query, ids, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []types.OutboxEntry
+	for rows.Next() {
+		var entry types.OutboxEntry
+		var commandJSON []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Strategy,
+			&entry.StrategyID,
+			&entry.EventID,
+			&entry.EventType,
+			&commandJSON,
+			&entry.ClientOrderID,
+			&entry.Status,
+			&entry.Attempts,
+			&entry.LastError,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(commandJSON, &entry.Command); err != nil {
+			return nil, fmt.Errorf("outbox entry %d: failed to parse command: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// MarkOutboxRetried records that a given-up command was sent through the
+// pipeline again. It reports false when the command was no longer failed or
+// unknown, for example because another retry took it first.
+func (s *PostgresStorage) MarkOutboxRetried(ctx context.Context, id int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE command_outbox
+		SET status = 'retried', completed_at = NOW()
+		WHERE id = $1 AND status IN ('failed', 'unknown')
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
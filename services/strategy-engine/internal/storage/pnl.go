@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+const insertPnL = `
+	INSERT INTO strategy_pnl (strategy, platform, market_id, order_id, event_id, kind, pnl, created_at)
+	VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8)
+	ON CONFLICT (event_id, kind, strategy) WHERE event_id IS NOT NULL DO NOTHING
+`
+
+func pnlArgs(record types.PnLRecord) []interface{} {
+	kind := record.Kind
+	if kind == "" {
+		kind = types.PnLRealized
+	}
+	return []interface{}{
+		record.Strategy,
+		record.Platform,
+		record.MarketID,
+		record.OrderID,
+		record.EventID,
+		kind,
+		record.PnL,
+		record.CreatedAt,
+	}
+}
+
+// RecordPnL stores a realized PnL amount attributed to a strategy. A record
+// of an event that already booked the same kind for the strategy is skipped.
+func (s *PostgresStorage) RecordPnL(ctx context.Context, record types.PnLRecord) error {
+	_, err := s.pool.Exec(ctx, insertPnL, pnlArgs(record)...)
+	return err
+}
+
+// BackfillPnL stores PnL records in one transaction and returns how many were
+// new; records of events already booked are skipped like in RecordPnL
+func (s *PostgresStorage) BackfillPnL(ctx context.Context, records []types.PnLRecord) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var inserted int64
+	for _, record := range records {
+		tag, err := tx.Exec(ctx, insertPnL, pnlArgs(record)...)
+		if err != nil {
+			return 0, err
+		}
+		inserted += tag.RowsAffected()
+	}
+	return inserted, tx.Commit(ctx)
+}
+
+// GetFirstPnLTime returns when the oldest PnL record was booked; ok is false
+// while the ledger is empty
+func (s *PostgresStorage) GetFirstPnLTime(ctx context.Context) (first time.Time, ok bool, err error) {
+	var t *time.Time
+	if err := s.pool.QueryRow(ctx, `SELECT MIN(created_at) FROM strategy_pnl`).Scan(&t); err != nil {
+		return time.Time{}, false, err
+	}
+	if t == nil {
+		return time.Time{}, false, nil
+	}
+	return *t, true, nil
+}
+
+// GetStrategyPerformance aggregates realized PnL for a strategy since the given time
+func (s *PostgresStorage) GetStrategyPerformance(ctx context.Context, strategy string, since time.Time) (types.StrategyPerformance, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE kind <> 'fee'),
+			COUNT(*) FILTER (WHERE kind <> 'fee' AND pnl > 0),
+			COALESCE(SUM(pnl), 0),
+			COALESCE(-SUM(pnl) FILTER (WHERE kind = 'fee'), 0)
+		FROM strategy_pnl
+		WHERE strategy = $1 AND created_at >= $2
+	`
+
+	perf := types.StrategyPerformance{Strategy: strategy}
+	if err := s.pool.QueryRow(ctx, query, strategy, since).Scan(&perf.Trades, &perf.Wins, &perf.PnL, &perf.Fees); err != nil {
+		return perf, err
+	}
+
+	if perf.Trades > 0 {
+		perf.HitRate = float64(perf.Wins) / float64(perf.Trades)
+	}
+
+	return perf, nil
+}
+
+// GetPnLSince sums the realized PnL of several strategies since the given time
+func (s *PostgresStorage) GetPnLSince(ctx context.Context, strategies []string, since time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(pnl), 0)
+		FROM strategy_pnl
+		WHERE strategy = ANY($1) AND created_at >= $2
+	`
+
+	var pnl float64
+	err := s.pool.QueryRow(ctx, query, strategies, since).Scan(&pnl)
+	return pnl, err
+}
+
+// GetOrderStrategy returns the strategy that placed a journaled order, or "" if unknown
+func (s *PostgresStorage) GetOrderStrategy(ctx context.Context, platform, orderID string) (string, error) {
+	query := `
+		SELECT COALESCE(strategy, '')
+		FROM order_journal
+		WHERE platform = $1 AND order_id = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var strategy string
+	err := s.pool.QueryRow(ctx, query, platform, orderID).Scan(&strategy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return strategy, err
+}
+
+// GetStrategyAttribution aggregates journaled orders, fills and realized PnL
+// per strategy since the given time. Shadow and dry-run orders are excluded.
+func (s *PostgresStorage) GetStrategyAttribution(ctx context.Context, since time.Time) ([]types.StrategyAttribution, error) {
+	query := `
+		WITH orders AS (
+			SELECT
+				strategy,
+				COUNT(*) AS orders,
+				COUNT(*) FILTER (WHERE status = 'accepted') AS accepted,
+				COUNT(*) FILTER (WHERE filled_shares > 0) AS filled
+			FROM order_journal
+			WHERE created_at >= $1 AND strategy IS NOT NULL AND status NOT IN ('dry_run', 'shadow')
+			GROUP BY strategy
+		), pnl AS (
+			SELECT strategy, COUNT(*) FILTER (WHERE kind <> 'fee') AS trades, SUM(pnl) AS pnl
+			FROM strategy_pnl
+			WHERE created_at >= $1
+			GROUP BY strategy
+		)
+		SELECT
+			COALESCE(o.strategy, p.strategy),
+			COALESCE((SELECT s.tenant FROM strategies s
+				WHERE s.name = COALESCE(o.strategy, p.strategy)
+				ORDER BY s.updated_at DESC LIMIT 1), ''),
+			COALESCE(o.orders, 0),
+			COALESCE(o.accepted, 0),
+			COALESCE(o.filled, 0),
+			COALESCE(p.trades, 0),
+			COALESCE(p.pnl, 0)
+		FROM orders o
+		FULL OUTER JOIN pnl p ON p.strategy = o.strategy
+		ORDER BY 1
+	`
+
+	rows, err := s.pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attribution []types.StrategyAttribution
+	for rows.Next() {
+		var a types.StrategyAttribution
+		if err := rows.Scan(&a.Strategy, &a.Tenant, &a.Orders, &a.Accepted, &a.Filled, &a.Trades, &a.RealizedPnL); err != nil {
+			return nil, err
+		}
+		if a.Accepted > 0 {
+			a.FillRate = float64(a.Filled) / float64(a.Accepted)
+		}
+		attribution = append(attribution, a)
+	}
+
+	return attribution, rows.Err()
+}
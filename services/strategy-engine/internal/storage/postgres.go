@@ -1,52 +1,113 @@
 package storage
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
 	"github.com/rs/zerolog/log"
 )
 
 type PostgresStorage struct {
-	db *sql.DB
+	pool *pgxpool.Pool
 }
 
-func NewPostgres(url string) (*PostgresStorage, error) {
-	db, err := sql.Open("postgres", url)
+// Options configures the connection pool. Zero values keep pgxpool defaults
+// (max connections = max(4, number of CPUs)).
+type Options struct {
+	URL             string
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+
+	// StatementTimeout is set as the server-side statement_timeout of every
+	// connection, so a stuck query cannot hold a pooled connection forever
+	StatementTimeout time.Duration
+
+	// Password, when set, is called for every new connection so rotated
+	// credentials are used without restarting. It overrides the URL's password.
+	Password func(ctx context.Context) (string, error)
+}
+
+func NewPostgres(ctx context.Context, opts Options) (*PostgresStorage, error) {
+	poolConfig, err := pgxpool.ParseConfig(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+
+	if opts.MaxConns > 0 {
+		poolConfig.MaxConns = opts.MaxConns
+	}
+	if opts.MinConns > 0 {
+		poolConfig.MinConns = opts.MinConns
+	}
+	if opts.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	}
+
+	if opts.Password != nil {
+		poolConfig.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			password, err := opts.Password(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to resolve database password: %w", err)
+			}
+			cc.Password = password
+			return nil
+		}
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Create tables if not exist
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
-	}
+	// Tables are created by init.sql
 
-	log.Info().Msg("Connected to PostgreSQL")
+	log.Info().
+		Int32("max_conns", poolConfig.MaxConns).
+		Dur("statement_timeout", opts.StatementTimeout).
+		Msg("Connected to PostgreSQL")
 
-	return &PostgresStorage{db: db}, nil
+	return &PostgresStorage{pool: pool}, nil
 }
 
-func createTables(db *sql.DB) error {
-	// Tables are created by init.sql, just verify connection
-	return nil
+func (s *PostgresStorage) GetActiveStrategies(ctx context.Context) ([]types.Strategy, error) {
+	return s.queryStrategies(ctx, `
+		SELECT id::text, name, type, enabled, shadow, COALESCE(shadow_reason, ''), dry_run, priority, version, tenant, config, created_at, updated_at
+		FROM strategies
+		WHERE enabled = true
+	`)
 }
 
-func (s *PostgresStorage) GetActiveStrategies() ([]types.Strategy, error) {
-	query := `
-		SELECT id, name, type, enabled, config, created_at, updated_at
+// GetStrategies returns every strategy, enabled or not, by name
+func (s *PostgresStorage) GetStrategies(ctx context.Context) ([]types.Strategy, error) {
+	return s.queryStrategies(ctx, `
+		SELECT id::text, name, type, enabled, shadow, COALESCE(shadow_reason, ''), dry_run, priority, version, tenant, config, created_at, updated_at
 		FROM strategies
-		WHERE enabled = true
-	`
+		ORDER BY name
+	`)
+}
 
-	rows, err := s.db.Query(query)
+func (s *PostgresStorage) queryStrategies(ctx context.Context, query string) ([]types.Strategy, error) {
+	rows, err := s.pool.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +123,12 @@ func (s *PostgresStorage) GetActiveStrategies() ([]types.Strategy, error) {
 			&strategy.Name,
 			&strategy.Type,
 			&strategy.Active,
+			&strategy.Shadow,
+			&strategy.ShadowReason,
+			&strategy.DryRun,
+			&strategy.Priority,
+			&strategy.Version,
+			&strategy.Tenant,
 			&configJSON,
 			&strategy.CreatedAt,
 			&strategy.UpdatedAt,
@@ -83,9 +150,9 @@ func (s *PostgresStorage) GetActiveStrategies() ([]types.Strategy, error) {
 	return strategies, nil
 }
 
-func (s *PostgresStorage) GetStrategy(id string) (*types.Strategy, error) {
+func (s *PostgresStorage) GetStrategy(ctx context.Context, id string) (*types.Strategy, error) {
 	query := `
-		SELECT id, name, type, enabled, config, created_at, updated_at
+		SELECT id::text, name, type, enabled, shadow, COALESCE(shadow_reason, ''), dry_run, priority, version, tenant, config, created_at, updated_at
 		FROM strategies
 		WHERE id = $1::uuid
 	`
@@ -93,17 +160,23 @@ func (s *PostgresStorage) GetStrategy(id string) (*types.Strategy, error) {
 	var strategy types.Strategy
 	var configJSON []byte
 
-	err := s.db.QueryRow(query, id).Scan(
+	err := s.pool.QueryRow(ctx, query, id).Scan(
 		&strategy.ID,
 		&strategy.Name,
 		&strategy.Type,
 		&strategy.Active,
+		&strategy.Shadow,
+		&strategy.ShadowReason,
+		&strategy.DryRun,
+		&strategy.Priority,
+		&strategy.Version,
+		&strategy.Tenant,
 		&configJSON,
 		&strategy.CreatedAt,
 		&strategy.UpdatedAt,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
@@ -117,6 +190,138 @@ func (s *PostgresStorage) GetStrategy(id string) (*types.Strategy, error) {
 	return &strategy, nil
 }
 
+// GetStrategyByName returns the most recently updated strategy with the given
+// name, enabled or not, or nil if there is none
+func (s *PostgresStorage) GetStrategyByName(ctx context.Context, name string) (*types.Strategy, error) {
+	query := `
+		SELECT id::text, name, type, enabled, shadow, COALESCE(shadow_reason, ''), dry_run, priority, version, tenant, config, created_at, updated_at
+		FROM strategies
+		WHERE name = $1
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+
+	var strategy types.Strategy
+	var configJSON []byte
+
+	err := s.pool.QueryRow(ctx, query, name).Scan(
+		&strategy.ID,
+		&strategy.Name,
+		&strategy.Type,
+		&strategy.Active,
+		&strategy.Shadow,
+		&strategy.ShadowReason,
+		&strategy.DryRun,
+		&strategy.Priority,
+		&strategy.Version,
+		&strategy.Tenant,
+		&configJSON,
+		&strategy.CreatedAt,
+		&strategy.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(configJSON, &strategy.Config); err != nil {
+		return nil, err
+	}
+
+	return &strategy, nil
+}
+
+// SetStrategyShadow moves a strategy into or out of shadow mode
+func (s *PostgresStorage) SetStrategyShadow(ctx context.Context, id string, shadow bool, reason string) error {
+	query := `
+		UPDATE strategies
+		SET shadow = $2, shadow_reason = NULLIF($3, '')
+		WHERE id = $1::uuid
+	`
+
+	_, err := s.pool.Exec(ctx, query, id, shadow, reason)
+	return err
+}
+
+// DisableStrategy turns a strategy off; it stays off across restarts until re-enabled
+func (s *PostgresStorage) DisableStrategy(ctx context.Context, id string) error {
+	query := `
+		UPDATE strategies
+		SET enabled = false, updated_at = NOW()
+		WHERE id = $1::uuid
+	`
+
+	_, err := s.pool.Exec(ctx, query, id)
+	return err
+}
+
+// EnableStrategy turns a strategy back on
+func (s *PostgresStorage) EnableStrategy(ctx context.Context, id string) error {
+	query := `
+		UPDATE strategies
+		SET enabled = true, updated_at = NOW()
+		WHERE id = $1::uuid
+	`
+
+	_, err := s.pool.Exec(ctx, query, id)
+	return err
+}
+
+// ErrPairNotFound is returned when a strategy has no pair with the given primary
+var ErrPairNotFound = errors.New("pair not found")
+
+// SetStrategyPairEnabled sets the "enabled" flag of the pair whose primary is
+// the given account in a strategy's "pairs" config. The config is read and
+// written in one transaction, so concurrent edits of other pairs are kept.
+func (s *PostgresStorage) SetStrategyPairEnabled(ctx context.Context, id, primary string, enabled bool) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var raw []byte
+	err = tx.QueryRow(ctx, `SELECT config FROM strategies WHERE id = $1::uuid FOR UPDATE`, id).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("strategy %s not found", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	found := false
+	pairs, _ := config["pairs"].([]interface{})
+	for _, pairRaw := range pairs {
+		if pair, ok := pairRaw.(map[string]interface{}); ok && pair["primary"] == primary {
+			pair["enabled"] = enabled
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrPairNotFound, primary)
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE strategies SET config = $2, updated_at = NOW() WHERE id = $1::uuid
+	`, id, data); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (s *PostgresStorage) Close() error {
-	return s.db.Close()
+	s.pool.Close()
+	return nil
 }
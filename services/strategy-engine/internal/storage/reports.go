@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// GetDailyActivity aggregates the journaled orders, fills, fees and realized
+// PnL of every strategy between from and to. Orders count by the time they
+// were placed, fills by the time they were last filled. Shadow and dry-run
+// orders are excluded.
+func (s *PostgresStorage) GetDailyActivity(ctx context.Context, from, to time.Time) ([]types.StrategyDay, error) {
+	query := `
+		WITH orders AS (
+			SELECT
+				strategy,
+				COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2) AS orders,
+				COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2 AND status = 'accepted') AS accepted,
+				COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2 AND status = 'rejected') AS rejected,
+				COUNT(*) FILTER (WHERE created_at >= $1 AND created_at < $2 AND status = 'failed') AS failed,
+				COUNT(*) FILTER (WHERE filled_at >= $1 AND filled_at < $2) AS filled,
+				COALESCE(SUM(filled_shares * fill_price) FILTER (WHERE filled_at >= $1 AND filled_at < $2), 0) AS volume
+			FROM order_journal
+			WHERE strategy IS NOT NULL AND status NOT IN ('dry_run', 'shadow')
+				AND ((created_at >= $1 AND created_at < $2) OR (filled_at >= $1 AND filled_at < $2))
+			GROUP BY strategy
+		), pnl AS (
+			SELECT
+				strategy,
+				COALESCE(-SUM(pnl) FILTER (WHERE kind = 'fee'), 0) AS fees,
+				SUM(pnl) AS pnl
+			FROM strategy_pnl
+			WHERE created_at >= $1 AND created_at < $2
+			GROUP BY strategy
+		)
+		SELECT
+			COALESCE(o.strategy, p.strategy),
+			COALESCE((SELECT s.tenant FROM strategies s
+				WHERE s.name = COALESCE(o.strategy, p.strategy)
+				ORDER BY s.updated_at DESC LIMIT 1), ''),
+			COALESCE(o.orders, 0),
+			COALESCE(o.accepted, 0),
+			COALESCE(o.rejected, 0),
+			COALESCE(o.failed, 0),
+			COALESCE(o.filled, 0),
+			COALESCE(o.volume, 0),
+			COALESCE(p.fees, 0),
+			COALESCE(p.pnl, 0)
+		FROM orders o
+		FULL OUTER JOIN pnl p ON p.strategy = o.strategy
+		ORDER BY 1
+	`
+
+	rows, err := s.pool.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []types.StrategyDay
+	for rows.Next() {
+		var d types.StrategyDay
+		if err := rows.Scan(&d.Strategy, &d.Tenant, &d.Orders, &d.Accepted, &d.Rejected, &d.Failed,
+			&d.Filled, &d.Volume, &d.Fees, &d.RealizedPnL); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// CountAlerts counts the alerts raised between from and to, by type
+func (s *PostgresStorage) CountAlerts(ctx context.Context, from, to time.Time) (map[string]int, error) {
+	query := `
+		SELECT type, COUNT(*)
+		FROM alerts
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY type
+	`
+
+	rows, err := s.pool.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var alertType string
+		var count int
+		if err := rows.Scan(&alertType, &count); err != nil {
+			return nil, err
+		}
+		counts[alertType] = count
+	}
+	return counts, rows.Err()
+}
+
+// SaveDailyReport stores the report of a day, replacing an earlier one
+func (s *PostgresStorage) SaveDailyReport(ctx context.Context, report types.DailyReport) error {
+	day, err := time.Parse(time.DateOnly, report.Day)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO daily_reports (day, report, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (day) DO UPDATE SET report = EXCLUDED.report, created_at = EXCLUDED.created_at
+	`
+
+	_, err = s.pool.Exec(ctx, query, day, data, report.GeneratedAt)
+	return err
+}
+
+// GetDailyReport returns the stored report of a day (YYYY-MM-DD), or nil if
+// none was generated
+func (s *PostgresStorage) GetDailyReport(ctx context.Context, day string) (*types.DailyReport, error) {
+	date, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT report FROM daily_reports WHERE day = $1`
+
+	var data []byte
+	err = s.pool.QueryRow(ctx, query, date).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var report types.DailyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetDailyReports returns the latest stored reports, newest first
+func (s *PostgresStorage) GetDailyReports(ctx context.Context, limit int) ([]types.DailyReport, error) {
+	query := `SELECT report FROM daily_reports ORDER BY day DESC LIMIT $1`
+
+	rows, err := s.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []types.DailyReport
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var report types.DailyReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
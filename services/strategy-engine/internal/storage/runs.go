@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Runs are written by the record_strategy_run trigger on the strategies
+// table; the engine only reads them. Realized PnL is attributed to the run
+// that was open when it was recorded.
+
+const runColumns = `
+	r.id, r.strategy_id::text, r.version, r.config, r.shadow, r.started_at,
+	r.ended_at, COALESCE(r.end_reason, ''),
+	(SELECT COUNT(*) FROM strategy_pnl p
+		WHERE p.strategy = s.name AND p.kind <> 'fee' AND p.created_at >= r.started_at
+			AND (r.ended_at IS NULL OR p.created_at < r.ended_at)),
+	(SELECT COALESCE(SUM(p.pnl), 0) FROM strategy_pnl p
+		WHERE p.strategy = s.name AND p.created_at >= r.started_at
+			AND (r.ended_at IS NULL OR p.created_at < r.ended_at))
+`
+
+// GetStrategyRuns returns the latest runs of a strategy, newest first
+func (s *PostgresStorage) GetStrategyRuns(ctx context.Context, strategyID string, limit int) ([]types.StrategyRun, error) {
+	query := `SELECT ` + runColumns + `
+		FROM strategy_runs r
+		JOIN strategies s ON s.id = r.strategy_id
+		WHERE r.strategy_id = $1::uuid
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT $2
+	`
+
+	rows, err := s.pool.Query(ctx, query, strategyID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []types.StrategyRun
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetStrategyRunAt returns the run of a strategy that was open at the given
+// time, or nil if the strategy was not enabled then
+func (s *PostgresStorage) GetStrategyRunAt(ctx context.Context, strategyID string, at time.Time) (*types.StrategyRun, error) {
+	query := `SELECT ` + runColumns + `
+		FROM strategy_runs r
+		JOIN strategies s ON s.id = r.strategy_id
+		WHERE r.strategy_id = $1::uuid
+			AND r.started_at <= $2
+			AND (r.ended_at IS NULL OR r.ended_at > $2)
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT 1
+	`
+
+	run, err := scanRun(s.pool.QueryRow(ctx, query, strategyID, at))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func scanRun(row pgx.Row) (types.StrategyRun, error) {
+	var r types.StrategyRun
+	var configJSON []byte
+	if err := row.Scan(
+		&r.ID,
+		&r.StrategyID,
+		&r.Version,
+		&configJSON,
+		&r.Shadow,
+		&r.StartedAt,
+		&r.EndedAt,
+		&r.EndReason,
+		&r.Trades,
+		&r.RealizedPnL,
+	); err != nil {
+		return r, err
+	}
+
+	if err := json.Unmarshal(configJSON, &r.Config); err != nil {
+		return r, fmt.Errorf("failed to parse run config: %w", err)
+	}
+	return r, nil
+}
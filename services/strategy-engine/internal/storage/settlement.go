@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// RecordResolution stores the outcome of a resolved market. Repeated
+// resolutions of the same market are ignored.
+func (s *PostgresStorage) RecordResolution(ctx context.Context, resolution types.MarketResolution) error {
+	query := `
+		INSERT INTO market_resolutions (platform, market_id, outcome, resolved_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (platform, market_id) DO NOTHING
+	`
+
+	_, err := s.pool.Exec(ctx, query,
+		resolution.Platform,
+		resolution.MarketID,
+		resolution.Outcome,
+		resolution.ResolvedAt,
+	)
+	return err
+}
+
+// GetResolutions returns the markets resolved since the given time
+func (s *PostgresStorage) GetResolutions(ctx context.Context, since time.Time) ([]types.MarketResolution, error) {
+	query := `
+		SELECT platform, market_id, outcome, resolved_at
+		FROM market_resolutions
+		WHERE resolved_at >= $1
+	`
+
+	rows, err := s.pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resolutions []types.MarketResolution
+	for rows.Next() {
+		var r types.MarketResolution
+		if err := rows.Scan(&r.Platform, &r.MarketID, &r.Outcome, &r.ResolvedAt); err != nil {
+			return nil, err
+		}
+		resolutions = append(resolutions, r)
+	}
+
+	return resolutions, rows.Err()
+}
+
+// SettlePositions closes out every open position on a resolved market: the
+// shares are paid out at 1 on the winning side and 0 otherwise, the result is
+// added to realized_pnl and the position is marked settled. Positions that are
+// already settled are left alone, so settling twice is harmless.
+func (s *PostgresStorage) SettlePositions(ctx context.Context, platform, marketID, outcome string, settledAt time.Time) ([]types.Settlement, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id::text, account_id::text, LOWER(side), shares, avg_price
+		FROM positions
+		WHERE platform = $1 AND market_id = $2 AND shares > 0 AND settled_at IS NULL
+		FOR UPDATE
+	`, platform, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	var settlements []types.Settlement
+	for rows.Next() {
+		var id string
+		st := types.Settlement{Platform: platform, MarketID: marketID}
+		if err := rows.Scan(&id, &st.AccountID, &st.Side, &st.Shares, &st.AvgPrice); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if st.Side == outcome {
+			st.Payout = 1
+		}
+		st.PnL = st.Shares * (st.Payout - st.AvgPrice)
+		ids = append(ids, id)
+		settlements = append(settlements, st)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		if _, err := tx.Exec(ctx, `
+			UPDATE positions
+			SET realized_pnl = realized_pnl + $2,
+				settlement_price = $3,
+				shares = 0,
+				settled_at = $4
+			WHERE id = $1::uuid
+		`, id, settlements[i].PnL, settlements[i].Payout, settledAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return settlements, nil
+}
+
+// GetFilledSharesByStrategy sums the journaled fills per strategy for one
+// account, market and side
+func (s *PostgresStorage) GetFilledSharesByStrategy(ctx context.Context, platform, accountID, marketID, side string) (map[string]float64, error) {
+	query := `
+		SELECT strategy, SUM(filled_shares)
+		FROM order_journal
+		WHERE platform = $1 AND account_id = $2 AND market_id = $3 AND LOWER(side) = $4
+			AND strategy IS NOT NULL AND filled_shares > 0
+		GROUP BY strategy
+	`
+
+	rows, err := s.pool.Query(ctx, query, platform, accountID, marketID, side)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := make(map[string]float64)
+	for rows.Next() {
+		var strategy string
+		var filled float64
+		if err := rows.Scan(&strategy, &filled); err != nil {
+			return nil, err
+		}
+		shares[strategy] = filled
+	}
+
+	return shares, rows.Err()
+}
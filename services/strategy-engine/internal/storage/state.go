@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/state"
+)
+
+// Strategy state is a JSONB value per (strategy ID, key) with a version
+// counter; see the state package. Writes compare the version in the WHERE
+// clause, so a lost race shows up as no row instead of an overwrite.
+
+// GetState returns one state entry, or a zero Entry if the key does not exist
+func (s *PostgresStorage) GetState(ctx context.Context, strategyID, key string) (state.Entry, error) {
+	query := `
+		SELECT value, version, updated_at
+		FROM strategy_state
+		WHERE strategy_id = $1 AND key = $2
+	`
+
+	var entry state.Entry
+	err := s.pool.QueryRow(ctx, query, strategyID, key).Scan(&entry.Value, &entry.Version, &entry.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return state.Entry{}, nil
+	}
+	return entry, err
+}
+
+// ListState returns every state entry of a strategy, by key
+func (s *PostgresStorage) ListState(ctx context.Context, strategyID string) (map[string]state.Entry, error) {
+	query := `
+		SELECT key, value, version, updated_at
+		FROM strategy_state
+		WHERE strategy_id = $1
+	`
+
+	rows, err := s.pool.Query(ctx, query, strategyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make(map[string]state.Entry)
+	for rows.Next() {
+		var key string
+		var entry state.Entry
+		if err := rows.Scan(&key, &entry.Value, &entry.Version, &entry.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entries[key] = entry
+	}
+	return entries, rows.Err()
+}
+
+// PutState creates (version 0) or replaces a state entry and returns its new
+// version, or state.ErrConflict if the stored version differs
+func (s *PostgresStorage) PutState(ctx context.Context, strategyID, key string, value json.RawMessage, version int64) (int64, error) {
+	query := `
+		UPDATE strategy_state
+		SET value = $3, version = version + 1, updated_at = NOW()
+		WHERE strategy_id = $1 AND key = $2 AND version = $4
+		RETURNING version
+	`
+	if version == 0 {
+		query = `
+			INSERT INTO strategy_state (strategy_id, key, value, version)
+			VALUES ($1, $2, $3, 1)
+			ON CONFLICT (strategy_id, key) DO NOTHING
+			RETURNING version
+		`
+	}
+
+	args := []interface{}{strategyID, key, value}
+	if version != 0 {
+		args = append(args, version)
+	}
+
+	var next int64
+	err := s.pool.QueryRow(ctx, query, args...).Scan(&next)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, state.ErrConflict
+	}
+	return next, err
+}
+
+// DeleteState removes a state entry, or returns state.ErrConflict if the
+// stored version differs
+func (s *PostgresStorage) DeleteState(ctx context.Context, strategyID, key string, version int64) error {
+	query := `DELETE FROM strategy_state WHERE strategy_id = $1 AND key = $2 AND version = $3`
+
+	tag, err := s.pool.Exec(ctx, query, strategyID, key, version)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return state.ErrConflict
+	}
+	return nil
+}
+
+// ReplaceState writes the whole state of a strategy, versions included, and
+// deletes the keys missing from entries. It is the flush of the write-behind
+// state cache, which owns the versions.
+func (s *PostgresStorage) ReplaceState(ctx context.Context, strategyID string, entries map[string]state.Entry) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM strategy_state WHERE strategy_id = $1 AND NOT (key = ANY($2))`, strategyID, keys); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO strategy_state (strategy_id, key, value, version, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (strategy_id, key) DO UPDATE
+		SET value = EXCLUDED.value, version = EXCLUDED.version, updated_at = EXCLUDED.updated_at
+	`
+	for key, entry := range entries {
+		if _, err := tx.Exec(ctx, query, strategyID, key, entry.Value, entry.Version, entry.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// ErrVersionNotCandidate is returned when promoting or rejecting a version
+// that does not exist or was already decided
+var ErrVersionNotCandidate = errors.New("version is not a pending candidate")
+
+const versionColumns = `
+	strategy_id::text, version, config, status, COALESCE(note, ''),
+	COALESCE(created_by, ''), COALESCE(decided_by, ''), created_at, decided_at
+`
+
+// CreateStrategyVersion stores config as the next candidate version of a strategy
+func (s *PostgresStorage) CreateStrategyVersion(ctx context.Context, strategyID string, config map[string]interface{}, createdBy, note string) (types.StrategyVersion, error) {
+	query := `
+		INSERT INTO strategy_versions (strategy_id, version, config, note, created_by)
+		SELECT s.id,
+			GREATEST(s.version, COALESCE((SELECT MAX(version) FROM strategy_versions WHERE strategy_id = s.id), 0)) + 1,
+			$2, NULLIF($3, ''), NULLIF($4, '')
+		FROM strategies s
+		WHERE s.id = $1::uuid
+		RETURNING ` + versionColumns
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return types.StrategyVersion{}, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	version, err := scanVersion(s.pool.QueryRow(ctx, query, strategyID, data, note, createdBy))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return version, fmt.Errorf("strategy %s not found", strategyID)
+	}
+	return version, err
+}
+
+// GetStrategyVersions returns every stored version of a strategy, newest first
+func (s *PostgresStorage) GetStrategyVersions(ctx context.Context, strategyID string) ([]types.StrategyVersion, error) {
+	query := `SELECT ` + versionColumns + `
+		FROM strategy_versions
+		WHERE strategy_id = $1::uuid
+		ORDER BY version DESC
+	`
+	return s.queryVersions(ctx, query, strategyID)
+}
+
+// GetCandidateVersions returns the pending candidates of all enabled strategies
+func (s *PostgresStorage) GetCandidateVersions(ctx context.Context) ([]types.StrategyVersion, error) {
+	query := `SELECT ` + versionColumns + `
+		FROM strategy_versions
+		WHERE status = 'candidate'
+			AND strategy_id IN (SELECT id FROM strategies WHERE enabled = true)
+		ORDER BY strategy_id, version
+	`
+	return s.queryVersions(ctx, query)
+}
+
+// PromoteStrategyVersion makes a candidate the live config. The config it
+// replaces is kept as a retired version so it can be proposed again.
+func (s *PostgresStorage) PromoteStrategyVersion(ctx context.Context, strategyID string, version int, decidedBy string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var config []byte
+	err = tx.QueryRow(ctx, `
+		SELECT config FROM strategy_versions
+		WHERE strategy_id = $1::uuid AND version = $2 AND status = 'candidate'
+		FOR UPDATE
+	`, strategyID, version).Scan(&config)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrVersionNotCandidate
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO strategy_versions (strategy_id, version, config, status, decided_by, decided_at)
+		SELECT id, version, config, 'retired', NULLIF($2, ''), NOW()
+		FROM strategies WHERE id = $1::uuid
+		ON CONFLICT (strategy_id, version)
+		DO UPDATE SET status = 'retired', decided_by = EXCLUDED.decided_by, decided_at = NOW()
+	`, strategyID, decidedBy); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE strategies SET config = $2, version = $3 WHERE id = $1::uuid
+	`, strategyID, config, version); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE strategy_versions
+		SET status = 'live', decided_by = NULLIF($3, ''), decided_at = NOW()
+		WHERE strategy_id = $1::uuid AND version = $2
+	`, strategyID, version, decidedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RejectStrategyVersion discards a candidate
+func (s *PostgresStorage) RejectStrategyVersion(ctx context.Context, strategyID string, version int, decidedBy string) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE strategy_versions
+		SET status = 'rejected', decided_by = NULLIF($3, ''), decided_at = NOW()
+		WHERE strategy_id = $1::uuid AND version = $2 AND status = 'candidate'
+	`, strategyID, version, decidedBy)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVersionNotCandidate
+	}
+	return nil
+}
+
+func (s *PostgresStorage) queryVersions(ctx context.Context, query string, args ...interface{}) ([]types.StrategyVersion, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []types.StrategyVersion
+	for rows.Next() {
+		version, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+func scanVersion(row pgx.Row) (types.StrategyVersion, error) {
+	var v types.StrategyVersion
+	var configJSON []byte
+	if err := row.Scan(
+		&v.StrategyID,
+		&v.Version,
+		&configJSON,
+		&v.Status,
+		&v.Note,
+		&v.CreatedBy,
+		&v.DecidedBy,
+		&v.CreatedAt,
+		&v.DecidedAt,
+	); err != nil {
+		return v, err
+	}
+
+	if err := json.Unmarshal(configJSON, &v.Config); err != nil {
+		return v, fmt.Errorf("failed to parse version config: %w", err)
+	}
+	return v, nil
+}
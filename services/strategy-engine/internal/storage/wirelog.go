@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// InsertWireLog stores recorded account service requests
+func (s *PostgresStorage) InsertWireLog(ctx context.Context, entries []types.WireLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO wire_log (platform, method, url, request_headers, request_body, status,
+			response_headers, response_body, error, latency_ms, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, 0), $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11)
+	`
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, entry := range entries {
+		requestHeaders, err := json.Marshal(entry.RequestHeaders)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request headers: %w", err)
+		}
+		var responseHeaders []byte
+		if entry.ResponseHeaders != nil {
+			if responseHeaders, err = json.Marshal(entry.ResponseHeaders); err != nil {
+				return fmt.Errorf("failed to marshal response headers: %w", err)
+			}
+		}
+		if _, err := tx.Exec(ctx, query,
+			entry.Platform, entry.Method, entry.URL, requestHeaders, entry.RequestBody, entry.Status,
+			responseHeaders, entry.ResponseBody, entry.Error, entry.Latency.Milliseconds(), entry.CreatedAt,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetWireLog returns the recorded requests between since and until, newest
+// first, optionally of one platform and with contains in the URL or a body
+func (s *PostgresStorage) GetWireLog(ctx context.Context, platform string, since, until time.Time, contains string, limit int) ([]types.WireLogEntry, error) {
+	query := `
+		SELECT id, platform, method, url, request_headers, COALESCE(request_body, ''),
+			COALESCE(status, 0), response_headers, COALESCE(response_body, ''),
+			COALESCE(error, ''), latency_ms, created_at
+		FROM wire_log
+		WHERE created_at >= $1 AND created_at < $2
+			AND ($3 = '' OR platform = $3)
+			AND ($4 = '' OR url LIKE '%' || $4 || '%' OR request_body LIKE '%' || $4 || '%'
+				OR response_body LIKE '%' || $4 || '%')
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5
+	`
+
+	rows, err := s.pool.Query(ctx, query, since, until, platform, contains, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []types.WireLogEntry
+	for rows.Next() {
+		var entry types.WireLogEntry
+		var requestHeaders, responseHeaders []byte
+		var latencyMs int64
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Platform,
+			&entry.Method,
+			&entry.URL,
+			&requestHeaders,
+			&entry.RequestBody,
+			&entry.Status,
+			&responseHeaders,
+			&entry.ResponseBody,
+			&entry.Error,
+			&latencyMs,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(requestHeaders, &entry.RequestHeaders); err != nil {
+			return nil, fmt.Errorf("wire log entry %d: failed to parse request headers: %w", entry.ID, err)
+		}
+		if responseHeaders != nil {
+			if err := json.Unmarshal(responseHeaders, &entry.ResponseHeaders); err != nil {
+				return nil, fmt.Errorf("wire log entry %d: failed to parse response headers: %w", entry.ID, err)
+			}
+		}
+		entry.Latency = time.Duration(latencyMs) * time.Millisecond
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteWireLog deletes the requests recorded before the given time and
+// returns how many there were
+func (s *PostgresStorage) DeleteWireLog(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM wire_log WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,174 @@
+package strategies
+
+import (
+	"fmt"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// CorrelationHedgeHandler hedges a fill in one market with an order in a
+// correlated market, typically the same real-world outcome listed under a
+// different market ID on the other platform.
+//
+// Config:
+//
+//	{
+//	  "mappings": [{
+//	    "source_platform": "predict",     "source_market": "123",
+//	    "target_platform": "polymarket",  "target_market": "0xabc",
+//	    "hedge_account": "acct-uuid",
+//	    "hedge_ratio": 1.0,
+//	    "inverse": false,        // true if the markets are negatively correlated
+//	    "bidirectional": false,  // also hedge fills on the target market back
+//	    "source_hedge_account": "acct-uuid"  // hedge account for the reverse direction
+//	  }],
+//	  "accounts": ["..."],       // optional: only hedge fills on these accounts
+//	  "price_adjustment": 0.01
+//	}
+//
+// For positively correlated markets the hedge buys the opposite outcome, for
+// inverse mappings the same outcome, both at the complement of the fill price.
+func CorrelationHedgeHandler(event types.Event, strategy types.Strategy) ([]types.Command, error) {
+	if event.Type != "fill" && event.Type != "trade_executed" {
+		return nil, nil
+	}
+
+	accountID, _ := event.Data["account_id"].(string)
+	accountName, _ := event.Data["account_name"].(string)
+	marketID, _ := event.Data["market_id"].(string)
+	side, _ := event.Data["side"].(string)
+	price, _ := event.Data["price"].(float64)
+	shares, _ := event.Data["shares"].(float64)
+
+	if accountID == "" || marketID == "" || side == "" {
+		log.Warn().Msg("Missing required fields in event data")
+		return nil, nil
+	}
+
+	if accounts, ok := strategy.Config["accounts"].([]interface{}); ok && len(accounts) > 0 {
+		if !containsAccount(accounts, accountID, accountName) {
+			return nil, nil
+		}
+	}
+
+	mappings, ok := strategy.Config["mappings"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid mappings config")
+	}
+
+	mapping, reversed, found := findMapping(mappings, event.Platform, marketID)
+	if !found {
+		log.Debug().
+			Str("platform", event.Platform).
+			Str("market", marketID).
+			Msg("Market not mapped, skipping")
+		return nil, nil
+	}
+
+	targetPlatform, _ := mapping["target_platform"].(string)
+	targetMarket, _ := mapping["target_market"].(string)
+	hedgeAccount, _ := mapping["hedge_account"].(string)
+	if reversed {
+		targetPlatform, _ = mapping["source_platform"].(string)
+		targetMarket, _ = mapping["source_market"].(string)
+		hedgeAccount, _ = mapping["source_hedge_account"].(string)
+	}
+
+	if targetMarket == "" || hedgeAccount == "" {
+		return nil, fmt.Errorf("mapping for market %s is missing its target market or hedge account", marketID)
+	}
+
+	hedgeRatio := 1.0
+	if ratio, ok := mapping["hedge_ratio"].(float64); ok && ratio > 0 {
+		hedgeRatio = ratio
+	}
+	if reversed {
+		hedgeRatio = 1 / hedgeRatio
+	}
+
+	priceAdjustment, _ := strategy.Config["price_adjustment"].(float64)
+
+	// Either way the hedge leg is worth about the complement of the fill
+	// price: the opposite outcome of a positively correlated market, or the
+	// same outcome of an inverse one
+	inverse, _ := mapping["inverse"].(bool)
+	hedgeSide := side
+	if !inverse {
+		hedgeSide = "no"
+		if side == "no" {
+			hedgeSide = "yes"
+		}
+	}
+	hedgePrice := clampPrice(1 - price + priceAdjustment)
+	hedgeShares := shares * hedgeRatio
+
+	command := types.Command{
+		Type:      "place_order",
+		Platform:  targetPlatform,
+		AccountID: hedgeAccount,
+		MarketID:  targetMarket,
+		Side:      hedgeSide,
+		Price:     hedgePrice,
+		Shares:    hedgeShares,
+		Metadata: map[string]interface{}{
+			"strategy":         strategy.Name,
+			"original_fill":    event.ID,
+			"original_account": accountID,
+			"original_market":  marketID,
+			"original_side":    side,
+			"reference_price":  price,
+			"hedge_ratio":      hedgeRatio,
+		},
+	}
+
+	log.Info().
+		Str("strategy", strategy.Name).
+		Str("original_market", marketID).
+		Str("hedge_platform", targetPlatform).
+		Str("hedge_market", targetMarket).
+		Str("hedge_side", hedgeSide).
+		Float64("price", hedgePrice).
+		Float64("shares", hedgeShares).
+		Msg("Creating correlation hedge order")
+
+	return []types.Command{command}, nil
+}
+
+// findMapping returns the mapping whose source matches the fill, or whose
+// target matches when the mapping is bidirectional (reversed = true).
+func findMapping(mappings []interface{}, platform, marketID string) (map[string]interface{}, bool, bool) {
+	for _, mappingRaw := range mappings {
+		mapping, ok := mappingRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		sourcePlatform, _ := mapping["source_platform"].(string)
+		sourceMarket, _ := mapping["source_market"].(string)
+		if sourceMarket == marketID && (sourcePlatform == "" || sourcePlatform == platform) {
+			return mapping, false, true
+		}
+
+		if bidirectional, _ := mapping["bidirectional"].(bool); bidirectional {
+			targetPlatform, _ := mapping["target_platform"].(string)
+			targetMarket, _ := mapping["target_market"].(string)
+			if targetMarket == marketID && (targetPlatform == "" || targetPlatform == platform) {
+				return mapping, true, true
+			}
+		}
+	}
+	return nil, false, false
+}
+
+func containsAccount(accounts []interface{}, ids ...string) bool {
+	for _, accountRaw := range accounts {
+		account, _ := accountRaw.(string)
+		for _, id := range ids {
+			if id != "" && account == id {
+				return true
+			}
+		}
+	}
+	return false
+}
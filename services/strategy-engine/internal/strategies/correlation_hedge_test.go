@@ -0,0 +1,121 @@
+package strategies
+
+import (
+	"testing"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategytest"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+func correlationStrategy(mapping map[string]interface{}, config map[string]interface{}) types.Strategy {
+	m := map[string]interface{}{
+		"source_platform": "predict",
+		"source_market":   "123",
+		"target_platform": "polymarket",
+		"target_market":   "0xabc",
+		"hedge_account":   "hedge",
+	}
+	for key, value := range mapping {
+		m[key] = value
+	}
+
+	b := strategytest.NewStrategy("ch", "correlation_hedge").With("mappings", []interface{}{m})
+	for key, value := range config {
+		b.With(key, value)
+	}
+	return b.Build()
+}
+
+func TestCorrelationHedge(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  map[string]interface{}
+		config   map[string]interface{}
+		platform string
+		market   string
+		side     string
+		want     []strategytest.Expect
+	}{
+		{
+			name:     "opposite outcome of the mapped market",
+			platform: "predict", market: "123", side: "yes",
+			want: []strategytest.Expect{{Type: "place_order", Platform: "polymarket", AccountID: "hedge", MarketID: "0xabc", Side: "no", Price: 0.6, Shares: 10,
+				Metadata: map[string]interface{}{"strategy": "ch", "original_market": "123", "hedge_ratio": 1.0}}},
+		},
+		{
+			name:     "inverse mapping buys the same outcome",
+			mapping:  map[string]interface{}{"inverse": true},
+			platform: "predict", market: "123", side: "yes",
+			want: []strategytest.Expect{{Side: "yes", Price: 0.6}},
+		},
+		{
+			name:     "hedge ratio and price adjustment",
+			mapping:  map[string]interface{}{"hedge_ratio": 2},
+			config:   map[string]interface{}{"price_adjustment": 0.02},
+			platform: "predict", market: "123", side: "no",
+			want: []strategytest.Expect{{Side: "yes", Price: 0.62, Shares: 20}},
+		},
+		{
+			name:     "reverse direction of a bidirectional mapping",
+			mapping:  map[string]interface{}{"bidirectional": true, "hedge_ratio": 2, "source_hedge_account": "back"},
+			platform: "polymarket", market: "0xabc", side: "yes",
+			want: []strategytest.Expect{{Platform: "predict", AccountID: "back", MarketID: "123", Side: "no", Shares: 5,
+				Metadata: map[string]interface{}{"hedge_ratio": 0.5}}},
+		},
+		{
+			name:     "target of a one-way mapping",
+			platform: "polymarket", market: "0xabc", side: "yes",
+		},
+		{
+			name:     "unmapped market",
+			platform: "predict", market: "456", side: "yes",
+		},
+		{
+			name:     "other platform",
+			platform: "polymarket", market: "123", side: "yes",
+		},
+		{
+			name:     "account not listed",
+			config:   map[string]interface{}{"accounts": []string{"other"}},
+			platform: "predict", market: "123", side: "yes",
+		},
+		{
+			name:     "account listed",
+			config:   map[string]interface{}{"accounts": []string{"a"}},
+			platform: "predict", market: "123", side: "yes",
+			want: []strategytest.Expect{{AccountID: "hedge"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := strategytest.NewHarness(CorrelationHedgeHandler, nil)
+			stream := strategytest.NewStream(tt.platform)
+
+			produced := h.Run(t, correlationStrategy(tt.mapping, tt.config), stream.Fill("a", tt.market, tt.side, 0.4, 10))
+			strategytest.AssertCommands(t, produced, tt.want...)
+		})
+	}
+}
+
+func TestCorrelationHedgeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy types.Strategy
+		platform string
+		market   string
+	}{
+		{"no mappings", strategytest.NewStrategy("ch", "correlation_hedge").Build(), "predict", "123"},
+		{"no hedge account", correlationStrategy(map[string]interface{}{"hedge_account": ""}, nil), "predict", "123"},
+		{"no hedge account for the reverse direction", correlationStrategy(map[string]interface{}{"bidirectional": true}, nil), "polymarket", "0xabc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := strategytest.NewHarness(CorrelationHedgeHandler, nil)
+			stream := strategytest.NewStream(tt.platform)
+
+			if _, err := h.Step(tt.strategy, stream.Fill("a", tt.market, "yes", 0.4, 10)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
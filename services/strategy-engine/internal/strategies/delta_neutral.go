@@ -2,21 +2,38 @@ package strategies
 
 import (
 	"fmt"
+	"math"
+	"time"
 
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategyctx"
 	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
 	"github.com/rs/zerolog/log"
 )
 
-// DeltaNeutralHandler implements delta neutral strategy
+// NewDeltaNeutralHandler returns the delta neutral handler. The market registry
+// is used to hedge negative-risk markets through their complement basket, and
+// the order book cache to price hedges off the live book (see "pricing").
+func NewDeltaNeutralHandler(sctx *strategyctx.Context) types.StrategyHandler {
+	return strategyctx.Handle(sctx, deltaNeutral)
+}
+
+// defaultBookMaxAge is how old a book may be before hedges fall back to the fill price
+const defaultBookMaxAge = 10 * time.Second
+
+// deltaNeutral implements delta neutral strategy
 // When a fill occurs on one account, immediately place opposite order on paired account
-func DeltaNeutralHandler(event types.Event, strategy types.Strategy) ([]types.Command, error) {
+func deltaNeutral(sc *strategyctx.Strategy, event types.Event) ([]types.Command, error) {
+	strategy := sc.Strategy
+	registry := sc.Markets
+
 	// Only process fill events
 	if event.Type != "fill" && event.Type != "trade_executed" {
+		sc.Explain("%s event: only fills are hedged", event.Type)
 		return nil, nil
 	}
 
-	log.Info().
-		Str("strategy", strategy.Name).
+	sc.Log.Info().
 		Str("event", event.Type).
 		Interface("data", event.Data).
 		Msg("Processing event in Delta Neutral strategy")
@@ -26,11 +43,13 @@ func DeltaNeutralHandler(event types.Event, strategy types.Strategy) ([]types.Co
 	accountName, _ := event.Data["account_name"].(string)
 	marketID, _ := event.Data["market_id"].(string)
 	side, _ := event.Data["side"].(string)
+	outcomeID := fillOutcome(event.Data)
 	price, _ := event.Data["price"].(float64)
 	shares, _ := event.Data["shares"].(float64)
 
 	if accountID == "" || marketID == "" || side == "" {
-		log.Warn().Msg("Missing required fields in event data")
+		sc.Log.Warn().Msg("Missing required fields in event data")
+		sc.Explain("fill without account_id, market_id or side")
 		return nil, nil
 	}
 
@@ -47,6 +66,7 @@ func DeltaNeutralHandler(event types.Event, strategy types.Strategy) ([]types.Co
 
 	// Find paired account
 	var pairedAccountID string
+	var pairConfig map[string]interface{}
 	for _, pairRaw := range pairs {
 		pair, ok := pairRaw.(map[string]interface{})
 		if !ok {
@@ -59,63 +79,455 @@ func DeltaNeutralHandler(event types.Event, strategy types.Strategy) ([]types.Co
 		// Check if this account is the primary in a pair
 		if primaryID == accountID || primaryID == accountName {
 			pairedAccountID = hedgeID
+			pairConfig = pair
 			break
 		}
 	}
 
 	if pairedAccountID == "" {
-		log.Debug().
+		sc.Log.Debug().
 			Str("account", accountID).
 			Msg("Account not found in any pair, skipping")
+		sc.Explain("account %s (%s) is not the primary of any pair", accountID, accountName)
 		return nil, nil
 	}
 
-	// Determine opposite side
-	oppositeSide := "no"
-	if side == "no" {
-		oppositeSide = "yes"
+	// A pair can be switched off on its own while the others keep hedging
+	if enabled, ok := pairConfig["enabled"].(bool); ok && !enabled {
+		sc.Log.Debug().
+			Str("account", accountID).
+			Msg("Pair disabled, skipping")
+		sc.Explain("pair of account %s is disabled", accountID)
+		return nil, nil
+	}
+
+	// Collect the fill for netting; the tick handler hedges the net position
+	nettedFills, netted := event.Data["netted_fills"]
+	if nettingWindow(strategy.Config) > 0 && !netted {
+		sc.Explain("%.4f %s on %s collected for netting", shares, side, marketID)
+		return nil, addToNetting(sc, event, accountID, marketID, side, outcomeID, price, shares)
+	}
+
+	// Where the hedge goes (platform, market, side) depends on the hedge mode
+	hedgeMode, _ := strategy.Config["hedge_mode"].(string)
+	hedgePlatform, hedgeMarket, hedgeSide, err := hedgeTarget(hedgeMode, strategy.Config, event.Platform, targetPlatform, marketID, side)
+	if err != nil {
+		return nil, err
+	}
+	if hedgeMarket == "" {
+		sc.Log.Warn().
+			Str("market", marketID).
+			Msg("No inverse market configured, skipping")
+		sc.Explain("no inverse market configured for %s", marketID)
+		return nil, nil
+	}
+
+	// A specific outcome is hedged with the outcome "hedge_outcomes" maps it to
+	hedgeOutcome, mappedSide := outcomeHedge(strategy.Config, outcomeID)
+	if mappedSide != "" {
+		hedgeSide = mappedSide
+	}
+	if hedgeOutcome == "" && !binarySide(side) {
+		sc.Log.Warn().
+			Str("market", marketID).
+			Str("outcome", outcomeID).
+			Str("side", side).
+			Msg("No hedge outcome configured for multi-outcome fill, skipping")
+		sc.Explain("no hedge_outcomes entry for outcome %q (%s) of %s", outcomeID, side, marketID)
+		return nil, nil
+	}
+
+	// Check if we should apply price adjustment; a pair may override it
+	priceAdjustment, _ := configFloat(strategy.Config, pairConfig, "price_adjustment")
+
+	// Hedge the same payout: a share may pay out differently on each platform
+	filledShares := shares
+	if event.Platform != "" {
+		shares = sc.Units.Convert(event.Platform, hedgePlatform, shares)
+	}
+
+	// Scale the hedge by the hedge ratio, per pair or for the whole strategy
+	ratio := 1.0
+	if r, ok := configFloat(strategy.Config, pairConfig, "hedge_ratio"); ok && r > 0 {
+		ratio = r
+		shares = shares * ratio
+	}
+
+	// Hedge only what the hedge account does not offset already, e.g. after
+	// a manual trade
+	if positionAware(strategy.Config, pairConfig) && event.Type == "fill" {
+		if unhedged, ok := unhedgedShares(sc, event.Platform, accountID, marketID, side, hedgePlatform, pairedAccountID, hedgeMarket, hedgeSide, ratio); ok && unhedged < shares {
+			if unhedged <= 0 {
+				sc.Log.Info().
+					Str("account", accountID).
+					Str("hedge_account", pairedAccountID).
+					Str("hedge_market", hedgeMarket).
+					Msg("Hedge account already offsets the position, skipping")
+				sc.Explain("hedge account %s already offsets the position on %s, no hedge needed", pairedAccountID, marketID)
+				sc.Count("hedges_already_hedged")
+				return nil, nil
+			}
+			sc.Explain("hedge of %.4f shares reduced to the unhedged %.4f", shares, unhedged)
+			sc.Count("hedges_reduced_by_position")
+			shares = unhedged
+		}
+	}
+
+	// Cap the hedge size, per pair or for the whole strategy
+	maxShares, _ := configFloat(strategy.Config, pairConfig, "max_shares")
+	if maxShares > 0 && shares > maxShares {
+		sc.Log.Warn().
+			Str("account", accountID).
+			Float64("shares", shares).
+			Float64("max_shares", maxShares).
+			Msg("Hedge capped at max shares")
+		sc.Explain("hedge of %.4f shares capped at max_shares %.4f", shares, maxShares)
+		sc.Count("hedges_capped")
+		shares = maxShares
 	}
 
-	// Check if we should apply price adjustment
-	priceAdjustment := 0.0
-	if adj, ok := strategy.Config["price_adjustment"].(float64); ok {
-		priceAdjustment = adj
+	// Round down to the lot size and drop hedges too small to be accepted
+	if lot, _ := configFloat(strategy.Config, pairConfig, "lot_size"); lot > 0 {
+		shares = math.Floor(shares/lot+1e-9) * lot
+	}
+	minShares, _ := configFloat(strategy.Config, pairConfig, "min_shares")
+	if shares <= 0 || shares < minShares {
+		sc.Log.Info().
+			Str("account", accountID).
+			Float64("filled_shares", filledShares).
+			Float64("shares", shares).
+			Float64("min_shares", minShares).
+			Msg("Hedge below minimum size, skipping")
+		sc.Explain("hedge of %.4f shares (fill %.4f) below min_shares %.4f or lot size", shares, filledShares, minShares)
+		sc.Count("hedges_below_min_size")
+		return nil, nil
 	}
 
-	hedgePrice := price + priceAdjustment
-	if hedgePrice < 0.01 {
-		hedgePrice = 0.01
+	// Price the hedge with the configured pricing model
+	referencePrice, priced, err := hedgeReferencePrice(strategy.Config, sc.OrderBooks, hedgePlatform, hedgeMarket, hedgeSide, price)
+	if err != nil {
+		return nil, err
 	}
-	if hedgePrice > 0.99 {
-		hedgePrice = 0.99
+	if !priced {
+		sc.Log.Debug().
+			Str("market", hedgeMarket).
+			Msg("No fresh market data, pricing hedge off the fill")
+		sc.Explain("no fresh book for %s, hedge priced off the fill at %.4f", hedgeMarket, price)
+		sc.Count("hedges_without_book")
 	}
+	hedgePrice := clampPrice(referencePrice + priceAdjustment)
 
 	// Create hedge command
 	command := types.Command{
 		Type:      "place_order",
-		Platform:  targetPlatform,
+		Platform:  hedgePlatform,
 		AccountID: pairedAccountID,
-		MarketID:  marketID,
-		Side:      oppositeSide,
+		MarketID:  hedgeMarket,
+		OutcomeID: hedgeOutcome,
+		Side:      hedgeSide,
 		Price:     hedgePrice,
 		Shares:    shares,
 		Metadata: map[string]interface{}{
 			"strategy":        strategy.Name,
 			"original_fill":   event.ID,
 			"original_account": accountID,
+			"filled_at":        fillTime(event).Format(time.RFC3339Nano),
 			"original_side":   side,
+			"reference_price": price,
+			"filled_shares":   filledShares,
 		},
 	}
+	if hedgeMode != "" {
+		command.Metadata["hedge_mode"] = hedgeMode
+	}
+	if hedgeMarket != marketID {
+		command.Metadata["original_market"] = marketID
+	}
+	if outcomeID != "" {
+		command.Metadata["original_outcome"] = outcomeID
+	}
+	if netted {
+		command.Metadata["netted_fills"] = nettedFills
+	}
 
-	log.Info().
-		Str("strategy", strategy.Name).
+	// In a negative-risk group, NO on one market can be hedged by buying YES
+	// on every other market of the group, which is often more liquid.
+	if mode, _ := strategy.Config["neg_risk_hedge"].(string); mode == "complement" && hedgeMarket == marketID && hedgeSide == "no" && hedgeOutcome == "" {
+		if group, ok := registry.NegRiskGroup(marketID); ok {
+			if commands := complementHedge(registry, group, command, priceAdjustment); commands != nil {
+				sc.Explain("hedging NO on %s with a YES basket of %d legs over neg-risk group %s", marketID, len(commands), group.ID)
+				return commands, nil
+			}
+		}
+	}
+
+	// Record what the hedge is expected to cost all-in, fees and slippage included
+	if sc.Costs != nil {
+		command.Metadata["effective_price"] = sc.Costs.EffectivePrice(hedgePlatform, hedgeMarket, hedgePrice, shares)
+	}
+
+	sc.Log.Info().
 		Str("original_account", accountID).
 		Str("hedge_account", pairedAccountID).
 		Str("original_side", side).
-		Str("hedge_side", oppositeSide).
+		Str("hedge_market", hedgeMarket).
+		Str("hedge_side", hedgeSide).
+		Str("hedge_outcome", hedgeOutcome).
 		Float64("price", hedgePrice).
 		Float64("shares", shares).
 		Msg("Creating hedge order")
+	sc.Explain("hedging %.4f %s on %s/%s with account %s at %.4f", shares, hedgeSide, hedgePlatform, hedgeMarket, pairedAccountID, hedgePrice)
 
 	return []types.Command{command}, nil
 }
+
+// positionAware reads "position_aware", per pair or for the whole strategy;
+// it is on unless set to false
+func positionAware(config, pairConfig map[string]interface{}) bool {
+	if aware, ok := pairConfig["position_aware"].(bool); ok {
+		return aware
+	}
+	aware, ok := config["position_aware"].(bool)
+	return aware || !ok
+}
+
+// unhedgedShares returns how much of the primary's position, in hedge shares
+// scaled by the hedge ratio, the hedge account does not offset yet, from the
+// engine's position tracker. The fill being hedged is already part of the
+// primary's position. Hedges still resting are not positions yet, so callers
+// never hedge more than the fill. ok is false when either account has no
+// baseline in the tracker, and the fill is hedged in full.
+func unhedgedShares(sc *strategyctx.Strategy, platform, accountID, marketID, side, hedgePlatform, hedgeAccountID, hedgeMarket, hedgeSide string, ratio float64) (float64, bool) {
+	if sc.Positions == nil || platform == "" {
+		return 0, false
+	}
+	exposure, ok := netPosition(sc, platform, accountID, marketID, side)
+	if !ok {
+		return 0, false
+	}
+	offset, ok := netPosition(sc, hedgePlatform, hedgeAccountID, hedgeMarket, hedgeSide)
+	if !ok {
+		return 0, false
+	}
+	return sc.Units.Convert(platform, hedgePlatform, exposure)*ratio - offset, true
+}
+
+// netPosition returns an account's shares on one side of a market, less its
+// shares on the other side of a yes/no market, and false if the account has
+// no baseline
+func netPosition(sc *strategyctx.Strategy, platform, accountID, marketID, side string) (float64, bool) {
+	positions, ok := sc.Positions.Account(platform, accountID)
+	if !ok {
+		return 0, false
+	}
+	opposite := ""
+	switch side {
+	case "yes":
+		opposite = "no"
+	case "no":
+		opposite = "yes"
+	}
+
+	var net float64
+	for _, position := range positions {
+		if position.MarketID != marketID {
+			continue
+		}
+		switch position.Side {
+		case side:
+			net += position.Shares
+		case opposite:
+			if opposite != "" {
+				net -= position.Shares
+			}
+		}
+	}
+	return net, true
+}
+
+// configFloat reads a number from the pair's config, falling back to the
+// strategy's
+func configFloat(config, pairConfig map[string]interface{}, key string) (float64, bool) {
+	if value, ok := pairConfig[key].(float64); ok {
+		return value, true
+	}
+	value, ok := config[key].(float64)
+	return value, ok
+}
+
+// Hedge modes, set with "hedge_mode":
+//
+//   - "opposite" (default): the opposite outcome of the same market on
+//     target_platform
+//   - "same_platform": the opposite outcome of the same market on the
+//     platform the fill happened on
+//   - "inverse": the same outcome on a negatively correlated market, taken
+//     from "inverse_markets" ({"<market>": "<inverse market>"}, matched both
+//     ways), on target_platform
+const (
+	HedgeModeOpposite     = "opposite"
+	HedgeModeSamePlatform = "same_platform"
+	HedgeModeInverse      = "inverse"
+)
+
+// hedgeTarget returns the platform, market and side a fill is hedged on. The
+// market is empty when an inverse hedge has no market configured.
+func hedgeTarget(mode string, config map[string]interface{}, fillPlatform, targetPlatform, marketID, side string) (string, string, string, error) {
+	oppositeSide := "no"
+	if side == "no" {
+		oppositeSide = "yes"
+	}
+
+	switch mode {
+	case "", HedgeModeOpposite:
+		return targetPlatform, marketID, oppositeSide, nil
+	case HedgeModeSamePlatform:
+		if fillPlatform == "" {
+			fillPlatform = targetPlatform
+		}
+		return fillPlatform, marketID, oppositeSide, nil
+	case HedgeModeInverse:
+		return targetPlatform, inverseMarket(config, marketID), side, nil
+	default:
+		return "", "", "", fmt.Errorf("unknown hedge_mode %q", mode)
+	}
+}
+
+// Multi-outcome markets: a fill may name the outcome token it traded in
+// "outcome_id" (or "token_id"). Its side is then "yes"/"no" for a binary
+// market, or the outcome's name. "hedge_outcomes" maps an outcome to the one
+// that hedges it, either as an outcome ID or as {"outcome_id", "side"}:
+//
+//	"hedge_outcomes": {"<outcome>": "<outcome>", "<outcome>": {"outcome_id": "<outcome>", "side": "<name>"}}
+//
+// Without a mapping, binary fills are hedged by side as before and fills of
+// other outcomes are skipped, since the outcome to buy is unknown.
+
+// fillOutcome returns the outcome token a fill traded, if the fill names it
+func fillOutcome(data map[string]interface{}) string {
+	for _, key := range []string{"outcome_id", "token_id"} {
+		if outcome, _ := data[key].(string); outcome != "" {
+			return outcome
+		}
+	}
+	return ""
+}
+
+// fillTime returns when the exchange filled the order, from "filled_at", or
+// when the fill was published if the fill does not say. Hedges carry it in
+// metadata "filled_at" so the executor can measure the hedge latency.
+func fillTime(event types.Event) time.Time {
+	if filledAt, _ := event.Data["filled_at"].(string); filledAt != "" {
+		if t, err := time.Parse(time.RFC3339Nano, filledAt); err == nil {
+			return t.UTC()
+		}
+	}
+	return event.Timestamp
+}
+
+// binarySide reports whether side is one of the two sides of a yes/no market
+func binarySide(side string) bool {
+	return side == "yes" || side == "no"
+}
+
+// outcomeHedge looks an outcome up in "hedge_outcomes" and returns the
+// outcome that hedges it, with its side when the mapping names one
+func outcomeHedge(config map[string]interface{}, outcomeID string) (string, string) {
+	if outcomeID == "" {
+		return "", ""
+	}
+	outcomes, _ := config["hedge_outcomes"].(map[string]interface{})
+	switch hedge := outcomes[outcomeID].(type) {
+	case string:
+		return hedge, ""
+	case map[string]interface{}:
+		hedgeOutcome, _ := hedge["outcome_id"].(string)
+		side, _ := hedge["side"].(string)
+		return hedgeOutcome, side
+	}
+	return "", ""
+}
+
+// inverseMarket looks a market up in "inverse_markets", in either direction
+func inverseMarket(config map[string]interface{}, marketID string) string {
+	markets, _ := config["inverse_markets"].(map[string]interface{})
+	if inverse, _ := markets[marketID].(string); inverse != "" {
+		return inverse
+	}
+	for market, inverseRaw := range markets {
+		if inverse, _ := inverseRaw.(string); inverse == marketID {
+			return market
+		}
+	}
+	return ""
+}
+
+// complementHedge builds a YES basket over the rest of a neg-risk group, one
+// leg per market, each priced off its last known price. It returns nil if the
+// group's membership is not fully known or a leg has no known price, so the
+// caller falls back to the plain NO hedge: a partial basket does not pay out
+// like NO.
+func complementHedge(
+	registry *markets.Registry,
+	group markets.NegRiskGroup,
+	hedge types.Command,
+	priceAdjustment float64,
+) []types.Command {
+	legs := group.ComplementLegs(hedge.MarketID)
+	if len(legs) == 0 {
+		if !group.Complete {
+			log.Warn().
+				Interface("strategy", hedge.Metadata["strategy"]).
+				Str("neg_risk_market_id", group.ID).
+				Msg("Neg-risk group membership incomplete, falling back to NO hedge")
+		}
+		return nil
+	}
+
+	commands := make([]types.Command, 0, len(legs))
+	for _, leg := range legs {
+		legPrice, ok := registry.Price(leg.MarketID)
+		if !ok {
+			log.Warn().
+				Interface("strategy", hedge.Metadata["strategy"]).
+				Str("market", leg.MarketID).
+				Msg("No price for neg-risk leg, falling back to NO hedge")
+			return nil
+		}
+
+		metadata := make(map[string]interface{}, len(hedge.Metadata)+4)
+		for k, v := range hedge.Metadata {
+			metadata[k] = v
+		}
+		metadata["original_market"] = hedge.MarketID
+		metadata["neg_risk_market_id"] = group.ID
+		metadata["leg_group"] = hedge.Metadata["original_fill"]
+		metadata["legs"] = len(legs)
+
+		cmd := hedge
+		cmd.MarketID = leg.MarketID
+		cmd.Side = leg.Side
+		cmd.Price = clampPrice(legPrice + priceAdjustment)
+		cmd.Metadata = metadata
+		commands = append(commands, cmd)
+	}
+
+	log.Info().
+		Str("hedge_account", hedge.AccountID).
+		Str("neg_risk_market_id", group.ID).
+		Int("legs", len(commands)).
+		Msg("Creating neg-risk complement hedge")
+
+	return commands
+}
+
+// clampPrice keeps a price inside the tradable (0, 1) range
+func clampPrice(price float64) float64 {
+	if price < 0.01 {
+		return 0.01
+	}
+	if price > 0.99 {
+		return 0.99
+	}
+	return price
+}
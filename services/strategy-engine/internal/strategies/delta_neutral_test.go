@@ -0,0 +1,240 @@
+package strategies
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategytest"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+func pair(primary, hedge string, settings ...interface{}) map[string]interface{} {
+	p := map[string]interface{}{"primary": primary, "hedge": hedge}
+	for i := 0; i+1 < len(settings); i += 2 {
+		p[settings[i].(string)] = settings[i+1]
+	}
+	return p
+}
+
+func deltaNeutralStrategy(config map[string]interface{}, pairs ...map[string]interface{}) types.Strategy {
+	b := strategytest.NewStrategy("dn", "delta_neutral")
+	if len(pairs) == 0 {
+		pairs = []map[string]interface{}{pair("a", "b")}
+	}
+	b.With("pairs", pairs)
+	for key, value := range config {
+		b.With(key, value)
+	}
+	return b.Build()
+}
+
+func TestDeltaNeutral(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform string
+		config   map[string]interface{}
+		pairs    []map[string]interface{}
+		fill     func(s *strategytest.Stream) types.Event
+		want     []strategytest.Expect
+	}{
+		{
+			name:     "opposite side on the target platform",
+			platform: "polymarket",
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 10) },
+			want: []strategytest.Expect{{Type: "place_order", Platform: "predict", AccountID: "b", MarketID: "m1", Side: "no", Price: 0.42, Shares: 10,
+				Metadata: map[string]interface{}{"strategy": "dn", "original_fill": "1-0", "original_side": "yes"}}},
+		},
+		{
+			name:     "pair matched by account name",
+			platform: "predict",
+			pairs:    []map[string]interface{}{pair("main", "b")},
+			fill: func(s *strategytest.Stream) types.Event {
+				return strategytest.With(s.Fill("a", "m1", "no", 0.3, 5), map[string]interface{}{"account_name": "main"})
+			},
+			want: []strategytest.Expect{{AccountID: "b", Side: "yes", Shares: 5}},
+		},
+		{
+			name:     "same platform",
+			platform: "polymarket",
+			config:   map[string]interface{}{"hedge_mode": "same_platform"},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 10) },
+			want:     []strategytest.Expect{{Platform: "polymarket", MarketID: "m1", Side: "no", Metadata: map[string]interface{}{"hedge_mode": "same_platform"}}},
+		},
+		{
+			name:     "inverse market, matched both ways",
+			platform: "predict",
+			config:   map[string]interface{}{"hedge_mode": "inverse", "inverse_markets": map[string]string{"m2": "m1"}},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 10) },
+			want:     []strategytest.Expect{{MarketID: "m2", Side: "yes", Metadata: map[string]interface{}{"original_market": "m1"}}},
+		},
+		{
+			name:     "inverse market not configured",
+			platform: "predict",
+			config:   map[string]interface{}{"hedge_mode": "inverse"},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 10) },
+		},
+		{
+			name:     "account not in a pair",
+			platform: "predict",
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("b", "m1", "yes", 0.42, 10) },
+		},
+		{
+			name:     "disabled pair",
+			platform: "predict",
+			pairs:    []map[string]interface{}{pair("a", "b", "enabled", false)},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 10) },
+		},
+		{
+			name:     "capped at max_shares of the pair",
+			platform: "predict",
+			config:   map[string]interface{}{"max_shares": 50},
+			pairs:    []map[string]interface{}{pair("a", "b", "max_shares", 8)},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 10) },
+			want:     []strategytest.Expect{{Shares: 8}},
+		},
+		{
+			name:     "hedge ratio",
+			platform: "predict",
+			config:   map[string]interface{}{"hedge_ratio": 0.5},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 10) },
+			want:     []strategytest.Expect{{Shares: 5}},
+		},
+		{
+			name:     "rounded down to the lot size",
+			platform: "predict",
+			config:   map[string]interface{}{"lot_size": 5},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 12) },
+			want:     []strategytest.Expect{{Shares: 10}},
+		},
+		{
+			name:     "below min_shares",
+			platform: "predict",
+			config:   map[string]interface{}{"min_shares": 20},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.42, 10) },
+		},
+		{
+			name:     "price adjustment is clamped",
+			platform: "predict",
+			config:   map[string]interface{}{"price_adjustment": 0.05},
+			fill:     func(s *strategytest.Stream) types.Event { return s.Fill("a", "m1", "yes", 0.97, 10) },
+			want:     []strategytest.Expect{{Price: 0.99}},
+		},
+		{
+			name:     "not a fill",
+			platform: "predict",
+			fill:     func(s *strategytest.Stream) types.Event { return s.MarketUpdate("m1", 0.4) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sctx := strategytest.NewContext()
+			h := strategytest.NewHarness(NewDeltaNeutralHandler(sctx), sctx)
+			stream := strategytest.NewStream(tt.platform)
+
+			produced := h.Run(t, deltaNeutralStrategy(tt.config, tt.pairs...), tt.fill(stream))
+			strategytest.AssertCommands(t, produced, tt.want...)
+		})
+	}
+}
+
+func TestDeltaNeutralErrors(t *testing.T) {
+	for name, strategy := range map[string]types.Strategy{
+		"no pairs":          strategytest.NewStrategy("dn", "delta_neutral").Build(),
+		"unknown mode":      deltaNeutralStrategy(map[string]interface{}{"hedge_mode": "sideways"}),
+		"unknown pricing":   deltaNeutralStrategy(map[string]interface{}{"pricing": "vwap"}),
+		"netting, no ticks": deltaNeutralStrategy(map[string]interface{}{"netting_window": 5}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			sctx := strategytest.NewContext()
+			h := strategytest.NewHarness(NewDeltaNeutralHandler(sctx), sctx)
+			stream := strategytest.NewStream("predict")
+
+			if _, err := h.Step(strategy, stream.Fill("a", "m1", "yes", 0.42, 10)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestDeltaNeutralAskPricing(t *testing.T) {
+	sctx := strategytest.NewContext()
+	h := strategytest.NewHarness(NewDeltaNeutralHandler(sctx), sctx)
+	strategy := deltaNeutralStrategy(map[string]interface{}{"pricing": "ask", "price_adjustment": 0.01})
+
+	// Books are fresh relative to the wall clock
+	stream := strategytest.NewStream("predict")
+	stream.Now = time.Now()
+
+	// NO is bought at the complement of the best YES bid
+	produced := h.Run(t, strategy,
+		stream.OrderBook("m1", 0.55, 100, 0.58, 100),
+		stream.Fill("a", "m1", "yes", 0.42, 10),
+	)
+	strategytest.AssertCommands(t, produced, strategytest.Expect{Side: "no", Price: 0.46})
+
+	// Without a fresh book the hedge is priced off the fill
+	produced = h.Run(t, strategy, stream.Fill("a", "m2", "yes", 0.42, 10))
+	strategytest.AssertCommands(t, produced, strategytest.Expect{MarketID: "m2", Price: 0.43})
+}
+
+func TestDeltaNeutralPositionAware(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		hedge  float64
+		want   []strategytest.Expect
+	}{
+		{"partly offset", nil, 4, []strategytest.Expect{{Shares: 6}}},
+		{"fully offset", nil, 10, nil},
+		{"switched off", map[string]interface{}{"position_aware": false}, 10, []strategytest.Expect{{Shares: 10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sctx := strategytest.NewContext()
+			// The fill is already part of the primary's position
+			sctx.Positions.Replace("predict", "a", []types.Position{{MarketID: "m1", Side: "yes", Shares: 10}})
+			sctx.Positions.Replace("predict", "b", []types.Position{{MarketID: "m1", Side: "no", Shares: tt.hedge}})
+			h := strategytest.NewHarness(NewDeltaNeutralHandler(sctx), sctx)
+			stream := strategytest.NewStream("predict")
+
+			produced := h.Run(t, deltaNeutralStrategy(tt.config), stream.Fill("a", "m1", "yes", 0.42, 10))
+			strategytest.AssertCommands(t, produced, tt.want...)
+		})
+	}
+}
+
+func TestDeltaNeutralNegRiskComplement(t *testing.T) {
+	strategy := deltaNeutralStrategy(map[string]interface{}{"target_platform": "polymarket", "neg_risk_hedge": "complement"})
+
+	t.Run("complete group", func(t *testing.T) {
+		sctx := strategytest.NewContext()
+		h := strategytest.NewHarness(NewDeltaNeutralHandler(sctx), sctx)
+		stream := strategytest.NewStream("polymarket")
+
+		produced := h.Run(t, strategy,
+			stream.NegRiskMarket("x", "g", 0.3, "x", "y", "z"),
+			stream.NegRiskMarket("y", "g", 0.5, "x", "y", "z"),
+			stream.NegRiskMarket("z", "g", 0.2, "x", "y", "z"),
+			stream.Fill("a", "x", "yes", 0.3, 10),
+		)
+		legs := map[string]interface{}{"original_market": "x", "neg_risk_market_id": "g", "legs": 2}
+		strategytest.AssertCommands(t, produced,
+			strategytest.Expect{AccountID: "b", MarketID: "y", Side: "yes", Price: 0.5, Shares: 10, Metadata: legs},
+			strategytest.Expect{AccountID: "b", MarketID: "z", Side: "yes", Price: 0.2, Shares: 10, Metadata: legs},
+		)
+	})
+
+	t.Run("incomplete group", func(t *testing.T) {
+		sctx := strategytest.NewContext()
+		h := strategytest.NewHarness(NewDeltaNeutralHandler(sctx), sctx)
+		stream := strategytest.NewStream("polymarket")
+
+		// Membership is not declared, so a basket might miss a market
+		produced := h.Run(t, strategy,
+			stream.NegRiskMarket("x", "g", 0.3),
+			stream.NegRiskMarket("y", "g", 0.5),
+			stream.Fill("a", "x", "yes", 0.3, 10),
+		)
+		strategytest.AssertCommands(t, produced, strategytest.Expect{MarketID: "x", Side: "no", Price: 0.3, Shares: 10})
+	})
+}
@@ -0,0 +1,206 @@
+package strategies
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategyctx"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Netting: with "netting_window" (seconds) set, delta neutral does not hedge
+// fills as they arrive. Fills are collected per (account, market) in the
+// strategy state, and once the window since the first one has passed, the
+// OnTick handler hedges only the net position: a YES fill and a NO fill of the
+// same size cancel out and nothing is hedged. Fills of an outcome of a
+// multi-outcome market are not netted against other outcomes: they get a
+// bucket of their own. The strategy needs a "tick_interval" for the windows
+// to be flushed.
+
+// nettingPrefix prefixes the state keys of pending netting buckets
+const nettingPrefix = "netting:"
+
+// nettingBucket is the fills of one (account, market) waiting to be netted
+type nettingBucket struct {
+	AccountID   string            `json:"account_id"`
+	AccountName string            `json:"account_name,omitempty"`
+	MarketID    string            `json:"market_id"`
+	Platform    string            `json:"platform"`
+	Side        string            `json:"side,omitempty"`     // outcome name of a multi-outcome bucket
+	Outcomes    map[string]string `json:"outcomes,omitempty"` // side -> outcome ID of the fills
+	YesShares   float64           `json:"yes_shares"`
+	YesCost     float64           `json:"yes_cost"`
+	NoShares    float64           `json:"no_shares"`
+	NoCost      float64           `json:"no_cost"`
+	Fills       []string          `json:"fills"`
+	FirstFillAt time.Time         `json:"first_fill_at"`
+	FilledAt    time.Time         `json:"filled_at"` // earliest exchange fill time
+}
+
+// nettingWindow reads the "netting_window" key of a strategy config; zero
+// means fills are hedged one by one
+func nettingWindow(config map[string]interface{}) time.Duration {
+	seconds, _ := config["netting_window"].(float64)
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// addToNetting records a fill in its netting bucket
+func addToNetting(sc *strategyctx.Strategy, event types.Event, accountID, marketID, side, outcomeID string, price, shares float64) error {
+	if _, set := sc.Strategy.Config["tick_interval"]; !set {
+		return fmt.Errorf("netting_window needs a tick_interval")
+	}
+
+	key := nettingPrefix + accountID + ":" + marketID
+	if !binarySide(side) {
+		key += ":" + side
+	}
+
+	var bucket nettingBucket
+	_, err := sc.Scope.Update(key, &bucket, func() error {
+		if bucket.FirstFillAt.IsZero() {
+			accountName, _ := event.Data["account_name"].(string)
+			bucket = nettingBucket{
+				AccountID:   accountID,
+				AccountName: accountName,
+				MarketID:    marketID,
+				Platform:    event.Platform,
+				FirstFillAt: time.Now().UTC(),
+			}
+			if !binarySide(side) {
+				bucket.Side = side
+			}
+		}
+		if outcomeID != "" {
+			if bucket.Outcomes == nil {
+				bucket.Outcomes = make(map[string]string)
+			}
+			bucket.Outcomes[side] = outcomeID
+		}
+		if side == "no" {
+			bucket.NoShares += shares
+			bucket.NoCost += shares * price
+		} else {
+			bucket.YesShares += shares
+			bucket.YesCost += shares * price
+		}
+		if filledAt := fillTime(event); bucket.FilledAt.IsZero() || filledAt.Before(bucket.FilledAt) {
+			bucket.FilledAt = filledAt
+		}
+		bucket.Fills = append(bucket.Fills, event.ID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sc.Log.Debug().
+		Str("account", accountID).
+		Str("market", marketID).
+		Float64("yes_shares", bucket.YesShares).
+		Float64("no_shares", bucket.NoShares).
+		Msg("Fill added to netting window")
+	return nil
+}
+
+// NewDeltaNeutralTickHandler returns the delta neutral OnTick handler, which
+// hedges the net position of netting windows that have closed
+func NewDeltaNeutralTickHandler(sctx *strategyctx.Context) types.StrategyHandler {
+	return strategyctx.Handle(sctx, flushNetting)
+}
+
+// flushNetting hedges and removes every netting bucket older than the window
+func flushNetting(sc *strategyctx.Strategy, event types.Event) ([]types.Command, error) {
+	window := nettingWindow(sc.Strategy.Config)
+	if window == 0 {
+		return nil, nil
+	}
+
+	scope := sc.Scope
+	entries, err := scope.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	var commands []types.Command
+	for key, entry := range entries {
+		if !strings.HasPrefix(key, nettingPrefix) {
+			continue
+		}
+
+		var bucket nettingBucket
+		if err := json.Unmarshal(entry.Value, &bucket); err != nil {
+			return commands, fmt.Errorf("state %s: %w", key, err)
+		}
+		if event.Timestamp.Before(bucket.FirstFillAt.Add(window)) {
+			continue
+		}
+		// A fill that lands in between fails the delete and waits for the next tick
+		if err := scope.Delete(key, entry.Version); err != nil {
+			sc.Log.Warn().Err(err).Str("key", key).Msg("Netting window changed, retrying next tick")
+			continue
+		}
+
+		fill, ok := bucket.netFill()
+		if !ok {
+			sc.Log.Info().
+				Str("account", bucket.AccountID).
+				Str("market", bucket.MarketID).
+				Int("fills", len(bucket.Fills)).
+				Msg("Fills netted out, nothing to hedge")
+			sc.Explain("%d fills on %s netted out, nothing to hedge", len(bucket.Fills), bucket.MarketID)
+			continue
+		}
+
+		hedges, err := deltaNeutral(sc, fill)
+		if err != nil {
+			return commands, err
+		}
+		commands = append(commands, hedges...)
+	}
+	return commands, nil
+}
+
+// netFill turns the bucket into a single fill of the net position, or false
+// if the fills cancel out
+func (b nettingBucket) netFill() (types.Event, bool) {
+	side, shares, cost := "yes", b.YesShares-b.NoShares, b.YesCost/b.YesShares
+	if shares < 0 {
+		side, shares, cost = "no", -shares, b.NoCost/b.NoShares
+	}
+	if shares < 1e-9 {
+		return types.Event{}, false
+	}
+	// A multi-outcome bucket only holds fills of its own outcome
+	if b.Side != "" {
+		side = b.Side
+	}
+
+	fill := types.Event{
+		ID:        fmt.Sprintf("netting-%s-%s-%d", b.AccountID, b.MarketID, b.FirstFillAt.UnixNano()),
+		Type:      "fill",
+		Platform:  b.Platform,
+		Timestamp: time.Now().UTC(),
+		Data: map[string]interface{}{
+			"account_id":   b.AccountID,
+			"account_name": b.AccountName,
+			"market_id":    b.MarketID,
+			"side":         side,
+			"price":        cost,
+			"shares":       shares,
+			"netted_fills": b.Fills,
+		},
+	}
+	if outcome := b.Outcomes[side]; outcome != "" {
+		fill.Data["outcome_id"] = outcome
+	}
+	// The hedge latency of a net position runs from its first fill
+	if !b.FilledAt.IsZero() {
+		fill.Data["filled_at"] = b.FilledAt.Format(time.RFC3339Nano)
+	}
+	return fill, true
+}
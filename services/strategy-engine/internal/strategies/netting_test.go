@@ -0,0 +1,55 @@
+package strategies
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategytest"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+func TestNetting(t *testing.T) {
+	tests := []struct {
+		name string
+		no   float64
+		want []strategytest.Expect
+	}{
+		{"fills cancel out", 10, nil},
+		{"net yes is hedged", 4, []strategytest.Expect{{AccountID: "b", MarketID: "m1", Side: "no", Price: 0.4, Shares: 6,
+			Metadata: map[string]interface{}{"netted_fills": []string{"1-0", "2-0"}}}}},
+		{"net no is hedged", 15, []strategytest.Expect{{AccountID: "b", MarketID: "m1", Side: "yes", Price: 0.6, Shares: 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sctx := strategytest.NewContext()
+			h := strategytest.NewHarness(NewDeltaNeutralHandler(sctx), sctx)
+			strategy := deltaNeutralStrategy(map[string]interface{}{"netting_window": 5, "tick_interval": 1})
+			stream := strategytest.NewStream("predict")
+
+			// Fills are collected, not hedged
+			produced := h.Run(t, strategy,
+				stream.Fill("a", "m1", "yes", 0.4, 10),
+				stream.Fill("a", "m1", "no", 0.6, tt.no),
+			)
+			strategytest.AssertNoCommands(t, produced)
+
+			tick := NewDeltaNeutralTickHandler(sctx)
+			// The window opens when the first fill arrives, on the wall clock
+			if commands, err := tick(types.Event{Type: "tick", Timestamp: time.Now()}, strategy); err != nil || len(commands) != 0 {
+				t.Fatalf("tick inside the window: %v, %v", commands, err)
+			}
+
+			closed := types.Event{Type: "tick", Timestamp: time.Now().Add(5*time.Second + time.Second)}
+			commands, err := tick(closed, strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			strategytest.AssertCommands(t, commands, tt.want...)
+
+			// The bucket is gone once hedged
+			if commands, err := tick(closed, strategy); err != nil || len(commands) != 0 {
+				t.Errorf("second tick: %v, %v", commands, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package strategies
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+)
+
+// Hedge pricing models, chosen with "pricing". Each gives a reference price
+// for the hedge side, to which "price_adjustment" is added:
+//
+//   - "fill" (default): the price of the fill being hedged
+//   - "ask" (or "book"): the best ask, crossing the book
+//   - "bid": the best bid, resting at the top of the book
+//   - "mid": the midpoint; a negative adjustment prices under it
+//   - "last_trade": the last trade of the hedge market
+//
+// Book and trade data older than "book_max_age" seconds is ignored, and the
+// hedge falls back to the fill price.
+
+// pricingModel returns the reference price of side on a market, or false if
+// the data it needs is missing or stale
+type pricingModel func(books *orderbook.Cache, platform, marketID, side string, maxAge time.Duration) (float64, bool)
+
+var pricingModels = map[string]pricingModel{
+	"ask":        askPrice,
+	"book":       askPrice,
+	"bid":        bidPrice,
+	"mid":        midPrice,
+	"last_trade": lastTradePrice,
+}
+
+// hedgeReferencePrice prices a hedge with the model named in config, falling
+// back to the fill price. It reports whether the model's data was used.
+func hedgeReferencePrice(config map[string]interface{}, books *orderbook.Cache, platform, marketID, side string, fillPrice float64) (float64, bool, error) {
+	name, _ := config["pricing"].(string)
+	if name == "" || name == "fill" {
+		return fillPrice, true, nil
+	}
+	model, ok := pricingModels[name]
+	if !ok {
+		return 0, false, fmt.Errorf("unknown pricing %q", name)
+	}
+	if books == nil {
+		return fillPrice, false, nil
+	}
+
+	maxAge := defaultBookMaxAge
+	if seconds, ok := config["book_max_age"].(float64); ok && seconds > 0 {
+		maxAge = time.Duration(seconds * float64(time.Second))
+	}
+	if price, ok := model(books, platform, marketID, side, maxAge); ok {
+		return price, true, nil
+	}
+	return fillPrice, false, nil
+}
+
+func askPrice(books *orderbook.Cache, platform, marketID, side string, maxAge time.Duration) (float64, bool) {
+	quote, ok := books.Top(platform, marketID, maxAge)
+	if !ok {
+		return 0, false
+	}
+	price, _, ok := quote.AskFor(side)
+	return price, ok
+}
+
+func bidPrice(books *orderbook.Cache, platform, marketID, side string, maxAge time.Duration) (float64, bool) {
+	quote, ok := books.Top(platform, marketID, maxAge)
+	if !ok {
+		return 0, false
+	}
+	price, _, ok := quote.BidFor(side)
+	return price, ok
+}
+
+func midPrice(books *orderbook.Cache, platform, marketID, side string, maxAge time.Duration) (float64, bool) {
+	quote, ok := books.Top(platform, marketID, maxAge)
+	if !ok {
+		return 0, false
+	}
+	mid, ok := quote.Mid()
+	if !ok {
+		return 0, false
+	}
+	if side == "no" {
+		mid = 1 - mid
+	}
+	return mid, true
+}
+
+func lastTradePrice(books *orderbook.Cache, platform, marketID, side string, maxAge time.Duration) (float64, bool) {
+	trade, ok := books.LastTrade(platform, marketID, maxAge)
+	if !ok {
+		return 0, false
+	}
+	return trade.PriceFor(side), true
+}
@@ -1,14 +1,65 @@
 package strategies
 
-import "github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+import (
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/engine"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategyctx"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
 
 // RegisterAll registers all available strategies
 func RegisterAll(eng *engine.Engine) {
-	// Register Delta Neutral strategy
-	eng.RegisterStrategy("delta_neutral", DeltaNeutralHandler)
-	eng.RegisterStrategy("delta_neutral_v1", DeltaNeutralHandler)
-	
-	// Future strategies can be registered here
-	// eng.RegisterStrategy("arbitrage", ArbitrageHandler)
-	// eng.RegisterStrategy("momentum", MomentumHandler)
+	for name, handler := range Handlers(eng.Context()) {
+		eng.RegisterStrategy(name, handler)
+	}
+	for name, handler := range TickHandlers(eng.Context()) {
+		eng.RegisterTickHandler(name, handler)
+	}
+	for name, handler := range RejectionHandlers(eng.Context()) {
+		eng.RegisterRejectionHandler(name, handler)
+	}
+}
+
+// Handlers returns every strategy handler by strategy type, built on sctx
+func Handlers(sctx *strategyctx.Context) map[string]types.StrategyHandler {
+	// Delta Neutral strategy
+	deltaNeutral := NewDeltaNeutralHandler(sctx)
+
+	return map[string]types.StrategyHandler{
+		"delta_neutral":    deltaNeutral,
+		"delta_neutral_v1": deltaNeutral,
+
+		// Correlation Hedge strategy
+		"correlation_hedge": CorrelationHedgeHandler,
+
+		// Future strategies can be registered here
+		// "arbitrage": ArbitrageHandler,
+		// "momentum":  MomentumHandler,
+	}
+}
+
+// TickHandlers returns the OnTick handlers of strategies that act on a timer
+// (see "tick_interval"), by strategy type
+func TickHandlers(sctx *strategyctx.Context) map[string]types.StrategyHandler {
+	// Delta Neutral flushes its netting windows on ticks
+	deltaNeutralTick := NewDeltaNeutralTickHandler(sctx)
+
+	return map[string]types.StrategyHandler{
+		"delta_neutral":    deltaNeutralTick,
+		"delta_neutral_v1": deltaNeutralTick,
+
+		// Timer-driven strategies can be registered here
+		// "market_maker": MarketMakerTickHandler,
+	}
+}
+
+// RejectionHandlers returns the OnRejected handlers of strategies that retry
+// orders rejected for their price, by strategy type
+func RejectionHandlers(sctx *strategyctx.Context) map[string]types.StrategyHandler {
+	// Delta Neutral reprices rejected hedges
+	deltaNeutralRejected := NewDeltaNeutralRejectionHandler(sctx)
+
+	return map[string]types.StrategyHandler{
+		"delta_neutral":    deltaNeutralRejected,
+		"delta_neutral_v1": deltaNeutralRejected,
+	}
 }
@@ -0,0 +1,126 @@
+package strategies
+
+import (
+	"math"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategyctx"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Hedges rejected for their price are repriced off the live book and sent
+// again, up to "max_reprice_attempts" times per hedge (default 2, 0 turns it
+// off):
+//
+//   - price_out_of_range: priced at the best ask of the hedge side, or pulled
+//     back into the tradable range when there is no fresh book
+//   - crossed_market: priced at the best bid of the hedge side, resting at the
+//     top of the book, or one tick under the rejected price without a book
+//
+// A retry that would go out at the rejected price is not sent. Retries carry
+// "reprice_attempts", "repriced_from" and "reject_reason" in their metadata.
+
+const defaultMaxRepriceAttempts = 2
+
+// NewDeltaNeutralRejectionHandler returns the OnRejected handler of the delta
+// neutral strategy, which reprices rejected hedges
+func NewDeltaNeutralRejectionHandler(sctx *strategyctx.Context) types.StrategyHandler {
+	return strategyctx.Handle(sctx, repriceRejected)
+}
+
+func repriceRejected(sc *strategyctx.Strategy, event types.Event) ([]types.Command, error) {
+	cmd, ok := event.Data["command"].(types.Command)
+	if !ok || cmd.Type != "place_order" {
+		sc.Log.Warn().
+			Str("event_id", event.ID).
+			Msg("Rejection without a place_order command, skipping")
+		sc.Explain("rejection without a place_order command")
+		return nil, nil
+	}
+	reason, _ := event.Data["reason"].(string)
+
+	maxAttempts := defaultMaxRepriceAttempts
+	if value, ok := sc.Strategy.Config["max_reprice_attempts"].(float64); ok {
+		maxAttempts = int(value)
+	}
+	// Metadata went through JSON, so the count is a float64
+	attempts, _ := cmd.Metadata["reprice_attempts"].(float64)
+	if int(attempts) >= maxAttempts {
+		sc.Log.Warn().
+			Str("market", cmd.MarketID).
+			Str("reason", reason).
+			Int("attempts", int(attempts)).
+			Msg("Hedge rejected, out of reprice attempts")
+		sc.Explain("hedge on %s rejected (%s) after %d reprice attempts", cmd.MarketID, reason, int(attempts))
+		return nil, nil
+	}
+
+	price, ok := repricedPrice(reason, sc.Strategy.Config, sc.Context, cmd)
+	if !ok || math.Abs(price-cmd.Price) < 1e-9 {
+		sc.Log.Warn().
+			Str("market", cmd.MarketID).
+			Str("reason", reason).
+			Float64("price", cmd.Price).
+			Msg("Hedge rejected, no better price to retry at")
+		sc.Explain("hedge on %s rejected (%s), no better price than %.4f to retry at", cmd.MarketID, reason, cmd.Price)
+		return nil, nil
+	}
+
+	metadata := make(map[string]interface{}, len(cmd.Metadata)+3)
+	for k, v := range cmd.Metadata {
+		metadata[k] = v
+	}
+	metadata["reprice_attempts"] = attempts + 1
+	metadata["repriced_from"] = cmd.Price
+	metadata["reject_reason"] = reason
+
+	sc.Log.Info().
+		Str("market", cmd.MarketID).
+		Str("reason", reason).
+		Float64("from", cmd.Price).
+		Float64("to", price).
+		Msg("Repricing rejected hedge")
+	sc.Explain("hedge on %s rejected (%s), retrying at %.4f instead of %.4f", cmd.MarketID, reason, price, cmd.Price)
+
+	// The retry is a new order: the rejected one's idempotency key is spent
+	retry := cmd
+	retry.Price = price
+	retry.Metadata = metadata
+	retry.ClientOrderID = ""
+	return []types.Command{retry}, nil
+}
+
+// repricedPrice returns the price to retry a rejected order at, or false if
+// the reason is not one repricing can fix
+func repricedPrice(reason string, config map[string]interface{}, sctx *strategyctx.Context, cmd types.Command) (float64, bool) {
+	maxAge := defaultBookMaxAge
+	if seconds, ok := config["book_max_age"].(float64); ok && seconds > 0 {
+		maxAge = time.Duration(seconds * float64(time.Second))
+	}
+
+	switch reason {
+	case executor.RejectPriceOutOfRange:
+		if sctx.OrderBooks != nil {
+			if price, ok := askPrice(sctx.OrderBooks, cmd.Platform, cmd.MarketID, cmd.Side, maxAge); ok {
+				return clampPrice(price), true
+			}
+		}
+		return clampPrice(cmd.Price), true
+	case executor.RejectCrossedMarket:
+		if sctx.OrderBooks != nil {
+			if price, ok := bidPrice(sctx.OrderBooks, cmd.Platform, cmd.MarketID, cmd.Side, maxAge); ok {
+				return clampPrice(price), true
+			}
+		}
+		tick := 0.01
+		if sctx.Markets != nil {
+			if info, ok := sctx.Markets.Info(cmd.MarketID); ok && info.TickSize > 0 {
+				tick = info.TickSize
+			}
+		}
+		return clampPrice(cmd.Price - tick), true
+	default:
+		return 0, false
+	}
+}
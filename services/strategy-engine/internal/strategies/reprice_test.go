@@ -0,0 +1,72 @@
+package strategies
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/executor"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategytest"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+func rejectedHedge(attempts float64) types.Command {
+	metadata := map[string]interface{}{"strategy": "dn"}
+	if attempts > 0 {
+		metadata["reprice_attempts"] = attempts
+	}
+	return types.Command{
+		Type:          "place_order",
+		Platform:      "predict",
+		AccountID:     "b",
+		MarketID:      "m1",
+		Side:          "no",
+		Price:         0.42,
+		Shares:        10,
+		ClientOrderID: "outbox-7",
+		Metadata:      metadata,
+	}
+}
+
+func TestRepriceRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		reason   string
+		attempts float64
+		book     bool
+		want     []strategytest.Expect
+	}{
+		{"out of range, at the best ask", executor.RejectPriceOutOfRange, 0, true, []strategytest.Expect{{Side: "no", Price: 0.45, Shares: 10,
+			Metadata: map[string]interface{}{"reprice_attempts": 1.0, "repriced_from": 0.42, "reject_reason": executor.RejectPriceOutOfRange}}}},
+		{"out of range, no book, same price", executor.RejectPriceOutOfRange, 0, false, nil},
+		{"crossed, at the best bid", executor.RejectCrossedMarket, 0, true, []strategytest.Expect{{Price: 0.4}}},
+		{"crossed, no book, a tick under", executor.RejectCrossedMarket, 0, false, []strategytest.Expect{{Price: 0.41}}},
+		{"out of attempts", executor.RejectCrossedMarket, 2, true, nil},
+		{"reason repricing does not fix", "insufficient_balance", 0, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sctx := strategytest.NewContext()
+			h := strategytest.NewHarness(NewDeltaNeutralRejectionHandler(sctx), sctx)
+			stream := strategytest.NewStream("predict")
+			stream.Now = time.Now()
+
+			var events []types.Event
+			if tt.book {
+				// NO is bid at 0.40 and offered at 0.45
+				events = append(events, stream.OrderBook("m1", 0.55, 100, 0.6, 100))
+			}
+			events = append(events, stream.Event("order_rejected", map[string]interface{}{
+				"command": rejectedHedge(tt.attempts),
+				"reason":  tt.reason,
+			}))
+
+			produced := h.Run(t, deltaNeutralStrategy(nil), events...)
+			strategytest.AssertCommands(t, produced, tt.want...)
+			for _, cmd := range produced {
+				if cmd.ClientOrderID != "" {
+					t.Errorf("retry reuses the idempotency key %q", cmd.ClientOrderID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package strategyctx gives strategy handlers read access to the engine's
+// live market data and positions, and their durable state, without depending
+// on the engine itself. Handlers built with Handle get the context of their
+// strategy on every call (see Strategy).
+package strategyctx
+
+import (
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/costs"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/flags"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/metrics"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/positions"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/state"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/units"
+)
+
+// Context is shared by all strategies of an engine. Its members are safe for
+// concurrent use.
+type Context struct {
+	// Markets holds market metadata and last prices from market_update events
+	Markets *markets.Registry
+
+	// Mappings pairs Predict markets with Polymarket conditions; Counterpart
+	// finds the matching market and side on the other platform
+	Mappings *markets.Mappings
+
+	// OrderBooks holds the top of book per market, from orderbook_events and
+	// the books the engine polls from the account services
+	OrderBooks *orderbook.Cache
+
+	// Costs models fees and slippage; EffectivePrice gives the all-in price
+	// of crossing the book. A nil model has no costs.
+	Costs *costs.Model
+
+	// Units converts sizes between the platforms' shares, 1 USD contracts
+	// and USD notional. A nil converter treats every share as 1 USD.
+	Units *units.Converter
+
+	// Positions is the engine's view of the positions of managed accounts,
+	// the venue baseline moved by fills. A nil tracker knows none.
+	Positions *positions.Tracker
+
+	// State keeps each strategy's key-value state across restarts; handlers
+	// use State.For(strategy.ID). A nil store keeps nothing.
+	State *state.Store
+
+	// Explainer records the reasons of strategies in explain mode (see
+	// Strategy.Explain). A nil explainer records nothing.
+	Explainer Explainer
+
+	// Metrics holds the custom counters and gauges of strategies, exported
+	// on /metrics (see Strategy.Count). A nil registry drops them.
+	Metrics *metrics.Registry
+
+	// Flags are the feature flags gating risky code paths (see
+	// Strategy.Feature). A nil set has every flag off.
+	Flags *flags.Set
+}
+
+// Explainer collects why strategies did or did not act on events
+type Explainer interface {
+	// Explaining reports whether the strategy runs in explain mode
+	Explaining(strategy types.Strategy) bool
+
+	// Explain adds a reason to what the strategy made of the event
+	Explain(strategy types.Strategy, event types.Event, reason string)
+}
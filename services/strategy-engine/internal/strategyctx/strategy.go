@@ -0,0 +1,106 @@
+package strategyctx
+
+import (
+	"fmt"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/state"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Strategy is the context of one strategy handling one event: the shared
+// services, plus the strategy's own state and a logger tagged with it. Its
+// log entries carry the "strategy" field, so strategy_log_levels apply.
+type Strategy struct {
+	*Context
+
+	Strategy types.Strategy
+	Event    types.Event
+	Log      zerolog.Logger
+
+	// Scope is the strategy's key-value state, State.For(Strategy.ID)
+	Scope *state.Scope
+}
+
+// For returns the context of one strategy handling event
+func (c *Context) For(strategy types.Strategy, event types.Event) *Strategy {
+	return &Strategy{
+		Context:  c,
+		Strategy: strategy,
+		Event:    event,
+		Log: log.With().
+			Str("strategy", strategy.Name).
+			Str("strategy_id", strategy.ID).
+			Logger(),
+		Scope: c.State.For(strategy.ID),
+	}
+}
+
+// Position returns the engine's view of a position of a managed account, and
+// false if the account has no baseline or holds nothing on that side
+func (s *Strategy) Position(platform, accountID, marketID, side string) (types.Position, bool) {
+	if s.Positions == nil {
+		return types.Position{}, false
+	}
+	positions, ok := s.Positions.Account(platform, accountID)
+	if !ok {
+		return types.Position{}, false
+	}
+	for _, position := range positions {
+		if position.MarketID == marketID && position.Side == side {
+			return position, true
+		}
+	}
+	return types.Position{}, false
+}
+
+// Explain records why the strategy did or did not act on the event, when it
+// runs in explain mode. Handlers call it where they skip an event or decide
+// an order, with the values that decided it.
+func (s *Strategy) Explain(format string, args ...interface{}) {
+	if s.Explainer == nil || !s.Explainer.Explaining(s.Strategy) {
+		return
+	}
+	s.Explainer.Explain(s.Strategy, s.Event, fmt.Sprintf(format, args...))
+}
+
+// Count adds one to a custom counter of the strategy, e.g.
+// sc.Count("spread_too_wide_skips"). It is exported on /metrics as
+// strategy_custom_<name>_total with the strategy's name as the "strategy"
+// label. Tags are extra label key-value pairs; keep their values few.
+func (s *Strategy) Count(name string, tags ...string) {
+	s.Metrics.Add(s.Strategy.Name, name, 1, tags...)
+}
+
+// AddCount adds delta to a custom counter of the strategy, see Count
+func (s *Strategy) AddCount(name string, delta float64, tags ...string) {
+	s.Metrics.Add(s.Strategy.Name, name, delta, tags...)
+}
+
+// Feature reports whether a feature flag is on for a market, so a handler can
+// try a new code path (a pricing model, a check) on a share of markets first:
+//
+//	if sc.Feature("pricing.v2", marketID) { ... }
+//
+// Unknown flags are off.
+func (s *Strategy) Feature(name, marketID string) bool {
+	return s.Flags.Enabled(name, marketID)
+}
+
+// SetGauge sets a custom gauge of the strategy, exported on /metrics as
+// strategy_custom_<name>, see Count
+func (s *Strategy) SetGauge(name string, value float64, tags ...string) {
+	s.Metrics.Set(s.Strategy.Name, name, value, tags...)
+}
+
+// Handler is a strategy handler that receives its strategy's context
+type Handler func(sc *Strategy, event types.Event) ([]types.Command, error)
+
+// Handle adapts a Handler to the engine's handler signature, building the
+// strategy's context on every call
+func Handle(c *Context, handler Handler) types.StrategyHandler {
+	return func(event types.Event, strategy types.Strategy) ([]types.Command, error) {
+		return handler(c.For(strategy, event), event)
+	}
+}
@@ -0,0 +1,83 @@
+package strategytest
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// priceTolerance absorbs float noise in computed prices and sizes
+const priceTolerance = 1e-9
+
+// Expect describes a command. Zero-valued fields are not checked; Metadata
+// checks only the listed keys.
+type Expect struct {
+	Type      string
+	Platform  string
+	AccountID string
+	MarketID  string
+	Side      string
+	Price     float64
+	Shares    float64
+	Metadata  map[string]interface{}
+}
+
+// AssertNoCommands fails if any command was produced
+func AssertNoCommands(t testing.TB, commands []types.Command) {
+	t.Helper()
+
+	if len(commands) != 0 {
+		t.Fatalf("expected no commands, got %d: %+v", len(commands), commands)
+	}
+}
+
+// AssertCommands checks that commands match expected one to one, in order
+func AssertCommands(t testing.TB, commands []types.Command, expected ...Expect) {
+	t.Helper()
+
+	if len(commands) != len(expected) {
+		t.Fatalf("expected %d commands, got %d: %+v", len(expected), len(commands), commands)
+	}
+	for i, want := range expected {
+		AssertCommand(t, commands[i], want)
+	}
+}
+
+// AssertCommand checks one command against the expectation
+func AssertCommand(t testing.TB, cmd types.Command, want Expect) {
+	t.Helper()
+
+	checkString(t, "type", cmd.Type, want.Type)
+	checkString(t, "platform", cmd.Platform, want.Platform)
+	checkString(t, "account_id", cmd.AccountID, want.AccountID)
+	checkString(t, "market_id", cmd.MarketID, want.MarketID)
+	checkString(t, "side", cmd.Side, want.Side)
+
+	if want.Price != 0 && math.Abs(cmd.Price-want.Price) > priceTolerance {
+		t.Errorf("price: got %v, want %v", cmd.Price, want.Price)
+	}
+	if want.Shares != 0 && math.Abs(cmd.Shares-want.Shares) > priceTolerance {
+		t.Errorf("shares: got %v, want %v", cmd.Shares, want.Shares)
+	}
+
+	for key, value := range want.Metadata {
+		got, ok := cmd.Metadata[key]
+		if !ok {
+			t.Errorf("metadata %q: missing, want %v", key, value)
+			continue
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("metadata %q: got %v, want %v", key, got, value)
+		}
+	}
+}
+
+func checkString(t testing.TB, field, got, want string) {
+	t.Helper()
+
+	if want != "" && got != want {
+		t.Errorf("%s: got %q, want %q", field, got, want)
+	}
+}
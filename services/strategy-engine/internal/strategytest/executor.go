@@ -0,0 +1,132 @@
+package strategytest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/markets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/metrics"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/orderbook"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/positions"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/state"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/strategyctx"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Executor captures commands instead of sending them. Fail, if set, decides
+// per command whether execution fails.
+type Executor struct {
+	Fail func(cmd types.Command) error
+
+	mu       sync.Mutex
+	commands []types.Command
+}
+
+func (e *Executor) ExecuteCommands(ctx context.Context, commands []types.Command) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, cmd := range commands {
+		if e.Fail != nil {
+			if err := e.Fail(cmd); err != nil {
+				return err
+			}
+		}
+		e.commands = append(e.commands, cmd)
+	}
+	return nil
+}
+
+// Commands returns the commands executed so far
+func (e *Executor) Commands() []types.Command {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]types.Command(nil), e.commands...)
+}
+
+// Reset forgets captured commands
+func (e *Executor) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.commands = nil
+}
+
+// Harness feeds events to a handler the way the engine does: order book
+// events only refresh the book cache, market_update events update the market
+// registry before the handler runs, and commands go to the in-memory executor.
+type Harness struct {
+	Handler  types.StrategyHandler
+	Context  *strategyctx.Context
+	Executor *Executor
+}
+
+// NewContext returns an empty strategy context for building handlers. Seed
+// positions with Context.Positions.Replace.
+func NewContext() *strategyctx.Context {
+	return &strategyctx.Context{
+		Markets:    markets.NewRegistry(),
+		Mappings:   markets.NewMappings(),
+		OrderBooks: orderbook.NewCache(),
+		Positions:  positions.NewTracker(),
+		State:      state.NewStore(state.NewMemory()),
+		Metrics:    metrics.NewRegistry(),
+	}
+}
+
+// NewHarness wires a handler to an in-memory executor. sctx should be the
+// context the handler was built with; nil creates an empty one.
+func NewHarness(handler types.StrategyHandler, sctx *strategyctx.Context) *Harness {
+	if sctx == nil {
+		sctx = NewContext()
+	}
+	return &Harness{
+		Handler:  handler,
+		Context:  sctx,
+		Executor: &Executor{},
+	}
+}
+
+// Run processes events in order and fails the test on a handler or execution
+// error. It returns the commands produced by these events.
+func (h *Harness) Run(t testing.TB, strategy types.Strategy, events ...types.Event) []types.Command {
+	t.Helper()
+
+	var produced []types.Command
+	for _, event := range events {
+		commands, err := h.Step(strategy, event)
+		if err != nil {
+			t.Fatalf("event %s (%s): %v", event.ID, event.Type, err)
+		}
+		produced = append(produced, commands...)
+	}
+	return produced
+}
+
+// Step processes one event and returns the handler's commands or its error
+func (h *Harness) Step(strategy types.Strategy, event types.Event) ([]types.Command, error) {
+	if h.Context.OrderBooks.Update(event) {
+		return nil, nil
+	}
+	h.Context.Markets.Update(event)
+
+	commands, err := h.Handler(event, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 {
+		return nil, nil
+	}
+
+	if err := h.Executor.ExecuteCommands(context.Background(), commands); err != nil {
+		return commands, err
+	}
+	return commands, nil
+}
+
+// Commands returns everything executed through the harness
+func (h *Harness) Commands() []types.Command {
+	return h.Executor.Commands()
+}
@@ -0,0 +1,62 @@
+package strategytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// StrategyBuilder builds a types.Strategy as it would be loaded from the
+// database
+type StrategyBuilder struct {
+	strategy types.Strategy
+}
+
+func NewStrategy(name, strategyType string) *StrategyBuilder {
+	return &StrategyBuilder{strategy: types.Strategy{
+		ID:        name + "-id",
+		Name:      name,
+		Type:      strategyType,
+		Active:    true,
+		Config:    map[string]interface{}{},
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+}
+
+// With sets a config key
+func (b *StrategyBuilder) With(key string, value interface{}) *StrategyBuilder {
+	b.strategy.Config[key] = value
+	return b
+}
+
+// Accounts sets the strategy's active accounts
+func (b *StrategyBuilder) Accounts(ids ...string) *StrategyBuilder {
+	b.strategy.ActiveAccounts = ids
+	return b
+}
+
+// Shadow puts the strategy in shadow mode
+func (b *StrategyBuilder) Shadow() *StrategyBuilder {
+	b.strategy.Shadow = true
+	return b
+}
+
+// Build returns the strategy. The config is round-tripped through JSON, so
+// handlers see float64 numbers and []interface{} slices exactly as they do
+// for JSONB configs; a config that cannot be encoded panics.
+func (b *StrategyBuilder) Build() types.Strategy {
+	data, err := json.Marshal(b.strategy.Config)
+	if err != nil {
+		panic(fmt.Sprintf("strategytest: invalid strategy config: %v", err))
+	}
+
+	strategy := b.strategy
+	strategy.Config = nil
+	if err := json.Unmarshal(data, &strategy.Config); err != nil {
+		panic(fmt.Sprintf("strategytest: invalid strategy config: %v", err))
+	}
+	return strategy
+}
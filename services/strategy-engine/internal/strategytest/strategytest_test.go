@@ -0,0 +1,226 @@
+package strategytest
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// echoHandler buys what every fill sold, at the fill price
+func echoHandler(event types.Event, strategy types.Strategy) ([]types.Command, error) {
+	if event.Type != "fill" {
+		return nil, nil
+	}
+	market, _ := event.Data["market_id"].(string)
+	if market == "broken" {
+		return nil, errors.New("broken market")
+	}
+	price, _ := event.Data["price"].(float64)
+	shares, _ := event.Data["shares"].(float64)
+	return []types.Command{{
+		Type:     "place_order",
+		Platform: event.Platform,
+		MarketID: market,
+		Side:     "no",
+		Price:    price,
+		Shares:   shares,
+		Metadata: map[string]interface{}{"strategy": strategy.Name},
+	}}, nil
+}
+
+func TestStream(t *testing.T) {
+	stream := NewStream("predict")
+	first := stream.Fill("acc", "m1", "yes", 0.4, 10)
+	second := stream.MarketUpdate("m1", 0.45)
+
+	if first.ID != "1-0" || second.ID != "2-0" {
+		t.Errorf("IDs = %s, %s, want 1-0, 2-0", first.ID, second.ID)
+	}
+	if got := second.Timestamp.Sub(first.Timestamp); got != time.Second {
+		t.Errorf("events are %s apart, want 1s", got)
+	}
+	if first.Platform != "predict" || first.Data["fill_id"] != "fill-1" || first.Data["shares"] != 10.0 {
+		t.Errorf("fill = %+v", first)
+	}
+
+	with := With(first, map[string]interface{}{"account_name": "main"})
+	if with.Data["account_name"] != "main" || with.Data["market_id"] != "m1" {
+		t.Errorf("With = %+v", with.Data)
+	}
+	if _, ok := first.Data["account_name"]; ok {
+		t.Error("With modified the original event")
+	}
+}
+
+func TestNegRiskMarket(t *testing.T) {
+	stream := NewStream("polymarket")
+	sctx := NewContext()
+
+	sctx.Markets.Update(stream.NegRiskMarket("a", "g", 0.3))
+	if group, ok := sctx.Markets.NegRiskGroup("a"); !ok || group.Complete {
+		t.Errorf("group without members = %+v, %t, want incomplete", group, ok)
+	}
+
+	sctx.Markets.Update(stream.NegRiskMarket("a", "g", 0.3, "a", "b"))
+	sctx.Markets.Update(stream.NegRiskMarket("b", "g", 0.7, "a", "b"))
+	if group, ok := sctx.Markets.NegRiskGroup("a"); !ok || !group.Complete {
+		t.Errorf("group with every member = %+v, %t, want complete", group, ok)
+	}
+}
+
+func TestStrategyBuilder(t *testing.T) {
+	strategy := NewStrategy("dn", "delta_neutral").
+		With("max_shares", 50).
+		With("pairs", []map[string]string{{"primary": "a", "hedge": "b"}}).
+		Accounts("a", "b").
+		Shadow().
+		Build()
+
+	if strategy.ID != "dn-id" || !strategy.Active || !strategy.Shadow || len(strategy.ActiveAccounts) != 2 {
+		t.Errorf("strategy = %+v", strategy)
+	}
+	// Configs look as they do decoded from JSONB
+	if _, ok := strategy.Config["max_shares"].(float64); !ok {
+		t.Errorf("max_shares is %T, want float64", strategy.Config["max_shares"])
+	}
+	pairs, ok := strategy.Config["pairs"].([]interface{})
+	if !ok || len(pairs) != 1 {
+		t.Fatalf("pairs = %#v, want []interface{}", strategy.Config["pairs"])
+	}
+	if _, ok := pairs[0].(map[string]interface{}); !ok {
+		t.Errorf("pair is %T, want map[string]interface{}", pairs[0])
+	}
+}
+
+func TestHarness(t *testing.T) {
+	h := NewHarness(echoHandler, nil)
+	strategy := NewStrategy("echo", "echo").Build()
+	stream := NewStream("predict")
+
+	produced := h.Run(t, strategy,
+		stream.OrderBook("m1", 0.39, 100, 0.41, 100),
+		stream.MarketUpdate("m1", 0.4),
+		stream.Fill("acc", "m1", "yes", 0.4, 10),
+	)
+
+	AssertCommands(t, produced, Expect{Type: "place_order", Platform: "predict", MarketID: "m1", Side: "no", Price: 0.4, Shares: 10,
+		Metadata: map[string]interface{}{"strategy": "echo"}})
+	AssertCommands(t, h.Commands(), Expect{MarketID: "m1"})
+
+	// Book events refresh the cache without reaching the handler
+	if quote, ok := h.Context.OrderBooks.Top("predict", "m1", 0); !ok || quote.BidPrice != 0.39 || quote.AskPrice != 0.41 {
+		t.Errorf("book = %+v, %t", quote, ok)
+	}
+	if price, ok := h.Context.Markets.Price("m1"); !ok || price != 0.4 {
+		t.Errorf("market price = %v, %t, want 0.4", price, ok)
+	}
+
+	h.Executor.Reset()
+	AssertNoCommands(t, h.Commands())
+}
+
+func TestHarnessErrors(t *testing.T) {
+	h := NewHarness(echoHandler, nil)
+	strategy := NewStrategy("echo", "echo").Build()
+	stream := NewStream("predict")
+
+	if _, err := h.Step(strategy, stream.Fill("acc", "broken", "yes", 0.4, 10)); err == nil {
+		t.Error("handler error was not returned")
+	}
+
+	h.Executor.Fail = func(cmd types.Command) error {
+		if cmd.MarketID == "m2" {
+			return errors.New("rejected")
+		}
+		return nil
+	}
+	commands, err := h.Step(strategy, stream.Fill("acc", "m2", "yes", 0.4, 10))
+	if err == nil || len(commands) != 1 {
+		t.Errorf("failed execution: got %d commands, %v", len(commands), err)
+	}
+	AssertNoCommands(t, h.Commands())
+}
+
+func TestExecutor(t *testing.T) {
+	var e Executor
+	if err := e.ExecuteCommands(context.Background(), []types.Command{{Type: "cancel_order", MarketID: "o1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := e.Commands()
+	commands[0].MarketID = "changed"
+	if e.Commands()[0].MarketID != "o1" {
+		t.Error("Commands returned the executor's own slice")
+	}
+}
+
+// recorder captures the failures of an assertion instead of failing the test
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()                                   {}
+func (r *recorder) Errorf(format string, args ...interface{}) { r.failed = true }
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failed = true
+	runtime.Goexit()
+}
+
+// fails reports whether an assertion fails
+func fails(t *testing.T, assert func(tb testing.TB)) bool {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert(r)
+	}()
+	<-done
+	return r.failed
+}
+
+func TestAssertCommand(t *testing.T) {
+	cmd := types.Command{
+		Type:      "place_order",
+		Platform:  "predict",
+		AccountID: "b",
+		MarketID:  "m1",
+		Side:      "no",
+		Price:     0.1 + 0.2,
+		Shares:    10,
+		Metadata:  map[string]interface{}{"original_fill": "1-0"},
+	}
+
+	tests := []struct {
+		name     string
+		want     Expect
+		failures bool
+	}{
+		{"zero expectation", Expect{}, false},
+		{"match with float noise", Expect{Price: 0.3, Shares: 10, Side: "no"}, false},
+		{"metadata", Expect{Metadata: map[string]interface{}{"original_fill": "1-0"}}, false},
+		{"wrong side", Expect{Side: "yes"}, true},
+		{"wrong price", Expect{Price: 0.31}, true},
+		{"wrong metadata", Expect{Metadata: map[string]interface{}{"original_fill": "2-0"}}, true},
+		{"missing metadata", Expect{Metadata: map[string]interface{}{"netted_fills": "x"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed := fails(t, func(tb testing.TB) { AssertCommand(tb, cmd, tt.want) })
+			if failed != tt.failures {
+				t.Errorf("assertion failed: %t, want %t", failed, tt.failures)
+			}
+		})
+	}
+
+	if !fails(t, func(tb testing.TB) { AssertCommands(tb, []types.Command{cmd}, Expect{}, Expect{}) }) {
+		t.Error("AssertCommands passed with a missing command")
+	}
+	if !fails(t, func(tb testing.TB) { AssertNoCommands(tb, []types.Command{cmd}) }) {
+		t.Error("AssertNoCommands passed with a command")
+	}
+}
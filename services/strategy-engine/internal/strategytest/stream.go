@@ -0,0 +1,122 @@
+// Package strategytest provides fixtures for testing strategy handlers without
+// Redis or Postgres: synthetic event streams, a strategy builder, an in-memory
+// executor that captures commands, a harness wiring them together, and
+// assertion helpers.
+//
+//	sctx := strategytest.NewContext()
+//	h := strategytest.NewHarness(strategies.NewDeltaNeutralHandler(sctx), sctx)
+//	strategy := strategytest.NewStrategy("dn", "delta_neutral").
+//		With("pairs", []interface{}{map[string]interface{}{"primary": "a", "hedge": "b"}}).
+//		Build()
+//
+//	stream := strategytest.NewStream("predict")
+//	h.Run(t, strategy, stream.Fill("a", "m1", "yes", 0.42, 10))
+//	strategytest.AssertCommands(t, h.Commands(), strategytest.Expect{AccountID: "b", Side: "no"})
+package strategytest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+)
+
+// Stream builds a deterministic sequence of events for one platform. Each
+// event gets the next ID and a timestamp one Step after the previous one.
+type Stream struct {
+	Platform string
+	Now      time.Time
+	Step     time.Duration
+
+	seq int
+}
+
+// NewStream starts a stream at a fixed time so test output is reproducible
+func NewStream(platform string) *Stream {
+	return &Stream{
+		Platform: platform,
+		Now:      time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Step:     time.Second,
+	}
+}
+
+// Event returns the next event with the given type and data. Numbers in data
+// should be float64, as they would be after decoding from the event bus.
+func (s *Stream) Event(eventType string, data map[string]interface{}) types.Event {
+	s.seq++
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	event := types.Event{
+		ID:        fmt.Sprintf("%d-0", s.seq),
+		Type:      eventType,
+		Platform:  s.Platform,
+		Timestamp: s.Now,
+		Data:      data,
+	}
+	s.Now = s.Now.Add(s.Step)
+	return event
+}
+
+// Fill returns a fill event with a unique fill_id
+func (s *Stream) Fill(accountID, marketID, side string, price, shares float64) types.Event {
+	return s.Event("fill", map[string]interface{}{
+		"fill_id":    fmt.Sprintf("fill-%d", s.seq+1),
+		"order_id":   fmt.Sprintf("order-%d", s.seq+1),
+		"account_id": accountID,
+		"market_id":  marketID,
+		"side":       side,
+		"price":      price,
+		"shares":     shares,
+	})
+}
+
+// MarketUpdate returns a market_update event carrying the YES price
+func (s *Stream) MarketUpdate(marketID string, yesPrice float64) types.Event {
+	return s.Event("market_update", map[string]interface{}{
+		"market_id": marketID,
+		"yes_price": yesPrice,
+	})
+}
+
+// NegRiskMarket returns a market_update event placing the market in a
+// negative-risk group. Passing members, every market ID of the group,
+// declares the group complete.
+func (s *Stream) NegRiskMarket(marketID, groupID string, yesPrice float64, members ...string) types.Event {
+	data := map[string]interface{}{
+		"market_id":          marketID,
+		"yes_price":          yesPrice,
+		"neg_risk":           true,
+		"neg_risk_market_id": groupID,
+	}
+	if len(members) > 0 {
+		ids := make([]interface{}, len(members))
+		for i, id := range members {
+			ids[i] = id
+		}
+		data["neg_risk_markets"] = ids
+	}
+	return s.Event("market_update", data)
+}
+
+// OrderBook returns an orderbook_snapshot event with one level per side
+func (s *Stream) OrderBook(marketID string, bid, bidSize, ask, askSize float64) types.Event {
+	return s.Event("orderbook_snapshot", map[string]interface{}{
+		"market_id": marketID,
+		"bids":      []interface{}{[]interface{}{bid, bidSize}},
+		"asks":      []interface{}{[]interface{}{ask, askSize}},
+	})
+}
+
+// With returns a copy of the event with extra data fields set
+func With(event types.Event, fields map[string]interface{}) types.Event {
+	data := make(map[string]interface{}, len(event.Data)+len(fields))
+	for k, v := range event.Data {
+		data[k] = v
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+	event.Data = data
+	return event
+}
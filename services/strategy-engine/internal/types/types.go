@@ -13,14 +13,32 @@ type Event struct {
 
 // Command represents a command to execute
 type Command struct {
-	Type      string                 `json:"type"`      // place_order, cancel_order
-	Platform  string                 `json:"platform"`  // predict, polymarket
-	AccountID string                 `json:"account_id"`
-	MarketID  string                 `json:"market_id"`
-	Side      string                 `json:"side"`      // yes, no
-	Price     float64                `json:"price"`
-	Shares    float64                `json:"shares"`
-	Metadata  map[string]interface{} `json:"metadata"`
+	Type        string                 `json:"type"`     // place_order, amend_order, cancel_order
+	Platform    string                 `json:"platform"` // predict, polymarket
+	AccountID   string                 `json:"account_id"`
+	Accounts    []string               `json:"accounts,omitempty"` // fan-out: the command for each account, replacing AccountID
+	Weights     []float64              `json:"weights,omitempty"`  // fan-out: size split by weight, evenly without
+	MarketID    string                 `json:"market_id"`
+	OutcomeID   string                 `json:"outcome_id,omitempty"` // outcome token; picks the outcome of multi-outcome markets
+	Side        string                 `json:"side"`                 // yes, no, or the outcome name
+	Action      string                 `json:"action,omitempty"`     // buy (default), sell, open, close
+	Price       float64                `json:"price"`
+	Shares      float64                `json:"shares"`
+	Size        float64                `json:"size,omitempty"`          // in SizeUnit; replaces Shares when set
+	SizeUnit    string                 `json:"size_unit,omitempty"`     // shares (default), contracts, usd
+	OrderType   string                 `json:"order_type,omitempty"`    // limit (default), market, post_only
+	TimeInForce string                 `json:"time_in_force,omitempty"` // GTC (default), IOC, FOK, GTD
+	ExpiresAt   *time.Time             `json:"expires_at,omitempty"`    // the engine cancels the order once past
+	Deadline    *time.Time             `json:"deadline,omitempty"`      // not sent once past; bounds the request
+	Metadata    map[string]interface{} `json:"metadata"`
+
+	ClientOrderID string `json:"client_order_id,omitempty"` // idempotency key; the account service places one order per key
+}
+
+// PlacesOrder reports whether the command puts an order on the book: a
+// place_order, or an amend_order replacing one
+func (c Command) PlacesOrder() bool {
+	return c.Type == "place_order" || c.Type == "amend_order"
 }
 
 // Strategy represents a trading strategy
@@ -31,10 +49,48 @@ type Strategy struct {
 	Active          bool                   `json:"active"`
 	Config          map[string]interface{} `json:"config"`
 	ActiveAccounts  []string               `json:"active_accounts"`
+	Shadow          bool                   `json:"shadow"` // commands are recorded, not executed
+	ShadowReason    string                 `json:"shadow_reason,omitempty"`
+	DryRun          bool                   `json:"dry_run"` // orders sent unconfirmed while the engine trades live
+	Priority        int                    `json:"priority"` // higher runs first on the same event
+	Version         int                    `json:"version"`
+	CandidateOf     string                 `json:"candidate_of,omitempty"` // live strategy ID of a shadow candidate version
+	Variant         string                 `json:"variant,omitempty"`      // A/B test variant the strategy runs as
+	VariantOf       string                 `json:"variant_of,omitempty"`   // strategy ID of the strategy under test
+	Tenant          string                 `json:"tenant"`                 // desk owning the strategy and its accounts
 	CreatedAt       time.Time              `json:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at"`
 }
 
+// StrategyVersion is one config version of a strategy
+type StrategyVersion struct {
+	StrategyID string                 `json:"strategy_id"`
+	Version    int                    `json:"version"`
+	Config     map[string]interface{} `json:"config"`
+	Status     string                 `json:"status"` // candidate, live, retired, rejected
+	Note       string                 `json:"note,omitempty"`
+	CreatedBy  string                 `json:"created_by,omitempty"`
+	DecidedBy  string                 `json:"decided_by,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	DecidedAt  *time.Time             `json:"decided_at,omitempty"`
+}
+
+// StrategyRun is one activation period of a strategy: the config that was live
+// from StartedAt until EndedAt (nil while it runs), with the realized PnL
+// recorded in that period
+type StrategyRun struct {
+	ID          int64                  `json:"id"`
+	StrategyID  string                 `json:"strategy_id"`
+	Version     int                    `json:"version"`
+	Config      map[string]interface{} `json:"config"`
+	Shadow      bool                   `json:"shadow"`
+	StartedAt   time.Time              `json:"started_at"`
+	EndedAt     *time.Time             `json:"ended_at,omitempty"`
+	EndReason   string                 `json:"end_reason,omitempty"` // disabled, config_changed, shadow_changed
+	Trades      int                    `json:"trades"`
+	RealizedPnL float64                `json:"realized_pnl"`
+}
+
 // StrategyHandler is the function signature for strategy handlers
 type StrategyHandler func(event Event, strategy Strategy) ([]Command, error)
 
@@ -49,3 +105,262 @@ type Position struct {
 	AvgPrice   float64   `json:"avg_price"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
+
+// OrderRecord is an order journal entry for an order sent to an account service
+type OrderRecord struct {
+	Strategy       string        `json:"strategy"`
+	Tenant         string        `json:"tenant,omitempty"`
+	Platform       string        `json:"platform"`
+	AccountID      string        `json:"account_id"`
+	MarketID       string        `json:"market_id"`
+	OutcomeID      string        `json:"outcome_id,omitempty"`
+	Side           string        `json:"side"`
+	Action         string        `json:"action,omitempty"` // buy or sell
+	Price          float64       `json:"price"`
+	Shares         float64       `json:"shares"`
+	ReferencePrice float64       `json:"reference_price"` // market price of the side when sent, or the order price without one
+	OrderID        string        `json:"order_id"`
+	Status         string        `json:"status"` // accepted, rejected, failed, dry_run, shadow
+	Error          string        `json:"error,omitempty"`
+	Latency        time.Duration `json:"latency"`
+	HedgeLatency   time.Duration `json:"hedge_latency,omitempty"` // accepted hedges: from the hedged fill to acceptance
+	ExpiresAt      *time.Time    `json:"expires_at,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// VenueQuality summarizes execution quality on one platform over a window
+type VenueQuality struct {
+	Platform        string  `json:"platform"`
+	Orders          int     `json:"orders"`
+	Accepted        int     `json:"accepted"`
+	Rejected        int     `json:"rejected"`
+	Failed          int     `json:"failed"`
+	Filled          int     `json:"filled"`
+	FillRate        float64 `json:"fill_rate"`   // filled / accepted
+	RejectRate      float64 `json:"reject_rate"` // rejected / orders
+	AvgLatencyMs    float64 `json:"avg_latency_ms"`
+	P95LatencyMs    float64 `json:"p95_latency_ms"`
+	EffectiveSpread float64 `json:"effective_spread"` // avg price paid over the reference price
+}
+
+// PnLRecord is a realized profit or loss attributed to a strategy
+type PnLRecord struct {
+	Strategy  string    `json:"strategy"`
+	Platform  string    `json:"platform"`
+	MarketID  string    `json:"market_id"`
+	OrderID   string    `json:"order_id"`
+	EventID   string    `json:"event_id,omitempty"` // booking event; each books a kind once per strategy
+	Kind      string    `json:"kind"` // PnLRealized (default) or PnLFee
+	PnL       float64   `json:"pnl"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PnL record kinds. Fee records book the modeled trading cost of a fill; they
+// count towards PnL but not as trades.
+const (
+	PnLRealized = "realized"
+	PnLFee      = "fee"
+)
+
+// StrategyPerformance summarizes a strategy's realized results over a window
+type StrategyPerformance struct {
+	Strategy string  `json:"strategy"`
+	Trades   int     `json:"trades"`
+	Wins     int     `json:"wins"`
+	PnL      float64 `json:"pnl"`      // net of fees
+	Fees     float64 `json:"fees"`     // modeled trading costs, included in PnL
+	HitRate  float64 `json:"hit_rate"` // wins / trades
+}
+
+// StrategyAttribution breaks a strategy's activity down from the events it saw
+// to the realized PnL of the orders it placed. Event and command counts are
+// since engine start; order, fill and PnL figures cover the requested window.
+type StrategyAttribution struct {
+	Strategy      string  `json:"strategy"`
+	Tenant        string  `json:"tenant,omitempty"`
+	Events        int64   `json:"events"`         // events delivered to the handler
+	EventsMatched int64   `json:"events_matched"` // events that produced at least one command
+	Commands      int64   `json:"commands"`
+	HandlerErrors int64   `json:"handler_errors"` // including timeouts
+	Timeouts      int64   `json:"handler_timeouts"`
+	Orders        int     `json:"orders"`
+	Accepted      int     `json:"accepted"`
+	Filled        int     `json:"filled"`
+	FillRate      float64 `json:"fill_rate"` // filled / accepted
+	Trades        int     `json:"trades"`
+	RealizedPnL   float64 `json:"realized_pnl"`
+}
+
+// DailyReport is the end-of-day summary of one UTC day: what every strategy
+// traded, earned and had dropped. Order figures exclude shadow and dry-run
+// orders; risk events are the strategy_error events of the day by class.
+type DailyReport struct {
+	Day         string         `json:"day"` // YYYY-MM-DD
+	Orders      int            `json:"orders"`
+	Accepted    int            `json:"accepted"`
+	Rejected    int            `json:"rejected"`
+	Failed      int            `json:"failed"`
+	Filled      int            `json:"filled"`
+	Volume      float64        `json:"volume"` // filled notional
+	Fees        float64        `json:"fees"`
+	RealizedPnL float64        `json:"realized_pnl"`
+	RiskEvents  map[string]int `json:"risk_events"`
+	Alerts      map[string]int `json:"alerts"` // alerts raised, by type
+	Strategies  []StrategyDay  `json:"strategies"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// StrategyDay is one strategy's share of a DailyReport
+type StrategyDay struct {
+	Strategy    string         `json:"strategy"`
+	Tenant      string         `json:"tenant,omitempty"`
+	Orders      int            `json:"orders"`
+	Accepted    int            `json:"accepted"`
+	Rejected    int            `json:"rejected"`
+	Failed      int            `json:"failed"`
+	Filled      int            `json:"filled"`
+	Volume      float64        `json:"volume"`
+	Fees        float64        `json:"fees"`
+	RealizedPnL float64        `json:"realized_pnl"`
+	RiskEvents  map[string]int `json:"risk_events,omitempty"`
+}
+
+// OutboxEntry is a command persisted for delivery by the outbox dispatcher
+type OutboxEntry struct {
+	ID         int64   `json:"id"`
+	Strategy   string  `json:"strategy"`
+	StrategyID string  `json:"strategy_id"`
+	EventID    string  `json:"event_id"`
+	EventType  string  `json:"event_type"`
+	Command    Command `json:"command"`
+	Status     string  `json:"status,omitempty"` // pending, processing, done, failed, unknown
+	Attempts   int     `json:"attempts"`
+	LastError  string  `json:"last_error,omitempty"`
+
+	ClientOrderID string `json:"client_order_id,omitempty"` // the key the command is sent with
+}
+
+// ConditionalCommand is a place_order the engine holds until its trigger
+// price is crossed, or a resting order of a one-cancels-other group
+type ConditionalCommand struct {
+	ID         int64     `json:"id"`
+	Strategy   string    `json:"strategy"`
+	StrategyID string    `json:"strategy_id"`
+	Group      string    `json:"oco_group,omitempty"`
+	Status     string    `json:"status"` // pending (waiting for its trigger) or resting (placed, in a group)
+	OrderID    string    `json:"order_id,omitempty"`
+	Command    Command   `json:"command"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Conditional command statuses
+const (
+	ConditionalPending = "pending"
+	ConditionalResting = "resting"
+)
+
+// WireLogEntry is a request sent to an account service and the answer it
+// got, with credentials and account IDs redacted
+type WireLogEntry struct {
+	ID              int64             `json:"id"`
+	Platform        string            `json:"platform"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	Status          int               `json:"status,omitempty"` // zero when no answer came
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	Error           string            `json:"error,omitempty"`
+	Latency         time.Duration     `json:"latency"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+// Settlement is a position closed out by the resolution of its market
+type Settlement struct {
+	AccountID string  `json:"account_id"`
+	Platform  string  `json:"platform"`
+	MarketID  string  `json:"market_id"`
+	Side      string  `json:"side"`
+	Shares    float64 `json:"shares"`
+	AvgPrice  float64 `json:"avg_price"`
+	Payout    float64 `json:"payout"` // per share: 1 for the winning side, 0 otherwise
+	PnL       float64 `json:"pnl"`
+}
+
+// MarketResolution records the winning side of a resolved market
+type MarketResolution struct {
+	Platform   string    `json:"platform"`
+	MarketID   string    `json:"market_id"`
+	Outcome    string    `json:"outcome"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// MarketMapping pairs a Predict market with the Polymarket condition that
+// asks the same question
+type MarketMapping struct {
+	ID                    int64     `json:"id"`
+	PredictMarketID       string    `json:"predict_market_id"`
+	PolymarketConditionID string    `json:"polymarket_condition_id"`
+	Inverted              bool      `json:"inverted"` // YES on Predict pays like NO on Polymarket
+	Note                  string    `json:"note,omitempty"`
+	CreatedBy             string    `json:"created_by"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// TradingAccount is an account the engine may place orders on, with its
+// limits. Orders are checked against it before they are sent.
+type TradingAccount struct {
+	Platform       string    `json:"platform"`
+	AccountID      string    `json:"account_id"` // the account service's ID, as commands name it
+	Name           string    `json:"name,omitempty"`
+	Enabled        bool      `json:"enabled"`
+	MaxOrderShares float64   `json:"max_order_shares,omitempty"` // zero: no limit
+	AllowedMarkets []string  `json:"allowed_markets,omitempty"`  // empty: any market
+	Note           string    `json:"note,omitempty"`
+	UpdatedBy      string    `json:"updated_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// FeatureFlag gates a risky engine subsystem, e.g. a new risk check, so it
+// can be switched on per environment and for a share of markets without a
+// deploy
+type FeatureFlag struct {
+	Name         string    `json:"name"`
+	Enabled      bool      `json:"enabled"`
+	Percent      float64   `json:"percent"`                // share of markets it is on for, 0 to 100
+	Environments []string  `json:"environments,omitempty"` // empty: every environment
+	Description  string    `json:"description,omitempty"`
+	UpdatedBy    string    `json:"updated_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Decision is what a strategy made of one event: the commands it would send,
+// or the class of error that dropped them. Engines record decisions so the
+// decisions of two builds can be compared.
+type Decision struct {
+	Instance   string    `json:"instance"`
+	Strategy   string    `json:"strategy"`
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	Commands   []Command `json:"commands,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Explanation is why a strategy in explain mode did or did not act on one
+// event: the reasons its handler and the engine gave, in order
+type Explanation struct {
+	Strategy  string    `json:"strategy"`
+	EventID   string    `json:"event_id"`
+	EventType string    `json:"event_type"`
+	Platform  string    `json:"platform,omitempty"`
+	Acted     bool      `json:"acted"`    // commands went on to execution
+	Commands  int       `json:"commands"` // how many
+	Reasons   []string  `json:"reasons"`
+	At        time.Time `json:"at"`
+}
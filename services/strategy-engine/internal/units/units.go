@@ -0,0 +1,121 @@
+// Package units converts order sizes between the share units of each venue
+// and the common units strategies can size orders in, so the same size means
+// the same exposure on every platform.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Size units of Command.SizeUnit:
+//
+//	"shares" (default): the venue's own shares, as Command.Shares
+//	"contracts":        contracts paying out 1 USD when they win
+//	"usd":              notional in USD, the cost of the order at its price
+const (
+	Shares    = "shares"
+	Contracts = "contracts"
+	USD       = "usd"
+)
+
+// ErrInvalidSize is returned for sizes that cannot be converted
+var ErrInvalidSize = errors.New("invalid size")
+
+// Platform is the share convention of one venue. Zero values mean 1.
+type Platform struct {
+	// ContractSize is how many collateral units one share pays out when it
+	// wins (1 USDC per share on Polymarket)
+	ContractSize float64
+
+	// CollateralUSD is the USD value of one collateral unit
+	CollateralUSD float64
+}
+
+// payout is the USD one share of the platform pays out when it wins
+func (p Platform) payout() float64 {
+	size, rate := p.ContractSize, p.CollateralUSD
+	if size <= 0 {
+		size = 1
+	}
+	if rate <= 0 {
+		rate = 1
+	}
+	return size * rate
+}
+
+// Converter converts sizes per platform. Platforms it does not know pay out
+// 1 USD per share, as does every platform of a nil Converter. It is safe for
+// concurrent use once built.
+type Converter struct {
+	platforms map[string]Platform
+}
+
+// NewConverter returns a converter over the given platforms
+func NewConverter(platforms map[string]Platform) *Converter {
+	return &Converter{platforms: platforms}
+}
+
+// Valid reports whether unit is a known size unit; empty means shares
+func Valid(unit string) bool {
+	switch normalize(unit) {
+	case Shares, Contracts, USD:
+		return true
+	}
+	return false
+}
+
+func normalize(unit string) string {
+	unit = strings.ToLower(unit)
+	if unit == "" {
+		return Shares
+	}
+	return unit
+}
+
+func (c *Converter) payout(platform string) float64 {
+	if c == nil {
+		return 1
+	}
+	return c.platforms[platform].payout()
+}
+
+// Shares converts a size in unit to shares of the platform. USD sizes are
+// bought at price, the price of one share as a fraction of its payout.
+func (c *Converter) Shares(platform, unit string, size, price float64) (float64, error) {
+	if size < 0 {
+		return 0, fmt.Errorf("%w: size %.4g is negative", ErrInvalidSize, size)
+	}
+
+	switch normalize(unit) {
+	case Shares:
+		return size, nil
+	case Contracts:
+		return size / c.payout(platform), nil
+	case USD:
+		if price <= 0 {
+			return 0, fmt.Errorf("%w: a usd size needs a positive price", ErrInvalidSize)
+		}
+		return size / (price * c.payout(platform)), nil
+	default:
+		return 0, fmt.Errorf("%w: unknown size unit %q", ErrInvalidSize, unit)
+	}
+}
+
+// Contracts converts shares of the platform to 1 USD contracts, e.g. to hedge
+// a fill with the same exposure on another platform
+func (c *Converter) Contracts(platform string, shares float64) float64 {
+	return shares * c.payout(platform)
+}
+
+// Notional returns the USD cost of shares of the platform bought at price
+func (c *Converter) Notional(platform string, shares, price float64) float64 {
+	return shares * price * c.payout(platform)
+}
+
+// Convert returns the shares of platform to that pay out the same as shares
+// of platform from
+func (c *Converter) Convert(from, to string, shares float64) float64 {
+	return shares * c.payout(from) / c.payout(to)
+}
@@ -0,0 +1,181 @@
+package wirelog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+)
+
+// Redaction: credentials are replaced by secrets.Redacted; account IDs by
+// "acct:" and a short hash, so the entries of one account can still be told
+// apart. It applies to
+//
+//   - headers whose name looks like a credential (Authorization, cookies,
+//     X-Signature, anything with key, token, secret or password) and the
+//     platform's API key header
+//   - JSON body fields and query parameters named like a credential or an
+//     account (credentialFields, accountFields), at any depth
+//   - path segments following /accounts/, /orders/ and /positions/, which
+//     carry account IDs in the account service API
+//
+// Finally every secret registered with the secrets package is masked
+// wherever it appears.
+
+var credentialFields = map[string]bool{
+	"api_key": true, "apikey": true, "api_secret": true, "secret": true,
+	"passphrase": true, "password": true, "private_key": true, "privatekey": true,
+	"signature": true, "token": true, "auth": true, "authorization": true,
+}
+
+var accountFields = map[string]bool{
+	"account_id": true, "accountid": true, "account": true, "address": true,
+	"wallet": true, "maker": true, "signer": true, "owner": true, "funder": true,
+}
+
+// accountPaths are the path segments followed by an account ID
+var accountPaths = map[string]bool{"accounts": true, "orders": true, "positions": true}
+
+func fieldKind(name string) (credential, account bool) {
+	name = strings.ToLower(name)
+	return credentialFields[name], accountFields[name]
+}
+
+// accountHash replaces an account ID
+func accountHash(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return "acct:" + hex.EncodeToString(sum[:4])
+}
+
+func sensitiveHeader(name, authHeader string) bool {
+	name = strings.ToLower(name)
+	if authHeader != "" && name == strings.ToLower(authHeader) {
+		return true
+	}
+	switch name {
+	case "authorization", "proxy-authorization", "cookie", "set-cookie", "x-signature":
+		return true
+	}
+	for _, part := range []string{"key", "token", "secret", "password"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeaders flattens headers for storage, redacted
+func redactHeaders(header http.Header, authHeader string) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for name, values := range header {
+		if sensitiveHeader(name, authHeader) {
+			redacted[name] = secrets.Redacted
+			continue
+		}
+		redacted[name] = string(secrets.Redact([]byte(strings.Join(values, ", "))))
+	}
+	return redacted
+}
+
+// redactURL returns the URL with account IDs and credentials redacted
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+
+	segments := strings.Split(redacted.Path, "/")
+	for i := 1; i < len(segments); i++ {
+		if accountPaths[segments[i-1]] && segments[i] != "" {
+			id, err := url.PathUnescape(segments[i])
+			if err != nil {
+				id = segments[i]
+			}
+			segments[i] = accountHash(id)
+		}
+	}
+	redacted.Path = strings.Join(segments, "/")
+	redacted.RawPath = ""
+
+	query := redacted.Query()
+	for name, values := range query {
+		credential, account := fieldKind(name)
+		for i, value := range values {
+			switch {
+			case credential:
+				values[i] = secrets.Redacted
+			case account:
+				values[i] = accountHash(value)
+			}
+		}
+		query[name] = values
+	}
+	redacted.RawQuery = query.Encode()
+
+	return string(secrets.Redact([]byte(redacted.String())))
+}
+
+// redactBody redacts a JSON body field by field, which re-encodes it with
+// its keys sorted; other bodies only get the registered secrets masked
+func redactBody(body []byte) []byte {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return secrets.Redact(body)
+	}
+	var redacted bytes.Buffer
+	encoder := json.NewEncoder(&redacted)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redactValue(value)); err != nil {
+		return secrets.Redact(body)
+	}
+	return secrets.Redact(bytes.TrimSuffix(redacted.Bytes(), []byte("\n")))
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			credential, account := fieldKind(key)
+			switch {
+			case credential:
+				v[key] = secrets.Redacted
+			case account:
+				v[key] = redactAccount(field)
+			default:
+				v[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return value
+}
+
+// redactAccount hashes an account field, or each ID of a list
+func redactAccount(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return v
+		}
+		return accountHash(v)
+	case json.Number:
+		return accountHash(v.String())
+	case []interface{}:
+		for i := range v {
+			v[i] = redactAccount(v[i])
+		}
+		return v
+	case nil:
+		return nil
+	default:
+		return redactValue(value)
+	}
+}
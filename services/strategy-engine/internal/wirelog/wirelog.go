@@ -0,0 +1,216 @@
+// Package wirelog records the requests the executor sends to the account
+// services and the answers it gets, as they went over the wire, so disputes
+// with a service about what was actually sent can be settled. It wraps the
+// services' HTTP transport below any other wrapper, so injected faults are
+// not mistaken for real answers.
+//
+// A share of requests is recorded (SampleRate); failed requests and 4xx/5xx
+// answers always are. Credentials and account IDs are redacted before
+// anything is stored (see redact.go). Entries are written to the wire_log
+// table in batches by Run, which also deletes them after Retention.
+package wirelog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/secrets"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/storage"
+	"github.com/mukhametgalin/predict-trading-system/strategy-engine/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Defaults for zero Options
+const (
+	DefaultRetention    = 30 * 24 * time.Hour
+	DefaultMaxBodyBytes = 64 << 10
+)
+
+const (
+	bufferSize    = 1000
+	flushSize     = 100
+	flushInterval = time.Second
+	pruneInterval = time.Hour
+)
+
+// Options configures a Recorder
+type Options struct {
+	// SampleRate is the share of requests recorded, from 0 to 1. Failures
+	// are recorded regardless.
+	SampleRate float64
+
+	// Retention is how long entries are kept; zero means DefaultRetention
+	Retention time.Duration
+
+	// MaxBodyBytes truncates stored bodies; zero means DefaultMaxBodyBytes
+	MaxBodyBytes int
+}
+
+// Recorder records account service requests and writes them to storage
+type Recorder struct {
+	storage *storage.PostgresStorage
+	opts    Options
+	entries chan types.WireLogEntry
+	dropped atomic.Int64
+}
+
+// New returns a recorder; entries are written once Run is started
+func New(storage *storage.PostgresStorage, opts Options) *Recorder {
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	return &Recorder{
+		storage: storage,
+		opts:    opts,
+		entries: make(chan types.WireLogEntry, bufferSize),
+	}
+}
+
+// Transport returns a wrapper of a platform's account service transport that
+// records its requests. authHeader is the header carrying the platform's API
+// key, if not Authorization.
+func (r *Recorder) Transport(platform, authHeader string) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &recordingTransport{next: next, recorder: r, platform: platform, authHeader: authHeader}
+	}
+}
+
+type recordingTransport struct {
+	next       http.RoundTripper
+	recorder   *Recorder
+	platform   string
+	authHeader string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sampled := t.recorder.opts.SampleRate > 0 && rand.Float64() < t.recorder.opts.SampleRate
+
+	// The body is read from a copy, so the request is sent untouched
+	var requestBody []byte
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			requestBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(start)
+
+	failed := err != nil || resp.StatusCode >= 400
+	if !sampled && !failed {
+		return resp, err
+	}
+
+	entry := types.WireLogEntry{
+		Platform:       t.platform,
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeaders: redactHeaders(req.Header, t.authHeader),
+		RequestBody:    t.recorder.body(requestBody),
+		Latency:        latency,
+		CreatedAt:      start.UTC(),
+	}
+	if err != nil {
+		entry.Error = string(secrets.Redact([]byte(err.Error())))
+	} else {
+		responseBody, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+		if readErr != nil {
+			entry.Error = string(secrets.Redact([]byte(readErr.Error())))
+		}
+		entry.Status = resp.StatusCode
+		entry.ResponseHeaders = redactHeaders(resp.Header, t.authHeader)
+		entry.ResponseBody = t.recorder.body(responseBody)
+	}
+	t.recorder.record(entry)
+	return resp, err
+}
+
+// body redacts and truncates a body for storage
+func (r *Recorder) body(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	redacted := redactBody(body)
+	if len(redacted) > r.opts.MaxBodyBytes {
+		return string(redacted[:r.opts.MaxBodyBytes]) + "…[truncated]"
+	}
+	return string(redacted)
+}
+
+// record queues an entry for the writer; entries are dropped rather than
+// holding up the request when the writer falls behind
+func (r *Recorder) record(entry types.WireLogEntry) {
+	select {
+	case r.entries <- entry:
+	default:
+		if r.dropped.Add(1) == 1 {
+			log.Warn().Msg("Wire log buffer full, dropping entries")
+		}
+	}
+}
+
+// Run writes recorded entries in batches and deletes expired ones every hour
+// until ctx is cancelled
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []types.WireLogEntry
+	var lastPrune time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			r.flush(context.WithoutCancel(ctx), batch)
+			return
+		case entry := <-r.entries:
+			batch = append(batch, entry)
+			if len(batch) < flushSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		r.flush(ctx, batch)
+		batch = batch[:0]
+
+		if time.Since(lastPrune) >= pruneInterval {
+			r.prune(ctx)
+			lastPrune = time.Now()
+		}
+	}
+}
+
+func (r *Recorder) flush(ctx context.Context, batch []types.WireLogEntry) {
+	if dropped := r.dropped.Swap(0); dropped > 0 {
+		log.Warn().Int64("dropped", dropped).Msg("Wire log entries dropped, the writer fell behind")
+	}
+	if len(batch) == 0 {
+		return
+	}
+	if err := r.storage.InsertWireLog(ctx, batch); err != nil {
+		log.Error().Err(err).Int("entries", len(batch)).Msg("Failed to write wire log")
+	}
+}
+
+func (r *Recorder) prune(ctx context.Context) {
+	deleted, err := r.storage.DeleteWireLog(ctx, time.Now().Add(-r.opts.Retention))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to apply wire log retention")
+		return
+	}
+	if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Msg("Deleted wire log entries past retention")
+	}
+}
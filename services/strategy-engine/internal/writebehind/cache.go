@@ -0,0 +1,208 @@
+// Package writebehind keeps state that the event loop reads and writes in
+// memory, and persists it in the background, so handling an event does not
+// wait on a database round trip.
+//
+// Consistency guarantees of a Cache:
+//
+//   - Reads see every write made through the same Cache (read-your-writes).
+//     A key is loaded from the store once, on first use; later changes made
+//     to the store by anyone else are not seen.
+//   - Writes are persisted at most FlushInterval later. Several writes to a
+//     key between two flushes are coalesced: only the latest value is saved,
+//     so the store never sees the intermediate ones.
+//   - A failed save keeps the key dirty and is retried on the next flush,
+//     unless the key was written again in between, in which case the newer
+//     value is saved instead.
+//   - A crash loses the writes of the last flush interval at most. Run flushes
+//     once more when its context ends, and Flush can be called on shutdown.
+//   - A key must have a single writing process. Two processes caching the
+//     same key overwrite each other's flushes, the last one wins.
+package writebehind
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultFlushInterval = time.Second
+	defaultFlushTimeout  = 10 * time.Second
+)
+
+// Options configure a Cache
+type Options[K comparable, V any] struct {
+	// Name identifies the cache in logs
+	Name string
+
+	// Load reads a key from the store; found is false if it does not exist
+	Load func(ctx context.Context, key K) (value V, found bool, err error)
+
+	// Save writes the latest value of a key to the store
+	Save func(ctx context.Context, key K, value V) error
+
+	// FlushInterval is how often dirty keys are saved; zero means 1s
+	FlushInterval time.Duration
+
+	// FlushTimeout bounds one flush; zero means 10s
+	FlushTimeout time.Duration
+}
+
+type entry[V any] struct {
+	value V
+	found bool
+}
+
+// Cache is a write-behind cache over a store; see the package documentation
+// for its guarantees
+type Cache[K comparable, V any] struct {
+	opts Options[K, V]
+
+	mu      sync.Mutex
+	entries map[K]entry[V]
+	dirty   map[K]uint64 // key -> write sequence of the last unsaved write
+	seq     uint64
+
+	flushMu sync.Mutex // one flush at a time
+}
+
+// New returns an empty cache
+func New[K comparable, V any](opts Options[K, V]) *Cache[K, V] {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+	if opts.FlushTimeout <= 0 {
+		opts.FlushTimeout = defaultFlushTimeout
+	}
+	return &Cache[K, V]{
+		opts:    opts,
+		entries: make(map[K]entry[V]),
+		dirty:   make(map[K]uint64),
+	}
+}
+
+// Get returns the value of a key, loading it from the store on first use
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return e.value, e.found, nil
+	}
+
+	value, found, err := c.opts.Load(ctx, key)
+	if err != nil {
+		var zero V
+		return zero, false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A concurrent load or write got there first
+	if e, ok := c.entries[key]; ok {
+		return e.value, e.found, nil
+	}
+	c.entries[key] = entry[V]{value: value, found: found}
+	return value, found, nil
+}
+
+// Update replaces the value of a key with what fn returns, given the current
+// value. fn runs under the cache lock, so updates of the cache are atomic; it
+// must not call back into the cache. If fn fails nothing changes.
+func (c *Cache[K, V]) Update(ctx context.Context, key K, fn func(value V, found bool) (V, error)) (V, error) {
+	if _, _, err := c.Get(ctx, key); err != nil {
+		var zero V
+		return zero, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.entries[key]
+	value, err := fn(current.value, current.found)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	c.seq++
+	c.entries[key] = entry[V]{value: value, found: true}
+	c.dirty[key] = c.seq
+	return value, nil
+}
+
+// Pending returns how many keys have writes not saved yet
+func (c *Cache[K, V]) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.dirty)
+}
+
+// Flush saves every dirty key and returns the errors of the failed saves
+func (c *Cache[K, V]) Flush(ctx context.Context) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	type write struct {
+		key   K
+		value V
+		seq   uint64
+	}
+
+	c.mu.Lock()
+	writes := make([]write, 0, len(c.dirty))
+	for key, seq := range c.dirty {
+		writes = append(writes, write{key: key, value: c.entries[key].value, seq: seq})
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for _, w := range writes {
+		err := c.opts.Save(ctx, w.key, w.value)
+
+		c.mu.Lock()
+		// Written again during the save: stays dirty for the next flush
+		if err == nil && c.dirty[w.key] == w.seq {
+			delete(c.dirty, w.key)
+		}
+		c.mu.Unlock()
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run flushes every FlushInterval until ctx is done, then flushes once more
+func (c *Cache[K, V]) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), c.opts.FlushTimeout)
+			c.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			flushCtx, cancel := context.WithTimeout(ctx, c.opts.FlushTimeout)
+			c.flush(flushCtx)
+			cancel()
+		}
+	}
+}
+
+func (c *Cache[K, V]) flush(ctx context.Context) {
+	if err := c.Flush(ctx); err != nil {
+		log.Error().
+			Err(err).
+			Str("cache", c.opts.Name).
+			Int("pending", c.Pending()).
+			Msg("Write-behind flush failed, retrying on the next flush")
+	}
+}
@@ -92,7 +92,7 @@ app.add_middleware(
 
 async def event_listener():
     """Listen to Redis streams and broadcast to WebSocket clients"""
-    streams = ["trade_events", "fill_events", "account_events"]
+    streams = ["trade_events", "fill_events", "account_events", "command_events"]
     last_ids = {s: "0" for s in streams}
     
     while True:
@@ -635,7 +635,7 @@ async def list_markets(
 async def list_strategies(db: AsyncSession = Depends(get_db)):
     """List all strategies"""
     result = await db.execute(text("""
-        SELECT id, name, type, config, enabled, created_at
+        SELECT id, name, type, config, enabled, created_at, tenant
         FROM strategies
         ORDER BY created_at DESC
     """))
@@ -649,6 +649,7 @@ async def list_strategies(db: AsyncSession = Depends(get_db)):
             config=row[3] or {},
             enabled=row[4],
             created_at=row[5],
+            tenant=row[6],
         ))
     
     return strategies
@@ -664,14 +665,15 @@ async def create_strategy(data: StrategyCreate, db: AsyncSession = Depends(get_d
     # Use raw SQL with proper casting
     config_json = json.dumps(data.config)
     result = await db.execute(text(
-        "INSERT INTO strategies (name, type, config, enabled) "
-        "VALUES (:name, :type, CAST(:config AS jsonb), :enabled) "
-        "RETURNING id, name, type, config, enabled, created_at"
+        "INSERT INTO strategies (name, type, config, enabled, tenant) "
+        "VALUES (:name, :type, CAST(:config AS jsonb), :enabled, :tenant) "
+        "RETURNING id, name, type, config, enabled, created_at, tenant"
     ).bindparams(
         name=data.name,
         type=data.type,
         config=config_json,
         enabled=data.enabled,
+        tenant=data.tenant,
     ))
     await db.commit()
     
@@ -683,6 +685,7 @@ async def create_strategy(data: StrategyCreate, db: AsyncSession = Depends(get_d
         config=row[3] or {},
         enabled=row[4],
         created_at=row[5],
+        tenant=row[6],
     )
 
 
@@ -715,7 +718,7 @@ async def update_strategy(
         UPDATE strategies
         SET {", ".join(updates)}, updated_at = NOW()
         WHERE id = CAST(:id AS uuid)
-        RETURNING id, name, type, config, enabled, created_at
+        RETURNING id, name, type, config, enabled, created_at, tenant
     """)
     result = await db.execute(query.bindparams(**params))
     await db.commit()
@@ -731,6 +734,7 @@ async def update_strategy(
         config=row[3] or {},
         enabled=row[4],
         created_at=row[5],
+        tenant=row[6],
     )
 
 
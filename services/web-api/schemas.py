@@ -95,6 +95,7 @@ class StrategyCreate(BaseModel):
     type: str  # delta_neutral, arbitrage, market_maker
     config: dict = {}
     enabled: bool = False
+    tenant: str = "default"  # desk owning the strategy
 
 
 class StrategyUpdate(BaseModel):
@@ -110,6 +111,7 @@ class StrategyDetail(BaseModel):
     config: dict
     enabled: bool
     created_at: datetime
+    tenant: str = "default"
     recent_logs: list[dict] = []
 
 